@@ -36,14 +36,24 @@ type config struct {
 	Host        string `env:"FORWARDER_HOST,required,report"`
 	MetricsPort string `env:"METRICS_PORT,report"`
 	BufferLimit int    `env:"SEND_BUFFER_SIZE,report"`
+
+	// BatchInterval coalesces the events of a namespace posted within
+	// the interval into one record. Events are posted one by one when
+	// it is not set.
+	BatchInterval time.Duration `env:"EVENT_BATCH_INTERVAL,report"`
+
+	// BatchMaxEvents is the number of events held for batching at which
+	// they are posted before the interval ends.
+	BatchMaxEvents int `env:"EVENT_BATCH_MAX_EVENTS,report"`
 }
 
 func main() {
 	stopCh := signals.SetupSignalHandler()
 
 	conf := config{
-		MetricsPort: "6060",
-		BufferLimit: 8 * 1024, // this is the default in fluent-logger-golang
+		MetricsPort:    "6060",
+		BufferLimit:    8 * 1024, // this is the default in fluent-logger-golang
+		BatchMaxEvents: 1000,
 	}
 	err := envstruct.Load(&conf)
 	if err != nil {
@@ -83,7 +93,17 @@ func main() {
 		}
 	}()
 
-	controller := event.NewController(f)
+	var fwd event.Forwarder = f
+	if conf.BatchInterval != 0 {
+		b, err := event.NewBatcher(f, conf.BatchInterval, conf.BatchMaxEvents)
+		if err != nil {
+			log.Fatalf("invalid event batching: %s", err)
+		}
+		go b.Run(stopCh)
+		fwd = b
+	}
+
+	controller := event.NewController(fwd)
 
 	informerFactory := informers.NewSharedInformerFactory(kclientset, 30*time.Second)
 
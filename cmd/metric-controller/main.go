@@ -18,14 +18,17 @@ package main
 import (
 	"flag"
 	"log"
+	"net"
+	"net/http"
 	"time"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
 	"github.com/knative/observability/pkg/client/clientset/versioned"
-	informers "github.com/knative/observability/pkg/client/informers/externalversions"
 	"github.com/knative/observability/pkg/metric"
+	"github.com/knative/observability/pkg/resync"
 	"github.com/knative/pkg/signals"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	coreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
@@ -35,13 +38,21 @@ import (
 type config struct {
 	Namespace                 string `env:"NAMESPACE,required,report"`
 	UseInsecureKubernetesPort bool   `env:"USE_INSECURE_KUBERNETES_PORT,report"`
+	HTTPPort                  string `env:"HTTP_PORT,report"`
 }
 
+var metricSinkResync = flag.Duration("metricsink-resync", resync.Default, "how often the MetricSinks and ClusterMetricSinks are reconciled again")
+
 func main() {
 	flag.Parse()
+	if err := resync.Validate("metricsink-resync", *metricSinkResync); err != nil {
+		log.Fatal(err.Error())
+	}
 	stopCh := signals.SetupSignalHandler()
 
-	var conf config
+	conf := config{
+		HTTPPort: "6060",
+	}
 	err := envstruct.Load(&conf)
 	if err != nil {
 		log.Fatal(err.Error())
@@ -95,14 +106,38 @@ func main() {
 		k8sClient.RbacV1(),
 	)
 
-	sinkInformerFactory := informers.NewSharedInformerFactory(client, time.Second*30)
+	sinkInformerFactory := resync.NewInformerFactory(client, resync.Periods{MetricSinks: *metricSinkResync})
 
 	cmsInformer := sinkInformerFactory.Observability().V1alpha1().ClusterMetricSinks().Informer()
 	cmsInformer.AddEventHandler(cmsController)
 
 	msInformer := sinkInformerFactory.Observability().V1alpha1().MetricSinks().Informer()
 	msInformer.AddEventHandler(msController)
+	err = msInformer.AddIndexers(metric.SecretIndexers)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	lsInformer := sinkInformerFactory.Observability().V1alpha1().LogSinks().Informer()
+	lsInformer.AddEventHandler(metric.NewLogSinkController(msController))
+
+	secretInformer := k8sinformers.NewSharedInformerFactory(k8sClient, time.Second*30).
+		Core().V1().Secrets()
+	secretInformer.Informer().AddEventHandler(metric.NewSecretKeyController(
+		msInformer.GetIndexer(),
+		secretInformer.Lister(),
+		client.ObservabilityV1alpha1(),
+		coreV1Client,
+	))
+	go secretInformer.Informer().Run(stopCh)
+
+	mux := http.NewServeMux()
+	mux.Handle("/secrets", metric.SecretDependenciesHandler(msInformer.GetIndexer()))
+	go func() {
+		log.Fatal(http.ListenAndServe(net.JoinHostPort("", conf.HTTPPort), mux))
+	}()
 
 	go msInformer.Run(stopCh)
+	go lsInformer.Run(stopCh)
 	cmsInformer.Run(stopCh)
 }
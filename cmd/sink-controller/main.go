@@ -16,29 +16,189 @@ limitations under the License.
 package main
 
 import (
+	"expvar"
 	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	// Sinks format timestamps in timezones the image may not have.
+	_ "time/tzdata"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
 	"github.com/knative/observability/pkg/client/clientset/versioned"
-	informers "github.com/knative/observability/pkg/client/informers/externalversions"
+	"github.com/knative/observability/pkg/debug"
+	"github.com/knative/observability/pkg/resync"
 	"github.com/knative/observability/pkg/sink"
 	"github.com/knative/pkg/signals"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	coreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 )
 
 type config struct {
-	Namespace           string `env:"NAMESPACE,              required, report"`
+	Namespace string `env:"NAMESPACE, required, report"`
+	HTTPPort  string `env:"HTTP_PORT, report"`
+
+	// Seconds between fluent-bit flushes and seconds fluent-bit waits for
+	// outputs to flush when it shuts down.
+	FluentBitFlush int `env:"FLUENT_BIT_FLUSH, report"`
+	FluentBitGrace int `env:"FLUENT_BIT_GRACE, report"`
+
+	// The directory on the nodes fluent-bit buffers container log chunks
+	// in. Chunks are only buffered in memory when unset and the buffer
+	// limits of sinks are ignored.
+	FluentBitStoragePath string `env:"FLUENT_BIT_STORAGE_PATH, report"`
+
+	// The images of the jobs that replay the chunks buffered in the
+	// storage path to a sink with the replay annotation and how long a
+	// replay runs.
+	ReplayFluentBitImage string        `env:"REPLAY_FLUENT_BIT_IMAGE, report"`
+	ReplayCopyImage      string        `env:"REPLAY_COPY_IMAGE, report"`
+	ReplayDuration       time.Duration `env:"REPLAY_DURATION, report"`
+
+	// pprof is disabled unless a port is set and only listens on localhost
+	// unless a host is set.
+	PprofHost string `env:"PPROF_HOST, report"`
+	PprofPort string `env:"PPROF_PORT, report"`
+
+	// The bearer token of POST requests to /reconcile and of requests to
+	// /topology and /debug/vars. Only requests from localhost are allowed
+	// when unset.
+	ReconcileToken string `env:"RECONCILE_TOKEN"`
+
+	// Namespaces with this annotation have their sinks throttled to the
+	// annotation's records per second in total.
+	NamespaceThrottleAnnotation string `env:"NAMESPACE_THROTTLE_ANNOTATION, report"`
+
+	// The parser of container logs, docker or cri. It is picked from the
+	// container runtime of the nodes when unset.
+	ContainerLogParser string `env:"CONTAINER_LOG_PARSER, report"`
+
+	// Container log files last written longer ago are skipped by fluent-bit,
+	// so a restart does not ship a backlog of old logs. All files are read
+	// when unset.
+	SkipLogsOlderThan time.Duration `env:"SKIP_LOGS_OLDER_THAN, report"`
+
+	// The configmap of custom parsers mounted into the fluent-bit pods.
+	// Fluent-bit is restarted when it changes.
+	ParsersConfigMap string `env:"PARSERS_CONFIGMAP, report"`
+
+	// Rendered configs are posted to the hook and the response is written
+	// to the configmap instead.
+	PostRenderHookURL     string        `env:"POST_RENDER_HOOK_URL, report"`
+	PostRenderHookTimeout time.Duration `env:"POST_RENDER_HOOK_TIMEOUT, report"`
+
+	// Sinks with debug_stdout set only write their records to the
+	// fluent-bit pods' stdout when this is enabled.
+	DebugStdoutEnabled bool `env:"DEBUG_STDOUT_ENABLED, report"`
+
+	// Sinks with geoip set only look up addresses when this is enabled. It
+	// needs a fluent-bit image built with the geoip2 filter, which the
+	// image in config/500-fluent-bit-daemon.yaml is not, and the databases
+	// of the sinks on every node.
+	GeoIPEnabled bool `env:"GEOIP_ENABLED, report"`
+
+	// Every sink's output discards its records while forwarding is
+	// disabled, which stops all egress without changing any sink.
+	ForwardingDisabled bool `env:"FORWARDING_DISABLED, report"`
+
+	// A threshold of 0 disables the circuit breaker.
+	FailureThreshold     int           `env:"CIRCUIT_BREAKER_FAILURE_THRESHOLD, report"`
+	ProbeInterval        time.Duration `env:"CIRCUIT_BREAKER_PROBE_INTERVAL, report"`
+	PollInterval         time.Duration `env:"CIRCUIT_BREAKER_POLL_INTERVAL, report"`
+	FluentBitMetricsPort int           `env:"FLUENT_BIT_METRICS_PORT, report"`
+
+	// How often the fluent-bit metrics are fetched while a sink has
+	// count_lines set.
+	LineCountInterval time.Duration `env:"LINE_COUNT_INTERVAL, report"`
+
+	// How often the fluent-bit storage metrics are fetched to export the
+	// buffer utilization of each sink.
+	BufferMetricsInterval time.Duration `env:"BUFFER_METRICS_INTERVAL, report"`
+
+	// The priority class set on the fluent-bit daemonset so its pods are
+	// not preempted. The daemonset is left as deployed when unset.
+	FluentBitPriorityClass string `env:"FLUENT_BIT_PRIORITY_CLASS, report"`
+
+	// The resources set on the fluent-bit container of the daemonset.
+	// Resources that are unset are left as deployed.
+	FluentBitCPURequest    string `env:"FLUENT_BIT_CPU_REQUEST, report"`
+	FluentBitMemoryRequest string `env:"FLUENT_BIT_MEMORY_REQUEST, report"`
+	FluentBitCPULimit      string `env:"FLUENT_BIT_CPU_LIMIT, report"`
+	FluentBitMemoryLimit   string `env:"FLUENT_BIT_MEMORY_LIMIT, report"`
+
+	// How long a preStop hook delays the shutdown of fluent-bit, which
+	// keeps delivering and retrying the records it holds in the meantime.
+	// No hook is set when unset.
+	FluentBitPreStopDelay time.Duration `env:"FLUENT_BIT_PRE_STOP_DELAY, report"`
+
+	// The file a line of JSON is appended to for every reconcile of the
+	// config and every other restart of fluent-bit, recording what changed
+	// and whether the config was written. It should be on a mounted volume,
+	// see config/300-sink-controller-audit.yaml, since the filesystem of
+	// the container does not survive its restarts. Nothing is recorded
+	// when unset.
+	ReconcileAuditPath string `env:"RECONCILE_AUDIT_PATH, report"`
+
+	// The git remote every config written to the configmap is committed
+	// and pushed to, with the branch, the directory it is cloned into and
+	// the author of the commits. Configs are not exported when unset. The
+	// remote is not reported since its URL may hold credentials. The
+	// export runs git, which the base image of .ko.yaml provides.
+	GitExportRemote      string `env:"GIT_EXPORT_REMOTE"`
+	GitExportBranch      string `env:"GIT_EXPORT_BRANCH, report"`
+	GitExportDir         string `env:"GIT_EXPORT_DIR, report"`
+	GitExportAuthorName  string `env:"GIT_EXPORT_AUTHOR_NAME, report"`
+	GitExportAuthorEmail string `env:"GIT_EXPORT_AUTHOR_EMAIL, report"`
 }
 
+// The render flag prints the config of the sinks in the manifests at its
+// path and exits without connecting to a cluster.
+var render = flag.String("render", "", "print the fluent-bit config of the sink manifests in a file or directory and exit")
+
+var sinkResync = flag.Duration("sink-resync", resync.Default, "how often the LogSinks and ClusterLogSinks are reconciled again")
+
 func main() {
 	flag.Parse()
+	if *render != "" {
+		rendered, err := sink.RenderManifests(*render)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Print(rendered)
+		return
+	}
+	if err := resync.Validate("sink-resync", *sinkResync); err != nil {
+		log.Fatal(err.Error())
+	}
+
 	stopCh := signals.SetupSignalHandler()
 
-	var conf config
+	conf := config{
+		HTTPPort:              "6060",
+		FluentBitFlush:        sink.DefaultFlush,
+		FluentBitGrace:        sink.DefaultGrace,
+		PostRenderHookTimeout: 5 * time.Second,
+		ProbeInterval:         5 * time.Minute,
+		PollInterval:          30 * time.Second,
+		FluentBitMetricsPort:  2020,
+		LineCountInterval:     30 * time.Second,
+		BufferMetricsInterval: 30 * time.Second,
+		ReplayFluentBitImage:  "oratos/fluent-bit-out-syslog:v0.19",
+		ReplayCopyImage:       "busybox:1.31",
+		ReplayDuration:        10 * time.Minute,
+		GitExportBranch:       "master",
+		GitExportDir:          "/tmp/config-export",
+		GitExportAuthorName:   "sink-controller",
+		GitExportAuthorEmail:  "sink-controller@observability.knative.dev",
+	}
 	err := envstruct.Load(&conf)
 	if err != nil {
 		log.Fatal(err.Error())
@@ -47,6 +207,24 @@ func main() {
 	if err != nil {
 		log.Fatal(err.Error())
 	}
+	if conf.SkipLogsOlderThan < 0 {
+		log.Fatalf("SKIP_LOGS_OLDER_THAN must be positive, got %s", conf.SkipLogsOlderThan)
+	}
+
+	fluentBitResources, err := sink.ResourceQuantities{
+		CPURequest:    conf.FluentBitCPURequest,
+		MemoryRequest: conf.FluentBitMemoryRequest,
+		CPULimit:      conf.FluentBitCPULimit,
+		MemoryLimit:   conf.FluentBitMemoryLimit,
+	}.Requirements()
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	setResources := len(fluentBitResources.Requests) > 0 || len(fluentBitResources.Limits) > 0
+
+	if conf.FluentBitPreStopDelay < 0 {
+		log.Fatalf("FLUENT_BIT_PRE_STOP_DELAY must be positive, got %s", conf.FluentBitPreStopDelay)
+	}
 
 	cfg, err := rest.InClusterConfig()
 	if err != nil {
@@ -63,6 +241,64 @@ func main() {
 		log.Fatal(err.Error())
 	}
 
+	k8sClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	missing := sink.CheckPermissions(
+		k8sClient.AuthorizationV1().SelfSubjectAccessReviews(),
+		sink.ControllerPermissions(conf.Namespace, sink.ControllerFeatures{
+			CircuitBreaker:    conf.FailureThreshold > 0,
+			NamespaceThrottle: conf.NamespaceThrottleAnnotation != "",
+			PriorityClass:     conf.FluentBitPriorityClass != "",
+			Resources:         setResources,
+			PreStop:           conf.FluentBitPreStopDelay > 0,
+			Replay:            conf.FluentBitStoragePath != "",
+		}),
+	)
+	if len(missing) > 0 {
+		log.Printf("The sink-controller is missing %d permissions, see config/200-sink-controller-roles.yaml", len(missing))
+	}
+
+	var configOpts []sink.ConfigOpt
+	if conf.PostRenderHookURL != "" {
+		configOpts = append(configOpts, sink.WithPostRenderHook(
+			sink.NewHTTPPostRenderHook(conf.PostRenderHookURL, conf.PostRenderHookTimeout),
+		))
+	}
+	if conf.DebugStdoutEnabled {
+		configOpts = append(configOpts, sink.WithDebugStdout())
+	}
+	if conf.GeoIPEnabled {
+		configOpts = append(configOpts, sink.WithGeoIP())
+	}
+	if conf.FluentBitStoragePath != "" {
+		configOpts = append(configOpts, sink.WithStorageBuffering())
+	}
+	if conf.ForwardingDisabled {
+		log.Print("Forwarding is disabled, every sink discards its records")
+		configOpts = append(configOpts, sink.WithForwardingDisabled())
+	}
+	if conf.ReconcileAuditPath != "" {
+		f, err := os.OpenFile(conf.ReconcileAuditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Unable to open the reconcile audit: %s", err)
+		}
+		defer f.Close()
+		configOpts = append(configOpts, sink.WithReconcileAudit(f))
+	}
+	if conf.GitExportRemote != "" {
+		configOpts = append(configOpts, sink.WithConfigExporter(sink.NewGitExporter(
+			conf.GitExportRemote,
+			conf.GitExportBranch,
+			conf.GitExportDir,
+			conf.GitExportAuthorName,
+			conf.GitExportAuthorEmail,
+		)))
+	}
+	sinkConfig := sink.NewConfig(configOpts...)
+
 	nodes, err := coreV1Client.Nodes().List(metav1.ListOptions{})
 	if err != nil {
 		log.Fatal(err.Error())
@@ -75,10 +311,36 @@ func main() {
 	sink.SetClusterNameFilter(
 		coreV1Client.ConfigMaps(conf.Namespace),
 		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
 		hostOverride,
 	)
 
-	sinkConfig := sink.NewConfig()
+	parser, err := sink.InputParser(conf.ContainerLogParser, nodes.Items)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	log.Printf("Using the %s parser for container logs", parser)
+	sink.SetInputParser(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+		parser,
+		conf.FluentBitStoragePath != "",
+		conf.SkipLogsOlderThan,
+	)
+
+	if conf.FluentBitFlush < 1 || conf.FluentBitGrace < 1 {
+		log.Fatal("FLUENT_BIT_FLUSH and FLUENT_BIT_GRACE must be at least 1")
+	}
+	sink.SetServiceConfig(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+		conf.FluentBitFlush,
+		conf.FluentBitGrace,
+		conf.FluentBitStoragePath,
+	)
+
 	controller := sink.NewController(
 		coreV1Client.ConfigMaps(conf.Namespace),
 		coreV1Client.Pods(conf.Namespace),
@@ -91,7 +353,91 @@ func main() {
 		sinkConfig,
 	)
 
-	sinkInformerFactory := informers.NewSharedInformerFactory(client, time.Second*30)
+	configMapController := sink.NewConfigMapController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+
+	filterSetController := sink.NewFilterSetController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+
+	if conf.FailureThreshold > 0 {
+		breaker := sink.NewBreaker(
+			coreV1Client.ConfigMaps(conf.Namespace),
+			coreV1Client.Pods(conf.Namespace),
+			client.ObservabilityV1alpha1(),
+			sinkConfig,
+			sink.WithFailureThreshold(conf.FailureThreshold),
+			sink.WithProbeInterval(conf.ProbeInterval),
+		)
+		go breaker.Run(
+			sink.NewFluentBitMetrics(
+				coreV1Client.Pods(conf.Namespace),
+				conf.FluentBitMetricsPort,
+			),
+			conf.PollInterval,
+			stopCh,
+		)
+	}
+
+	// SIGUSR1 pauses the reconciles for maintenance and resumes them when
+	// sent again. The informers keep running while paused.
+	pauseCh := make(chan os.Signal, 1)
+	signal.Notify(pauseCh, syscall.SIGUSR1)
+	go sink.NewPauser(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	).Run(pauseCh, stopCh)
+
+	go sink.NewForwardingReporter(client.ObservabilityV1alpha1(), sinkConfig).
+		Run(30*time.Second, stopCh)
+
+	lineCounter := sink.NewLineCounter(sinkConfig)
+	go lineCounter.Run(
+		sink.NewFluentBitMetrics(
+			coreV1Client.Pods(conf.Namespace),
+			conf.FluentBitMetricsPort,
+		),
+		conf.LineCountInterval,
+		stopCh,
+	)
+
+	go sink.NewBufferReporter(sinkConfig).Run(
+		sink.NewFluentBitMetrics(
+			coreV1Client.Pods(conf.Namespace),
+			conf.FluentBitMetricsPort,
+		),
+		conf.BufferMetricsInterval,
+		stopCh,
+	)
+
+	healthChecker := sink.NewHealthChecker(
+		k8sClient.AppsV1().DaemonSets(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/topology", sink.RequireToken(conf.ReconcileToken, sink.TopologyHandler(sinkConfig)))
+	mux.Handle("/health", sink.HealthHandler(healthChecker))
+	mux.Handle("/debug/vars", sink.RequireToken(conf.ReconcileToken, expvar.Handler()))
+	go func() {
+		log.Fatal(http.ListenAndServe(net.JoinHostPort("", conf.HTTPPort), mux))
+	}()
+
+	if srv := debug.NewPprofServer(conf.PprofHost, conf.PprofPort); srv != nil {
+		go func() {
+			log.Fatal(srv.ListenAndServe())
+		}()
+	}
+
+	sinkInformerFactory := resync.NewInformerFactory(client, resync.Periods{Sinks: *sinkResync})
 
 	sinkInformer := sinkInformerFactory.Observability().V1alpha1().LogSinks().Informer()
 	sinkInformer.AddEventHandler(controller)
@@ -99,6 +445,146 @@ func main() {
 	clusterSinkInformer := sinkInformerFactory.Observability().V1alpha1().ClusterLogSinks().Informer()
 	clusterSinkInformer.AddEventHandler(clusterController)
 
+	if conf.FluentBitStoragePath != "" {
+		replayController := sink.NewReplayController(
+			sink.ReplayOptions{
+				Namespace:      conf.Namespace,
+				StoragePath:    conf.FluentBitStoragePath,
+				FluentBitImage: conf.ReplayFluentBitImage,
+				CopyImage:      conf.ReplayCopyImage,
+				Duration:       conf.ReplayDuration,
+			},
+			k8sClient.BatchV1().Jobs(conf.Namespace),
+			coreV1Client.ConfigMaps(conf.Namespace),
+			client.ObservabilityV1alpha1(),
+			sinkConfig,
+		)
+		sinkInformer.AddEventHandler(replayController)
+		clusterSinkInformer.AddEventHandler(replayController)
+	}
+
+	filterSetInformer := sinkInformerFactory.Observability().V1alpha1().ClusterFilterSets().Informer()
+	filterSetInformer.AddEventHandler(filterSetController)
+
+	mux.Handle("/reconcile", sink.ReconcileHandler(
+		sink.NewReconciler(
+			coreV1Client.ConfigMaps(conf.Namespace),
+			coreV1Client.Pods(conf.Namespace),
+			sinkConfig,
+			sinkInformer.GetStore(),
+			clusterSinkInformer.GetStore(),
+			filterSetInformer.GetStore(),
+		),
+		conf.ReconcileToken,
+	))
+
+	configMapInformer := k8sinformers.NewSharedInformerFactoryWithOptions(
+		k8sClient,
+		time.Second*30,
+		k8sinformers.WithNamespace(conf.Namespace),
+		k8sinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = "metadata.name=" + sink.ConfigMapName
+		}),
+	).Core().V1().ConfigMaps().Informer()
+	configMapInformer.AddEventHandler(configMapController)
+	go configMapInformer.Run(stopCh)
+
+	if conf.ParsersConfigMap != "" {
+		parsersInformer := k8sinformers.NewSharedInformerFactoryWithOptions(
+			k8sClient,
+			time.Second*30,
+			k8sinformers.WithNamespace(conf.Namespace),
+			k8sinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.FieldSelector = "metadata.name=" + conf.ParsersConfigMap
+			}),
+		).Core().V1().ConfigMaps().Informer()
+		parsersInformer.AddEventHandler(sink.NewParsersController(
+			conf.ParsersConfigMap,
+			coreV1Client.Pods(conf.Namespace),
+			sinkConfig,
+		))
+		go parsersInformer.Run(stopCh)
+	}
+
+	namespaceController := sink.NewNamespaceController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+		conf.NamespaceThrottleAnnotation,
+	)
+	namespaceInformer := k8sinformers.NewSharedInformerFactory(k8sClient, time.Second*30).
+		Core().V1().Namespaces().Informer()
+	namespaceInformer.AddEventHandler(namespaceController)
+	go namespaceInformer.Run(stopCh)
+
+	nodeController := sink.NewNodeController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+	nodeInformer := k8sinformers.NewSharedInformerFactory(k8sClient, time.Second*30).
+		Core().V1().Nodes().Informer()
+	nodeInformer.AddEventHandler(nodeController)
+	go nodeInformer.Run(stopCh)
+
+	if conf.FluentBitPriorityClass != "" || setResources || conf.FluentBitPreStopDelay > 0 {
+		daemonSetInformer := k8sinformers.NewSharedInformerFactoryWithOptions(
+			k8sClient,
+			time.Second*30,
+			k8sinformers.WithNamespace(conf.Namespace),
+			k8sinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+				o.FieldSelector = "metadata.name=" + sink.DaemonSetName
+			}),
+		).Apps().V1().DaemonSets().Informer()
+		if conf.FluentBitPriorityClass != "" {
+			sink.CheckPriorityClass(
+				k8sClient.SchedulingV1beta1().PriorityClasses(),
+				conf.FluentBitPriorityClass,
+			)
+			daemonSetInformer.AddEventHandler(sink.NewPriorityClassController(
+				conf.FluentBitPriorityClass,
+				k8sClient.AppsV1().DaemonSets(conf.Namespace),
+			))
+		}
+		if setResources {
+			daemonSetInformer.AddEventHandler(sink.NewResourcesController(
+				fluentBitResources,
+				k8sClient.AppsV1().DaemonSets(conf.Namespace),
+			))
+		}
+		if conf.FluentBitPreStopDelay > 0 {
+			daemonSetInformer.AddEventHandler(sink.NewPreStopController(
+				conf.FluentBitPreStopDelay,
+				conf.FluentBitGrace,
+				k8sClient.AppsV1().DaemonSets(conf.Namespace),
+			))
+		}
+		go daemonSetInformer.Run(stopCh)
+	}
+
+	serviceController := sink.NewServiceController(
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+	serviceInformer := k8sinformers.NewSharedInformerFactory(k8sClient, time.Second*30).
+		Core().V1().Services().Informer()
+	serviceInformer.AddEventHandler(serviceController)
+	go serviceInformer.Run(stopCh)
+
+	// The Secrets sinks reference are read one at a time rather than
+	// watched, so the controller is not allowed to read every Secret.
+	headerSecretController := sink.NewHeaderSecretController(
+		conf.Namespace,
+		sink.ClientSecretGetter{Client: coreV1Client},
+		coreV1Client.Secrets(conf.Namespace),
+		coreV1Client.ConfigMaps(conf.Namespace),
+		coreV1Client.Pods(conf.Namespace),
+		sinkConfig,
+	)
+	go headerSecretController.Run(time.Second*30, stopCh)
+
+	go filterSetInformer.Run(stopCh)
 	go sinkInformer.Run(stopCh)
 	clusterSinkInformer.Run(stopCh)
 }
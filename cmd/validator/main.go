@@ -3,21 +3,76 @@ package main
 import (
 	"crypto/tls"
 	"log"
+	"time"
+	// Sink timezones are validated against the embedded database since
+	// the image may not have one.
+	_ "time/tzdata"
 
 	envstruct "code.cloudfoundry.org/go-envstruct"
+	"github.com/knative/observability/pkg/client/clientset/versioned"
+	informers "github.com/knative/observability/pkg/client/informers/externalversions"
+	listers "github.com/knative/observability/pkg/client/listers/sink/v1alpha1"
 	"github.com/knative/observability/pkg/webhook"
+	"github.com/knative/pkg/signals"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 )
 
 type config struct {
 	HTTPAddr string `env:"HTTP_ADDR, required, report"`
 	Cert     string `env:"VALIDATOR_CERT, required, report"`
 	Key      string `env:"VALIDATOR_KEY, required, report"`
+
+	// Types LogSinks may use, as a comma separated list for every
+	// namespace and namespace=types pairs separated by semicolons for
+	// specific namespaces. Every type is allowed when unset.
+	AllowedOutputTypes          string `env:"ALLOWED_OUTPUT_TYPES, report"`
+	NamespaceAllowedOutputTypes string `env:"NAMESPACE_ALLOWED_OUTPUT_TYPES, report"`
+
+	// LogSinks in the namespace of the logging pipeline are rejected
+	// unless they allow self capture.
+	ObservabilityNamespace string `env:"OBSERVABILITY_NAMESPACE, report"`
+
+	// Sinks whose destinations resolve to the service the logging
+	// pipeline ingests records on, as name.namespace with an optional
+	// :port, are rejected. The check is disabled when empty.
+	PipelineService string `env:"PIPELINE_SERVICE, report"`
+
+	// The startup rates of a namespace's LogSinks may add up to at most
+	// this many records per second. A cap of 0 disables the check.
+	NamespaceRateCap int `env:"NAMESPACE_RATE_CAP, report"`
+
+	// Syslog sinks that reference a service or port that does not exist
+	// are annotated when set.
+	CheckServiceRefs bool `env:"CHECK_SERVICE_REFS, report"`
+
+	// Updates that widen the records a ClusterLogSink reads, e.g. its
+	// namespace globs or node selector, are rejected unless the user may
+	// get the logs of pods in every namespace when set.
+	AuthorizeScopeWidening bool `env:"AUTHORIZE_SCOPE_WIDENING, report"`
+
+	// Operations accepted for each kind as kind=operations pairs separated
+	// by semicolons, e.g. LogSink=UPDATE,DELETE. Kinds without an entry
+	// accept every operation.
+	AllowedOperations string `env:"ALLOWED_OPERATIONS, report"`
+
+	// The configmap in the observability namespace whose operations key
+	// replaces ALLOWED_OPERATIONS while it is set, so operations can be
+	// frozen without a restart.
+	OperationsConfigMap string `env:"OPERATIONS_CONFIGMAP, report"`
 }
 
 func main() {
 	cfg := config{
 		Cert: "/etc/validator-certs/tls.crt",
 		Key:  "/etc/validator-certs/tls.key",
+
+		ObservabilityNamespace: "knative-observability",
+		PipelineService:        "fluent-bit.knative-observability:24224",
 	}
 	if err := envstruct.Load(&cfg); err != nil {
 		log.Fatalf("Failed to load config from environment: %s", err)
@@ -35,5 +90,132 @@ func main() {
 		log.Printf("Unable to write envstruct report: %s", err)
 	}
 
-	webhook.NewServer(cfg.HTTPAddr, webhook.WithTLSConfig(tlsConf)).Run(true)
+	outputTypes, err := webhook.ParseOutputTypePolicy(
+		cfg.AllowedOutputTypes,
+		cfg.NamespaceAllowedOutputTypes,
+	)
+	if err != nil {
+		log.Fatalf("Unable to parse output type policy: %s", err)
+	}
+
+	operations, err := webhook.ParseOperationPolicy(cfg.AllowedOperations)
+	if err != nil {
+		log.Fatalf("Unable to parse operation policy: %s", err)
+	}
+
+	pipelineService, err := webhook.ParsePipelineService(cfg.PipelineService)
+	if err != nil {
+		log.Fatalf("Unable to parse pipeline service: %s", err)
+	}
+
+	opts := []webhook.ServerOpt{
+		webhook.WithTLSConfig(tlsConf),
+		webhook.WithOutputTypePolicy(outputTypes),
+		webhook.WithObservabilityNamespace(cfg.ObservabilityNamespace),
+		webhook.WithPipelineService(pipelineService),
+		webhook.WithOperationPolicy(operations),
+	}
+	// The signal handler may only be set up once, so the listers share it.
+	var stopCh <-chan struct{}
+	if cfg.NamespaceRateCap > 0 || cfg.CheckServiceRefs || cfg.OperationsConfigMap != "" {
+		stopCh = signals.SetupSignalHandler()
+	}
+	if cfg.NamespaceRateCap > 0 {
+		sinks, synced := logSinkLister(stopCh)
+		opts = append(opts,
+			webhook.WithNamespaceRateCap(cfg.NamespaceRateCap, sinks),
+			webhook.WithCacheSyncs(synced),
+		)
+	}
+	if cfg.CheckServiceRefs {
+		services, synced := serviceLister(stopCh)
+		opts = append(opts,
+			webhook.WithServiceLister(services),
+			webhook.WithCacheSyncs(synced),
+		)
+	}
+	if cfg.AuthorizeScopeWidening {
+		client, err := kubernetes.NewForConfig(inClusterConfig())
+		if err != nil {
+			log.Fatalf("Unable to create kubernetes client: %s", err)
+		}
+		opts = append(opts, webhook.WithScopeAuthorizer(
+			client.AuthorizationV1().SubjectAccessReviews(),
+		))
+	}
+
+	server := webhook.NewServer(cfg.HTTPAddr, opts...)
+	if cfg.OperationsConfigMap != "" {
+		watchOperationPolicy(
+			stopCh,
+			cfg.ObservabilityNamespace,
+			cfg.OperationsConfigMap,
+			webhook.NewOperationPolicyController(server, operations),
+		)
+	}
+	server.Run(true)
+}
+
+func inClusterConfig() *rest.Config {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("Unable to load in cluster config: %s", err)
+	}
+	return restConfig
+}
+
+// logSinkLister returns a lister of every LogSink backed by an informer
+// cache and whether the cache has synced. The server reports unready until
+// it has.
+func logSinkLister(stopCh <-chan struct{}) (listers.LogSinkLister, cache.InformerSynced) {
+	client, err := versioned.NewForConfig(inClusterConfig())
+	if err != nil {
+		log.Fatalf("Unable to create sink client: %s", err)
+	}
+
+	sinks := informers.NewSharedInformerFactory(client, 30*time.Second).
+		Observability().V1alpha1().LogSinks()
+	informer := sinks.Informer()
+	go informer.Run(stopCh)
+	return sinks.Lister(), informer.HasSynced
+}
+
+// watchOperationPolicy sends the changes of the operation policy configmap
+// to the controller.
+func watchOperationPolicy(
+	stopCh <-chan struct{},
+	namespace string,
+	name string,
+	c *webhook.OperationPolicyController,
+) {
+	client, err := kubernetes.NewForConfig(inClusterConfig())
+	if err != nil {
+		log.Fatalf("Unable to create kubernetes client: %s", err)
+	}
+
+	informer := k8sinformers.NewSharedInformerFactoryWithOptions(
+		client,
+		30*time.Second,
+		k8sinformers.WithNamespace(namespace),
+		k8sinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = "metadata.name=" + name
+		}),
+	).Core().V1().ConfigMaps().Informer()
+	informer.AddEventHandler(c)
+	go informer.Run(stopCh)
+}
+
+// serviceLister returns a lister of every service backed by an informer
+// cache and whether the cache has synced.
+func serviceLister(stopCh <-chan struct{}) (corelisters.ServiceLister, cache.InformerSynced) {
+	client, err := kubernetes.NewForConfig(inClusterConfig())
+	if err != nil {
+		log.Fatalf("Unable to create kubernetes client: %s", err)
+	}
+
+	services := k8sinformers.NewSharedInformerFactory(client, 30*time.Second).
+		Core().V1().Services()
+	informer := services.Informer()
+	go informer.Run(stopCh)
+	return services.Lister(), informer.HasSynced
 }
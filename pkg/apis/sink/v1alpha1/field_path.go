@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFieldPath splits the field of a record named in a SinkSpec into its
+// keys. Nested keys are joined with dots and a key holding dots is written
+// in brackets, e.g. kubernetes.labels[app.kubernetes.io/name]. It returns
+// an error for empty keys and unbalanced brackets.
+func ParseFieldPath(f string) ([]string, error) {
+	var keys []string
+	for i := 0; i < len(f); {
+		var key string
+		if f[i] == '[' {
+			end := strings.IndexByte(f[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid field path %q: unterminated bracket", f)
+			}
+			key = f[i+1 : i+end]
+			i += end + 1
+		} else {
+			end := strings.IndexAny(f[i:], ".[]")
+			if end < 0 {
+				end = len(f) - i
+			}
+			key = f[i : i+end]
+			i += end
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid field path %q: empty key", f)
+		}
+		keys = append(keys, key)
+
+		if i == len(f) {
+			break
+		}
+		switch f[i] {
+		case '.':
+			i++
+			if i == len(f) {
+				return nil, fmt.Errorf("invalid field path %q: empty key", f)
+			}
+		case '[':
+		default:
+			return nil, fmt.Errorf("invalid field path %q: unexpected %q", f, f[i])
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("invalid field path %q: empty key", f)
+	}
+	return keys, nil
+}
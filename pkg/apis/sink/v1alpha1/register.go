@@ -51,6 +51,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&ClusterLogSinkList{},
 		&ClusterMetricSink{},
 		&ClusterMetricSinkList{},
+		&ClusterFilterSet{},
+		&ClusterFilterSetList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -16,6 +16,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -27,7 +29,8 @@ type LogSink struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
-	Spec SinkSpec `json:"spec"`
+	Spec   SinkSpec   `json:"spec"`
+	Status SinkStatus `json:"status,omitempty"`
 }
 
 // SinkSpec is the spec for a Sink resource
@@ -36,17 +39,411 @@ type SinkSpec struct {
 
 	SyslogSpec         `json:",inline"`
 	WebhookSpec        `json:",inline"`
+	UnixSocketSpec     `json:",inline"`
 	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+
+	// StartupDelay and StartupRate throttle the records the sink receives
+	// so that the backlog fluent-bit reads when it starts on a node is
+	// released at StartupRate records per second instead of all at once.
+	// Records above the rate are dropped until StartupDelay has passed
+	// since fluent-bit started, after which the sink is not throttled.
+	StartupDelay *metav1.Duration `json:"startup_delay,omitempty"`
+	StartupRate  int              `json:"startup_rate,omitempty"`
+
+	// SeveritySampling maps a severity to the fraction of records with that
+	// severity to keep, e.g. {"info": 0.1} keeps one in ten info records.
+	// Records with other or no severity are always kept.
+	SeveritySampling map[string]float64 `json:"severity_sampling,omitempty"`
+
+	// SeverityMapping maps a severity to the numeric syslog severity, from
+	// 0 for emergency to 7 for debug, that is set in SeverityField, e.g.
+	// {"warn": 4} for apps that log level=warn. Severities are matched
+	// in lower case and records with other or no severity are unchanged.
+	SeverityMapping map[string]int `json:"severity_mapping,omitempty"`
+	// SeverityField is the field the numeric severity is set in. It
+	// defaults to severity_number.
+	SeverityField string `json:"severity_field,omitempty"`
+
+	// RawMode forwards the container log lines of the sink's namespace as
+	// read from disk, without the docker parser or kubernetes metadata.
+	// Only webhook sinks support it.
+	RawMode bool `json:"raw_mode,omitempty"`
+
+	// Timestamp adds the time of each record, formatted in a timezone, to
+	// the record's timestamp field, or as epoch milliseconds to a field of
+	// its own, or both.
+	Timestamp *TimestampSpec `json:"timestamp,omitempty"`
+
+	// Metadata changes the kubernetes metadata added to records.
+	Metadata *MetadataSpec `json:"metadata,omitempty"`
+
+	// Priority orders the sink's filters and outputs in the rendered
+	// config. Sinks with a lower priority are rendered first and sinks with
+	// the same priority keep their order by namespace and name.
+	Priority int `json:"priority,omitempty"`
+
+	// DebugStdout also writes the records the sink receives to the stdout
+	// of the fluent-bit pods. It is ignored unless the sink-controller
+	// enables debug outputs.
+	DebugStdout bool `json:"debug_stdout,omitempty"`
+
+	// AllowSelfCapture allows a sink to receive the logs of the namespace
+	// the logging pipeline runs in. The validator rejects LogSinks in that
+	// namespace without it.
+	AllowSelfCapture bool `json:"allow_self_capture,omitempty"`
+
+	// IncludeImageMetadata adds the image and image digest of the container
+	// that wrote each record to the record's kubernetes metadata as
+	// container_image and container_image_digest.
+	IncludeImageMetadata bool `json:"include_image_metadata,omitempty"`
+
+	// IncludeSequence adds a sequence field to each record that increases
+	// by one for every record of the same container, so the destination
+	// can detect gaps. Each fluent-bit pod counts on its own and starts
+	// over when it restarts.
+	IncludeSequence bool `json:"include_sequence,omitempty"`
+
+	// SanitizeUTF8 replaces the bytes of each record's log that are not
+	// valid UTF-8 with U+FFFD, for destinations that only accept JSON.
+	SanitizeUTF8 bool `json:"sanitize_utf8,omitempty"`
+
+	// ParseJSONBody expands records whose log is a JSON object into top
+	// level fields of the record, in place of the log. Records whose log
+	// is not JSON keep it as a string.
+	ParseJSONBody bool `json:"parse_json_body,omitempty"`
+
+	// Coalesce collapses consecutive records of a container with the same
+	// log into the first of them. The next record of the container that is
+	// kept counts the dropped repeats in its repeated field. The repeats
+	// are dropped after IncludeSequence numbers them, so the gaps they
+	// leave in the sequence add up to the repeated counts.
+	Coalesce *CoalesceSpec `json:"coalesce,omitempty"`
+
+	// Heartbeat sends a heartbeat record to the sink every interval, so
+	// the destination can tell a namespace without logs from a pipeline
+	// that stopped delivering. Other sinks do not receive the heartbeats.
+	Heartbeat *HeartbeatSpec `json:"heartbeat,omitempty"`
+
+	// PerLabelThrottle caps the records per second of each value of a pod
+	// label, so a chatty pod does not use up the budget of the others.
+	PerLabelThrottle *PerLabelThrottleSpec `json:"per_label_throttle,omitempty"`
+
+	// FilterSetRefs are the names of ClusterFilterSets whose filters are
+	// applied to the sink's records, in order, before its own filters.
+	// The sink reads its own copy of its records for its filters, so the
+	// records of other sinks are not filtered.
+	FilterSetRefs []string `json:"filter_set_refs,omitempty"`
+
+	// Project lists the only top level keys of the records the sink sends.
+	// The sink receives its own copy of its namespace's records, so other
+	// sinks keep every key. Only webhook sinks support it.
+	Project []string `json:"project,omitempty"`
+
+	// Schema lists the top level keys the destination accepts. In strict
+	// mode the sink drops every other key before sending, like Project,
+	// and lenient mode passes records through unchanged.
+	Schema *SchemaSpec `json:"schema,omitempty"`
+
+	// NodeSelector limits the sink to the records of containers running on
+	// the nodes matching the labels, e.g. the nodes of a node pool. The sink
+	// receives its own copy of the records of the selected nodes, so other
+	// sinks keep the records of every node. Syslog, raw mode and audit
+	// sinks do not support it.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+
+	// CountLines exports the number of records the sink's output has
+	// processed from the sink-controller as sink_log_lines_total.
+	CountLines bool `json:"count_lines,omitempty"`
+
+	// NamespaceGlobs limits a ClusterLogSink to the namespaces matching
+	// any of the globs, e.g. team-*-prod, instead of every namespace. The
+	// globs are matched against the namespaces in the cluster as they are
+	// created and deleted.
+	NamespaceGlobs []string `json:"namespace_globs,omitempty"`
+
+	// RequireKubernetesMetadata is require to drop the records the sink
+	// receives without kubernetes metadata, e.g. the node's own logs, or
+	// allow to pass them through. It defaults to allow.
+	RequireKubernetesMetadata string `json:"require_kubernetes_metadata,omitempty"`
+
+	// RequireFields drops the records without any of the fields before
+	// they are sent, for destinations that reject a whole batch when a
+	// record misses a field of their schema. Nested fields are joined with
+	// dots, e.g. kubernetes.namespace_name, and keys holding dots are
+	// written in brackets, e.g. kubernetes.labels[app.kubernetes.io/name].
+	RequireFields []string `json:"require_fields,omitempty"`
+
+	// Redact drops, hashes or masks fields of each record before it is
+	// sent, e.g. email addresses that must not leave the cluster.
+	Redact []RedactRule `json:"redact,omitempty"`
+
+	// MaxFieldBytes truncates the string values of fields to at most the
+	// number of bytes, e.g. {"kubernetes.annotations.config": 1024}.
+	// Values are cut at the last whole UTF-8 character that fits. Nested
+	// fields are joined with dots like RequireFields.
+	MaxFieldBytes map[string]int `json:"max_field_bytes,omitempty"`
+
+	// GeoIP adds the country and ASN of the IP address in a field of each
+	// record, looked up in a MaxMind database mounted into the fluent-bit
+	// pods. It is only supported for ClusterLogSinks and is ignored unless
+	// the sink-controller has GEOIP_ENABLED set, which needs a fluent-bit
+	// image built with the geoip2 filter.
+	GeoIP *GeoIPSpec `json:"geoip,omitempty"`
+
+	// AuditLog makes a ClusterLogSink receive the kubernetes audit log of
+	// the control plane nodes instead of container logs.
+	AuditLog *AuditLogSpec `json:"audit_log,omitempty"`
+
+	// AuditFile writes a copy of every record the sink ships to a file on
+	// the node.
+	AuditFile *AuditFileSpec `json:"audit_file,omitempty"`
+
+	// Retry limits the records buffered for the sink's output while its
+	// destination is down.
+	Retry *RetrySpec `json:"retry,omitempty"`
+
+	// RetentionHint is how long the destination should keep the sink's
+	// records. Webhook sinks send it in seconds as the X-Retention-Hint
+	// header of every request. Syslog and unix socket sinks have nowhere
+	// to send it and ignore it.
+	RetentionHint *metav1.Duration `json:"retention_hint,omitempty"`
+
+	// SkipLogsOlderThan makes the sink's own tail input, that of a raw mode
+	// or audit log sink, skip the files last written longer ago, so a
+	// restarted fluent-bit does not ship a backlog of old logs. The shared
+	// container logs input is set by the sink-controller's
+	// SKIP_LOGS_OLDER_THAN instead.
+	SkipLogsOlderThan *metav1.Duration `json:"skip_logs_older_than,omitempty"`
+
+	// AnnotationRouting sends the records of a webhook ClusterLogSink to
+	// the URL routed to by a pod annotation of their kubernetes metadata.
+	// The sink reads its own copy of the records, so other sinks receive
+	// every record.
+	AnnotationRouting *AnnotationRoutingSpec `json:"annotation_routing,omitempty"`
+
+	// StatusCodeRouting sends the records of a webhook ClusterLogSink to
+	// the URL routed to by the range of the HTTP status code in a field of
+	// the record, such as the status of a parsed access log. It reads its
+	// own copy of the records like AnnotationRouting and cannot be combined
+	// with it.
+	StatusCodeRouting *StatusCodeRoutingSpec `json:"status_code_routing,omitempty"`
+
+	// Metrics also scrapes the pods of a LogSink's namespace with a telegraf
+	// deployment, as a MetricSink with the same spec would. The deployment
+	// is owned by the LogSink and is named telegraf-logsink-<name>, so it
+	// does not collide with a MetricSink of the same name. ClusterLogSinks
+	// do not support it.
+	Metrics *MetricSinkSpec `json:"metrics,omitempty"`
+}
+
+type AnnotationRoutingSpec struct {
+	// Annotation is the pod annotation, e.g. logging/destination, whose
+	// value picks the route of a record.
+	Annotation string `json:"annotation"`
+
+	// Routes maps annotation values to the URLs their records are sent
+	// to. Records of pods without the annotation or with a value that has
+	// no route are sent to the sink's URL.
+	Routes map[string]string `json:"routes"`
+}
+
+type StatusCodeRoutingSpec struct {
+	// Field is the top level key of the record, e.g. status, holding the
+	// status code.
+	Field string `json:"field"`
+
+	// Routes maps ranges of status codes, e.g. 500-599, or single codes,
+	// e.g. 404, to the URLs their records are sent to. The ranges do not
+	// overlap. Records without the field or with a code outside every
+	// range are sent to the sink's URL.
+	Routes map[string]string `json:"routes"`
+}
+
+type CoalesceSpec struct {
+	// Window is how long after the first record of a run of repeats
+	// further repeats are collapsed. The next repeat after it starts a new
+	// run, so a container that repeats a line forever is reported once per
+	// window. It is between 1s and 1h.
+	Window metav1.Duration `json:"window"`
+}
+
+type SchemaSpec struct {
+	AllowedKeys []string `json:"allowed_keys,omitempty"`
+	Strict      bool     `json:"strict,omitempty"`
+}
+
+type HeartbeatSpec struct {
+	// Interval is how often a heartbeat record is sent. It is at least 1s.
+	Interval metav1.Duration `json:"interval"`
+}
+
+type PerLabelThrottleSpec struct {
+	// Label is the pod label whose values are throttled separately, or
+	// pod_name or container_name to throttle each pod or container.
+	// Records without it are not throttled.
+	Label string `json:"label"`
+
+	// Rate is the records per second each value may send. Records over it
+	// are dropped until the next second.
+	Rate int `json:"rate"`
+}
+
+type RetrySpec struct {
+	// MaxBufferSize is the most the output's chunks may take up on the
+	// node's disk. Once it is reached the oldest chunks are dropped. It
+	// only applies when the controller enables filesystem buffering.
+	MaxBufferSize *resource.Quantity `json:"max_buffer_size,omitempty"`
+}
+
+type AuditFileSpec struct {
+	// Path is the file on the node. ClusterLogSinks write directly to one
+	// of the directories the validator allows and LogSinks to the
+	// subdirectory named after their namespace.
+	Path string `json:"path"`
+
+	// Rotation rotates the file once it reaches MaxSize bytes. It defaults
+	// to keeping 5 files of 100MiB.
+	Rotation *FileRotation `json:"rotation,omitempty"`
+}
+
+type AuditLogSpec struct {
+	// Path is the audit log file on the nodes. It defaults to
+	// /var/log/kubernetes/audit.log.
+	Path string `json:"path,omitempty"`
+
+	// Parser is the parser in parsers.conf the lines are parsed with,
+	// k8s-audit or json. It defaults to k8s-audit.
+	Parser string `json:"parser,omitempty"`
+
+	// NodeSelector selects the nodes whose audit log is read. It defaults
+	// to the nodes labelled node-role.kubernetes.io/master.
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+}
+
+type MetadataSpec struct {
+	// StripKeyRegex removes the kubernetes labels and annotations of
+	// records with keys matching the regular expression.
+	StripKeyRegex string `json:"strip_key_regex,omitempty"`
+}
+
+type RedactRule struct {
+	// Field is the record key to redact. Nested fields are joined with
+	// dots like RequireFields, e.g. user.email.
+	Field string `json:"field"`
+
+	// Method is drop to remove the field, hash to replace its value with
+	// the hex SHA-256 of the value or mask to replace all but the last four
+	// characters of the value with *.
+	Method string `json:"method"`
+}
+
+type GeoIPSpec struct {
+	// SourceField is the record key holding the IP address, e.g. the
+	// client address of a parsed access log.
+	SourceField string `json:"source_field"`
+
+	// Database is the path of the MaxMind database, e.g.
+	// /fluent-bit/geoip/GeoLite2-Country.mmdb. Databases are mounted from
+	// /var/lib/observability/geoip on the nodes to /fluent-bit/geoip.
+	Database string `json:"database"`
+}
+
+type TimestampSpec struct {
+	// Timezone is an IANA timezone name, e.g. America/New_York.
+	Timezone string `json:"timezone,omitempty"`
+
+	// EpochMillisField is the field set to the time of each record in
+	// milliseconds since the Unix epoch, as a number.
+	EpochMillisField string `json:"epoch_millis_field,omitempty"`
 }
 
 type SyslogSpec struct {
 	Host      string `json:"host"`
 	Port      int    `json:"port"`
 	EnableTLS bool   `json:"enable_tls"`
+
+	// StructuredData is added to every message as RFC5424 structured data,
+	// keyed by SD-ID and then by param name.
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+
+	// Framing is how messages are delimited on the connection, either
+	// octet-counting or non-transparent. It defaults to the output's own
+	// framing when empty.
+	Framing string `json:"framing,omitempty"`
+
+	// ServiceRef sends to a port of a Service instead of Host and Port.
+	// The controller resolves it to the Service's cluster DNS name, or the
+	// name an ExternalName Service points at, and the number of the named
+	// port.
+	ServiceRef *ServiceRef `json:"service_ref,omitempty"`
+
+	// HostnameSource is the hostname sent as the HOSTNAME of each message,
+	// either the node, pod or container the record was written on. The
+	// output's own hostname is sent when empty.
+	HostnameSource string `json:"hostname_source,omitempty"`
+
+	// MsgID is sent as the MSGID of each message. It is either static text
+	// or holds references to record keys, e.g. $app or
+	// $kubernetes['labels']['app'], which are replaced by the record's
+	// values.
+	MsgID string `json:"msg_id,omitempty"`
+
+	// Receivers sends every record to each receiver instead of Host and
+	// Port. Each receiver is a separate output, so a receiver that is down
+	// does not hold back delivery to the others.
+	Receivers []SyslogReceiver `json:"receivers,omitempty"`
+}
+
+type SyslogReceiver struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// ServiceRef references a named port of a Service. Namespace defaults to the
+// namespace of a LogSink and is required for ClusterLogSinks.
+type ServiceRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	PortName  string `json:"port_name"`
 }
 
 type WebhookSpec struct {
 	URL string `json:"url"`
+
+	// KeepAlive reuses connections to the webhook between flushes.
+	// KeepAliveIdleTimeout is how long an idle connection is kept open,
+	// fluent-bit's default is 30s.
+	KeepAlive            bool             `json:"keep_alive,omitempty"`
+	KeepAliveIdleTimeout *metav1.Duration `json:"keep_alive_idle_timeout,omitempty"`
+
+	// MaxConnections caps the connections each fluent-bit pod opens to the
+	// webhook at once. It is unlimited when unset.
+	MaxConnections int `json:"max_connections,omitempty"`
+
+	// HeadersFromSecret adds headers to the webhook's requests with values
+	// read from a Secret. The values are passed to fluent-bit in its
+	// environment, so rotating the Secret restarts fluent-bit without a
+	// change to the sink.
+	HeadersFromSecret *HeadersFromSecret `json:"headers_from_secret,omitempty"`
+}
+
+// HeadersFromSecret reads header values from the keys of a Secret. LogSinks
+// read Secrets in their namespace and ClusterLogSinks read Secrets in the
+// namespace of the sink-controller.
+type HeadersFromSecret struct {
+	SecretName string `json:"secret_name"`
+
+	// Headers maps header names to the keys of the Secret holding their
+	// values.
+	Headers map[string]string `json:"headers"`
+}
+
+// UnixSocketSpec forwards records to a collector listening on a Unix domain
+// socket of the node. The fluent-bit pods mount the node's
+// /var/run/observability directory, and the validator only allows sockets
+// under the directories the daemonset mounts.
+type UnixSocketSpec struct {
+	Path string `json:"path,omitempty"`
 }
 
 // SinkStatus is the status for a Sink resource
@@ -55,6 +452,7 @@ type SinkStatus struct {
 	LastSuccessfulSend metav1.MicroTime  `json:"last_successful_send,omitempty"`
 	LastError          *string           `json:"last_error,omitempty"`
 	LastErrorTime      *metav1.MicroTime `json:"last_error_time,omitempty"`
+	Conditions         []SinkCondition   `json:"conditions,omitempty"`
 }
 
 type SinkState string
@@ -64,6 +462,48 @@ const (
 	SinkStateFailing SinkState = "Failing"
 )
 
+type SinkConditionType string
+
+const (
+	// SinkConditionCircuitOpen is true while the sink's output is replaced
+	// with a null output because its destination keeps failing.
+	SinkConditionCircuitOpen SinkConditionType = "CircuitOpen"
+
+	// SinkConditionDegraded is true while a secret key the sink references
+	// does not exist, so its pods keep running with the last value they
+	// read.
+	SinkConditionDegraded SinkConditionType = "Degraded"
+
+	// SinkConditionForwardingDisabled is true while forwarding is disabled
+	// for every sink, so the sink's output discards its records.
+	SinkConditionForwardingDisabled SinkConditionType = "ForwardingDisabled"
+)
+
+// SinkCondition describes the state of a sink at a certain point
+type SinkCondition struct {
+	Type               SinkConditionType      `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time            `json:"last_transition_time,omitempty"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+}
+
+// SetCondition replaces the condition of the same type, or appends it if
+// the status has none. The transition time is kept if the condition's
+// status did not change.
+func (s *SinkStatus) SetCondition(cond SinkCondition) {
+	for i, c := range s.Conditions {
+		if c.Type == cond.Type {
+			if c.Status == cond.Status {
+				cond.LastTransitionTime = c.LastTransitionTime
+			}
+			s.Conditions[i] = cond
+			return
+		}
+	}
+	s.Conditions = append(s.Conditions, cond)
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // LogSinkList is a list of LogSink resources
@@ -82,7 +522,8 @@ type ClusterLogSink struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
-	Spec SinkSpec `json:"spec"`
+	Spec   SinkSpec   `json:"spec"`
+	Status SinkStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -95,6 +536,42 @@ type ClusterLogSinkList struct {
 	Items []ClusterLogSink `json:"items"`
 }
 
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterFilterSet is a specification for a ClusterFilterSet resource
+type ClusterFilterSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ClusterFilterSetSpec `json:"spec"`
+}
+
+// ClusterFilterSetSpec is a list of fluent-bit filters that sinks apply by
+// referencing the set in FilterSetRefs.
+type ClusterFilterSetSpec struct {
+	Filters []FilterSpec `json:"filters"`
+}
+
+// FilterSpec is a fluent-bit filter. Name is the filter plugin, e.g. grep,
+// and Options are the plugin's options. The filter matches the records of
+// the sinks referencing its set.
+type FilterSpec struct {
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterFilterSetList is a list of ClusterFilterSet resources
+type ClusterFilterSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterFilterSet `json:"items"`
+}
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -121,8 +598,96 @@ type MetricSink struct {
 
 // MetricSinkSpec is the spec for a Sink resource
 type MetricSinkSpec struct {
-	Inputs  []MetricSinkMap `json:"inputs"`
+	Inputs []MetricSinkMap `json:"inputs"`
+
+	// Outputs are telegraf outputs. Outputs that serialize metrics write
+	// them in the format of their data_format, one of carbon2, graphite,
+	// influx, json, prometheus, splunkmetric or wavefront, which defaults
+	// to influx.
 	Outputs []MetricSinkMap `json:"outputs"`
+
+	// ScrapeAuth is how the prometheus input of a MetricSink authenticates
+	// to the pods it scrapes. ClusterMetricSinks do not scrape pods and do
+	// not support it.
+	ScrapeAuth *ScrapeAuth `json:"scrape_auth,omitempty"`
+
+	// ScrapeTLS is how the prometheus input of a MetricSink verifies the
+	// pods it scrapes over https, and the client certificate it presents
+	// to them. ClusterMetricSinks do not support it.
+	ScrapeTLS *ScrapeTLS `json:"scrape_tls,omitempty"`
+
+	// FileRotation rotates the files written by the sink's file outputs.
+	FileRotation *FileRotation `json:"file_rotation,omitempty"`
+
+	// StatsD receives metrics pushed to a MetricSink over statsd. The
+	// controller exposes its port with a service named after the sink's
+	// telegraf deployment. ClusterMetricSinks do not support it.
+	StatsD *StatsD `json:"statsd,omitempty"`
+
+	// LoadBalance spreads the writes of the sink over its outputs, which
+	// are identical endpoints, instead of writing every metric to each of
+	// them.
+	LoadBalance *LoadBalance `json:"load_balance,omitempty"`
+
+	// KubeletMetrics scrapes the container metrics the kubelet of each
+	// node serves from cAdvisor, authorized by the telegraf service
+	// account. Only ClusterMetricSinks, whose telegraf runs on every node,
+	// support it. MetricSinks are rejected since cAdvisor serves the
+	// metrics of every pod on the node, which would leak the metrics of
+	// other namespaces into a namespaced sink.
+	KubeletMetrics bool `json:"kubelet_metrics,omitempty"`
+}
+
+// LoadBalance weighs the outputs of a sink. The outputs are influxdb or
+// influxdb_v2 outputs that differ only in their urls. They are rendered as
+// one output listing the urls of each output as many times as its weight.
+// The output writes each batch to a url picked at random from the list and
+// tries the others when the write fails, so the weights hold while every
+// endpoint is up.
+type LoadBalance struct {
+	// Weights are the shares of the writes of each output, in the order of
+	// the outputs.
+	Weights []int `json:"weights"`
+}
+
+// StatsD listens for statsd metrics on Port. Protocol is udp or tcp and
+// defaults to udp. Templates, MetricSeparator and ParseDataDogTags are
+// telegraf's rules for parsing metric names into measurements and tags.
+type StatsD struct {
+	Port             int      `json:"port"`
+	Protocol         string   `json:"protocol,omitempty"`
+	Templates        []string `json:"templates,omitempty"`
+	MetricSeparator  string   `json:"metric_separator,omitempty"`
+	ParseDataDogTags bool     `json:"parse_data_dog_tags,omitempty"`
+}
+
+// FileRotation rotates a file once it reaches MaxSize bytes and keeps
+// RotationCount rotated files.
+type FileRotation struct {
+	RotationCount int   `json:"rotation_count"`
+	MaxSize       int64 `json:"max_size"`
+}
+
+// ScrapeAuth sets either a bearer token or basic auth. Secrets are read from
+// the MetricSink's namespace.
+type ScrapeAuth struct {
+	BearerTokenSecretRef *corev1.SecretKeySelector `json:"bearer_token_secret_ref,omitempty"`
+	BasicAuth            *BasicAuth                `json:"basic_auth,omitempty"`
+}
+
+// ScrapeTLS sets the CA the scraped pods are verified with and the client
+// certificate and key, which are set together. The secrets are read from
+// the MetricSink's namespace and mounted into the telegraf pods.
+type ScrapeTLS struct {
+	CASecretRef        *corev1.SecretKeySelector `json:"ca_secret_ref,omitempty"`
+	CertSecretRef      *corev1.SecretKeySelector `json:"cert_secret_ref,omitempty"`
+	KeySecretRef       *corev1.SecretKeySelector `json:"key_secret_ref,omitempty"`
+	InsecureSkipVerify bool                      `json:"insecure_skip_verify,omitempty"`
+}
+
+type BasicAuth struct {
+	Username          string                    `json:"username"`
+	PasswordSecretRef *corev1.SecretKeySelector `json:"password_secret_ref"`
 }
 
 // MetricSinkMap contains key/values that define inputs and outputs for a
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package builder helps tooling provision sinks.
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	sinkclient "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// BatchError is returned when sinks of a batch are invalid. Errors maps the
+// index of each invalid sink in the batch to its errors.
+type BatchError struct {
+	Errors map[int]field.ErrorList
+}
+
+func (e *BatchError) Error() string {
+	indexes := make([]int, 0, len(e.Errors))
+	for i := range e.Errors {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	msgs := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		msgs = append(msgs, fmt.Sprintf("sink %d: %s", i, e.Errors[i].ToAggregate()))
+	}
+	return "invalid sinks: " + strings.Join(msgs, "; ")
+}
+
+// CreateLogSinks creates every LogSink or none of them. The sinks are
+// validated as the webhook validates them, and if any is invalid nothing is
+// created and a *BatchError is returned. When a create fails the sinks
+// already created are deleted.
+func CreateLogSinks(c sinkclient.LogSinksGetter, sinks []*v1alpha1.LogSink) ([]*v1alpha1.LogSink, error) {
+	items := make([]batchItem, len(sinks))
+	created := make([]*v1alpha1.LogSink, len(sinks))
+	for i, s := range sinks {
+		i, s := i, s
+		items[i] = batchItem{
+			key:  s.Namespace + "/" + s.Name,
+			errs: webhook.ValidateLogSink(s),
+			create: func() (err error) {
+				created[i], err = c.LogSinks(s.Namespace).Create(s)
+				return err
+			},
+			delete: func() error {
+				return c.LogSinks(s.Namespace).Delete(s.Name, &metav1.DeleteOptions{})
+			},
+		}
+	}
+
+	if err := createBatch(items); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// CreateClusterLogSinks creates every ClusterLogSink or none of them, like
+// CreateLogSinks.
+func CreateClusterLogSinks(c sinkclient.ClusterLogSinksGetter, sinks []*v1alpha1.ClusterLogSink) ([]*v1alpha1.ClusterLogSink, error) {
+	items := make([]batchItem, len(sinks))
+	created := make([]*v1alpha1.ClusterLogSink, len(sinks))
+	for i, s := range sinks {
+		i, s := i, s
+		items[i] = batchItem{
+			key:  s.Name,
+			errs: webhook.ValidateClusterLogSink(s),
+			create: func() (err error) {
+				created[i], err = c.ClusterLogSinks(s.Namespace).Create(s)
+				return err
+			},
+			delete: func() error {
+				return c.ClusterLogSinks(s.Namespace).Delete(s.Name, &metav1.DeleteOptions{})
+			},
+		}
+	}
+
+	if err := createBatch(items); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+type batchItem struct {
+	key    string
+	errs   field.ErrorList
+	create func() error
+	delete func() error
+}
+
+func createBatch(items []batchItem) error {
+	invalid := make(map[int]field.ErrorList)
+	seen := make(map[string]bool)
+	for i, item := range items {
+		errs := item.errs
+		if seen[item.key] {
+			errs = append(errs, field.Duplicate(field.NewPath("metadata", "name"), item.key))
+		}
+		seen[item.key] = true
+		if len(errs) > 0 {
+			invalid[i] = errs
+		}
+	}
+	if len(invalid) > 0 {
+		return &BatchError{Errors: invalid}
+	}
+
+	for i, item := range items {
+		err := item.create()
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("unable to create %s: %s", item.key, err)
+		for j := i - 1; j >= 0; j-- {
+			if derr := items[j].delete(); derr != nil {
+				err = fmt.Errorf("%s, unable to delete %s: %s", err, items[j].key, derr)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package builder_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ktesting "k8s.io/client-go/testing"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/builder"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestCreateLogSinks(t *testing.T) {
+	t.Run("it creates every sink when all are valid", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		sinks := []*v1alpha1.LogSink{
+			logSink("ns1", "sink-a", "https://example.com/a"),
+			logSink("ns2", "sink-b", "https://example.com/b"),
+		}
+
+		created, err := builder.CreateLogSinks(client.ObservabilityV1alpha1(), sinks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(created) != len(sinks) {
+			t.Fatalf("Expected %d created sinks, got %d", len(sinks), len(created))
+		}
+		for i, s := range created {
+			if s.Name != sinks[i].Name {
+				t.Errorf("Expected created sink %d to be %s, got %s", i, sinks[i].Name, s.Name)
+			}
+		}
+
+		for _, s := range sinks {
+			_, err := client.ObservabilityV1alpha1().LogSinks(s.Namespace).Get(s.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Errorf("Expected %s/%s to be created: %s", s.Namespace, s.Name, err)
+			}
+		}
+	})
+
+	t.Run("it creates nothing and reports each invalid sink", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		sinks := []*v1alpha1.LogSink{
+			logSink("ns1", "sink-a", "https://example.com/a"),
+			logSink("ns1", "sink-b", "http://example.com/b"),
+			logSink("ns1", "sink-a", "https://example.com/c"),
+		}
+
+		_, err := builder.CreateLogSinks(client.ObservabilityV1alpha1(), sinks)
+
+		batchErr, ok := err.(*builder.BatchError)
+		if !ok {
+			t.Fatalf("Expected a BatchError, got %v", err)
+		}
+		expected := map[int]field.ErrorList{
+			1: {field.Invalid(field.NewPath("spec", "url"), "http://example.com/b", webhook.ConfigWebhookInsecureError)},
+			2: {field.Duplicate(field.NewPath("metadata", "name"), "ns1/sink-a")},
+		}
+		if diff := cmp.Diff(expected, batchErr.Errors); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		list, err := client.ObservabilityV1alpha1().LogSinks("ns1").List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 0 {
+			t.Errorf("Expected no sinks to be created, got %d", len(list.Items))
+		}
+	})
+
+	t.Run("it deletes the created sinks when a create fails", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		client.PrependReactor("create", "logsinks", func(action ktesting.Action) (bool, runtime.Object, error) {
+			s := action.(ktesting.CreateAction).GetObject().(*v1alpha1.LogSink)
+			if s.Name == "sink-c" {
+				return true, nil, errors.New("unavailable")
+			}
+			return false, nil, nil
+		})
+		sinks := []*v1alpha1.LogSink{
+			logSink("ns1", "sink-a", "https://example.com/a"),
+			logSink("ns1", "sink-b", "https://example.com/b"),
+			logSink("ns1", "sink-c", "https://example.com/c"),
+		}
+
+		_, err := builder.CreateLogSinks(client.ObservabilityV1alpha1(), sinks)
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+
+		list, err := client.ObservabilityV1alpha1().LogSinks("ns1").List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 0 {
+			t.Errorf("Expected the created sinks to be deleted, got %d", len(list.Items))
+		}
+	})
+}
+
+func TestCreateClusterLogSinks(t *testing.T) {
+	t.Run("it creates every sink when all are valid", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		sinks := []*v1alpha1.ClusterLogSink{
+			clusterLogSink("sink-a", "https://example.com/a"),
+			clusterLogSink("sink-b", "https://example.com/b"),
+		}
+
+		_, err := builder.CreateClusterLogSinks(client.ObservabilityV1alpha1(), sinks)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		list, err := client.ObservabilityV1alpha1().ClusterLogSinks("").List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 2 {
+			t.Errorf("Expected 2 sinks to be created, got %d", len(list.Items))
+		}
+	})
+
+	t.Run("it creates nothing when a sink is invalid", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+		sinks := []*v1alpha1.ClusterLogSink{
+			clusterLogSink("sink-a", "https://example.com/a"),
+			clusterLogSink("sink-b", ""),
+		}
+
+		_, err := builder.CreateClusterLogSinks(client.ObservabilityV1alpha1(), sinks)
+
+		batchErr, ok := err.(*builder.BatchError)
+		if !ok {
+			t.Fatalf("Expected a BatchError, got %v", err)
+		}
+		if _, ok := batchErr.Errors[1]; !ok || len(batchErr.Errors) != 1 {
+			t.Errorf("Expected only sink 1 to be invalid, got %v", batchErr.Errors)
+		}
+
+		list, err := client.ObservabilityV1alpha1().ClusterLogSinks("").List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != 0 {
+			t.Errorf("Expected no sinks to be created, got %d", len(list.Items))
+		}
+	})
+}
+
+func logSink(namespace, name, url string) *v1alpha1.LogSink {
+	return &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: url,
+			},
+		},
+	}
+}
+
+func clusterLogSink(name, url string) *v1alpha1.ClusterLogSink {
+	return &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: url,
+			},
+		},
+	}
+}
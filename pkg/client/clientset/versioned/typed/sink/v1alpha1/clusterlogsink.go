@@ -39,6 +39,7 @@ type ClusterLogSinksGetter interface {
 type ClusterLogSinkInterface interface {
 	Create(*v1alpha1.ClusterLogSink) (*v1alpha1.ClusterLogSink, error)
 	Update(*v1alpha1.ClusterLogSink) (*v1alpha1.ClusterLogSink, error)
+	UpdateStatus(*v1alpha1.ClusterLogSink) (*v1alpha1.ClusterLogSink, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.ClusterLogSink, error)
@@ -132,6 +133,22 @@ func (c *clusterLogSinks) Update(clusterLogSink *v1alpha1.ClusterLogSink) (resul
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *clusterLogSinks) UpdateStatus(clusterLogSink *v1alpha1.ClusterLogSink) (result *v1alpha1.ClusterLogSink, err error) {
+	result = &v1alpha1.ClusterLogSink{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("clusterlogsinks").
+		Name(clusterLogSink.Name).
+		SubResource("status").
+		Body(clusterLogSink).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the clusterLogSink and deletes it. Returns an error if one occurs.
 func (c *clusterLogSinks) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -100,6 +100,18 @@ func (c *FakeLogSinks) Update(logSink *v1alpha1.LogSink) (result *v1alpha1.LogSi
 	return obj.(*v1alpha1.LogSink), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeLogSinks) UpdateStatus(logSink *v1alpha1.LogSink) (*v1alpha1.LogSink, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(logsinksResource, "status", c.ns, logSink), &v1alpha1.LogSink{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.LogSink), err
+}
+
 // Delete takes name of the logSink and deletes it. Returns an error if one occurs.
 func (c *FakeLogSinks) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
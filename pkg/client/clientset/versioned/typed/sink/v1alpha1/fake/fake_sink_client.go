@@ -28,6 +28,10 @@ type FakeObservabilityV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeObservabilityV1alpha1) ClusterFilterSets(namespace string) v1alpha1.ClusterFilterSetInterface {
+	return &FakeClusterFilterSets{c, namespace}
+}
+
 func (c *FakeObservabilityV1alpha1) ClusterLogSinks(namespace string) v1alpha1.ClusterLogSinkInterface {
 	return &FakeClusterLogSinks{c, namespace}
 }
@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1alpha1
 
+type ClusterFilterSetExpansion interface{}
+
 type ClusterLogSinkExpansion interface{}
 
 type ClusterMetricSinkExpansion interface{}
@@ -27,6 +27,7 @@ import (
 
 type ObservabilityV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterFilterSetsGetter
 	ClusterLogSinksGetter
 	ClusterMetricSinksGetter
 	LogSinksGetter
@@ -38,6 +39,10 @@ type ObservabilityV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *ObservabilityV1alpha1Client) ClusterFilterSets(namespace string) ClusterFilterSetInterface {
+	return newClusterFilterSets(c, namespace)
+}
+
 func (c *ObservabilityV1alpha1Client) ClusterLogSinks(namespace string) ClusterLogSinkInterface {
 	return newClusterLogSinks(c, namespace)
 }
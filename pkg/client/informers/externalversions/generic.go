@@ -53,6 +53,8 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=observability.knative.dev, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clusterfiltersets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Observability().V1alpha1().ClusterFilterSets().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("clusterlogsinks"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Observability().V1alpha1().ClusterLogSinks().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("clustermetricsinks"):
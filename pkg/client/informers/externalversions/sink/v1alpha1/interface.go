@@ -24,6 +24,8 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterFilterSets returns a ClusterFilterSetInformer.
+	ClusterFilterSets() ClusterFilterSetInformer
 	// ClusterLogSinks returns a ClusterLogSinkInformer.
 	ClusterLogSinks() ClusterLogSinkInformer
 	// ClusterMetricSinks returns a ClusterMetricSinkInformer.
@@ -45,6 +47,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterFilterSets returns a ClusterFilterSetInformer.
+func (v *version) ClusterFilterSets() ClusterFilterSetInformer {
+	return &clusterFilterSetInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // ClusterLogSinks returns a ClusterLogSinkInformer.
 func (v *version) ClusterLogSinks() ClusterLogSinkInformer {
 	return &clusterLogSinkInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
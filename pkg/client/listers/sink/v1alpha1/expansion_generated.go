@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1alpha1
 
+// ClusterFilterSetListerExpansion allows custom methods to be added to
+// ClusterFilterSetLister.
+type ClusterFilterSetListerExpansion interface{}
+
+// ClusterFilterSetNamespaceListerExpansion allows custom methods to be added to
+// ClusterFilterSetNamespaceLister.
+type ClusterFilterSetNamespaceListerExpansion interface{}
+
 // ClusterLogSinkListerExpansion allows custom methods to be added to
 // ClusterLogSinkLister.
 type ClusterLogSinkListerExpansion interface{}
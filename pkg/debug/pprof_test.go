@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package debug_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/knative/observability/pkg/debug"
+)
+
+func TestPprofServer(t *testing.T) {
+	t.Run("it serves pprof when enabled", func(t *testing.T) {
+		srv := debug.NewPprofServer("", "6061")
+		if srv == nil {
+			t.Fatal("expected a server")
+		}
+
+		for _, path := range []string{
+			"/debug/pprof/",
+			"/debug/pprof/cmdline",
+			"/debug/pprof/goroutine",
+		} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			srv.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected http status 200 for %s, got %d", path, rec.Code)
+			}
+		}
+	})
+
+	t.Run("it does not serve anything else", func(t *testing.T) {
+		srv := debug.NewPprofServer("", "6061")
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/topology", nil)
+		srv.Handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected http status 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("it binds to localhost by default", func(t *testing.T) {
+		srv := debug.NewPprofServer("", "6061")
+		if srv.Addr != "localhost:6061" {
+			t.Errorf("expected addr localhost:6061, got %s", srv.Addr)
+		}
+
+		srv = debug.NewPprofServer("0.0.0.0", "6061")
+		if srv.Addr != "0.0.0.0:6061" {
+			t.Errorf("expected addr 0.0.0.0:6061, got %s", srv.Addr)
+		}
+	})
+
+	t.Run("it is disabled without a port", func(t *testing.T) {
+		if srv := debug.NewPprofServer("", ""); srv != nil {
+			t.Errorf("expected no server, got one listening on %s", srv.Addr)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package event
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Batcher is a Forwarder that coalesces the records posted with a tag
+// within an interval into a single batched record, so that sinks of
+// namespaces with many events receive fewer records. It counts the events
+// it posts as sent itself, since a posted event is only held.
+type Batcher struct {
+	f          Forwarder
+	interval   time.Duration
+	maxPending int
+
+	mu      sync.Mutex
+	tags    []string
+	pending map[string][]map[string]interface{}
+	held    int
+}
+
+// NewBatcher returns a Batcher posting to f. The held records are flushed
+// early once maxPending records are held. It returns an error unless the
+// interval and maxPending are positive.
+func NewBatcher(f Forwarder, interval time.Duration, maxPending int) (*Batcher, error) {
+	if interval <= 0 {
+		return nil, errors.New("batch interval must be positive")
+	}
+	if maxPending <= 0 {
+		return nil, errors.New("batch max pending events must be positive")
+	}
+	return &Batcher{
+		f:          f,
+		interval:   interval,
+		maxPending: maxPending,
+		pending:    make(map[string][]map[string]interface{}),
+	}, nil
+}
+
+// Post holds the record until the next flush. Records other than maps
+// cannot be batched and are posted as they are.
+func (b *Batcher) Post(tag string, message interface{}) error {
+	m, ok := message.(map[string]interface{})
+	if !ok {
+		if err := b.f.Post(tag, message); err != nil {
+			return err
+		}
+		ForwarderSent.Add(1)
+		return nil
+	}
+
+	b.mu.Lock()
+	if _, ok := b.pending[tag]; !ok {
+		b.tags = append(b.tags, tag)
+	}
+	b.pending[tag] = append(b.pending[tag], m)
+	b.held++
+	full := b.held >= b.maxPending
+	b.mu.Unlock()
+
+	if full {
+		b.Flush()
+	}
+	return nil
+}
+
+// Run flushes the held records every interval until stopCh is closed,
+// when the remaining records are flushed.
+func (b *Batcher) Run(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.Flush()
+		case <-stopCh:
+			b.Flush()
+			return
+		}
+	}
+}
+
+// Flush posts a record for each tag with held records, in the order the
+// tags were first posted, and counts the events of the posted records as
+// sent.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	tags, pending := b.tags, b.pending
+	b.tags = nil
+	b.pending = make(map[string][]map[string]interface{})
+	b.held = 0
+	b.mu.Unlock()
+
+	for _, tag := range tags {
+		records := pending[tag]
+		if err := b.f.Post(tag, batchRecord(records)); err != nil {
+			log.Printf("unable to forward %d batched events: %s\n", len(records), err)
+			ForwarderFailed.Add(int64(len(records)))
+			continue
+		}
+		ForwarderSent.Add(int64(len(records)))
+		ForwarderBatches.Add(1)
+	}
+}
+
+// batchRecord returns the record of a batch. A batch of one record is the
+// record itself. Otherwise the log is the logs of the records, one per
+// line, the records are kept in events and the kubernetes metadata is the
+// metadata every record shares.
+func batchRecord(records []map[string]interface{}) map[string]interface{} {
+	if len(records) == 1 {
+		return records[0]
+	}
+
+	logs := make([][]byte, 0, len(records))
+	events := make([]interface{}, 0, len(records))
+	for _, r := range records {
+		if l, ok := r["log"].([]byte); ok {
+			logs = append(logs, l)
+		}
+		events = append(events, r)
+	}
+
+	return map[string]interface{}{
+		"log":        bytes.Join(logs, []byte("\n")),
+		"stream":     []byte("stdout"),
+		"kubernetes": sharedMetadata(records),
+		"events":     events,
+		"count":      len(records),
+	}
+}
+
+func sharedMetadata(records []map[string]interface{}) map[string]interface{} {
+	first, _ := records[0]["kubernetes"].(map[string]interface{})
+	shared := make(map[string]interface{}, len(first))
+	for k, v := range first {
+		value, ok := v.([]byte)
+		if !ok {
+			continue
+		}
+		same := true
+		for _, r := range records[1:] {
+			md, _ := r["kubernetes"].(map[string]interface{})
+			other, ok := md[k].([]byte)
+			if !ok || !bytes.Equal(value, other) {
+				same = false
+				break
+			}
+		}
+		if same {
+			shared[k] = value
+		}
+	}
+	return shared
+}
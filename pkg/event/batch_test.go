@@ -0,0 +1,251 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package event_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/api/core/v1"
+
+	"github.com/knative/observability/pkg/event"
+)
+
+func TestBatcher(t *testing.T) {
+	newEvent := func(namespace, pod, message string) *v1.Event {
+		return &v1.Event{
+			InvolvedObject: v1.ObjectReference{
+				Name:      pod,
+				Namespace: namespace,
+			},
+			Message: message,
+			Source: v1.EventSource{
+				Host: "some-host",
+			},
+		}
+	}
+
+	t.Run("it batches the events of a namespace into one record", func(t *testing.T) {
+		spy := &spyBatchForwarder{}
+		b, err := event.NewBatcher(spy, time.Hour, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := event.NewController(b)
+
+		c.OnAdd(newEvent("ns1", "pod-a", "first"))
+		c.OnAdd(newEvent("ns2", "pod-c", "other"))
+		c.OnAdd(newEvent("ns1", "pod-b", "second"))
+		if len(spy.posts()) != 0 {
+			t.Fatalf("Expected no records before the flush, got %v", spy.posts())
+		}
+		b.Flush()
+
+		posts := spy.posts()
+		if len(posts) != 2 {
+			t.Fatalf("Expected 2 records, got %d", len(posts))
+		}
+		if posts[0].tag != "k8s.event._ns1_" || posts[1].tag != "k8s.event._ns2_" {
+			t.Errorf("Expected the tags in the order they were posted, got %s and %s", posts[0].tag, posts[1].tag)
+		}
+
+		batch := posts[0].msg.(map[string]interface{})
+		expectedMetadata := map[string]interface{}{
+			"host":           []byte("some-host"),
+			"namespace_name": []byte("ns1"),
+			"source_type":    []byte("k8s.event"),
+		}
+		if diff := cmp.Diff(expectedMetadata, batch["kubernetes"]); diff != "" {
+			t.Errorf("Expected the shared metadata (-want +got): %v", diff)
+		}
+		if diff := cmp.Diff([]byte("first\nsecond"), batch["log"]); diff != "" {
+			t.Errorf("Expected the logs of the events (-want +got): %v", diff)
+		}
+		if batch["count"] != 2 {
+			t.Errorf("Expected a count of 2, got %v", batch["count"])
+		}
+		events := batch["events"].([]interface{})
+		if len(events) != 2 {
+			t.Fatalf("Expected the batch to hold 2 events, got %d", len(events))
+		}
+		pod := events[1].(map[string]interface{})["kubernetes"].(map[string]interface{})["pod_name"]
+		if diff := cmp.Diff([]byte("pod-b"), pod); diff != "" {
+			t.Errorf("Expected the events to keep their metadata (-want +got): %v", diff)
+		}
+	})
+
+	t.Run("it posts a single event as it is", func(t *testing.T) {
+		spy := &spyBatchForwarder{}
+		b, err := event.NewBatcher(spy, time.Hour, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		record := map[string]interface{}{"log": []byte("alone")}
+		if err := b.Post("k8s.event._ns1_", record); err != nil {
+			t.Fatal(err)
+		}
+		b.Flush()
+		b.Flush()
+
+		posts := spy.posts()
+		if len(posts) != 1 {
+			t.Fatalf("Expected 1 record, got %d", len(posts))
+		}
+		if diff := cmp.Diff(record, posts[0].msg); diff != "" {
+			t.Errorf("Unexpected record (-want +got): %v", diff)
+		}
+	})
+
+	t.Run("it flushes every interval and when stopped", func(t *testing.T) {
+		spy := &spyBatchForwarder{}
+		b, err := event.NewBatcher(spy, 10*time.Millisecond, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			b.Run(stop)
+			close(done)
+		}()
+
+		for i := 0; i < 3; i++ {
+			b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("tick")})
+		}
+		deadline := time.Now().Add(time.Second)
+		for len(spy.posts()) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := len(spy.posts()); n != 1 {
+			t.Fatalf("Expected the events to be flushed as 1 record, got %d", n)
+		}
+
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("last")})
+		close(stop)
+		<-done
+		if n := len(spy.posts()); n != 2 {
+			t.Errorf("Expected the held event to be flushed when stopped, got %d records", n)
+		}
+	})
+
+	t.Run("it counts the events of a batch that fails to post", func(t *testing.T) {
+		ResetForwarderMetrics()
+		spy := &spyBatchForwarder{err: errors.New("unavailable")}
+		b, err := event.NewBatcher(spy, time.Hour, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("a")})
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("b")})
+		b.Flush()
+
+		if event.ForwarderFailed.Value() != 2 {
+			t.Errorf("Expected 2 failed events, got %d", event.ForwarderFailed.Value())
+		}
+	})
+
+	t.Run("it counts the events as sent once they are flushed", func(t *testing.T) {
+		ResetForwarderMetrics()
+		spy := &spyBatchForwarder{}
+		b, err := event.NewBatcher(spy, time.Hour, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := event.NewController(b)
+
+		c.OnAdd(newEvent("ns1", "pod-a", "first"))
+		c.OnAdd(newEvent("ns1", "pod-b", "second"))
+		if event.ForwarderSent.Value() != 0 {
+			t.Errorf("Expected no events to be sent before the flush, got %d", event.ForwarderSent.Value())
+		}
+
+		b.Flush()
+		if event.ForwarderSent.Value() != 2 {
+			t.Errorf("Expected 2 sent events, got %d", event.ForwarderSent.Value())
+		}
+	})
+
+	t.Run("it flushes once the max pending events are held", func(t *testing.T) {
+		spy := &spyBatchForwarder{}
+		b, err := event.NewBatcher(spy, time.Hour, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("a")})
+		b.Post("k8s.event._ns2_", map[string]interface{}{"log": []byte("b")})
+		if n := len(spy.posts()); n != 0 {
+			t.Fatalf("Expected the events to be held, got %d records", n)
+		}
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("c")})
+		if n := len(spy.posts()); n != 2 {
+			t.Fatalf("Expected the held events to be flushed, got %d records", n)
+		}
+
+		b.Post("k8s.event._ns1_", map[string]interface{}{"log": []byte("d")})
+		if n := len(spy.posts()); n != 2 {
+			t.Errorf("Expected the count of held events to restart after the flush, got %d records", n)
+		}
+	})
+
+	t.Run("it rejects max pending events that are not positive", func(t *testing.T) {
+		for _, max := range []int{0, -1} {
+			if _, err := event.NewBatcher(&spyBatchForwarder{}, time.Second, max); err == nil {
+				t.Errorf("Expected an error for %d", max)
+			}
+		}
+	})
+
+	t.Run("it rejects an interval that is not positive", func(t *testing.T) {
+		for _, interval := range []time.Duration{0, -time.Second} {
+			if _, err := event.NewBatcher(&spyBatchForwarder{}, interval, 100); err == nil {
+				t.Errorf("Expected an error for %s", interval)
+			}
+		}
+	})
+}
+
+type post struct {
+	tag string
+	msg interface{}
+}
+
+type spyBatchForwarder struct {
+	mu     sync.Mutex
+	err    error
+	posted []post
+}
+
+func (s *spyBatchForwarder) Post(tag string, msg interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.posted = append(s.posted, post{tag: tag, msg: msg})
+	return nil
+}
+
+func (s *spyBatchForwarder) posts() []post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]post(nil), s.posted...)
+}
@@ -30,6 +30,7 @@ var (
 	ForwarderSent          *expvar.Int
 	ForwarderFailed        *expvar.Int
 	ForwarderConvertFailed *expvar.Int
+	ForwarderBatches       *expvar.Int
 )
 
 func init() {
@@ -39,6 +40,7 @@ func init() {
 	ForwarderSent = expvar.NewInt("eventcontroller_forwarder_sent_count")
 	ForwarderFailed = expvar.NewInt("eventcontroller_forwarder_failed_count")
 	ForwarderConvertFailed = expvar.NewInt("eventcontroller_convert_failed_count")
+	ForwarderBatches = expvar.NewInt("eventcontroller_forwarder_batches_count")
 }
 
 type Forwarder interface {
@@ -89,6 +91,10 @@ func (c *Controller) sendToFluent(tag string, m map[string]interface{}) {
 		ForwarderFailed.Add(1)
 		return
 	}
+	// The Batcher only holds the event and counts it once it is sent.
+	if _, ok := c.f.(*Batcher); ok {
+		return
+	}
 	ForwarderSent.Add(1)
 }
 
@@ -0,0 +1,71 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// The secrets referenced by a ScrapeAuth are passed to telegraf as env vars,
+// which telegraf substitutes into its config, so they are never written to
+// the configmap.
+const (
+	scrapeBearerTokenEnv = "SCRAPE_BEARER_TOKEN"
+	scrapePasswordEnv    = "SCRAPE_PASSWORD"
+)
+
+// addScrapeAuth sets the auth options of the prometheus input.
+func addScrapeAuth(input map[string]interface{}, auth *v1alpha1.ScrapeAuth) {
+	if auth == nil {
+		return
+	}
+
+	if auth.BearerTokenSecretRef != nil {
+		input["bearer_token_string"] = "${" + scrapeBearerTokenEnv + "}"
+	}
+	if auth.BasicAuth != nil {
+		input["username"] = auth.BasicAuth.Username
+		input["password"] = "${" + scrapePasswordEnv + "}"
+	}
+}
+
+// scrapeAuthEnv returns the env vars of the telegraf container that hold the
+// secrets referenced by the ScrapeAuth.
+func scrapeAuthEnv(auth *v1alpha1.ScrapeAuth) []v1.EnvVar {
+	if auth == nil {
+		return nil
+	}
+
+	var env []v1.EnvVar
+	if auth.BearerTokenSecretRef != nil {
+		env = append(env, secretEnv(scrapeBearerTokenEnv, auth.BearerTokenSecretRef))
+	}
+	if auth.BasicAuth != nil && auth.BasicAuth.PasswordSecretRef != nil {
+		env = append(env, secretEnv(scrapePasswordEnv, auth.BasicAuth.PasswordSecretRef))
+	}
+
+	return env
+}
+
+func secretEnv(name string, ref *v1.SecretKeySelector) v1.EnvVar {
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: ref.DeepCopy(),
+		},
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestScrapeAuth(t *testing.T) {
+	tokenRef := &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "scrape-secret"},
+		Key:                  "token",
+	}
+	passwordRef := &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "scrape-secret"},
+		Key:                  "password",
+	}
+
+	tests := []struct {
+		name           string
+		auth           *sinkv1alpha1.ScrapeAuth
+		expectedConfig string
+		expectedEnv    []v1.EnvVar
+	}{
+		{
+			name: "bearer token",
+			auth: &sinkv1alpha1.ScrapeAuth{
+				BearerTokenSecretRef: tokenRef,
+			},
+			expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    bearer_token_string = "${SCRAPE_BEARER_TOKEN}"
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			expectedEnv: []v1.EnvVar{{
+				Name:      "SCRAPE_BEARER_TOKEN",
+				ValueFrom: &v1.EnvVarSource{SecretKeyRef: tokenRef},
+			}},
+		},
+		{
+			name: "basic auth",
+			auth: &sinkv1alpha1.ScrapeAuth{
+				BasicAuth: &sinkv1alpha1.BasicAuth{
+					Username:          "some-user",
+					PasswordSecretRef: passwordRef,
+				},
+			},
+			expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+    password = "${SCRAPE_PASSWORD}"
+    username = "some-user"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			expectedEnv: []v1.EnvVar{{
+				Name:      "SCRAPE_PASSWORD",
+				ValueFrom: &v1.EnvVarSource{SecretKeyRef: passwordRef},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				receivedCM         v1.ConfigMap
+				receivedDeployment appsv1.Deployment
+			)
+			spyCoreClient := &spyCoreV1Client{
+				spyConfigMapCUDer: spyConfigMapCUDer{
+					createFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+						receivedCM = *cm
+						return cm, nil
+					},
+				},
+			}
+			spyExtensionsClient := &spyAppsV1Client{
+				spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+					createFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+						receivedDeployment = *d
+						return d, nil
+					},
+				},
+			}
+			spyRBACClient := &spyRBACV1Client{
+				spyRoleCUDer: spyRoleCUDer{
+					createFunc: func(r *rbacv1.Role) (*rbacv1.Role, error) {
+						return r, nil
+					},
+				},
+				spyRoleBindingCUDer: spyRoleBindingCUDer{
+					createFunc: func(rb *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+						return rb, nil
+					},
+				},
+			}
+
+			c := metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient)
+			c.OnAdd(&sinkv1alpha1.MetricSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-metric-sink",
+					Namespace: "test-namespace",
+				},
+				Spec: sinkv1alpha1.MetricSinkSpec{
+					Outputs: []sinkv1alpha1.MetricSinkMap{{
+						"type":   "datadog",
+						"apikey": "some-key",
+					}},
+					ScrapeAuth: test.auth,
+				},
+			})
+
+			if diff := cmp.Diff(test.expectedConfig, receivedCM.Data["metric-sinks.conf"]); diff != "" {
+				t.Errorf("Config not equal (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(test.expectedEnv, receivedDeployment.Spec.Template.Spec.Containers[0].Env); diff != "" {
+				t.Errorf("Env not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+
+	t.Run("it updates the deployment when the auth changes", func(t *testing.T) {
+		var deploymentUpdated bool
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				updateFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					deploymentUpdated = true
+					return d, nil
+				},
+			},
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, nil)
+		oms := &sinkv1alpha1.MetricSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-metric-sink",
+				Namespace: "test-namespace",
+			},
+		}
+		nms := oms.DeepCopy()
+		nms.Spec.ScrapeAuth = &sinkv1alpha1.ScrapeAuth{
+			BearerTokenSecretRef: tokenRef,
+		}
+		c.OnUpdate(oms, nms)
+
+		if !deploymentUpdated {
+			t.Error("Expected the deployment to be updated")
+		}
+	})
+
+	t.Run("it drops the secret refs when the auth is removed", func(t *testing.T) {
+		var receivedDeployment *appsv1.Deployment
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				updateFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					receivedDeployment = d
+					return d, nil
+				},
+			},
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, nil)
+		oms := &sinkv1alpha1.MetricSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-metric-sink",
+				Namespace: "test-namespace",
+			},
+			Spec: sinkv1alpha1.MetricSinkSpec{
+				ScrapeAuth: &sinkv1alpha1.ScrapeAuth{
+					BearerTokenSecretRef: tokenRef,
+				},
+			},
+		}
+		nms := oms.DeepCopy()
+		nms.Spec.ScrapeAuth = nil
+		c.OnUpdate(oms, nms)
+
+		if receivedDeployment == nil {
+			t.Fatal("Expected the deployment to be updated")
+		}
+		pod := receivedDeployment.Spec.Template.Spec
+		for _, c := range pod.Containers {
+			if len(c.Env) != 0 {
+				t.Errorf("Expected no secret env vars, got %v", c.Env)
+			}
+		}
+		for _, v := range pod.Volumes {
+			if v.Secret != nil || v.Projected != nil {
+				t.Errorf("Expected no secret volumes, got %v", v)
+			}
+		}
+	})
+}
@@ -58,6 +58,7 @@ func (t telegrafConfig) String() string {
 type ClusterConfig struct {
 	mu            sync.RWMutex
 	defaultInputs map[string][]map[string]interface{}
+	kubelet       map[string]interface{}
 	clusterName   string
 	clusterSinks  map[string]v1alpha1.ClusterMetricSink
 }
@@ -66,20 +67,9 @@ type ModifierFunc func(*ClusterConfig)
 
 func KubernetesDefault(insecurePort bool) ModifierFunc {
 	return func(c *ClusterConfig) {
-		if insecurePort {
-			c.defaultInputs["kubernetes"] = []map[string]interface{}{
-				{
-					"url": "http://127.0.0.1:10255",
-				},
-			}
-		} else {
-			c.defaultInputs["kubernetes"] = []map[string]interface{}{
-				{
-					"bearer_token":         "/var/run/secrets/kubernetes.io/serviceaccount/token",
-					"insecure_skip_verify": true,
-					"url":                  "https://127.0.0.1:10250",
-				},
-			}
+		c.kubelet = kubeletInput(insecurePort)
+		c.defaultInputs["kubernetes"] = []map[string]interface{}{
+			c.kubelet,
 		}
 	}
 }
@@ -108,7 +98,7 @@ func copyInputs(input map[string][]map[string]interface{}) map[string][]map[stri
 	return cloned
 }
 
-func appendInputsAndOutputs(config *telegrafConfig, inputs, outputs []v1alpha1.MetricSinkMap) {
+func appendInputsAndOutputs(config *telegrafConfig, inputs, outputs []v1alpha1.MetricSinkMap, rotation *v1alpha1.FileRotation) {
 	for _, input := range inputs {
 		t, ok := input["type"].(string)
 		if !ok {
@@ -135,6 +125,9 @@ func appendInputsAndOutputs(config *telegrafConfig, inputs, outputs []v1alpha1.M
 				newOutputs[k] = v
 			}
 		}
+		if t == "file" {
+			addFileRotation(newOutputs, rotation)
+		}
 		config.Outputs[t] = append(config.Outputs[t], newOutputs)
 	}
 }
@@ -151,8 +144,13 @@ func (c *ClusterConfig) String() string {
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	var kubeletMetrics bool
 	for _, cms := range c.clusterSinks {
-		appendInputsAndOutputs(&tConfig, cms.Spec.Inputs, cms.Spec.Outputs)
+		appendInputsAndOutputs(&tConfig, cms.Spec.Inputs, loadBalancedOutputs(cms.Spec), cms.Spec.FileRotation)
+		kubeletMetrics = kubeletMetrics || cms.Spec.KubeletMetrics
+	}
+	if kubeletMetrics && c.kubelet != nil {
+		tConfig.Inputs["prometheus"] = append(tConfig.Inputs["prometheus"], cadvisorInput(c.kubelet))
 	}
 
 	return tConfig.String()
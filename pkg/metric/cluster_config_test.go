@@ -1,6 +1,7 @@
 package metric_test
 
 import (
+	"strings"
 	"sync"
 	"testing"
 
@@ -72,6 +73,87 @@ func TestSingleSink(t *testing.T) {
 	assertEquals(t, sc, expected)
 }
 
+func TestFileRotation(t *testing.T) {
+	sc := metric.NewConfig("")
+	sc.UpsertSink(v1alpha1.ClusterMetricSink{
+		Spec: v1alpha1.MetricSinkSpec{
+			Inputs: []v1alpha1.MetricSinkMap{
+				{
+					"type": "cpu",
+				},
+			},
+			Outputs: []v1alpha1.MetricSinkMap{
+				{
+					"type":  "file",
+					"files": []string{"/tmp/metrics"},
+				},
+				{
+					"type":    "datadog",
+					"api_key": "some-key",
+				},
+			},
+			FileRotation: &v1alpha1.FileRotation{
+				RotationCount: 3,
+				MaxSize:       10485760,
+			},
+		},
+	})
+
+	const expected = `[inputs]
+
+  [[inputs.cpu]]
+
+[outputs]
+
+  [[outputs.datadog]]
+    api_key = "some-key"
+
+  [[outputs.file]]
+    files = ["/tmp/metrics"]
+    rotation_max_archives = 3
+    rotation_max_size = 10485760
+`
+
+	assertEquals(t, sc, expected)
+}
+
+func TestDataFormat(t *testing.T) {
+	for _, format := range []string{"influx", "json", "prometheus"} {
+		t.Run(format, func(t *testing.T) {
+			sc := metric.NewConfig("")
+			sc.UpsertSink(v1alpha1.ClusterMetricSink{
+				Spec: v1alpha1.MetricSinkSpec{
+					Inputs: []v1alpha1.MetricSinkMap{
+						{
+							"type": "cpu",
+						},
+					},
+					Outputs: []v1alpha1.MetricSinkMap{
+						{
+							"type":        "file",
+							"files":       []string{"stdout"},
+							"data_format": format,
+						},
+					},
+				},
+			})
+
+			expected := `[inputs]
+
+  [[inputs.cpu]]
+
+[outputs]
+
+  [[outputs.file]]
+    data_format = "` + format + `"
+    files = ["stdout"]
+`
+
+			assertEquals(t, sc, expected)
+		})
+	}
+}
+
 func TestClusterNameTag(t *testing.T) {
 	sc := metric.NewConfig("cluster-name", metric.KubernetesDefault(false))
 	sink := v1alpha1.ClusterMetricSink{
@@ -352,6 +434,78 @@ func TestUseInsecureKubernetesPortConfig(t *testing.T) {
 	assertEquals(t, sc, expected)
 }
 
+func TestKubeletMetrics(t *testing.T) {
+	kubeletSink := func(name string) v1alpha1.ClusterMetricSink {
+		return v1alpha1.ClusterMetricSink{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.MetricSinkSpec{
+				Outputs: []v1alpha1.MetricSinkMap{
+					{
+						"type": "discard",
+					},
+				},
+				KubeletMetrics: true,
+			},
+		}
+	}
+
+	t.Run("it scrapes the cadvisor metrics of the node's kubelet", func(t *testing.T) {
+		sc := metric.NewConfig("", metric.KubernetesDefault(false))
+		sc.UpsertSink(kubeletSink("first"))
+		sc.UpsertSink(kubeletSink("second"))
+
+		const expected = `[inputs]
+
+  [[inputs.kubernetes]]
+    bearer_token = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+    insecure_skip_verify = true
+    url = "https://127.0.0.1:10250"
+
+  [[inputs.prometheus]]
+    bearer_token = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+    insecure_skip_verify = true
+    urls = ["https://127.0.0.1:10250/metrics/cadvisor"]
+
+[outputs]
+
+  [[outputs.discard]]
+
+  [[outputs.discard]]
+`
+		assertEquals(t, sc, expected)
+	})
+
+	t.Run("it scrapes the read only port without auth", func(t *testing.T) {
+		sc := metric.NewConfig("", metric.KubernetesDefault(true))
+		sc.UpsertSink(kubeletSink("first"))
+
+		const expected = `[inputs]
+
+  [[inputs.kubernetes]]
+    url = "http://127.0.0.1:10255"
+
+  [[inputs.prometheus]]
+    urls = ["http://127.0.0.1:10255/metrics/cadvisor"]
+
+[outputs]
+
+  [[outputs.discard]]
+`
+		assertEquals(t, sc, expected)
+	})
+
+	t.Run("it does not scrape the kubelet without a sink that sets it", func(t *testing.T) {
+		sc := metric.NewConfig("", metric.KubernetesDefault(false))
+		s := kubeletSink("first")
+		s.Spec.KubeletMetrics = false
+		sc.UpsertSink(s)
+
+		if strings.Contains(sc.String(), "cadvisor") {
+			t.Errorf("Expected no kubelet scrape, got %s", sc.String())
+		}
+	})
+}
+
 func TestNoDefaultInput(t *testing.T) {
 	sc := metric.NewConfig("")
 	sink := v1alpha1.ClusterMetricSink{
@@ -32,11 +32,12 @@ import (
 
 // This is a build arg that's injected with the appropriate SHA of
 // the telegraf image
-var TelegrafImageVersion string = "1.11-alpine"
+var TelegrafImageVersion string = "1.12-alpine"
 
 type V1CoreClient interface {
 	typedv1.ConfigMapsGetter
 	typedv1.PodsGetter
+	typedv1.ServicesGetter
 }
 
 type V1beta1ExtensionsClient interface {
@@ -96,6 +97,14 @@ func (c *Controller) OnAdd(o interface{}) {
 		log.Printf("Unable to create deployment: %s\n", err)
 		return
 	}
+
+	if ms.Spec.StatsD != nil {
+		_, err = c.coreClient.Services(ms.Namespace).Create(getTelegrafService(ms))
+		if err != nil {
+			log.Printf("Unable to create service: %s\n", err)
+			return
+		}
+	}
 }
 
 func (c *Controller) OnUpdate(o, n interface{}) {
@@ -118,6 +127,22 @@ func (c *Controller) OnUpdate(o, n interface{}) {
 		return
 	}
 
+	// The scrape auth and tls secrets and the statsd port are set on the
+	// deployment, which restarts the pods when it is updated.
+	if !reflect.DeepEqual(oms.Spec.ScrapeAuth, nms.Spec.ScrapeAuth) ||
+		!reflect.DeepEqual(oms.Spec.ScrapeTLS, nms.Spec.ScrapeTLS) ||
+		!reflect.DeepEqual(oms.Spec.StatsD, nms.Spec.StatsD) {
+		_, err = c.extensionsClient.Deployments(nms.Namespace).Update(getTelegrafDeployment(nms))
+		if err != nil {
+			log.Printf("Unable to update deployment: %s\n", err)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(oms.Spec.StatsD, nms.Spec.StatsD) {
+		c.replaceService(oms, nms)
+	}
+
 	err = c.coreClient.Pods(nms.Namespace).DeleteCollection(
 		nil,
 		metav1.ListOptions{
@@ -160,6 +185,35 @@ func (c *Controller) OnDelete(o interface{}) {
 		log.Printf("Unable to delete role: %s\n", err)
 		return
 	}
+
+	if ms.Spec.StatsD != nil {
+		err = c.coreClient.Services(ms.Namespace).Delete(name, nil)
+		if err != nil {
+			log.Printf("Unable to delete service: %s\n", err)
+			return
+		}
+	}
+}
+
+// replaceService recreates the statsd service of a MetricSink whose statsd
+// input changed. A service cannot be updated without its cluster IP, so the
+// old one is deleted rather than updated.
+func (c *Controller) replaceService(oms, nms *v1alpha1.MetricSink) {
+	if oms.Spec.StatsD != nil {
+		err := c.coreClient.Services(oms.Namespace).Delete(getAppName(oms), nil)
+		if err != nil {
+			log.Printf("Unable to delete service: %s\n", err)
+			return
+		}
+	}
+
+	if nms.Spec.StatsD != nil {
+		_, err := c.coreClient.Services(nms.Namespace).Create(getTelegrafService(nms))
+		if err != nil {
+			log.Printf("Unable to create service: %s\n", err)
+			return
+		}
+	}
 }
 
 func (c *Controller) getTelegrafConfigMap(ms *v1alpha1.MetricSink) *v1.ConfigMap {
@@ -183,7 +237,13 @@ func (c *Controller) getTelegrafConfigMap(ms *v1alpha1.MetricSink) *v1.ConfigMap
 	}
 }
 
+// getAppName names the telegraf resources of a sink. The resources of a
+// LogSink's metrics are prefixed with logsink so that they do not collide
+// with those of a MetricSink of the same name.
 func getAppName(ms *v1alpha1.MetricSink) string {
+	if ms.Kind == "LogSink" {
+		return fmt.Sprintf("telegraf-logsink-%s", ms.Name)
+	}
 	return fmt.Sprintf("telegraf-%s", ms.Name)
 }
 
@@ -215,7 +275,7 @@ func getTelegrafDeployment(ms *v1alpha1.MetricSink) *appsv1.Deployment {
 					Labels: map[string]string{"app": name},
 				},
 				Spec: v1.PodSpec{
-					Volumes: []v1.Volume{{
+					Volumes: append([]v1.Volume{{
 						Name: "telegraf-config",
 						VolumeSource: v1.VolumeSource{
 							ConfigMap: &v1.ConfigMapVolumeSource{
@@ -224,15 +284,17 @@ func getTelegrafDeployment(ms *v1alpha1.MetricSink) *appsv1.Deployment {
 								},
 							},
 						},
-					}},
+					}}, scrapeTLSVolumes(ms.Spec.ScrapeTLS)...),
 					Containers: []v1.Container{{
 						Name:    "telegraf",
 						Image:   "telegraf:" + TelegrafImageVersion,
 						Command: []string{"telegraf", "--config-directory", "/etc/telegraf"},
-						VolumeMounts: []v1.VolumeMount{{
+						Env:     scrapeAuthEnv(ms.Spec.ScrapeAuth),
+						Ports:   statsdPorts(ms.Spec.StatsD),
+						VolumeMounts: append([]v1.VolumeMount{{
 							Name:      "telegraf-config",
 							MountPath: "/etc/telegraf",
-						}},
+						}}, scrapeTLSVolumeMounts(ms.Spec.ScrapeTLS)...),
 						ImagePullPolicy: "IfNotPresent",
 					}},
 				},
@@ -308,9 +370,13 @@ func (c *Controller) metricSinkConfig(ms *v1alpha1.MetricSink) string {
 		config.GlobalTags = map[string]string{"cluster_name": c.clusterName}
 	}
 
-	config.Inputs["prometheus"] = []map[string]interface{}{{"monitor_kubernetes_pods": true, "monitor_kubernetes_pods_namespace": ms.Namespace}}
+	prometheus := map[string]interface{}{"monitor_kubernetes_pods": true, "monitor_kubernetes_pods_namespace": ms.Namespace}
+	addScrapeAuth(prometheus, ms.Spec.ScrapeAuth)
+	addScrapeTLS(prometheus, ms.Spec.ScrapeTLS)
+	config.Inputs["prometheus"] = []map[string]interface{}{prometheus}
 
-	appendInputsAndOutputs(&config, ms.Spec.Inputs, ms.Spec.Outputs)
+	addStatsD(&config, ms.Spec.StatsD)
+	appendInputsAndOutputs(&config, ms.Spec.Inputs, loadBalancedOutputs(ms.Spec), ms.Spec.FileRotation)
 
 	return config.String()
 }
@@ -1159,6 +1159,7 @@ func TestMetricSink(t *testing.T) {
 type spyCoreV1Client struct {
 	spyConfigMapCUDer
 	spyPodDeleter
+	spyServiceCUDer
 }
 
 func (c *spyCoreV1Client) Pods(namespace string) typedv1.PodInterface {
@@ -1169,6 +1170,10 @@ func (c *spyCoreV1Client) ConfigMaps(namespace string) typedv1.ConfigMapInterfac
 	return &c.spyConfigMapCUDer
 }
 
+func (c *spyCoreV1Client) Services(namespace string) typedv1.ServiceInterface {
+	return &c.spyServiceCUDer
+}
+
 type spyPodDeleter struct {
 	called              bool
 	receivedListOptions metav1.ListOptions
@@ -1198,6 +1203,19 @@ func (s *spyConfigMapCUDer) Delete(name string, options *metav1.DeleteOptions) e
 	return s.deleteFunc(name, options)
 }
 
+type spyServiceCUDer struct {
+	createFunc func(*v1.Service) (*v1.Service, error)
+	deleteFunc func(name string, options *metav1.DeleteOptions) error
+}
+
+func (s *spyServiceCUDer) Create(svc *v1.Service) (*v1.Service, error) {
+	return s.createFunc(svc)
+}
+
+func (s *spyServiceCUDer) Delete(name string, options *metav1.DeleteOptions) error {
+	return s.deleteFunc(name, options)
+}
+
 type spyAppsV1Client struct {
 	spyTelegrafDeploymentCUDer
 }
@@ -1406,3 +1424,31 @@ func (s *spyPodDeleter) Evict(eviction *policyv1beta1.Eviction) error {
 func (s *spyPodDeleter) GetLogs(name string, opts *v1.PodLogOptions) *rest.Request {
 	panic("should not be called")
 }
+
+func (s *spyServiceCUDer) Update(*v1.Service) (*v1.Service, error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) UpdateStatus(*v1.Service) (*v1.Service, error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) Get(name string, options metav1.GetOptions) (*v1.Service, error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) List(opts metav1.ListOptions) (*v1.ServiceList, error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) Watch(opts metav1.ListOptions) (watch.Interface, error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1.Service, err error) {
+	panic("should not be called")
+}
+
+func (s *spyServiceCUDer) ProxyGet(scheme, name, port, path string, params map[string]string) rest.ResponseWrapper {
+	panic("should not be called")
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+// serviceAccountToken is the token of the telegraf service account, which
+// the kubelet authorizes its requests with.
+const serviceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// kubeletInput returns the address and auth of the kubelet of the node the
+// telegraf daemon runs on. The daemon runs on the host network, so the
+// kubelet listens on localhost. Its read only port needs no auth.
+func kubeletInput(insecurePort bool) map[string]interface{} {
+	if insecurePort {
+		return map[string]interface{}{
+			"url": "http://127.0.0.1:10255",
+		}
+	}
+	return map[string]interface{}{
+		"bearer_token":         serviceAccountToken,
+		"insecure_skip_verify": true,
+		"url":                  "https://127.0.0.1:10250",
+	}
+}
+
+// cadvisorInput returns a prometheus input scraping the container metrics
+// the kubelet serves from cAdvisor. Every sink's outputs receive the
+// metrics of every input, so it is rendered once however many sinks set
+// KubeletMetrics.
+func cadvisorInput(kubelet map[string]interface{}) map[string]interface{} {
+	input := make(map[string]interface{}, len(kubelet))
+	for k, v := range kubelet {
+		if k == "url" {
+			k, v = "urls", []string{v.(string) + "/metrics/cadvisor"}
+		}
+		input[k] = v
+	}
+	return input
+}
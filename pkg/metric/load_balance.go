@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// loadBalancedOutputs returns the outputs of the sink, with the outputs of
+// a load balanced sink merged into one. The influxdb outputs write each
+// batch to one of their urls picked at random, so each output's urls are
+// listed as many times as its weight, divided by the greatest common
+// divisor of the weights to keep the list short. Weights the webhook
+// rejects leave the outputs as they are.
+func loadBalancedOutputs(spec v1alpha1.MetricSinkSpec) []v1alpha1.MetricSinkMap {
+	lb := spec.LoadBalance
+	if lb == nil || len(spec.Outputs) < 2 || len(lb.Weights) != len(spec.Outputs) {
+		return spec.Outputs
+	}
+
+	var divisor int
+	for _, w := range lb.Weights {
+		if w < 1 {
+			return spec.Outputs
+		}
+		divisor = gcd(divisor, w)
+	}
+
+	merged := make(v1alpha1.MetricSinkMap, len(spec.Outputs[0]))
+	for k, v := range spec.Outputs[0] {
+		merged[k] = v
+	}
+	var urls []string
+	for i, output := range spec.Outputs {
+		for n := 0; n < lb.Weights[i]/divisor; n++ {
+			urls = append(urls, outputURLs(output)...)
+		}
+	}
+	merged["urls"] = urls
+
+	return []v1alpha1.MetricSinkMap{merged}
+}
+
+// outputURLs returns the urls of an output, which are a list of strings
+// when decoded from JSON.
+func outputURLs(output v1alpha1.MetricSinkMap) []string {
+	switch urls := output["urls"].(type) {
+	case []string:
+		return urls
+	case []interface{}:
+		var s []string
+		for _, u := range urls {
+			if str, ok := u.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestLoadBalance(t *testing.T) {
+	balancedSink := func(lb *v1alpha1.LoadBalance) v1alpha1.ClusterMetricSink {
+		return v1alpha1.ClusterMetricSink{
+			Spec: v1alpha1.MetricSinkSpec{
+				Inputs: []v1alpha1.MetricSinkMap{
+					{
+						"type": "cpu",
+					},
+				},
+				Outputs: []v1alpha1.MetricSinkMap{
+					{
+						"type":     "influxdb",
+						"urls":     []string{"http://tsdb-0:8086"},
+						"database": "metrics",
+					},
+					{
+						"type":     "influxdb",
+						"urls":     []string{"http://tsdb-1:8086", "http://tsdb-2:8086"},
+						"database": "metrics",
+					},
+				},
+				LoadBalance: lb,
+			},
+		}
+	}
+
+	t.Run("it lists the urls of each output by weight", func(t *testing.T) {
+		sc := metric.NewConfig("")
+		sc.UpsertSink(balancedSink(&v1alpha1.LoadBalance{Weights: []int{4, 2}}))
+
+		expected := `[inputs]
+
+  [[inputs.cpu]]
+
+[outputs]
+
+  [[outputs.influxdb]]
+    database = "metrics"
+    urls = ["http://tsdb-0:8086", "http://tsdb-0:8086", "http://tsdb-1:8086", "http://tsdb-2:8086"]
+`
+
+		assertEquals(t, sc, expected)
+	})
+
+	t.Run("it balances outputs decoded from JSON", func(t *testing.T) {
+		var spec v1alpha1.MetricSinkSpec
+		err := json.Unmarshal([]byte(`{
+			"inputs": [{"type": "cpu"}],
+			"outputs": [
+				{"type": "influxdb_v2", "urls": ["http://tsdb-0:9999"], "bucket": "metrics"},
+				{"type": "influxdb_v2", "urls": ["http://tsdb-1:9999"], "bucket": "metrics"}
+			],
+			"load_balance": {"weights": [1, 3]}
+		}`), &spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := metric.NewConfig("")
+		sc.UpsertSink(v1alpha1.ClusterMetricSink{Spec: spec})
+
+		expected := `[inputs]
+
+  [[inputs.cpu]]
+
+[outputs]
+
+  [[outputs.influxdb_v2]]
+    bucket = "metrics"
+    urls = ["http://tsdb-0:9999", "http://tsdb-1:9999", "http://tsdb-1:9999", "http://tsdb-1:9999"]
+`
+
+		assertEquals(t, sc, expected)
+	})
+
+	t.Run("it writes to every output without load balancing", func(t *testing.T) {
+		sc := metric.NewConfig("")
+		sc.UpsertSink(balancedSink(nil))
+
+		expected := `[inputs]
+
+  [[inputs.cpu]]
+
+[outputs]
+
+  [[outputs.influxdb]]
+    database = "metrics"
+    urls = ["http://tsdb-0:8086"]
+
+  [[outputs.influxdb]]
+    database = "metrics"
+    urls = ["http://tsdb-1:8086", "http://tsdb-2:8086"]
+`
+
+		assertEquals(t, sc, expected)
+	})
+}
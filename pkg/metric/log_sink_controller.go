@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// LogSinkController provisions the telegraf deployment of LogSinks with
+// metrics. The sink-controller renders the LogSink's fluent-bit config and
+// this controller its telegraf resources, which are owned by the LogSink
+// and named apart from those of MetricSinks.
+type LogSinkController struct {
+	c *Controller
+}
+
+func NewLogSinkController(c *Controller) *LogSinkController {
+	return &LogSinkController{c: c}
+}
+
+func (lc *LogSinkController) OnAdd(o interface{}) {
+	ls, ok := o.(*v1alpha1.LogSink)
+	if !ok || ls.Spec.Metrics == nil {
+		return
+	}
+
+	lc.c.OnAdd(logSinkMetrics(ls))
+}
+
+func (lc *LogSinkController) OnUpdate(o, n interface{}) {
+	ols, ok := o.(*v1alpha1.LogSink)
+	if !ok {
+		return
+	}
+	nls, ok := n.(*v1alpha1.LogSink)
+	if !ok {
+		return
+	}
+
+	switch {
+	case ols.Spec.Metrics == nil && nls.Spec.Metrics != nil:
+		lc.c.OnAdd(logSinkMetrics(nls))
+	case ols.Spec.Metrics != nil && nls.Spec.Metrics == nil:
+		lc.c.OnDelete(logSinkMetrics(ols))
+	case ols.Spec.Metrics != nil:
+		lc.c.OnUpdate(logSinkMetrics(ols), logSinkMetrics(nls))
+	}
+}
+
+func (lc *LogSinkController) OnDelete(o interface{}) {
+	ls, ok := o.(*v1alpha1.LogSink)
+	if !ok || ls.Spec.Metrics == nil {
+		return
+	}
+
+	lc.c.OnDelete(logSinkMetrics(ls))
+}
+
+// logSinkMetrics returns the MetricSink the telegraf resources of a
+// LogSink are built from. It keeps the LogSink's type and object meta so
+// that the resources are owned by the LogSink.
+func logSinkMetrics(ls *v1alpha1.LogSink) *v1alpha1.MetricSink {
+	ms := &v1alpha1.MetricSink{
+		TypeMeta:   ls.TypeMeta,
+		ObjectMeta: ls.ObjectMeta,
+		Spec:       *ls.Spec.Metrics,
+	}
+	if ms.Kind == "" {
+		ms.Kind = "LogSink"
+	}
+	setDefaultTypeMeta(ms)
+	return ms
+}
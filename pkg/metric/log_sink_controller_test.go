@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestLogSinkController(t *testing.T) {
+	logSink := func(metrics *sinkv1alpha1.MetricSinkSpec) *sinkv1alpha1.LogSink {
+		return &sinkv1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "combined-sink",
+				Namespace: "test-namespace",
+				UID:       "some-random-uid",
+			},
+			Spec: sinkv1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: sinkv1alpha1.WebhookSpec{
+					URL: "https://example.com/logs",
+				},
+				Metrics: metrics,
+			},
+		}
+	}
+	metrics := &sinkv1alpha1.MetricSinkSpec{
+		Outputs: []sinkv1alpha1.MetricSinkMap{{
+			"type":   "datadog",
+			"apikey": "some-key",
+		}},
+	}
+
+	type received struct {
+		configMaps  []v1.ConfigMap
+		deployments []appsv1.Deployment
+		deleted     []string
+	}
+	newController := func(r *received) *metric.LogSinkController {
+		deleteFunc := func(name string, _ *metav1.DeleteOptions) error {
+			r.deleted = append(r.deleted, name)
+			return nil
+		}
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				createFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					r.configMaps = append(r.configMaps, *cm)
+					return cm, nil
+				},
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					r.configMaps = append(r.configMaps, *cm)
+					return cm, nil
+				},
+				deleteFunc: deleteFunc,
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				createFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					r.deployments = append(r.deployments, *d)
+					return d, nil
+				},
+				deleteFunc: deleteFunc,
+			},
+		}
+		spyRBACClient := &spyRBACV1Client{
+			spyRoleCUDer: spyRoleCUDer{
+				createFunc: func(r *rbacv1.Role) (*rbacv1.Role, error) {
+					return r, nil
+				},
+				deleteFunc: deleteFunc,
+			},
+			spyRoleBindingCUDer: spyRoleBindingCUDer{
+				createFunc: func(rb *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+					return rb, nil
+				},
+				deleteFunc: deleteFunc,
+			},
+		}
+		return metric.NewLogSinkController(
+			metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient),
+		)
+	}
+
+	t.Run("it renders both fluent-bit and telegraf config", func(t *testing.T) {
+		r := &received{}
+		ls := logSink(metrics)
+		newController(r).OnAdd(ls)
+
+		sc := sink.NewConfig()
+		sc.UpsertSink(ls)
+		if config := sc.String(); !strings.Contains(config, "URI /logs") {
+			t.Errorf("Expected the sink's fluent-bit output, got %s", config)
+		}
+
+		if len(r.configMaps) != 1 || len(r.deployments) != 1 {
+			t.Fatalf("Expected a config map and a deployment, got %d and %d", len(r.configMaps), len(r.deployments))
+		}
+		expectedConfig := `[inputs]
+
+  [[inputs.prometheus]]
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`
+		if diff := cmp.Diff(expectedConfig, r.configMaps[0].Data["metric-sinks.conf"]); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+
+		expectedOwners := []metav1.OwnerReference{{
+			APIVersion: "observability.knative.dev/v1alpha1",
+			Kind:       "LogSink",
+			Name:       "combined-sink",
+			UID:        "some-random-uid",
+		}}
+		if diff := cmp.Diff(expectedOwners, r.deployments[0].OwnerReferences); diff != "" {
+			t.Errorf("Owners not equal (-want, +got) = %v", diff)
+		}
+		if r.deployments[0].Name != "telegraf-logsink-combined-sink" {
+			t.Errorf("Expected the deployment to be named apart from metric sinks, got %s", r.deployments[0].Name)
+		}
+	})
+
+	t.Run("it ignores LogSinks without metrics", func(t *testing.T) {
+		r := &received{}
+		c := newController(r)
+		c.OnAdd(logSink(nil))
+		c.OnUpdate(logSink(nil), logSink(nil))
+		c.OnDelete(logSink(nil))
+
+		if len(r.configMaps) != 0 || len(r.deployments) != 0 || len(r.deleted) != 0 {
+			t.Errorf("Expected no telegraf resources, got %+v", r)
+		}
+	})
+
+	t.Run("it provisions and removes metrics as they are set", func(t *testing.T) {
+		r := &received{}
+		c := newController(r)
+
+		c.OnUpdate(logSink(nil), logSink(metrics))
+		if len(r.deployments) != 1 {
+			t.Errorf("Expected a deployment to be created, got %d", len(r.deployments))
+		}
+
+		c.OnUpdate(logSink(metrics), logSink(nil))
+		expected := []string{
+			"telegraf-logsink-combined-sink",
+			"telegraf-logsink-combined-sink",
+			"telegraf-logsink-combined-sink",
+			"telegraf-logsink-combined-sink",
+		}
+		if diff := cmp.Diff(expected, r.deleted); diff != "" {
+			t.Errorf("Deleted not equal (-want, +got) = %v", diff)
+		}
+	})
+}
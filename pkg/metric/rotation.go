@@ -0,0 +1,28 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+
+// addFileRotation sets telegraf's rotation options on a file output.
+func addFileRotation(output map[string]interface{}, rotation *v1alpha1.FileRotation) {
+	if rotation == nil {
+		return
+	}
+
+	output["rotation_max_archives"] = rotation.RotationCount
+	output["rotation_max_size"] = rotation.MaxSize
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	sinkclient "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SecretKeyController checks that the secret keys MetricSinks reference
+// exist whenever one of their secrets changes. Telegraf reads the keys
+// when its pods start, so a sink whose key disappears is marked Degraded
+// and its pods are left running, and its pods are restarted to read the
+// key once it exists again.
+type SecretKeyController struct {
+	sinks   cache.Indexer
+	secrets corelisters.SecretLister
+	msg     sinkclient.MetricSinksGetter
+	pg      typedv1.PodsGetter
+	now     func() time.Time
+}
+
+// NewSecretKeyController returns a controller for the events of secrets.
+// sinks is the cache of the MetricSink informer with the SecretIndexers.
+func NewSecretKeyController(
+	sinks cache.Indexer,
+	secrets corelisters.SecretLister,
+	msg sinkclient.MetricSinksGetter,
+	pg typedv1.PodsGetter,
+) *SecretKeyController {
+	return &SecretKeyController{
+		sinks:   sinks,
+		secrets: secrets,
+		msg:     msg,
+		pg:      pg,
+		now:     time.Now,
+	}
+}
+
+func (c *SecretKeyController) OnAdd(o interface{}) {
+	c.check(o)
+}
+
+func (c *SecretKeyController) OnUpdate(old, new interface{}) {
+	c.check(new)
+}
+
+func (c *SecretKeyController) OnDelete(o interface{}) {
+	if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+		o = tombstone.Obj
+	}
+	c.check(o)
+}
+
+func (c *SecretKeyController) check(o interface{}) {
+	s, ok := o.(*v1.Secret)
+	if !ok {
+		return
+	}
+
+	objs, err := c.sinks.ByIndex(SecretIndex, s.Namespace+"/"+s.Name)
+	if err != nil {
+		log.Printf("Unable to look up sinks depending on secret %s/%s: %s", s.Namespace, s.Name, err)
+		return
+	}
+	for _, obj := range objs {
+		if ms, ok := obj.(*v1alpha1.MetricSink); ok {
+			c.reconcile(ms)
+		}
+	}
+}
+
+// reconcile updates the sink's Degraded condition from the keys of all of
+// its secrets, since a sink may reference more than one.
+func (c *SecretKeyController) reconcile(ms *v1alpha1.MetricSink) {
+	missing := c.missingKeys(ms)
+	degraded := len(missing) > 0
+	if degraded == isDegraded(ms) {
+		return
+	}
+
+	cond := v1alpha1.SinkCondition{
+		Type:               v1alpha1.SinkConditionDegraded,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(c.now()),
+		Reason:             "SecretKeysFound",
+	}
+	if degraded {
+		cond.Status = v1.ConditionTrue
+		cond.Reason = "SecretKeyMissing"
+		cond.Message = "Missing secret keys " + strings.Join(missing, ", ")
+	}
+
+	ms = ms.DeepCopy()
+	ms.Status.SetCondition(cond)
+	_, err := c.msg.MetricSinks(ms.Namespace).UpdateStatus(ms)
+	if err != nil {
+		log.Printf("Unable to update status of MetricSink %s/%s: %s", ms.Namespace, ms.Name, err)
+	}
+
+	if degraded {
+		return
+	}
+	err = c.pg.Pods(ms.Namespace).DeleteCollection(
+		nil,
+		metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("app=%s", getAppName(ms)),
+		},
+	)
+	if err != nil {
+		log.Printf("Unable to delete pod collection: %s\n", err)
+	}
+}
+
+// missingKeys returns the secret/key of every key the sink references that
+// does not exist.
+func (c *SecretKeyController) missingKeys(ms *v1alpha1.MetricSink) []string {
+	var missing []string
+	for _, ref := range sinkSecretRefs(ms) {
+		s, err := c.secrets.Secrets(ms.Namespace).Get(ref.Name)
+		if errors.IsNotFound(err) {
+			missing = append(missing, ref.Name+"/"+ref.Key)
+			continue
+		}
+		if err != nil {
+			log.Printf("Unable to get secret %s/%s: %s", ms.Namespace, ref.Name, err)
+			continue
+		}
+		if _, ok := s.Data[ref.Key]; !ok {
+			missing = append(missing, ref.Name+"/"+ref.Key)
+		}
+	}
+	return missing
+}
+
+func isDegraded(ms *v1alpha1.MetricSink) bool {
+	for _, c := range ms.Status.Conditions {
+		if c.Type == v1alpha1.SinkConditionDegraded {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestSecretKeyController(t *testing.T) {
+	setup := func(t *testing.T) (*metric.SecretKeyController, *secretKeyFixture) {
+		ms := secretSink("ns1", "sink", tokenAuth("scrape-secret"))
+		f := &secretKeyFixture{
+			t:       t,
+			client:  fake.NewSimpleClientset(ms),
+			sinks:   cache.NewIndexer(cache.MetaNamespaceKeyFunc, metric.SecretIndexers),
+			secrets: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+			core:    &spyCoreV1Client{},
+		}
+		addSink(t, f.sinks, ms)
+
+		c := metric.NewSecretKeyController(
+			f.sinks,
+			corelisters.NewSecretLister(f.secrets),
+			f.client.ObservabilityV1alpha1(),
+			f.core,
+		)
+		return c, f
+	}
+
+	t.Run("it leaves sinks whose keys exist alone", func(t *testing.T) {
+		c, f := setup(t)
+
+		c.OnAdd(f.setSecret("token"))
+
+		if f.condition() != nil {
+			t.Errorf("Expected no condition, got %+v", f.condition())
+		}
+		if f.core.spyPodDeleter.called {
+			t.Error("Expected pods not to be restarted")
+		}
+	})
+
+	t.Run("it degrades sinks when the key is removed", func(t *testing.T) {
+		c, f := setup(t)
+		old := f.setSecret("token")
+
+		c.OnUpdate(old, f.setSecret("renamed-token"))
+
+		cond := f.condition()
+		if cond == nil || cond.Status != v1.ConditionTrue {
+			t.Fatalf("Expected the sink to be degraded, got %+v", cond)
+		}
+		if cond.Message != "Missing secret keys scrape-secret/token" {
+			t.Errorf("Unexpected message %q", cond.Message)
+		}
+		if f.core.spyPodDeleter.called {
+			t.Error("Expected pods to keep running with the last key")
+		}
+	})
+
+	t.Run("it recovers and restarts sinks when the key is re-added", func(t *testing.T) {
+		c, f := setup(t)
+		old := f.setSecret("token")
+		removed := f.setSecret("renamed-token")
+		c.OnUpdate(old, removed)
+		f.syncSink()
+
+		c.OnUpdate(removed, f.setSecret("token"))
+
+		cond := f.condition()
+		if cond == nil || cond.Status != v1.ConditionFalse {
+			t.Fatalf("Expected the sink to no longer be degraded, got %+v", cond)
+		}
+		if !f.core.spyPodDeleter.called {
+			t.Fatal("Expected pods to be restarted")
+		}
+		if f.core.spyPodDeleter.receivedListOptions.LabelSelector != "app=telegraf-sink" {
+			t.Errorf("Unexpected selector %q", f.core.spyPodDeleter.receivedListOptions.LabelSelector)
+		}
+	})
+
+	t.Run("it degrades sinks when the secret is deleted", func(t *testing.T) {
+		c, f := setup(t)
+		s := f.setSecret("token")
+		if err := f.secrets.Delete(s); err != nil {
+			t.Fatal(err)
+		}
+
+		c.OnDelete(cache.DeletedFinalStateUnknown{Key: "ns1/scrape-secret", Obj: s})
+
+		cond := f.condition()
+		if cond == nil || cond.Status != v1.ConditionTrue {
+			t.Errorf("Expected the sink to be degraded, got %+v", cond)
+		}
+	})
+}
+
+type secretKeyFixture struct {
+	t       *testing.T
+	client  *fake.Clientset
+	sinks   cache.Indexer
+	secrets cache.Indexer
+	core    *spyCoreV1Client
+}
+
+// setSecret stores the scrape secret with the given keys in the cache.
+func (f *secretKeyFixture) setSecret(keys ...string) *v1.Secret {
+	s := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "scrape-secret",
+			Namespace: "ns1",
+		},
+		Data: make(map[string][]byte),
+	}
+	for _, k := range keys {
+		s.Data[k] = []byte("value")
+	}
+	if err := f.secrets.Update(s); err != nil {
+		f.t.Fatal(err)
+	}
+	return s
+}
+
+// syncSink updates the cached sink from the client, as the informer would.
+func (f *secretKeyFixture) syncSink() {
+	ms, err := f.client.ObservabilityV1alpha1().MetricSinks("ns1").Get("sink", metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	if err := f.sinks.Update(ms); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+func (f *secretKeyFixture) condition() *sinkv1alpha1.SinkCondition {
+	ms, err := f.client.ObservabilityV1alpha1().MetricSinks("ns1").Get("sink", metav1.GetOptions{})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	for _, c := range ms.Status.Conditions {
+		if c.Type == sinkv1alpha1.SinkConditionDegraded {
+			return &c
+		}
+	}
+	return nil
+}
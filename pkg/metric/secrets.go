@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SecretIndex is the name of the MetricSink informer index from the
+// namespace/name of a secret to the MetricSinks referencing it.
+const SecretIndex = "secret"
+
+// SecretIndexers are added to the MetricSink informer so the sinks
+// depending on a secret are looked up from its cache.
+var SecretIndexers = cache.Indexers{SecretIndex: secretIndexFunc}
+
+func secretIndexFunc(obj interface{}) ([]string, error) {
+	ms, ok := obj.(*v1alpha1.MetricSink)
+	if !ok {
+		return nil, nil
+	}
+
+	secrets := SinkSecrets(ms)
+	keys := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		keys = append(keys, ms.Namespace+"/"+s)
+	}
+	return keys, nil
+}
+
+// SinkSecrets returns the names of the secrets the MetricSink references.
+// Secrets are always read from the sink's namespace.
+func SinkSecrets(ms *v1alpha1.MetricSink) []string {
+	names := make(map[string]bool)
+	for _, ref := range sinkSecretRefs(ms) {
+		names[ref.Name] = true
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	secrets := make([]string, 0, len(names))
+	for n := range names {
+		secrets = append(secrets, n)
+	}
+	sort.Strings(secrets)
+	return secrets
+}
+
+// sinkSecretRefs returns the secret keys referenced by the scrape auth and
+// tls of the MetricSink.
+func sinkSecretRefs(ms *v1alpha1.MetricSink) []*v1.SecretKeySelector {
+	var refs []*v1.SecretKeySelector
+	if auth := ms.Spec.ScrapeAuth; auth != nil {
+		if auth.BearerTokenSecretRef != nil {
+			refs = append(refs, auth.BearerTokenSecretRef)
+		}
+		if auth.BasicAuth != nil && auth.BasicAuth.PasswordSecretRef != nil {
+			refs = append(refs, auth.BasicAuth.PasswordSecretRef)
+		}
+	}
+	if tls := ms.Spec.ScrapeTLS; tls != nil {
+		for _, ref := range []*v1.SecretKeySelector{tls.CASecretRef, tls.CertSecretRef, tls.KeySecretRef} {
+			if ref != nil {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// DependentSinks returns the names of the MetricSinks referencing the
+// secret. indexer must have the SecretIndexers.
+func DependentSinks(indexer cache.Indexer, namespace, secret string) ([]string, error) {
+	objs, err := indexer.ByIndex(SecretIndex, namespace+"/"+secret)
+	if err != nil {
+		return nil, err
+	}
+
+	sinks := make([]string, 0, len(objs))
+	for _, o := range objs {
+		if ms, ok := o.(*v1alpha1.MetricSink); ok {
+			sinks = append(sinks, ms.Name)
+		}
+	}
+	sort.Strings(sinks)
+	return sinks, nil
+}
+
+// SecretDependencies lists either the sinks depending on a secret or the
+// secrets a sink depends on, all in Namespace.
+type SecretDependencies struct {
+	Namespace string   `json:"namespace"`
+	Secret    string   `json:"secret,omitempty"`
+	Sink      string   `json:"sink,omitempty"`
+	Sinks     []string `json:"sinks,omitempty"`
+	Secrets   []string `json:"secrets,omitempty"`
+}
+
+// SecretDependenciesHandler serves the secret dependencies of MetricSinks
+// as JSON. Requests set namespace and either secret, for the sinks
+// depending on it, or sink, for the secrets it depends on.
+func SecretDependenciesHandler(indexer cache.Indexer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		deps := SecretDependencies{
+			Namespace: q.Get("namespace"),
+			Secret:    q.Get("secret"),
+			Sink:      q.Get("sink"),
+		}
+		if deps.Namespace == "" || (deps.Secret == "") == (deps.Sink == "") {
+			http.Error(w, "namespace and one of secret or sink are required", http.StatusBadRequest)
+			return
+		}
+
+		if deps.Secret != "" {
+			sinks, err := DependentSinks(indexer, deps.Namespace, deps.Secret)
+			if err != nil {
+				log.Printf("Unable to look up sinks depending on secret %s/%s: %s", deps.Namespace, deps.Secret, err)
+				http.Error(w, "Unable to look up dependent sinks", http.StatusInternalServerError)
+				return
+			}
+			deps.Sinks = sinks
+		} else {
+			obj, exists, err := indexer.GetByKey(deps.Namespace + "/" + deps.Sink)
+			if err != nil {
+				log.Printf("Unable to get MetricSink %s/%s: %s", deps.Namespace, deps.Sink, err)
+				http.Error(w, "Unable to get sink", http.StatusInternalServerError)
+				return
+			}
+			ms, ok := obj.(*v1alpha1.MetricSink)
+			if !exists || !ok {
+				http.Error(w, "Sink not found", http.StatusNotFound)
+				return
+			}
+			deps.Secrets = SinkSecrets(ms)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(deps)
+		if err != nil {
+			log.Printf("Unable to marshal secret dependencies: %s", err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestSecretDependencies(t *testing.T) {
+	newIndexer := func() cache.Indexer {
+		return cache.NewIndexer(cache.MetaNamespaceKeyFunc, metric.SecretIndexers)
+	}
+
+	t.Run("it finds the sinks referencing a secret", func(t *testing.T) {
+		indexer := newIndexer()
+		addSink(t, indexer, secretSink("ns1", "bearer", tokenAuth("shared")))
+		addSink(t, indexer, secretSink("ns1", "basic", basicAuth("shared")))
+		addSink(t, indexer, secretSink("ns1", "other", tokenAuth("other")))
+		addSink(t, indexer, secretSink("ns2", "bearer", tokenAuth("shared")))
+		addSink(t, indexer, secretSink("ns1", "no-auth", nil))
+
+		expectDependents(t, indexer, "ns1", "shared", []string{"basic", "bearer"})
+		expectDependents(t, indexer, "ns2", "shared", []string{"bearer"})
+		expectDependents(t, indexer, "ns1", "missing", []string{})
+	})
+
+	t.Run("it moves a sink to the secrets of its update", func(t *testing.T) {
+		indexer := newIndexer()
+		addSink(t, indexer, secretSink("ns1", "sink", tokenAuth("old")))
+
+		err := indexer.Update(secretSink("ns1", "sink", basicAuth("new")))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectDependents(t, indexer, "ns1", "old", []string{})
+		expectDependents(t, indexer, "ns1", "new", []string{"sink"})
+	})
+
+	t.Run("it removes deleted sinks", func(t *testing.T) {
+		indexer := newIndexer()
+		s := secretSink("ns1", "sink", tokenAuth("secret"))
+		addSink(t, indexer, s)
+
+		if err := indexer.Delete(s); err != nil {
+			t.Fatal(err)
+		}
+
+		expectDependents(t, indexer, "ns1", "secret", []string{})
+	})
+
+	t.Run("it lists a sink's secrets once", func(t *testing.T) {
+		auth := basicAuth("password")
+		auth.BearerTokenSecretRef = tokenAuth("token").BearerTokenSecretRef
+		s := secretSink("ns1", "sink", auth)
+		s.Spec.ScrapeAuth.BasicAuth.PasswordSecretRef.Name = "token"
+
+		expected := []string{"token"}
+		if diff := cmp.Diff(expected, metric.SinkSecrets(s)); diff != "" {
+			t.Errorf("Secrets not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestSecretDependenciesHandler(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, metric.SecretIndexers)
+	addSink(t, indexer, secretSink("ns1", "sink", tokenAuth("secret")))
+	h := metric.SecretDependenciesHandler(indexer)
+
+	t.Run("it returns the sinks depending on a secret", func(t *testing.T) {
+		deps := getDependencies(t, h, "/secrets?namespace=ns1&secret=secret", http.StatusOK)
+
+		if diff := cmp.Diff([]string{"sink"}, deps.Sinks); diff != "" {
+			t.Errorf("Sinks not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it returns the secrets a sink depends on", func(t *testing.T) {
+		deps := getDependencies(t, h, "/secrets?namespace=ns1&sink=sink", http.StatusOK)
+
+		if diff := cmp.Diff([]string{"secret"}, deps.Secrets); diff != "" {
+			t.Errorf("Secrets not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it returns not found for unknown sinks", func(t *testing.T) {
+		getDependencies(t, h, "/secrets?namespace=ns1&sink=missing", http.StatusNotFound)
+	})
+
+	t.Run("it requires a namespace and one of secret or sink", func(t *testing.T) {
+		getDependencies(t, h, "/secrets?secret=secret", http.StatusBadRequest)
+		getDependencies(t, h, "/secrets?namespace=ns1", http.StatusBadRequest)
+		getDependencies(t, h, "/secrets?namespace=ns1&secret=secret&sink=sink", http.StatusBadRequest)
+	})
+}
+
+func addSink(t *testing.T, indexer cache.Indexer, ms *sinkv1alpha1.MetricSink) {
+	if err := indexer.Add(ms); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func expectDependents(t *testing.T, indexer cache.Indexer, namespace, secret string, expected []string) {
+	t.Helper()
+	sinks, err := metric.DependentSinks(indexer, namespace, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, sinks); diff != "" {
+		t.Errorf("Sinks depending on %s/%s not equal (-want, +got) = %v", namespace, secret, diff)
+	}
+}
+
+func getDependencies(t *testing.T, h http.Handler, target string, status int) metric.SecretDependencies {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != status {
+		t.Fatalf("Expected status %d, got %d", status, rec.Code)
+	}
+
+	var deps metric.SecretDependencies
+	if status == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &deps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return deps
+}
+
+func secretSink(namespace, name string, auth *sinkv1alpha1.ScrapeAuth) *sinkv1alpha1.MetricSink {
+	return &sinkv1alpha1.MetricSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: sinkv1alpha1.MetricSinkSpec{
+			ScrapeAuth: auth,
+		},
+	}
+}
+
+func tokenAuth(secret string) *sinkv1alpha1.ScrapeAuth {
+	return &sinkv1alpha1.ScrapeAuth{
+		BearerTokenSecretRef: &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: secret},
+			Key:                  "token",
+		},
+	}
+}
+
+func basicAuth(secret string) *sinkv1alpha1.ScrapeAuth {
+	return &sinkv1alpha1.ScrapeAuth{
+		BasicAuth: &sinkv1alpha1.BasicAuth{
+			Username: "user",
+			PasswordSecretRef: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: secret},
+				Key:                  "password",
+			},
+		},
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const DefaultStatsDProtocol = "udp"
+
+// addStatsD adds the statsd input of a MetricSink to the config.
+func addStatsD(config *telegrafConfig, statsd *v1alpha1.StatsD) {
+	if statsd == nil {
+		return
+	}
+
+	input := map[string]interface{}{
+		"service_address": fmt.Sprintf(":%d", statsd.Port),
+		"protocol":        statsdProtocol(statsd),
+	}
+	if len(statsd.Templates) > 0 {
+		input["templates"] = statsd.Templates
+	}
+	if statsd.MetricSeparator != "" {
+		input["metric_separator"] = statsd.MetricSeparator
+	}
+	if statsd.ParseDataDogTags {
+		input["parse_data_dog_tags"] = true
+	}
+	config.Inputs["statsd"] = append(config.Inputs["statsd"], input)
+}
+
+func statsdProtocol(statsd *v1alpha1.StatsD) string {
+	if statsd.Protocol == "" {
+		return DefaultStatsDProtocol
+	}
+	return statsd.Protocol
+}
+
+// statsdPorts returns the ports of the telegraf container that receive
+// statsd metrics.
+func statsdPorts(statsd *v1alpha1.StatsD) []v1.ContainerPort {
+	if statsd == nil {
+		return nil
+	}
+
+	return []v1.ContainerPort{{
+		Name:          "statsd",
+		ContainerPort: int32(statsd.Port),
+		Protocol:      v1.Protocol(strings.ToUpper(statsdProtocol(statsd))),
+	}}
+}
+
+// getTelegrafService returns the service that exposes the statsd port of
+// the MetricSink's telegraf pods.
+func getTelegrafService(ms *v1alpha1.MetricSink) *v1.Service {
+	name := getAppName(ms)
+	return &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ms.Namespace,
+			Labels:    map[string]string{"app": name},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: ms.APIVersion,
+				Kind:       ms.Kind,
+				Name:       ms.Name,
+				UID:        ms.UID,
+			}},
+		},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"app": name},
+			Ports: []v1.ServicePort{{
+				Name:       "statsd",
+				Port:       int32(ms.Spec.StatsD.Port),
+				TargetPort: intstr.FromString("statsd"),
+				Protocol:   v1.Protocol(strings.ToUpper(statsdProtocol(ms.Spec.StatsD))),
+			}},
+		},
+	}
+}
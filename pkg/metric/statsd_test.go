@@ -0,0 +1,313 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestStatsD(t *testing.T) {
+	statsdSink := func(statsd *sinkv1alpha1.StatsD) *sinkv1alpha1.MetricSink {
+		return &sinkv1alpha1.MetricSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-metric-sink",
+				Namespace: "test-namespace",
+				UID:       "some-random-uid",
+			},
+			Spec: sinkv1alpha1.MetricSinkSpec{
+				Outputs: []sinkv1alpha1.MetricSinkMap{{
+					"type":   "datadog",
+					"apikey": "some-key",
+				}},
+				StatsD: statsd,
+			},
+		}
+	}
+
+	newSpyClients := func() (*spyCoreV1Client, *spyAppsV1Client, *spyRBACV1Client) {
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				createFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				createFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					return d, nil
+				},
+			},
+		}
+		spyRBACClient := &spyRBACV1Client{
+			spyRoleCUDer: spyRoleCUDer{
+				createFunc: func(r *rbacv1.Role) (*rbacv1.Role, error) {
+					return r, nil
+				},
+			},
+			spyRoleBindingCUDer: spyRoleBindingCUDer{
+				createFunc: func(rb *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+					return rb, nil
+				},
+			},
+		}
+		return spyCoreClient, spyExtensionsClient, spyRBACClient
+	}
+
+	t.Run("it renders the statsd input", func(t *testing.T) {
+		tests := []struct {
+			name           string
+			statsd         *sinkv1alpha1.StatsD
+			expectedConfig string
+		}{
+			{
+				name:   "defaults",
+				statsd: &sinkv1alpha1.StatsD{Port: 8125},
+				expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+
+  [[inputs.statsd]]
+    protocol = "udp"
+    service_address = ":8125"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			},
+			{
+				name: "parsing rules",
+				statsd: &sinkv1alpha1.StatsD{
+					Port:             9125,
+					Protocol:         "tcp",
+					Templates:        []string{"*.app env.service.measurement*", "measurement.field"},
+					MetricSeparator:  "_",
+					ParseDataDogTags: true,
+				},
+				expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+
+  [[inputs.statsd]]
+    metric_separator = "_"
+    parse_data_dog_tags = true
+    protocol = "tcp"
+    service_address = ":9125"
+    templates = ["*.app env.service.measurement*", "measurement.field"]
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				var receivedCM v1.ConfigMap
+				spyCoreClient, spyExtensionsClient, spyRBACClient := newSpyClients()
+				spyCoreClient.spyConfigMapCUDer.createFunc = func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					receivedCM = *cm
+					return cm, nil
+				}
+				spyCoreClient.spyServiceCUDer.createFunc = func(svc *v1.Service) (*v1.Service, error) {
+					return svc, nil
+				}
+
+				c := metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient)
+				c.OnAdd(statsdSink(test.statsd))
+
+				if diff := cmp.Diff(test.expectedConfig, receivedCM.Data["metric-sinks.conf"]); diff != "" {
+					t.Errorf("Config not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it creates a service for the statsd port", func(t *testing.T) {
+		var (
+			receivedService    *v1.Service
+			receivedDeployment appsv1.Deployment
+		)
+		spyCoreClient, spyExtensionsClient, spyRBACClient := newSpyClients()
+		spyCoreClient.spyServiceCUDer.createFunc = func(svc *v1.Service) (*v1.Service, error) {
+			receivedService = svc
+			return svc, nil
+		}
+		spyExtensionsClient.spyTelegrafDeploymentCUDer.createFunc = func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+			receivedDeployment = *d
+			return d, nil
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient)
+		c.OnAdd(statsdSink(&sinkv1alpha1.StatsD{Port: 8125}))
+
+		expectedService := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "telegraf-test-metric-sink",
+				Namespace: "test-namespace",
+				Labels:    map[string]string{"app": "telegraf-test-metric-sink"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "observability.knative.dev/v1alpha1",
+					Kind:       "MetricSink",
+					Name:       "test-metric-sink",
+					UID:        "some-random-uid",
+				}},
+			},
+			Spec: v1.ServiceSpec{
+				Selector: map[string]string{"app": "telegraf-test-metric-sink"},
+				Ports: []v1.ServicePort{{
+					Name:       "statsd",
+					Port:       8125,
+					TargetPort: intstr.FromString("statsd"),
+					Protocol:   v1.ProtocolUDP,
+				}},
+			},
+		}
+		if diff := cmp.Diff(expectedService, receivedService); diff != "" {
+			t.Errorf("Service not equal (-want, +got) = %v", diff)
+		}
+
+		expectedPorts := []v1.ContainerPort{{
+			Name:          "statsd",
+			ContainerPort: 8125,
+			Protocol:      v1.ProtocolUDP,
+		}}
+		if diff := cmp.Diff(expectedPorts, receivedDeployment.Spec.Template.Spec.Containers[0].Ports); diff != "" {
+			t.Errorf("Ports not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not create a service without statsd", func(t *testing.T) {
+		spyCoreClient, spyExtensionsClient, spyRBACClient := newSpyClients()
+		spyCoreClient.spyServiceCUDer.createFunc = func(svc *v1.Service) (*v1.Service, error) {
+			t.Fatal("should not be called")
+			return nil, nil
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient)
+		c.OnAdd(statsdSink(nil))
+	})
+
+	t.Run("it recreates the service when the statsd input changes", func(t *testing.T) {
+		var (
+			deletedService    string
+			receivedService   *v1.Service
+			deploymentUpdated bool
+		)
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+			spyServiceCUDer: spyServiceCUDer{
+				createFunc: func(svc *v1.Service) (*v1.Service, error) {
+					receivedService = svc
+					return svc, nil
+				},
+				deleteFunc: func(name string, _ *metav1.DeleteOptions) error {
+					deletedService = name
+					return nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				updateFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					deploymentUpdated = true
+					return d, nil
+				},
+			},
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, nil)
+		oms := statsdSink(&sinkv1alpha1.StatsD{Port: 8125})
+		nms := statsdSink(&sinkv1alpha1.StatsD{Port: 9125, Protocol: "tcp"})
+		c.OnUpdate(oms, nms)
+
+		if deletedService != "telegraf-test-metric-sink" {
+			t.Errorf("Expected the old service to be deleted, got %q", deletedService)
+		}
+		if receivedService == nil {
+			t.Fatal("Expected a new service to be created")
+		}
+		expectedPorts := []v1.ServicePort{{
+			Name:       "statsd",
+			Port:       9125,
+			TargetPort: intstr.FromString("statsd"),
+			Protocol:   v1.ProtocolTCP,
+		}}
+		if diff := cmp.Diff(expectedPorts, receivedService.Spec.Ports); diff != "" {
+			t.Errorf("Ports not equal (-want, +got) = %v", diff)
+		}
+		if !deploymentUpdated {
+			t.Error("Expected the deployment to be updated")
+		}
+	})
+
+	t.Run("it deletes the service when statsd is removed", func(t *testing.T) {
+		var deletedService string
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+			spyServiceCUDer: spyServiceCUDer{
+				createFunc: func(svc *v1.Service) (*v1.Service, error) {
+					t.Fatal("should not be called")
+					return nil, nil
+				},
+				deleteFunc: func(name string, _ *metav1.DeleteOptions) error {
+					deletedService = name
+					return nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				updateFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					return d, nil
+				},
+			},
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, nil)
+		c.OnUpdate(statsdSink(&sinkv1alpha1.StatsD{Port: 8125}), statsdSink(nil))
+
+		if deletedService != "telegraf-test-metric-sink" {
+			t.Errorf("Expected the service to be deleted, got %q", deletedService)
+		}
+	})
+}
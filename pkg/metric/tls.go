@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric
+
+import (
+	"path"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// The secrets referenced by a ScrapeTLS are projected into a single volume
+// of the telegraf pods. telegraf reads the keys as files, so they are never
+// written to the configmap.
+const (
+	scrapeTLSVolume = "scrape-tls"
+	scrapeTLSDir    = "/etc/telegraf-tls"
+	scrapeTLSCA     = "ca.crt"
+	scrapeTLSCert   = "tls.crt"
+	scrapeTLSKey    = "tls.key"
+)
+
+// addScrapeTLS sets the tls options of the prometheus input.
+func addScrapeTLS(input map[string]interface{}, tls *v1alpha1.ScrapeTLS) {
+	if tls == nil {
+		return
+	}
+
+	if tls.CASecretRef != nil {
+		input["tls_ca"] = path.Join(scrapeTLSDir, scrapeTLSCA)
+	}
+	if tls.CertSecretRef != nil {
+		input["tls_cert"] = path.Join(scrapeTLSDir, scrapeTLSCert)
+	}
+	if tls.KeySecretRef != nil {
+		input["tls_key"] = path.Join(scrapeTLSDir, scrapeTLSKey)
+	}
+	if tls.InsecureSkipVerify {
+		input["insecure_skip_verify"] = true
+	}
+}
+
+// scrapeTLSVolumes returns the volume of the telegraf pods that holds the
+// secrets referenced by the ScrapeTLS.
+func scrapeTLSVolumes(tls *v1alpha1.ScrapeTLS) []v1.Volume {
+	sources := scrapeTLSSources(tls)
+	if len(sources) == 0 {
+		return nil
+	}
+
+	return []v1.Volume{{
+		Name: scrapeTLSVolume,
+		VolumeSource: v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: sources,
+			},
+		},
+	}}
+}
+
+// scrapeTLSVolumeMounts mounts the volume of scrapeTLSVolumes into the
+// telegraf container.
+func scrapeTLSVolumeMounts(tls *v1alpha1.ScrapeTLS) []v1.VolumeMount {
+	if len(scrapeTLSSources(tls)) == 0 {
+		return nil
+	}
+
+	return []v1.VolumeMount{{
+		Name:      scrapeTLSVolume,
+		MountPath: scrapeTLSDir,
+		ReadOnly:  true,
+	}}
+}
+
+func scrapeTLSSources(tls *v1alpha1.ScrapeTLS) []v1.VolumeProjection {
+	if tls == nil {
+		return nil
+	}
+
+	var sources []v1.VolumeProjection
+	for _, f := range []struct {
+		ref  *v1.SecretKeySelector
+		path string
+	}{
+		{tls.CASecretRef, scrapeTLSCA},
+		{tls.CertSecretRef, scrapeTLSCert},
+		{tls.KeySecretRef, scrapeTLSKey},
+	} {
+		if f.ref == nil {
+			continue
+		}
+		sources = append(sources, v1.VolumeProjection{
+			Secret: &v1.SecretProjection{
+				LocalObjectReference: f.ref.LocalObjectReference,
+				Items: []v1.KeyToPath{{
+					Key:  f.ref.Key,
+					Path: f.path,
+				}},
+			},
+		})
+	}
+	return sources
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metric_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sinkv1alpha1 "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/metric"
+)
+
+func TestScrapeTLS(t *testing.T) {
+	secretRef := func(name, key string) *v1.SecretKeySelector {
+		return &v1.SecretKeySelector{
+			LocalObjectReference: v1.LocalObjectReference{Name: name},
+			Key:                  key,
+		}
+	}
+	mtls := &sinkv1alpha1.ScrapeTLS{
+		CASecretRef:   secretRef("scrape-ca", "ca.pem"),
+		CertSecretRef: secretRef("scrape-client", "cert.pem"),
+		KeySecretRef:  secretRef("scrape-client", "key.pem"),
+	}
+	tlsSink := func(tls *sinkv1alpha1.ScrapeTLS) *sinkv1alpha1.MetricSink {
+		return &sinkv1alpha1.MetricSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-metric-sink",
+				Namespace: "test-namespace",
+			},
+			Spec: sinkv1alpha1.MetricSinkSpec{
+				Outputs: []sinkv1alpha1.MetricSinkMap{{
+					"type":   "datadog",
+					"apikey": "some-key",
+				}},
+				ScrapeTLS: tls,
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		tls            *sinkv1alpha1.ScrapeTLS
+		expectedConfig string
+		expectedMounts []v1.VolumeMount
+		expectedSource []v1.VolumeProjection
+	}{
+		{
+			name: "mutual tls",
+			tls:  mtls,
+			expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+    tls_ca = "/etc/telegraf-tls/ca.crt"
+    tls_cert = "/etc/telegraf-tls/tls.crt"
+    tls_key = "/etc/telegraf-tls/tls.key"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			expectedMounts: []v1.VolumeMount{
+				{Name: "telegraf-config", MountPath: "/etc/telegraf"},
+				{Name: "scrape-tls", MountPath: "/etc/telegraf-tls", ReadOnly: true},
+			},
+			expectedSource: []v1.VolumeProjection{
+				{Secret: &v1.SecretProjection{
+					LocalObjectReference: v1.LocalObjectReference{Name: "scrape-ca"},
+					Items:                []v1.KeyToPath{{Key: "ca.pem", Path: "ca.crt"}},
+				}},
+				{Secret: &v1.SecretProjection{
+					LocalObjectReference: v1.LocalObjectReference{Name: "scrape-client"},
+					Items:                []v1.KeyToPath{{Key: "cert.pem", Path: "tls.crt"}},
+				}},
+				{Secret: &v1.SecretProjection{
+					LocalObjectReference: v1.LocalObjectReference{Name: "scrape-client"},
+					Items:                []v1.KeyToPath{{Key: "key.pem", Path: "tls.key"}},
+				}},
+			},
+		},
+		{
+			name: "skipping verification",
+			tls:  &sinkv1alpha1.ScrapeTLS{InsecureSkipVerify: true},
+			expectedConfig: `[inputs]
+
+  [[inputs.prometheus]]
+    insecure_skip_verify = true
+    monitor_kubernetes_pods = true
+    monitor_kubernetes_pods_namespace = "test-namespace"
+
+[outputs]
+
+  [[outputs.datadog]]
+    apikey = "some-key"
+`,
+			expectedMounts: []v1.VolumeMount{
+				{Name: "telegraf-config", MountPath: "/etc/telegraf"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				receivedCM         v1.ConfigMap
+				receivedDeployment appsv1.Deployment
+			)
+			spyCoreClient := &spyCoreV1Client{
+				spyConfigMapCUDer: spyConfigMapCUDer{
+					createFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+						receivedCM = *cm
+						return cm, nil
+					},
+				},
+			}
+			spyExtensionsClient := &spyAppsV1Client{
+				spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+					createFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+						receivedDeployment = *d
+						return d, nil
+					},
+				},
+			}
+			spyRBACClient := &spyRBACV1Client{
+				spyRoleCUDer: spyRoleCUDer{
+					createFunc: func(r *rbacv1.Role) (*rbacv1.Role, error) {
+						return r, nil
+					},
+				},
+				spyRoleBindingCUDer: spyRoleBindingCUDer{
+					createFunc: func(rb *rbacv1.RoleBinding) (*rbacv1.RoleBinding, error) {
+						return rb, nil
+					},
+				},
+			}
+
+			c := metric.NewController("", spyCoreClient, spyExtensionsClient, spyRBACClient)
+			c.OnAdd(tlsSink(test.tls))
+
+			if diff := cmp.Diff(test.expectedConfig, receivedCM.Data["metric-sinks.conf"]); diff != "" {
+				t.Errorf("Config not equal (-want, +got) = %v", diff)
+			}
+			pod := receivedDeployment.Spec.Template.Spec
+			if diff := cmp.Diff(test.expectedMounts, pod.Containers[0].VolumeMounts); diff != "" {
+				t.Errorf("Volume mounts not equal (-want, +got) = %v", diff)
+			}
+			var sources []v1.VolumeProjection
+			for _, v := range pod.Volumes {
+				if v.Name == "scrape-tls" {
+					sources = v.Projected.Sources
+				}
+			}
+			if diff := cmp.Diff(test.expectedSource, sources); diff != "" {
+				t.Errorf("Volume sources not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+
+	t.Run("it updates the deployment when the tls changes", func(t *testing.T) {
+		var deploymentUpdated bool
+		spyCoreClient := &spyCoreV1Client{
+			spyConfigMapCUDer: spyConfigMapCUDer{
+				updateFunc: func(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+					return cm, nil
+				},
+			},
+		}
+		spyExtensionsClient := &spyAppsV1Client{
+			spyTelegrafDeploymentCUDer: spyTelegrafDeploymentCUDer{
+				updateFunc: func(d *appsv1.Deployment) (*appsv1.Deployment, error) {
+					deploymentUpdated = true
+					return d, nil
+				},
+			},
+		}
+
+		c := metric.NewController("", spyCoreClient, spyExtensionsClient, nil)
+		c.OnUpdate(tlsSink(nil), tlsSink(mtls))
+
+		if !deploymentUpdated {
+			t.Error("Expected the deployment to be updated")
+		}
+	})
+
+	t.Run("it lists the tls secrets", func(t *testing.T) {
+		s := tlsSink(mtls)
+		s.Spec.ScrapeAuth = tokenAuth("scrape-client")
+
+		expected := []string{"scrape-ca", "scrape-client"}
+		if diff := cmp.Diff(expected, metric.SinkSecrets(s)); diff != "" {
+			t.Errorf("Secrets not equal (-want, +got) = %v", diff)
+		}
+	})
+}
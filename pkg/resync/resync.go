@@ -0,0 +1,76 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Package resync sets the resync periods of the informers of the
+// observability resources.
+package resync
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned"
+	"github.com/knative/observability/pkg/client/informers/externalversions"
+)
+
+// Default is the resync period of informers without one of their own.
+const Default = 30 * time.Second
+
+// Minimum is the shortest period informers resync at. Shorter periods are
+// raised to it by client-go.
+const Minimum = time.Second
+
+// Periods are the resync periods of the informers of each kind of sink.
+// Sinks applies to LogSinks and ClusterLogSinks and MetricSinks to
+// MetricSinks and ClusterMetricSinks. A zero period is the Default.
+type Periods struct {
+	Sinks       time.Duration
+	MetricSinks time.Duration
+}
+
+// Validate returns an error for a period below the Minimum.
+// flagName is the flag the period was set with.
+func Validate(flagName string, period time.Duration) error {
+	if period < Minimum {
+		return fmt.Errorf("invalid -%s %s, should be at least %s", flagName, period, Minimum)
+	}
+	return nil
+}
+
+// NewInformerFactory returns an informer factory whose informers resync
+// at the periods of their kind.
+func NewInformerFactory(client versioned.Interface, p Periods) externalversions.SharedInformerFactory {
+	sinks, metricSinks := orDefault(p.Sinks), orDefault(p.MetricSinks)
+	return externalversions.NewSharedInformerFactoryWithOptions(
+		client,
+		Default,
+		externalversions.WithCustomResyncConfig(map[v1.Object]time.Duration{
+			&v1alpha1.LogSink{}:           sinks,
+			&v1alpha1.ClusterLogSink{}:    sinks,
+			&v1alpha1.MetricSink{}:        metricSinks,
+			&v1alpha1.ClusterMetricSink{}: metricSinks,
+		}),
+	)
+}
+
+func orDefault(period time.Duration) time.Duration {
+	if period == 0 {
+		return Default
+	}
+	return period
+}
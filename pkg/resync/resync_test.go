@@ -0,0 +1,92 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package resync_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/resync"
+)
+
+func TestNewInformerFactory(t *testing.T) {
+	t.Run("it resyncs the informers at the periods of their kind", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			&v1alpha1.LogSink{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}},
+			&v1alpha1.ClusterLogSink{ObjectMeta: metav1.ObjectMeta{Name: "platform"}},
+			&v1alpha1.MetricSink{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}},
+		)
+		f := resync.NewInformerFactory(client, resync.Periods{
+			Sinks:       resync.Minimum,
+			MetricSinks: time.Hour,
+		})
+
+		var sinkResyncs, clusterSinkResyncs, metricSinkResyncs int64
+		counting := func(n *int64) cache.ResourceEventHandler {
+			return cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(_, _ interface{}) { atomic.AddInt64(n, 1) },
+			}
+		}
+		v1 := f.Observability().V1alpha1()
+		v1.LogSinks().Informer().AddEventHandler(counting(&sinkResyncs))
+		v1.ClusterLogSinks().Informer().AddEventHandler(counting(&clusterSinkResyncs))
+		v1.MetricSinks().Informer().AddEventHandler(counting(&metricSinkResyncs))
+
+		stopCh := make(chan struct{})
+		defer close(stopCh)
+		f.Start(stopCh)
+		f.WaitForCacheSync(stopCh)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt64(&sinkResyncs) == 0 || atomic.LoadInt64(&clusterSinkResyncs) == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf(
+					"Expected the sinks to be resynced, got %d LogSink and %d ClusterLogSink resyncs",
+					atomic.LoadInt64(&sinkResyncs),
+					atomic.LoadInt64(&clusterSinkResyncs),
+				)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if n := atomic.LoadInt64(&metricSinkResyncs); n != 0 {
+			t.Errorf("Expected the MetricSinks not to be resynced, got %d resyncs", n)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("it accepts periods of at least the minimum", func(t *testing.T) {
+		for _, d := range []time.Duration{resync.Minimum, resync.Default, time.Hour} {
+			if err := resync.Validate("sink-resync", d); err != nil {
+				t.Errorf("Unexpected error for %s: %s", d, err)
+			}
+		}
+	})
+
+	t.Run("it rejects periods below the minimum", func(t *testing.T) {
+		for _, d := range []time.Duration{-time.Second, 0, 500 * time.Millisecond} {
+			if err := resync.Validate("sink-resync", d); err == nil {
+				t.Errorf("Expected an error for %s", d)
+			}
+		}
+	})
+}
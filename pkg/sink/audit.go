@@ -0,0 +1,198 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// The defaults of a sink's AuditLogSpec. The parser is defined in
+// parsers.conf of the fluent-bit configmap.
+const (
+	DefaultAuditLogPath = "/var/log/kubernetes/audit.log"
+	AuditParser         = "k8s-audit"
+)
+
+var defaultAuditNodeSelector = map[string]string{
+	"node-role.kubernetes.io/master": "",
+}
+
+const auditInputConfig = `
+[INPUT]
+    Name tail
+    Tag %s
+    Alias %s
+    Path %s
+    Parser %s
+    DB /var/log/flb_%s.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+%s`
+
+// auditLuaTemplate drops the audit records read on nodes the sink's node
+// selector does not match. Every fluent-bit pod runs the same config, so
+// the pods compare the NODE_NAME they are given by the daemonset against
+// the names of the selected nodes.
+const auditLuaTemplate = `
+local %[1]s_nodes = {%[2]s}
+
+function %[1]s(tag, timestamp, record)
+    if not %[1]s_nodes[os.getenv("NODE_NAME")] then
+        return -1, timestamp, record
+    end
+    return 0, timestamp, record
+end
+`
+
+// SetNode records the labels of a node. It returns whether the rendered
+// config changed, which is only the case when the node's labels changed
+// whether an audit sink or a sink with a node selector selects it.
+func (sc *Config) SetNode(name string, nodeLabels map[string]string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	old, ok := sc.nodes[name]
+	sc.nodes[name] = nodeLabels
+	return sc.auditSelectionChanged(old, ok, nodeLabels, true) ||
+		sc.nodeSelectionChanged(old, ok, nodeLabels, true)
+}
+
+// DeleteNode removes a node. It returns whether the rendered config
+// changed.
+func (sc *Config) DeleteNode(name string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	old, ok := sc.nodes[name]
+	delete(sc.nodes, name)
+	return sc.auditSelectionChanged(old, ok, nil, false) ||
+		sc.nodeSelectionChanged(old, ok, nil, false)
+}
+
+func (sc *Config) auditSelectionChanged(old map[string]string, existed bool, cur map[string]string, exists bool) bool {
+	for _, cs := range sc.clusterSinks {
+		if cs.Spec.AuditLog == nil {
+			continue
+		}
+		selector := auditNodeSelector(cs.Spec.AuditLog)
+		was := existed && selector.Matches(labels.Set(old))
+		is := exists && selector.Matches(labels.Set(cur))
+		if was != is {
+			return true
+		}
+	}
+	return false
+}
+
+// audited reports whether ref reads the audit log instead of container
+// logs. Syslog outputs route records by namespace rather than by tag, so
+// only cluster sinks of other types can read it.
+func audited(ref sinkRef) bool {
+	return ref.cluster && ref.spec.AuditLog != nil && ref.spec.Type != "syslog"
+}
+
+// auditTag is the tag of the records read by an audit sink's input. It has
+// no underscores, so other cluster sinks do not match the audit log.
+func auditTag(ref sinkRef) string {
+	return fmt.Sprintf("audit.cluster.%s", ref.name)
+}
+
+// auditConfig renders the tail input of every audit sink followed by the
+// filter that drops the records read on nodes it does not select.
+func (sc *Config) auditConfig() string {
+	var config string
+	for i, ref := range sc.auditSinkRefs() {
+		tag := auditTag(ref)
+		config += fmt.Sprintf(
+			auditInputConfig,
+			tag,
+			inputAlias(ref, "audit"),
+			auditPath(ref.spec.AuditLog),
+			auditParser(ref.spec.AuditLog),
+			tag,
+			ignoreOlderConfig(ref.spec.SkipLogsOlderThan)+sc.inputStorageConfig(),
+		)
+		config += fmt.Sprintf(luaFilterConfig, tag, auditLuaFuncName(i))
+	}
+	return config
+}
+
+// auditLua renders the functions of the filters rendered by auditConfig.
+func (sc *Config) auditLua() string {
+	var script string
+	for i, ref := range sc.auditSinkRefs() {
+		nodes := sc.selectedNodes(auditNodeSelector(ref.spec.AuditLog))
+		entries := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			entries = append(entries, fmt.Sprintf("[%q] = true", n))
+		}
+		script += fmt.Sprintf(auditLuaTemplate, auditLuaFuncName(i), strings.Join(entries, ", "))
+	}
+	return script
+}
+
+func (sc *Config) auditSinkRefs() []sinkRef {
+	var refs []sinkRef
+	for _, ref := range sc.filteredSinkRefs() {
+		if audited(ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// selectedNodes returns the sorted names of the nodes selector matches.
+func (sc *Config) selectedNodes(selector labels.Selector) []string {
+	var nodes []string
+	for name, l := range sc.nodes {
+		if selector.Matches(labels.Set(l)) {
+			nodes = append(nodes, name)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func auditLuaFuncName(i int) string {
+	return fmt.Sprintf("audit_%d", i)
+}
+
+func auditPath(spec *v1alpha1.AuditLogSpec) string {
+	if spec.Path == "" {
+		return DefaultAuditLogPath
+	}
+	return spec.Path
+}
+
+func auditParser(spec *v1alpha1.AuditLogSpec) string {
+	if spec.Parser == "" {
+		return AuditParser
+	}
+	return spec.Parser
+}
+
+func auditNodeSelector(spec *v1alpha1.AuditLogSpec) labels.Selector {
+	if len(spec.NodeSelector) == 0 {
+		return labels.SelectorFromSet(defaultAuditNodeSelector)
+	}
+	return labels.SelectorFromSet(spec.NodeSelector)
+}
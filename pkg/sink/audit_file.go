@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// auditFilesName is the key of the fluent-bit configmap listing the audit
+// files to rotate. The rotator container of the daemonset reads one
+// "path rotation_count max_size" line per file.
+const auditFilesName = "audit-files.conf"
+
+// DefaultAuditFileRotation is the rotation of audit files that do not set
+// one.
+var DefaultAuditFileRotation = v1alpha1.FileRotation{
+	RotationCount: 5,
+	MaxSize:       100 * 1024 * 1024,
+}
+
+const auditFileOutputConfig = `
+[OUTPUT]
+    Name file
+    Match %s
+    Path %s
+    File %s
+    Format plain
+`
+
+// auditFileConfig renders a file output next to the output of every sink
+// with an audit file. The output matches the same records as the sink, so
+// the file holds what the sink shipped. Sinks with an open circuit ship
+// nothing and get no file output.
+func (sc *Config) auditFileConfig() string {
+	var config string
+	for _, ref := range sc.filteredSinkRefs() {
+		if ref.spec.AuditFile == nil || sc.openCircuits[ref.key] {
+			continue
+		}
+
+		dir, file := path.Split(ref.spec.AuditFile.Path)
+		config += fmt.Sprintf(auditFileOutputConfig, sinkMatch(ref), path.Clean(dir), file)
+	}
+
+	return config
+}
+
+// auditFilesConfig lists the rotation of every audit file. A file written
+// by several sinks of the same namespace is listed once, with the rotation
+// of the first of them. The rotator creates the directories of the files.
+func (sc *Config) auditFilesConfig() string {
+	rotations := make(map[string]v1alpha1.FileRotation)
+	var paths []string
+	for _, ref := range sc.filteredSinkRefs() {
+		af := ref.spec.AuditFile
+		if af == nil {
+			continue
+		}
+		if _, ok := rotations[af.Path]; ok {
+			continue
+		}
+
+		rotation := DefaultAuditFileRotation
+		if af.Rotation != nil {
+			rotation = *af.Rotation
+		}
+		rotations[af.Path] = rotation
+		paths = append(paths, af.Path)
+	}
+	sort.Strings(paths)
+
+	var config string
+	for _, p := range paths {
+		r := rotations[p]
+		config += fmt.Sprintf("%s %d %d\n", p, r.RotationCount, r.MaxSize)
+	}
+
+	return config
+}
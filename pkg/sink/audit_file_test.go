@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestAuditFile(t *testing.T) {
+	auditedSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "http://example.com/place",
+			},
+			AuditFile: &v1alpha1.AuditFileSpec{
+				Path: "/var/log/observability/some-sink.log",
+			},
+		},
+	}
+
+	t.Run("it writes a file next to the sink's output", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(auditedSink)
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				AuditFile: &v1alpha1.AuditFileSpec{
+					Path: "/var/log/observability/cluster.log",
+				},
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_*
+    Alias cluster:cluster-sink
+    InstanceName cluster:cluster-sink
+    Addr example.com:12345
+    Cluster true
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/some-sink
+    Format json
+    Host example.com
+    Port 80
+    URI /place
+
+
+[OUTPUT]
+    Name file
+    Match *_*
+    Path /var/log/observability
+    File cluster.log
+    Format plain
+
+[OUTPUT]
+    Name file
+    Match *_ns1_*
+    Path /var/log/observability
+    File some-sink.log
+    Format plain
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it writes the records of raw mode sinks", func(t *testing.T) {
+		s := auditedSink.DeepCopy()
+		s.Spec.RawMode = true
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		if config := sc.String(); strings.Count(config, "Match raw.ns.ns1.some-sink") != 2 {
+			t.Errorf("Expected the file to match the raw tag, got %s", config)
+		}
+	})
+
+	t.Run("it does not write a file while the circuit is open", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(auditedSink)
+		b := sink.NewBreaker(
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			fake.NewSimpleClientset(auditedSink).ObservabilityV1alpha1(),
+			sc,
+			sink.WithFailureThreshold(1),
+		)
+
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink": {}})
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink": {Errors: 1}})
+
+		if config := sc.String(); strings.Contains(config, "Name file") {
+			t.Errorf("Expected no file output, got %s", config)
+		}
+	})
+
+	t.Run("it patches the rotation of the files", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		sc := sink.NewConfig()
+		c := sink.NewController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(auditedSink)
+		rotated := auditedSink.DeepCopy()
+		rotated.Name = "rotated-sink"
+		rotated.Spec.AuditFile = &v1alpha1.AuditFileSpec{
+			Path: "/var/log/observability/rotated.log",
+			Rotation: &v1alpha1.FileRotation{
+				RotationCount: 3,
+				MaxSize:       1024,
+			},
+		}
+		c.OnAdd(rotated)
+		shared := auditedSink.DeepCopy()
+		shared.Name = "shared-sink"
+		c.OnAdd(shared)
+
+		expected := jsonPatch{
+			Op:   "add",
+			Path: "/data/audit-files.conf",
+			Value: `/var/log/observability/rotated.log 3 1024
+/var/log/observability/some-sink.log 5 104857600
+`,
+		}
+		if diff := cmp.Diff(expected, findPatch(lastPatch(t, spyPatcher), "/data/audit-files.conf")); diff != "" {
+			t.Errorf("Patch not equal (-want, +got) = %v", diff)
+		}
+	})
+}
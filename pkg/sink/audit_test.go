@@ -0,0 +1,252 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestAuditLog(t *testing.T) {
+	auditSink := func(audit *v1alpha1.AuditLogSpec) *v1alpha1.ClusterLogSink {
+		return &v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "audit"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/audit",
+				},
+				AuditLog: audit,
+			},
+		}
+	}
+	master := map[string]string{"node-role.kubernetes.io/master": ""}
+
+	t.Run("it tails the audit log for the sink's output", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+
+		expected := `
+[INPUT]
+    Name tail
+    Tag audit.cluster.audit
+    Alias cluster:audit:audit
+    Path /var/log/kubernetes/audit.log
+    Parser k8s-audit
+    DB /var/log/flb_audit.cluster.audit.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+
+[FILTER]
+    Name lua
+    Match audit.cluster.audit
+    script /fluent-bit/etc/sinks.lua
+    call audit_0
+
+[OUTPUT]
+    Name http
+    Match audit.cluster.audit
+    Alias cluster:audit
+    Format json
+    Host example.com
+    Port 443
+    URI /audit
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not send the audit log to other cluster sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "containers"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/containers",
+				},
+			},
+		})
+
+		config := sc.String()
+		expected := "Name http\n    Match *_*\n    Alias cluster:containers\n    Format json\n    Host example.com\n    Port 443\n    URI /containers\n"
+		if !strings.Contains(config, expected) {
+			t.Fatalf("Expected the container sink to match the container logs, got %s", config)
+		}
+		if ok, _ := path.Match("*_*", "audit.cluster.audit"); ok {
+			t.Error("Expected the container sink not to match the audit log")
+		}
+	})
+
+	t.Run("it uses the configured path and parser", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{
+			Path:   "/var/log/kube-apiserver/audit.log",
+			Parser: "json",
+		}))
+
+		config := sc.String()
+		if !strings.Contains(config, "Path /var/log/kube-apiserver/audit.log\n    Parser json\n") {
+			t.Errorf("Expected the configured path and parser, got %s", config)
+		}
+	})
+
+	t.Run("it only keeps the records of selected nodes", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+		sc.SetNode("master-b", master)
+		sc.SetNode("worker", map[string]string{"node-role.kubernetes.io/worker": ""})
+		sc.SetNode("master-a", master)
+
+		expected := `
+local audit_0_nodes = {["master-a"] = true, ["master-b"] = true}
+
+function audit_0(tag, timestamp, record)
+    if not audit_0_nodes[os.getenv("NODE_NAME")] then
+        return -1, timestamp, record
+    end
+    return 0, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+	})
+
+	t.Run("it selects nodes with the sink's node selector", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{
+			NodeSelector: map[string]string{"role": "control-plane"},
+		}))
+		sc.SetNode("master", master)
+		sc.SetNode("control-plane", map[string]string{"role": "control-plane"})
+
+		script := sc.Script()
+		if !strings.Contains(script, `local audit_0_nodes = {["control-plane"] = true}`) {
+			t.Errorf("Expected only the selected node, got %s", script)
+		}
+	})
+
+	t.Run("it reports changes to the selected nodes", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+
+		if !sc.SetNode("master", master) {
+			t.Error("Expected a new selected node to change the config")
+		}
+		if sc.SetNode("master", master) {
+			t.Error("Expected an unchanged node to not change the config")
+		}
+		if sc.SetNode("worker", nil) {
+			t.Error("Expected a node that is not selected to not change the config")
+		}
+		if !sc.SetNode("master", nil) {
+			t.Error("Expected a node that is no longer selected to change the config")
+		}
+		sc.SetNode("master", master)
+		if !sc.DeleteNode("master") {
+			t.Error("Expected a deleted selected node to change the config")
+		}
+	})
+
+	t.Run("it does not read the audit log for syslog sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "audit"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "example.com", Port: 514},
+				AuditLog:   &v1alpha1.AuditLogSpec{},
+			},
+		})
+
+		if config := sc.String(); strings.Contains(config, "[INPUT]") {
+			t.Errorf("Expected no audit input, got %s", config)
+		}
+	})
+
+	t.Run("it patches the config when selected nodes change", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewNodeController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker"}})
+		if spyPatcher.patchCalled {
+			t.Fatal("Expected patch to not be called for a node that is not selected")
+		}
+
+		c.OnAdd(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: master}})
+		script := findPatch(lastPatch(t, spyPatcher), "/data/sinks.lua").Value
+		if !strings.Contains(script, `{["master"] = true}`) {
+			t.Errorf("Expected the master node to be selected, got %s", script)
+		}
+
+		c.OnDelete(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: master}})
+		script = findPatch(lastPatch(t, spyPatcher), "/data/sinks.lua").Value
+		if !strings.Contains(script, "local audit_0_nodes = {}") {
+			t.Errorf("Expected no selected nodes, got %s", script)
+		}
+	})
+
+	t.Run("it patches the config when a node is relabeled", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewNodeController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		worker := &coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}
+		c.OnAdd(worker)
+		promoted := worker.DeepCopy()
+		promoted.Labels = master
+		c.OnUpdate(worker, promoted)
+
+		script := findPatch(lastPatch(t, spyPatcher), "/data/sinks.lua").Value
+		if !strings.Contains(script, `{["node-a"] = true}`) {
+			t.Errorf("Expected the relabeled node to be selected, got %s", script)
+		}
+	})
+
+	t.Run("it patches the config for deleted node tombstones", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(auditSink(&v1alpha1.AuditLogSpec{}))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewNodeController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		node := &coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: master}}
+		c.OnAdd(node)
+		c.OnDelete(cache.DeletedFinalStateUnknown{Key: "master", Obj: node})
+
+		script := findPatch(lastPatch(t, spyPatcher), "/data/sinks.lua").Value
+		if !strings.Contains(script, "local audit_0_nodes = {}") {
+			t.Errorf("Expected no selected nodes, got %s", script)
+		}
+	})
+}
@@ -0,0 +1,401 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	clientv1alpha1 "github.com/knative/observability/pkg/client/clientset/versioned/typed/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OutputMetrics are the per output counters reported by fluent-bit's
+// /api/v1/metrics endpoint.
+type OutputMetrics struct {
+	ProcRecords   uint64 `json:"proc_records"`
+	Errors        uint64 `json:"errors"`
+	RetriesFailed uint64 `json:"retries_failed"`
+}
+
+// since returns the counters accumulated after prev. A counter lower than
+// its previous value means fluent-bit restarted, so it is counted from zero.
+func (m OutputMetrics) since(prev OutputMetrics) OutputMetrics {
+	delta := func(cur, prev uint64) uint64 {
+		if cur < prev {
+			return cur
+		}
+		return cur - prev
+	}
+	return OutputMetrics{
+		ProcRecords:   delta(m.ProcRecords, prev.ProcRecords),
+		Errors:        delta(m.Errors, prev.Errors),
+		RetriesFailed: delta(m.RetriesFailed, prev.RetriesFailed),
+	}
+}
+
+type MetricsFetcher interface {
+	Fetch() (map[string]OutputMetrics, error)
+}
+
+type PodLister interface {
+	List(opts metav1.ListOptions) (*coreV1.PodList, error)
+}
+
+// FluentBitMetrics fetches output metrics from every fluent-bit pod and sums
+// them by output instance name, which is the alias of the outputs of sinks.
+type FluentBitMetrics struct {
+	pods   PodLister
+	port   int
+	client *http.Client
+}
+
+func NewFluentBitMetrics(pods PodLister, port int) *FluentBitMetrics {
+	return &FluentBitMetrics{
+		pods: pods,
+		port: port,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func (f *FluentBitMetrics) Fetch() (map[string]OutputMetrics, error) {
+	pods, err := f.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]OutputMetrics)
+	for _, p := range pods.Items {
+		if p.Status.PodIP == "" {
+			continue
+		}
+
+		podMetrics, err := f.fetchPod(p.Status.PodIP)
+		if err != nil {
+			log.Printf("Unable to fetch metrics from %s: %s", p.Name, err)
+			continue
+		}
+
+		for name, m := range podMetrics {
+			total := metrics[name]
+			total.ProcRecords += m.ProcRecords
+			total.Errors += m.Errors
+			total.RetriesFailed += m.RetriesFailed
+			metrics[name] = total
+		}
+	}
+
+	return metrics, nil
+}
+
+func (f *FluentBitMetrics) fetchPod(ip string) (map[string]OutputMetrics, error) {
+	resp, err := f.client.Get(fmt.Sprintf(
+		"http://%s/api/v1/metrics",
+		net.JoinHostPort(ip, strconv.Itoa(f.port)),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Output map[string]OutputMetrics `json:"output"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	return body.Output, nil
+}
+
+// SinkStatusGetter is used to write conditions back to sinks.
+type SinkStatusGetter interface {
+	clientv1alpha1.LogSinksGetter
+	clientv1alpha1.ClusterLogSinksGetter
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+type circuit struct {
+	state    circuitState
+	failures int
+	openedAt time.Time
+	last     OutputMetrics
+	primed   bool
+}
+
+type BreakerOpt func(*Breaker)
+
+// WithFailureThreshold sets how many consecutive failing observations open
+// a sink's circuit.
+func WithFailureThreshold(n int) BreakerOpt {
+	return func(b *Breaker) {
+		b.threshold = n
+	}
+}
+
+// WithProbeInterval sets how long a circuit stays open before the sink's
+// real output is rendered again to probe the destination.
+func WithProbeInterval(d time.Duration) BreakerOpt {
+	return func(b *Breaker) {
+		b.probeInterval = d
+	}
+}
+
+func WithClock(now func() time.Time) BreakerOpt {
+	return func(b *Breaker) {
+		b.now = now
+	}
+}
+
+// Breaker replaces the output of a sink whose destination keeps failing
+// with a null output so that it stops back-pressuring the fluent-bit
+// pipeline.
+//
+// A closed circuit opens after the configured number of consecutive
+// observations where the sink's output reported errors without delivering
+// any records. Once the probe interval has passed the circuit half opens:
+// the real output is rendered again, and the next observation either closes
+// the circuit or opens it again.
+type Breaker struct {
+	mu            sync.Mutex
+	cmp           ConfigMapPatcher
+	dsp           DaemonSetPodDeleter
+	ssg           SinkStatusGetter
+	sc            *Config
+	threshold     int
+	probeInterval time.Duration
+	now           func() time.Time
+	circuits      map[string]*circuit
+}
+
+func NewBreaker(
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	ssg SinkStatusGetter,
+	sc *Config,
+	opts ...BreakerOpt,
+) *Breaker {
+	b := &Breaker{
+		cmp:           cmp,
+		dsp:           dsp,
+		ssg:           ssg,
+		sc:            sc,
+		threshold:     3,
+		probeInterval: 5 * time.Minute,
+		now:           time.Now,
+		circuits:      make(map[string]*circuit),
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
+}
+
+// Run observes the fetched metrics every interval until stopCh is closed.
+func (b *Breaker) Run(f MetricsFetcher, interval time.Duration, stopCh <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			m, err := f.Fetch()
+			if err != nil {
+				log.Printf("Unable to fetch fluent-bit metrics: %s", err)
+				continue
+			}
+			b.Observe(m)
+		}
+	}
+}
+
+// Observe feeds a snapshot of fluent-bit output metrics through each sink's
+// circuit. The config is re-rendered when any circuit opens or closes.
+func (b *Breaker) Observe(metrics map[string]OutputMetrics) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	instances := b.sc.instances()
+
+	for k := range b.circuits {
+		if !b.sc.hasSink(circuitSink(k)) {
+			delete(b.circuits, k)
+		}
+	}
+
+	// A cluster sink with namespace globs has an output for each namespace,
+	// so the metrics of the outputs of a circuit are summed. Its first
+	// output names it in the condition messages.
+	names := make(map[string]string)
+	sinkMetrics := make(map[string]OutputMetrics)
+	for name, k := range instances {
+		if n, ok := names[k]; !ok || name < n {
+			names[k] = name
+		}
+		m, ok := metrics[name]
+		if !ok {
+			continue
+		}
+		total := sinkMetrics[k]
+		total.ProcRecords += m.ProcRecords
+		total.Errors += m.Errors
+		total.RetriesFailed += m.RetriesFailed
+		sinkMetrics[k] = total
+	}
+
+	var transitions []transition
+	for k, name := range names {
+		c, ok := b.circuits[k]
+		if !ok {
+			c = &circuit{}
+			b.circuits[k] = c
+		}
+
+		m, ok := sinkMetrics[k]
+		if !ok {
+			continue
+		}
+		if !c.primed {
+			c.last = m
+			c.primed = true
+			continue
+		}
+		delta := m.since(c.last)
+		c.last = m
+
+		switch {
+		case delta.ProcRecords == 0 && delta.Errors+delta.RetriesFailed > 0:
+			c.failures++
+			if c.state == circuitHalfOpen || c.failures >= b.threshold {
+				c.state = circuitOpen
+				c.openedAt = now
+				transitions = append(transitions, transition{
+					key:     k,
+					status:  coreV1.ConditionTrue,
+					reason:  "OutputFailing",
+					message: fmt.Sprintf("output %s reported errors without delivering records", name),
+				})
+			}
+		case delta.ProcRecords > 0:
+			c.failures = 0
+			if c.state == circuitHalfOpen {
+				c.state = circuitClosed
+				transitions = append(transitions, transition{
+					key:     k,
+					status:  coreV1.ConditionFalse,
+					reason:  "OutputRecovered",
+					message: fmt.Sprintf("output %s delivered records", name),
+				})
+			}
+		}
+	}
+
+	for k, c := range b.circuits {
+		if c.state == circuitOpen && now.Sub(c.openedAt) >= b.probeInterval {
+			c.state = circuitHalfOpen
+			c.failures = 0
+			transitions = append(transitions, transition{
+				key:     k,
+				status:  coreV1.ConditionTrue,
+				reason:  "Probing",
+				message: "output re-enabled to probe the destination",
+			})
+		}
+	}
+
+	if len(transitions) == 0 {
+		return
+	}
+
+	open := make(map[string]bool)
+	for k, c := range b.circuits {
+		if c.state == circuitOpen {
+			open[k] = true
+		}
+		// Every fluent-bit pod restarts with the new config and its
+		// counters start over.
+		c.primed = false
+	}
+	b.sc.setOpenCircuits(open)
+
+	applyConfig(b.sc, b.cmp, b.dsp, "Circuits changed")
+
+	for _, t := range transitions {
+		b.updateCondition(t, now)
+	}
+}
+
+type transition struct {
+	key     string
+	status  coreV1.ConditionStatus
+	reason  string
+	message string
+}
+
+func (b *Breaker) updateCondition(t transition, now time.Time) {
+	cond := v1alpha1.SinkCondition{
+		Type:               v1alpha1.SinkConditionCircuitOpen,
+		Status:             t.status,
+		LastTransitionTime: metav1.NewTime(now),
+		Reason:             t.reason,
+		Message:            t.message,
+	}
+
+	s, cs := b.sc.lookup(circuitSink(t.key))
+	var err error
+	switch {
+	case s != nil:
+		s = s.DeepCopy()
+		s.Status.SetCondition(cond)
+		_, err = b.ssg.LogSinks(s.Namespace).UpdateStatus(s)
+	case cs != nil:
+		cs = cs.DeepCopy()
+		cs.Status.SetCondition(cond)
+		_, err = b.ssg.ClusterLogSinks(cs.Namespace).UpdateStatus(cs)
+	}
+	if err != nil {
+		log.Printf("Unable to update sink status: %s", err)
+	}
+}
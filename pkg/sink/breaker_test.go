@@ -0,0 +1,371 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestBreaker(t *testing.T) {
+	healthy := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/failing
+    InstanceName ns1/failing
+    Addr failing.example.com:514
+    Namespace ns1
+
+[OUTPUT]
+    Name syslog
+    Match *_ns2_*
+    Alias ns2/working
+    InstanceName ns2/working
+    Addr working.example.com:514
+    Namespace ns2
+`
+	open := `
+[OUTPUT]
+    Name null
+    Match *_ns1_*
+    Alias ns1/failing
+
+[OUTPUT]
+    Name syslog
+    Match *_ns2_*
+    Alias ns2/working
+    InstanceName ns2/working
+    Addr working.example.com:514
+    Namespace ns2
+`
+
+	t.Run("it opens the circuit after consecutive failures", func(t *testing.T) {
+		b, spyPatcher, client, _ := setupBreaker(3)
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(0, 5, 10))
+		b.Observe(outputs(0, 10, 20))
+		if spyPatcher.patchCalled {
+			t.Fatal("Expected patch to not be called before the threshold")
+		}
+
+		b.Observe(outputs(0, 15, 30))
+		if got := lastOutputsConf(t, spyPatcher); got != open {
+			t.Errorf("Config not equal (-want, +got) = %v", cmp.Diff(open, got))
+		}
+
+		cond := circuitCondition(t, client, "ns1", "failing")
+		if cond.Status != coreV1.ConditionTrue || cond.Reason != "OutputFailing" {
+			t.Errorf("Expected an open circuit condition, got %+v", cond)
+		}
+		if cond := circuitCondition(t, client, "ns2", "working"); cond != nil {
+			t.Errorf("Expected no condition on the working sink, got %+v", cond)
+		}
+	})
+
+	t.Run("it does not open the circuit while records are delivered", func(t *testing.T) {
+		b, spyPatcher, _, _ := setupBreaker(2)
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(5, 5, 10))
+		b.Observe(outputs(10, 10, 20))
+		b.Observe(outputs(15, 15, 30))
+
+		if spyPatcher.patchCalled {
+			t.Error("Expected patch to not be called")
+		}
+	})
+
+	t.Run("it resets the failure count after a success", func(t *testing.T) {
+		b, spyPatcher, _, _ := setupBreaker(2)
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(0, 5, 10))
+		b.Observe(outputs(5, 5, 10))
+		b.Observe(outputs(5, 10, 20))
+
+		if spyPatcher.patchCalled {
+			t.Error("Expected patch to not be called")
+		}
+	})
+
+	t.Run("it closes the circuit when the probe succeeds", func(t *testing.T) {
+		b, spyPatcher, client, clock := setupBreaker(1)
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(0, 5, 10))
+		if got := lastOutputsConf(t, spyPatcher); got != open {
+			t.Fatalf("Config not equal (-want, +got) = %v", cmp.Diff(open, got))
+		}
+
+		clock.Add(time.Minute)
+		b.Observe(workingOutput(0))
+		if got := lastOutputsConf(t, spyPatcher); got != open {
+			t.Fatalf("Expected circuit to stay open before the probe interval")
+		}
+
+		clock.Add(5 * time.Minute)
+		b.Observe(workingOutput(0))
+		if got := lastOutputsConf(t, spyPatcher); got != healthy {
+			t.Fatalf("Config not equal (-want, +got) = %v", cmp.Diff(healthy, got))
+		}
+		cond := circuitCondition(t, client, "ns1", "failing")
+		if cond.Status != coreV1.ConditionTrue || cond.Reason != "Probing" {
+			t.Errorf("Expected a probing circuit condition, got %+v", cond)
+		}
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(10, 0, 0))
+		if got := lastOutputsConf(t, spyPatcher); got != healthy {
+			t.Fatalf("Config not equal (-want, +got) = %v", cmp.Diff(healthy, got))
+		}
+		cond = circuitCondition(t, client, "ns1", "failing")
+		if cond.Status != coreV1.ConditionFalse || cond.Reason != "OutputRecovered" {
+			t.Errorf("Expected a closed circuit condition, got %+v", cond)
+		}
+	})
+
+	t.Run("it reopens the circuit when the probe fails", func(t *testing.T) {
+		b, spyPatcher, client, clock := setupBreaker(3)
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(0, 5, 10))
+		b.Observe(outputs(0, 10, 10))
+		b.Observe(outputs(0, 15, 10))
+		clock.Add(5 * time.Minute)
+		b.Observe(workingOutput(0))
+		if got := lastOutputsConf(t, spyPatcher); got != healthy {
+			t.Fatalf("Config not equal (-want, +got) = %v", cmp.Diff(healthy, got))
+		}
+
+		b.Observe(outputs(0, 0, 0))
+		b.Observe(outputs(0, 1, 0))
+		if got := lastOutputsConf(t, spyPatcher); got != open {
+			t.Fatalf("Config not equal (-want, +got) = %v", cmp.Diff(open, got))
+		}
+		cond := circuitCondition(t, client, "ns1", "failing")
+		if cond.Status != coreV1.ConditionTrue || cond.Reason != "OutputFailing" {
+			t.Errorf("Expected an open circuit condition, got %+v", cond)
+		}
+	})
+
+	t.Run("it forgets circuits of deleted sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		failing := failingSink()
+		sc.UpsertSink(failing)
+		spyPatcher := &spyConfigMapPatcher{}
+		b := sink.NewBreaker(
+			spyPatcher,
+			&spyDaemonSetPodDeleter{},
+			fake.NewSimpleClientset(failing).ObservabilityV1alpha1(),
+			sc,
+			sink.WithFailureThreshold(1),
+		)
+
+		b.Observe(map[string]sink.OutputMetrics{"ns1/failing": {}})
+		b.Observe(map[string]sink.OutputMetrics{"ns1/failing": {Errors: 1}})
+		sc.DeleteSink(failing)
+		sc.UpsertSink(failing)
+
+		if got := sc.String(); got != `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/failing
+    InstanceName ns1/failing
+    Addr failing.example.com:514
+    Namespace ns1
+` {
+			t.Errorf("Expected a re-created sink to have a closed circuit, got %s", got)
+		}
+	})
+}
+
+func TestFluentBitMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{
+			"input": {"tail.0": {"records": 100}},
+			"output": {
+				"syslog.0": {"proc_records": 10, "errors": 1, "retries_failed": 2},
+				"http.0": {"proc_records": 3}
+			}
+		}`)
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods := &spyPodLister{
+		pods: []coreV1.Pod{
+			{Status: coreV1.PodStatus{PodIP: host}},
+			{Status: coreV1.PodStatus{PodIP: host}},
+			{Status: coreV1.PodStatus{}},
+		},
+	}
+	m, err := sink.NewFluentBitMetrics(pods, p).Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]sink.OutputMetrics{
+		"syslog.0": {ProcRecords: 20, Errors: 2, RetriesFailed: 4},
+		"http.0":   {ProcRecords: 6},
+	}
+	if diff := cmp.Diff(expected, m); diff != "" {
+		t.Errorf("Metrics not equal (-want, +got) = %v", diff)
+	}
+	if pods.selector != "app=fluent-bit" {
+		t.Errorf("Expected selector app=fluent-bit, got %s", pods.selector)
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func failingSink() *v1alpha1.LogSink {
+	return &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "failing",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "failing.example.com",
+				Port: 514,
+			},
+		},
+	}
+}
+
+func setupBreaker(threshold int) (*sink.Breaker, *spyConfigMapPatcher, *fake.Clientset, *fakeClock) {
+	failing := failingSink()
+	working := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "working",
+			Namespace: "ns2",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "working.example.com",
+				Port: 514,
+			},
+		},
+	}
+	sc := sink.NewConfig()
+	sc.UpsertSink(failing)
+	sc.UpsertSink(working)
+
+	client := fake.NewSimpleClientset(failing, working)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	spyPatcher := &spyConfigMapPatcher{}
+	b := sink.NewBreaker(
+		spyPatcher,
+		&spyDaemonSetPodDeleter{},
+		client.ObservabilityV1alpha1(),
+		sc,
+		sink.WithFailureThreshold(threshold),
+		sink.WithProbeInterval(5*time.Minute),
+		sink.WithClock(clock.Now),
+	)
+
+	return b, spyPatcher, client, clock
+}
+
+// outputs returns metrics where the failing sink's output has the given
+// counters and the working sink's output is delivering records.
+func outputs(procRecords, errors, retriesFailed uint64) map[string]sink.OutputMetrics {
+	return map[string]sink.OutputMetrics{
+		"ns1/failing": {
+			ProcRecords:   procRecords,
+			Errors:        errors,
+			RetriesFailed: retriesFailed,
+		},
+		"ns2/working": {
+			ProcRecords: procRecords + errors + 1,
+		},
+	}
+}
+
+// workingOutput returns metrics while the failing sink's circuit is open and
+// only the working sink delivers records.
+func workingOutput(procRecords uint64) map[string]sink.OutputMetrics {
+	return map[string]sink.OutputMetrics{
+		"ns2/working": {ProcRecords: procRecords},
+	}
+}
+
+func lastOutputsConf(t *testing.T, s *spyConfigMapPatcher) string {
+	return findPatch(lastPatch(t, s), "/data/outputs.conf").Value
+}
+
+func circuitCondition(t *testing.T, c *fake.Clientset, namespace, name string) *v1alpha1.SinkCondition {
+	s, err := c.ObservabilityV1alpha1().LogSinks(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cond := range s.Status.Conditions {
+		if cond.Type == v1alpha1.SinkConditionCircuitOpen {
+			return &cond
+		}
+	}
+	return nil
+}
+
+type spyPodLister struct {
+	pods     []coreV1.Pod
+	selector string
+}
+
+func (s *spyPodLister) List(opts metav1.ListOptions) (*coreV1.PodList, error) {
+	s.selector = opts.LabelSelector
+	return &coreV1.PodList{Items: s.pods}, nil
+}
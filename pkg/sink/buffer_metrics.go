@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SinkBufferUsedBytes and SinkBufferTotalBytes are the bytes buffered in
+// memory by the inputs records of a sink are read by and the buffer limits
+// of those inputs, summed over every fluent-bit pod. Sinks sharing the
+// container logs input each report its buffer. LogSinks are keyed by
+// namespace/name and ClusterLogSinks by name.
+var (
+	SinkBufferUsedBytes  = expvar.NewMap("sink_buffer_used_bytes")
+	SinkBufferTotalBytes = expvar.NewMap("sink_buffer_total_bytes")
+)
+
+// StorageMetrics are the memory buffer sizes of an input reported by
+// fluent-bit's /api/v1/storage endpoint.
+type StorageMetrics struct {
+	MemSize  uint64
+	MemLimit uint64
+}
+
+type StorageFetcher interface {
+	FetchStorage() (map[string]StorageMetrics, error)
+}
+
+// FetchStorage fetches the storage metrics from every fluent-bit pod and
+// sums them by input instance name.
+func (f *FluentBitMetrics) FetchStorage() (map[string]StorageMetrics, error) {
+	pods, err := f.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]StorageMetrics)
+	for _, p := range pods.Items {
+		if p.Status.PodIP == "" {
+			continue
+		}
+
+		podMetrics, err := f.fetchPodStorage(p.Status.PodIP)
+		if err != nil {
+			log.Printf("Unable to fetch storage metrics from %s: %s", p.Name, err)
+			continue
+		}
+
+		for name, m := range podMetrics {
+			total := metrics[name]
+			total.MemSize += m.MemSize
+			total.MemLimit += m.MemLimit
+			metrics[name] = total
+		}
+	}
+
+	return metrics, nil
+}
+
+func (f *FluentBitMetrics) fetchPodStorage(ip string) (map[string]StorageMetrics, error) {
+	resp, err := f.client.Get(fmt.Sprintf(
+		"http://%s/api/v1/storage",
+		net.JoinHostPort(ip, strconv.Itoa(f.port)),
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var body struct {
+		InputChunks map[string]struct {
+			Status struct {
+				MemSize  string `json:"mem_size"`
+				MemLimit string `json:"mem_limit"`
+			} `json:"status"`
+		} `json:"input_chunks"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]StorageMetrics, len(body.InputChunks))
+	for name, input := range body.InputChunks {
+		size, err := parseStorageSize(input.Status.MemSize)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := parseStorageSize(input.Status.MemLimit)
+		if err != nil {
+			return nil, err
+		}
+		metrics[name] = StorageMetrics{MemSize: size, MemLimit: limit}
+	}
+	return metrics, nil
+}
+
+// parseStorageSize parses the sizes fluent-bit reports in human readable
+// form, e.g. 0b, 512b, 1.5K or 5.0M, in multiples of 1024.
+func parseStorageSize(s string) (uint64, error) {
+	units := []string{"b", "K", "M", "G"}
+	for i := len(units) - 1; i >= 0; i-- {
+		if !strings.HasSuffix(s, units[i]) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, units[i]), 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("invalid storage size %q", s)
+		}
+		return uint64(v * float64(uint64(1)<<(10*uint(i)))), nil
+	}
+	return 0, fmt.Errorf("invalid storage size %q", s)
+}
+
+// BufferReporter re-exports the memory buffers of the inputs that read the
+// records of each sink. The inputs are mapped to sinks by their aliases.
+type BufferReporter struct {
+	mu       sync.Mutex
+	sc       *Config
+	exported map[string]bool
+}
+
+func NewBufferReporter(sc *Config) *BufferReporter {
+	return &BufferReporter{
+		sc:       sc,
+		exported: make(map[string]bool),
+	}
+}
+
+// Run observes the fetched storage metrics every interval until stopCh is
+// closed.
+func (r *BufferReporter) Run(f StorageFetcher, interval time.Duration, stopCh <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			m, err := f.FetchStorage()
+			if err != nil {
+				log.Printf("Unable to fetch fluent-bit storage metrics: %s", err)
+				continue
+			}
+			r.Observe(m)
+		}
+	}
+}
+
+// Observe sets the buffer metrics of every sink to the sums of its inputs.
+// The metrics of sinks that are deleted are removed.
+func (r *BufferReporter) Observe(metrics map[string]StorageMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sinks := r.sc.storageInstances()
+	for name := range r.exported {
+		if _, ok := sinks[name]; !ok {
+			SinkBufferUsedBytes.Delete(name)
+			SinkBufferTotalBytes.Delete(name)
+			delete(r.exported, name)
+		}
+	}
+
+	for name, instances := range sinks {
+		var used, total uint64
+		for _, instance := range instances {
+			used += metrics[instance].MemSize
+			total += metrics[instance].MemLimit
+		}
+		usedVar, totalVar := new(expvar.Int), new(expvar.Int)
+		usedVar.Set(int64(used))
+		totalVar.Set(int64(total))
+		SinkBufferUsedBytes.Set(name, usedVar)
+		SinkBufferTotalBytes.Set(name, totalVar)
+		r.exported[name] = true
+	}
+}
+
+// storageInstances maps the metric name of every sink to the input
+// instances that read its records by their aliases. Sinks without an input
+// of their own read the container logs input, whose alias is kube.
+// Projected, routed and node selected sinks and sinks with filters of their
+// own also read the emitters of their copies of the records, and sinks with
+// a heartbeat its dummy input.
+func (sc *Config) storageInstances() map[string][]string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sinks := make(map[string][]string)
+	if len(sc.sinks)+len(sc.clusterSinks) == 0 || sc.forwardingDisabled {
+		return sinks
+	}
+	add := func(ref sinkRef, instance string) {
+		name := bufferMetricName(ref)
+		for _, i := range sinks[name] {
+			if i == instance {
+				return
+			}
+		}
+		sinks[name] = append(sinks[name], instance)
+	}
+
+	for _, ref := range sc.filteredSinkRefs() {
+		switch {
+		case audited(ref):
+			add(ref, inputAlias(ref, "audit"))
+		case ref.spec.Type == "webhook" && ref.spec.RawMode:
+			add(ref, inputAlias(ref, "raw"))
+		default:
+			add(ref, kubernetesInputAlias)
+		}
+		if hb := ref.spec.Heartbeat; hb != nil && hb.Interval.Duration > 0 {
+			add(ref, inputAlias(ref, "heartbeat"))
+		}
+		if projected(ref) {
+			add(ref, inputAlias(ref, "project"))
+		}
+		if nodeCopied(ref) {
+			add(ref, inputAlias(ref, "nodes"))
+		}
+		if filterCopied(ref) {
+			add(ref, inputAlias(ref, "filtered"))
+		}
+		if routed(ref) {
+			add(ref, inputAlias(ref, "route"))
+			for j := range routes(ref) {
+				add(ref, inputAlias(ref, fmt.Sprintf("route:%d", j)))
+			}
+		}
+	}
+	return sinks
+}
+
+func bufferMetricName(ref sinkRef) string {
+	if ref.cluster || ref.glob {
+		return ref.name
+	}
+	return ref.namespace + "/" + ref.name
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestFluentBitStorageMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/storage" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{
+			"storage_layer": {"chunks": {"total_chunks": 3, "mem_chunks": 3}},
+			"input_chunks": {
+				"kube": {
+					"status": {"overlimit": false, "mem_size": "1.5K", "mem_limit": "5.0M"},
+					"chunks": {"total": 2, "up": 2, "down": 0}
+				},
+				"ns1/app:project": {
+					"status": {"overlimit": false, "mem_size": "0b", "mem_limit": "10.0M"},
+					"chunks": {"total": 1, "up": 1, "down": 0}
+				}
+			}
+		}`)
+	}))
+	defer server.Close()
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pods := &spyPodLister{
+		pods: []coreV1.Pod{
+			{Status: coreV1.PodStatus{PodIP: host}},
+			{Status: coreV1.PodStatus{PodIP: host}},
+			{Status: coreV1.PodStatus{}},
+		},
+	}
+	m, err := sink.NewFluentBitMetrics(pods, p).FetchStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]sink.StorageMetrics{
+		"kube":            {MemSize: 3072, MemLimit: 10 << 20},
+		"ns1/app:project": {MemSize: 0, MemLimit: 20 << 20},
+	}
+	if diff := cmp.Diff(expected, m); diff != "" {
+		t.Errorf("Metrics not equal (-want, +got) = %v", diff)
+	}
+	if pods.selector != "app=fluent-bit" {
+		t.Errorf("Expected selector app=fluent-bit, got %s", pods.selector)
+	}
+}
+
+func TestBufferReporter(t *testing.T) {
+	t.Run("it exports the buffers of the inputs of each sink", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(countedSink("ns1", "app", false))
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "projected", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://example.com"},
+				Project:     []string{"log"},
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "raw", Namespace: "ns2"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://example.com"},
+				RawMode:     true,
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "socket", Namespace: "ns2"},
+			Spec: v1alpha1.SinkSpec{
+				Type:           "unix_socket",
+				UnixSocketSpec: v1alpha1.UnixSocketSpec{Path: "/var/run/sink.sock"},
+				Heartbeat:      &v1alpha1.HeartbeatSpec{Interval: metav1.Duration{Duration: time.Minute}},
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "example.com", Port: 514},
+			},
+		})
+		r := sink.NewBufferReporter(sc)
+
+		// No sink reads the forward input of the fluent-bit configmap.
+		r.Observe(map[string]sink.StorageMetrics{
+			"kube":                  {MemSize: 100, MemLimit: 1000},
+			"ns2/raw:raw":           {MemSize: 20, MemLimit: 500},
+			"ns1/projected:project": {MemSize: 5, MemLimit: 2000},
+			"ns2/socket:heartbeat":  {MemSize: 3, MemLimit: 30},
+			"ns2/socket:filtered":   {MemSize: 4, MemLimit: 40},
+			"forward.0":             {MemSize: 1, MemLimit: 1},
+		})
+
+		expectBuffer(t, "ns1/app", 100, 1000)
+		expectBuffer(t, "ns1/projected", 105, 3000)
+		expectBuffer(t, "ns2/raw", 20, 500)
+		expectBuffer(t, "ns2/socket", 107, 1070)
+		expectBuffer(t, "cluster", 100, 1000)
+	})
+
+	t.Run("it maps inputs by alias whatever order sinks are rendered in", func(t *testing.T) {
+		raw := func(name string) *v1alpha1.LogSink {
+			return &v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns3"},
+				Spec: v1alpha1.SinkSpec{
+					Type:        "webhook",
+					WebhookSpec: v1alpha1.WebhookSpec{URL: "https://example.com"},
+					RawMode:     true,
+				},
+			}
+		}
+		sc := sink.NewConfig()
+		sc.UpsertSink(raw("second"))
+		r := sink.NewBufferReporter(sc)
+		metrics := map[string]sink.StorageMetrics{
+			"ns3/first:raw":  {MemSize: 1, MemLimit: 10},
+			"ns3/second:raw": {MemSize: 2, MemLimit: 20},
+		}
+
+		r.Observe(metrics)
+		expectBuffer(t, "ns3/second", 2, 20)
+
+		sc.UpsertSink(raw("first"))
+		r.Observe(metrics)
+		expectBuffer(t, "ns3/first", 1, 10)
+		expectBuffer(t, "ns3/second", 2, 20)
+	})
+
+	t.Run("it removes the buffers of deleted sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		s := countedSink("ns1", "deleted", false)
+		sc.UpsertSink(s)
+		sc.UpsertSink(countedSink("ns1", "kept", false))
+		r := sink.NewBufferReporter(sc)
+
+		metrics := map[string]sink.StorageMetrics{
+			"kube": {MemSize: 7, MemLimit: 70},
+		}
+		r.Observe(metrics)
+		expectBuffer(t, "ns1/deleted", 7, 70)
+
+		sc.DeleteSink(s)
+		r.Observe(metrics)
+		if v := sink.SinkBufferUsedBytes.Get("ns1/deleted"); v != nil {
+			t.Errorf("Expected the deleted sink to not be exported, got %s", v)
+		}
+		if v := sink.SinkBufferTotalBytes.Get("ns1/deleted"); v != nil {
+			t.Errorf("Expected the deleted sink to not be exported, got %s", v)
+		}
+		expectBuffer(t, "ns1/kept", 7, 70)
+	})
+}
+
+func expectBuffer(t *testing.T, name string, used, total int64) {
+	t.Helper()
+	for metric, expected := range map[*expvar.Map]int64{
+		sink.SinkBufferUsedBytes:  used,
+		sink.SinkBufferTotalBytes: total,
+	} {
+		v, ok := metric.Get(name).(*expvar.Int)
+		if !ok {
+			t.Fatalf("Expected the buffer of %s to be exported", name)
+		}
+		if v.Value() != expected {
+			t.Errorf("Expected the buffer of %s to be %d, got %d", name, expected, v.Value())
+		}
+	}
+}
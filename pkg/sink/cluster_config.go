@@ -12,17 +12,18 @@ const clusterNameFilterTemplate = `
 func SetClusterNameFilter(
 	cmp ConfigMapPatcher,
 	dsp DaemonSetPodDeleter,
+	sc *Config,
 	clusterName string,
 ) {
 	if clusterName == "" {
 		return
 	}
 
-	patchConfig([]patch{
+	patchConfig(sc, cmp, dsp, []patch{
 		{
 			Op:    "replace",
 			Path:  "/data/cluster-name-filter.conf",
 			Value: fmt.Sprintf(clusterNameFilterTemplate, clusterName),
 		},
-	}, cmp, dsp)
+	}, "Cluster name set")
 }
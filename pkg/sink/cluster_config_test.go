@@ -13,6 +13,7 @@ func TestSetClusterNameFilter(t *testing.T) {
 	sink.SetClusterNameFilter(
 		spyConfigMapPatcher,
 		spyDaemonSetPodDeleter,
+		sink.NewConfig(),
 		"test-cluster-name",
 	)
 
@@ -36,6 +37,7 @@ func TestSetClusterNameFilterIgnoresEmptyClustername(t *testing.T) {
 	sink.SetClusterNameFilter(
 		spyConfigMapPatcher,
 		spyDaemonSetPodDeleter,
+		sink.NewConfig(),
 		"",
 	)
 
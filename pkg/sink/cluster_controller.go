@@ -16,6 +16,7 @@ limitations under the License.
 package sink
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
@@ -43,14 +44,7 @@ func (c *ClusterController) OnAdd(o interface{}) {
 
 	c.sc.UpsertClusterSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("ClusterLogSink %s upserted", d.Name))
 }
 
 func (c *ClusterController) OnDelete(o interface{}) {
@@ -61,14 +55,7 @@ func (c *ClusterController) OnDelete(o interface{}) {
 
 	c.sc.DeleteClusterSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("ClusterLogSink %s deleted", d.Name))
 }
 
 func (c *ClusterController) OnUpdate(old, new interface{}) {
@@ -41,8 +41,9 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 `,
@@ -58,8 +59,9 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
     TLSConfig {}
@@ -76,8 +78,9 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
     TLSConfig {"insecure_skip_verify":true}
@@ -95,23 +98,26 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-test.com
+    Match *_*
+    Alias cluster:sink-test.com
+    InstanceName cluster:sink-test.com
     Addr test.com:4567
     Cluster true
 `,
@@ -128,16 +134,18 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:4567
     Cluster true
 `,
@@ -154,16 +162,18 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12346
     Cluster true
 `,
@@ -180,8 +190,9 @@ func TestClusterLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_*
+    Alias cluster:sink-example.com
+    InstanceName cluster:sink-example.com
     Addr example.com:12345
     Cluster true
 `,
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// coalesceLua drops the records of a tag whose log repeats the log of the
+// record before them, until window seconds have passed since the first of
+// them. The next record of the tag that is kept has its repeated field set
+// to the number dropped, like syslog's "last message repeated N times".
+//
+// The state of a tag is evicted once no record of it was seen for window
+// seconds, so the table does not grow with every container that ever
+// logged. A run of repeats is therefore only reported when the container
+// logs again within the window of its last repeat. The table is swept at
+// most once per window.
+func coalesceLua(name string, window float64) luaStep {
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %[1]s = {}\nlocal %[1]s_swept = 0\n", name),
+		body: fmt.Sprintf(`
+    local log = record["log"]
+    if timestamp - %[1]s_swept >= %[2]s then
+        for t, l in pairs(%[1]s) do
+            if timestamp - l.seen >= %[2]s then
+                %[1]s[t] = nil
+            end
+        end
+        %[1]s_swept = timestamp
+    end
+    local last = %[1]s[tag]
+    if last ~= nil and type(log) == "string" and last.log == log and timestamp - last.start < %[2]s then
+        last.count = last.count + 1
+        last.seen = timestamp
+        return -1, timestamp, record
+    end
+    %[1]s[tag] = {log = log, start = timestamp, seen = timestamp, count = 0}
+    if last ~= nil and last.count > 0 then
+        record["repeated"] = last.count
+        code = 1
+    end
+`, name, strconv.FormatFloat(window, 'g', -1, 64)),
+	}
+}
@@ -19,10 +19,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/url"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
 )
@@ -33,10 +35,23 @@ const nullConfig = `
     Match *
 `
 
+const rawInputConfig = `
+[INPUT]
+    Name tail
+    Tag %s
+    Alias %s
+    Path %s
+    DB /var/log/flb_%s.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+%s`
+
 const httpOutputConfig = `
 [OUTPUT]
     Name http
     Match %s
+    Alias %s
     Format json
     Host %s
     Port %s
@@ -48,13 +63,77 @@ type Config struct {
 	mu           sync.Mutex
 	sinks        map[string]*v1alpha1.LogSink
 	clusterSinks map[string]*v1alpha1.ClusterLogSink
+	openCircuits map[string]bool
+	nsThrottles  map[string]int
+	filterSets   map[string]*v1alpha1.ClusterFilterSet
+
+	// namespaces are the namespaces in the cluster, which the namespace
+	// globs of cluster sinks are matched against.
+	namespaces map[string]bool
+
+	// nodes are the labels of the cluster's nodes by name, which the node
+	// selectors of audit sinks are matched against.
+	nodes map[string]map[string]string
+
+	// services are the external names and named ports of the cluster's
+	// services by namespace and name, which the service refs of sinks are
+	// resolved with.
+	services map[string]service
+
+	// generation counts the configs written to the configmap and appliedAt
+	// is when the last one was written.
+	generation int64
+	appliedAt  time.Time
+
+	hook PostRenderHook
+
+	// debugStdout enables the stdout outputs of sinks with DebugStdout set.
+	debugStdout bool
+	// geoIP enables the geoip2 filters of sinks with GeoIP set.
+	geoIP bool
+
+	// storageBuffering enables the buffer limits of sinks with Retry set.
+	storageBuffering bool
+
+	// forwardingDisabled replaces the outputs of every sink with a null
+	// output.
+	forwardingDisabled bool
+
+	// paused holds the reconciles of the controllers and held is whether
+	// one was held since they were paused. heldPatches are the patches of
+	// the parts of the config that are not rendered from the sinks that
+	// were held, by path.
+	paused      bool
+	held        bool
+	heldPatches []patch
+
+	// audit receives the decision of every reconcile.
+	audit *json.Encoder
+
+	// exporter receives every config written to the configmap and
+	// exported is closed once the last export started is done.
+	exporter ConfigExporter
+	exported chan struct{}
+
+	// headerSync syncs the header values of sinks from their Secrets.
+	headerSync func()
 }
 
-func NewConfig() *Config {
-	return &Config{
+func NewConfig(opts ...ConfigOpt) *Config {
+	sc := &Config{
 		sinks:        make(map[string]*v1alpha1.LogSink),
 		clusterSinks: make(map[string]*v1alpha1.ClusterLogSink),
+		openCircuits: make(map[string]bool),
+		nsThrottles:  make(map[string]int),
+		filterSets:   make(map[string]*v1alpha1.ClusterFilterSet),
+		namespaces:   make(map[string]bool),
+		nodes:        make(map[string]map[string]string),
+		services:     make(map[string]service),
+	}
+	for _, o := range opts {
+		o(sc)
 	}
+	return sc
 }
 
 func (sc *Config) UpsertSink(s *v1alpha1.LogSink) {
@@ -73,105 +152,497 @@ func (sc *Config) DeleteSink(s *v1alpha1.LogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 	delete(sc.sinks, key(s))
+	sc.deleteCircuits(key(s))
 }
 
 func (sc *Config) DeleteClusterSink(s *v1alpha1.ClusterLogSink) {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
 	delete(sc.clusterSinks, clusterKey(s))
+	sc.deleteCircuits(clusterKey(s))
 }
 
-func (sc *Config) String() string {
+// SetNamespaceThrottle sets the records per second the sinks of a namespace
+// may deliver in total. A rate of 0 removes the throttle. It returns whether
+// the throttle changed.
+func (sc *Config) SetNamespaceThrottle(namespace string, rate int) bool {
 	sc.mu.Lock()
 	defer sc.mu.Unlock()
-	if len(sc.sinks)+len(sc.clusterSinks) == 0 {
-		return nullConfig
+
+	if sc.nsThrottles[namespace] == rate {
+		return false
+	}
+	if rate == 0 {
+		delete(sc.nsThrottles, namespace)
+		return true
 	}
-	return sc.syslogConfig() + sc.webhookConfig()
+	sc.nsThrottles[namespace] = rate
+	return true
 }
 
-func (sc *Config) webhookConfig() string {
-	var config string
-	for _, s := range sc.sinks {
-		if s.Spec.Type != "webhook" {
-			continue
+func (sc *Config) String() string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.outputsConfig()
+}
+
+// Script returns the Lua functions referenced by the sinks' lua filters.
+func (sc *Config) Script() string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.luaScript()
+}
+
+// patches returns the configmap patches that render every sink. The script
+// and the audit files are added rather than replaced since configmaps
+// created before they existed do not have the keys. The config is passed
+// through the post-render hook outside of the lock since the hook may be
+// slow.
+func (sc *Config) patches() ([]patch, error) {
+	sc.mu.Lock()
+	outputs := sc.outputsConfig()
+	script := sc.luaScript()
+	auditFiles := sc.auditFilesConfig()
+	sc.mu.Unlock()
+
+	if sc.hook != nil {
+		var err error
+		outputs, err = sc.hook.Mutate(outputs)
+		if err != nil {
+			return nil, fmt.Errorf("post-render hook failed: %s", err)
 		}
+	}
+
+	return []patch{
+		{
+			Op:    "replace",
+			Path:  "/data/outputs.conf",
+			Value: outputs,
+		},
+		{
+			Op:    "add",
+			Path:  "/data/" + luaScriptName,
+			Value: script,
+		},
+		{
+			Op:    "add",
+			Path:  "/data/" + auditFilesName,
+			Value: auditFiles,
+		},
+	}, nil
+}
+
+// configApplied records that the current config was written to the
+// configmap.
+func (sc *Config) configApplied(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.appliedAt = now
+}
 
-		config += buildHTTPConfig(s.Namespace, s.Spec, false)
+// togglePaused pauses or resumes the reconciles. It returns whether they
+// are paused and, when resuming, whether a reconcile was held and the
+// patches that were held.
+func (sc *Config) togglePaused() (bool, bool, []patch) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.paused = !sc.paused
+	held, patches := sc.held, sc.heldPatches
+	sc.held = false
+	sc.heldPatches = nil
+	return sc.paused, held, patches
+}
+
+// hold returns whether the reconciles are paused and records that one was
+// held if they are.
+func (sc *Config) hold() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.paused {
+		sc.held = true
 	}
+	return sc.paused
+}
 
-	for _, s := range sc.clusterSinks {
-		if s.Spec.Type != "webhook" {
-			continue
+// holdPatches returns whether the reconciles are paused and holds patches
+// if they are. A held patch of the same path is replaced.
+func (sc *Config) holdPatches(patches []patch) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if !sc.paused {
+		return false
+	}
+
+	sc.held = true
+	for _, p := range patches {
+		replaced := false
+		for i, h := range sc.heldPatches {
+			if h.Path == p.Path {
+				sc.heldPatches[i] = p
+				replaced = true
+			}
 		}
+		if !replaced {
+			sc.heldPatches = append(sc.heldPatches, p)
+		}
+	}
+	return true
+}
+
+// applied returns the generation of the last config written to the
+// configmap and when it was written.
+func (sc *Config) applied() (int64, time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.generation, sc.appliedAt
+}
 
-		config += buildHTTPConfig("", s.Spec, true)
+func (sc *Config) outputsConfig() string {
+	if len(sc.sinks)+len(sc.clusterSinks) == 0 || sc.forwardingDisabled {
+		return nullConfig
 	}
+	return sc.rawInputConfig() +
+		sc.heartbeatConfig() +
+		sc.auditConfig() +
+		sc.namespaceThrottleConfig() +
+		sc.filterConfig() +
+		sc.syslogConfig() +
+		sc.webhookConfig() +
+		sc.unixSocketConfig() +
+		sc.auditFileConfig() +
+		sc.debugStdoutConfig()
+}
 
-	return config
+func (sc *Config) instances() map[string]string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.outputInstances()
 }
 
-func (sc *Config) syslogConfig() string {
-	sinks := make(sinkList, 0, len(sc.sinks))
-	for _, s := range sc.sinks {
-		if s.Spec.Type != "syslog" {
+func (sc *Config) hasSink(k string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	_, ok := sc.sinks[k]
+	_, clusterOK := sc.clusterSinks[k]
+	return ok || clusterOK
+}
+
+func (sc *Config) lookup(k string) (*v1alpha1.LogSink, *v1alpha1.ClusterLogSink) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.sinks[k], sc.clusterSinks[k]
+}
+
+func (sc *Config) setOpenCircuits(open map[string]bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.openCircuits = open
+}
+
+// sinkRef is a LogSink or ClusterLogSink flattened for rendering.
+type sinkRef struct {
+	key       string
+	name      string
+	namespace string
+	cluster   bool
+	// glob is set on the refs of a cluster sink with namespace globs, which
+	// are rendered like a namespaced sink for each matching namespace.
+	glob bool
+	// throttled is set on the refs of the namespaced sinks of a namespace
+	// with a throttle, which read their own copy of the records so that
+	// the throttle does not drop the records of other sinks.
+	throttled bool
+	spec      v1alpha1.SinkSpec
+}
+
+// sinkRefs returns the sinks of the given type in the order they are
+// rendered: by priority, then namespaced sinks by namespace and name before
+// cluster sinks by name. A cluster sink with namespace globs has a ref for
+// each matching namespace, in order.
+func (sc *Config) sinkRefs(sinkType string) []sinkRef {
+	refs := make([]sinkRef, 0, len(sc.sinks))
+	for k, s := range sc.sinks {
+		if s.Spec.Type != sinkType {
 			continue
 		}
+		_, throttled := sc.nsThrottles[canonicalNamespace(s.Namespace)]
+		refs = append(refs, sinkRef{
+			key:       k,
+			name:      s.Name,
+			namespace: s.Namespace,
+			throttled: throttled,
+			spec:      s.Spec,
+		})
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		nsi, nsj := canonicalNamespace(refs[i].namespace), canonicalNamespace(refs[j].namespace)
+		if nsi != nsj {
+			return nsi < nsj
+		}
+		return refs[i].name < refs[j].name
+	})
 
-		var tlsConfig *tls
-		if s.Spec.EnableTLS {
-			tlsConfig = &tls{
-				InsecureSkipVerify: s.Spec.InsecureSkipVerify,
+	clusterRefs := make([]sinkRef, 0, len(sc.clusterSinks))
+	for k, s := range sc.clusterSinks {
+		if s.Spec.Type != sinkType {
+			continue
+		}
+		if len(s.Spec.NamespaceGlobs) > 0 {
+			for _, ns := range sc.globNamespaces(s.Spec.NamespaceGlobs) {
+				clusterRefs = append(clusterRefs, sinkRef{
+					key:       k,
+					name:      s.Name,
+					namespace: ns,
+					glob:      true,
+					spec:      s.Spec,
+				})
 			}
+			continue
 		}
-		sinks = append(sinks, sink{
-			Addr:      fmt.Sprintf("%s:%d", s.Spec.Host, s.Spec.Port),
-			Namespace: canonicalNamespace(s.Namespace),
-			TLS:       tlsConfig,
-			Name:      s.Name,
+		clusterRefs = append(clusterRefs, sinkRef{
+			key:     k,
+			name:    s.Name,
+			cluster: true,
+			spec:    s.Spec,
 		})
 	}
-	sort.Slice(sinks, func(i, j int) bool {
-		if sinks[i].Namespace != sinks[j].Namespace {
-			return sinks[i].Namespace < sinks[j].Namespace
+	sort.Slice(clusterRefs, func(i, j int) bool {
+		if clusterRefs[i].name != clusterRefs[j].name {
+			return clusterRefs[i].name < clusterRefs[j].name
 		}
-		return sinks[i].Name < sinks[j].Name
+		return clusterRefs[i].namespace < clusterRefs[j].namespace
 	})
 
-	clusterSinks := make(sinkList, 0, len(sc.clusterSinks))
-	for _, s := range sc.clusterSinks {
-		if s.Spec.Type != "syslog" {
+	return sortByPriority(append(refs, clusterRefs...))
+}
+
+// sortByPriority sorts refs by priority, keeping the order of refs with the
+// same priority.
+func sortByPriority(refs []sinkRef) []sinkRef {
+	sort.SliceStable(refs, func(i, j int) bool {
+		return refs[i].spec.Priority < refs[j].spec.Priority
+	})
+	return refs
+}
+
+// outputInstances maps the aliases of the outputs of the rendered config,
+// which fluent-bit reports their metrics by, to the circuits of the outputs.
+// Circuits are keyed by the sink they deliver for or, for syslog receivers,
+// by the sink and receiver. Outputs with an open circuit are rendered as
+// null outputs and are not included, nor is any sink while forwarding is
+// disabled. A cluster sink with namespace globs has an instance for each
+// namespace and a routed sink has one for each route.
+func (sc *Config) outputInstances() map[string]string {
+	instances := make(map[string]string)
+	if sc.forwardingDisabled {
+		return instances
+	}
+	for _, sinkType := range []string{"syslog", "webhook", "unix_socket"} {
+		for _, ref := range sc.sinkRefs(sinkType) {
+			if sc.unresolved(ref) {
+				continue
+			}
+			aliases := outputAliases(ref)
+			for i, k := range outputCircuits(ref) {
+				if sc.openCircuits[k] {
+					continue
+				}
+				instances[aliases[i]] = k
+			}
+		}
+	}
+	return instances
+}
+
+// outputAliases returns the alias of each output of a sink, in the order
+// they are rendered.
+func outputAliases(ref sinkRef) []string {
+	if routed(ref) {
+		aliases := []string{outputAlias(ref)}
+		for j := range routes(ref) {
+			aliases = append(aliases, routeAlias(ref, j))
+		}
+		return aliases
+	}
+
+	aliases := make([]string, outputCount(ref))
+	for j := range aliases {
+		aliases[j] = receiverAlias(ref, j)
+	}
+	return aliases
+}
+
+// outputCircuits returns the key of the circuit of each output of a sink,
+// in the order they are rendered.
+func outputCircuits(ref sinkRef) []string {
+	if receivers(ref) {
+		var keys []string
+		for _, addr := range syslogAddrs(ref.spec) {
+			keys = append(keys, receiverKey(ref, addr))
+		}
+		return keys
+	}
+
+	keys := make([]string, outputCount(ref))
+	for i := range keys {
+		keys[i] = ref.key
+	}
+	return keys
+}
+
+// receivers returns whether a sink sends its records to a list of syslog
+// receivers.
+func receivers(ref sinkRef) bool {
+	return ref.spec.Type == "syslog" && ref.spec.ServiceRef == nil && len(ref.spec.Receivers) > 0
+}
+
+// receiverKey returns the key of the circuit of a syslog receiver. Every
+// receiver has a circuit of its own, so a receiver that is down does not
+// discard the records of the others. A sink without receivers has a single
+// circuit keyed by the sink.
+func receiverKey(ref sinkRef, addr string) string {
+	if !receivers(ref) {
+		return ref.key
+	}
+	return ref.key + "#" + addr
+}
+
+// circuitSink returns the key of the sink a circuit belongs to.
+func circuitSink(k string) string {
+	if i := strings.LastIndex(k, "#"); i >= 0 {
+		return k[:i]
+	}
+	return k
+}
+
+// deleteCircuits forgets the circuits of a deleted sink. It is called with
+// sc.mu held.
+func (sc *Config) deleteCircuits(k string) {
+	for c := range sc.openCircuits {
+		if circuitSink(c) == k {
+			delete(sc.openCircuits, c)
+		}
+	}
+}
+
+// rawInputConfig renders a tail input for every raw mode sink. The input
+// tags records with the sink's own tag, so neither the shared parsers and
+// filters nor other sinks see them.
+func (sc *Config) rawInputConfig() string {
+	var config string
+	for _, ref := range sc.sinkRefs("webhook") {
+		if !ref.spec.RawMode || audited(ref) {
+			continue
+		}
+
+		path := "/var/log/containers/*.log"
+		if !ref.cluster {
+			path = fmt.Sprintf("/var/log/containers/*_%s_*.log", canonicalNamespace(ref.namespace))
+		}
+		tag := rawTag(ref)
+		config += fmt.Sprintf(rawInputConfig, tag, inputAlias(ref, "raw"), path, tag, ignoreOlderConfig(ref.spec.SkipLogsOlderThan)+sc.inputStorageConfig())
+	}
+
+	return config
+}
+
+func (sc *Config) webhookConfig() string {
+	var config string
+	for _, ref := range sc.sinkRefs("webhook") {
+		if sc.openCircuits[ref.key] {
+			config += nullOutputConfig(sinkMatch(ref), outputAlias(ref))
 			continue
 		}
 
+		config += buildHTTPConfig(sinkMatch(ref), outputAlias(ref), ref.spec, sc.bufferLimitConfig(ref.spec)+headersConfig(ref))
+		if routed(ref) {
+			config += sc.routesConfig(ref)
+		}
+	}
+
+	return config
+}
+
+func (sc *Config) syslogConfig() string {
+	var sinks sinkList
+	for _, ref := range sc.sinkRefs("syslog") {
 		var tlsConfig *tls
-		if s.Spec.EnableTLS {
+		if ref.spec.EnableTLS {
 			tlsConfig = &tls{
-				InsecureSkipVerify: s.Spec.InsecureSkipVerify,
+				InsecureSkipVerify: ref.spec.InsecureSkipVerify,
 			}
 		}
-		clusterSinks = append(clusterSinks, sink{
-			Addr: fmt.Sprintf("%s:%d", s.Spec.Host, s.Spec.Port),
-			TLS:  tlsConfig,
-			Name: s.Name,
-		})
+
+		var namespace string
+		if !ref.cluster {
+			namespace = canonicalNamespace(ref.namespace)
+		}
+
+		addrs := syslogAddrs(ref.spec)
+		resolved := true
+		if ref.spec.ServiceRef != nil {
+			var addr string
+			addr, resolved = sc.serviceAddr(ref)
+			if !resolved {
+				log.Printf("Port %s of service %s referenced by sink %s not found", ref.spec.ServiceRef.PortName, serviceRefKey(ref), ref.name)
+			}
+			addrs = []string{addr}
+		}
+
+		// The instance name is the sink's alias rather than its name, so
+		// a LogSink and a ClusterLogSink of the same name, or LogSinks of
+		// the same name in two namespaces, are told apart.
+		for j, addr := range addrs {
+			sinks = append(sinks, sink{
+				Match:          sinkMatch(ref),
+				Alias:          receiverAlias(ref, j),
+				Addr:           addr,
+				Namespace:      namespace,
+				TLS:            tlsConfig,
+				Name:           outputAlias(ref),
+				StructuredData: ref.spec.StructuredData,
+				Framing:        ref.spec.Framing,
+				MsgID:          ref.spec.MsgID,
+				CircuitOpen:    sc.openCircuits[receiverKey(ref, addr)] || !resolved,
+				BufferLimit:    sc.bufferLimitConfig(ref.spec),
+			})
+		}
 	}
-	sort.Slice(clusterSinks, func(i, j int) bool {
-		return clusterSinks[i].Name < clusterSinks[j].Name
-	})
 
-	if len(sinks)+len(clusterSinks) == 0 {
+	if len(sinks) == 0 {
 		return ""
 	}
 
-	return sinks.String() + clusterSinks.String()
+	return sinks.String()
+}
+
+// syslogAddrs returns the address of every receiver of a syslog sink, or
+// its host and port when it lists no receivers.
+func syslogAddrs(spec v1alpha1.SinkSpec) []string {
+	if len(spec.Receivers) == 0 {
+		return []string{fmt.Sprintf("%s:%d", spec.Host, spec.Port)}
+	}
+
+	addrs := make([]string, 0, len(spec.Receivers))
+	for _, r := range spec.Receivers {
+		addrs = append(addrs, fmt.Sprintf("%s:%d", r.Host, r.Port))
+	}
+	return addrs
 }
 
 type sink struct {
-	Addr      string `json:"addr"`
-	Namespace string `json:"namespace,omitempty"`
-	TLS       *tls   `json:"tls,omitempty"`
-	Name      string `json:"name,omitempty"`
+	Match          string                       `json:"-"`
+	Alias          string                       `json:"-"`
+	Addr           string                       `json:"addr"`
+	Namespace      string                       `json:"namespace,omitempty"`
+	TLS            *tls                         `json:"tls,omitempty"`
+	Name           string                       `json:"name,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Framing        string                       `json:"framing,omitempty"`
+	MsgID          string                       `json:"msg_id,omitempty"`
+	CircuitOpen    bool                         `json:"-"`
+	BufferLimit    string                       `json:"-"`
 }
 
 type sinkList []sink
@@ -187,6 +658,10 @@ func (ss sinkList) String() string {
 }
 
 func (s *sink) String() string {
+	if s.CircuitOpen {
+		return nullOutputConfig(s.Match, s.Alias)
+	}
+
 	var clusterOrNamespace string
 	if s.Namespace != "" {
 		clusterOrNamespace = fmt.Sprintf("Namespace %s", s.Namespace)
@@ -197,12 +672,49 @@ func (s *sink) String() string {
 	return fmt.Sprintf(`
 [OUTPUT]
     Name syslog
-    Match *
+    Match %s
+    Alias %s
     InstanceName %s
     Addr %s
-    %s%s
-`, s.Name, s.Addr, clusterOrNamespace, s.TLS.String())
+    %s%s%s%s%s
+%s`, s.Match, s.Alias, s.Name, s.Addr, clusterOrNamespace, s.TLS.String(), structuredDataConfig(s.StructuredData), framingConfig(s.Framing), msgIDConfig(s.MsgID), s.BufferLimit)
+
+}
+
+// structuredDataConfig renders the structured data option of the syslog
+// output. Map keys are marshalled in order, so the option is stable.
+func structuredDataConfig(sd map[string]map[string]string) string {
+	if len(sd) == 0 {
+		return ""
+	}
 
+	b, err := json.Marshal(sd)
+	if err != nil {
+		log.Print("unable to marshal sink structured data")
+		return ""
+	}
+
+	return fmt.Sprintf("\n    StructuredData %s", b)
+}
+
+// framingConfig renders the framing option of the syslog output. The
+// option is left out when empty so the output keeps its default framing.
+func framingConfig(framing string) string {
+	if framing == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("\n    Framing %s", framing)
+}
+
+// msgIDConfig renders the MSGID option of the syslog output, which replaces
+// the record references of the MSGID with the values of each record.
+func msgIDConfig(msgID string) string {
+	if msgID == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("\n    MsgID %s", msgID)
 }
 
 type tls struct {
@@ -223,7 +735,7 @@ func (t *tls) String() string {
 	return fmt.Sprintf("\n    TLSConfig %s", b)
 }
 
-func buildHTTPConfig(namespace string, spec v1alpha1.SinkSpec, isCluster bool) string {
+func buildHTTPConfig(match, alias string, spec v1alpha1.SinkSpec, extra string) string {
 	url, err := url.Parse(spec.URL)
 	if err != nil {
 		return ""
@@ -251,10 +763,27 @@ func buildHTTPConfig(namespace string, spec v1alpha1.SinkSpec, isCluster bool) s
 		}
 	}
 
-	match := fmt.Sprintf("*_%s_*", namespace)
-	if isCluster {
-		match = "*"
+	if spec.KeepAlive {
+		extras += "    net.keepalive on\n"
+		if spec.KeepAliveIdleTimeout != nil {
+			extras += fmt.Sprintf(
+				"    net.keepalive_idle_timeout %d\n",
+				int(math.Ceil(spec.KeepAliveIdleTimeout.Seconds())),
+			)
+		}
+	}
+
+	if spec.MaxConnections > 0 {
+		extras += fmt.Sprintf("    net.max_worker_connections %d\n", spec.MaxConnections)
+	}
+
+	if spec.RetentionHint != nil {
+		extras += fmt.Sprintf(
+			"    Header X-Retention-Hint %d\n",
+			int(math.Ceil(spec.RetentionHint.Seconds())),
+		)
 	}
+	extras += extra
 
 	path := url.Path
 	if path == "" {
@@ -264,6 +793,7 @@ func buildHTTPConfig(namespace string, spec v1alpha1.SinkSpec, isCluster bool) s
 	return fmt.Sprintf(
 		httpOutputConfig,
 		match,
+		alias,
 		url.Hostname(),
 		port,
 		path,
@@ -271,6 +801,95 @@ func buildHTTPConfig(namespace string, spec v1alpha1.SinkSpec, isCluster bool) s
 	)
 }
 
+// nullOutputConfig discards records for a sink whose circuit is open. The
+// output keeps the alias of the output it replaces.
+func nullOutputConfig(match, alias string) string {
+	return fmt.Sprintf(`
+[OUTPUT]
+    Name null
+    Match %s
+    Alias %s
+`, match, alias)
+}
+
+// outputAlias names the output of a sink after the sink, e.g. "ns1/sink"
+// or "cluster:sink", so fluent-bit reports its metrics and tools read its
+// destination by sink rather than by the order outputs are rendered in. A
+// cluster sink with namespace globs has an alias for each namespace, e.g.
+// "cluster:sink/ns1". Names have neither colons nor slashes, so aliases do
+// not collide.
+func outputAlias(ref sinkRef) string {
+	if ref.cluster {
+		return fmt.Sprintf("cluster:%s", ref.name)
+	}
+	if ref.glob {
+		return fmt.Sprintf("cluster:%s/%s", ref.name, ref.namespace)
+	}
+	return fmt.Sprintf("%s/%s", canonicalNamespace(ref.namespace), ref.name)
+}
+
+// receiverAlias is the alias of the output of the jth receiver of a sink.
+func receiverAlias(ref sinkRef, j int) string {
+	if !receivers(ref) {
+		return outputAlias(ref)
+	}
+	return fmt.Sprintf("%s:receiver:%d", outputAlias(ref), j)
+}
+
+// inputAlias names an input that reads the records of a sink after the
+// sink and the input, e.g. "ns1/sink:raw" or "cluster:sink:project", so
+// the storage metrics of the input are reported by sink. The emitters of
+// the records a sink copies are inputs too.
+func inputAlias(ref sinkRef, input string) string {
+	return fmt.Sprintf("%s:%s", outputAlias(ref), input)
+}
+
+// sinkMatch is the pattern a sink's filters and output match records with.
+func sinkMatch(ref sinkRef) string {
+	if audited(ref) {
+		return auditTag(ref)
+	}
+	if ref.spec.Type == "webhook" && ref.spec.RawMode {
+		return rawTag(ref)
+	}
+	if projected(ref) {
+		return projectTag(ref)
+	}
+	if routed(ref) {
+		return routeTag(ref)
+	}
+	if nodeCopied(ref) {
+		return nodeTag(ref)
+	}
+	if filterCopied(ref) {
+		return filterTag(ref)
+	}
+	return namespaceMatch(ref.namespace, ref.cluster)
+}
+
+// rawTag is the tag of the records read by a raw mode sink's input.
+func rawTag(ref sinkRef) string {
+	if ref.cluster {
+		return fmt.Sprintf("raw.cluster.%s", ref.name)
+	}
+	if ref.glob {
+		return fmt.Sprintf("raw.glob.%s.%s", ref.name, ref.namespace)
+	}
+	return fmt.Sprintf("raw.ns.%s.%s", canonicalNamespace(ref.namespace), ref.name)
+}
+
+// namespaceMatch is the pattern of the records of a namespace, or of every
+// namespace for a cluster sink. The tags the container logs and events are
+// read with have the namespace between underscores. The tags of the copies
+// and inputs of sinks are made of names and namespaces, which cannot
+// contain underscores, so cluster sinks do not receive them.
+func namespaceMatch(namespace string, isCluster bool) string {
+	if isCluster {
+		return "*_*"
+	}
+	return fmt.Sprintf("*_%s_*", canonicalNamespace(namespace))
+}
+
 func canonicalNamespace(ns string) string {
 	if ns == "" {
 		return "default"
@@ -278,10 +897,13 @@ func canonicalNamespace(ns string) string {
 	return ns
 }
 
+// key and clusterKey identify a sink by its kind as well as its namespace
+// and name, so a LogSink without a namespace, as in rendered manifests,
+// does not share its key with a ClusterLogSink of the same name.
 func key(s *v1alpha1.LogSink) string {
-	return fmt.Sprintf("%s|%s", s.Namespace, s.Name)
+	return fmt.Sprintf("LogSink|%s|%s", canonicalNamespace(s.Namespace), s.Name)
 }
 
 func clusterKey(s *v1alpha1.ClusterLogSink) string {
-	return fmt.Sprintf("%s|%s", s.ClusterName, s.Name)
+	return fmt.Sprintf("ClusterLogSink|%s|%s", s.ClusterName, s.Name)
 }
@@ -18,6 +18,8 @@ package sink_test
 import (
 	"encoding/json"
 	"fmt"
+	"path"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +27,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
 	"github.com/knative/observability/pkg/sink"
 	"github.com/knative/observability/pkg/sink/flbconfig"
 )
@@ -42,6 +45,290 @@ func TestEmptyConfig(t *testing.T) {
 	}
 }
 
+func TestSinkPriority(t *testing.T) {
+	webhookSink := func(name, namespace string, priority int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/" + name,
+				},
+				Priority: priority,
+			},
+		}
+	}
+
+	t.Run("it renders sinks with a lower priority first", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink("a-sink", "ns1", 10))
+		sc.UpsertSink(webhookSink("b-sink", "ns1", -5))
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/cluster-sink",
+				},
+				StartupDelay: &metav1.Duration{Duration: time.Second},
+				StartupRate:  10,
+				Priority:     1,
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "syslog-sink",
+				Namespace: "ns2",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				StartupDelay: &metav1.Duration{Duration: time.Second},
+				StartupRate:  100,
+				Priority:     5,
+			},
+		})
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* filtered.cluster.cluster-sink true
+    Emitter_Name cluster:cluster-sink:filtered
+
+[FILTER]
+    Name rewrite_tag
+    Match *_ns2_*
+    Rule $log .* filtered.ns.ns2.syslog-sink true
+    Emitter_Name ns2/syslog-sink:filtered
+
+[FILTER]
+    Name lua
+    Match filtered.cluster.cluster-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_1
+
+[FILTER]
+    Name lua
+    Match filtered.ns.ns2.syslog-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_2
+
+[OUTPUT]
+    Name syslog
+    Match filtered.ns.ns2.syslog-sink
+    Alias ns2/syslog-sink
+    InstanceName ns2/syslog-sink
+    Addr example.com:12345
+    Namespace ns2
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/b-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /b-sink
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match filtered.cluster.cluster-sink
+    Alias cluster:cluster-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /cluster-sink
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/a-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /a-sink
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it breaks ties by namespace and name", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink("b-sink", "ns1", 1))
+		sc.UpsertSink(webhookSink("a-sink", "ns2", 1))
+		sc.UpsertSink(webhookSink("a-sink", "ns1", 1))
+
+		// ns1/a-sink, ns1/b-sink, ns2/a-sink
+		expected := []string{"URI /a-sink", "URI /b-sink", "URI /a-sink"}
+		var got []string
+		for _, line := range strings.Split(sc.String(), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "URI ") {
+				got = append(got, strings.TrimSpace(line))
+			}
+		}
+		if diff := cmp.Diff(expected, got); diff != "" {
+			t.Errorf("Order not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestSameNamedSinks(t *testing.T) {
+	t.Run("it tags the records of a LogSink and ClusterLogSink apart", func(t *testing.T) {
+		specs := map[string]v1alpha1.SinkSpec{
+			"raw": {
+				RawMode: true,
+			},
+			"project": {
+				Project: []string{"log"},
+			},
+			"nodes": {
+				NodeSelector: map[string]string{"pool": "gpu"},
+			},
+		}
+		for prefix, spec := range specs {
+			t.Run(prefix, func(t *testing.T) {
+				spec.Type = "webhook"
+				spec.URL = "https://example.com/logs"
+				sc := sink.NewConfig()
+				sc.SetNode("gpu-1", map[string]string{"pool": "gpu"})
+				sc.UpsertSink(&v1alpha1.LogSink{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "cluster"},
+					Spec:       spec,
+				})
+				sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+					ObjectMeta: metav1.ObjectMeta{Name: "app"},
+					Spec:       spec,
+				})
+
+				config := sc.String()
+				for _, tag := range []string{
+					prefix + ".ns.cluster.app",
+					prefix + ".cluster.app",
+				} {
+					output := "[OUTPUT]\n    Name http\n    Match " + tag + "\n"
+					if n := strings.Count(config, output); n != 1 {
+						t.Errorf("Expected one output of %s, got %d in %s", tag, n, config)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("it names the syslog outputs of same-named sinks apart", func(t *testing.T) {
+		spec := v1alpha1.SinkSpec{
+			Type:       "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{Host: "example.com", Port: 514},
+		}
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns1"},
+			Spec:       spec,
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns2"},
+			Spec:       spec,
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "app"},
+			Spec:       spec,
+		})
+
+		config := sc.String()
+		for _, name := range []string{"ns1/app", "ns2/app", "cluster:app"} {
+			line := "\n    InstanceName " + name + "\n"
+			if n := strings.Count(config, line); n != 1 {
+				t.Errorf("Expected one output named %s, got %d in %s", name, n, config)
+			}
+		}
+	})
+}
+
+func TestClusterSinkMatch(t *testing.T) {
+	webhookSink := func(name string, spec v1alpha1.SinkSpec) *v1alpha1.LogSink {
+		spec.Type = "webhook"
+		spec.URL = "https://example.com/" + name
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: spec,
+		}
+	}
+
+	sc := sink.NewConfig()
+	sc.UpsertSink(webhookSink("projected", v1alpha1.SinkSpec{Project: []string{"log"}}))
+	sc.UpsertSink(webhookSink("filtered", v1alpha1.SinkSpec{ParseJSONBody: true}))
+	sc.UpsertSink(webhookSink("raw", v1alpha1.SinkSpec{RawMode: true}))
+	sc.UpsertSink(webhookSink("heartbeat", v1alpha1.SinkSpec{
+		Heartbeat: &v1alpha1.HeartbeatSpec{Interval: metav1.Duration{Duration: time.Minute}},
+	}))
+	sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	})
+
+	f, err := flbconfig.Parse("", sc.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var match string
+	var ownTags []string
+	for _, s := range f.Sections {
+		params := make(map[string]string)
+		for _, kv := range s.KeyValues {
+			params[kv.Key] = kv.Value
+		}
+		switch {
+		case params["Name"] == "syslog":
+			match = params["Match"]
+		case params["Tag"] != "":
+			ownTags = append(ownTags, params["Tag"])
+		case params["Name"] == "rewrite_tag":
+			ownTags = append(ownTags, strings.Fields(params["Rule"])[2])
+		}
+	}
+	if len(ownTags) != 5 {
+		t.Fatalf("Expected the tags of 3 copies and 2 inputs, got %v", ownTags)
+	}
+
+	for _, tag := range []string{
+		"kube.var.log.containers.app-6d4c_ns1_app-2f9e.log",
+		"k8s.event._ns1_",
+	} {
+		if ok, _ := path.Match(match, tag); !ok {
+			t.Errorf("Expected the cluster sink to match %s, got %s", tag, match)
+		}
+	}
+	for _, tag := range ownTags {
+		if ok, _ := path.Match(match, tag); ok {
+			t.Errorf("Expected the cluster sink not to match %s, got %s", tag, match)
+		}
+	}
+}
+
 func TestSyslogSinks(t *testing.T) {
 	t.Run("it generates separate config for log sinks and cluster log sinks", func(t *testing.T) {
 		sc := sink.NewConfig()
@@ -744,6 +1031,453 @@ func TestSyslogSinks(t *testing.T) {
 	})
 }
 
+func TestSyslogStructuredData(t *testing.T) {
+	t.Run("it renders the structured data of the sink", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host:      "example.com",
+					Port:      12345,
+					EnableTLS: true,
+					StructuredData: map[string]map[string]string{
+						"siem@32473": {"team": "a", "env": "prod"},
+						"meta":       {"source": "k8s"},
+					},
+				},
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/some-sink
+    InstanceName ns1/some-sink
+    Addr example.com:12345
+    Namespace ns1
+    TLSConfig {}
+    StructuredData {"meta":{"source":"k8s"},"siem@32473":{"env":"prod","team":"a"}}
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not render empty structured data", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host:           "example.com",
+					Port:           12345,
+					StructuredData: map[string]map[string]string{},
+				},
+			},
+		})
+
+		if config := sc.String(); strings.Contains(config, "StructuredData") {
+			t.Errorf("Expected no structured data, got %s", config)
+		}
+	})
+}
+
+func TestSyslogFraming(t *testing.T) {
+	for _, framing := range []string{"octet-counting", "non-transparent"} {
+		t.Run("it renders "+framing+" framing", func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "some-sink",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: v1alpha1.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						Framing:   framing,
+					},
+				},
+			})
+
+			expected := `
+[OUTPUT]
+    Name syslog
+    Match *_*
+    Alias cluster:some-sink
+    InstanceName cluster:some-sink
+    Addr example.com:12345
+    Cluster true
+    TLSConfig {}
+    Framing ` + framing + `
+`
+			if diff := cmp.Diff(expected, sc.String()); diff != "" {
+				t.Errorf("Config not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+
+	t.Run("it does not render framing when unset", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+
+		if config := sc.String(); strings.Contains(config, "Framing") {
+			t.Errorf("Expected no framing, got %s", config)
+		}
+	})
+}
+
+func TestSyslogMsgID(t *testing.T) {
+	for name, msgID := range map[string]string{
+		"static":       "app-logs",
+		"interpolated": "$kubernetes['labels']['app']-$stream",
+	} {
+		t.Run("it renders a "+name+" msgid", func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "some-sink",
+				},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: v1alpha1.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						Framing:   "octet-counting",
+						MsgID:     msgID,
+					},
+				},
+			})
+
+			expected := `
+[OUTPUT]
+    Name syslog
+    Match *_*
+    Alias cluster:some-sink
+    InstanceName cluster:some-sink
+    Addr example.com:12345
+    Cluster true
+    TLSConfig {}
+    Framing octet-counting
+    MsgID ` + msgID + `
+`
+			if diff := cmp.Diff(expected, sc.String()); diff != "" {
+				t.Errorf("Config not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+
+	t.Run("it does not render a msgid when unset", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "some-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+
+		if config := sc.String(); strings.Contains(config, "MsgID") {
+			t.Errorf("Expected no msgid, got %s", config)
+		}
+	})
+}
+
+func TestSyslogReceivers(t *testing.T) {
+	receiversSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				EnableTLS: true,
+				Receivers: []v1alpha1.SyslogReceiver{
+					{Host: "primary.example.com", Port: 6514},
+					{Host: "secondary.example.com", Port: 6514},
+				},
+			},
+		},
+	}
+
+	t.Run("it renders an output for each receiver", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(receiversSink)
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/some-sink:receiver:0
+    InstanceName ns1/some-sink
+    Addr primary.example.com:6514
+    Namespace ns1
+    TLSConfig {}
+
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/some-sink:receiver:1
+    InstanceName ns1/some-sink
+    Addr secondary.example.com:6514
+    Namespace ns1
+    TLSConfig {}
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it only opens the circuit of the failing receiver", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(receiversSink)
+		b := sink.NewBreaker(
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			fake.NewSimpleClientset(receiversSink).ObservabilityV1alpha1(),
+			sc,
+			sink.WithFailureThreshold(1),
+		)
+
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink:receiver:0": {}, "ns1/some-sink:receiver:1": {}})
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink:receiver:0": {}, "ns1/some-sink:receiver:1": {Errors: 1}})
+
+		config := sc.String()
+		if strings.Count(config, "Name null") != 1 || !strings.Contains(config, "Addr primary.example.com:6514") {
+			t.Errorf("Expected only the secondary output to discard records, got %s", config)
+		}
+
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink:receiver:0": {}})
+		b.Observe(map[string]sink.OutputMetrics{"ns1/some-sink:receiver:0": {Errors: 1}})
+		if config := sc.String(); strings.Count(config, "Name null") != 2 {
+			t.Errorf("Expected both outputs to discard records, got %s", config)
+		}
+	})
+}
+
+func TestWebhookKeepAlive(t *testing.T) {
+	webhookSink := func(spec v1alpha1.WebhookSpec) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: spec,
+			},
+		}
+	}
+
+	t.Run("it renders keepalive with an idle timeout", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink(v1alpha1.WebhookSpec{
+			URL:                  "https://example.com/logs",
+			KeepAlive:            true,
+			KeepAliveIdleTimeout: &metav1.Duration{Duration: 90 * time.Second},
+		}))
+
+		expected := `
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/some-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+    net.keepalive on
+    net.keepalive_idle_timeout 90
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it renders keepalive without an idle timeout", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink(v1alpha1.WebhookSpec{
+			URL:       "https://example.com/logs",
+			KeepAlive: true,
+		}))
+
+		config := sc.String()
+		if !strings.Contains(config, "    net.keepalive on\n") {
+			t.Errorf("Expected keepalive to be on, got %s", config)
+		}
+		if strings.Contains(config, "net.keepalive_idle_timeout") {
+			t.Errorf("Expected no idle timeout, got %s", config)
+		}
+	})
+
+	t.Run("it does not render keepalive when off", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink(v1alpha1.WebhookSpec{
+			URL: "https://example.com/logs",
+		}))
+
+		if config := sc.String(); strings.Contains(config, "net.keepalive") {
+			t.Errorf("Expected no keepalive, got %s", config)
+		}
+	})
+}
+
+func TestWebhookMaxConnections(t *testing.T) {
+	webhookSink := func(spec v1alpha1.WebhookSpec) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: spec,
+			},
+		}
+	}
+
+	t.Run("it caps the connections to the webhook", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink(v1alpha1.WebhookSpec{
+			URL:            "https://example.com/logs",
+			KeepAlive:      true,
+			MaxConnections: 4,
+		}))
+
+		expected := `
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/some-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+    net.keepalive on
+    net.max_worker_connections 4
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not cap the connections by default", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink(v1alpha1.WebhookSpec{
+			URL: "https://example.com/logs",
+		}))
+
+		if config := sc.String(); strings.Contains(config, "net.max_worker_connections") {
+			t.Errorf("Expected no connection cap, got %s", config)
+		}
+	})
+}
+
+func TestRetentionHint(t *testing.T) {
+	hintedSink := func(spec v1alpha1.SinkSpec) *v1alpha1.LogSink {
+		spec.RetentionHint = &metav1.Duration{Duration: 30 * 24 * time.Hour}
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: spec,
+		}
+	}
+
+	t.Run("it sends the hint in a header of webhook requests", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(hintedSink(v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/logs",
+			},
+		}))
+
+		expected := `
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/some-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+    Header X-Retention-Hint 2592000
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it ignores the hint of syslog and unix socket sinks", func(t *testing.T) {
+		for _, spec := range []v1alpha1.SinkSpec{
+			{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+			{
+				Type: "unix_socket",
+				UnixSocketSpec: v1alpha1.UnixSocketSpec{
+					Path: "/var/run/observability/collector.sock",
+				},
+			},
+		} {
+			hinted := sink.NewConfig()
+			hinted.UpsertSink(hintedSink(spec))
+			plain := sink.NewConfig()
+			plain.UpsertSink(&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "some-sink",
+					Namespace: "ns1",
+				},
+				Spec: spec,
+			})
+
+			if diff := cmp.Diff(plain.String(), hinted.String()); diff != "" {
+				t.Errorf("Expected the %s config to be unchanged (-want, +got) = %v", spec.Type, diff)
+			}
+		}
+	})
+}
+
 func TestWebhookSinks(t *testing.T) {
 	testCases := map[string]struct {
 		logSinks        []*v1alpha1.LogSink
@@ -780,6 +1514,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace/some-name",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -835,6 +1573,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace/some-name",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -893,6 +1635,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace/some-name",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -943,6 +1689,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace/some-name",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -1005,6 +1755,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace-1_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace-1/some-name-1",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -1034,6 +1788,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace-2_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace-2/some-name-2",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -1081,7 +1839,11 @@ func TestWebhookSinks(t *testing.T) {
 						},
 						{
 							Key:   "Match",
-							Value: "*",
+							Value: "*_*",
+						},
+						{
+							Key:   "Alias",
+							Value: "cluster:some-name",
 						},
 						{
 							Key:   "Format",
@@ -1153,6 +1915,10 @@ func TestWebhookSinks(t *testing.T) {
 							Key:   "Match",
 							Value: "*_some-namespace_*",
 						},
+						{
+							Key:   "Alias",
+							Value: "some-namespace/some-name",
+						},
 						{
 							Key:   "Format",
 							Value: "json",
@@ -1282,18 +2048,22 @@ func createOutputSection(sink interface{}) flbconfig.Section {
 			Key:   "Name",
 			Value: "syslog",
 		},
-		{
-			Key:   "Match",
-			Value: "*",
-		},
 	}
 
 	switch s := sink.(type) {
 	case namespaceSink:
 		keyValues = append(keyValues,
+			flbconfig.KeyValue{
+				Key:   "Match",
+				Value: fmt.Sprintf("*_%s_*", s.Namespace),
+			},
+			flbconfig.KeyValue{
+				Key:   "Alias",
+				Value: fmt.Sprintf("%s/%s", s.Namespace, s.Name),
+			},
 			flbconfig.KeyValue{
 				Key:   "InstanceName",
-				Value: s.Name,
+				Value: fmt.Sprintf("%s/%s", s.Namespace, s.Name),
 			},
 			flbconfig.KeyValue{
 				Key:   "Addr",
@@ -1310,9 +2080,17 @@ func createOutputSection(sink interface{}) flbconfig.Section {
 
 	case clusterSink:
 		keyValues = append(keyValues,
+			flbconfig.KeyValue{
+				Key:   "Match",
+				Value: "*_*",
+			},
+			flbconfig.KeyValue{
+				Key:   "Alias",
+				Value: fmt.Sprintf("cluster:%s", s.Name),
+			},
 			flbconfig.KeyValue{
 				Key:   "InstanceName",
-				Value: s.Name,
+				Value: fmt.Sprintf("cluster:%s", s.Name),
 			},
 			flbconfig.KeyValue{
 				Key:   "Addr",
@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+type ConfigMapCreator interface {
+	Create(*coreV1.ConfigMap) (*coreV1.ConfigMap, error)
+}
+
+// ConfigMapController recreates the fluent-bit configmap when it is deleted
+// out of band. Without it, every patch fails until the configmap is created
+// again by hand.
+type ConfigMapController struct {
+	cmc ConfigMapCreator
+	cmp ConfigMapPatcher
+	dsp DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewConfigMapController(
+	cmc ConfigMapCreator,
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	sc *Config,
+) *ConfigMapController {
+	return &ConfigMapController{
+		cmc: cmc,
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+func (c *ConfigMapController) OnAdd(o interface{}) {}
+
+func (c *ConfigMapController) OnUpdate(old, new interface{}) {}
+
+// OnDelete creates the configmap again from its last state, which includes
+// the files fluent-bit is deployed with and the options set at startup,
+// and then writes the current sinks to it.
+func (c *ConfigMapController) OnDelete(o interface{}) {
+	if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+		o = tombstone.Obj
+	}
+	cm, ok := o.(*coreV1.ConfigMap)
+	if !ok || cm.Name != ConfigMapName {
+		return
+	}
+
+	log.Printf("ConfigMap %s was deleted, recreating it", cm.Name)
+	_, err := c.cmc.Create(&coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cm.Name,
+			Namespace:   cm.Namespace,
+			Labels:      cm.Labels,
+			Annotations: cm.Annotations,
+		},
+		Data: cm.Data,
+	})
+	if err != nil {
+		log.Printf("Unable to recreate ConfigMap %s: %s", cm.Name, err)
+		return
+	}
+
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("ConfigMap %s recreated", cm.Name))
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestConfigMapController(t *testing.T) {
+	deleted := &coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "fluent-bit",
+			Namespace:       "knative-observability",
+			Labels:          map[string]string{"app": "fluent-bit"},
+			ResourceVersion: "42",
+			UID:             "some-uid",
+		},
+		Data: map[string]string{
+			"fluent-bit.conf": "[SERVICE]",
+			"outputs.conf":    "stale",
+		},
+	}
+	newConfig := func() *sink.Config {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+		return sc
+	}
+
+	t.Run("it recreates the deleted configmap with the current sinks", func(t *testing.T) {
+		creator := &spyConfigMapCreator{}
+		patcher := &spyConfigMapPatcher{}
+		deleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewConfigMapController(creator, patcher, deleter, newConfig())
+
+		c.OnDelete(deleted)
+
+		expected := []*coreV1.ConfigMap{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fluent-bit",
+				Namespace: "knative-observability",
+				Labels:    map[string]string{"app": "fluent-bit"},
+			},
+			Data: deleted.Data,
+		}}
+		if diff := cmp.Diff(expected, creator.created); diff != "" {
+			t.Errorf("Created configmaps not equal (-want, +got) = %v", diff)
+		}
+		if !strings.Contains(lastOutputs(t, patcher), "Addr example.com:12345") {
+			t.Errorf("Expected the current sinks to be written, got %s", lastOutputs(t, patcher))
+		}
+		if deleter.Selector != "app=fluent-bit" {
+			t.Errorf("Expected fluent-bit pods to be restarted, got selector %q", deleter.Selector)
+		}
+	})
+
+	t.Run("it recreates configmaps deleted while disconnected", func(t *testing.T) {
+		creator := &spyConfigMapCreator{}
+		c := sink.NewConfigMapController(creator, &spyConfigMapPatcher{}, &spyDaemonSetPodDeleter{}, newConfig())
+
+		c.OnDelete(cache.DeletedFinalStateUnknown{Key: "knative-observability/fluent-bit", Obj: deleted})
+
+		if len(creator.created) != 1 {
+			t.Errorf("Expected the configmap to be recreated, got %v", creator.created)
+		}
+	})
+
+	t.Run("it ignores other configmaps", func(t *testing.T) {
+		creator := &spyConfigMapCreator{}
+		patcher := &spyConfigMapPatcher{}
+		c := sink.NewConfigMapController(creator, patcher, &spyDaemonSetPodDeleter{}, newConfig())
+
+		other := deleted.DeepCopy()
+		other.Name = "other"
+		c.OnDelete(other)
+
+		if len(creator.created) != 0 || patcher.patchCalled {
+			t.Error("Expected other configmaps to be ignored")
+		}
+	})
+
+	t.Run("it does not patch when the configmap cannot be created", func(t *testing.T) {
+		creator := &spyConfigMapCreator{err: errors.New("forbidden")}
+		patcher := &spyConfigMapPatcher{}
+		c := sink.NewConfigMapController(creator, patcher, &spyDaemonSetPodDeleter{}, newConfig())
+
+		c.OnDelete(deleted)
+
+		if patcher.patchCalled {
+			t.Error("Expected no patch")
+		}
+	})
+}
+
+type spyConfigMapCreator struct {
+	created []*coreV1.ConfigMap
+	err     error
+}
+
+func (s *spyConfigMapCreator) Create(cm *coreV1.ConfigMap) (*coreV1.ConfigMap, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.created = append(s.created, cm)
+	return cm, nil
+}
@@ -17,8 +17,10 @@ package sink
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"reflect"
+	"time"
 
 	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,14 +49,7 @@ func (c *Controller) OnAdd(o interface{}) {
 
 	c.sc.UpsertSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
-	}
-	patchConfig(patches, c.cmp, c.dsp)
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("LogSink %s/%s upserted", d.Namespace, d.Name))
 }
 
 func (c *Controller) OnDelete(o interface{}) {
@@ -65,37 +60,109 @@ func (c *Controller) OnDelete(o interface{}) {
 
 	c.sc.DeleteSink(d)
 
-	patches := []patch{
-		{
-			Op:    "replace",
-			Path:  "/data/outputs.conf",
-			Value: c.sc.String(),
-		},
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("LogSink %s/%s deleted", d.Namespace, d.Name))
+}
+
+// applyConfig writes every sink to the configmap, syncs their header values
+// and restarts fluent-bit. Nothing is written if the config can not be
+// rendered or the reconciles are paused. reason is the change being
+// reconciled, which is recorded with the decision in the reconcile audit.
+func applyConfig(sc *Config, cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, reason string) {
+	applyNodeConfig(sc, cmp, dsp, "", reason)
+}
+
+// applyNodeConfig applies the config like applyConfig but only restarts the
+// fluent-bit pod of node, or every pod when node is empty.
+func applyNodeConfig(sc *Config, cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, node, reason string) {
+	if sc.hold() {
+		sc.auditReconcile(reason, DecisionHeld, nil)
+		return
+	}
+
+	patches, err := sc.patches()
+	if err != nil {
+		log.Printf("Unable to render config, keeping the last config: %s", err)
+		sc.auditReconcile(reason, DecisionRenderFailed, err)
+		return
+	}
+
+	if err := patchConfigMap(patches, cmp); err != nil {
+		sc.auditReconcile(reason, DecisionPatchFailed, err)
+	} else {
+		sc.configApplied(time.Now())
+		sc.auditReconcile(reason, DecisionApplied, nil)
+		sc.exportConfig(patches, reason)
+	}
+	sc.syncHeaders()
+	deleteNodeFluentBitPods(dsp, node)
+}
+
+// patchConfig applies patches to the parts of the config that are not
+// rendered from the sinks and restarts fluent-bit. While the reconciles are
+// paused the patches are held until they resume. The decision is recorded
+// in the reconcile audit with reason.
+func patchConfig(sc *Config, cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, patches []patch, reason string) {
+	if sc.holdPatches(patches) {
+		sc.auditReconcile(reason, DecisionHeld, nil)
+		return
+	}
+
+	if err := patchConfigMap(patches, cmp); err != nil {
+		sc.auditReconcile(reason, DecisionPatchFailed, err)
+	} else {
+		sc.auditReconcile(reason, DecisionApplied, nil)
+	}
+	deleteFluentBitPods(dsp)
+}
+
+// restartFluentBit restarts fluent-bit to read a change outside of the
+// configmap. While the reconciles are paused the restart is held until they
+// resume. The restart is recorded in the reconcile audit with reason.
+func restartFluentBit(sc *Config, dsp DaemonSetPodDeleter, reason string) {
+	if sc.hold() {
+		sc.auditReconcile(reason, DecisionHeld, nil)
+		return
 	}
-	patchConfig(patches, c.cmp, c.dsp)
+
+	sc.auditReconcile(reason, DecisionRestarted, nil)
+	deleteFluentBitPods(dsp)
 }
 
-func patchConfig(patches []patch, cmp ConfigMapPatcher, dsp DaemonSetPodDeleter) {
+// patchConfigMap applies the patches to the fluent-bit configmap and returns
+// the error they were not applied with.
+func patchConfigMap(patches []patch, cmp ConfigMapPatcher) error {
 	data, err := json.Marshal(patches)
 	if err != nil {
 		log.Println(err.Error())
+		return err
 	}
 
 	_, err = cmp.Patch(ConfigMapName, types.JSONPatchType, data)
 	if err != nil {
 		log.Println(err.Error())
+		return err
 	}
 
-	err = dsp.DeleteCollection(
-		nil,
-		metav1.ListOptions{
-			LabelSelector: "app=fluent-bit",
-		},
-	)
+	return nil
+}
+
+func deleteFluentBitPods(dsp DaemonSetPodDeleter) {
+	deleteNodeFluentBitPods(dsp, "")
+}
+
+// deleteNodeFluentBitPods deletes the fluent-bit pod of node, or every pod
+// when node is empty.
+func deleteNodeFluentBitPods(dsp DaemonSetPodDeleter, node string) {
+	opts := metav1.ListOptions{
+		LabelSelector: "app=fluent-bit",
+	}
+	if node != "" {
+		opts.FieldSelector = "spec.nodeName=" + node
+	}
+	err := dsp.DeleteCollection(nil, opts)
 	if err != nil {
 		log.Println(err.Error())
 	}
-
 }
 
 func (c *Controller) OnUpdate(old, new interface{}) {
@@ -48,8 +48,9 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 `,
@@ -65,8 +66,9 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
     TLSConfig {}
@@ -83,8 +85,9 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
     TLSConfig {"insecure_skip_verify":true}
@@ -102,23 +105,26 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-test.com
+    Match *_test-ns_*
+    Alias test-ns/sink-test.com
+    InstanceName test-ns/sink-test.com
     Addr test.com:4567
     Namespace test-ns
 `,
@@ -135,16 +141,18 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:4567
     Namespace test-ns
 `,
@@ -161,8 +169,9 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 `,
@@ -184,16 +193,18 @@ func TestLogSinkController(t *testing.T) {
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12345
     Namespace test-ns
 `,
 				`
 [OUTPUT]
     Name syslog
-    Match *
-    InstanceName sink-example.com
+    Match *_test-ns_*
+    Alias test-ns/sink-example.com
+    InstanceName test-ns/sink-example.com
     Addr example.com:12346
     Namespace test-ns
 `,
@@ -400,12 +411,10 @@ func (s *spyConfigMapPatcher) expectPatches(patches []spyPatch, t *testing.T) {
 			t.Errorf("Patch Type does not equal Got: %s, Expected %s", s.patches[i].pt, types.JSONPatchType)
 		}
 
-		jpExpected := []jsonPatch{
-			{
-				Op:    "replace",
-				Path:  p.Path,
-				Value: p.Value,
-			},
+		jpExpected := jsonPatch{
+			Op:    "replace",
+			Path:  p.Path,
+			Value: p.Value,
 		}
 		var jpActual []jsonPatch
 		err := json.Unmarshal(s.patches[i].data, &jpActual)
@@ -413,12 +422,36 @@ func (s *spyConfigMapPatcher) expectPatches(patches []spyPatch, t *testing.T) {
 			t.Errorf("Could not Unmarshal json patch: %s", err)
 		}
 
-		if diff := cmp.Diff(jpExpected, jpActual); diff != "" {
+		if diff := cmp.Diff(jpExpected, findPatch(jpActual, p.Path)); diff != "" {
 			t.Errorf("Patches not equal (-want, +got) = %v", diff)
 		}
 	}
 }
 
+func lastPatch(t *testing.T, s *spyConfigMapPatcher) []jsonPatch {
+	if len(s.patches) == 0 {
+		t.Fatal("Expected patch to be called")
+	}
+
+	var jp []jsonPatch
+	err := json.Unmarshal(s.patches[len(s.patches)-1].data, &jp)
+	if err != nil {
+		t.Fatalf("Could not Unmarshal json patch: %s", err)
+	}
+	return jp
+}
+
+// findPatch returns the operation on path. Sink patches also update the Lua
+// script alongside outputs.conf.
+func findPatch(jp []jsonPatch, path string) jsonPatch {
+	for _, p := range jp {
+		if p.Path == path {
+			return p
+		}
+	}
+	return jsonPatch{}
+}
+
 type spyPatch struct {
 	Path  string
 	Value string
@@ -427,6 +460,7 @@ type spyPatch struct {
 type spyDaemonSetPodDeleter struct {
 	deleteCollectionCalled bool
 	Selector               string
+	FieldSelector          string
 }
 
 func (s *spyDaemonSetPodDeleter) DeleteCollection(
@@ -435,5 +469,6 @@ func (s *spyDaemonSetPodDeleter) DeleteCollection(
 ) error {
 	s.deleteCollectionCalled = true
 	s.Selector = listOptions.LabelSelector
+	s.FieldSelector = listOptions.FieldSelector
 	return nil
 }
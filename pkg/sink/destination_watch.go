@@ -0,0 +1,191 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/knative/observability/pkg/sink/flbconfig"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// The changes of a destination between two configs.
+const (
+	DestinationAdded   = "added"
+	DestinationRemoved = "removed"
+	DestinationChanged = "changed"
+)
+
+// Destination is an output of the config. Key is the alias of the output,
+// which names the sink it delivers for, e.g. "ns1/webhook" or
+// "cluster:all:route:0", so a destination keeps its key when other sinks are
+// added or removed and when its circuit opens. Outputs without an alias,
+// such as debug and audit file outputs, are keyed by their plugin and the
+// records they match and told apart by their order, e.g. "stdout *_ns1_*#1".
+// Params are the output's keys other than its Name.
+type Destination struct {
+	Key    string
+	Plugin string
+	Params map[string]string
+}
+
+// DestinationEvent is a change of a destination. Old is nil for added
+// destinations and New is nil for removed destinations.
+type DestinationEvent struct {
+	Change string
+	Key    string
+	Old    *Destination
+	New    *Destination
+}
+
+// DestinationWatcher sends the changes of the destinations in the
+// outputs.conf of the fluent-bit configmap to a channel, so tools can react
+// to the config fluent-bit runs rather than to edits of the sinks. It is an
+// event handler of a configmap informer. The first config it sees is
+// reported as added. Sends block until they are received.
+type DestinationWatcher struct {
+	mu           sync.Mutex
+	destinations map[string]Destination
+	events       chan<- DestinationEvent
+}
+
+func NewDestinationWatcher(events chan<- DestinationEvent) *DestinationWatcher {
+	return &DestinationWatcher{
+		destinations: make(map[string]Destination),
+		events:       events,
+	}
+}
+
+func (w *DestinationWatcher) OnAdd(o interface{}) {
+	cm, ok := o.(*coreV1.ConfigMap)
+	if !ok || cm.Name != ConfigMapName {
+		return
+	}
+
+	destinations, err := parseDestinations(cm.Data["outputs.conf"])
+	if err != nil {
+		log.Printf("Unable to parse the outputs of ConfigMap %s: %s", cm.Name, err)
+		return
+	}
+	w.update(destinations)
+}
+
+func (w *DestinationWatcher) OnUpdate(old, new interface{}) {
+	w.OnAdd(new)
+}
+
+// OnDelete reports every destination as removed.
+func (w *DestinationWatcher) OnDelete(o interface{}) {
+	if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+		o = tombstone.Obj
+	}
+	cm, ok := o.(*coreV1.ConfigMap)
+	if !ok || cm.Name != ConfigMapName {
+		return
+	}
+
+	w.update(nil)
+}
+
+// update sends the changes from the last destinations to destinations in
+// the order of their keys. The events are sent after the lock is released,
+// so a receiver that is slow or calls back into the watcher does not block
+// the next update.
+func (w *DestinationWatcher) update(destinations []Destination) {
+	w.mu.Lock()
+
+	next := make(map[string]Destination, len(destinations))
+	for _, d := range destinations {
+		next[d.Key] = d
+	}
+
+	keys := make([]string, 0, len(next)+len(w.destinations))
+	for k := range next {
+		keys = append(keys, k)
+	}
+	for k := range w.destinations {
+		if _, ok := next[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var events []DestinationEvent
+	for _, k := range keys {
+		o, hadOld := w.destinations[k]
+		n, hasNew := next[k]
+		switch {
+		case !hadOld:
+			events = append(events, DestinationEvent{Change: DestinationAdded, Key: k, New: &n})
+		case !hasNew:
+			events = append(events, DestinationEvent{Change: DestinationRemoved, Key: k, Old: &o})
+		case !reflect.DeepEqual(o, n):
+			events = append(events, DestinationEvent{Change: DestinationChanged, Key: k, Old: &o, New: &n})
+		}
+	}
+	w.destinations = next
+	w.mu.Unlock()
+
+	for _, e := range events {
+		w.events <- e
+	}
+}
+
+// parseDestinations returns the destinations of the outputs in a rendered
+// outputs.conf.
+func parseDestinations(outputs string) ([]Destination, error) {
+	f, err := flbconfig.Parse("outputs.conf", outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var destinations []Destination
+	seen := make(map[string]int)
+	for _, s := range f.Sections {
+		if !strings.EqualFold(s.Name, "OUTPUT") {
+			continue
+		}
+
+		d := Destination{Params: make(map[string]string)}
+		for _, kv := range s.KeyValues {
+			if strings.EqualFold(kv.Key, "Name") {
+				d.Plugin = kv.Value
+				continue
+			}
+			d.Params[kv.Key] = kv.Value
+		}
+
+		key := d.Plugin + " " + d.Params["Match"]
+		if alias, ok := d.Params["Alias"]; ok {
+			key = alias
+		}
+		if n := seen[key]; n > 0 {
+			d.Key = fmt.Sprintf("%s#%d", key, n)
+		} else {
+			d.Key = key
+		}
+		seen[key]++
+
+		destinations = append(destinations, d)
+	}
+	return destinations, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDestinationWatcher(t *testing.T) {
+	syslogSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "syslog-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	}
+	webhookSink := func(name, url string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: url,
+				},
+			},
+		}
+	}
+	configMap := func(sinks ...*v1alpha1.LogSink) *coreV1.ConfigMap {
+		sc := sink.NewConfig()
+		for _, s := range sinks {
+			sc.UpsertSink(s)
+		}
+		return &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: sink.ConfigMapName},
+			Data:       map[string]string{"outputs.conf": sc.String()},
+		}
+	}
+	syslogDestination := func(addr string) *sink.Destination {
+		return &sink.Destination{
+			Key:    "ns1/syslog-sink",
+			Plugin: "syslog",
+			Params: map[string]string{
+				"Match":        "*_ns1_*",
+				"Alias":        "ns1/syslog-sink",
+				"InstanceName": "ns1/syslog-sink",
+				"Addr":         addr,
+				"Namespace":    "ns1",
+			},
+		}
+	}
+	webhookDestination := func(key, path string) *sink.Destination {
+		return &sink.Destination{
+			Key:    key,
+			Plugin: "http",
+			Params: map[string]string{
+				"Match":  "*_ns1_*",
+				"Alias":  key,
+				"Format": "json",
+				"Host":   "example.com",
+				"Port":   "443",
+				"URI":    path,
+				"tls":    "On",
+			},
+		}
+	}
+	receive := func(events chan sink.DestinationEvent) []sink.DestinationEvent {
+		var received []sink.DestinationEvent
+		for {
+			select {
+			case e := <-events:
+				received = append(received, e)
+			default:
+				return received
+			}
+		}
+	}
+
+	t.Run("it reports the destinations of the first config as added", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+
+		w.OnAdd(configMap(syslogSink, webhookSink("a-sink", "https://example.com/a"), webhookSink("b-sink", "https://example.com/b")))
+
+		expected := []sink.DestinationEvent{
+			{Change: sink.DestinationAdded, Key: "ns1/a-sink", New: webhookDestination("ns1/a-sink", "/a")},
+			{Change: sink.DestinationAdded, Key: "ns1/b-sink", New: webhookDestination("ns1/b-sink", "/b")},
+			{Change: sink.DestinationAdded, Key: "ns1/syslog-sink", New: syslogDestination("example.com:12345")},
+		}
+		if diff := cmp.Diff(expected, receive(events)); diff != "" {
+			t.Errorf("Events not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it reports the destinations an edit changes", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+		old := configMap(syslogSink, webhookSink("a-sink", "https://example.com/a"))
+		w.OnAdd(old)
+		receive(events)
+
+		moved := syslogSink.DeepCopy()
+		moved.Spec.Port = 23456
+		w.OnUpdate(old, configMap(moved))
+
+		expected := []sink.DestinationEvent{
+			{Change: sink.DestinationRemoved, Key: "ns1/a-sink", Old: webhookDestination("ns1/a-sink", "/a")},
+			{
+				Change: sink.DestinationChanged,
+				Key:    "ns1/syslog-sink",
+				Old:    syslogDestination("example.com:12345"),
+				New:    syslogDestination("example.com:23456"),
+			},
+		}
+		if diff := cmp.Diff(expected, receive(events)); diff != "" {
+			t.Errorf("Events not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it keeps the key of a destination when an earlier sink is removed", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+		old := configMap(webhookSink("a-sink", "https://example.com/a"), webhookSink("b-sink", "https://example.com/b"))
+		w.OnAdd(old)
+		receive(events)
+
+		w.OnUpdate(old, configMap(webhookSink("b-sink", "https://example.com/b")))
+
+		expected := []sink.DestinationEvent{
+			{Change: sink.DestinationRemoved, Key: "ns1/a-sink", Old: webhookDestination("ns1/a-sink", "/a")},
+		}
+		if diff := cmp.Diff(expected, receive(events)); diff != "" {
+			t.Errorf("Events not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not report resyncs", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+		cm := configMap(syslogSink)
+		w.OnAdd(cm)
+		receive(events)
+
+		w.OnUpdate(cm, cm)
+
+		if received := receive(events); len(received) != 0 {
+			t.Errorf("Expected no events, got %v", received)
+		}
+	})
+
+	t.Run("it reports every destination as removed when the configmap is deleted", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+		cm := configMap(syslogSink)
+		w.OnAdd(cm)
+		receive(events)
+
+		w.OnDelete(cm)
+
+		expected := []sink.DestinationEvent{
+			{Change: sink.DestinationRemoved, Key: "ns1/syslog-sink", Old: syslogDestination("example.com:12345")},
+		}
+		if diff := cmp.Diff(expected, receive(events)); diff != "" {
+			t.Errorf("Events not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it ignores other configmaps", func(t *testing.T) {
+		events := make(chan sink.DestinationEvent, 10)
+		w := sink.NewDestinationWatcher(events)
+		cm := configMap(syslogSink)
+		cm.Name = "other"
+
+		w.OnAdd(cm)
+
+		if received := receive(events); len(received) != 0 {
+			t.Errorf("Expected no events, got %v", received)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+// epochMillisLua sets the field of records to their time in milliseconds
+// since the Unix epoch. The timestamp is a number of seconds with a
+// fraction, so it is rounded to the nearest millisecond rather than
+// truncated, which would drop one for times that are not exact. Lua
+// numbers without a fraction are packed as integers.
+func epochMillisLua(field string) string {
+	return fmt.Sprintf(`
+    record[%q] = math.floor(timestamp * 1000 + 0.5)
+    code = 1
+`, field)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// fieldPathPrelude defines field_parent, which returns the table holding
+// the last key of a field path in a record. It returns nil when the path
+// leads through a value that is not a table.
+const fieldPathPrelude = `
+local function field_parent(record, path)
+    local parent = record
+    for i = 1, #path - 1 do
+        if type(parent) ~= "table" then
+            return nil
+        end
+        parent = parent[path[i]]
+    end
+    if type(parent) ~= "table" then
+        return nil
+    end
+    return parent
+end
+`
+
+// luaFieldPath renders the keys of a field path as a Lua table. The
+// webhook rejects invalid paths, which are rendered as a single key.
+func luaFieldPath(f string) string {
+	keys, err := v1alpha1.ParseFieldPath(f)
+	if err != nil {
+		keys = []string{f}
+	}
+
+	quoted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		quoted = append(quoted, luaQuote(k))
+	}
+	return "{" + strings.Join(quoted, ", ") + "}"
+}
+
+// luaQuote renders s as a Lua string literal. Lua has no escapes for
+// runes, so the bytes outside of printable ASCII are escaped by value.
+func luaQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%03d", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+func (sc *Config) UpsertFilterSet(fs *v1alpha1.ClusterFilterSet) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.filterSets[fs.Name] = fs
+}
+
+func (sc *Config) DeleteFilterSet(fs *v1alpha1.ClusterFilterSet) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.filterSets, fs.Name)
+}
+
+// filterSetFilters returns the filters of the sets a sink references, in
+// the order they are referenced. A set that does not exist is logged and
+// skipped so the rest of the sink's chain is still rendered. rendered holds
+// the sets already rendered for each match, so a set referenced twice runs
+// on the records once.
+func (sc *Config) filterSetFilters(match string, ref sinkRef, rendered map[string]bool) []string {
+	var filters []string
+	for _, name := range ref.spec.FilterSetRefs {
+		fs, ok := sc.filterSets[name]
+		if !ok {
+			log.Printf("Sink %s references missing ClusterFilterSet %s", ref.key, name)
+			continue
+		}
+		if rendered[match+"/"+name] {
+			continue
+		}
+		rendered[match+"/"+name] = true
+
+		for _, f := range fs.Spec.Filters {
+			filters = append(filters, filterSpecConfig(match, f))
+		}
+	}
+	return filters
+}
+
+func filterSpecConfig(match string, f v1alpha1.FilterSpec) string {
+	options := make([]string, 0, len(f.Options))
+	for k := range f.Options {
+		options = append(options, k)
+	}
+	sort.Strings(options)
+
+	config := fmt.Sprintf("\n[FILTER]\n    Name %s\n    Match %s\n", f.Name, match)
+	for _, k := range options {
+		config += fmt.Sprintf("    %s %s\n", k, f.Options[k])
+	}
+	return config
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// FilterSetController renders the filters of ClusterFilterSets into the
+// chains of the sinks referencing them.
+type FilterSetController struct {
+	cmp ConfigMapPatcher
+	dsp DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewFilterSetController(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *FilterSetController {
+	return &FilterSetController{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+func (c *FilterSetController) OnAdd(o interface{}) {
+	fs, ok := o.(*v1alpha1.ClusterFilterSet)
+	if !ok {
+		return
+	}
+
+	c.sc.UpsertFilterSet(fs)
+
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("ClusterFilterSet %s upserted", fs.Name))
+}
+
+func (c *FilterSetController) OnDelete(o interface{}) {
+	fs, ok := o.(*v1alpha1.ClusterFilterSet)
+	if !ok {
+		return
+	}
+
+	c.sc.DeleteFilterSet(fs)
+
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("ClusterFilterSet %s deleted", fs.Name))
+}
+
+func (c *FilterSetController) OnUpdate(old, new interface{}) {
+	o, ok := old.(*v1alpha1.ClusterFilterSet)
+	if !ok {
+		return
+	}
+	n, ok := new.(*v1alpha1.ClusterFilterSet)
+	if !ok {
+		return
+	}
+	if !reflect.DeepEqual(o.Spec, n.Spec) {
+		c.OnAdd(new)
+	}
+}
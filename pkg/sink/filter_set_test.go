@@ -0,0 +1,244 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestFilterSets(t *testing.T) {
+	redact := &v1alpha1.ClusterFilterSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "redact",
+		},
+		Spec: v1alpha1.ClusterFilterSetSpec{
+			Filters: []v1alpha1.FilterSpec{
+				{
+					Name: "modify",
+					Options: map[string]string{
+						"Remove": "password",
+						"Add":    "redacted true",
+					},
+				},
+			},
+		},
+	}
+	drop := &v1alpha1.ClusterFilterSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "drop-health",
+		},
+		Spec: v1alpha1.ClusterFilterSetSpec{
+			Filters: []v1alpha1.FilterSpec{
+				{
+					Name: "grep",
+					Options: map[string]string{
+						"Exclude": "log healthz",
+					},
+				},
+			},
+		},
+	}
+	logSink := func(namespace string, refs ...string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sink",
+				Namespace: namespace,
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				FilterSetRefs: refs,
+			},
+		}
+	}
+
+	t.Run("it renders the referenced filters for the sink's records", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertSink(logSink("ns1", "redact"))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* filtered.ns.ns1.sink true
+    Emitter_Name ns1/sink:filtered
+
+[FILTER]
+    Name modify
+    Match filtered.ns.ns1.sink
+    Add redacted true
+    Remove password
+
+[OUTPUT]
+    Name syslog
+    Match filtered.ns.ns1.sink
+    Alias ns1/sink
+    InstanceName ns1/sink
+    Addr example.com:12345
+    Namespace ns1
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it renders the sets in order before the sink's filters", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertFilterSet(drop)
+		s := logSink("ns1", "drop-health", "redact")
+		s.Spec.StartupDelay = &metav1.Duration{Duration: time.Minute}
+		s.Spec.StartupRate = 10
+		sc.UpsertSink(s)
+
+		config := sc.String()
+		grep := strings.Index(config, "Name grep")
+		modify := strings.Index(config, "Name modify")
+		lua := strings.Index(config, "Name lua")
+		if grep == -1 || modify == -1 || lua == -1 {
+			t.Fatalf("Expected every filter to be rendered, got %s", config)
+		}
+		if !(grep < modify && modify < lua) {
+			t.Errorf("Expected filters in reference order before the sink's own, got %s", config)
+		}
+	})
+
+	t.Run("it renders a set on the records of each sink referencing it", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertSink(logSink("ns1", "redact"))
+		other := logSink("ns1", "redact")
+		other.Name = "other-sink"
+		sc.UpsertSink(other)
+		sc.UpsertSink(logSink("ns2", "redact"))
+
+		config := sc.String()
+		if n := strings.Count(config, "Name modify"); n != 3 {
+			t.Errorf("Expected the set once per sink, got %d in %s", n, config)
+		}
+	})
+
+	t.Run("it does not filter the records of other sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertSink(logSink("ns1", "redact"))
+		other := logSink("ns1")
+		other.Name = "other-sink"
+		sc.UpsertSink(other)
+
+		config := sc.String()
+		if !strings.Contains(config, "Name modify\n    Match filtered.ns.ns1.sink\n") {
+			t.Errorf("Expected the set to match the sink's own records, got %s", config)
+		}
+		if !strings.Contains(config, "Match *_ns1_*\n    Alias ns1/other-sink\n") {
+			t.Errorf("Expected the other sink to read the shared records, got %s", config)
+		}
+	})
+
+	t.Run("it skips missing sets", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertSink(logSink("ns1", "missing", "redact"))
+
+		config := sc.String()
+		if !strings.Contains(config, "Name modify") {
+			t.Errorf("Expected the existing set to be rendered, got %s", config)
+		}
+		if !strings.Contains(config, "Name syslog") {
+			t.Errorf("Expected the sink to be rendered, got %s", config)
+		}
+	})
+
+	t.Run("it removes the filters of deleted sets", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertFilterSet(redact)
+		sc.UpsertSink(logSink("ns1", "redact"))
+		sc.DeleteFilterSet(redact)
+
+		if config := sc.String(); strings.Contains(config, "Name modify") {
+			t.Errorf("Expected no set filters, got %s", config)
+		}
+	})
+}
+
+func TestFilterSetController(t *testing.T) {
+	t.Run("it patches the config when a set changes", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:          "webhook",
+				WebhookSpec:   v1alpha1.WebhookSpec{URL: "https://example.com"},
+				FilterSetRefs: []string{"drop"},
+			},
+		})
+		c := sink.NewFilterSetController(spyPatcher, spyDeleter, sc)
+
+		fs := &v1alpha1.ClusterFilterSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "drop"},
+			Spec: v1alpha1.ClusterFilterSetSpec{
+				Filters: []v1alpha1.FilterSpec{{Name: "grep"}},
+			},
+		}
+		c.OnAdd(fs)
+		if !strings.Contains(lastOutputs(t, spyPatcher), "Name grep") {
+			t.Errorf("Expected the set's filter to be patched, got %s", lastOutputs(t, spyPatcher))
+		}
+
+		c.OnUpdate(fs, fs)
+		updated := fs.DeepCopy()
+		updated.Spec.Filters[0].Name = "modify"
+		c.OnUpdate(fs, updated)
+		if !strings.Contains(lastOutputs(t, spyPatcher), "Name modify") {
+			t.Errorf("Expected the updated filter to be patched, got %s", lastOutputs(t, spyPatcher))
+		}
+
+		c.OnDelete(updated)
+		if strings.Contains(lastOutputs(t, spyPatcher), "Name modify") {
+			t.Errorf("Expected the filter to be removed, got %s", lastOutputs(t, spyPatcher))
+		}
+	})
+}
+
+// lastOutputs returns the outputs.conf of the last patch.
+func lastOutputs(t *testing.T, s *spyConfigMapPatcher) string {
+	if len(s.patches) == 0 {
+		t.Fatal("Expected the config to be patched")
+	}
+	var jp []jsonPatch
+	if err := json.Unmarshal(s.patches[len(s.patches)-1].data, &jp); err != nil {
+		t.Fatal(err)
+	}
+	return jp[0].Value
+}
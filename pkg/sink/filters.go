@@ -0,0 +1,210 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+const throttleFilterConfig = `
+[FILTER]
+    Name throttle
+    Match %s
+    Rate %d
+    Window %d
+    Interval 1s
+`
+
+// namespaceThrottleWindow is the number of seconds a namespace's rate is
+// averaged over.
+const namespaceThrottleWindow = 5
+
+// copyFilterConfig copies the records the rule matches to the tag of a
+// sink reading its own copy of them. The original record is kept for other
+// sinks.
+const copyFilterConfig = `
+[FILTER]
+    Name rewrite_tag
+    Match %s
+    Rule %s %s true
+    Emitter_Name %s
+%s`
+
+// filterConfig renders the filters sinks configure on top of the shared
+// chain in filters.conf. Fluent-bit runs every filter before any output, so
+// a sink with filters of its own reads its own copy of the records and its
+// filters only match the copy. Raw mode and audit sinks read their own
+// records already. The filters of a sink's filter sets run before its own.
+// The copies are made before any sink's filters.
+func (sc *Config) filterConfig() string {
+	refs := sc.filteredSinkRefs()
+
+	var config []string
+	for _, ref := range refs {
+		rule := sc.copyRule(ref)
+		if projected(ref) {
+			config = append(config, sc.copyConfig(ref, projectTag(ref), inputAlias(ref, "project"), rule))
+		}
+		if routed(ref) {
+			config = append(config, sc.copyConfig(ref, routeTag(ref), inputAlias(ref, "route"), rule))
+		}
+		if nodeCopied(ref) {
+			config = append(config, sc.copyConfig(ref, nodeTag(ref), inputAlias(ref, "nodes"), rule))
+		}
+		if filterCopied(ref) {
+			config = append(config, sc.copyConfig(ref, filterTag(ref), inputAlias(ref, "filtered"), rule))
+		}
+	}
+
+	rendered := make(map[string]bool)
+	for i, ref := range refs {
+		match := sinkMatch(ref)
+		spec := ref.spec
+		if !sc.geoIP {
+			spec.GeoIP = nil
+		}
+		config = append(config, sc.filterSetFilters(match, ref, rendered)...)
+		config = append(config, sinkFilters(
+			match,
+			luaFuncName(i),
+			spec,
+		)...)
+		if keys := projectKeys(ref.spec); len(keys) > 0 {
+			config = append(config, projectFilterConfig(match, keys))
+		}
+		if routed(ref) {
+			config = append(config, sc.routeFiltersConfig(ref))
+		}
+	}
+
+	return strings.Join(config, "")
+}
+
+// copyConfig renders the copy of a sink's records to tag. Copies are
+// emitted at the start of the pipeline again, so they only copy the records
+// of the namespace, or of every namespace for a cluster sink, and never the
+// copies of other sinks. rule is the key and pattern of the records that
+// are copied.
+func (sc *Config) copyConfig(ref sinkRef, tag, emitter, rule string) string {
+	return fmt.Sprintf(copyFilterConfig, namespaceMatch(ref.namespace, ref.cluster), rule, tag, emitter, sc.emitterStorageConfig())
+}
+
+// hasOwnFilters returns whether the spec sets a filter that only the sink's
+// records may pass through.
+func hasOwnFilters(spec v1alpha1.SinkSpec) bool {
+	return (spec.StartupDelay != nil && spec.StartupRate > 0) ||
+		spec.ParseJSONBody ||
+		spec.GeoIP != nil ||
+		len(spec.FilterSetRefs) > 0 ||
+		hasLua(spec)
+}
+
+// filterCopied returns whether the sink reads a copy of the records for its
+// filters or its namespace's throttle to run on, or for its heartbeats to
+// be tagged like. Raw mode, audit, projected, routed and node selected
+// sinks already read their own records.
+func filterCopied(ref sinkRef) bool {
+	if !(hasOwnFilters(ref.spec) || ref.spec.Heartbeat != nil || ref.throttled) || audited(ref) || (ref.spec.Type == "webhook" && ref.spec.RawMode) {
+		return false
+	}
+	return !projected(ref) && !routed(ref) && !nodeCopied(ref)
+}
+
+// filterTag is the tag of the copies of the records read by a sink with
+// filters of its own.
+func filterTag(ref sinkRef) string {
+	if ref.cluster {
+		return fmt.Sprintf("filtered.cluster.%s", ref.name)
+	}
+	if ref.glob {
+		return fmt.Sprintf("filtered.glob.%s.%s", ref.name, ref.namespace)
+	}
+	return fmt.Sprintf("filtered.ns.%s.%s", canonicalNamespace(ref.namespace), ref.name)
+}
+
+// namespaceThrottleConfig renders the throttles of namespaces with a rate
+// set. The rate is split evenly across the namespace's sinks, and each sink
+// is throttled on its own records. Throttling the namespace's shared
+// records would drop them for the cluster sinks too.
+func (sc *Config) namespaceThrottleConfig() string {
+	namespaces := make([]string, 0, len(sc.nsThrottles))
+	for ns := range sc.nsThrottles {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	refs := sc.filteredSinkRefs()
+
+	var config string
+	for _, ns := range namespaces {
+		var nsRefs []sinkRef
+		for _, ref := range refs {
+			if !ref.cluster && !ref.glob && canonicalNamespace(ref.namespace) == ns {
+				nsRefs = append(nsRefs, ref)
+			}
+		}
+		if len(nsRefs) == 0 {
+			continue
+		}
+
+		share := sc.nsThrottles[ns] / len(nsRefs)
+		if share < 1 {
+			share = 1
+		}
+
+		for _, ref := range nsRefs {
+			config += fmt.Sprintf(
+				throttleFilterConfig,
+				sinkMatch(ref),
+				share,
+				namespaceThrottleWindow,
+			)
+		}
+	}
+
+	return config
+}
+
+// filteredSinkRefs returns every sink that is rendered in the order their
+// filters are rendered.
+func (sc *Config) filteredSinkRefs() []sinkRef {
+	refs := append(sc.sinkRefs("syslog"), sc.sinkRefs("webhook")...)
+	return sortByPriority(append(refs, sc.sinkRefs("unix_socket")...))
+}
+
+// sinkFilters returns a filter section for each per sink option that is set
+// on the spec. luaFunc is the name of the sink's function in the Lua script.
+func sinkFilters(match, luaFunc string, spec v1alpha1.SinkSpec) []string {
+	var filters []string
+
+	if spec.ParseJSONBody {
+		filters = append(filters, fmt.Sprintf(jsonBodyFilterConfig, match))
+	}
+
+	if hasLua(spec) {
+		filters = append(filters, fmt.Sprintf(luaFilterConfig, match, luaFunc))
+	}
+
+	if spec.GeoIP != nil {
+		filters = append(filters, geoIPConfig(match, spec.GeoIP.SourceField, spec.GeoIP.Database))
+	}
+
+	return filters
+}
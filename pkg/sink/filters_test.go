@@ -0,0 +1,805 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+	"github.com/knative/observability/pkg/sink/flbconfig"
+)
+
+func TestStartupThrottle(t *testing.T) {
+	t.Run("it renders a startup ramp for each sink's records", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+				StartupRate:  100,
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/logs",
+				},
+				StartupDelay: &metav1.Duration{Duration: 1500 * time.Millisecond},
+				StartupRate:  10,
+			},
+		})
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* filtered.ns.ns1.some-sink true
+    Emitter_Name ns1/some-sink:filtered
+
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* filtered.cluster.cluster-sink true
+    Emitter_Name cluster:cluster-sink:filtered
+
+[FILTER]
+    Name lua
+    Match filtered.ns.ns1.some-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_0
+
+[FILTER]
+    Name lua
+    Match filtered.cluster.cluster-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_1
+
+[OUTPUT]
+    Name syslog
+    Match filtered.ns.ns1.some-sink
+    Alias ns1/some-sink
+    InstanceName ns1/some-sink
+    Addr example.com:12345
+    Namespace ns1
+
+[OUTPUT]
+    Name http
+    Match filtered.cluster.cluster-sink
+    Alias cluster:cluster-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+
+		script := sc.Script()
+		for _, ramp := range []string{
+			"local sink_0_startup = {ends = os.time() + 30, second = 0, count = 0}",
+			"local sink_1_startup = {ends = os.time() + 2, second = 0, count = 0}",
+			"if sink_0_startup.count > 100 then",
+			"if sink_1_startup.count > 10 then",
+		} {
+			if !strings.Contains(script, ramp) {
+				t.Errorf("Expected the script to contain %q, got %s", ramp, script)
+			}
+		}
+	})
+
+	t.Run("it does not throttle the records of other sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "throttled",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/throttled",
+				},
+				StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+				StartupRate:  100,
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "other",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/other",
+				},
+			},
+		})
+
+		f, err := flbconfig.Parse("", sc.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches := make(map[string]string)
+		for _, s := range f.Sections {
+			params := make(map[string]string)
+			for _, kv := range s.KeyValues {
+				params[kv.Key] = kv.Value
+			}
+			switch {
+			case params["Name"] == "lua":
+				matches["lua"] = params["Match"]
+			case params["Name"] == "http":
+				matches[params["URI"]] = params["Match"]
+			}
+		}
+
+		expected := map[string]string{
+			"lua":        "filtered.ns.ns1.throttled",
+			"/throttled": "filtered.ns.ns1.throttled",
+			"/other":     "*_ns1_*",
+		}
+		if diff := cmp.Diff(expected, matches); diff != "" {
+			t.Errorf("Matches not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not render a filter when unset", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/some-sink
+    InstanceName ns1/some-sink
+    Addr example.com:12345
+    Namespace ns1
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	rampSink := func(rate int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/logs",
+				},
+				StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+				StartupRate:  rate,
+			},
+		}
+	}
+	records := func(n int) []luaRecord {
+		var records []luaRecord
+		for i := 0; i < n; i++ {
+			records = append(records, luaRecord{
+				tag:       "filtered.ns.ns1.some-sink",
+				timestamp: 1,
+				record:    map[string]interface{}{"log": "hello"},
+			})
+		}
+		return records
+	}
+
+	t.Run("it drops the records above the rate during the delay when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(rampSink(1))
+
+		var dropped int
+		for _, r := range runLua(t, sc.Script(), "sink_0", records(3)...) {
+			if r.Code == -1 {
+				dropped++
+			}
+		}
+		// The records may be run across the start of a second, which
+		// lets one more through.
+		if dropped == 0 {
+			t.Error("Expected records above the startup rate to be dropped")
+		}
+	})
+
+	t.Run("it lifts the rate once the delay has passed when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(rampSink(1))
+		script := strings.Replace(sc.Script(), "os.time() + 30", "os.time() - 1", 1)
+
+		for i, r := range runLua(t, script, "sink_0", records(3)...) {
+			if r.Code != 0 {
+				t.Errorf("Expected record %d to be kept, got code %d", i, r.Code)
+			}
+		}
+	})
+}
+
+func TestRawMode(t *testing.T) {
+	t.Run("it reads raw lines with the sink's own input", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "raw-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/raw",
+				},
+				RawMode:      true,
+				StartupDelay: &metav1.Duration{Duration: 10 * time.Second},
+				StartupRate:  100,
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "raw-cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/cluster",
+				},
+				RawMode: true,
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "parsed-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/parsed",
+				},
+			},
+		})
+
+		expected := `
+[INPUT]
+    Name tail
+    Tag raw.ns.ns1.raw-sink
+    Alias ns1/raw-sink:raw
+    Path /var/log/containers/*_ns1_*.log
+    DB /var/log/flb_raw.ns.ns1.raw-sink.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+
+[INPUT]
+    Name tail
+    Tag raw.cluster.raw-cluster-sink
+    Alias cluster:raw-cluster-sink:raw
+    Path /var/log/containers/*.log
+    DB /var/log/flb_raw.cluster.raw-cluster-sink.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+
+[FILTER]
+    Name lua
+    Match raw.ns.ns1.raw-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_1
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/parsed-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /parsed
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match raw.ns.ns1.raw-sink
+    Alias ns1/raw-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /raw
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match raw.cluster.raw-cluster-sink
+    Alias cluster:raw-cluster-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /cluster
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it ignores raw mode for syslog sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				RawMode: true,
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/some-sink
+    InstanceName ns1/some-sink
+    Addr example.com:12345
+    Namespace ns1
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestNamespaceThrottle(t *testing.T) {
+	syslogSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "syslog-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	}
+	webhookSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/logs",
+			},
+		},
+	}
+	otherSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-sink",
+			Namespace: "ns2",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	}
+
+	t.Run("it splits the namespace's rate between its sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(syslogSink)
+		sc.UpsertSink(webhookSink)
+		sc.UpsertSink(otherSink)
+		sc.SetNamespaceThrottle("ns1", 100)
+
+		expected := `
+[FILTER]
+    Name throttle
+    Match filtered.ns.ns1.syslog-sink
+    Rate 50
+    Window 5
+    Interval 1s
+
+[FILTER]
+    Name throttle
+    Match filtered.ns.ns1.webhook-sink
+    Rate 50
+    Window 5
+    Interval 1s
+
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* filtered.ns.ns1.syslog-sink true
+    Emitter_Name ns1/syslog-sink:filtered
+
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* filtered.ns.ns1.webhook-sink true
+    Emitter_Name ns1/webhook-sink:filtered
+
+[OUTPUT]
+    Name syslog
+    Match filtered.ns.ns1.syslog-sink
+    Alias ns1/syslog-sink
+    InstanceName ns1/syslog-sink
+    Addr example.com:12345
+    Namespace ns1
+
+[OUTPUT]
+    Name syslog
+    Match *_ns2_*
+    Alias ns2/other-sink
+    InstanceName ns2/other-sink
+    Addr example.com:12345
+    Namespace ns2
+
+[OUTPUT]
+    Name http
+    Match filtered.ns.ns1.webhook-sink
+    Alias ns1/webhook-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not throttle the records of cluster sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(syslogSink)
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+		sc.SetNamespaceThrottle("ns1", 100)
+
+		expected := `
+[FILTER]
+    Name throttle
+    Match filtered.ns.ns1.syslog-sink
+    Rate 100
+    Window 5
+    Interval 1s
+`
+		config := sc.String()
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected config to contain %s, got %s", expected, config)
+		}
+		if strings.Count(config, "Name throttle") != 1 {
+			t.Errorf("Expected only the sink's copy to be throttled, got %s", config)
+		}
+	})
+
+	t.Run("it throttles raw mode sinks on their own tag", func(t *testing.T) {
+		raw := webhookSink.DeepCopy()
+		raw.Spec.RawMode = true
+
+		sc := sink.NewConfig()
+		sc.UpsertSink(syslogSink)
+		sc.UpsertSink(raw)
+		sc.SetNamespaceThrottle("ns1", 100)
+
+		expected := `
+[FILTER]
+    Name throttle
+    Match filtered.ns.ns1.syslog-sink
+    Rate 50
+    Window 5
+    Interval 1s
+
+[FILTER]
+    Name throttle
+    Match raw.ns.ns1.webhook-sink
+    Rate 50
+    Window 5
+    Interval 1s
+`
+		if config := sc.String(); !strings.Contains(config, expected) {
+			t.Errorf("Expected config to contain %s, got %s", expected, config)
+		}
+	})
+
+	t.Run("it does not throttle below one record per second", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(syslogSink)
+		sc.UpsertSink(webhookSink)
+		sc.SetNamespaceThrottle("ns1", 1)
+
+		if config := sc.String(); !strings.Contains(config, "Rate 1\n") {
+			t.Errorf("Expected a rate of 1, got %s", config)
+		}
+	})
+
+	t.Run("it removes the throttle", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(syslogSink)
+
+		if !sc.SetNamespaceThrottle("ns1", 100) {
+			t.Error("Expected setting a throttle to change the config")
+		}
+		if sc.SetNamespaceThrottle("ns1", 100) {
+			t.Error("Expected setting the same throttle to not change the config")
+		}
+		if !sc.SetNamespaceThrottle("ns1", 0) {
+			t.Error("Expected removing the throttle to change the config")
+		}
+
+		if config := sc.String(); strings.Contains(config, "throttle") {
+			t.Errorf("Expected no throttle, got %s", config)
+		}
+	})
+}
+
+func TestParseJSONBody(t *testing.T) {
+	jsonSink := func(parse bool) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "json-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				ParseJSONBody:   parse,
+				IncludeSequence: true,
+			},
+		}
+	}
+
+	t.Run("it expands the log before the sink's lua filter", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(jsonSink(true))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* filtered.ns.ns1.json-sink true
+    Emitter_Name ns1/json-sink:filtered
+
+[FILTER]
+    Name parser
+    Match filtered.ns.ns1.json-sink
+    Key_Name log
+    Parser json-body
+    Reserve_Data On
+
+[FILTER]
+    Name lua
+    Match filtered.ns.ns1.json-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_0
+`
+		config := sc.String()
+		if diff := cmp.Diff(expected, config[:len(expected)]); diff != "" {
+			t.Errorf("Filters not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it expands the fields of JSON logs when run", func(t *testing.T) {
+		record := runFluentBit(t, parserFilters(t, jsonSink(true)), deployedFiles(t)["parsers.conf"], map[string]interface{}{
+			"log":        `{"level":"info","msg":"started"}`,
+			"kubernetes": map[string]interface{}{"namespace_name": "ns1"},
+		})
+
+		expected := map[string]interface{}{
+			"level":      "info",
+			"msg":        "started",
+			"kubernetes": map[string]interface{}{"namespace_name": "ns1"},
+		}
+		if diff := cmp.Diff(expected, record); diff != "" {
+			t.Errorf("Record not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it keeps the log of records that are not JSON when run", func(t *testing.T) {
+		logs := []string{"plain text", `{"truncated":`, `["an", "array"]`}
+		for _, l := range logs {
+			record := runFluentBit(t, parserFilters(t, jsonSink(true)), deployedFiles(t)["parsers.conf"], map[string]interface{}{
+				"log":        l,
+				"kubernetes": map[string]interface{}{"namespace_name": "ns1"},
+			})
+
+			expected := map[string]interface{}{
+				"log":        l,
+				"kubernetes": map[string]interface{}{"namespace_name": "ns1"},
+			}
+			if diff := cmp.Diff(expected, record); diff != "" {
+				t.Errorf("Record not equal (-want, +got) = %v", diff)
+			}
+		}
+	})
+
+	t.Run("it does not parse the log of other sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(jsonSink(false))
+
+		if config := sc.String(); strings.Contains(config, "Name parser") {
+			t.Errorf("Expected no parser filter, got %s", config)
+		}
+	})
+}
+
+// parserFilters returns the parser filters rendered for a sink.
+func parserFilters(t *testing.T, s *v1alpha1.LogSink) []flbconfig.Section {
+	sc := sink.NewConfig()
+	sc.UpsertSink(s)
+
+	f, err := flbconfig.Parse("", sc.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filters []flbconfig.Section
+	for _, s := range f.Sections {
+		for _, kv := range s.KeyValues {
+			if kv.Key == "Name" && kv.Value == "parser" {
+				filters = append(filters, s)
+			}
+		}
+	}
+	if len(filters) == 0 {
+		t.Fatal("Expected a parser filter")
+	}
+	return filters
+}
+
+func TestGeoIP(t *testing.T) {
+	geoIPSink := func(geoIP *v1alpha1.GeoIPSpec) *v1alpha1.ClusterLogSink {
+		return &v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "security-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/security",
+				},
+				IncludeSequence: true,
+				GeoIP:           geoIP,
+			},
+		}
+	}
+	geoIP := &v1alpha1.GeoIPSpec{
+		SourceField: "client_ip",
+		Database:    "/fluent-bit/geoip/GeoLite2-Country.mmdb",
+	}
+
+	t.Run("it looks up the source field in the sink's copy after its lua filter", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithGeoIP())
+		sc.UpsertClusterSink(geoIPSink(geoIP))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* filtered.cluster.security-sink true
+    Emitter_Name cluster:security-sink:filtered
+
+[FILTER]
+    Name lua
+    Match filtered.cluster.security-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_0
+
+[FILTER]
+    Name geoip2
+    Match filtered.cluster.security-sink
+    Database /fluent-bit/geoip/GeoLite2-Country.mmdb
+    Lookup_key client_ip
+    Record geoip_country client_ip %{country.iso_code}
+    Record geoip_asn client_ip %{autonomous_system_number}
+`
+		config := sc.String()
+		if diff := cmp.Diff(expected, config[:len(expected)]); diff != "" {
+			t.Errorf("Filters not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not add the lookup to the records of other sinks", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithGeoIP())
+		s := geoIPSink(geoIP)
+		s.Spec.IncludeSequence = false
+		sc.UpsertClusterSink(s)
+
+		if config := sc.String(); strings.Contains(config, "Name geoip2\n    Match *_*\n") {
+			t.Errorf("Expected the lookup to match the sink's copy, got %s", config)
+		}
+	})
+
+	t.Run("it ignores the option unless geoip is enabled", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(geoIPSink(geoIP))
+
+		if config := sc.String(); strings.Contains(config, "geoip2") {
+			t.Errorf("Expected no geoip filter, got %s", config)
+		}
+	})
+
+	t.Run("it does not look up addresses without the option", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithGeoIP())
+		sc.UpsertClusterSink(geoIPSink(nil))
+
+		if config := sc.String(); strings.Contains(config, "geoip2") {
+			t.Errorf("Expected no geoip filter, got %s", config)
+		}
+	})
+}
@@ -194,7 +194,7 @@ func LexKey(l *Lexer) StateFunc {
 		}
 
 		next := l.PeekNext()
-		if !unicode.IsLetter(next) && !unicode.IsNumber(next) && next != '.' {
+		if !isKeyRune(next) {
 			switch next {
 			case RuneTab, RuneSpace:
 				l.Emit(TokenKey)
@@ -208,6 +208,12 @@ func LexKey(l *Lexer) StateFunc {
 	}
 }
 
+// isKeyRune reports whether r may appear in a key. Fluent-bit keys such as
+// Mem_Buf_Limit and K8S-Logging.Parser separate words with '_' and '-'.
+func isKeyRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '.' || r == '_' || r == '-'
+}
+
 func LexValue(l *Lexer) StateFunc {
 	for {
 		if l.EOF() {
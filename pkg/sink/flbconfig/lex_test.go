@@ -249,6 +249,26 @@ keyB1.subkey valB1s
 				},
 			},
 		},
+		"separated key": {
+			input: "K8S-Logging.Parser_Name On\n",
+			expectedTokens: []flbconfig.Token{
+				{
+					Type:  flbconfig.TokenKey,
+					Value: "K8S-Logging.Parser_Name",
+				},
+				{
+					Type:  flbconfig.TokenValue,
+					Value: "On",
+				},
+				{
+					Type:  flbconfig.TokenNewLine,
+					Value: "\n",
+				},
+				{
+					Type: flbconfig.TokenEOF,
+				},
+			},
+		},
 	}
 
 	for name, tc := range testCases {
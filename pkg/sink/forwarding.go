@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"log"
+	"time"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WithForwardingDisabled replaces the outputs of every sink with a null
+// output, which stops all forwarding without changing any sink. It is
+// meant to cut off egress during an incident.
+func WithForwardingDisabled() ConfigOpt {
+	return func(sc *Config) {
+		sc.forwardingDisabled = true
+	}
+}
+
+// ForwardingReporter sets the ForwardingDisabled condition of every sink to
+// whether forwarding is disabled. Sinks that never had forwarding disabled
+// are not updated.
+type ForwardingReporter struct {
+	ssg SinkStatusGetter
+	sc  *Config
+	now func() time.Time
+}
+
+func NewForwardingReporter(ssg SinkStatusGetter, sc *Config) *ForwardingReporter {
+	return &ForwardingReporter{
+		ssg: ssg,
+		sc:  sc,
+		now: time.Now,
+	}
+}
+
+// Run reports every interval until stopCh is closed, so that sinks created
+// after the controller started get the condition too.
+func (r *ForwardingReporter) Run(interval time.Duration, stopCh <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			r.Report()
+		}
+	}
+}
+
+// Report updates the status of every sink whose condition does not match
+// the config.
+func (r *ForwardingReporter) Report() {
+	r.sc.mu.Lock()
+	disabled := r.sc.forwardingDisabled
+	var sinks []*v1alpha1.LogSink
+	for _, s := range r.sc.sinks {
+		sinks = append(sinks, s)
+	}
+	var clusterSinks []*v1alpha1.ClusterLogSink
+	for _, cs := range r.sc.clusterSinks {
+		clusterSinks = append(clusterSinks, cs)
+	}
+	r.sc.mu.Unlock()
+
+	cond := v1alpha1.SinkCondition{
+		Type:               v1alpha1.SinkConditionForwardingDisabled,
+		Status:             coreV1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(r.now()),
+		Reason:             "ForwardingEnabled",
+		Message:            "records are forwarded to the sink",
+	}
+	if disabled {
+		cond.Status = coreV1.ConditionTrue
+		cond.Reason = "ForwardingDisabled"
+		cond.Message = "forwarding is disabled for every sink, the sink's output discards records"
+	}
+
+	for _, s := range sinks {
+		if !needsForwardingCondition(s.Status, cond.Status) {
+			continue
+		}
+		s = s.DeepCopy()
+		s.Status.SetCondition(cond)
+		if _, err := r.ssg.LogSinks(s.Namespace).UpdateStatus(s); err != nil {
+			log.Printf("Unable to update sink status: %s", err)
+		}
+	}
+	for _, cs := range clusterSinks {
+		if !needsForwardingCondition(cs.Status, cond.Status) {
+			continue
+		}
+		cs = cs.DeepCopy()
+		cs.Status.SetCondition(cond)
+		if _, err := r.ssg.ClusterLogSinks(cs.Namespace).UpdateStatus(cs); err != nil {
+			log.Printf("Unable to update sink status: %s", err)
+		}
+	}
+}
+
+// needsForwardingCondition returns whether a sink's ForwardingDisabled
+// condition differs from status. A sink without the condition only needs
+// it while forwarding is disabled.
+func needsForwardingCondition(s v1alpha1.SinkStatus, status coreV1.ConditionStatus) bool {
+	for _, c := range s.Conditions {
+		if c.Type == v1alpha1.SinkConditionForwardingDisabled {
+			return c.Status != status
+		}
+	}
+	return status == coreV1.ConditionTrue
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestForwardingDisabled(t *testing.T) {
+	newSinks := func() (*v1alpha1.LogSink, *v1alpha1.ClusterLogSink) {
+		s := &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		}
+		cs := &v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+			},
+		}
+		return s, cs
+	}
+
+	t.Run("it discards the records of every sink", func(t *testing.T) {
+		s, cs := newSinks()
+		sc := sink.NewConfig(sink.WithForwardingDisabled())
+		sc.UpsertSink(s)
+		sc.UpsertClusterSink(cs)
+
+		expected := `
+[OUTPUT]
+    Name null
+    Match *
+`
+		if config := sc.String(); config != expected {
+			t.Errorf("Expected only a null output, got %s", config)
+		}
+	})
+
+	t.Run("it forwards again once enabled", func(t *testing.T) {
+		s, cs := newSinks()
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+		sc.UpsertClusterSink(cs)
+
+		config := sc.String()
+		if !strings.Contains(config, "Name syslog") || !strings.Contains(config, "Name http") {
+			t.Errorf("Expected the outputs of every sink, got %s", config)
+		}
+	})
+
+	t.Run("it sets the condition of every sink", func(t *testing.T) {
+		s, cs := newSinks()
+		client := fake.NewSimpleClientset(s, cs)
+		sc := sink.NewConfig(sink.WithForwardingDisabled())
+		sc.UpsertSink(s)
+		sc.UpsertClusterSink(cs)
+
+		sink.NewForwardingReporter(client.ObservabilityV1alpha1(), sc).Report()
+
+		ls, err := client.ObservabilityV1alpha1().LogSinks("ns1").Get("some-sink", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond := forwardingCondition(ls.Status); cond == nil || cond.Status != coreV1.ConditionTrue {
+			t.Errorf("Expected the LogSink's condition to be true, got %+v", cond)
+		}
+		cls, err := client.ObservabilityV1alpha1().ClusterLogSinks("").Get("cluster-sink", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond := forwardingCondition(cls.Status); cond == nil || cond.Status != coreV1.ConditionTrue {
+			t.Errorf("Expected the ClusterLogSink's condition to be true, got %+v", cond)
+		}
+	})
+
+	t.Run("it clears the condition once enabled", func(t *testing.T) {
+		s, _ := newSinks()
+		s.Status.Conditions = []v1alpha1.SinkCondition{{
+			Type:   v1alpha1.SinkConditionForwardingDisabled,
+			Status: coreV1.ConditionTrue,
+		}}
+		client := fake.NewSimpleClientset(s)
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		sink.NewForwardingReporter(client.ObservabilityV1alpha1(), sc).Report()
+
+		ls, err := client.ObservabilityV1alpha1().LogSinks("ns1").Get("some-sink", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond := forwardingCondition(ls.Status); cond == nil || cond.Status != coreV1.ConditionFalse {
+			t.Errorf("Expected the condition to be false, got %+v", cond)
+		}
+	})
+
+	t.Run("it does not update sinks that were always enabled", func(t *testing.T) {
+		s, _ := newSinks()
+		client := fake.NewSimpleClientset(s)
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		sink.NewForwardingReporter(client.ObservabilityV1alpha1(), sc).Report()
+
+		for _, a := range client.Actions() {
+			if a.GetVerb() == "update" {
+				t.Errorf("Expected no updates, got %v", a)
+			}
+		}
+	})
+}
+
+func forwardingCondition(s v1alpha1.SinkStatus) *v1alpha1.SinkCondition {
+	for _, cond := range s.Conditions {
+		if cond.Type == v1alpha1.SinkConditionForwardingDisabled {
+			return &cond
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink/flbconfig"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Fragment is the fluent-bit config rendered for a single sink.
+type Fragment struct {
+	// Config holds the inputs, filters and outputs of the sink as they
+	// would be rendered into outputs.conf. It parses as a fluent-bit config
+	// on its own, so it can be included in a config built elsewhere.
+	Config string
+
+	// Script holds the Lua functions the lua filters of Config call from
+	// /fluent-bit/etc/sinks.lua. It is empty when the sink has no
+	// lua filters.
+	Script string
+}
+
+// RenderFragment renders the config of a LogSink or ClusterLogSink as the
+// controller would if it were the only sink in the cluster. The filter
+// sets the sink references are rendered from filterSets. It returns an
+// error for other objects and for a config that does not parse.
+func RenderFragment(obj runtime.Object, filterSets ...*v1alpha1.ClusterFilterSet) (Fragment, error) {
+	sc := NewConfig()
+	switch s := obj.(type) {
+	case *v1alpha1.LogSink:
+		sc.UpsertSink(s)
+	case *v1alpha1.ClusterLogSink:
+		sc.UpsertClusterSink(s)
+	default:
+		return Fragment{}, fmt.Errorf("unsupported object %T", obj)
+	}
+	for _, fs := range filterSets {
+		sc.UpsertFilterSet(fs)
+	}
+
+	f := Fragment{
+		Config: sc.String(),
+		Script: sc.Script(),
+	}
+	if _, err := flbconfig.Parse("outputs.conf", f.Config); err != nil {
+		return Fragment{}, err
+	}
+	return f, nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestRenderFragment(t *testing.T) {
+	var goldenTests = []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{
+			name: "webhook-log-sink",
+			obj: &v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-logs", Namespace: "team-a"},
+				Spec: v1alpha1.SinkSpec{
+					Type:        "webhook",
+					WebhookSpec: v1alpha1.WebhookSpec{URL: "https://logs.example.com/ingest"},
+				},
+			},
+		},
+		{
+			name: "syslog-cluster-sink",
+			obj: &v1alpha1.ClusterLogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+				Spec: v1alpha1.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: v1alpha1.SyslogSpec{
+						Host:      "syslog.example.com",
+						Port:      6514,
+						EnableTLS: true,
+					},
+				},
+			},
+		},
+		{
+			name: "lua-log-sink",
+			obj: &v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "sampled", Namespace: "team-b"},
+				Spec: v1alpha1.SinkSpec{
+					Type:             "webhook",
+					WebhookSpec:      v1alpha1.WebhookSpec{URL: "https://logs.example.com/sampled"},
+					SeveritySampling: map[string]float64{"debug": 0.1},
+					IncludeSequence:  true,
+				},
+			},
+		},
+	}
+
+	for _, test := range goldenTests {
+		t.Run("it renders a "+test.name, func(t *testing.T) {
+			f, err := sink.RenderFragment(test.obj)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			golden := filepath.Join("testdata/fragment", test.name)
+			checkGolden(t, golden+".conf.golden", f.Config)
+			checkGolden(t, golden+".lua.golden", f.Script)
+		})
+	}
+
+	t.Run("it renders the filter sets of the sink", func(t *testing.T) {
+		f, err := sink.RenderFragment(
+			&v1alpha1.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Name: "filtered", Namespace: "team-a"},
+				Spec: v1alpha1.SinkSpec{
+					Type:          "webhook",
+					WebhookSpec:   v1alpha1.WebhookSpec{URL: "https://logs.example.com/ingest"},
+					FilterSetRefs: []string{"drop-health"},
+				},
+			},
+			&v1alpha1.ClusterFilterSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "drop-health"},
+				Spec: v1alpha1.ClusterFilterSetSpec{
+					Filters: []v1alpha1.FilterSpec{{
+						Name:    "grep",
+						Options: map[string]string{"Exclude": "log healthz"},
+					}},
+				},
+			},
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		checkGolden(t, "testdata/fragment/filter-set.conf.golden", f.Config)
+	})
+
+	t.Run("it returns an error for other objects", func(t *testing.T) {
+		_, err := sink.RenderFragment(&coreV1.Namespace{})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+// checkGolden compares rendered with the golden file, which is written
+// instead when the tests are run with -update. An empty rendering has no
+// golden file.
+func checkGolden(t *testing.T, golden, rendered string) {
+	t.Helper()
+	if *updateGolden {
+		var err error
+		if rendered == "" {
+			err = os.Remove(golden)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		} else {
+			err = ioutil.WriteFile(golden, []byte(rendered), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if os.IsNotExist(err) {
+		err = nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(expected), rendered); diff != "" {
+		t.Errorf("%s not equal (-want, +got) = %v", golden, diff)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// ObjectStore lists the objects cached by an informer.
+type ObjectStore interface {
+	List() []interface{}
+}
+
+// Reconciler renders the config of every cached sink and filter set and
+// applies it at once, without waiting for the informers to resync.
+type Reconciler struct {
+	cmp    ConfigMapPatcher
+	dsp    DaemonSetPodDeleter
+	sc     *Config
+	stores []ObjectStore
+}
+
+// NewReconciler returns a Reconciler of the LogSinks, ClusterLogSinks and
+// ClusterFilterSets in the stores. The config is rebuilt from the stores, so
+// they must hold every kind.
+func NewReconciler(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config, stores ...ObjectStore) *Reconciler {
+	return &Reconciler{
+		cmp:    cmp,
+		dsp:    dsp,
+		sc:     sc,
+		stores: stores,
+	}
+}
+
+// Reconcile rebuilds the config from every cached object and applies it
+// once, so fluent-bit is only restarted once however many sinks there are.
+// Sinks and filter sets that are no longer cached, e.g. because their
+// delete was missed, are dropped from the config.
+func (r *Reconciler) Reconcile() {
+	sinks := make(map[string]*v1alpha1.LogSink)
+	clusterSinks := make(map[string]*v1alpha1.ClusterLogSink)
+	filterSets := make(map[string]*v1alpha1.ClusterFilterSet)
+	for _, s := range r.stores {
+		for _, o := range s.List() {
+			switch d := o.(type) {
+			case *v1alpha1.LogSink:
+				sinks[key(d)] = d
+			case *v1alpha1.ClusterLogSink:
+				clusterSinks[clusterKey(d)] = d
+			case *v1alpha1.ClusterFilterSet:
+				filterSets[d.Name] = d
+			}
+		}
+	}
+	r.sc.replaceSinks(sinks, clusterSinks, filterSets)
+
+	applyConfig(r.sc, r.cmp, r.dsp, "Full reconcile requested")
+}
+
+// replaceSinks replaces the sinks and filter sets of the config. The open
+// circuits of the sinks that are dropped are closed.
+func (sc *Config) replaceSinks(
+	sinks map[string]*v1alpha1.LogSink,
+	clusterSinks map[string]*v1alpha1.ClusterLogSink,
+	filterSets map[string]*v1alpha1.ClusterFilterSet,
+) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for k := range sc.sinks {
+		if _, ok := sinks[k]; !ok {
+			sc.deleteCircuits(k)
+		}
+	}
+	for k := range sc.clusterSinks {
+		if _, ok := clusterSinks[k]; !ok {
+			sc.deleteCircuits(k)
+		}
+	}
+	sc.sinks = sinks
+	sc.clusterSinks = clusterSinks
+	sc.filterSets = filterSets
+}
+
+// ReconcileHandler runs a full reconcile on POST requests. Requests must
+// carry the token as a bearer token, or come from localhost when the
+// token is empty.
+func ReconcileHandler(r *Reconciler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !reconcileAllowed(req, token) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		log.Printf("Full reconcile requested by %s", req.RemoteAddr)
+		r.Reconcile()
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// RequireToken serves the requests to h that would be allowed to
+// reconcile, those with the bearer token or from localhost when token is
+// empty, and forbids the rest.
+func RequireToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !reconcileAllowed(req, token) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, req)
+	})
+}
+
+func reconcileAllowed(req *http.Request, token string) bool {
+	if token != "" {
+		auth := req.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return false
+		}
+		given := strings.TrimPrefix(auth, "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestReconcileHandler(t *testing.T) {
+	setup := func() (*sink.Reconciler, *spyConfigMapPatcher, *spyDaemonSetPodDeleter) {
+		sinks := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		sinks.Add(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "app.example.com", Port: 514},
+			},
+		})
+		sinks.Add(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns2"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "other.example.com", Port: 514},
+			},
+		})
+		clusterSinks := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		clusterSinks.Add(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://platform.example.com"},
+			},
+		})
+
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		r := sink.NewReconciler(spyPatcher, spyDeleter, sink.NewConfig(), sinks, clusterSinks)
+		return r, spyPatcher, spyDeleter
+	}
+
+	t.Run("it reconciles every cached sink at once", func(t *testing.T) {
+		r, spyPatcher, spyDeleter := setup()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/reconcile", nil)
+		req.Header.Set("Authorization", "Bearer some-token")
+		sink.ReconcileHandler(r, "some-token").ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected http status 204, got %d", rec.Code)
+		}
+		if len(spyPatcher.patches) != 1 {
+			t.Fatalf("Expected the config to be patched once, got %d patches", len(spyPatcher.patches))
+		}
+		data := string(spyPatcher.patches[0].data)
+		for _, host := range []string{"app.example.com", "other.example.com", "platform.example.com"} {
+			if !strings.Contains(data, host) {
+				t.Errorf("Expected the config to send to %s, got %s", host, data)
+			}
+		}
+		if !spyDeleter.deleteCollectionCalled {
+			t.Error("Expected the fluent-bit pods to be restarted")
+		}
+	})
+
+	t.Run("it drops sinks that are no longer cached", func(t *testing.T) {
+		sinks := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		sinks.Add(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "app.example.com", Port: 514},
+			},
+		})
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "ns2"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "deleted.example.com", Port: 514},
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://deleted-cluster.example.com"},
+			},
+		})
+		spyPatcher := &spyConfigMapPatcher{}
+		r := sink.NewReconciler(spyPatcher, &spyDaemonSetPodDeleter{}, sc, sinks, cache.NewStore(cache.MetaNamespaceKeyFunc))
+
+		r.Reconcile()
+
+		if len(spyPatcher.patches) != 1 {
+			t.Fatalf("Expected the config to be patched once, got %d patches", len(spyPatcher.patches))
+		}
+		data := string(spyPatcher.patches[0].data)
+		if !strings.Contains(data, "app.example.com") {
+			t.Errorf("Expected the config to send to app.example.com, got %s", data)
+		}
+		for _, host := range []string{"deleted.example.com", "deleted-cluster.example.com"} {
+			if strings.Contains(data, host) {
+				t.Errorf("Expected the config to not send to %s, got %s", host, data)
+			}
+		}
+	})
+
+	t.Run("it allows requests from localhost without a token", func(t *testing.T) {
+		r, spyPatcher, _ := setup()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/reconcile", nil)
+		req.RemoteAddr = "127.0.0.1:43210"
+		sink.ReconcileHandler(r, "").ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected http status 204, got %d", rec.Code)
+		}
+		if !spyPatcher.patchCalled {
+			t.Error("Expected the config to be patched")
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			method, remoteAddr, auth, token string
+			code                            int
+		}{
+			"other methods": {
+				method: http.MethodGet, remoteAddr: "127.0.0.1:43210",
+				code: http.StatusMethodNotAllowed,
+			},
+			"remote requests without a token": {
+				method: http.MethodPost, remoteAddr: "192.0.2.1:43210",
+				code: http.StatusForbidden,
+			},
+			"requests without the token": {
+				method: http.MethodPost, remoteAddr: "127.0.0.1:43210", token: "some-token",
+				code: http.StatusForbidden,
+			},
+			"requests with another token": {
+				method: http.MethodPost, remoteAddr: "192.0.2.1:43210", auth: "Bearer other-token", token: "some-token",
+				code: http.StatusForbidden,
+			},
+		}
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				r, spyPatcher, _ := setup()
+
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(test.method, "/reconcile", nil)
+				req.RemoteAddr = test.remoteAddr
+				if test.auth != "" {
+					req.Header.Set("Authorization", test.auth)
+				}
+				sink.ReconcileHandler(r, test.token).ServeHTTP(rec, req)
+
+				if rec.Code != test.code {
+					t.Errorf("Expected http status %d, got %d", test.code, rec.Code)
+				}
+				if spyPatcher.patchCalled {
+					t.Error("Expected the config not to be patched")
+				}
+			})
+		}
+	})
+}
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := map[string]struct {
+		remoteAddr, auth, token string
+		code                    int
+	}{
+		"it allows requests from localhost without a token": {
+			remoteAddr: "127.0.0.1:43210",
+			code:       http.StatusOK,
+		},
+		"it allows requests with the token": {
+			remoteAddr: "192.0.2.1:43210", auth: "Bearer some-token", token: "some-token",
+			code: http.StatusOK,
+		},
+		"it rejects remote requests without a token": {
+			remoteAddr: "192.0.2.1:43210",
+			code:       http.StatusForbidden,
+		},
+		"it rejects requests with another token": {
+			remoteAddr: "192.0.2.1:43210", auth: "Bearer other-token", token: "some-token",
+			code: http.StatusForbidden,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/topology", nil)
+			req.RemoteAddr = test.remoteAddr
+			if test.auth != "" {
+				req.Header.Set("Authorization", test.auth)
+			}
+			sink.RequireToken(test.token, ok).ServeHTTP(rec, req)
+
+			if rec.Code != test.code {
+				t.Errorf("Expected http status %d, got %d", test.code, rec.Code)
+			}
+		})
+	}
+}
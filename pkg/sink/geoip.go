@@ -0,0 +1,47 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+// WithGeoIP renders a geoip2 filter for sinks with GeoIP set. Without it
+// the field is ignored. The filter is not in every fluent-bit build, e.g.
+// not in oratos/fluent-bit-out-syslog:v0.19, and fluent-bit fails to start
+// when it is missing or a database it names is not on the node, so it is
+// only enabled for a daemonset running an image built with the filter and
+// nodes that have the databases.
+func WithGeoIP() ConfigOpt {
+	return func(sc *Config) {
+		sc.geoIP = true
+	}
+}
+
+// geoIPFilterConfig adds the country and ASN of the address in a record key
+// to the record. A lookup the database has no answer for, e.g. an ASN in a
+// country database, adds an empty value.
+const geoIPFilterConfig = `
+[FILTER]
+    Name geoip2
+    Match %[1]s
+    Database %[2]s
+    Lookup_key %[3]s
+    Record geoip_country %[3]s %%{country.iso_code}
+    Record geoip_asn %[3]s %%{autonomous_system_number}
+`
+
+func geoIPConfig(match, sourceField, database string) string {
+	return fmt.Sprintf(geoIPFilterConfig, match, database, sourceField)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exportTimeout is how long an export may take before it is canceled.
+const exportTimeout = time.Minute
+
+// ConfigExporter records the configs written to the configmap elsewhere,
+// e.g. to track their changes. Files are the written configmap keys and
+// their values and message describes the change that caused the write. An
+// export stops when ctx is done.
+type ConfigExporter interface {
+	Export(ctx context.Context, files map[string]string, message string) error
+}
+
+// WithConfigExporter passes every config written to the configmap to the
+// exporter. Configs are exported in the background in the order they are
+// written, so a slow exporter does not hold back the config or the restart
+// of fluent-bit. A failed export is logged.
+func WithConfigExporter(e ConfigExporter) ConfigOpt {
+	return func(sc *Config) {
+		sc.exporter = e
+	}
+}
+
+// exportConfig exports the files of the patches the configmap was written
+// with once the exports of the earlier configs are done. Each export is
+// canceled after exportTimeout.
+func (sc *Config) exportConfig(patches []patch, reason string) {
+	if sc.exporter == nil {
+		return
+	}
+
+	files := make(map[string]string, len(patches))
+	for _, p := range patches {
+		files[strings.TrimPrefix(p.Path, "/data/")] = p.Value
+	}
+
+	sc.mu.Lock()
+	message := fmt.Sprintf("%s\n\nGeneration: %d\n", reason, sc.generation)
+	previous, done := sc.exported, make(chan struct{})
+	sc.exported = done
+	sc.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		if previous != nil {
+			<-previous
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		if err := sc.exporter.Export(ctx, files, message); err != nil {
+			log.Printf("Unable to export config: %s", err)
+		}
+	}()
+}
+
+// GitExporter commits every exported config to a branch of a git remote
+// and pushes it. The remote is cloned into dir, which is reset to the
+// remote branch before every commit so that commits pushed by others are
+// kept. A config that does not change the files is not committed. It runs
+// the git command, so it must be on the PATH. The default ko base image has
+// no git, so .ko.yaml builds the sink-controller on an image with git.
+type GitExporter struct {
+	mu     sync.Mutex
+	remote string
+	branch string
+	dir    string
+	name   string
+	email  string
+}
+
+func NewGitExporter(remote, branch, dir, name, email string) *GitExporter {
+	return &GitExporter{
+		remote: remote,
+		branch: branch,
+		dir:    dir,
+		name:   name,
+		email:  email,
+	}
+}
+
+func (e *GitExporter) Export(ctx context.Context, files map[string]string, message string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.sync(ctx); err != nil {
+		return err
+	}
+
+	for name, value := range files {
+		err := ioutil.WriteFile(filepath.Join(e.dir, name), []byte(value), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := e.git(ctx, "add", "--all"); err != nil {
+		return err
+	}
+	status, err := e.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+
+	if _, err := e.git(ctx, "commit", "--quiet", "--message", message); err != nil {
+		return err
+	}
+	_, err = e.git(ctx, "push", "origin", "HEAD:refs/heads/"+e.branch)
+	return err
+}
+
+// sync clones the remote into the exporter's dir unless it was cloned
+// before and resets it to the remote branch when the branch exists.
+func (e *GitExporter) sync(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(e.dir, ".git")); os.IsNotExist(err) {
+		if _, err := e.git(ctx, "clone", "--quiet", e.remote, "."); err != nil {
+			return err
+		}
+	}
+
+	if _, err := e.git(ctx, "fetch", "--quiet", "origin"); err != nil {
+		return err
+	}
+	ref := "refs/remotes/origin/" + e.branch
+	if _, err := e.git(ctx, "rev-parse", "--verify", "--quiet", ref); err != nil {
+		return nil
+	}
+	_, err := e.git(ctx, "checkout", "--quiet", "-B", e.branch, ref)
+	if err != nil {
+		return err
+	}
+	_, err = e.git(ctx, "reset", "--quiet", "--hard", ref)
+	return err
+}
+
+func (e *GitExporter) git(ctx context.Context, args ...string) (string, error) {
+	if err := os.MkdirAll(e.dir, 0755); err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = e.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+e.name,
+		"GIT_AUTHOR_EMAIL="+e.email,
+		"GIT_COMMITTER_NAME="+e.name,
+		"GIT_COMMITTER_EMAIL="+e.email,
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestConfigExporter(t *testing.T) {
+	exportedSink := func(port int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: port,
+				},
+			},
+		}
+	}
+
+	t.Run("it exports every config written to the configmap", func(t *testing.T) {
+		exporter := newSpyConfigExporter(nil)
+		sc := sink.NewConfig(sink.WithConfigExporter(exporter))
+		c := sink.NewController(&spyConfigMapPatcher{}, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(exportedSink(12345))
+		c.OnDelete(exportedSink(12345))
+
+		first, second := exporter.next(t), exporter.next(t)
+		if first.message != "LogSink ns1/some-sink upserted\n\nGeneration: 1\n" {
+			t.Errorf("Unexpected message %q", first.message)
+		}
+		if !strings.Contains(first.files["outputs.conf"], "example.com:12345") {
+			t.Errorf("Expected the outputs of the sink, got %s", first.files["outputs.conf"])
+		}
+		for _, name := range []string{"sinks.lua", "audit-files.conf"} {
+			if _, ok := first.files[name]; !ok {
+				t.Errorf("Expected %s to be exported", name)
+			}
+		}
+		if second.message != "LogSink ns1/some-sink deleted\n\nGeneration: 2\n" {
+			t.Errorf("Unexpected message %q", second.message)
+		}
+		if !first.deadline || !second.deadline {
+			t.Error("Expected the exports to time out")
+		}
+	})
+
+	t.Run("it restarts fluent-bit before the export is done", func(t *testing.T) {
+		exporter := newSpyConfigExporter(nil)
+		exporter.block = make(chan struct{})
+		sc := sink.NewConfig(sink.WithConfigExporter(exporter))
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewController(&spyConfigMapPatcher{}, spyDeleter, sc)
+
+		c.OnAdd(exportedSink(12345))
+
+		if !spyDeleter.deleteCollectionCalled {
+			t.Error("Expected fluent-bit to be restarted while the export runs")
+		}
+		close(exporter.block)
+		if commit := exporter.next(t); !strings.Contains(commit.files["outputs.conf"], "example.com:12345") {
+			t.Errorf("Expected the outputs of the sink, got %s", commit.files["outputs.conf"])
+		}
+	})
+
+	t.Run("it does not export configs that are not written", func(t *testing.T) {
+		exporter := newSpyConfigExporter(nil)
+		sc := sink.NewConfig(sink.WithConfigExporter(exporter))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		sink.NewPauser(spyPatcher, &spyDaemonSetPodDeleter{}, sc).Toggle()
+		c.OnAdd(exportedSink(12345))
+
+		if len(exporter.commits) != 0 {
+			t.Errorf("Expected no exports, got %d", len(exporter.commits))
+		}
+	})
+
+	t.Run("it writes the config when the export fails", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithConfigExporter(newSpyConfigExporter(errors.New("push rejected"))))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(exportedSink(12345))
+
+		if !spyPatcher.patchCalled {
+			t.Error("Expected the configmap to be patched")
+		}
+	})
+}
+
+func TestGitExporter(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp, err := ioutil.TempDir("", "git-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	remote := filepath.Join(tmp, "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("Unable to create remote: %s: %s", err, out)
+	}
+	e := sink.NewGitExporter(remote, "configs", filepath.Join(tmp, "clone"), "sink-controller", "sink-controller@example.com")
+
+	files := map[string]string{"outputs.conf": "[OUTPUT]\n    Name null\n"}
+	if err := e.Export(context.Background(), files, "LogSink ns1/a upserted"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Export(context.Background(), files, "LogSink ns1/a upserted"); err != nil {
+		t.Fatal(err)
+	}
+	files["outputs.conf"] = "[OUTPUT]\n    Name stdout\n"
+	if err := e.Export(context.Background(), files, "LogSink ns1/b upserted"); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := exec.Command("git", "--git-dir", remote, "log", "--format=%s|%an", "configs").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unable to read the remote log: %s: %s", err, log)
+	}
+	expected := "LogSink ns1/b upserted|sink-controller\nLogSink ns1/a upserted|sink-controller\n"
+	if diff := cmp.Diff(expected, string(log)); diff != "" {
+		t.Errorf("Commits not equal (-want, +got) = %v", diff)
+	}
+
+	config, err := exec.Command("git", "--git-dir", remote, "show", "configs:outputs.conf").CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unable to read the pushed config: %s: %s", err, config)
+	}
+	if string(config) != files["outputs.conf"] {
+		t.Errorf("Expected the last config to be pushed, got %s", config)
+	}
+}
+
+type exportedCommit struct {
+	files    map[string]string
+	message  string
+	deadline bool
+}
+
+type spyConfigExporter struct {
+	commits chan exportedCommit
+	block   chan struct{}
+	err     error
+}
+
+func newSpyConfigExporter(err error) *spyConfigExporter {
+	return &spyConfigExporter{
+		commits: make(chan exportedCommit, 10),
+		err:     err,
+	}
+}
+
+func (s *spyConfigExporter) Export(ctx context.Context, files map[string]string, message string) error {
+	if s.block != nil {
+		<-s.block
+	}
+	if s.err != nil {
+		return s.err
+	}
+	_, deadline := ctx.Deadline()
+	s.commits <- exportedCommit{files: files, message: message, deadline: deadline}
+	return nil
+}
+
+func (s *spyConfigExporter) next(t *testing.T) exportedCommit {
+	t.Helper()
+	select {
+	case c := <-s.commits:
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("Expected a config to be exported")
+	}
+	return exportedCommit{}
+}
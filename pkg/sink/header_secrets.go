@@ -0,0 +1,260 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// HeaderSecretName is the Secret in the namespace of fluent-bit that holds
+// the header values of sinks. The fluent-bit daemonset reads its keys into
+// its environment.
+const HeaderSecretName = "fluent-bit-headers"
+
+type SecretUpdater interface {
+	Update(*coreV1.Secret) (*coreV1.Secret, error)
+}
+
+// SecretGetter gets a Secret by namespace and name.
+type SecretGetter interface {
+	Get(namespace, name string) (*coreV1.Secret, error)
+}
+
+// ClientSecretGetter gets each Secret from the API server, so the
+// controller only reads the Secrets sinks reference and does not need to
+// list or watch every Secret of the cluster.
+type ClientSecretGetter struct {
+	Client typedcorev1.SecretsGetter
+}
+
+func (g ClientSecretGetter) Get(namespace, name string) (*coreV1.Secret, error) {
+	return g.Client.Secrets(namespace).Get(name, metav1.GetOptions{})
+}
+
+// headerSecretRef is a header value of a sink and the environment variable
+// fluent-bit reads it from.
+type headerSecretRef struct {
+	env       string
+	namespace string
+	name      string
+	key       string
+}
+
+// headerEnv names the environment variable of a sink's header. Header
+// names and sink keys are not valid variable names, so they are hashed.
+func headerEnv(ref sinkRef, header string) string {
+	h := fnv.New32a()
+	h.Write([]byte(ref.key + "|" + header))
+	return fmt.Sprintf("HEADER_%X", h.Sum32())
+}
+
+// headersConfig renders a header for every header from a secret of a sink,
+// which fluent-bit interpolates from its environment.
+func headersConfig(ref sinkRef) string {
+	hfs := ref.spec.HeadersFromSecret
+	if hfs == nil {
+		return ""
+	}
+
+	headers := make([]string, 0, len(hfs.Headers))
+	for h := range hfs.Headers {
+		headers = append(headers, h)
+	}
+	sort.Strings(headers)
+
+	var config string
+	for _, h := range headers {
+		config += fmt.Sprintf("    Header %s ${%s}\n", h, headerEnv(ref, h))
+	}
+	return config
+}
+
+// headerSecretRefs returns the header values of every webhook sink.
+// clusterNamespace is the namespace the Secrets of cluster sinks are in.
+func (sc *Config) headerSecretRefs(clusterNamespace string) []headerSecretRef {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	var refs []headerSecretRef
+	for _, ref := range sc.sinkRefs("webhook") {
+		hfs := ref.spec.HeadersFromSecret
+		if hfs == nil {
+			continue
+		}
+
+		namespace := canonicalNamespace(ref.namespace)
+		if ref.cluster || ref.glob {
+			namespace = clusterNamespace
+		}
+		for h, k := range hfs.Headers {
+			refs = append(refs, headerSecretRef{
+				env:       headerEnv(ref, h),
+				namespace: namespace,
+				name:      hfs.SecretName,
+				key:       k,
+			})
+		}
+	}
+	return refs
+}
+
+// HeaderSecretController copies the header values sinks read from Secrets
+// into the Secret of fluent-bit and restarts fluent-bit when they change,
+// since fluent-bit only reads its environment when it starts. Only the
+// Secrets sinks reference are read, so they are polled rather than
+// watched. The values are also synced by every reconcile of the sinks
+// before fluent-bit is restarted, so a sink's new values take effect with
+// a single restart.
+type HeaderSecretController struct {
+	namespace string
+	secrets   SecretGetter
+	su        SecretUpdater
+	cmp       ConfigMapPatcher
+	dsp       DaemonSetPodDeleter
+	sc        *Config
+
+	mu     sync.Mutex
+	synced bool
+	data   map[string][]byte
+}
+
+func NewHeaderSecretController(
+	namespace string,
+	secrets SecretGetter,
+	su SecretUpdater,
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	sc *Config,
+) *HeaderSecretController {
+	c := &HeaderSecretController{
+		namespace: namespace,
+		secrets:   secrets,
+		su:        su,
+		cmp:       cmp,
+		dsp:       dsp,
+		sc:        sc,
+	}
+	sc.setHeaderSync(c.update)
+	return c
+}
+
+// Run syncs the values every interval until stopCh is closed.
+func (c *HeaderSecretController) Run(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Sync()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Sync reconciles the sinks when the values changed. The values are
+// written by the reconcile, so neither they nor fluent-bit change while the
+// reconciles are paused.
+func (c *HeaderSecretController) Sync() {
+	c.mu.Lock()
+	_, changed := c.values()
+	c.mu.Unlock()
+
+	if changed {
+		applyConfig(c.sc, c.cmp, c.dsp, "Header values of sinks changed")
+	}
+}
+
+// update writes the values sinks read to the Secret of fluent-bit when
+// they changed.
+func (c *HeaderSecretController) update() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, changed := c.values()
+	if !changed {
+		return
+	}
+	_, err := c.su.Update(&coreV1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HeaderSecretName,
+			Namespace: c.namespace,
+		},
+		Data: data,
+	})
+	if err != nil {
+		log.Printf("Unable to update Secret %s: %s", HeaderSecretName, err)
+		return
+	}
+	c.data = data
+}
+
+// values returns the values sinks read and whether they differ from the
+// values of the Secret of fluent-bit. It is called with c.mu held.
+func (c *HeaderSecretController) values() (map[string][]byte, bool) {
+	if !c.synced {
+		// The values already in the Secret are what fluent-bit runs with,
+		// so they are not written again when the controller starts.
+		if s, err := c.secrets.Get(c.namespace, HeaderSecretName); err == nil {
+			c.data = s.Data
+		}
+		c.synced = true
+	}
+
+	data := make(map[string][]byte)
+	for _, ref := range c.sc.headerSecretRefs(c.namespace) {
+		s, err := c.secrets.Get(ref.namespace, ref.name)
+		if err != nil {
+			continue
+		}
+		if v, ok := s.Data[ref.key]; ok {
+			data[ref.env] = v
+		}
+	}
+	if len(data) == 0 && len(c.data) == 0 || reflect.DeepEqual(data, c.data) {
+		return nil, false
+	}
+	return data, true
+}
+
+// setHeaderSync sets the function syncing the header values of sinks
+// before fluent-bit is restarted.
+func (sc *Config) setHeaderSync(f func()) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.headerSync = f
+}
+
+// syncHeaders syncs the header values of sinks, if a controller syncs them.
+func (sc *Config) syncHeaders() {
+	sc.mu.Lock()
+	f := sc.headerSync
+	sc.mu.Unlock()
+
+	if f != nil {
+		f()
+	}
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestHeadersFromSecret(t *testing.T) {
+	headerSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/place",
+				HeadersFromSecret: &v1alpha1.HeadersFromSecret{
+					SecretName: "auth",
+					Headers: map[string]string{
+						"Authorization": "token",
+					},
+				},
+			},
+		},
+	}
+	secret := func(namespace, name string, data map[string]string) *coreV1.Secret {
+		s := &coreV1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Data: make(map[string][]byte),
+		}
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	headerRegex := regexp.MustCompile(`Header Authorization \$\{(HEADER_[0-9A-F]+)\}`)
+
+	t.Run("it renders the header from the environment", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(headerSink)
+
+		expected := `
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/some-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /place
+    tls On
+    Header Authorization ${HEADER_`
+		config := sc.String()
+		if diff := cmp.Diff(expected, config[:len(expected)]); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it copies the header values and restarts fluent-bit when they rotate", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(headerSink)
+		env := headerRegex.FindStringSubmatch(sc.String())[1]
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "first"}))
+		spyUpdater := &spySecretUpdater{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			&spyConfigMapPatcher{},
+			spyDeleter,
+			sc,
+		)
+
+		c.Sync()
+		expected := secret("knative-observability", sink.HeaderSecretName, map[string]string{env: "first"})
+		if diff := cmp.Diff(expected, spyUpdater.updated); diff != "" {
+			t.Errorf("Secret not equal (-want, +got) = %v", diff)
+		}
+		if !spyDeleter.deleteCollectionCalled {
+			t.Error("Expected fluent-bit to be restarted")
+		}
+
+		spyDeleter.deleteCollectionCalled = false
+		c.Sync()
+		if spyUpdater.updates != 1 || spyDeleter.deleteCollectionCalled {
+			t.Error("Expected no update without a change")
+		}
+
+		indexer.Update(secret("ns1", "auth", map[string]string{"token": "second"}))
+		c.Sync()
+		if string(spyUpdater.updated.Data[env]) != "second" || !spyDeleter.deleteCollectionCalled {
+			t.Errorf("Expected the rotated value and a restart, got %v", spyUpdater.updated.Data)
+		}
+	})
+
+	t.Run("it reads ClusterLogSink secrets in the controller's namespace", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+			Spec:       headerSink.Spec,
+		})
+		env := headerRegex.FindStringSubmatch(sc.String())[1]
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "other"}))
+		indexer.Add(secret("knative-observability", "auth", map[string]string{"token": "cluster"}))
+		spyUpdater := &spySecretUpdater{}
+		c := sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			sc,
+		)
+
+		c.Sync()
+		if string(spyUpdater.updated.Data[env]) != "cluster" {
+			t.Errorf("Expected the value of the controller's namespace, got %v", spyUpdater.updated.Data)
+		}
+	})
+
+	t.Run("it polls the secrets until stopped", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(headerSink)
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "first"}))
+		spyUpdater := &spySecretUpdater{}
+		c := sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			sc,
+		)
+
+		stopCh := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			c.Run(time.Millisecond, stopCh)
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(stopCh)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected Run to return when stopped")
+		}
+		if spyUpdater.updates != 1 {
+			t.Errorf("Expected the values to be written once, got %d updates", spyUpdater.updates)
+		}
+	})
+
+	t.Run("it does not restart fluent-bit for values it already has", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(headerSink)
+		env := headerRegex.FindStringSubmatch(sc.String())[1]
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "first"}))
+		indexer.Add(secret("knative-observability", sink.HeaderSecretName, map[string]string{env: "first"}))
+		spyUpdater := &spySecretUpdater{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			&spyConfigMapPatcher{},
+			spyDeleter,
+			sc,
+		)
+
+		c.Sync()
+		if spyUpdater.updates != 0 || spyDeleter.deleteCollectionCalled {
+			t.Error("Expected no update or restart")
+		}
+	})
+
+	t.Run("it writes the values before the restart of a sink change", func(t *testing.T) {
+		sc := sink.NewConfig()
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "first"}))
+		spyUpdater := &spySecretUpdater{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			&spyConfigMapPatcher{},
+			spyDeleter,
+			sc,
+		)
+		c := sink.NewController(&spyConfigMapPatcher{}, spyDeleter, sc)
+
+		c.OnAdd(headerSink)
+		env := headerRegex.FindStringSubmatch(sc.String())[1]
+		if spyUpdater.updates != 1 || string(spyUpdater.updated.Data[env]) != "first" {
+			t.Errorf("Expected the values to be written once, got %d updates", spyUpdater.updates)
+		}
+		if !spyDeleter.deleteCollectionCalled {
+			t.Error("Expected fluent-bit to be restarted")
+		}
+	})
+
+	t.Run("it holds the values and the restart while paused", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(headerSink)
+		env := headerRegex.FindStringSubmatch(sc.String())[1]
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		indexer.Add(secret("ns1", "auth", map[string]string{"token": "first"}))
+		spyUpdater := &spySecretUpdater{}
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewHeaderSecretController(
+			"knative-observability",
+			listerSecrets{corelisters.NewSecretLister(indexer)},
+			spyUpdater,
+			spyPatcher,
+			spyDeleter,
+			sc,
+		)
+		p := sink.NewPauser(spyPatcher, spyDeleter, sc)
+
+		p.Toggle()
+		c.Sync()
+		if spyUpdater.updates != 0 || spyDeleter.deleteCollectionCalled {
+			t.Fatal("Expected no update or restart while paused")
+		}
+
+		p.Toggle()
+		if spyUpdater.updates != 1 || string(spyUpdater.updated.Data[env]) != "first" {
+			t.Errorf("Expected the values to be written on resume, got %d updates", spyUpdater.updates)
+		}
+		if !spyDeleter.deleteCollectionCalled {
+			t.Error("Expected fluent-bit to be restarted on resume")
+		}
+	})
+}
+
+type spySecretUpdater struct {
+	updates int
+	updated *coreV1.Secret
+}
+
+func (s *spySecretUpdater) Update(secret *coreV1.Secret) (*coreV1.Secret, error) {
+	s.updates++
+	s.updated = secret
+	return secret, nil
+}
+
+// listerSecrets gets Secrets from a lister in place of the API server.
+type listerSecrets struct {
+	lister corelisters.SecretLister
+}
+
+func (s listerSecrets) Get(namespace, name string) (*coreV1.Secret, error) {
+	return s.lister.Secrets(namespace).Get(name)
+}
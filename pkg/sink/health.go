@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	appsV1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type DaemonSetGetter interface {
+	Get(name string, options metav1.GetOptions) (*appsV1.DaemonSet, error)
+}
+
+// Health summarizes the state of the fluent-bit daemonset.
+type Health struct {
+	DesiredPods int32 `json:"desired_pods"`
+	ReadyPods   int32 `json:"ready_pods"`
+
+	// ConfigGeneration counts the configs the controller has written since
+	// it started. CurrentConfigPods is the number of pods started after
+	// the last one was written.
+	ConfigGeneration  int64 `json:"config_generation"`
+	CurrentConfigPods int   `json:"current_config_pods"`
+	ConfigCurrent     bool  `json:"config_current"`
+
+	Healthy bool `json:"healthy"`
+}
+
+// HealthChecker reports the health of the fluent-bit daemonset managed by
+// the sink controllers.
+type HealthChecker struct {
+	dsg  DaemonSetGetter
+	pods PodLister
+	sc   *Config
+}
+
+func NewHealthChecker(dsg DaemonSetGetter, pods PodLister, sc *Config) *HealthChecker {
+	return &HealthChecker{
+		dsg:  dsg,
+		pods: pods,
+		sc:   sc,
+	}
+}
+
+// Health returns the current health of the daemonset. The controllers
+// restart every fluent-bit pod after writing a config, so a pod started
+// after the last write runs the current config.
+func (h *HealthChecker) Health() (Health, error) {
+	ds, err := h.dsg.Get(DaemonSetName, metav1.GetOptions{})
+	if err != nil {
+		return Health{}, err
+	}
+
+	pods, err := h.pods.List(metav1.ListOptions{
+		LabelSelector: "app=fluent-bit",
+	})
+	if err != nil {
+		return Health{}, err
+	}
+
+	generation, appliedAt := h.sc.applied()
+	// Pod timestamps only have second precision.
+	appliedAt = appliedAt.Truncate(time.Second)
+
+	var current int
+	for _, p := range pods.Items {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		if !p.CreationTimestamp.Time.Before(appliedAt) {
+			current++
+		}
+	}
+
+	health := Health{
+		DesiredPods:       ds.Status.DesiredNumberScheduled,
+		ReadyPods:         ds.Status.NumberReady,
+		ConfigGeneration:  generation,
+		CurrentConfigPods: current,
+		ConfigCurrent:     int32(current) >= ds.Status.DesiredNumberScheduled,
+	}
+	health.Healthy = health.ConfigCurrent &&
+		health.ReadyPods >= health.DesiredPods
+
+	return health, nil
+}
+
+// HealthHandler serves the daemonset's health as JSON. It responds with a
+// 503 when the daemonset is unhealthy.
+func HealthHandler(h *HealthChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		health, err := h.Health()
+		if err != nil {
+			log.Printf("Unable to get fluent-bit health: %s", err)
+			http.Error(w, "Unable to get fluent-bit health", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !health.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		err = json.NewEncoder(w).Encode(health)
+		if err != nil {
+			log.Printf("Unable to marshal health: %s", err)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestHealth(t *testing.T) {
+	someSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	}
+
+	// applied returns a config that has written a sink to the configmap.
+	applied := func() *sink.Config {
+		sc := sink.NewConfig()
+		sink.NewController(
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			sc,
+		).OnAdd(someSink)
+		return sc
+	}
+
+	t.Run("it is healthy when every pod is ready with the current config", func(t *testing.T) {
+		sc := applied()
+		dsg := &spyDaemonSetGetter{desired: 2, ready: 2}
+		pods := &spyPodLister{pods: []coreV1.Pod{
+			podCreatedAt(time.Now().Add(time.Hour)),
+			podCreatedAt(time.Now().Add(time.Hour)),
+		}}
+
+		health, err := sink.NewHealthChecker(dsg, pods, sc).Health()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := sink.Health{
+			DesiredPods:       2,
+			ReadyPods:         2,
+			ConfigGeneration:  1,
+			CurrentConfigPods: 2,
+			ConfigCurrent:     true,
+			Healthy:           true,
+		}
+		if diff := cmp.Diff(expected, health); diff != "" {
+			t.Errorf("Health not equal (-want, +got) = %v", diff)
+		}
+		if dsg.name != "fluent-bit" {
+			t.Errorf("Expected the fluent-bit daemonset, got %s", dsg.name)
+		}
+		if pods.selector != "app=fluent-bit" {
+			t.Errorf("Expected pods to be selected by app=fluent-bit, got %s", pods.selector)
+		}
+	})
+
+	t.Run("it is unhealthy when pods are not ready", func(t *testing.T) {
+		sc := applied()
+		dsg := &spyDaemonSetGetter{desired: 2, ready: 1}
+		pods := &spyPodLister{pods: []coreV1.Pod{
+			podCreatedAt(time.Now().Add(time.Hour)),
+			podCreatedAt(time.Now().Add(time.Hour)),
+		}}
+
+		health, err := sink.NewHealthChecker(dsg, pods, sc).Health()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if health.ReadyPods != 1 || health.DesiredPods != 2 {
+			t.Errorf("Expected 1 of 2 pods ready, got %+v", health)
+		}
+		if health.Healthy {
+			t.Errorf("Expected to be unhealthy, got %+v", health)
+		}
+	})
+
+	t.Run("it is unhealthy when pods started before the last config", func(t *testing.T) {
+		sc := applied()
+		dsg := &spyDaemonSetGetter{desired: 2, ready: 2}
+		deleted := podCreatedAt(time.Now().Add(time.Hour))
+		deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		pods := &spyPodLister{pods: []coreV1.Pod{
+			podCreatedAt(time.Now().Add(time.Hour)),
+			podCreatedAt(time.Now().Add(-time.Hour)),
+			deleted,
+		}}
+
+		health, err := sink.NewHealthChecker(dsg, pods, sc).Health()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if health.CurrentConfigPods != 1 {
+			t.Errorf("Expected 1 pod with the current config, got %d", health.CurrentConfigPods)
+		}
+		if health.ConfigCurrent || health.Healthy {
+			t.Errorf("Expected the config to not be current, got %+v", health)
+		}
+	})
+
+	t.Run("it serves the health as JSON", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			ready int32
+			code  int
+		}{
+			{"healthy", 1, http.StatusOK},
+			{"unhealthy", 0, http.StatusServiceUnavailable},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				h := sink.NewHealthChecker(
+					&spyDaemonSetGetter{desired: 1, ready: test.ready},
+					&spyPodLister{pods: []coreV1.Pod{podCreatedAt(time.Now())}},
+					sink.NewConfig(),
+				)
+
+				rec := httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/health", nil)
+				sink.HealthHandler(h).ServeHTTP(rec, req)
+
+				if rec.Code != test.code {
+					t.Errorf("expected http status %d, got %d", test.code, rec.Code)
+				}
+				if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+					t.Errorf("expected Content-Type application/json, got %s", ct)
+				}
+
+				var health sink.Health
+				err := json.NewDecoder(rec.Body).Decode(&health)
+				if err != nil {
+					t.Fatalf("unable to decode health: %s", err)
+				}
+				if health.ReadyPods != test.ready {
+					t.Errorf("expected %d ready pods, got %d", test.ready, health.ReadyPods)
+				}
+			})
+		}
+	})
+
+	t.Run("it returns an error when the daemonset can not be read", func(t *testing.T) {
+		h := sink.NewHealthChecker(
+			&spyDaemonSetGetter{err: errors.New("some-error")},
+			&spyPodLister{},
+			sink.NewConfig(),
+		)
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		sink.HealthHandler(h).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected http status 500, got %d", rec.Code)
+		}
+	})
+}
+
+func podCreatedAt(t time.Time) coreV1.Pod {
+	return coreV1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(t),
+		},
+	}
+}
+
+type spyDaemonSetGetter struct {
+	desired int32
+	ready   int32
+	err     error
+	name    string
+}
+
+func (s *spyDaemonSetGetter) Get(name string, options metav1.GetOptions) (*appsV1.DaemonSet, error) {
+	s.name = name
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &appsV1.DaemonSet{
+		Status: appsV1.DaemonSetStatus{
+			DesiredNumberScheduled: s.desired,
+			NumberReady:            s.ready,
+		},
+	}, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// heartbeatInputConfig renders a dummy input producing the heartbeat
+// record every interval. Fluent-bit substitutes the NODE_NAME the
+// daemonset gives its pods into the record, so every node's heartbeat can
+// be told apart.
+const heartbeatInputConfig = `
+[INPUT]
+    Name dummy
+    Tag %s
+    Alias %s
+    Dummy %s
+    Interval_Sec %d
+    Interval_NSec %d
+%s`
+
+// heartbeatConfig renders the heartbeat input of every sink with a
+// heartbeat. The heartbeats are tagged like the sink's own copy of its
+// records, so no other sink receives them. The refs of a cluster sink with
+// namespace globs each have an output, so each gets its own heartbeat.
+func (sc *Config) heartbeatConfig() string {
+	var config string
+	for _, ref := range sc.filteredSinkRefs() {
+		hb := ref.spec.Heartbeat
+		if hb == nil || hb.Interval.Duration <= 0 {
+			continue
+		}
+
+		heartbeat := map[string]string{"sink": ref.name, "host": "${NODE_NAME}"}
+		if !ref.cluster {
+			heartbeat["namespace"] = canonicalNamespace(ref.namespace)
+		}
+		record, err := json.Marshal(map[string]interface{}{
+			"log":       "heartbeat",
+			"heartbeat": heartbeat,
+		})
+		if err != nil {
+			continue
+		}
+
+		interval := hb.Interval.Duration
+		config += fmt.Sprintf(
+			heartbeatInputConfig,
+			sinkMatch(ref),
+			inputAlias(ref, "heartbeat"),
+			record,
+			int64(interval/time.Second),
+			int64(interval%time.Second),
+			sc.inputStorageConfig(),
+		)
+	}
+	return config
+}
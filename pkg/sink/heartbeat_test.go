@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+	"github.com/knative/observability/pkg/sink/flbconfig"
+)
+
+func TestHeartbeat(t *testing.T) {
+	heartbeatSpec := func(interval time.Duration) v1alpha1.SinkSpec {
+		spec := v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+		}
+		if interval != 0 {
+			spec.Heartbeat = &v1alpha1.HeartbeatSpec{
+				Interval: metav1.Duration{Duration: interval},
+			}
+		}
+		return spec
+	}
+	heartbeatSink := func(name string, interval time.Duration) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: heartbeatSpec(interval),
+		}
+	}
+
+	t.Run("it adds a heartbeat input tagged like the sink's own records", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(heartbeatSink("quiet", 30*time.Second))
+
+		expected := `
+[INPUT]
+    Name dummy
+    Tag filtered.ns.ns1.quiet
+    Alias ns1/quiet:heartbeat
+    Dummy {"heartbeat":{"host":"${NODE_NAME}","namespace":"ns1","sink":"quiet"},"log":"heartbeat"}
+    Interval_Sec 30
+    Interval_NSec 0
+`
+		config := sc.String()
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected config to contain %s, got %s", expected, config)
+		}
+		if !strings.Contains(config, "Name http\n    Match filtered.ns.ns1.quiet\n") {
+			t.Errorf("Expected the sink to match the heartbeat tag, got %s", config)
+		}
+		if _, err := flbconfig.Parse("outputs.conf", config); err != nil {
+			t.Errorf("Expected the config to parse, got %s", err)
+		}
+	})
+
+	t.Run("it splits the interval into seconds and nanoseconds", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(heartbeatSink("quick", 1500*time.Millisecond))
+
+		config := sc.String()
+		if !strings.Contains(config, "    Interval_Sec 1\n    Interval_NSec 500000000\n") {
+			t.Errorf("Expected an interval of 1.5s, got %s", config)
+		}
+	})
+
+	t.Run("it does not send the heartbeats to other sinks of the namespace", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(heartbeatSink("quiet", 30*time.Second))
+		sc.UpsertSink(heartbeatSink("silent", 0))
+
+		config := sc.String()
+		if strings.Count(config, "Name dummy") != 1 {
+			t.Errorf("Expected one heartbeat input, got %s", config)
+		}
+		if !strings.Contains(config, "Name http\n    Match *_ns1_*\n") {
+			t.Errorf("Expected the other sink to read the shared records, got %s", config)
+		}
+	})
+
+	t.Run("it tags the heartbeats of a cluster sink like its own records", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "everything"},
+			Spec:       heartbeatSpec(time.Minute),
+		})
+
+		config := sc.String()
+		expected := "    Tag filtered.cluster.everything\n" +
+			"    Alias cluster:everything:heartbeat\n" +
+			`    Dummy {"heartbeat":{"host":"${NODE_NAME}","sink":"everything"},"log":"heartbeat"}` + "\n"
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected config to contain %s, got %s", expected, config)
+		}
+	})
+
+	t.Run("it tags the heartbeats of a sink with its own tag", func(t *testing.T) {
+		s := heartbeatSink("raw", time.Minute)
+		s.Spec.RawMode = true
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		config := sc.String()
+		if strings.Count(config, "Tag raw.ns.ns1.raw\n") != 2 {
+			t.Errorf("Expected the heartbeat to be tagged like the raw records, got %s", config)
+		}
+	})
+
+	t.Run("it does not add a heartbeat for other sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(heartbeatSink("silent", 0))
+
+		if config := sc.String(); strings.Contains(config, "Name dummy") {
+			t.Errorf("Expected no heartbeat input, got %s", config)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// PostRenderHook modifies the rendered outputs.conf before it is written to
+// the configmap.
+type PostRenderHook interface {
+	Mutate(config string) (string, error)
+}
+
+type ConfigOpt func(*Config)
+
+// WithPostRenderHook passes every rendered config through the hook. When
+// the hook fails the config is not written, so fluent-bit keeps running the
+// last config that passed the hook.
+func WithPostRenderHook(h PostRenderHook) ConfigOpt {
+	return func(sc *Config) {
+		sc.hook = h
+	}
+}
+
+// HTTPPostRenderHook posts the rendered config to an external service and
+// uses the response body as the config.
+type HTTPPostRenderHook struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPPostRenderHook(url string, timeout time.Duration) *HTTPPostRenderHook {
+	return &HTTPPostRenderHook{
+		url: url,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (h *HTTPPostRenderHook) Mutate(config string) (string, error) {
+	resp, err := h.client.Post(h.url, "text/plain", bytes.NewBufferString(config))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if len(body) == 0 {
+		return "", errors.New("empty config")
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestPostRenderHook(t *testing.T) {
+	someSink := func(name string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		}
+	}
+
+	t.Run("it writes the config returned by the hook", func(t *testing.T) {
+		var received string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			received = string(body)
+			w.Write(append(body, "    Retry_Limit 5\n"...))
+		}))
+		defer server.Close()
+
+		spyPatcher := &spyConfigMapPatcher{}
+		sc := sink.NewConfig(sink.WithPostRenderHook(
+			sink.NewHTTPPostRenderHook(server.URL, time.Second),
+		))
+		c := sink.NewController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(someSink("some-sink"))
+
+		if received != sc.String() {
+			t.Errorf("Expected the hook to receive the rendered config, got %s", received)
+		}
+		jp := lastPatch(t, spyPatcher)
+		expected := sc.String() + "    Retry_Limit 5\n"
+		if actual := findPatch(jp, "/data/outputs.conf").Value; actual != expected {
+			t.Errorf("Expected config %s, got %s", expected, actual)
+		}
+	})
+
+	t.Run("it keeps the last config when the hook fails", func(t *testing.T) {
+		fail := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(body)
+		}))
+		defer server.Close()
+
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		sc := sink.NewConfig(sink.WithPostRenderHook(
+			sink.NewHTTPPostRenderHook(server.URL, time.Second),
+		))
+		c := sink.NewController(spyPatcher, spyDeleter, sc)
+
+		c.OnAdd(someSink("some-sink"))
+		if len(spyPatcher.patches) != 1 {
+			t.Fatalf("Expected 1 patch, got %d", len(spyPatcher.patches))
+		}
+
+		fail = true
+		spyDeleter.deleteCollectionCalled = false
+		c.OnAdd(someSink("other-sink"))
+
+		if len(spyPatcher.patches) != 1 {
+			t.Errorf("Expected the config to not be patched, got %d patches", len(spyPatcher.patches))
+		}
+		if spyDeleter.deleteCollectionCalled {
+			t.Error("Expected fluent-bit to not be restarted")
+		}
+	})
+
+	t.Run("it fails when the hook returns an empty config", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		_, err := sink.NewHTTPPostRenderHook(server.URL, time.Second).Mutate("config")
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+// hostnameSources are the Lua expressions of the hostname of each
+// hostname source. The daemonset gives the fluent-bit pods the name of
+// their node as NODE_NAME.
+var hostnameSources = map[string]string{
+	"node":      `os.getenv("NODE_NAME")`,
+	"pod":       `k8s["pod_name"]`,
+	"container": `k8s["container_name"]`,
+}
+
+// hostnameLua sets the host of a record's kubernetes metadata, which the
+// syslog output sends as the HOSTNAME of the message. Records without the
+// source are left as they are.
+func hostnameLua(source string) string {
+	expr, ok := hostnameSources[source]
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local host = %s
+        if type(host) == "string" and host ~= "" then
+            k8s["host"] = host
+            code = 1
+        end
+    end
+`, expr)
+}
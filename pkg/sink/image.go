@@ -0,0 +1,62 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+// imageMetadataPrelude defines image_metadata, which returns the image and
+// digest of the container that wrote a record. The kubernetes filter adds
+// the container's image ID as container_hash, e.g.
+// docker-pullable://nginx@sha256:..., and newer versions also add
+// container_image.
+const imageMetadataPrelude = `
+local function image_metadata(k8s)
+    local image = k8s["container_image"]
+    if type(image) ~= "string" then
+        image = nil
+    end
+
+    local hash = k8s["container_hash"]
+    if type(hash) ~= "string" then
+        return image, nil
+    end
+    hash = (string.gsub(hash, "^[%w%-]+://", ""))
+
+    local at = string.find(hash, "@", 1, true)
+    if at == nil then
+        if string.find(hash, "^sha256:") then
+            return image, hash
+        end
+        return image, nil
+    end
+    return image or string.sub(hash, 1, at - 1), string.sub(hash, at + 1)
+end
+`
+
+// imageMetadataBody sets the container_image and container_image_digest
+// fields of a record's kubernetes metadata.
+const imageMetadataBody = `
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local image, digest = image_metadata(k8s)
+        if image ~= nil then
+            k8s["container_image"] = image
+            code = 1
+        end
+        if digest ~= nil then
+            k8s["container_image_digest"] = digest
+            code = 1
+        end
+    end
+`
@@ -0,0 +1,31 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+// jsonBodyFilterConfig parses the log of a sink's records with the
+// json-body parser in parsers.conf. Reserve_Data keeps the record's other
+// keys, e.g. its kubernetes metadata, next to the parsed fields. The parser
+// filter passes records it cannot parse through unchanged, so a log that
+// is not JSON is kept as a string. It is rendered before the sink's Lua
+// filter so that the Lua steps see the parsed fields.
+const jsonBodyFilterConfig = `
+[FILTER]
+    Name parser
+    Match %s
+    Key_Name log
+    Parser json-body
+    Reserve_Data On
+`
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// labelThrottleKeys are the Lua expressions of the kubernetes metadata a
+// throttle may be keyed by instead of a pod label.
+var labelThrottleKeys = map[string]string{
+	"pod_name":       `k8s["pod_name"]`,
+	"container_name": `k8s["container_name"]`,
+}
+
+// labelThrottleLua drops the records of a label value once it has sent the
+// throttle's rate in the current second. fluent-bit's throttle filter has
+// one budget for every record it matches, so the budgets are counted in
+// Lua instead. The counts of every value start over each second, so values
+// that stop logging are forgotten.
+//
+// Like the sequence counters, the counts live in the Lua state of each
+// fluent-bit pod, so the rate applies to each node on its own.
+func labelThrottleLua(name string, spec *v1alpha1.PerLabelThrottleSpec) luaStep {
+	expr, ok := labelThrottleKeys[spec.Label]
+	if !ok {
+		expr = fmt.Sprintf(`type(k8s["labels"]) == "table" and k8s["labels"][%q]`, spec.Label)
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {start = 0, counts = {}}\n", name),
+		body: fmt.Sprintf(`
+    if timestamp - %[1]s.start >= 1 then
+        %[1]s.start = timestamp
+        %[1]s.counts = {}
+    end
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local value = %[2]s
+        if type(value) == "string" then
+            local count = (%[1]s.counts[value] or 0) + 1
+            %[1]s.counts[value] = count
+            if count > %[3]d then
+                return -1, timestamp, record
+            end
+        end
+    end
+`, name, expr, spec.Rate),
+	}
+}
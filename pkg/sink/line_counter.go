@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"expvar"
+	"log"
+	"sync"
+	"time"
+)
+
+// LogLinesTotal counts the records processed by the outputs of sinks with
+// CountLines set. LogSinks are keyed by namespace/name and ClusterLogSinks
+// by name.
+var LogLinesTotal = expvar.NewMap("sink_log_lines_total")
+
+type lineCount struct {
+	key     string
+	records uint64
+}
+
+// LineCounter re-exports the processed records that fluent-bit reports for
+// the outputs of counted sinks. fluent-bit names its outputs by index, so
+// an output's last count is only compared while it delivers for the same
+// sink.
+type LineCounter struct {
+	mu       sync.Mutex
+	sc       *Config
+	last     map[string]lineCount
+	exported map[string]bool
+}
+
+func NewLineCounter(sc *Config) *LineCounter {
+	return &LineCounter{
+		sc:       sc,
+		last:     make(map[string]lineCount),
+		exported: make(map[string]bool),
+	}
+}
+
+// Run observes the fetched metrics every interval until stopCh is closed.
+// Metrics are only fetched while a sink is counted.
+func (c *LineCounter) Run(f MetricsFetcher, interval time.Duration, stopCh <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			if len(c.sc.countedSinks()) == 0 {
+				c.Observe(nil)
+				continue
+			}
+			m, err := f.Fetch()
+			if err != nil {
+				log.Printf("Unable to fetch fluent-bit metrics: %s", err)
+				continue
+			}
+			c.Observe(m)
+		}
+	}
+}
+
+// Observe adds the records each counted sink's output processed since the
+// last observation to LogLinesTotal. The counters of sinks that are deleted
+// or no longer counted are removed.
+func (c *LineCounter) Observe(metrics map[string]OutputMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counted := c.sc.countedSinks()
+	for name := range c.exported {
+		if !counted[name] {
+			LogLinesTotal.Delete(name)
+			delete(c.exported, name)
+		}
+	}
+	for name := range counted {
+		if !c.exported[name] {
+			LogLinesTotal.Add(name, 0)
+			c.exported[name] = true
+		}
+	}
+
+	instances := c.sc.instances()
+	for instance := range c.last {
+		if _, ok := instances[instance]; !ok {
+			delete(c.last, instance)
+		}
+	}
+
+	for instance, k := range instances {
+		name, ok := c.sc.counterName(circuitSink(k))
+		if !ok {
+			delete(c.last, instance)
+			continue
+		}
+		m, ok := metrics[instance]
+		if !ok {
+			continue
+		}
+
+		prev, primed := c.last[instance]
+		c.last[instance] = lineCount{key: k, records: m.ProcRecords}
+		if !primed || prev.key != k {
+			continue
+		}
+		delta := m.since(OutputMetrics{ProcRecords: prev.records})
+		LogLinesTotal.Add(name, int64(delta.ProcRecords))
+	}
+}
+
+// countedSinks returns the counter names of the sinks with CountLines set.
+func (sc *Config) countedSinks() map[string]bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	counted := make(map[string]bool)
+	for _, s := range sc.sinks {
+		if s.Spec.CountLines {
+			counted[s.Namespace+"/"+s.Name] = true
+		}
+	}
+	for _, s := range sc.clusterSinks {
+		if s.Spec.CountLines {
+			counted[s.Name] = true
+		}
+	}
+	return counted
+}
+
+// counterName returns the counter name of the sink with key k if the sink
+// is counted.
+func (sc *Config) counterName(k string) (string, bool) {
+	s, cs := sc.lookup(k)
+	switch {
+	case s != nil && s.Spec.CountLines:
+		return s.Namespace + "/" + s.Name, true
+	case cs != nil && cs.Spec.CountLines:
+		return cs.Name, true
+	}
+	return "", false
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"expvar"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestLineCounter(t *testing.T) {
+	t.Run("it counts the records of counted sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(countedSink("ns1", "counted", true))
+		sc.UpsertSink(countedSink("ns2", "uncounted", false))
+		c := sink.NewLineCounter(sc)
+
+		c.Observe(procRecords(map[string]uint64{"ns1/counted": 100, "ns2/uncounted": 200}))
+		expectLines(t, "ns1/counted", 0)
+
+		c.Observe(procRecords(map[string]uint64{"ns1/counted": 130, "ns2/uncounted": 250}))
+		expectLines(t, "ns1/counted", 30)
+		if v := sink.LogLinesTotal.Get("ns2/uncounted"); v != nil {
+			t.Errorf("Expected uncounted sink to not be exported, got %s", v)
+		}
+	})
+
+	t.Run("it counts cluster sinks by name", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+			Spec: v1alpha1.SinkSpec{
+				Type:       "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{Host: "example.com", Port: 514},
+				CountLines: true,
+			},
+		})
+		c := sink.NewLineCounter(sc)
+
+		c.Observe(procRecords(map[string]uint64{"cluster:cluster": 5}))
+		c.Observe(procRecords(map[string]uint64{"cluster:cluster": 12}))
+		expectLines(t, "cluster", 7)
+	})
+
+	t.Run("it counts from zero when fluent-bit restarts", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(countedSink("ns1", "restarted", true))
+		c := sink.NewLineCounter(sc)
+
+		c.Observe(procRecords(map[string]uint64{"ns1/restarted": 100}))
+		c.Observe(procRecords(map[string]uint64{"ns1/restarted": 110}))
+		c.Observe(procRecords(map[string]uint64{"ns1/restarted": 4}))
+		expectLines(t, "ns1/restarted", 14)
+	})
+
+	t.Run("it keeps counting an output when a sink is rendered before it", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(countedSink("ns2", "second", true))
+		c := sink.NewLineCounter(sc)
+
+		c.Observe(procRecords(map[string]uint64{"ns2/second": 100}))
+		c.Observe(procRecords(map[string]uint64{"ns2/second": 110}))
+		expectLines(t, "ns2/second", 10)
+
+		sc.UpsertSink(countedSink("ns1", "first", true))
+		c.Observe(procRecords(map[string]uint64{"ns1/first": 3, "ns2/second": 120}))
+		expectLines(t, "ns1/first", 0)
+		expectLines(t, "ns2/second", 20)
+
+		c.Observe(procRecords(map[string]uint64{"ns1/first": 8, "ns2/second": 125}))
+		expectLines(t, "ns1/first", 5)
+		expectLines(t, "ns2/second", 25)
+	})
+
+	t.Run("it removes the counters of sinks that are no longer counted", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(countedSink("ns1", "removed", true))
+		c := sink.NewLineCounter(sc)
+
+		c.Observe(procRecords(map[string]uint64{"ns1/removed": 1}))
+		if v := sink.LogLinesTotal.Get("ns1/removed"); v == nil {
+			t.Fatal("Expected counter to be exported")
+		}
+
+		sc.UpsertSink(countedSink("ns1", "removed", false))
+		c.Observe(procRecords(map[string]uint64{"ns1/removed": 2}))
+		if v := sink.LogLinesTotal.Get("ns1/removed"); v != nil {
+			t.Errorf("Expected counter to be removed, got %s", v)
+		}
+	})
+}
+
+func countedSink(namespace, name string, count bool) *v1alpha1.LogSink {
+	return &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: name + ".example.com",
+				Port: 514,
+			},
+			CountLines: count,
+		},
+	}
+}
+
+// procRecords returns metrics where each output, by alias, processed the
+// given records.
+func procRecords(records map[string]uint64) map[string]sink.OutputMetrics {
+	m := make(map[string]sink.OutputMetrics)
+	for alias, r := range records {
+		m[alias] = sink.OutputMetrics{ProcRecords: r}
+	}
+	return m
+}
+
+func expectLines(t *testing.T, name string, expected int64) {
+	t.Helper()
+	v, ok := sink.LogLinesTotal.Get(name).(*expvar.Int)
+	if !ok {
+		t.Fatalf("Expected counter %s to be exported", name)
+	}
+	if v.Value() != expected {
+		t.Errorf("Expected %s to count %d lines, got %d", name, expected, v.Value())
+	}
+}
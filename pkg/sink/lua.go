@@ -0,0 +1,251 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// luaScriptName is the key of the fluent-bit configmap holding the Lua
+// functions of every sink.
+const luaScriptName = "sinks.lua"
+
+const luaFilterConfig = `
+[FILTER]
+    Name lua
+    Match %s
+    script /fluent-bit/etc/` + luaScriptName + `
+    call %s
+`
+
+// luaPrelude defines the helpers shared by the sinks' functions. A record's
+// severity is read from the level or severity field of the merged log.
+// Times are formatted as RFC3339 with milliseconds at the offset that
+// applies to them in a list of {start, offset} pairs.
+const luaPrelude = `math.randomseed(os.time())
+
+local function severity(record)
+    local s = record["level"] or record["severity"]
+    if type(s) ~= "string" then
+        return nil
+    end
+    return string.lower(s)
+end
+
+local function zone_offset(t, offsets)
+    local offset = offsets[1][2]
+    for _, o in ipairs(offsets) do
+        if t < o[1] then
+            break
+        end
+        offset = o[2]
+    end
+    return offset
+end
+
+local function format_time(t, offset)
+    local sign = "+"
+    if offset < 0 then
+        sign = "-"
+    end
+    local abs = math.abs(offset)
+    return os.date("!%Y-%m-%dT%H:%M:%S", math.floor(t + offset)) ..
+        string.format(".%03d%s%02d:%02d",
+            math.floor((t % 1) * 1000),
+            sign,
+            math.floor(abs / 3600),
+            math.floor(abs % 3600 / 60))
+end
+` + stripKeysPrelude + imageMetadataPrelude + utf8Prelude + fieldPathPrelude + redactPrelude
+
+// luaFuncTemplate wraps the steps of a sink's function. Steps drop a record
+// by returning -1 and set code to 1 when they modify it.
+const luaFuncTemplate = `
+function %s(tag, timestamp, record)
+    local code = 0
+%s
+    return code, timestamp, record
+end
+`
+
+// luaScript renders the prelude and a function for every sink with options
+// that are implemented in Lua. It is empty when no sink has any.
+func (sc *Config) luaScript() string {
+	var funcs []string
+	for i, ref := range sc.filteredSinkRefs() {
+		if f := sinkLua(luaFuncName(i), ref.spec); f != "" {
+			funcs = append(funcs, f)
+		}
+	}
+
+	if audit := sc.auditLua(); audit != "" {
+		funcs = append(funcs, audit)
+	}
+
+	if len(funcs) == 0 {
+		return ""
+	}
+
+	return luaPrelude + strings.Join(funcs, "")
+}
+
+func luaFuncName(i int) string {
+	return fmt.Sprintf("sink_%d", i)
+}
+
+// luaStep is part of a sink's function. Its decl is rendered before the
+// function so that values that do not change between records are only built
+// once.
+type luaStep struct {
+	decl string
+	body string
+}
+
+func hasLua(spec v1alpha1.SinkSpec) bool {
+	return len(luaSteps("", spec)) > 0
+}
+
+func sinkLua(name string, spec v1alpha1.SinkSpec) string {
+	steps := luaSteps(name, spec)
+	if len(steps) == 0 {
+		return ""
+	}
+
+	var decls, bodies string
+	for _, s := range steps {
+		decls += s.decl
+		bodies += s.body
+	}
+
+	return decls + fmt.Sprintf(luaFuncTemplate, name, bodies)
+}
+
+// luaSteps returns the steps of the sink's function. Decls are named after
+// the function.
+func luaSteps(name string, spec v1alpha1.SinkSpec) []luaStep {
+	var steps []luaStep
+
+	if spec.StartupDelay != nil && spec.StartupRate > 0 {
+		steps = append(steps, startupRampLua(name+"_startup", spec))
+	}
+
+	if spec.RequireKubernetesMetadata == "require" {
+		steps = append(steps, luaStep{body: requireKubernetesLua})
+	}
+
+	if spec.PerLabelThrottle != nil && spec.PerLabelThrottle.Rate > 0 {
+		steps = append(steps, labelThrottleLua(name+"_throttle", spec.PerLabelThrottle))
+	}
+
+	if len(spec.SeveritySampling) > 0 {
+		steps = append(steps, luaStep{
+			body: severitySamplingLua(spec.SeveritySampling),
+		})
+	}
+
+	if len(spec.SeverityMapping) > 0 {
+		steps = append(steps, severityMappingLua(name+"_severities", spec.SeverityMapping, spec.SeverityField))
+	}
+
+	if spec.Timestamp != nil && spec.Timestamp.Timezone != "" {
+		if body := timestampLua(spec.Timestamp.Timezone, time.Now()); body != "" {
+			steps = append(steps, luaStep{body: body})
+		}
+	}
+
+	if spec.Timestamp != nil && spec.Timestamp.EpochMillisField != "" {
+		steps = append(steps, luaStep{body: epochMillisLua(spec.Timestamp.EpochMillisField)})
+	}
+
+	if spec.Metadata != nil && spec.Metadata.StripKeyRegex != "" {
+		if step, ok := stripKeysLua(name+"_strip_keys", spec.Metadata.StripKeyRegex); ok {
+			steps = append(steps, step)
+		}
+	}
+
+	if spec.IncludeImageMetadata {
+		steps = append(steps, luaStep{body: imageMetadataBody})
+	}
+
+	if spec.SanitizeUTF8 {
+		steps = append(steps, luaStep{body: sanitizeUTF8Body})
+	}
+
+	if body := hostnameLua(spec.HostnameSource); body != "" {
+		steps = append(steps, luaStep{body: body})
+	}
+
+	if len(spec.Redact) > 0 {
+		steps = append(steps, redactLua(name+"_redact", spec.Redact))
+	}
+
+	if len(spec.MaxFieldBytes) > 0 {
+		steps = append(steps, truncateFieldsLua(name+"_max_bytes", spec.MaxFieldBytes))
+	}
+
+	if len(spec.RequireFields) > 0 {
+		steps = append(steps, requireFieldsLua(name+"_required", spec.RequireFields))
+	}
+
+	if spec.IncludeSequence {
+		steps = append(steps, sequenceLua(name+"_sequences"))
+	}
+
+	if spec.Coalesce != nil && spec.Coalesce.Window.Duration > 0 {
+		steps = append(steps, coalesceLua(name+"_coalesced", spec.Coalesce.Window.Seconds()))
+	}
+
+	return steps
+}
+
+// requireKubernetesLua drops records the kubernetes filter did not enrich.
+const requireKubernetesLua = `
+    if type(record["kubernetes"]) ~= "table" then
+        return -1, timestamp, record
+    end
+`
+
+// severitySamplingLua drops records of a sampled severity unless a random
+// number falls below its keep rate. Records of other severities are kept.
+func severitySamplingLua(sampling map[string]float64) string {
+	severities := make([]string, 0, len(sampling))
+	for s := range sampling {
+		severities = append(severities, s)
+	}
+	sort.Strings(severities)
+
+	rates := make([]string, 0, len(severities))
+	for _, s := range severities {
+		rates = append(rates, fmt.Sprintf(
+			"[%q] = %s",
+			s,
+			strconv.FormatFloat(sampling[s], 'g', -1, 64),
+		))
+	}
+
+	return fmt.Sprintf(`
+    local rate = ({%s})[severity(record)]
+    if rate ~= nil and math.random() >= rate then
+        return -1, timestamp, record
+    end
+`, strings.Join(rates, ", "))
+}
@@ -0,0 +1,1438 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestSeveritySampling(t *testing.T) {
+	t.Run("it renders a lua filter with the keep rate of each severity", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "unsampled-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sampled-sink",
+				Namespace: "ns2",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				SeveritySampling: map[string]float64{
+					"info":  0.1,
+					"error": 1,
+				},
+			},
+		})
+
+		expectedConfig := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns2_*
+    Rule $log .* filtered.ns.ns2.sampled-sink true
+    Emitter_Name ns2/sampled-sink:filtered
+
+[FILTER]
+    Name lua
+    Match filtered.ns.ns2.sampled-sink
+    script /fluent-bit/etc/sinks.lua
+    call sink_1
+
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/unsampled-sink
+    InstanceName ns1/unsampled-sink
+    Addr example.com:12345
+    Namespace ns1
+
+[OUTPUT]
+    Name syslog
+    Match filtered.ns.ns2.sampled-sink
+    Alias ns2/sampled-sink
+    InstanceName ns2/sampled-sink
+    Addr example.com:12345
+    Namespace ns2
+`
+		if diff := cmp.Diff(expectedConfig, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+
+		expectedFunc := `
+function sink_1(tag, timestamp, record)
+    local code = 0
+
+    local rate = ({["error"] = 1, ["info"] = 0.1})[severity(record)]
+    if rate ~= nil and math.random() >= rate then
+        return -1, timestamp, record
+    end
+
+    return code, timestamp, record
+end
+`
+		script := sc.Script()
+		if !strings.HasPrefix(script, "math.randomseed(os.time())\n") {
+			t.Errorf("Expected script to start with the prelude, got %s", script)
+		}
+		if !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if strings.Contains(script, "sink_0") {
+			t.Errorf("Expected no function for the unsampled sink, got %s", script)
+		}
+	})
+
+	t.Run("it renders an empty script when no sink is sampled", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it patches the script with the config", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		sc := sink.NewConfig()
+		c := sink.NewController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				SeveritySampling: map[string]float64{"info": 0.5},
+			},
+		})
+
+		jp := lastPatch(t, spyPatcher)
+		expected := jsonPatch{
+			Op:    "add",
+			Path:  "/data/sinks.lua",
+			Value: sc.Script(),
+		}
+		if diff := cmp.Diff(expected, findPatch(jp, "/data/sinks.lua")); diff != "" {
+			t.Errorf("Patch not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestTimestampTimezone(t *testing.T) {
+	timestampSink := func(timezone string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				Timestamp: &v1alpha1.TimestampSpec{Timezone: timezone},
+			},
+		}
+	}
+	start := time.Date(time.Now().Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	t.Run("it formats the timestamp at the timezone's offset", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(timestampSink("Asia/Kolkata"))
+
+		expectedFunc := fmt.Sprintf(`
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local offset = zone_offset(timestamp, {{%d, 19800}})
+    record["timestamp"] = format_time(timestamp, offset)
+    code = 1
+
+    return code, timestamp, record
+end
+`, start)
+		if script := sc.Script(); !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter, got %s", config)
+		}
+	})
+
+	t.Run("it renders daylight saving time transitions", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(timestampSink("America/New_York"))
+
+		loc, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The second Sunday of March this year at 2am local time.
+		year := time.Now().Year()
+		dst := time.Date(year, time.March, 8, 7, 0, 0, 0, time.UTC)
+		for dst.In(loc).Weekday() != time.Sunday {
+			dst = dst.AddDate(0, 0, 1)
+		}
+
+		script := sc.Script()
+		expected := []string{
+			fmt.Sprintf("{%d, -18000}", start),
+			fmt.Sprintf("{%d, -14400}", dst.Unix()),
+		}
+		for _, e := range expected {
+			if !strings.Contains(script, e) {
+				t.Errorf("Expected script to contain %s, got %s", e, script)
+			}
+		}
+	})
+
+	t.Run("it does not render an unknown timezone", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(timestampSink("Not/A_Zone"))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+}
+
+func TestTimestampEpochMillis(t *testing.T) {
+	millisSink := func(ts *v1alpha1.TimestampSpec) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				Timestamp: ts,
+			},
+		}
+	}
+
+	t.Run("it sets the field to the record's time in epoch milliseconds", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(millisSink(&v1alpha1.TimestampSpec{EpochMillisField: "time_ms"}))
+
+		const expected = `
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    record["time_ms"] = math.floor(timestamp * 1000 + 0.5)
+    code = 1
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter, got %s", config)
+		}
+	})
+
+	t.Run("it also formats the timestamp in the timezone", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(millisSink(&v1alpha1.TimestampSpec{
+			Timezone:         "UTC",
+			EpochMillisField: "time_ms",
+		}))
+
+		expected := fmt.Sprintf(`
+    local offset = zone_offset(timestamp, {{%d, 0}})
+    record["timestamp"] = format_time(timestamp, offset)
+    code = 1
+
+    record["time_ms"] = math.floor(timestamp * 1000 + 0.5)
+    code = 1
+`, time.Date(time.Now().Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC).Unix())
+		if script := sc.Script(); !strings.Contains(script, expected) {
+			t.Errorf("Expected script to contain %s, got %s", expected, script)
+		}
+	})
+}
+
+func TestStripKeyRegex(t *testing.T) {
+	stripSink := func(expr string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				Metadata: &v1alpha1.MetadataSpec{StripKeyRegex: expr},
+			},
+		}
+	}
+
+	t.Run("it strips labels and annotations with the compiled regex", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(stripSink("^secret"))
+
+		expected := `
+local sink_0_strip_keys = {start = 1, [0] = {"fail"}, [1] = {"empty", 2, begin_text = true}, [2] = {"byte", 3, {115, 115}}, [3] = {"byte", 4, {101, 101}}, [4] = {"byte", 5, {99, 99}}, [5] = {"byte", 6, {114, 114}}, [6] = {"byte", 7, {101, 101}}, [7] = {"byte", 8, {116, 116}}, [8] = {"match"}}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    if strip_keys(record, sink_0_strip_keys) then
+        code = 1
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter, got %s", config)
+		}
+	})
+
+	t.Run("it renders the ASCII bytes a class matches", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(stripSink("(?i)[a-c_]"))
+
+		expected := `[1] = {"byte", 2, {65, 67, 95, 95, 97, 99}}`
+		if script := sc.Script(); !strings.Contains(script, expected) {
+			t.Errorf("Expected script to contain %s, got %s", expected, script)
+		}
+	})
+
+	t.Run("it does not render an invalid regex", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(stripSink("secret("))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it strips the matching labels and annotations when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(stripSink("^secret"))
+
+		results := runLua(t, sc.Script(), "sink_0", luaRecord{
+			tag: "kube.var.log.containers.pod_ns1_app-1.log",
+			record: map[string]interface{}{
+				"log": "hello",
+				"kubernetes": map[string]interface{}{
+					"labels":      map[string]interface{}{"secret-token": "x", "app": "web", "not-secret": "y"},
+					"annotations": map[string]interface{}{"secret": "z"},
+				},
+			},
+		})
+
+		expected := map[string]interface{}{
+			"labels":      map[string]interface{}{"app": "web", "not-secret": "y"},
+			"annotations": map[string]interface{}{},
+		}
+		if diff := cmp.Diff(expected, results[0].Record["kubernetes"]); diff != "" {
+			t.Errorf("Metadata not equal (-want, +got) = %v", diff)
+		}
+		if results[0].Code != 1 {
+			t.Errorf("Expected code 1, got %d", results[0].Code)
+		}
+	})
+}
+
+func TestImageMetadata(t *testing.T) {
+	imageSink := func(name string, include bool) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				IncludeImageMetadata: include,
+			},
+		}
+	}
+
+	t.Run("it adds the image and digest for opted in sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(imageSink("image-sink", true))
+		sc.UpsertSink(imageSink("other-sink", false))
+
+		expectedFunc := `
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local image, digest = image_metadata(k8s)
+        if image ~= nil then
+            k8s["container_image"] = image
+            code = 1
+        end
+        if digest ~= nil then
+            k8s["container_image_digest"] = digest
+            code = 1
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+		script := sc.Script()
+		if !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if !strings.Contains(script, "local function image_metadata(k8s)") {
+			t.Errorf("Expected script to define image_metadata, got %s", script)
+		}
+		if strings.Contains(script, "function sink_1(") {
+			t.Errorf("Expected no function for the sink without image metadata, got %s", script)
+		}
+
+		config := sc.String()
+		if !strings.Contains(config, "call sink_0") || strings.Contains(config, "call sink_1") {
+			t.Errorf("Expected a lua filter for only the opted in sink, got %s", config)
+		}
+	})
+
+	t.Run("it does not render a script when no sink opts in", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(imageSink("other-sink", false))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+}
+
+func TestRequireKubernetesMetadata(t *testing.T) {
+	metadataSink := func(policy string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "metadata-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				RequireKubernetesMetadata: policy,
+			},
+		}
+	}
+
+	t.Run("it drops records without kubernetes metadata when required", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(metadataSink("require"))
+
+		expectedFunc := `
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    if type(record["kubernetes"]) ~= "table" then
+        return -1, timestamp, record
+    end
+
+    return code, timestamp, record
+end
+`
+		script := sc.Script()
+		if !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "Match filtered.ns.ns1.metadata-sink\n    script /fluent-bit/etc/sinks.lua\n    call sink_0\n") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it passes records through when allowed", func(t *testing.T) {
+		for _, policy := range []string{"allow", ""} {
+			sc := sink.NewConfig()
+			sc.UpsertSink(metadataSink(policy))
+
+			if script := sc.Script(); script != "" {
+				t.Errorf("Expected empty script for %q, got %s", policy, script)
+			}
+			if config := sc.String(); strings.Contains(config, "Name lua") {
+				t.Errorf("Expected no lua filter for %q, got %s", policy, config)
+			}
+		}
+	})
+}
+
+func TestIncludeSequence(t *testing.T) {
+	sequenceSink := func(name string, include bool) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				IncludeSequence: include,
+			},
+		}
+	}
+
+	t.Run("it numbers the records of opted in sinks by tag", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(sequenceSink("numbered-sink", true))
+		sc.UpsertSink(sequenceSink("other-sink", false))
+
+		expectedFunc := `
+local sink_0_sequences = {}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local sequence = (sink_0_sequences[tag] or 0) + 1
+    sink_0_sequences[tag] = sequence
+    record["sequence"] = sequence
+    code = 1
+
+    return code, timestamp, record
+end
+`
+		script := sc.Script()
+		if !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if strings.Contains(script, "function sink_1(") {
+			t.Errorf("Expected no function for the sink without a sequence, got %s", script)
+		}
+
+		config := sc.String()
+		if !strings.Contains(config, "call sink_0") || strings.Contains(config, "call sink_1") {
+			t.Errorf("Expected a lua filter for only the opted in sink, got %s", config)
+		}
+	})
+
+	t.Run("it does not render a script when no sink opts in", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(sequenceSink("other-sink", false))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it numbers the records of each tag from 1 when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(sequenceSink("numbered-sink", true))
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", record: map[string]interface{}{"log": "first"}},
+			luaRecord{tag: "a", record: map[string]interface{}{"log": "second"}},
+			luaRecord{tag: "b", record: map[string]interface{}{"log": "other"}},
+		)
+
+		var sequences []interface{}
+		for _, r := range results {
+			sequences = append(sequences, r.Record["sequence"])
+		}
+		if diff := cmp.Diff([]interface{}{float64(1), float64(2), float64(1)}, sequences); diff != "" {
+			t.Errorf("Sequences not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestCoalesce(t *testing.T) {
+	coalescedSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "coalesced-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+			Coalesce: &v1alpha1.CoalesceSpec{
+				Window: metav1.Duration{Duration: 90 * time.Second},
+			},
+		},
+	}
+
+	t.Run("it collapses repeats of the last log of a tag within the window", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(coalescedSink)
+
+		expectedFunc := `
+local sink_0_coalesced = {}
+local sink_0_coalesced_swept = 0
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local log = record["log"]
+    if timestamp - sink_0_coalesced_swept >= 90 then
+        for t, l in pairs(sink_0_coalesced) do
+            if timestamp - l.seen >= 90 then
+                sink_0_coalesced[t] = nil
+            end
+        end
+        sink_0_coalesced_swept = timestamp
+    end
+    local last = sink_0_coalesced[tag]
+    if last ~= nil and type(log) == "string" and last.log == log and timestamp - last.start < 90 then
+        last.count = last.count + 1
+        last.seen = timestamp
+        return -1, timestamp, record
+    end
+    sink_0_coalesced[tag] = {log = log, start = timestamp, seen = timestamp, count = 0}
+    if last ~= nil and last.count > 0 then
+        record["repeated"] = last.count
+        code = 1
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+	})
+
+	t.Run("it coalesces after the sink's other steps", func(t *testing.T) {
+		s := coalescedSink.DeepCopy()
+		s.Spec.IncludeSequence = true
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		sequence := strings.Index(script, `record["sequence"] = sequence`)
+		coalesce := strings.Index(script, `record["repeated"] = last.count`)
+		if sequence < 0 || coalesce < sequence {
+			t.Errorf("Expected the records to be coalesced after they are numbered, got %s", script)
+		}
+	})
+
+	t.Run("it counts the dropped repeats on the next record when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(coalescedSink)
+
+		tag := "kube.var.log.containers.pod_ns1_app-1.log"
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: tag, timestamp: 0, record: map[string]interface{}{"log": "retrying"}},
+			luaRecord{tag: tag, timestamp: 1, record: map[string]interface{}{"log": "retrying"}},
+			luaRecord{tag: tag, timestamp: 2, record: map[string]interface{}{"log": "retrying"}},
+			luaRecord{tag: tag, timestamp: 3, record: map[string]interface{}{"log": "connected"}},
+			luaRecord{tag: tag, timestamp: 100, record: map[string]interface{}{"log": "connected"}},
+		)
+
+		codes := []int{results[0].Code, results[1].Code, results[2].Code, results[3].Code, results[4].Code}
+		if diff := cmp.Diff([]int{0, -1, -1, 1, 0}, codes); diff != "" {
+			t.Errorf("Codes not equal (-want, +got) = %v", diff)
+		}
+		if repeated := results[3].Record["repeated"]; repeated != float64(2) {
+			t.Errorf("Expected the next record to count 2 repeats, got %v", repeated)
+		}
+		if _, ok := results[4].Record["repeated"]; ok {
+			t.Errorf("Expected a repeat after the window to be kept, got %v", results[4].Record)
+		}
+	})
+
+	t.Run("it evicts the state of tags that stopped logging when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(coalescedSink)
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", timestamp: 1, record: map[string]interface{}{"log": "retrying"}},
+			luaRecord{tag: "a", timestamp: 2, record: map[string]interface{}{"log": "retrying"}},
+			luaRecord{tag: "b", timestamp: 100, record: map[string]interface{}{"log": "started"}},
+			luaRecord{tag: "a", timestamp: 101, record: map[string]interface{}{"log": "connected"}},
+		)
+
+		if _, ok := results[3].Record["repeated"]; ok || results[3].Code != 0 {
+			t.Errorf("Expected the state of the tag to be evicted, got %v", results[3].Record)
+		}
+	})
+}
+
+func TestHostnameSource(t *testing.T) {
+	hostnameSink := func(source string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "hostname-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host:           "example.com",
+					Port:           12345,
+					HostnameSource: source,
+				},
+			},
+		}
+	}
+
+	for source, expr := range map[string]string{
+		"node":      `os.getenv("NODE_NAME")`,
+		"pod":       `k8s["pod_name"]`,
+		"container": `k8s["container_name"]`,
+	} {
+		t.Run("it sets the host from the "+source, func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertSink(hostnameSink(source))
+
+			expectedFunc := `
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local host = ` + expr + `
+        if type(host) == "string" and host ~= "" then
+            k8s["host"] = host
+            code = 1
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+			if script := sc.Script(); !strings.HasSuffix(script, expectedFunc) {
+				t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+			}
+			if config := sc.String(); !strings.Contains(config, "call sink_0") {
+				t.Errorf("Expected a lua filter for the sink, got %s", config)
+			}
+		})
+	}
+
+	t.Run("it leaves the host when unset", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(hostnameSink(""))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+}
+
+func TestSanitizeUTF8(t *testing.T) {
+	utf8Sink := func(sanitize bool) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "utf8-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				SanitizeUTF8: sanitize,
+			},
+		}
+	}
+
+	t.Run("it replaces the invalid UTF-8 of the log", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(utf8Sink(true))
+
+		expectedFunc := `
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local log = record["log"]
+    if type(log) == "string" then
+        local sanitized, replaced = sanitize_utf8(log)
+        if replaced then
+            record["log"] = sanitized
+            code = 1
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+		script := sc.Script()
+		if !strings.HasSuffix(script, expectedFunc) {
+			t.Errorf("Expected script to end with %s, got %s", expectedFunc, script)
+		}
+		if !strings.Contains(script, `s:sub(start, i - 1) .. "\239\191\189"`) {
+			t.Errorf("Expected invalid bytes to be replaced with U+FFFD, got %s", script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it does not render a script when no sink opts in", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(utf8Sink(false))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it replaces each invalid byte with U+FFFD when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(utf8Sink(true))
+
+		logs := map[string]string{
+			"café":                      "café",
+			"bad \xff byte":             "bad \ufffd byte",
+			"overlong \xc0\xaf":         "overlong \ufffd\ufffd",
+			"surrogate \xed\xa0\x80":    "surrogate \ufffd\ufffd\ufffd",
+			"cut \xe2\x82":              "cut \ufffd\ufffd",
+			"past max \xf4\x90\x80\x80": "past max \ufffd\ufffd\ufffd\ufffd",
+		}
+		for in, expected := range logs {
+			results := runLua(t, sc.Script(), "sink_0", luaRecord{
+				tag:    "kube.var.log.containers.pod_ns1_app-1.log",
+				record: map[string]interface{}{"log": in},
+			})
+			if got := results[0].Record["log"]; got != expected {
+				t.Errorf("Expected %q to be sanitized to %q, got %q", in, expected, got)
+			}
+			if code := results[0].Code; (in == expected) != (code == 0) {
+				t.Errorf("Expected code 1 only for a replaced log %q, got %d", in, code)
+			}
+		}
+	})
+}
+
+func TestPerLabelThrottle(t *testing.T) {
+	throttledSink := func(label string, rate int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "throttled-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				PerLabelThrottle: &v1alpha1.PerLabelThrottleSpec{
+					Label: label,
+					Rate:  rate,
+				},
+			},
+		}
+	}
+
+	for label, expr := range map[string]string{
+		"app":                    `type(k8s["labels"]) == "table" and k8s["labels"]["app"]`,
+		"app.kubernetes.io/name": `type(k8s["labels"]) == "table" and k8s["labels"]["app.kubernetes.io/name"]`,
+		"pod_name":               `k8s["pod_name"]`,
+		"container_name":         `k8s["container_name"]`,
+	} {
+		t.Run("it counts the records of each value of "+label, func(t *testing.T) {
+			sc := sink.NewConfig()
+			sc.UpsertSink(throttledSink(label, 50))
+
+			expected := `
+local sink_0_throttle = {start = 0, counts = {}}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    if timestamp - sink_0_throttle.start >= 1 then
+        sink_0_throttle.start = timestamp
+        sink_0_throttle.counts = {}
+    end
+    local k8s = record["kubernetes"]
+    if type(k8s) == "table" then
+        local value = ` + expr + `
+        if type(value) == "string" then
+            local count = (sink_0_throttle.counts[value] or 0) + 1
+            sink_0_throttle.counts[value] = count
+            if count > 50 then
+                return -1, timestamp, record
+            end
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+			if script := sc.Script(); !strings.HasSuffix(script, expected) {
+				t.Errorf("Expected script to end with %s, got %s", expected, script)
+			}
+			if config := sc.String(); !strings.Contains(config, "call sink_0") {
+				t.Errorf("Expected a lua filter for the sink, got %s", config)
+			}
+		})
+	}
+
+	t.Run("it throttles before the sink's other steps", func(t *testing.T) {
+		s := throttledSink("app", 50)
+		s.Spec.IncludeSequence = true
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		if strings.Index(script, "sink_0_throttle.counts[value] = count") > strings.Index(script, `record["sequence"] = sequence`) {
+			t.Errorf("Expected records to be throttled before they are numbered, got %s", script)
+		}
+	})
+
+	t.Run("it does not render a script without a rate", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(throttledSink("app", 0))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+}
+
+func TestRequireFields(t *testing.T) {
+	requiringSink := func(fields ...string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "schema-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				RequireFields: fields,
+			},
+		}
+	}
+
+	t.Run("it drops records missing a field and passes complete ones", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(requiringSink("log", "kubernetes.namespace_name"))
+
+		expected := `
+local sink_0_required = {{"log"}, {"kubernetes", "namespace_name"}}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    for _, path in ipairs(sink_0_required) do
+        local parent = field_parent(record, path)
+        if parent == nil or parent[path[#path]] == nil then
+            return -1, timestamp, record
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it requires the fields before numbering the records", func(t *testing.T) {
+		s := requiringSink("log")
+		s.Spec.IncludeSequence = true
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		required := strings.Index(script, "ipairs(sink_0_required)")
+		sequenced := strings.Index(script, "sink_0_sequences[tag] = sequence")
+		if required < 0 || sequenced < 0 || required > sequenced {
+			t.Errorf("Expected the fields to be required before the sequence, got %s", script)
+		}
+	})
+
+	t.Run("it does not render a script without required fields", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(requiringSink())
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it drops the records missing a field when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(requiringSink("log", "kubernetes.namespace_name"))
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", record: map[string]interface{}{
+				"log":        "complete",
+				"kubernetes": map[string]interface{}{"namespace_name": "ns1"},
+			}},
+			luaRecord{tag: "a", record: map[string]interface{}{"log": "no metadata"}},
+			luaRecord{tag: "a", record: map[string]interface{}{"log": "flat metadata", "kubernetes": "ns1"}},
+		)
+
+		codes := []int{results[0].Code, results[1].Code, results[2].Code}
+		if diff := cmp.Diff([]int{0, -1, -1}, codes); diff != "" {
+			t.Errorf("Codes not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	redactingSink := func(rules ...v1alpha1.RedactRule) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "private-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				Redact: rules,
+			},
+		}
+	}
+
+	t.Run("it redacts each field with its method", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(redactingSink(
+			v1alpha1.RedactRule{Field: "password", Method: "drop"},
+			v1alpha1.RedactRule{Field: "user.email", Method: "hash"},
+			v1alpha1.RedactRule{Field: "ssn", Method: "mask"},
+		))
+
+		expected := `
+local sink_0_redact = {{{"password"}, "drop"}, {{"user", "email"}, "hash"}, {{"ssn"}, "mask"}}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    for _, rule in ipairs(sink_0_redact) do
+        local path = rule[1]
+        local parent, key = field_parent(record, path), path[#path]
+        if parent ~= nil and parent[key] ~= nil then
+            parent[key] = redact(parent[key], rule[2])
+            code = 1
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it replaces hashed fields with their SHA-256 when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(redactingSink(v1alpha1.RedactRule{Field: "secret", Method: "hash"}))
+
+		// The values cover the padding of one and two blocks, the block
+		// boundaries and bytes outside of ASCII.
+		values := []interface{}{
+			"",
+			"abc",
+			"abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq",
+			strings.Repeat("a", 55),
+			strings.Repeat("a", 56),
+			strings.Repeat("a", 63),
+			strings.Repeat("a", 64),
+			strings.Repeat("a", 65),
+			strings.Repeat("a", 1000),
+			"pässwörd ✓",
+			"\xff\x00\x80",
+			42,
+		}
+		records := make([]luaRecord, 0, len(values))
+		for _, v := range values {
+			records = append(records, luaRecord{tag: "a", record: map[string]interface{}{"secret": v}})
+		}
+		results := runLua(t, sc.Script(), "sink_0", records...)
+
+		known := map[string]string{
+			"":    "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			"abc": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+			"abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq": "248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1",
+		}
+		for i, v := range values {
+			text := fmt.Sprint(v)
+			expected := fmt.Sprintf("%x", sha256.Sum256([]byte(text)))
+			if k, ok := known[text]; ok && k != expected {
+				t.Fatalf("Expected the known hash of %q to be %s, got %s", text, k, expected)
+			}
+			if got := results[i].Record["secret"]; got != expected {
+				t.Errorf("Expected %q to hash to %s, got %v", text, expected, got)
+			}
+			if results[i].Code != 1 {
+				t.Errorf("Expected code 1 for %q, got %d", text, results[i].Code)
+			}
+		}
+	})
+
+	t.Run("it masks and drops fields when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(redactingSink(
+			v1alpha1.RedactRule{Field: "password", Method: "drop"},
+			v1alpha1.RedactRule{Field: "user.card", Method: "mask"},
+			v1alpha1.RedactRule{Field: "pin", Method: "mask"},
+		))
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", record: map[string]interface{}{
+				"log":      "paid",
+				"password": "hunter2",
+				"user":     map[string]interface{}{"card": "1234567890"},
+				"pin":      "abc",
+			}},
+			luaRecord{tag: "a", record: map[string]interface{}{
+				"log":  "paid again",
+				"user": map[string]interface{}{"card": 1234567},
+				"pin":  map[string]interface{}{"digits": "1234"},
+			}},
+			luaRecord{tag: "a", record: map[string]interface{}{"log": "nothing to redact", "user": "flat"}},
+		)
+
+		expected := []luaResult{
+			{Code: 1, Record: map[string]interface{}{
+				"log":  "paid",
+				"user": map[string]interface{}{"card": "******7890"},
+				"pin":  "***",
+			}},
+			{Code: 1, Record: map[string]interface{}{
+				"log":  "paid again",
+				"user": map[string]interface{}{"card": "***4567"},
+			}},
+			{Code: 0, Record: map[string]interface{}{"log": "nothing to redact", "user": "flat"}},
+		}
+		if diff := cmp.Diff(expected, results); diff != "" {
+			t.Errorf("Results not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it redacts fields before they are required", func(t *testing.T) {
+		s := redactingSink(v1alpha1.RedactRule{Field: "ssn", Method: "drop"})
+		s.Spec.RequireFields = []string{"log"}
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		redacted := strings.Index(script, "ipairs(sink_0_redact)")
+		required := strings.Index(script, "ipairs(sink_0_required)")
+		if redacted < 0 || required < 0 || redacted > required {
+			t.Errorf("Expected fields to be redacted before they are required, got %s", script)
+		}
+	})
+}
+
+func TestSeverityMapping(t *testing.T) {
+	mappingSink := func(mapping map[string]int, targetField string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "leveled-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				SeverityMapping: mapping,
+				SeverityField:   targetField,
+			},
+		}
+	}
+
+	t.Run("it sets the numeric level of mapped severities", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(mappingSink(map[string]int{"warn": 4, "error": 3, "debug": 7}, ""))
+
+		expected := `
+local sink_0_severities = {["debug"] = 7, ["error"] = 3, ["warn"] = 4}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    local level = sink_0_severities[severity(record)]
+    if level ~= nil then
+        record["severity_number"] = level
+        code = 1
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it sets the level in the sink's field", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(mappingSink(map[string]int{"warn": 4}, "syslog_severity"))
+
+		script := sc.Script()
+		if !strings.Contains(script, `record["syslog_severity"] = level`) {
+			t.Errorf("Expected the level to be set in syslog_severity, got %s", script)
+		}
+	})
+
+	t.Run("it maps the severities after sampling them", func(t *testing.T) {
+		s := mappingSink(map[string]int{"info": 6}, "")
+		s.Spec.SeveritySampling = map[string]float64{"info": 0.5}
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		sampled := strings.Index(script, "math.random() >= rate")
+		mapped := strings.Index(script, "sink_0_severities[severity(record)]")
+		if sampled < 0 || mapped < 0 || sampled > mapped {
+			t.Errorf("Expected the severities to be sampled before they are mapped, got %s", script)
+		}
+	})
+
+	t.Run("it does not render a script without a mapping", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(mappingSink(nil, ""))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+
+	t.Run("it sets the level of the severity of each record when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(mappingSink(map[string]int{"warn": 4, "error": 3}, ""))
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", record: map[string]interface{}{"level": "WARN"}},
+			luaRecord{tag: "a", record: map[string]interface{}{"severity": "error"}},
+			luaRecord{tag: "a", record: map[string]interface{}{"level": "info"}},
+		)
+
+		var levels []interface{}
+		for _, r := range results {
+			levels = append(levels, r.Record["severity_number"])
+		}
+		if diff := cmp.Diff([]interface{}{float64(4), float64(3), nil}, levels); diff != "" {
+			t.Errorf("Levels not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestMaxFieldBytes(t *testing.T) {
+	limitingSink := func(limits map[string]int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "capped-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				MaxFieldBytes: limits,
+			},
+		}
+	}
+
+	t.Run("it truncates each field to its limit", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(limitingSink(map[string]int{
+			"log":                           4096,
+			"kubernetes.annotations.config": 256,
+		}))
+
+		expected := `
+local sink_0_max_bytes = {{{"kubernetes", "annotations", "config"}, 256}, {{"log"}, 4096}}
+
+function sink_0(tag, timestamp, record)
+    local code = 0
+
+    for _, limit in ipairs(sink_0_max_bytes) do
+        local path, n = limit[1], limit[2]
+        local parent, key = field_parent(record, path), path[#path]
+        if parent ~= nil and type(parent[key]) == "string" and #parent[key] > n then
+            local value = parent[key]
+            while n > 0 and value:byte(n + 1) >= 128 and value:byte(n + 1) < 192 do
+                n = n - 1
+            end
+            parent[key] = value:sub(1, n)
+            code = 1
+        end
+    end
+
+    return code, timestamp, record
+end
+`
+		if script := sc.Script(); !strings.HasSuffix(script, expected) {
+			t.Errorf("Expected script to end with %s, got %s", expected, script)
+		}
+		if config := sc.String(); !strings.Contains(config, "call sink_0") {
+			t.Errorf("Expected a lua filter for the sink, got %s", config)
+		}
+	})
+
+	t.Run("it cuts values before a split character when run", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(limitingSink(map[string]int{
+			"accented": 2,
+			"checks":   4,
+			"kanji":    8,
+			"whole":    3,
+			"short":    16,
+			"number":   1,
+		}))
+
+		results := runLua(t, sc.Script(), "sink_0",
+			luaRecord{tag: "a", record: map[string]interface{}{
+				"accented": "héllo",
+				"checks":   "✓✓",
+				"kanji":    "日本語",
+				"whole":    "hé!",
+				"short":    "fits",
+				"number":   12345,
+			}},
+			luaRecord{tag: "a", record: map[string]interface{}{"short": "fits"}},
+		)
+
+		expected := []luaResult{
+			{Code: 1, Record: map[string]interface{}{
+				"accented": "h",
+				"checks":   "✓",
+				"kanji":    "日本",
+				"whole":    "hé",
+				"short":    "fits",
+				"number":   float64(12345),
+			}},
+			{Code: 0, Record: map[string]interface{}{"short": "fits"}},
+		}
+		if diff := cmp.Diff(expected, results); diff != "" {
+			t.Errorf("Results not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it reads keys holding dots from brackets", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(limitingSink(map[string]int{
+			"kubernetes.labels[app.kubernetes.io/name]": 3,
+		}))
+
+		script := sc.Script()
+		if !strings.Contains(script, `{{"kubernetes", "labels", "app.kubernetes.io/name"}, 3}`) {
+			t.Errorf("Expected the bracketed key to be one key, got %s", script)
+		}
+
+		results := runLua(t, script, "sink_0", luaRecord{tag: "a", record: map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"labels": map[string]interface{}{"app.kubernetes.io/name": "frontend"},
+			},
+		}})
+		labels := results[0].Record["kubernetes"].(map[string]interface{})["labels"]
+		if diff := cmp.Diff(map[string]interface{}{"app.kubernetes.io/name": "fro"}, labels); diff != "" {
+			t.Errorf("Labels not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it truncates fields after redacting and before requiring them", func(t *testing.T) {
+		s := limitingSink(map[string]int{"email": 8})
+		s.Spec.Redact = []v1alpha1.RedactRule{{Field: "email", Method: "hash"}}
+		s.Spec.RequireFields = []string{"email"}
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		script := sc.Script()
+		redacted := strings.Index(script, "ipairs(sink_0_redact)")
+		truncated := strings.Index(script, "ipairs(sink_0_max_bytes)")
+		required := strings.Index(script, "ipairs(sink_0_required)")
+		if redacted < 0 || truncated < redacted || required < truncated {
+			t.Errorf("Expected the fields to be redacted, truncated and then required, got %s", script)
+		}
+	})
+
+	t.Run("it does not render a script without limits", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(limitingSink(nil))
+
+		if script := sc.Script(); script != "" {
+			t.Errorf("Expected empty script, got %s", script)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// NamespaceController records the namespaces of the cluster, which cluster
+// sinks with namespace globs are rendered for. When an annotation is
+// configured it also throttles the sinks of namespaces that have it. The
+// annotation's value is the number of records per second the namespace's
+// sinks may deliver in total.
+type NamespaceController struct {
+	cmp        ConfigMapPatcher
+	dsp        DaemonSetPodDeleter
+	sc         *Config
+	annotation string
+}
+
+func NewNamespaceController(
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	sc *Config,
+	annotation string,
+) *NamespaceController {
+	return &NamespaceController{
+		cmp:        cmp,
+		dsp:        dsp,
+		sc:         sc,
+		annotation: annotation,
+	}
+}
+
+func (c *NamespaceController) OnAdd(o interface{}) {
+	ns, ok := o.(*coreV1.Namespace)
+	if !ok {
+		return
+	}
+
+	var rate int
+	if v, ok := ns.Annotations[c.annotation]; ok && c.annotation != "" {
+		var err error
+		rate, err = strconv.Atoi(v)
+		if err != nil || rate < 0 {
+			log.Printf("Ignoring invalid %s annotation on namespace %s: %q", c.annotation, ns.Name, v)
+			rate = 0
+		}
+	}
+
+	c.update(ns.Name, true, rate)
+}
+
+func (c *NamespaceController) OnDelete(o interface{}) {
+	ns, ok := o.(*coreV1.Namespace)
+	if !ok {
+		return
+	}
+
+	c.update(ns.Name, false, 0)
+}
+
+func (c *NamespaceController) OnUpdate(old, new interface{}) {
+	c.OnAdd(new)
+}
+
+// update only patches the config when the namespace's throttle changed or
+// its existence changed the sinks matching it, so that resyncs do not
+// restart fluent-bit.
+func (c *NamespaceController) update(namespace string, exists bool, rate int) {
+	changed := c.sc.SetNamespace(namespace, exists)
+	if c.sc.SetNamespaceThrottle(namespace, rate) {
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("Namespace %s changed", namespace))
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+const throttleAnnotation = "observability.knative.dev/log-rate"
+
+func TestNamespaceController(t *testing.T) {
+	setup := func() (*sink.NamespaceController, *spyConfigMapPatcher) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+			},
+		})
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewNamespaceController(
+			spyPatcher,
+			&spyDaemonSetPodDeleter{},
+			sc,
+			throttleAnnotation,
+		)
+		return c, spyPatcher
+	}
+	namespace := func(annotations map[string]string) *coreV1.Namespace {
+		return &coreV1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ns1",
+				Annotations: annotations,
+			},
+		}
+	}
+
+	t.Run("it throttles namespaces with the annotation", func(t *testing.T) {
+		c, spyPatcher := setup()
+
+		c.OnAdd(namespace(map[string]string{throttleAnnotation: "100"}))
+
+		config := lastOutputsConf(t, spyPatcher)
+		if !strings.Contains(config, "Match filtered.ns.ns1.some-sink\n    Rate 100\n") {
+			t.Errorf("Expected namespace throttle, got %s", config)
+		}
+	})
+
+	t.Run("it does not patch when the throttle is unchanged", func(t *testing.T) {
+		c, spyPatcher := setup()
+
+		c.OnAdd(namespace(nil))
+		if spyPatcher.patchCalled {
+			t.Error("Expected patch to not be called for namespace without annotation")
+		}
+
+		ns := namespace(map[string]string{throttleAnnotation: "100"})
+		c.OnAdd(ns)
+		c.OnUpdate(ns, ns)
+		if len(spyPatcher.patches) != 1 {
+			t.Errorf("Expected 1 patch, got %d", len(spyPatcher.patches))
+		}
+	})
+
+	t.Run("it removes the throttle", func(t *testing.T) {
+		for name, remove := range map[string]func(*sink.NamespaceController, *coreV1.Namespace){
+			"annotation removed": func(c *sink.NamespaceController, old *coreV1.Namespace) {
+				c.OnUpdate(old, namespace(nil))
+			},
+			"namespace deleted": func(c *sink.NamespaceController, old *coreV1.Namespace) {
+				c.OnDelete(old)
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				c, spyPatcher := setup()
+				ns := namespace(map[string]string{throttleAnnotation: "100"})
+				c.OnAdd(ns)
+
+				remove(c, ns)
+
+				if config := lastOutputsConf(t, spyPatcher); strings.Contains(config, "throttle") {
+					t.Errorf("Expected no throttle, got %s", config)
+				}
+			})
+		}
+	})
+
+	t.Run("it ignores invalid annotations", func(t *testing.T) {
+		for _, v := range []string{"fast", "-1", ""} {
+			c, spyPatcher := setup()
+
+			c.OnAdd(namespace(map[string]string{throttleAnnotation: v}))
+
+			if spyPatcher.patchCalled {
+				t.Errorf("Expected patch to not be called for %q", v)
+			}
+		}
+	})
+}
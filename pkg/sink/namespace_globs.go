@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"path"
+	"sort"
+)
+
+// SetNamespace records whether the namespace exists in the cluster. It
+// returns whether the rendered config changed, which is only the case when
+// the namespace matches the namespace globs of a cluster sink.
+func (sc *Config) SetNamespace(namespace string, exists bool) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.namespaces[namespace] == exists {
+		return false
+	}
+	if exists {
+		sc.namespaces[namespace] = true
+	} else {
+		delete(sc.namespaces, namespace)
+	}
+
+	for _, cs := range sc.clusterSinks {
+		if matchesGlobs(cs.Spec.NamespaceGlobs, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// globNamespaces returns the sorted namespaces matching any of globs.
+func (sc *Config) globNamespaces(globs []string) []string {
+	var namespaces []string
+	for ns := range sc.namespaces {
+		if matchesGlobs(globs, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// matchesGlobs reports whether namespace matches any of globs. Namespace
+// names cannot contain a slash, so path.Match's rules apply as is. The
+// validator rejects malformed globs, which never match.
+func matchesGlobs(globs []string, namespace string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestNamespaceGlobs(t *testing.T) {
+	globSink := func(sinkType string, globs ...string) *v1alpha1.ClusterLogSink {
+		s := &v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+			Spec: v1alpha1.SinkSpec{
+				Type:           sinkType,
+				NamespaceGlobs: globs,
+			},
+		}
+		switch sinkType {
+		case "syslog":
+			s.Spec.SyslogSpec = v1alpha1.SyslogSpec{Host: "example.com", Port: 514}
+		case "webhook":
+			s.Spec.WebhookSpec = v1alpha1.WebhookSpec{URL: "https://example.com/logs"}
+		}
+		return s
+	}
+
+	t.Run("it renders a syslog output for each matching namespace", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(globSink("syslog", "team-*-prod", "ops"))
+		for _, ns := range []string{"team-b-prod", "team-a-prod", "team-a-dev", "ops"} {
+			sc.SetNamespace(ns, true)
+		}
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ops_*
+    Alias cluster:prod/ops
+    InstanceName cluster:prod/ops
+    Addr example.com:514
+    Namespace ops
+
+[OUTPUT]
+    Name syslog
+    Match *_team-a-prod_*
+    Alias cluster:prod/team-a-prod
+    InstanceName cluster:prod/team-a-prod
+    Addr example.com:514
+    Namespace team-a-prod
+
+[OUTPUT]
+    Name syslog
+    Match *_team-b-prod_*
+    Alias cluster:prod/team-b-prod
+    InstanceName cluster:prod/team-b-prod
+    Addr example.com:514
+    Namespace team-b-prod
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it matches the records of each matching namespace for webhooks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(globSink("webhook", "team-*"))
+		sc.SetNamespace("team-a", true)
+		sc.SetNamespace("other", true)
+
+		expected := `
+[OUTPUT]
+    Name http
+    Match *_team-a_*
+    Alias cluster:prod/team-a
+    Format json
+    Host example.com
+    Port 443
+    URI /logs
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it tails each matching namespace for raw mode sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		s := globSink("webhook", "team-*")
+		s.Spec.RawMode = true
+		sc.UpsertClusterSink(s)
+		sc.SetNamespace("team-a", true)
+
+		config := sc.String()
+		for _, want := range []string{
+			"Tag raw.glob.prod.team-a\n",
+			"Path /var/log/containers/*_team-a_*.log\n",
+			"Match raw.glob.prod.team-a\n",
+		} {
+			if !strings.Contains(config, want) {
+				t.Errorf("Expected config to contain %q, got %s", want, config)
+			}
+		}
+	})
+
+	t.Run("it reports changes for namespaces matching a glob", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(globSink("syslog", "team-*"))
+
+		if !sc.SetNamespace("team-a", true) {
+			t.Error("Expected a new matching namespace to change the config")
+		}
+		if sc.SetNamespace("team-a", true) {
+			t.Error("Expected an existing namespace to not change the config")
+		}
+		if sc.SetNamespace("other", true) {
+			t.Error("Expected a namespace that does not match to not change the config")
+		}
+		if !sc.SetNamespace("team-a", false) {
+			t.Error("Expected a deleted matching namespace to change the config")
+		}
+	})
+
+	t.Run("it renders new namespaces as they are created", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(globSink("syslog", "team-*"))
+		spyPatcher := &spyConfigMapPatcher{}
+		c := sink.NewNamespaceController(spyPatcher, &spyDaemonSetPodDeleter{}, sc, "")
+
+		c.OnAdd(&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}})
+		if spyPatcher.patchCalled {
+			t.Fatal("Expected patch to not be called for a namespace that does not match")
+		}
+
+		c.OnAdd(&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+		if config := lastOutputsConf(t, spyPatcher); !strings.Contains(config, "Namespace team-a\n") {
+			t.Errorf("Expected an output for team-a, got %s", config)
+		}
+
+		c.OnDelete(&coreV1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}})
+		if config := lastOutputsConf(t, spyPatcher); strings.Contains(config, "Namespace team-a\n") {
+			t.Errorf("Expected the output for team-a to be removed, got %s", config)
+		}
+	})
+
+	t.Run("it sums the outputs of a sink in the circuit breaker", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(globSink("syslog", "team-*"))
+		sc.SetNamespace("team-a", true)
+		sc.SetNamespace("team-b", true)
+		spyPatcher := &spyConfigMapPatcher{}
+		b := sink.NewBreaker(
+			spyPatcher,
+			&spyDaemonSetPodDeleter{},
+			fake.NewSimpleClientset().ObservabilityV1alpha1(),
+			sc,
+			sink.WithFailureThreshold(1),
+		)
+
+		b.Observe(map[string]sink.OutputMetrics{
+			"cluster:prod/team-a": {},
+			"cluster:prod/team-b": {},
+		})
+		// One namespace's output is failing while the other delivers.
+		b.Observe(map[string]sink.OutputMetrics{
+			"cluster:prod/team-a": {Errors: 5},
+			"cluster:prod/team-b": {ProcRecords: 5},
+		})
+		if spyPatcher.patchCalled {
+			t.Error("Expected the circuit to stay closed while an output delivers")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NodeController records the labels of the cluster's nodes, which select
+// the nodes audit sinks and sinks with a node selector read the records of.
+// A fluent-bit pod only reads the records of its own node, so the selection
+// of a node only changes the config of the pod on it. Only that pod is
+// restarted, and nodes joining or leaving the cluster do not restart the
+// pods of the other nodes.
+type NodeController struct {
+	cmp ConfigMapPatcher
+	dsp DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewNodeController(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *NodeController {
+	return &NodeController{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+// OnAdd only patches the config when the node changes the nodes an audit
+// sink selects, so that resyncs do not restart fluent-bit.
+func (c *NodeController) OnAdd(o interface{}) {
+	n, ok := o.(*coreV1.Node)
+	if !ok {
+		return
+	}
+
+	if c.sc.SetNode(n.Name, n.Labels) {
+		applyNodeConfig(c.sc, c.cmp, c.dsp, n.Name, fmt.Sprintf("Node %s upserted", n.Name))
+	}
+}
+
+func (c *NodeController) OnUpdate(old, new interface{}) {
+	c.OnAdd(new)
+}
+
+func (c *NodeController) OnDelete(o interface{}) {
+	if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+		o = tombstone.Obj
+	}
+	n, ok := o.(*coreV1.Node)
+	if !ok {
+		return
+	}
+
+	if c.sc.DeleteNode(n.Name) {
+		applyNodeConfig(c.sc, c.cmp, c.dsp, n.Name, fmt.Sprintf("Node %s deleted", n.Name))
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// allRecordsRule matches every container log record, since every record
+// has a log key.
+const allRecordsRule = "$log .*"
+
+// nodeSelected returns whether the sink only reads the records of the
+// nodes its node selector matches. Raw mode and audit sinks read records
+// without kubernetes metadata, and syslog outputs route records by
+// namespace rather than by tag.
+func nodeSelected(ref sinkRef) bool {
+	return len(ref.spec.NodeSelector) > 0 && ref.spec.Type != "syslog" && !ref.spec.RawMode && !audited(ref)
+}
+
+// nodeCopied returns whether the sink reads a copy of the records of the
+// selected nodes. Projected and routed sinks already read their own copy,
+// which only holds the records of the selected nodes.
+func nodeCopied(ref sinkRef) bool {
+	return nodeSelected(ref) && !projected(ref) && !routed(ref)
+}
+
+// nodeTag is the tag of the copies of the records read by a sink with a
+// node selector.
+func nodeTag(ref sinkRef) string {
+	if ref.cluster {
+		return fmt.Sprintf("nodes.cluster.%s", ref.name)
+	}
+	if ref.glob {
+		return fmt.Sprintf("nodes.glob.%s.%s", ref.name, ref.namespace)
+	}
+	return fmt.Sprintf("nodes.ns.%s.%s", canonicalNamespace(ref.namespace), ref.name)
+}
+
+// copyRule returns the rewrite_tag rule of the records copied to the tag
+// of a sink. The kubernetes filter sets the host of a record's metadata to
+// the node the container runs on, so a sink with a node selector copies
+// the records whose host is a selected node. It copies nothing while no
+// node is selected.
+func (sc *Config) copyRule(ref sinkRef) string {
+	if !nodeSelected(ref) {
+		return allRecordsRule
+	}
+
+	nodes := sc.selectedNodes(labels.SelectorFromSet(ref.spec.NodeSelector))
+	if len(nodes) == 0 {
+		return "$kubernetes['host'] ^$"
+	}
+	for i, n := range nodes {
+		nodes[i] = regexp.QuoteMeta(n)
+	}
+	return fmt.Sprintf("$kubernetes['host'] ^(%s)$", strings.Join(nodes, "|"))
+}
+
+// nodeSelectionChanged returns whether a node changes the nodes a sink
+// reads the records of. The node selectors of sinks that read every node
+// are not rendered, so they are skipped.
+func (sc *Config) nodeSelectionChanged(old map[string]string, existed bool, cur map[string]string, exists bool) bool {
+	for _, ref := range sc.filteredSinkRefs() {
+		if !nodeSelected(ref) {
+			continue
+		}
+		selector := labels.SelectorFromSet(ref.spec.NodeSelector)
+		was := existed && selector.Matches(labels.Set(old))
+		is := exists && selector.Matches(labels.Set(cur))
+		if was != is {
+			return true
+		}
+	}
+	return false
+}
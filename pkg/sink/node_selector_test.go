@@ -0,0 +1,196 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestNodeSelector(t *testing.T) {
+	poolSink := func(name string, selector map[string]string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/" + name,
+				},
+				NodeSelector: selector,
+			},
+		}
+	}
+	gpuPool := map[string]string{"pool": "gpu"}
+	withNodes := func(sc *sink.Config) {
+		sc.SetNode("gpu-1", map[string]string{"pool": "gpu"})
+		sc.SetNode("gpu-2.example.com", map[string]string{"pool": "gpu"})
+		sc.SetNode("cpu-1", map[string]string{"pool": "cpu"})
+	}
+
+	t.Run("it copies the records of the selected nodes to the sink", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		sc.UpsertSink(poolSink("all-nodes", nil))
+		sc.UpsertSink(poolSink("gpu", gpuPool))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $kubernetes['host'] ^(gpu-1|gpu-2\.example\.com)$ nodes.ns.ns1.gpu true
+    Emitter_Name ns1/gpu:nodes
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/all-nodes
+    Format json
+    Host example.com
+    Port 443
+    URI /all-nodes
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match nodes.ns.ns1.gpu
+    Alias ns1/gpu
+    Format json
+    Host example.com
+    Port 443
+    URI /gpu
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it copies the container logs for cluster sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "gpu"},
+			Spec:       poolSink("gpu", gpuPool).Spec,
+		})
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $kubernetes['host'] ^(gpu-1|gpu-2\.example\.com)$ nodes.cluster.gpu true
+    Emitter_Name cluster:gpu:nodes
+`
+		if config := sc.String(); !strings.HasPrefix(config, expected) {
+			t.Errorf("Expected the copy of the container logs, got %s", config)
+		}
+	})
+
+	t.Run("it copies nothing while no node is selected", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(poolSink("gpu", gpuPool))
+
+		if config := sc.String(); !strings.Contains(config, "Rule $kubernetes['host'] ^$ nodes.ns.ns1.gpu true") {
+			t.Errorf("Expected a rule matching no records, got %s", config)
+		}
+	})
+
+	t.Run("it only projects the records of the selected nodes", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		s := poolSink("gpu", gpuPool)
+		s.Spec.Project = []string{"log"}
+		sc.UpsertSink(s)
+
+		config := sc.String()
+		if !strings.Contains(config, "Rule $kubernetes['host'] ^(gpu-1|gpu-2\\.example\\.com)$ project.ns.ns1.gpu true") {
+			t.Errorf("Expected the projected copy to be limited to the selected nodes, got %s", config)
+		}
+		if strings.Contains(config, "nodes.ns.ns1.gpu") {
+			t.Errorf("Expected a single copy of the records, got %s", config)
+		}
+	})
+
+	t.Run("it reports a changed config when the selected nodes change", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		sc.UpsertSink(poolSink("gpu", gpuPool))
+
+		if sc.SetNode("cpu-1", map[string]string{"pool": "cpu", "zone": "a"}) {
+			t.Error("Expected a label change of an unselected node to keep the config")
+		}
+		if !sc.SetNode("cpu-1", map[string]string{"pool": "gpu"}) {
+			t.Error("Expected a newly selected node to change the config")
+		}
+		if !sc.DeleteNode("gpu-1") {
+			t.Error("Expected a deleted selected node to change the config")
+		}
+		if !strings.Contains(sc.String(), "^(cpu-1|gpu-2\\.example\\.com)$") {
+			t.Errorf("Expected the selected nodes to be updated, got %s", sc.String())
+		}
+	})
+
+	t.Run("it keeps the config when a node selector is not rendered", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		s := poolSink("syslog", gpuPool)
+		s.Spec.Type = "syslog"
+		s.Spec.SyslogSpec = v1alpha1.SyslogSpec{Host: "example.com", Port: 514}
+		sc.UpsertSink(s)
+
+		if sc.SetNode("gpu-3", map[string]string{"pool": "gpu"}) {
+			t.Error("Expected a node selected by a syslog sink to keep the config")
+		}
+	})
+
+	t.Run("it only restarts the fluent-bit pod of a node that changes the selection", func(t *testing.T) {
+		sc := sink.NewConfig()
+		withNodes(sc)
+		sc.UpsertSink(poolSink("gpu", gpuPool))
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewNodeController(spyPatcher, spyDeleter, sc)
+
+		c.OnAdd(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cpu-2", Labels: map[string]string{"pool": "cpu"}}})
+		if spyPatcher.patchCalled || spyDeleter.deleteCollectionCalled {
+			t.Fatal("Expected an unselected node to keep the config")
+		}
+
+		c.OnAdd(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-3", Labels: gpuPool}})
+		if !spyPatcher.patchCalled {
+			t.Error("Expected the config to be patched")
+		}
+		if spyDeleter.Selector != "app=fluent-bit" || spyDeleter.FieldSelector != "spec.nodeName=gpu-3" {
+			t.Errorf("Expected the fluent-bit pod of gpu-3 to be restarted, got %q %q", spyDeleter.Selector, spyDeleter.FieldSelector)
+		}
+
+		c.OnDelete(&coreV1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-1", Labels: gpuPool}})
+		if spyDeleter.FieldSelector != "spec.nodeName=gpu-1" {
+			t.Errorf("Expected only the pod of the deleted node to be restarted, got %q", spyDeleter.FieldSelector)
+		}
+	})
+}
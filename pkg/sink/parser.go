@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The parsers in parsers.conf for the log files written by each container
+// runtime. Docker writes JSON lines and CRI runtimes, e.g. containerd and
+// cri-o, write the time, stream and tag before each line.
+const (
+	DockerParser = "docker"
+	CRIParser    = "cri"
+)
+
+// kubernetesInputAlias is the alias of the tail input for container logs.
+const kubernetesInputAlias = "kube"
+
+// kubernetesInputTemplate is the tail input for container logs. It must be
+// kept in sync with the fluent-bit configmap.
+const kubernetesInputTemplate = `[INPUT]
+    Name              tail
+    Tag               kube.*
+    Alias             kube
+    Path              /var/log/containers/*.log
+    Parser            %s
+    DB                /var/log/flb_kube.db
+    Mem_Buf_Limit     5MB
+    Skip_Long_Lines   On
+    Refresh_Interval  10
+%s`
+
+// InputParser returns the parser for the container logs of the nodes. The
+// override is returned when it is set, otherwise the parser is picked from
+// the container runtime the nodes report. Fluent-bit runs a single config on
+// every node, so when nodes run different runtimes the parser of the most
+// common one is used.
+func InputParser(override string, nodes []coreV1.Node) (string, error) {
+	if override != "" {
+		if override != DockerParser && override != CRIParser {
+			return "", fmt.Errorf("unknown parser %q, should be %s or %s", override, DockerParser, CRIParser)
+		}
+		return override, nil
+	}
+
+	var docker, cri int
+	for _, n := range nodes {
+		if runtimeParser(n) == DockerParser {
+			docker++
+			continue
+		}
+		cri++
+	}
+	if docker > 0 && cri > 0 {
+		log.Printf("%d nodes run docker and %d run a CRI runtime, logs of the less common runtime will not be parsed", docker, cri)
+	}
+
+	if cri > docker {
+		return CRIParser, nil
+	}
+	return DockerParser, nil
+}
+
+// runtimeParser returns the parser for a node's container runtime, which is
+// reported as e.g. docker://18.9.0 or containerd://1.2.0. Nodes that do not
+// report a runtime are assumed to run docker.
+func runtimeParser(n coreV1.Node) string {
+	runtime := n.Status.NodeInfo.ContainerRuntimeVersion
+	if runtime == "" || strings.HasPrefix(runtime, "docker://") {
+		return DockerParser
+	}
+	return CRIParser
+}
+
+// SetInputParser sets the parser of the container logs input. When buffered
+// is set, the input buffers its chunks on the filesystem, which requires
+// the storage path of the service config. When skipOlderThan is set, the
+// input skips the log files last written longer ago.
+func SetInputParser(
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	sc *Config,
+	parser string,
+	buffered bool,
+	skipOlderThan time.Duration,
+) {
+	var options string
+	if skipOlderThan > 0 {
+		options += fmt.Sprintf("    Ignore_Older      %d\n", int(math.Ceil(skipOlderThan.Seconds())))
+	}
+	if buffered {
+		options += "    storage.type      filesystem\n"
+	}
+
+	patchConfig(sc, cmp, dsp, []patch{
+		{
+			Op:    "replace",
+			Path:  "/data/input-kubernetes.conf",
+			Value: fmt.Sprintf(kubernetesInputTemplate, parser, options),
+		},
+	}, "Container logs input set")
+}
+
+// ignoreOlderConfig renders the option of a sink's own tail input that
+// skips the files last written before skipOlderThan.
+func ignoreOlderConfig(skipOlderThan *metav1.Duration) string {
+	if skipOlderThan == nil || skipOlderThan.Duration <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("    Ignore_Older %d\n", int(math.Ceil(skipOlderThan.Seconds())))
+}
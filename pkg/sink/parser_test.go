@@ -0,0 +1,273 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestInputParser(t *testing.T) {
+	tests := map[string]struct {
+		override string
+		runtimes []string
+		expected string
+	}{
+		"containerd nodes": {
+			runtimes: []string{"containerd://1.2.0", "containerd://1.2.0"},
+			expected: sink.CRIParser,
+		},
+		"cri-o nodes": {
+			runtimes: []string{"cri-o://1.13.0"},
+			expected: sink.CRIParser,
+		},
+		"docker nodes": {
+			runtimes: []string{"docker://18.9.0", "docker://18.9.0"},
+			expected: sink.DockerParser,
+		},
+		"nodes without a runtime": {
+			runtimes: []string{""},
+			expected: sink.DockerParser,
+		},
+		"mostly containerd nodes": {
+			runtimes: []string{"docker://18.9.0", "containerd://1.2.0", "containerd://1.2.0"},
+			expected: sink.CRIParser,
+		},
+		"an even mix of nodes": {
+			runtimes: []string{"docker://18.9.0", "containerd://1.2.0"},
+			expected: sink.DockerParser,
+		},
+		"a docker override on containerd nodes": {
+			override: "docker",
+			runtimes: []string{"containerd://1.2.0"},
+			expected: sink.DockerParser,
+		},
+		"a cri override on docker nodes": {
+			override: "cri",
+			runtimes: []string{"docker://18.9.0"},
+			expected: sink.CRIParser,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			parser, err := sink.InputParser(test.override, nodes(test.runtimes...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parser != test.expected {
+				t.Errorf("Expected parser %s, got %s", test.expected, parser)
+			}
+		})
+	}
+
+	t.Run("it returns an error for an unknown override", func(t *testing.T) {
+		_, err := sink.InputParser("containerd", nodes("containerd://1.2.0"))
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+func TestSetInputParser(t *testing.T) {
+	spyConfigMapPatcher := &spyConfigMapPatcher{}
+	spyDaemonSetPodDeleter := &spyDaemonSetPodDeleter{}
+
+	sink.SetInputParser(
+		spyConfigMapPatcher,
+		spyDaemonSetPodDeleter,
+		sink.NewConfig(),
+		sink.CRIParser,
+		false,
+		0,
+	)
+
+	expectedPatch := []spyPatch{
+		{
+			Path: "/data/input-kubernetes.conf",
+			Value: `[INPUT]
+    Name              tail
+    Tag               kube.*
+    Alias             kube
+    Path              /var/log/containers/*.log
+    Parser            cri
+    DB                /var/log/flb_kube.db
+    Mem_Buf_Limit     5MB
+    Skip_Long_Lines   On
+    Refresh_Interval  10
+`,
+		},
+	}
+
+	spyConfigMapPatcher.expectPatches(expectedPatch, t)
+	if spyDaemonSetPodDeleter.Selector != "app=fluent-bit" {
+		t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", spyDaemonSetPodDeleter.Selector, "app=fluent-bit")
+	}
+}
+
+func nodes(runtimes ...string) []coreV1.Node {
+	var ns []coreV1.Node
+	for _, r := range runtimes {
+		ns = append(ns, coreV1.Node{
+			Status: coreV1.NodeStatus{
+				NodeInfo: coreV1.NodeSystemInfo{
+					ContainerRuntimeVersion: r,
+				},
+			},
+		})
+	}
+	return ns
+}
+
+func TestSetInputParserBuffered(t *testing.T) {
+	spyConfigMapPatcher := &spyConfigMapPatcher{}
+
+	sink.SetInputParser(
+		spyConfigMapPatcher,
+		&spyDaemonSetPodDeleter{},
+		sink.NewConfig(),
+		sink.DockerParser,
+		true,
+		0,
+	)
+
+	expectedPatch := []spyPatch{
+		{
+			Path: "/data/input-kubernetes.conf",
+			Value: `[INPUT]
+    Name              tail
+    Tag               kube.*
+    Alias             kube
+    Path              /var/log/containers/*.log
+    Parser            docker
+    DB                /var/log/flb_kube.db
+    Mem_Buf_Limit     5MB
+    Skip_Long_Lines   On
+    Refresh_Interval  10
+    storage.type      filesystem
+`,
+		},
+	}
+
+	spyConfigMapPatcher.expectPatches(expectedPatch, t)
+}
+
+func TestSetInputParserSkipsOldLogs(t *testing.T) {
+	spyConfigMapPatcher := &spyConfigMapPatcher{}
+
+	sink.SetInputParser(
+		spyConfigMapPatcher,
+		&spyDaemonSetPodDeleter{},
+		sink.NewConfig(),
+		sink.DockerParser,
+		true,
+		90*time.Minute,
+	)
+
+	expectedPatch := []spyPatch{
+		{
+			Path: "/data/input-kubernetes.conf",
+			Value: `[INPUT]
+    Name              tail
+    Tag               kube.*
+    Alias             kube
+    Path              /var/log/containers/*.log
+    Parser            docker
+    DB                /var/log/flb_kube.db
+    Mem_Buf_Limit     5MB
+    Skip_Long_Lines   On
+    Refresh_Interval  10
+    Ignore_Older      5400
+    storage.type      filesystem
+`,
+		},
+	}
+
+	spyConfigMapPatcher.expectPatches(expectedPatch, t)
+}
+
+func TestSkipLogsOlderThan(t *testing.T) {
+	skip := &metav1.Duration{Duration: 24 * time.Hour}
+
+	t.Run("it skips old files of raw mode inputs", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "raw", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:              "webhook",
+				WebhookSpec:       v1alpha1.WebhookSpec{URL: "https://example.com/raw"},
+				RawMode:           true,
+				SkipLogsOlderThan: skip,
+			},
+		})
+
+		expected := `
+[INPUT]
+    Name tail
+    Tag raw.ns.ns1.raw
+    Alias ns1/raw:raw
+    Path /var/log/containers/*_ns1_*.log
+    DB /var/log/flb_raw.ns.ns1.raw.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+    Ignore_Older 86400
+`
+		if config := sc.String(); !strings.HasPrefix(config, expected) {
+			t.Errorf("Expected the raw input to skip old files, got %s", config)
+		}
+	})
+
+	t.Run("it skips old files of audit inputs", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "audit"},
+			Spec: v1alpha1.SinkSpec{
+				Type:              "webhook",
+				WebhookSpec:       v1alpha1.WebhookSpec{URL: "https://example.com/audit"},
+				AuditLog:          &v1alpha1.AuditLogSpec{},
+				SkipLogsOlderThan: skip,
+			},
+		})
+
+		if config := sc.String(); !strings.Contains(config, "Refresh_Interval 10\n    Ignore_Older 86400\n") {
+			t.Errorf("Expected the audit input to skip old files, got %s", config)
+		}
+	})
+
+	t.Run("it reads every file without a threshold", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "raw", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://example.com/raw"},
+				RawMode:     true,
+			},
+		})
+
+		if config := sc.String(); strings.Contains(config, "Ignore_Older") {
+			t.Errorf("Expected no Ignore_Older, got %s", config)
+		}
+	})
+}
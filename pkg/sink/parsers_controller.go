@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// ParsersController restarts fluent-bit when the configmap of custom
+// parsers changes. Fluent-bit only reads parsers when it starts, so without
+// a restart edited parsers are not used until the pods are restarted by
+// something else.
+type ParsersController struct {
+	name string
+	dsp  DaemonSetPodDeleter
+	sc   *Config
+}
+
+func NewParsersController(name string, dsp DaemonSetPodDeleter, sc *Config) *ParsersController {
+	return &ParsersController{
+		name: name,
+		dsp:  dsp,
+		sc:   sc,
+	}
+}
+
+// OnAdd does not restart fluent-bit, since the configmap is listed when
+// the controller starts and fluent-bit already read it when it started.
+func (c *ParsersController) OnAdd(o interface{}) {}
+
+// OnUpdate restarts fluent-bit when the parsers changed. Resyncs update the
+// configmap with the same data and are ignored.
+func (c *ParsersController) OnUpdate(old, new interface{}) {
+	o, ok := old.(*coreV1.ConfigMap)
+	if !ok {
+		return
+	}
+	n, ok := new.(*coreV1.ConfigMap)
+	if !ok || n.Name != c.name {
+		return
+	}
+	if reflect.DeepEqual(o.Data, n.Data) && reflect.DeepEqual(o.BinaryData, n.BinaryData) {
+		return
+	}
+
+	log.Printf("Parsers in ConfigMap %s changed, restarting fluent-bit", n.Name)
+	restartFluentBit(c.sc, c.dsp, fmt.Sprintf("Parsers in ConfigMap %s changed", n.Name))
+}
+
+func (c *ParsersController) OnDelete(o interface{}) {}
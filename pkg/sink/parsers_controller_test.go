@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestParsersController(t *testing.T) {
+	parsers := func(name, conf string) *coreV1.ConfigMap {
+		return &coreV1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "knative-observability",
+			},
+			Data: map[string]string{"custom-parsers.conf": conf},
+		}
+	}
+	old := parsers("fluent-bit-parsers", "[PARSER]\n    Name old\n")
+
+	t.Run("it restarts fluent-bit when the parsers change", func(t *testing.T) {
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewParsersController("fluent-bit-parsers", spyDeleter, sink.NewConfig())
+
+		c.OnUpdate(old, parsers("fluent-bit-parsers", "[PARSER]\n    Name new\n"))
+
+		if !spyDeleter.deleteCollectionCalled {
+			t.Fatal("Expected fluent-bit to be restarted")
+		}
+		if spyDeleter.Selector != "app=fluent-bit" {
+			t.Errorf("Expected selector app=fluent-bit, got %s", spyDeleter.Selector)
+		}
+	})
+
+	t.Run("it does not restart fluent-bit", func(t *testing.T) {
+		tests := map[string]func(c *sink.ParsersController){
+			"on resyncs": func(c *sink.ParsersController) {
+				c.OnUpdate(old, old.DeepCopy())
+			},
+			"for other configmaps": func(c *sink.ParsersController) {
+				c.OnUpdate(parsers("other", "a"), parsers("other", "b"))
+			},
+			"when the configmap is listed": func(c *sink.ParsersController) {
+				c.OnAdd(old)
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				spyDeleter := &spyDaemonSetPodDeleter{}
+				test(sink.NewParsersController("fluent-bit-parsers", spyDeleter, sink.NewConfig()))
+
+				if spyDeleter.deleteCollectionCalled {
+					t.Error("Expected fluent-bit to not be restarted")
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"log"
+	"os"
+)
+
+// Pauser pauses and resumes the reconciles of the controllers. While
+// paused, the controllers keep the config up to date from their informers
+// but do not write it to the configmap or restart fluent-bit. Resuming
+// applies the held patches and the config if a reconcile was held while
+// paused.
+type Pauser struct {
+	cmp ConfigMapPatcher
+	dsp DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewPauser(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *Pauser {
+	return &Pauser{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+// Run toggles the pause every time a signal is received until stopCh is
+// closed.
+func (p *Pauser) Run(signals <-chan os.Signal, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-signals:
+			p.Toggle()
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Toggle pauses the reconciles if they are running and resumes them if
+// they are paused. It returns whether they are paused.
+func (p *Pauser) Toggle() bool {
+	paused, held, patches := p.sc.togglePaused()
+	if paused {
+		log.Print("Reconciles are paused, the config is not written until they resume")
+		return true
+	}
+
+	log.Print("Reconciles resumed")
+	if len(patches) > 0 {
+		if err := patchConfigMap(patches, p.cmp); err != nil {
+			p.sc.auditReconcile("Reconciles resumed", DecisionPatchFailed, err)
+		}
+	}
+	if held {
+		applyConfig(p.sc, p.cmp, p.dsp, "Reconciles resumed")
+	}
+	return false
+}
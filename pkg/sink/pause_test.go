@@ -0,0 +1,185 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestPauser(t *testing.T) {
+	pausedSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "paused-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+		},
+	}
+
+	t.Run("it holds reconciles while paused and applies them on resume", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		sc := sink.NewConfig()
+		c := sink.NewController(spyPatcher, spyDeleter, sc)
+		p := sink.NewPauser(spyPatcher, spyDeleter, sc)
+
+		if !p.Toggle() {
+			t.Fatal("Expected the reconciles to be paused")
+		}
+		c.OnAdd(pausedSink)
+		if spyPatcher.patchCalled || spyDeleter.deleteCollectionCalled {
+			t.Fatal("Expected no patch or restart while paused")
+		}
+		if !strings.Contains(sc.String(), "paused-sink") {
+			t.Error("Expected the config to be kept up to date while paused")
+		}
+
+		if p.Toggle() {
+			t.Fatal("Expected the reconciles to be resumed")
+		}
+		if len(spyPatcher.patches) != 1 || !spyDeleter.deleteCollectionCalled {
+			t.Fatalf("Expected the held config to be applied once, got %d patches", len(spyPatcher.patches))
+		}
+		if !strings.Contains(string(spyPatcher.patches[0].data), "paused-sink") {
+			t.Errorf("Expected the patch to include the sink, got %s", spyPatcher.patches[0].data)
+		}
+
+		c.OnDelete(pausedSink)
+		if len(spyPatcher.patches) != 2 {
+			t.Errorf("Expected reconciles to run after resuming, got %d patches", len(spyPatcher.patches))
+		}
+	})
+
+	t.Run("it does not apply on resume when nothing was held", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		p := sink.NewPauser(spyPatcher, &spyDaemonSetPodDeleter{}, sink.NewConfig())
+
+		p.Toggle()
+		p.Toggle()
+
+		if spyPatcher.patchCalled {
+			t.Error("Expected no patch")
+		}
+	})
+
+	t.Run("it toggles on signals", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		sc := sink.NewConfig()
+		c := sink.NewController(spyPatcher, spyDeleter, sc)
+		p := sink.NewPauser(spyPatcher, spyDeleter, sc)
+
+		signals := make(chan os.Signal)
+		stopCh := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			p.Run(signals, stopCh)
+			close(done)
+		}()
+
+		p.Toggle()
+		c.OnAdd(pausedSink)
+		signals <- syscall.SIGUSR1
+		close(stopCh)
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected Run to return when stopped")
+		}
+		if len(spyPatcher.patches) != 1 {
+			t.Errorf("Expected the held config to be applied on resume, got %d patches", len(spyPatcher.patches))
+		}
+	})
+
+	t.Run("it holds the config outside of the sinks while paused", func(t *testing.T) {
+		tests := map[string]struct {
+			run   func(*spyConfigMapPatcher, *spyDaemonSetPodDeleter, *sink.Config)
+			patch string
+		}{
+			"cluster name": {
+				run: func(cmp *spyConfigMapPatcher, dsp *spyDaemonSetPodDeleter, sc *sink.Config) {
+					sink.SetClusterNameFilter(cmp, dsp, sc, "some-cluster")
+				},
+				patch: "/data/cluster-name-filter.conf",
+			},
+			"input parser": {
+				run: func(cmp *spyConfigMapPatcher, dsp *spyDaemonSetPodDeleter, sc *sink.Config) {
+					sink.SetInputParser(cmp, dsp, sc, "docker", false, 0)
+				},
+				patch: "/data/input-kubernetes.conf",
+			},
+			"service config": {
+				run: func(cmp *spyConfigMapPatcher, dsp *spyDaemonSetPodDeleter, sc *sink.Config) {
+					sink.SetServiceConfig(cmp, dsp, sc, 1, 5, "")
+				},
+				patch: "/data/fluent-bit.conf",
+			},
+			"parsers": {
+				run: func(cmp *spyConfigMapPatcher, dsp *spyDaemonSetPodDeleter, sc *sink.Config) {
+					parsers := func(conf string) *coreV1.ConfigMap {
+						return &coreV1.ConfigMap{
+							ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit-parsers"},
+							Data:       map[string]string{"parsers.conf": conf},
+						}
+					}
+					sink.NewParsersController("fluent-bit-parsers", dsp, sc).OnUpdate(parsers("a"), parsers("b"))
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				spyPatcher := &spyConfigMapPatcher{}
+				spyDeleter := &spyDaemonSetPodDeleter{}
+				sc := sink.NewConfig()
+				p := sink.NewPauser(spyPatcher, spyDeleter, sc)
+
+				p.Toggle()
+				test.run(spyPatcher, spyDeleter, sc)
+				if spyPatcher.patchCalled || spyDeleter.deleteCollectionCalled {
+					t.Fatal("Expected no patch or restart while paused")
+				}
+
+				p.Toggle()
+				if !spyDeleter.deleteCollectionCalled {
+					t.Error("Expected fluent-bit to be restarted on resume")
+				}
+				if test.patch == "" {
+					return
+				}
+				if len(spyPatcher.patches) == 0 || !strings.Contains(string(spyPatcher.patches[0].data), test.patch) {
+					t.Errorf("Expected the held patch of %s to be applied on resume, got %v", test.patch, spyPatcher.patches)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+
+	authV1 "k8s.io/api/authorization/v1"
+)
+
+type AccessReviewer interface {
+	Create(*authV1.SelfSubjectAccessReview) (*authV1.SelfSubjectAccessReview, error)
+}
+
+// Permission is an action the controller needs to be allowed to take for a
+// feature to work.
+type Permission struct {
+	Feature     string
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+	// Name is empty for access to every object of the resource.
+	Name string
+	// Namespace is empty for cluster scoped resources and for access across
+	// all namespaces.
+	Namespace string
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Name != "" {
+		resource += " " + p.Name
+	}
+	if p.Namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %s", p.Verb, resource, p.Namespace)
+	}
+	return fmt.Sprintf("%s %s", p.Verb, resource)
+}
+
+// ControllerFeatures are the optional features of the sink-controller that
+// need permissions of their own.
+type ControllerFeatures struct {
+	CircuitBreaker    bool
+	NamespaceThrottle bool
+	PriorityClass     bool
+	Resources         bool
+	PreStop           bool
+	Replay            bool
+}
+
+// ControllerPermissions returns the permissions the sink-controller needs
+// in namespace, the namespace of fluent-bit, with the given features
+// enabled.
+func ControllerPermissions(namespace string, features ControllerFeatures) []Permission {
+	perms := []Permission{
+		{Feature: "config", Resource: "configmaps", Verb: "patch", Namespace: namespace},
+		{Feature: "config", Resource: "configmaps", Verb: "create", Namespace: namespace},
+		{Feature: "config", Resource: "configmaps", Verb: "list", Namespace: namespace},
+		{Feature: "config", Resource: "configmaps", Verb: "watch", Namespace: namespace},
+		{Feature: "config", Resource: "pods", Verb: "deletecollection", Namespace: namespace},
+		{Feature: "config", Resource: "nodes", Verb: "list"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "logsinks", Verb: "list"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "logsinks", Verb: "watch"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "clusterlogsinks", Verb: "list"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "clusterlogsinks", Verb: "watch"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "clusterfiltersets", Verb: "list"},
+		{Feature: "config", Group: "observability.knative.dev", Resource: "clusterfiltersets", Verb: "watch"},
+		{Feature: "namespace globs", Resource: "namespaces", Verb: "list"},
+		{Feature: "namespace globs", Resource: "namespaces", Verb: "watch"},
+		{Feature: "audit logs", Resource: "nodes", Verb: "watch"},
+		{Feature: "service refs", Resource: "services", Verb: "list"},
+		{Feature: "service refs", Resource: "services", Verb: "watch"},
+		{Feature: "headers from secrets", Resource: "secrets", Verb: "get"},
+		{Feature: "headers from secrets", Resource: "secrets", Name: HeaderSecretName, Verb: "update", Namespace: namespace},
+		{Feature: "health", Group: "apps", Resource: "daemonsets", Verb: "get", Namespace: namespace},
+		{Feature: "health", Resource: "pods", Verb: "list", Namespace: namespace},
+	}
+	if features.CircuitBreaker {
+		perms = append(perms,
+			Permission{Feature: "circuit breaker", Group: "observability.knative.dev", Resource: "logsinks", Subresource: "status", Verb: "update"},
+			Permission{Feature: "circuit breaker", Group: "observability.knative.dev", Resource: "clusterlogsinks", Subresource: "status", Verb: "update"},
+		)
+	}
+	if features.NamespaceThrottle {
+		perms = append(perms,
+			Permission{Feature: "namespace throttle", Resource: "namespaces", Verb: "list"},
+			Permission{Feature: "namespace throttle", Resource: "namespaces", Verb: "watch"},
+		)
+	}
+	if features.PriorityClass {
+		perms = append(perms,
+			Permission{Feature: "priority class", Group: "apps", Resource: "daemonsets", Verb: "list", Namespace: namespace},
+			Permission{Feature: "priority class", Group: "apps", Resource: "daemonsets", Verb: "watch", Namespace: namespace},
+			Permission{Feature: "priority class", Group: "apps", Resource: "daemonsets", Verb: "patch", Namespace: namespace},
+			Permission{Feature: "priority class", Group: "scheduling.k8s.io", Resource: "priorityclasses", Verb: "get"},
+		)
+	}
+	if features.Resources {
+		perms = append(perms,
+			Permission{Feature: "daemonset resources", Group: "apps", Resource: "daemonsets", Verb: "list", Namespace: namespace},
+			Permission{Feature: "daemonset resources", Group: "apps", Resource: "daemonsets", Verb: "watch", Namespace: namespace},
+			Permission{Feature: "daemonset resources", Group: "apps", Resource: "daemonsets", Verb: "patch", Namespace: namespace},
+		)
+	}
+	if features.PreStop {
+		perms = append(perms,
+			Permission{Feature: "prestop hook", Group: "apps", Resource: "daemonsets", Verb: "list", Namespace: namespace},
+			Permission{Feature: "prestop hook", Group: "apps", Resource: "daemonsets", Verb: "watch", Namespace: namespace},
+			Permission{Feature: "prestop hook", Group: "apps", Resource: "daemonsets", Verb: "patch", Namespace: namespace},
+		)
+	}
+	if features.Replay {
+		perms = append(perms,
+			Permission{Feature: "replay", Group: "batch", Resource: "jobs", Verb: "get", Namespace: namespace},
+			Permission{Feature: "replay", Group: "batch", Resource: "jobs", Verb: "list", Namespace: namespace},
+			Permission{Feature: "replay", Group: "batch", Resource: "jobs", Verb: "create", Namespace: namespace},
+			Permission{Feature: "replay", Group: "batch", Resource: "jobs", Verb: "delete", Namespace: namespace},
+			Permission{Feature: "replay", Group: "observability.knative.dev", Resource: "logsinks", Verb: "patch"},
+			Permission{Feature: "replay", Group: "observability.knative.dev", Resource: "clusterlogsinks", Verb: "patch"},
+		)
+	}
+	return perms
+}
+
+// CheckPermissions asks the API server whether the controller is allowed
+// each permission and returns the ones that are denied. Every missing
+// permission is logged with the feature that needs it, so an incomplete
+// role shows up at startup instead of as failing requests later. A review
+// that cannot be made is logged and the permission is not reported as
+// missing.
+func CheckPermissions(ar AccessReviewer, perms []Permission) []Permission {
+	var missing []Permission
+	for _, p := range perms {
+		review, err := ar.Create(&authV1.SelfSubjectAccessReview{
+			Spec: authV1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authV1.ResourceAttributes{
+					Namespace:   p.Namespace,
+					Verb:        p.Verb,
+					Group:       p.Group,
+					Resource:    p.Resource,
+					Subresource: p.Subresource,
+					Name:        p.Name,
+				},
+			},
+		})
+		if err != nil {
+			log.Printf("Unable to review permission to %s: %s", p, err)
+			continue
+		}
+		if review.Status.Allowed {
+			continue
+		}
+
+		log.Printf("Missing permission to %s needed by %s", p, p.Feature)
+		missing = append(missing, p)
+	}
+	return missing
+}
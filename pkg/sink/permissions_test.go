@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authV1 "k8s.io/api/authorization/v1"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	perms := []sink.Permission{
+		{Feature: "config", Resource: "configmaps", Verb: "patch", Namespace: "knative-observability"},
+		{Feature: "health", Group: "apps", Resource: "daemonsets", Verb: "get", Namespace: "knative-observability"},
+		{Feature: "circuit breaker", Group: "observability.knative.dev", Resource: "logsinks", Subresource: "status", Verb: "update"},
+		{Feature: "headers from secrets", Resource: "secrets", Name: "fluent-bit-headers", Verb: "update", Namespace: "knative-observability"},
+	}
+
+	t.Run("it returns the denied permissions", func(t *testing.T) {
+		ar := &fakeAccessReviewer{
+			denied: map[string]bool{
+				"daemonsets":      true,
+				"logsinks/status": true,
+			},
+		}
+
+		missing := sink.CheckPermissions(ar, perms)
+
+		if diff := cmp.Diff(perms[1:3], missing); diff != "" {
+			t.Errorf("Missing permissions not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it reviews each permission's attributes", func(t *testing.T) {
+		ar := &fakeAccessReviewer{}
+
+		missing := sink.CheckPermissions(ar, perms)
+
+		if len(missing) != 0 {
+			t.Errorf("Expected no missing permissions, got %v", missing)
+		}
+		expected := []authV1.ResourceAttributes{
+			{Namespace: "knative-observability", Verb: "patch", Resource: "configmaps"},
+			{Namespace: "knative-observability", Verb: "get", Group: "apps", Resource: "daemonsets"},
+			{Verb: "update", Group: "observability.knative.dev", Resource: "logsinks", Subresource: "status"},
+			{Namespace: "knative-observability", Verb: "update", Resource: "secrets", Name: "fluent-bit-headers"},
+		}
+		if diff := cmp.Diff(expected, ar.reviewed); diff != "" {
+			t.Errorf("Reviews not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it does not report permissions it cannot review", func(t *testing.T) {
+		ar := &fakeAccessReviewer{err: errors.New("unavailable")}
+
+		missing := sink.CheckPermissions(ar, perms)
+
+		if len(missing) != 0 {
+			t.Errorf("Expected no missing permissions, got %v", missing)
+		}
+	})
+}
+
+func TestControllerPermissions(t *testing.T) {
+	t.Run("it only requires the permissions of enabled features", func(t *testing.T) {
+		features := func(perms []sink.Permission) map[string]bool {
+			f := make(map[string]bool)
+			for _, p := range perms {
+				f[p.Feature] = true
+			}
+			return f
+		}
+
+		base := features(sink.ControllerPermissions("ns", sink.ControllerFeatures{}))
+		if base["circuit breaker"] || base["namespace throttle"] || base["priority class"] || base["daemonset resources"] ||
+			base["prestop hook"] || base["replay"] {
+			t.Errorf("Expected only base features, got %v", base)
+		}
+
+		all := features(sink.ControllerPermissions("ns", sink.ControllerFeatures{
+			CircuitBreaker:    true,
+			NamespaceThrottle: true,
+			PriorityClass:     true,
+			Resources:         true,
+			PreStop:           true,
+			Replay:            true,
+		}))
+		if !all["circuit breaker"] || !all["namespace throttle"] || !all["priority class"] || !all["daemonset resources"] ||
+			!all["prestop hook"] || !all["replay"] {
+			t.Errorf("Expected every feature, got %v", all)
+		}
+	})
+
+	t.Run("it checks namespaced permissions in the given namespace", func(t *testing.T) {
+		for _, p := range sink.ControllerPermissions("ns", sink.ControllerFeatures{
+			CircuitBreaker:    true,
+			NamespaceThrottle: true,
+			PriorityClass:     true,
+			Resources:         true,
+			PreStop:           true,
+			Replay:            true,
+		}) {
+			if p.Resource == "configmaps" && p.Namespace != "ns" {
+				t.Errorf("Expected configmaps to be checked in ns, got %q", p.Namespace)
+			}
+		}
+	})
+}
+
+type fakeAccessReviewer struct {
+	denied   map[string]bool
+	err      error
+	reviewed []authV1.ResourceAttributes
+}
+
+func (f *fakeAccessReviewer) Create(r *authV1.SelfSubjectAccessReview) (*authV1.SelfSubjectAccessReview, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	attrs := *r.Spec.ResourceAttributes
+	f.reviewed = append(f.reviewed, attrs)
+
+	resource := attrs.Resource
+	if attrs.Subresource != "" {
+		resource += "/" + attrs.Subresource
+	}
+	r.Status.Allowed = !f.denied[resource]
+	return r, nil
+}
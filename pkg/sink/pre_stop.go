@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PreStopController sets a preStop hook on the fluent-bit container that
+// delays its shutdown. The hook only sleeps: fluent-bit has no command to
+// flush its buffers, so the delay gives it time to deliver and retry the
+// chunks it holds on its own flush interval before it is sent SIGTERM.
+// Nothing is flushed that fluent-bit would not have delivered in that time.
+// The fluent-bit image has no shell tools, so the hook runs the sleep the
+// pre-stop-tools init container of the daemonset copies to PreStopSleep.
+// The termination grace period of the pods is raised to cover the delay
+// and the grace fluent-bit waits for its outputs after SIGTERM.
+type PreStopController struct {
+	lifecycle   *coreV1.Lifecycle
+	gracePeriod int64
+	dp          DaemonSetPatcher
+}
+
+// PreStopSleep is the sleep the preStop hook runs, a static busybox copied
+// by the pre-stop-tools init container of the daemonset.
+const PreStopSleep = "/fluent-bit/pre-stop/sleep"
+
+// NewPreStopController returns a controller delaying the shutdown of
+// fluent-bit by delay. Grace is the fluent-bit grace in seconds.
+func NewPreStopController(delay time.Duration, grace int, dp DaemonSetPatcher) *PreStopController {
+	seconds := int64(math.Ceil(delay.Seconds()))
+	return &PreStopController{
+		lifecycle: &coreV1.Lifecycle{
+			PreStop: &coreV1.Handler{
+				Exec: &coreV1.ExecAction{
+					Command: []string{PreStopSleep, strconv.FormatInt(seconds, 10)},
+				},
+			},
+		},
+		gracePeriod: seconds + int64(grace),
+		dp:          dp,
+	}
+}
+
+func (c *PreStopController) OnAdd(o interface{}) {
+	c.reconcile(o)
+}
+
+func (c *PreStopController) OnUpdate(_, n interface{}) {
+	c.reconcile(n)
+}
+
+func (c *PreStopController) OnDelete(o interface{}) {}
+
+// reconcile patches the pod template of the daemonset when the hook of the
+// fluent-bit container differs or its grace period is too short. A longer
+// grace period and a postStart hook are kept.
+func (c *PreStopController) reconcile(o interface{}) {
+	ds, ok := o.(*appsV1.DaemonSet)
+	if !ok || ds.Name != DaemonSetName {
+		return
+	}
+	if !c.differs(ds) {
+		return
+	}
+
+	gracePeriod := c.gracePeriod
+	if current := ds.Spec.Template.Spec.TerminationGracePeriodSeconds; current != nil && *current > gracePeriod {
+		gracePeriod = *current
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"terminationGracePeriodSeconds": gracePeriod,
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":      ContainerName,
+							"lifecycle": c.lifecycle,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Unable to marshal preStop patch: %s", err)
+		return
+	}
+
+	log.Printf("Setting the preStop hook of DaemonSet %s", ds.Name)
+	_, err = c.dp.Patch(ds.Name, types.StrategicMergePatchType, data)
+	if err != nil {
+		log.Printf("Unable to patch DaemonSet %s: %s", ds.Name, err)
+	}
+}
+
+func (c *PreStopController) differs(ds *appsV1.DaemonSet) bool {
+	gracePeriod := ds.Spec.Template.Spec.TerminationGracePeriodSeconds
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		if container.Name != ContainerName {
+			continue
+		}
+		return container.Lifecycle == nil ||
+			!reflect.DeepEqual(container.Lifecycle.PreStop, c.lifecycle.PreStop) ||
+			gracePeriod == nil || *gracePeriod < c.gracePeriod
+	}
+	return false
+}
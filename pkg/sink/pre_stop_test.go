@@ -0,0 +1,183 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestPreStopController(t *testing.T) {
+	daemonSet := func(gracePeriod int64, lifecycle *coreV1.Lifecycle) *appsV1.DaemonSet {
+		return &appsV1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fluent-bit",
+				Namespace: "knative-observability",
+			},
+			Spec: appsV1.DaemonSetSpec{
+				Template: coreV1.PodTemplateSpec{
+					Spec: coreV1.PodSpec{
+						TerminationGracePeriodSeconds: &gracePeriod,
+						Containers: []coreV1.Container{
+							{Name: "fluent-bit", Image: "fluent-bit", Lifecycle: lifecycle},
+							{Name: "rotator", Image: "rotator"},
+						},
+					},
+				},
+			},
+		}
+	}
+	hook := &coreV1.Lifecycle{
+		PreStop: &coreV1.Handler{
+			Exec: &coreV1.ExecAction{Command: []string{sink.PreStopSleep, "15"}},
+		},
+	}
+	patch := func(t *testing.T, ds *appsV1.DaemonSet, data []byte) appsV1.DaemonSet {
+		original, err := json.Marshal(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, data, appsV1.DaemonSet{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got appsV1.DaemonSet
+		if err := json.Unmarshal(patched, &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("it sets the preStop hook of the fluent-bit container", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPreStopController(14500*time.Millisecond, 5, spyPatcher)
+
+		ds := daemonSet(10, nil)
+		c.OnAdd(ds)
+
+		if spyPatcher.name != "fluent-bit" {
+			t.Fatalf("Expected the fluent-bit daemonset to be patched, got %q", spyPatcher.name)
+		}
+		got := patch(t, ds, spyPatcher.data)
+
+		containers := got.Spec.Template.Spec.Containers
+		if len(containers) != 2 || containers[0].Image != "fluent-bit" || containers[1].Name != "rotator" {
+			t.Fatalf("Expected the containers to be kept, got %v", containers)
+		}
+		if diff := cmp.Diff(hook, containers[0].Lifecycle); diff != "" {
+			t.Errorf("Lifecycle not equal (-want, +got) = %v", diff)
+		}
+		if containers[1].Lifecycle != nil {
+			t.Errorf("Expected the rotator to have no hook, got %v", containers[1].Lifecycle)
+		}
+		if gp := got.Spec.Template.Spec.TerminationGracePeriodSeconds; gp == nil || *gp != 20 {
+			t.Errorf("Expected a grace period of 20s, got %v", gp)
+		}
+	})
+
+	t.Run("it keeps a longer grace period", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPreStopController(15*time.Second, 5, spyPatcher)
+
+		ds := daemonSet(60, nil)
+		c.OnAdd(ds)
+
+		got := patch(t, ds, spyPatcher.data)
+		if gp := got.Spec.Template.Spec.TerminationGracePeriodSeconds; gp == nil || *gp != 60 {
+			t.Errorf("Expected a grace period of 60s, got %v", gp)
+		}
+	})
+
+	t.Run("it does not patch a daemonset with the hook", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPreStopController(15*time.Second, 5, spyPatcher)
+
+		c.OnAdd(daemonSet(20, hook))
+		c.OnUpdate(daemonSet(10, nil), daemonSet(30, hook))
+
+		if spyPatcher.patchCalled != 0 {
+			t.Errorf("Expected no patches, got %d", spyPatcher.patchCalled)
+		}
+	})
+
+	t.Run("it sets the hook again when the daemonset is changed", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPreStopController(15*time.Second, 5, spyPatcher)
+
+		c.OnUpdate(daemonSet(20, hook), daemonSet(20, nil))
+		c.OnUpdate(daemonSet(20, hook), daemonSet(10, hook))
+
+		if spyPatcher.patchCalled != 2 {
+			t.Errorf("Expected the daemonset to be patched twice, got %d", spyPatcher.patchCalled)
+		}
+	})
+
+	t.Run("it runs a sleep the deployed daemonset provides", func(t *testing.T) {
+		f, err := os.Open("../../config/500-fluent-bit-daemon.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var ds appsV1.DaemonSet
+		if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&ds); err != nil {
+			t.Fatal(err)
+		}
+
+		volume := func(mounts []coreV1.VolumeMount, dir string) string {
+			for _, m := range mounts {
+				if m.MountPath == dir {
+					return m.Name
+				}
+			}
+			return ""
+		}
+		var mounted string
+		for _, c := range ds.Spec.Template.Spec.Containers {
+			if c.Name == "fluent-bit" {
+				mounted = volume(c.VolumeMounts, path.Dir(sink.PreStopSleep))
+			}
+		}
+		if mounted == "" {
+			t.Fatalf("Expected fluent-bit to mount %s", path.Dir(sink.PreStopSleep))
+		}
+
+		for _, c := range ds.Spec.Template.Spec.InitContainers {
+			for _, m := range c.VolumeMounts {
+				if m.Name != mounted {
+					continue
+				}
+				expected := []string{"cp", "/bin/busybox", path.Join(m.MountPath, path.Base(sink.PreStopSleep))}
+				if !cmp.Equal(c.Command, expected) {
+					t.Errorf("Expected %s to copy the sleep with %v, got %v", c.Name, expected, c.Command)
+				}
+				return
+			}
+		}
+		t.Errorf("Expected an init container to copy the sleep to volume %s", mounted)
+	})
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"log"
+
+	appsV1 "k8s.io/api/apps/v1"
+	schedulingV1beta1 "k8s.io/api/scheduling/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type PriorityClassGetter interface {
+	Get(name string, options metav1.GetOptions) (*schedulingV1beta1.PriorityClass, error)
+}
+
+type DaemonSetPatcher interface {
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*appsV1.DaemonSet, error)
+}
+
+// CheckPriorityClass logs a warning when the priority class does not
+// exist. The daemonset cannot create pods with a missing class, so the
+// class is still set and its pods are created once the class exists.
+func CheckPriorityClass(pcg PriorityClassGetter, name string) {
+	_, err := pcg.Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		log.Printf("PriorityClass %s does not exist, fluent-bit pods are not created until it does", name)
+		return
+	}
+	if err != nil {
+		log.Printf("Unable to get PriorityClass %s: %s", name, err)
+	}
+}
+
+// PriorityClassController sets the priority class of the fluent-bit
+// daemonset, so its pods are not preempted under node pressure. The class
+// is set again whenever the daemonset is changed to another one.
+type PriorityClassController struct {
+	name string
+	dp   DaemonSetPatcher
+}
+
+func NewPriorityClassController(name string, dp DaemonSetPatcher) *PriorityClassController {
+	return &PriorityClassController{
+		name: name,
+		dp:   dp,
+	}
+}
+
+func (c *PriorityClassController) OnAdd(o interface{}) {
+	c.reconcile(o)
+}
+
+func (c *PriorityClassController) OnUpdate(_, n interface{}) {
+	c.reconcile(n)
+}
+
+func (c *PriorityClassController) OnDelete(o interface{}) {}
+
+// reconcile patches the pod template of the daemonset when its priority
+// class differs. The daemonset rolls its pods to the new class.
+func (c *PriorityClassController) reconcile(o interface{}) {
+	ds, ok := o.(*appsV1.DaemonSet)
+	if !ok || ds.Name != DaemonSetName {
+		return
+	}
+	if ds.Spec.Template.Spec.PriorityClassName == c.name {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"priorityClassName": c.name,
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Unable to marshal priority class patch: %s", err)
+		return
+	}
+
+	log.Printf("Setting the priority class of DaemonSet %s to %s", ds.Name, c.name)
+	_, err = c.dp.Patch(ds.Name, types.StrategicMergePatchType, data)
+	if err != nil {
+		log.Printf("Unable to patch DaemonSet %s: %s", ds.Name, err)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestPriorityClassController(t *testing.T) {
+	daemonSet := func(name, class string) *appsV1.DaemonSet {
+		return &appsV1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "knative-observability",
+			},
+			Spec: appsV1.DaemonSetSpec{
+				Template: coreV1.PodTemplateSpec{
+					Spec: coreV1.PodSpec{
+						PriorityClassName: class,
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("it sets the priority class of the daemonset", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPriorityClassController("system-node-critical", spyPatcher)
+
+		ds := daemonSet("fluent-bit", "")
+		c.OnAdd(ds)
+
+		if spyPatcher.name != "fluent-bit" {
+			t.Fatalf("Expected the fluent-bit daemonset to be patched, got %q", spyPatcher.name)
+		}
+		if spyPatcher.pt != types.StrategicMergePatchType {
+			t.Errorf("Expected a strategic merge patch, got %s", spyPatcher.pt)
+		}
+
+		original, err := json.Marshal(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, spyPatcher.data, appsV1.DaemonSet{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got appsV1.DaemonSet
+		if err := json.Unmarshal(patched, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Spec.Template.Spec.PriorityClassName != "system-node-critical" {
+			t.Errorf("Expected the priority class to be set, got %q", got.Spec.Template.Spec.PriorityClassName)
+		}
+	})
+
+	t.Run("it sets the class again when the daemonset is changed", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPriorityClassController("system-node-critical", spyPatcher)
+
+		c.OnUpdate(
+			daemonSet("fluent-bit", "system-node-critical"),
+			daemonSet("fluent-bit", "other-class"),
+		)
+
+		if spyPatcher.patchCalled != 1 {
+			t.Errorf("Expected the daemonset to be patched once, got %d", spyPatcher.patchCalled)
+		}
+	})
+
+	t.Run("it does not patch a daemonset with the class", func(t *testing.T) {
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewPriorityClassController("system-node-critical", spyPatcher)
+
+		c.OnAdd(daemonSet("fluent-bit", "system-node-critical"))
+		c.OnAdd(daemonSet("other-daemonset", ""))
+
+		if spyPatcher.patchCalled != 0 {
+			t.Errorf("Expected no patches, got %d", spyPatcher.patchCalled)
+		}
+	})
+}
+
+type spyDaemonSetPatcher struct {
+	patchCalled int
+	name        string
+	pt          types.PatchType
+	data        []byte
+}
+
+func (s *spyDaemonSetPatcher) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (*appsV1.DaemonSet, error) {
+	s.patchCalled++
+	s.name = name
+	s.pt = pt
+	s.data = data
+	return nil, nil
+}
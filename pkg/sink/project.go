@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// projected returns whether the sink reads its own copy of the records to
+// remove keys from. Raw mode and audit sinks already read their own
+// records.
+func projected(ref sinkRef) bool {
+	return ref.spec.Type == "webhook" && !ref.spec.RawMode && !audited(ref) && len(projectKeys(ref.spec)) > 0
+}
+
+// projectKeys returns the only keys the sink sends, which are listed by
+// Project or by a strict Schema.
+func projectKeys(spec v1alpha1.SinkSpec) []string {
+	if len(spec.Project) > 0 {
+		return spec.Project
+	}
+	if spec.Schema != nil && spec.Schema.Strict {
+		return spec.Schema.AllowedKeys
+	}
+	return nil
+}
+
+// projectTag is the tag of the copies of the records read by a projected
+// sink.
+func projectTag(ref sinkRef) string {
+	if ref.cluster {
+		return fmt.Sprintf("project.cluster.%s", ref.name)
+	}
+	if ref.glob {
+		return fmt.Sprintf("project.glob.%s.%s", ref.name, ref.namespace)
+	}
+	return fmt.Sprintf("project.ns.%s.%s", canonicalNamespace(ref.namespace), ref.name)
+}
+
+// projectFilterConfig removes every key but keys from the records matching
+// match. It is rendered after the sink's other filters since they may read
+// keys that are not projected.
+func projectFilterConfig(match string, keys []string) string {
+	config := fmt.Sprintf("\n[FILTER]\n    Name record_modifier\n    Match %s\n", match)
+	for _, k := range keys {
+		config += fmt.Sprintf("    Whitelist_key %s\n", k)
+	}
+	return config
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestProject(t *testing.T) {
+	webhookSink := func(name string, project ...string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/" + name,
+				},
+				Project: project,
+			},
+		}
+	}
+
+	t.Run("it only keeps the projected keys in the sink's copy", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(webhookSink("all-keys"))
+		sc.UpsertSink(webhookSink("projected", "log", "kubernetes"))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* project.ns.ns1.projected true
+    Emitter_Name ns1/projected:project
+
+[FILTER]
+    Name record_modifier
+    Match project.ns.ns1.projected
+    Whitelist_key log
+    Whitelist_key kubernetes
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/all-keys
+    Format json
+    Host example.com
+    Port 443
+    URI /all-keys
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match project.ns.ns1.projected
+    Alias ns1/projected
+    Format json
+    Host example.com
+    Port 443
+    URI /projected
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it projects after the sink's own filters", func(t *testing.T) {
+		sc := sink.NewConfig()
+		s := webhookSink("projected", "log")
+		s.Spec.StartupDelay = &metav1.Duration{Duration: 5e9}
+		s.Spec.StartupRate = 10
+		sc.UpsertSink(s)
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* project.ns.ns1.projected true
+    Emitter_Name ns1/projected:project
+
+[FILTER]
+    Name lua
+    Match project.ns.ns1.projected
+    script /fluent-bit/etc/sinks.lua
+    call sink_0
+
+[FILTER]
+    Name record_modifier
+    Match project.ns.ns1.projected
+    Whitelist_key log
+`
+		config := sc.String()
+		if diff := cmp.Diff(expected, config[:len(expected)]); diff != "" {
+			t.Errorf("Filters not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it copies container records for cluster sinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "projected",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/cluster",
+				},
+				Project: []string{"log"},
+			},
+		})
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* project.cluster.projected true
+    Emitter_Name cluster:projected:project
+`
+		config := sc.String()
+		if diff := cmp.Diff(expected, config[:len(expected)]); diff != "" {
+			t.Errorf("Filters not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it projects the records of raw mode sinks in place", func(t *testing.T) {
+		sc := sink.NewConfig()
+		s := webhookSink("raw", "log")
+		s.Spec.RawMode = true
+		sc.UpsertSink(s)
+
+		config := sc.String()
+		expected := `
+[FILTER]
+    Name record_modifier
+    Match raw.ns.ns1.raw
+    Whitelist_key log
+`
+		if !strings.Contains(config, expected) {
+			t.Errorf("Expected the raw records to be projected, got %s", config)
+		}
+		if strings.Contains(config, "rewrite_tag") {
+			t.Errorf("Expected raw records not to be copied, got %s", config)
+		}
+	})
+}
+
+func TestSchema(t *testing.T) {
+	schemaSink := func(strict bool, keys ...string) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "schema",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/schema",
+				},
+				Schema: &v1alpha1.SchemaSpec{
+					AllowedKeys: keys,
+					Strict:      strict,
+				},
+			},
+		}
+	}
+
+	t.Run("it strips the keys a strict schema does not allow", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(schemaSink(true, "log", "stream"))
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_ns1_*
+    Rule $log .* project.ns.ns1.schema true
+    Emitter_Name ns1/schema:project
+
+[FILTER]
+    Name record_modifier
+    Match project.ns.ns1.schema
+    Whitelist_key log
+    Whitelist_key stream
+
+[OUTPUT]
+    Name http
+    Match project.ns.ns1.schema
+    Alias ns1/schema
+    Format json
+    Host example.com
+    Port 443
+    URI /schema
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it passes every key with a lenient schema", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(schemaSink(false, "log", "stream"))
+
+		config := sc.String()
+		if strings.Contains(config, "record_modifier") || strings.Contains(config, "rewrite_tag") {
+			t.Errorf("Expected the records to pass unchanged, got %s", config)
+		}
+		if !strings.Contains(config, "Match *_ns1_*") {
+			t.Errorf("Expected the sink to read the namespace's records, got %s", config)
+		}
+	})
+}
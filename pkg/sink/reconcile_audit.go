@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"time"
+)
+
+// The decisions a reconcile makes about the config.
+const (
+	// DecisionApplied is a config written to the configmap.
+	DecisionApplied = "applied"
+	// DecisionHeld is a config not written while the reconciles are
+	// paused.
+	DecisionHeld = "held"
+	// DecisionRenderFailed is a config that could not be rendered, so the
+	// last config is kept.
+	DecisionRenderFailed = "render_failed"
+	// DecisionPatchFailed is a config the configmap could not be patched
+	// with.
+	DecisionPatchFailed = "patch_failed"
+	// DecisionRestarted is a restart of fluent-bit to read a change that
+	// is not in the configmap, e.g. of the parsers.
+	DecisionRestarted = "restarted"
+)
+
+// ReconcileDecision is a record of the audit stream. Reason is the change
+// that caused the reconcile and Generation is the generation of the last
+// config written to the configmap.
+type ReconcileDecision struct {
+	Time       time.Time `json:"time"`
+	Reason     string    `json:"reason"`
+	Decision   string    `json:"decision"`
+	Generation int64     `json:"generation"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// WithReconcileAudit writes a ReconcileDecision for every reconcile of the
+// config and every other restart of fluent-bit to w as a line of JSON.
+// Records are only appended, so w is expected to be a file opened for
+// appending on a volume that outlives the sink-controller's container.
+func WithReconcileAudit(w io.Writer) ConfigOpt {
+	return func(sc *Config) {
+		sc.audit = json.NewEncoder(w)
+	}
+}
+
+// auditReconcile writes the decision of a reconcile to the audit stream.
+func (sc *Config) auditReconcile(reason, decision string, err error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.audit == nil {
+		return
+	}
+
+	d := ReconcileDecision{
+		Time:       time.Now(),
+		Reason:     reason,
+		Decision:   decision,
+		Generation: sc.generation,
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if err := sc.audit.Encode(d); err != nil {
+		log.Printf("Unable to write reconcile audit: %s", err)
+	}
+}
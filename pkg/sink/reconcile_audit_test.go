@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestReconcileAudit(t *testing.T) {
+	auditedSink := func(port int) *v1alpha1.LogSink {
+		return &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-sink",
+				Namespace: "ns1",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: port,
+				},
+			},
+		}
+	}
+
+	t.Run("it records the decision of every reconcile", func(t *testing.T) {
+		var audit bytes.Buffer
+		sc := sink.NewConfig(sink.WithReconcileAudit(&audit))
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+		c := sink.NewController(spyPatcher, spyDeleter, sc)
+		cc := sink.NewClusterController(spyPatcher, spyDeleter, sc)
+		p := sink.NewPauser(spyPatcher, spyDeleter, sc)
+
+		c.OnAdd(auditedSink(12345))
+		c.OnUpdate(auditedSink(12345), auditedSink(23456))
+		p.Toggle()
+		c.OnDelete(auditedSink(23456))
+		p.Toggle()
+		cc.OnAdd(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+			Spec:       auditedSink(12345).Spec,
+		})
+
+		expected := []sink.ReconcileDecision{
+			{Reason: "LogSink ns1/some-sink upserted", Decision: sink.DecisionApplied, Generation: 1},
+			{Reason: "LogSink ns1/some-sink upserted", Decision: sink.DecisionApplied, Generation: 2},
+			{Reason: "LogSink ns1/some-sink deleted", Decision: sink.DecisionHeld, Generation: 2},
+			{Reason: "Reconciles resumed", Decision: sink.DecisionApplied, Generation: 3},
+			{Reason: "ClusterLogSink cluster-sink upserted", Decision: sink.DecisionApplied, Generation: 4},
+		}
+		if diff := cmp.Diff(expected, decodeDecisions(t, &audit)); diff != "" {
+			t.Errorf("Decisions not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it records why a config was not written", func(t *testing.T) {
+		var audit bytes.Buffer
+		sc := sink.NewConfig(
+			sink.WithReconcileAudit(&audit),
+			sink.WithPostRenderHook(failingHook{}),
+		)
+		sink.NewController(&spyConfigMapPatcher{}, &spyDaemonSetPodDeleter{}, sc).OnAdd(auditedSink(12345))
+
+		sc = sink.NewConfig(sink.WithReconcileAudit(&audit))
+		sink.NewController(failingConfigMapPatcher{}, &spyDaemonSetPodDeleter{}, sc).OnAdd(auditedSink(12345))
+
+		expected := []sink.ReconcileDecision{
+			{
+				Reason:   "LogSink ns1/some-sink upserted",
+				Decision: sink.DecisionRenderFailed,
+				Error:    "post-render hook failed: unavailable",
+			},
+			{
+				Reason:   "LogSink ns1/some-sink upserted",
+				Decision: sink.DecisionPatchFailed,
+				Error:    "forbidden",
+			},
+		}
+		if diff := cmp.Diff(expected, decodeDecisions(t, &audit)); diff != "" {
+			t.Errorf("Decisions not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it records every restart of fluent-bit", func(t *testing.T) {
+		var audit bytes.Buffer
+		sc := sink.NewConfig(sink.WithReconcileAudit(&audit))
+		spyPatcher := &spyConfigMapPatcher{}
+		spyDeleter := &spyDaemonSetPodDeleter{}
+
+		sink.SetClusterNameFilter(spyPatcher, spyDeleter, sc, "some-cluster")
+		sink.SetInputParser(spyPatcher, spyDeleter, sc, sink.DockerParser, false, 0)
+		sink.SetServiceConfig(failingConfigMapPatcher{}, spyDeleter, sc, 1, 5, "")
+		sink.NewParsersController("fluent-bit-parsers", spyDeleter, sc).OnUpdate(
+			&coreV1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit-parsers"},
+				Data:       map[string]string{"custom-parsers.conf": "old"},
+			},
+			&coreV1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "fluent-bit-parsers"},
+				Data:       map[string]string{"custom-parsers.conf": "new"},
+			},
+		)
+
+		expected := []sink.ReconcileDecision{
+			{Reason: "Cluster name set", Decision: sink.DecisionApplied},
+			{Reason: "Container logs input set", Decision: sink.DecisionApplied},
+			{Reason: "Service config set", Decision: sink.DecisionPatchFailed, Error: "forbidden"},
+			{Reason: "Parsers in ConfigMap fluent-bit-parsers changed", Decision: sink.DecisionRestarted},
+		}
+		if diff := cmp.Diff(expected, decodeDecisions(t, &audit)); diff != "" {
+			t.Errorf("Decisions not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+// decodeDecisions decodes the lines of the audit stream without their
+// times.
+func decodeDecisions(t *testing.T, audit *bytes.Buffer) []sink.ReconcileDecision {
+	var decisions []sink.ReconcileDecision
+	dec := json.NewDecoder(audit)
+	for dec.More() {
+		var d sink.ReconcileDecision
+		if err := dec.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Time.IsZero() {
+			t.Errorf("Expected the decision to have a time, got %+v", d)
+		}
+		decisions = append(decisions, sink.ReconcileDecision{
+			Reason:     d.Reason,
+			Decision:   d.Decision,
+			Generation: d.Generation,
+			Error:      d.Error,
+		})
+	}
+	return decisions
+}
+
+type failingHook struct{}
+
+func (failingHook) Mutate(string) (string, error) {
+	return "", errors.New("unavailable")
+}
+
+type failingConfigMapPatcher struct{}
+
+func (failingConfigMapPatcher) Patch(string, types.PatchType, []byte, ...string) (*coreV1.ConfigMap, error) {
+	return nil, errors.New("forbidden")
+}
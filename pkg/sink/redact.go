@@ -0,0 +1,142 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// redactPrelude defines redact, which applies a redaction method to a
+// value. Hashed values are the hex SHA-256 of the value, so records of the
+// same value can still be joined. Masked values keep their last four bytes
+// and have every other byte replaced with *. Numbers are hashed and masked
+// as their text and other values are dropped, since they have no text to
+// hash or mask. Fluent-bit runs LuaJIT, whose bit library works on signed
+// 32 bit integers.
+const redactPrelude = `
+local bit = require("bit")
+
+local sha256_k = {
+    0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1, 0x923f82a4, 0xab1c5ed5,
+    0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3, 0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174,
+    0xe49b69c1, 0xefbe4786, 0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+    0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147, 0x06ca6351, 0x14292967,
+    0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13, 0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85,
+    0xa2bfe8a1, 0xa81a664b, 0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+    0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a, 0x5b9cca4f, 0x682e6ff3,
+    0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208, 0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+local function sha256(s)
+    local band, bor, bxor, bnot = bit.band, bit.bor, bit.bxor, bit.bnot
+    local rshift, lshift, ror, tobit = bit.rshift, bit.lshift, bit.ror, bit.tobit
+
+    local n = #s
+    local bits = n * 8
+    s = s .. "\128" .. string.rep("\0", (55 - n) % 64) .. "\0\0\0\0" ..
+        string.char(
+            band(rshift(bits, 24), 255),
+            band(rshift(bits, 16), 255),
+            band(rshift(bits, 8), 255),
+            band(bits, 255))
+
+    local h = {
+        tobit(0x6a09e667), tobit(0xbb67ae85), tobit(0x3c6ef372), tobit(0xa54ff53a),
+        tobit(0x510e527f), tobit(0x9b05688c), tobit(0x1f83d9ab), tobit(0x5be0cd19),
+    }
+    local w = {}
+    for chunk = 1, #s, 64 do
+        for i = 0, 15 do
+            local b1, b2, b3, b4 = s:byte(chunk + i * 4, chunk + i * 4 + 3)
+            w[i] = bor(lshift(b1, 24), lshift(b2, 16), lshift(b3, 8), b4)
+        end
+        for i = 16, 63 do
+            local w15, w2 = w[i - 15], w[i - 2]
+            local s0 = bxor(ror(w15, 7), ror(w15, 18), rshift(w15, 3))
+            local s1 = bxor(ror(w2, 17), ror(w2, 19), rshift(w2, 10))
+            w[i] = tobit(w[i - 16] + s0 + w[i - 7] + s1)
+        end
+
+        local a, b, c, d, e, f, g, hh = h[1], h[2], h[3], h[4], h[5], h[6], h[7], h[8]
+        for i = 0, 63 do
+            local s1 = bxor(ror(e, 6), ror(e, 11), ror(e, 25))
+            local ch = bxor(band(e, f), band(bnot(e), g))
+            local t1 = tobit(hh + s1 + ch + sha256_k[i + 1] + w[i])
+            local s0 = bxor(ror(a, 2), ror(a, 13), ror(a, 22))
+            local maj = bxor(band(a, b), band(a, c), band(b, c))
+            local t2 = tobit(s0 + maj)
+            hh, g, f, e, d, c, b, a = g, f, e, tobit(d + t1), c, b, a, tobit(t1 + t2)
+        end
+
+        local v = {a, b, c, d, e, f, g, hh}
+        for i = 1, 8 do
+            h[i] = tobit(h[i] + v[i])
+        end
+    end
+
+    local hex = {}
+    for i = 1, 8 do
+        hex[i] = bit.tohex(h[i])
+    end
+    return table.concat(hex)
+end
+
+local function redact(value, method)
+    if method == "drop" then
+        return nil
+    end
+    if type(value) == "number" then
+        value = tostring(value)
+    elseif type(value) ~= "string" then
+        return nil
+    end
+
+    if method == "hash" then
+        return sha256(value)
+    end
+    if #value <= 4 then
+        return string.rep("*", #value)
+    end
+    return string.rep("*", #value - 4) .. value:sub(-4)
+end
+`
+
+// redactLua applies the redaction rules to the fields of each record. It
+// runs before the steps that read fields to drop or coalesce records, so
+// that no step sees the values.
+func redactLua(name string, rules []v1alpha1.RedactRule) luaStep {
+	quoted := make([]string, 0, len(rules))
+	for _, r := range rules {
+		quoted = append(quoted, fmt.Sprintf("{%s, %q}", luaFieldPath(r.Field), r.Method))
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {%s}\n", name, strings.Join(quoted, ", ")),
+		body: fmt.Sprintf(`
+    for _, rule in ipairs(%s) do
+        local path = rule[1]
+        local parent, key = field_parent(record, path), path[#path]
+        if parent ~= nil and parent[key] ~= nil then
+            parent[key] = redact(parent[key], rule[2])
+            code = 1
+        end
+    end
+`, name),
+	}
+}
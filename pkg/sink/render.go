@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// RenderManifests renders the fluent-bit config of the LogSinks,
+// ClusterLogSinks and ClusterFilterSets in the manifests at path without a
+// cluster. Namespace globs are matched against the Namespaces in the
+// manifests. path is a manifest file or a directory of .yaml, .yml and .json
+// files, which may hold several documents. Other kinds are ignored so that
+// a directory of a whole application can be rendered. The outputs config is
+// followed by the Lua script when any sink has one.
+func RenderManifests(path string, opts ...ConfigOpt) (string, error) {
+	files, err := manifestFiles(path)
+	if err != nil {
+		return "", err
+	}
+
+	sc := NewConfig(opts...)
+	for _, f := range files {
+		err := loadManifest(sc, f)
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", f, err)
+		}
+	}
+
+	patches, err := sc.patches()
+	if err != nil {
+		return "", err
+	}
+
+	var rendered string
+	for _, p := range patches {
+		if p.Value == "" {
+			continue
+		}
+		rendered += fmt.Sprintf("# %s\n%s\n", filepath.Base(p.Path), p.Value)
+	}
+	return rendered, nil
+}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			if !e.IsDir() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func loadManifest(sc *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d := yaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		var doc runtime.RawExtension
+		err := d.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(doc.Raw) == 0 || string(doc.Raw) == "null" {
+			continue
+		}
+
+		err = loadObject(sc, doc.Raw)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func loadObject(sc *Config, raw []byte) error {
+	var tm metav1.TypeMeta
+	err := json.Unmarshal(raw, &tm)
+	if err != nil {
+		return err
+	}
+
+	switch tm.Kind {
+	case "LogSink":
+		var s v1alpha1.LogSink
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		if s.Namespace == "" {
+			s.Namespace = "default"
+		}
+		sc.UpsertSink(&s)
+	case "ClusterLogSink":
+		var s v1alpha1.ClusterLogSink
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		sc.UpsertClusterSink(&s)
+	case "ClusterFilterSet":
+		var fs v1alpha1.ClusterFilterSet
+		if err := json.Unmarshal(raw, &fs); err != nil {
+			return err
+		}
+		sc.UpsertFilterSet(&fs)
+	case "Namespace":
+		var ns coreV1.Namespace
+		if err := json.Unmarshal(raw, &ns); err != nil {
+			return err
+		}
+		sc.SetNamespace(ns.Name, true)
+	}
+	return nil
+}
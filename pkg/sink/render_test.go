@@ -0,0 +1,87 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the render tests")
+
+func TestRenderManifests(t *testing.T) {
+	var goldenTests = []struct {
+		name string
+		path string
+	}{
+		{"a directory of sinks", "testdata/render/sinks"},
+		{"a file with a filter set", "testdata/render/filter-set/manifests.yaml"},
+	}
+
+	for _, test := range goldenTests {
+		t.Run("it renders "+test.name, func(t *testing.T) {
+			rendered, err := sink.RenderManifests(test.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			golden := filepath.Join("testdata/render", filepath.Base(test.path)+".golden")
+			if *updateGolden {
+				err := ioutil.WriteFile(golden, []byte(rendered), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(expected), rendered); diff != "" {
+				t.Errorf("Rendered config not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+
+	t.Run("it returns an error for invalid manifests", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "render")
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "invalid.yaml")
+		err = ioutil.WriteFile(path, []byte("kind: LogSink\nspec: [\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = sink.RenderManifests(path)
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("it returns an error for a missing path", func(t *testing.T) {
+		_, err := sink.RenderManifests("testdata/render/missing")
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
@@ -0,0 +1,386 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+const (
+	// ReplayAnnotation triggers a replay of the chunks fluent-bit buffered
+	// on disk to a sink when it is set to a value the sink was not replayed
+	// for yet, e.g. the time of the outage.
+	ReplayAnnotation = "observability.knative.dev/replay"
+
+	// ReplayedAnnotation is the marker of the last replay value the jobs of
+	// a sink were created for. A value is only replayed once, even when the
+	// controller restarts.
+	ReplayedAnnotation = "observability.knative.dev/replayed"
+
+	// ReplayAppLabel is the app label of the replay pods. It differs from
+	// the fluent-bit daemonset's, so they are not restarted with it.
+	ReplayAppLabel = "fluent-bit-replay"
+)
+
+// replayJobTTL is how long a finished replay job is kept on clusters that
+// delete finished jobs. Elsewhere the finished jobs of a sink are deleted
+// by its next replay.
+const replayJobTTL = time.Hour
+
+// sinkAnnotation is the key of the sink a replay job replays to.
+const sinkAnnotation = "observability.knative.dev/sink"
+
+const replayServiceConfig = `[SERVICE]
+    Flush         1
+    Grace         5
+    Log_Level     warning
+    Daemon        off
+    storage.path  /replay/storage/
+    storage.backlog.mem_limit 5M
+
+@INCLUDE outputs.conf
+`
+
+type JobClient interface {
+	Get(name string, options metav1.GetOptions) (*batchV1.Job, error)
+	List(options metav1.ListOptions) (*batchV1.JobList, error)
+	Create(*batchV1.Job) (*batchV1.Job, error)
+	Delete(name string, options *metav1.DeleteOptions) error
+}
+
+// ReplayOptions configure the jobs that replay the chunks buffered in
+// StoragePath on every node.
+type ReplayOptions struct {
+	Namespace      string
+	StoragePath    string
+	FluentBitImage string
+	// CopyImage is the image that copies the buffered chunks before they
+	// are replayed, which needs cp.
+	CopyImage string
+	// Duration is how long a replay runs before its job is stopped.
+	Duration time.Duration
+}
+
+// ReplayController creates a job on every node that replays the chunks
+// buffered on disk to a sink when the sink's replay annotation is set. The
+// chunks are copied first, so the replay does not touch the buffer of the
+// running fluent-bit pod, and records still buffered there may be
+// delivered twice. Chunks of other sinks are not delivered by the replay.
+type ReplayController struct {
+	opts ReplayOptions
+	jc   JobClient
+	cmc  ConfigMapCreator
+	ssg  SinkStatusGetter
+	sc   *Config
+}
+
+func NewReplayController(
+	opts ReplayOptions,
+	jc JobClient,
+	cmc ConfigMapCreator,
+	ssg SinkStatusGetter,
+	sc *Config,
+) *ReplayController {
+	return &ReplayController{
+		opts: opts,
+		jc:   jc,
+		cmc:  cmc,
+		ssg:  ssg,
+		sc:   sc,
+	}
+}
+
+func (c *ReplayController) OnAdd(o interface{}) {
+	c.reconcile(o)
+}
+
+func (c *ReplayController) OnUpdate(_, n interface{}) {
+	c.reconcile(n)
+}
+
+func (c *ReplayController) OnDelete(o interface{}) {}
+
+// reconcile creates the replay jobs of a sink whose replay annotation is
+// not marked as replayed and then marks it. A failed replay is retried on
+// the next update of the sink.
+func (c *ReplayController) reconcile(o interface{}) {
+	var (
+		meta metav1.ObjectMeta
+		k    string
+		obj  runtime.Object
+	)
+	switch s := o.(type) {
+	case *v1alpha1.LogSink:
+		meta, k, obj = s.ObjectMeta, key(s), s
+	case *v1alpha1.ClusterLogSink:
+		meta, k, obj = s.ObjectMeta, clusterKey(s), s
+	default:
+		return
+	}
+
+	token := meta.Annotations[ReplayAnnotation]
+	if token == "" || token == meta.Annotations[ReplayedAnnotation] {
+		return
+	}
+
+	if err := c.replay(k, token, obj); err != nil {
+		log.Printf("Unable to replay the buffered chunks to %s: %s", k, err)
+		return
+	}
+
+	// The replay annotation is set, so the annotations exist and the
+	// marker can be added to them.
+	data, err := json.Marshal([]patch{{
+		Op:    "add",
+		Path:  "/metadata/annotations/" + strings.Replace(ReplayedAnnotation, "/", "~1", -1),
+		Value: token,
+	}})
+	if err != nil {
+		log.Printf("Unable to marshal replay marker: %s", err)
+		return
+	}
+	switch obj.(type) {
+	case *v1alpha1.LogSink:
+		_, err = c.ssg.LogSinks(meta.Namespace).Patch(meta.Name, types.JSONPatchType, data)
+	case *v1alpha1.ClusterLogSink:
+		_, err = c.ssg.ClusterLogSinks(meta.Namespace).Patch(meta.Name, types.JSONPatchType, data)
+	}
+	if err != nil {
+		log.Printf("Unable to mark %s as replayed: %s", k, err)
+	}
+}
+
+// replay creates the job of every node and the configmap of their config,
+// which is owned by the jobs and deleted with them. Jobs are named after
+// the sink, the replay value and the node, so a job that already exists was
+// created by an earlier attempt of the same replay and is kept.
+func (c *ReplayController) replay(k, token string, obj runtime.Object) error {
+	f, err := c.sc.replayFragment(obj)
+	if err != nil {
+		return err
+	}
+
+	nodes := c.sc.nodeNames()
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes to replay the chunks of")
+	}
+
+	c.deleteFinishedJobs(k, token)
+
+	configName := replayName(k, token) + "-config"
+	var owners []metav1.OwnerReference
+	for _, node := range nodes {
+		name := replayName(k, token, node)
+		job, err := c.jc.Create(c.job(name, configName, k, token, node))
+		if errors.IsAlreadyExists(err) {
+			job, err = c.jc.Get(name, metav1.GetOptions{})
+		} else if err == nil {
+			log.Printf("Replaying the buffered chunks of node %s to %s", node, k)
+		}
+		if err != nil {
+			return err
+		}
+		owners = append(owners, metav1.OwnerReference{
+			APIVersion: batchV1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+			Name:       job.Name,
+			UID:        job.UID,
+		})
+	}
+
+	_, err = c.cmc.Create(&coreV1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            configName,
+			Namespace:       c.opts.Namespace,
+			Labels:          map[string]string{"app": ReplayAppLabel},
+			OwnerReferences: owners,
+		},
+		Data: map[string]string{
+			"fluent-bit.conf": replayServiceConfig,
+			"outputs.conf":    f.Config,
+			luaScriptName:     f.Script,
+		},
+	})
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteFinishedJobs deletes the finished jobs of the earlier replays of a
+// sink, which deletes their configmaps with them. Jobs that cannot be
+// listed or deleted are logged and deleted by the next replay.
+func (c *ReplayController) deleteFinishedJobs(k, token string) {
+	jobs, err := c.jc.List(metav1.ListOptions{
+		LabelSelector: "app=" + ReplayAppLabel,
+	})
+	if err != nil {
+		log.Printf("Unable to list the replay jobs of %s: %s", k, err)
+		return
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	for _, j := range jobs.Items {
+		if j.Annotations[sinkAnnotation] != k || j.Annotations[ReplayAnnotation] == token || !jobFinished(j) {
+			continue
+		}
+		err := c.jc.Delete(j.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !errors.IsNotFound(err) {
+			log.Printf("Unable to delete replay job %s of %s: %s", j.Name, k, err)
+		}
+	}
+}
+
+// jobFinished returns whether a job completed or failed.
+func jobFinished(j batchV1.Job) bool {
+	for _, c := range j.Status.Conditions {
+		if (c.Type == batchV1.JobComplete || c.Type == batchV1.JobFailed) && c.Status == coreV1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// job returns the job replaying the chunks buffered on node. It is stopped
+// after the replay duration, since fluent-bit does not exit once its
+// backlog is delivered.
+func (c *ReplayController) job(name, configName, k, token, node string) *batchV1.Job {
+	deadline := int64(c.opts.Duration.Seconds())
+	ttl := int32(replayJobTTL.Seconds())
+	var backoffLimit int32 = 2
+	optional := true
+	return &batchV1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.opts.Namespace,
+			Labels:    map[string]string{"app": ReplayAppLabel},
+			Annotations: map[string]string{
+				sinkAnnotation:   k,
+				ReplayAnnotation: token,
+			},
+		},
+		Spec: batchV1.JobSpec{
+			ActiveDeadlineSeconds:   &deadline,
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: coreV1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": ReplayAppLabel},
+				},
+				Spec: coreV1.PodSpec{
+					NodeName:      node,
+					RestartPolicy: coreV1.RestartPolicyNever,
+					Tolerations: []coreV1.Toleration{{
+						Operator: coreV1.TolerationOpExists,
+					}},
+					InitContainers: []coreV1.Container{{
+						Name:    "copy",
+						Image:   c.opts.CopyImage,
+						Command: []string{"cp", "-a", "/buffer/.", "/replay/storage/"},
+						VolumeMounts: []coreV1.VolumeMount{
+							{Name: "buffer", MountPath: "/buffer", ReadOnly: true},
+							{Name: "storage", MountPath: "/replay/storage"},
+						},
+					}},
+					Containers: []coreV1.Container{{
+						Name:  ContainerName,
+						Image: c.opts.FluentBitImage,
+						EnvFrom: []coreV1.EnvFromSource{{
+							SecretRef: &coreV1.SecretEnvSource{
+								LocalObjectReference: coreV1.LocalObjectReference{Name: HeaderSecretName},
+								Optional:             &optional,
+							},
+						}},
+						Env: []coreV1.EnvVar{{
+							Name: "NODE_NAME",
+							ValueFrom: &coreV1.EnvVarSource{
+								FieldRef: &coreV1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+							},
+						}},
+						VolumeMounts: []coreV1.VolumeMount{
+							{Name: "config", MountPath: "/fluent-bit/etc"},
+							{Name: "storage", MountPath: "/replay/storage"},
+						},
+					}},
+					Volumes: []coreV1.Volume{
+						{
+							Name: "buffer",
+							VolumeSource: coreV1.VolumeSource{
+								HostPath: &coreV1.HostPathVolumeSource{Path: c.opts.StoragePath},
+							},
+						},
+						{
+							Name:         "storage",
+							VolumeSource: coreV1.VolumeSource{EmptyDir: &coreV1.EmptyDirVolumeSource{}},
+						},
+						{
+							Name: "config",
+							VolumeSource: coreV1.VolumeSource{
+								ConfigMap: &coreV1.ConfigMapVolumeSource{
+									LocalObjectReference: coreV1.LocalObjectReference{Name: configName},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// replayName names the objects of a replay. Sink keys are not valid object
+// names, so they are hashed with the other parts.
+func replayName(parts ...string) string {
+	h := fnv.New32a()
+	for _, p := range parts {
+		h.Write([]byte(p + "|"))
+	}
+	return fmt.Sprintf("replay-%08x", h.Sum32())
+}
+
+// replayFragment renders the config of the sink on its own with the filter
+// sets it references.
+func (sc *Config) replayFragment(obj runtime.Object) (Fragment, error) {
+	sc.mu.Lock()
+	filterSets := make([]*v1alpha1.ClusterFilterSet, 0, len(sc.filterSets))
+	for _, fs := range sc.filterSets {
+		filterSets = append(filterSets, fs)
+	}
+	sc.mu.Unlock()
+	return RenderFragment(obj, filterSets...)
+}
+
+// nodeNames returns the sorted names of the cluster's nodes.
+func (sc *Config) nodeNames() []string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.selectedNodes(labels.Everything())
+}
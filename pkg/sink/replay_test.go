@@ -0,0 +1,291 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	batchV1 "k8s.io/api/batch/v1"
+	coreV1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestReplayController(t *testing.T) {
+	replayedSink := func(replay, replayed string) *v1alpha1.LogSink {
+		s := &v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   "ns1",
+				Annotations: map[string]string{},
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "https://logs.example.com"},
+			},
+		}
+		if replay != "" {
+			s.Annotations[sink.ReplayAnnotation] = replay
+		}
+		if replayed != "" {
+			s.Annotations[sink.ReplayedAnnotation] = replayed
+		}
+		return s
+	}
+	opts := sink.ReplayOptions{
+		Namespace:      "knative-observability",
+		StoragePath:    "/var/log/flb-storage/",
+		FluentBitImage: "fluent-bit",
+		CopyImage:      "busybox",
+		Duration:       10 * time.Minute,
+	}
+	setup := func(s *v1alpha1.LogSink) (*sink.ReplayController, *spyJobClient, *spyConfigMapCreator, *fake.Clientset) {
+		sc := sink.NewConfig()
+		sc.SetNode("node-b", nil)
+		sc.SetNode("node-a", nil)
+		sc.UpsertSink(s)
+		jobs := &spyJobClient{jobs: make(map[string]*batchV1.Job)}
+		configMaps := &spyConfigMapCreator{}
+		client := fake.NewSimpleClientset(s)
+		return sink.NewReplayController(opts, jobs, configMaps, client.ObservabilityV1alpha1(), sc), jobs, configMaps, client
+	}
+
+	t.Run("it creates a replay job on every node", func(t *testing.T) {
+		s := replayedSink("2019-01-01T00:00:00Z", "")
+		c, jobs, configMaps, _ := setup(s)
+
+		c.OnUpdate(replayedSink("", ""), s)
+
+		if len(jobs.created) != 2 {
+			t.Fatalf("Expected a job per node, got %d", len(jobs.created))
+		}
+		nodes := map[string]bool{}
+		for _, j := range jobs.created {
+			spec := j.Spec.Template.Spec
+			nodes[spec.NodeName] = true
+			if j.Namespace != "knative-observability" {
+				t.Errorf("Expected the job in the fluent-bit namespace, got %s", j.Namespace)
+			}
+			if j.Spec.Template.Labels["app"] == "fluent-bit" {
+				t.Error("Expected the replay pods to not be fluent-bit pods")
+			}
+			if *j.Spec.ActiveDeadlineSeconds != 600 {
+				t.Errorf("Expected a deadline of 600s, got %d", *j.Spec.ActiveDeadlineSeconds)
+			}
+			if j.Spec.TTLSecondsAfterFinished == nil || *j.Spec.TTLSecondsAfterFinished != 3600 {
+				t.Errorf("Expected finished jobs to be kept for an hour, got %v", j.Spec.TTLSecondsAfterFinished)
+			}
+			if spec.Volumes[0].HostPath.Path != "/var/log/flb-storage/" {
+				t.Errorf("Expected the storage path to be mounted, got %v", spec.Volumes[0])
+			}
+			if spec.InitContainers[0].Image != "busybox" || spec.Containers[0].Image != "fluent-bit" {
+				t.Errorf("Expected the configured images, got %v", spec)
+			}
+		}
+		if !nodes["node-a"] || !nodes["node-b"] {
+			t.Errorf("Expected jobs on node-a and node-b, got %v", nodes)
+		}
+
+		if len(configMaps.created) != 1 {
+			t.Fatalf("Expected the replay config to be created, got %d", len(configMaps.created))
+		}
+		cm := configMaps.created[0]
+		if cm.Name != jobs.created[0].Spec.Template.Spec.Volumes[2].ConfigMap.Name {
+			t.Errorf("Expected the jobs to mount configmap %s", cm.Name)
+		}
+		if len(cm.OwnerReferences) != 2 {
+			t.Errorf("Expected the configmap to be owned by the jobs, got %v", cm.OwnerReferences)
+		}
+		f, err := sink.RenderFragment(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cm.Data["outputs.conf"] != f.Config {
+			t.Errorf("Expected the outputs of the sink, got %s", cm.Data["outputs.conf"])
+		}
+	})
+
+	t.Run("it marks the sink as replayed", func(t *testing.T) {
+		s := replayedSink("first", "")
+		c, _, _, client := setup(s)
+
+		c.OnAdd(s)
+
+		got, err := client.ObservabilityV1alpha1().LogSinks("ns1").Get("app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Annotations[sink.ReplayedAnnotation] != "first" {
+			t.Errorf("Expected the replay to be marked, got %v", got.Annotations)
+		}
+	})
+
+	t.Run("it does not replay a marked value again", func(t *testing.T) {
+		s := replayedSink("first", "first")
+		c, jobs, _, _ := setup(s)
+
+		c.OnAdd(s)
+		c.OnUpdate(s, s)
+
+		if len(jobs.created) != 0 {
+			t.Errorf("Expected no jobs, got %d", len(jobs.created))
+		}
+	})
+
+	t.Run("it does not replay sinks without the annotation", func(t *testing.T) {
+		s := replayedSink("", "")
+		c, jobs, _, _ := setup(s)
+
+		c.OnAdd(s)
+
+		if len(jobs.created) != 0 {
+			t.Errorf("Expected no jobs, got %d", len(jobs.created))
+		}
+	})
+
+	t.Run("it keeps the jobs of an earlier attempt", func(t *testing.T) {
+		s := replayedSink("first", "")
+		c, jobs, configMaps, _ := setup(s)
+		configMaps.err = errors.New("forbidden")
+
+		c.OnAdd(s)
+		configMaps.err = nil
+		c.OnAdd(s)
+
+		if len(jobs.created) != 2 {
+			t.Errorf("Expected the jobs to be created once, got %d", len(jobs.created))
+		}
+		if len(configMaps.created) != 1 || len(configMaps.created[0].OwnerReferences) != 2 {
+			t.Errorf("Expected the config to be owned by the existing jobs, got %v", configMaps.created)
+		}
+	})
+
+	t.Run("it replays a new value", func(t *testing.T) {
+		s := replayedSink("second", "first")
+		c, jobs, _, _ := setup(s)
+
+		c.OnAdd(s)
+		c.OnAdd(replayedSink("first", ""))
+
+		if len(jobs.created) != 4 {
+			t.Errorf("Expected jobs for each value, got %d", len(jobs.created))
+		}
+	})
+
+	t.Run("it deletes the finished jobs of earlier replays of the sink", func(t *testing.T) {
+		s := replayedSink("first", "")
+		c, jobs, _, _ := setup(s)
+		c.OnAdd(s)
+		finished := jobs.created[0].Name
+		jobs.jobs[finished].Status.Conditions = []batchV1.JobCondition{
+			{Type: batchV1.JobComplete, Status: coreV1.ConditionTrue},
+		}
+		jobs.jobs["other-sink"] = &batchV1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "other-sink",
+				Annotations: map[string]string{
+					"observability.knative.dev/sink":   "ns2/app",
+					"observability.knative.dev/replay": "first",
+				},
+			},
+			Status: batchV1.JobStatus{Conditions: []batchV1.JobCondition{
+				{Type: batchV1.JobFailed, Status: coreV1.ConditionTrue},
+			}},
+		}
+
+		c.OnAdd(replayedSink("second", "first"))
+
+		if diff := cmp.Diff([]string{finished}, jobs.deleted); diff != "" {
+			t.Errorf("Deleted jobs not equal (-want, +got) = %v", diff)
+		}
+		if jobs.selector != "app=fluent-bit-replay" {
+			t.Errorf("Expected the replay jobs to be listed, got %s", jobs.selector)
+		}
+		if len(jobs.created) != 4 {
+			t.Errorf("Expected the jobs of the new value to be created, got %d", len(jobs.created))
+		}
+	})
+
+	t.Run("it keeps the jobs of the current replay", func(t *testing.T) {
+		s := replayedSink("first", "")
+		c, jobs, _, _ := setup(s)
+		c.OnAdd(s)
+		for _, j := range jobs.jobs {
+			j.Status.Conditions = []batchV1.JobCondition{
+				{Type: batchV1.JobComplete, Status: coreV1.ConditionTrue},
+			}
+		}
+
+		c.OnAdd(s)
+
+		if len(jobs.deleted) != 0 {
+			t.Errorf("Expected no jobs to be deleted, got %v", jobs.deleted)
+		}
+	})
+}
+
+type spyJobClient struct {
+	jobs     map[string]*batchV1.Job
+	created  []*batchV1.Job
+	deleted  []string
+	selector string
+}
+
+func (s *spyJobClient) Get(name string, _ metav1.GetOptions) (*batchV1.Job, error) {
+	j, ok := s.jobs[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "jobs"}, name)
+	}
+	return j, nil
+}
+
+func (s *spyJobClient) List(opts metav1.ListOptions) (*batchV1.JobList, error) {
+	s.selector = opts.LabelSelector
+	list := &batchV1.JobList{}
+	for _, j := range s.jobs {
+		list.Items = append(list.Items, *j)
+	}
+	return list, nil
+}
+
+func (s *spyJobClient) Delete(name string, _ *metav1.DeleteOptions) error {
+	if _, ok := s.jobs[name]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "jobs"}, name)
+	}
+	delete(s.jobs, name)
+	s.deleted = append(s.deleted, name)
+	return nil
+}
+
+func (s *spyJobClient) Create(j *batchV1.Job) (*batchV1.Job, error) {
+	if _, ok := s.jobs[j.Name]; ok {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Group: "batch", Resource: "jobs"}, j.Name)
+	}
+	j = j.DeepCopy()
+	j.UID = types.UID("uid-" + j.Name)
+	s.jobs[j.Name] = j
+	s.created = append(s.created, j)
+	return j, nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requireFieldsLua drops the records missing any of the fields. It runs
+// after the steps that add fields, e.g. the hostname, so they can be
+// required, and before the sequence so that dropped records leave no gaps.
+func requireFieldsLua(name string, fields []string) luaStep {
+	paths := make([]string, 0, len(fields))
+	for _, f := range fields {
+		paths = append(paths, luaFieldPath(f))
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {%s}\n", name, strings.Join(paths, ", ")),
+		body: fmt.Sprintf(`
+    for _, path in ipairs(%s) do
+        local parent = field_parent(record, path)
+        if parent == nil or parent[path[#path]] == nil then
+            return -1, timestamp, record
+        end
+    end
+`, name),
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ContainerName is the name of the fluent-bit container of the daemonset.
+const ContainerName = "fluent-bit"
+
+// ResourceQuantities are the quantities of the fluent-bit container
+// resources. Empty quantities are left as deployed.
+type ResourceQuantities struct {
+	CPURequest    string
+	MemoryRequest string
+	CPULimit      string
+	MemoryLimit   string
+}
+
+// Requirements parses the quantities. It returns an error for a quantity
+// that cannot be parsed, is negative or a request that is above its limit.
+func (q ResourceQuantities) Requirements() (coreV1.ResourceRequirements, error) {
+	var rr coreV1.ResourceRequirements
+	quantities := []struct {
+		list  *coreV1.ResourceList
+		name  coreV1.ResourceName
+		value string
+	}{
+		{&rr.Requests, coreV1.ResourceCPU, q.CPURequest},
+		{&rr.Requests, coreV1.ResourceMemory, q.MemoryRequest},
+		{&rr.Limits, coreV1.ResourceCPU, q.CPULimit},
+		{&rr.Limits, coreV1.ResourceMemory, q.MemoryLimit},
+	}
+	for _, rq := range quantities {
+		if rq.value == "" {
+			continue
+		}
+
+		v, err := resource.ParseQuantity(rq.value)
+		if err != nil {
+			return coreV1.ResourceRequirements{}, fmt.Errorf("invalid %s quantity %q: %s", rq.name, rq.value, err)
+		}
+		if v.Sign() < 0 {
+			return coreV1.ResourceRequirements{}, fmt.Errorf("invalid %s quantity %q: must not be negative", rq.name, rq.value)
+		}
+		if *rq.list == nil {
+			*rq.list = coreV1.ResourceList{}
+		}
+		(*rq.list)[rq.name] = v
+	}
+
+	for name, request := range rr.Requests {
+		limit, ok := rr.Limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			return coreV1.ResourceRequirements{}, fmt.Errorf(
+				"%s request %s must not be above its limit %s",
+				name,
+				request.String(),
+				limit.String(),
+			)
+		}
+	}
+	return rr, nil
+}
+
+// ResourcesController sets the resource requests and limits of the
+// fluent-bit container, so its pods cannot starve the nodes they run on.
+// The resources are set again whenever the daemonset is changed to other
+// ones.
+type ResourcesController struct {
+	rr coreV1.ResourceRequirements
+	dp DaemonSetPatcher
+}
+
+func NewResourcesController(rr coreV1.ResourceRequirements, dp DaemonSetPatcher) *ResourcesController {
+	return &ResourcesController{
+		rr: rr,
+		dp: dp,
+	}
+}
+
+func (c *ResourcesController) OnAdd(o interface{}) {
+	c.reconcile(o)
+}
+
+func (c *ResourcesController) OnUpdate(_, n interface{}) {
+	c.reconcile(n)
+}
+
+func (c *ResourcesController) OnDelete(o interface{}) {}
+
+// reconcile patches the fluent-bit container of the daemonset when one of
+// its resources differs. Resources that are not set by the controller are
+// kept.
+func (c *ResourcesController) reconcile(o interface{}) {
+	ds, ok := o.(*appsV1.DaemonSet)
+	if !ok || ds.Name != DaemonSetName {
+		return
+	}
+	if !c.differs(ds) {
+		return
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":      ContainerName,
+							"resources": c.rr,
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Unable to marshal resources patch: %s", err)
+		return
+	}
+
+	log.Printf("Setting the resources of DaemonSet %s", ds.Name)
+	_, err = c.dp.Patch(ds.Name, types.StrategicMergePatchType, data)
+	if err != nil {
+		log.Printf("Unable to patch DaemonSet %s: %s", ds.Name, err)
+	}
+}
+
+func (c *ResourcesController) differs(ds *appsV1.DaemonSet) bool {
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		if container.Name != ContainerName {
+			continue
+		}
+		return !containsQuantities(container.Resources.Requests, c.rr.Requests) ||
+			!containsQuantities(container.Resources.Limits, c.rr.Limits)
+	}
+	return false
+}
+
+func containsQuantities(list, quantities coreV1.ResourceList) bool {
+	for name, q := range quantities {
+		v, ok := list[name]
+		if !ok || v.Cmp(q) != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	appsV1 "k8s.io/api/apps/v1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestResourcesController(t *testing.T) {
+	daemonSet := func(rr coreV1.ResourceRequirements) *appsV1.DaemonSet {
+		return &appsV1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "fluent-bit",
+				Namespace: "knative-observability",
+			},
+			Spec: appsV1.DaemonSetSpec{
+				Template: coreV1.PodTemplateSpec{
+					Spec: coreV1.PodSpec{
+						Containers: []coreV1.Container{
+							{Name: "fluent-bit", Image: "fluent-bit", Resources: rr},
+							{Name: "rotator", Image: "rotator"},
+						},
+					},
+				},
+			},
+		}
+	}
+	deployed := coreV1.ResourceRequirements{
+		Limits: coreV1.ResourceList{
+			coreV1.ResourceMemory: resource.MustParse("100Mi"),
+		},
+		Requests: coreV1.ResourceList{
+			coreV1.ResourceCPU:    resource.MustParse("100m"),
+			coreV1.ResourceMemory: resource.MustParse("100Mi"),
+		},
+	}
+
+	t.Run("it sets the resources of the fluent-bit container", func(t *testing.T) {
+		rr, err := sink.ResourceQuantities{
+			CPURequest:  "250m",
+			CPULimit:    "1",
+			MemoryLimit: "512Mi",
+		}.Requirements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewResourcesController(rr, spyPatcher)
+
+		ds := daemonSet(deployed)
+		c.OnAdd(ds)
+
+		if spyPatcher.name != "fluent-bit" {
+			t.Fatalf("Expected the fluent-bit daemonset to be patched, got %q", spyPatcher.name)
+		}
+		original, err := json.Marshal(ds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patched, err := strategicpatch.StrategicMergePatch(original, spyPatcher.data, appsV1.DaemonSet{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got appsV1.DaemonSet
+		if err := json.Unmarshal(patched, &got); err != nil {
+			t.Fatal(err)
+		}
+
+		containers := got.Spec.Template.Spec.Containers
+		if len(containers) != 2 || containers[0].Image != "fluent-bit" || containers[1].Name != "rotator" {
+			t.Fatalf("Expected the containers to be kept, got %v", containers)
+		}
+		expected := map[string]coreV1.ResourceList{
+			"requests": {
+				coreV1.ResourceCPU:    resource.MustParse("250m"),
+				coreV1.ResourceMemory: resource.MustParse("100Mi"),
+			},
+			"limits": {
+				coreV1.ResourceCPU:    resource.MustParse("1"),
+				coreV1.ResourceMemory: resource.MustParse("512Mi"),
+			},
+		}
+		actual := map[string]coreV1.ResourceList{
+			"requests": containers[0].Resources.Requests,
+			"limits":   containers[0].Resources.Limits,
+		}
+		for kind, list := range expected {
+			if len(actual[kind]) != len(list) {
+				t.Errorf("Expected %s %v, got %v", kind, list, actual[kind])
+				continue
+			}
+			for name, q := range list {
+				v := actual[kind][name]
+				if v.Cmp(q) != 0 {
+					t.Errorf("Expected %s %s of %s, got %s", name, kind, q.String(), v.String())
+				}
+			}
+		}
+		if len(containers[1].Resources.Limits) != 0 || len(containers[1].Resources.Requests) != 0 {
+			t.Errorf("Expected the rotator resources to be kept, got %v", containers[1].Resources)
+		}
+	})
+
+	t.Run("it does not patch a container with the resources", func(t *testing.T) {
+		rr, err := sink.ResourceQuantities{
+			CPURequest:  "0.1",
+			MemoryLimit: "100Mi",
+		}.Requirements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewResourcesController(rr, spyPatcher)
+
+		c.OnAdd(daemonSet(deployed))
+		c.OnUpdate(daemonSet(coreV1.ResourceRequirements{}), daemonSet(deployed))
+
+		if spyPatcher.patchCalled != 0 {
+			t.Errorf("Expected no patches, got %d", spyPatcher.patchCalled)
+		}
+	})
+
+	t.Run("it sets the resources again when the daemonset is changed", func(t *testing.T) {
+		rr, err := sink.ResourceQuantities{MemoryLimit: "100Mi"}.Requirements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		spyPatcher := &spyDaemonSetPatcher{}
+		c := sink.NewResourcesController(rr, spyPatcher)
+
+		c.OnUpdate(daemonSet(deployed), daemonSet(coreV1.ResourceRequirements{}))
+
+		if spyPatcher.patchCalled != 1 {
+			t.Errorf("Expected the daemonset to be patched once, got %d", spyPatcher.patchCalled)
+		}
+	})
+}
+
+func TestResourceQuantities(t *testing.T) {
+	t.Run("it leaves unset quantities out", func(t *testing.T) {
+		rr, err := sink.ResourceQuantities{}.Requirements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rr.Requests != nil || rr.Limits != nil {
+			t.Errorf("Expected no resources, got %v", rr)
+		}
+	})
+
+	t.Run("it rejects invalid quantities", func(t *testing.T) {
+		tests := map[string]sink.ResourceQuantities{
+			"unparsable":        {CPURequest: "lots"},
+			"negative":          {MemoryLimit: "-1Gi"},
+			"request above":     {MemoryRequest: "1Gi", MemoryLimit: "512Mi"},
+			"cpu request above": {CPURequest: "2", CPULimit: "1500m"},
+		}
+		for name, q := range tests {
+			t.Run(name, func(t *testing.T) {
+				if _, err := q.Requirements(); err == nil {
+					t.Errorf("Expected an error for %+v", q)
+				}
+			})
+		}
+	})
+}
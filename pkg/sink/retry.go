@@ -0,0 +1,63 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// WithStorageBuffering renders the buffer limits of sinks with
+// Retry.MaxBufferSize set and buffers the records of the sinks' own inputs
+// and copies on the filesystem. fluent-bit only limits the chunks it
+// buffers on the filesystem, so without filesystem buffering the field is
+// ignored.
+func WithStorageBuffering() ConfigOpt {
+	return func(sc *Config) {
+		sc.storageBuffering = true
+	}
+}
+
+// inputStorageConfig renders the storage type of the inputs of sinks, so
+// their chunks are buffered on the filesystem like the container logs and
+// the buffer limits of their outputs apply to them.
+func (sc *Config) inputStorageConfig() string {
+	if !sc.storageBuffering {
+		return ""
+	}
+	return "    storage.type filesystem\n"
+}
+
+// emitterStorageConfig renders the storage type of the emitters of the
+// rewrite_tag filters copying the records of sinks, for the same reason.
+func (sc *Config) emitterStorageConfig() string {
+	if !sc.storageBuffering {
+		return ""
+	}
+	return "    Emitter_Storage.type filesystem\n"
+}
+
+// bufferLimitConfig renders the most bytes of chunks an output may buffer.
+// Once an output reaches it fluent-bit drops its oldest chunks and counts
+// their records in the output's dropped_records metric.
+func (sc *Config) bufferLimitConfig(spec v1alpha1.SinkSpec) string {
+	if !sc.storageBuffering || spec.Retry == nil || spec.Retry.MaxBufferSize == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("    storage.total_limit_size %d\n", spec.Retry.MaxBufferSize.Value())
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestRetryBufferLimit(t *testing.T) {
+	limit := resource.MustParse("512Mi")
+	retry := &v1alpha1.RetrySpec{MaxBufferSize: &limit}
+	sinks := []*v1alpha1.LogSink{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "syslog-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				Retry: retry,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/place",
+				},
+				Retry: retry,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "socket-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "unix_socket",
+				UnixSocketSpec: v1alpha1.UnixSocketSpec{
+					Path: "/var/run/collector.sock",
+				},
+				Retry: retry,
+			},
+		},
+	}
+
+	t.Run("it limits the buffer of each output", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithStorageBuffering())
+		for _, s := range sinks {
+			sc.UpsertSink(s)
+		}
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/syslog-sink
+    InstanceName ns1/syslog-sink
+    Addr example.com:12345
+    Namespace ns1
+    storage.total_limit_size 536870912
+
+[OUTPUT]
+    Name http
+    Match *_ns1_*
+    Alias ns1/webhook-sink
+    Format json
+    Host example.com
+    Port 80
+    URI /place
+    storage.total_limit_size 536870912
+
+
+[OUTPUT]
+    Name forward
+    Match *_ns1_*
+    Alias ns1/socket-sink
+    Unix_Path /var/run/collector.sock
+    storage.total_limit_size 536870912
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it buffers the sinks' own inputs and copies on the filesystem", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithStorageBuffering())
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "raw-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/raw",
+				},
+				RawMode: true,
+				Retry:   retry,
+			},
+		})
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "projected-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "http://example.com/projected",
+				},
+				Project: []string{"log"},
+				Retry:   retry,
+			},
+		})
+
+		expected := `
+[INPUT]
+    Name tail
+    Tag raw.ns.ns1.raw-sink
+    Alias ns1/raw-sink:raw
+    Path /var/log/containers/*_ns1_*.log
+    DB /var/log/flb_raw.ns.ns1.raw-sink.db
+    Mem_Buf_Limit 5MB
+    Skip_Long_Lines On
+    Refresh_Interval 10
+    storage.type filesystem
+`
+		config := sc.String()
+		if !strings.HasPrefix(config, expected) {
+			t.Errorf("Expected the raw input to be buffered on the filesystem, got %s", config)
+		}
+		emitter := "    Emitter_Name ns1/projected-sink:project\n    Emitter_Storage.type filesystem\n"
+		if !strings.Contains(config, emitter) {
+			t.Errorf("Expected the copy to be buffered on the filesystem, got %s", config)
+		}
+	})
+
+	t.Run("it ignores the limit without buffering", func(t *testing.T) {
+		sc := sink.NewConfig()
+		for _, s := range sinks {
+			sc.UpsertSink(s)
+		}
+
+		if config := sc.String(); strings.Contains(config, "storage.total_limit_size") {
+			t.Errorf("Expected no buffer limits, got %s", config)
+		}
+
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "raw-sink", Namespace: "ns1"},
+			Spec: v1alpha1.SinkSpec{
+				Type:        "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{URL: "http://example.com/raw"},
+				RawMode:     true,
+			},
+		})
+		if config := sc.String(); strings.Contains(config, "storage.type") {
+			t.Errorf("Expected no storage types, got %s", config)
+		}
+	})
+}
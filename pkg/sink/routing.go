@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// routeFilterConfig moves the records of a routed sink that match a rule to
+// the tag of its route. Records that no rule moves keep the sink's tag and
+// are sent to the sink's URL.
+const routeFilterConfig = `
+[FILTER]
+    Name rewrite_tag
+    Match %s
+    Rule %s %s %s false
+    Emitter_Name %s
+%s`
+
+// route is a rewrite_tag rule of a routed sink and the URL of the records it
+// moves.
+type route struct {
+	key     string
+	pattern string
+	url     string
+}
+
+// routed returns whether the sink reads its own copy of the records to
+// route by annotation or status code.
+func routed(ref sinkRef) bool {
+	return ref.cluster && ref.spec.Type == "webhook" &&
+		(ref.spec.AnnotationRouting != nil || ref.spec.StatusCodeRouting != nil)
+}
+
+// routeTag is the tag of the copies of the records read by a routed sink.
+func routeTag(ref sinkRef) string {
+	return fmt.Sprintf("route.cluster.%s", ref.name)
+}
+
+// routes returns the routes of a routed sink in the order they are
+// rendered.
+func routes(ref sinkRef) []route {
+	if ref.spec.StatusCodeRouting != nil {
+		return statusCodeRoutes(ref.spec.StatusCodeRouting)
+	}
+
+	routing := ref.spec.AnnotationRouting
+	values := make([]string, 0, len(routing.Routes))
+	for v := range routing.Routes {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	rs := make([]route, 0, len(values))
+	for _, v := range values {
+		rs = append(rs, route{
+			key:     fmt.Sprintf("$kubernetes['annotations']['%s']", routing.Annotation),
+			pattern: fmt.Sprintf("^%s$", regexp.QuoteMeta(v)),
+			url:     routing.Routes[v],
+		})
+	}
+	return rs
+}
+
+// routeValueTag is the tag of the records routed to the jth route of a
+// sink. Sink names may contain dots, so it has a prefix of its own rather
+// than extending the sink's tag, which would be the tag of the sink named
+// after the route.
+func routeValueTag(ref sinkRef, j int) string {
+	return fmt.Sprintf("routes.cluster.%s.%d", ref.name, j)
+}
+
+// routeAlias is the alias of the output of the jth route of a sink.
+func routeAlias(ref sinkRef, j int) string {
+	return fmt.Sprintf("%s:route:%d", outputAlias(ref), j)
+}
+
+// routeFiltersConfig renders the rules that move a routed sink's records to
+// their routes. They are rendered after the sink's other filters so that
+// the routed records are filtered like the rest.
+func (sc *Config) routeFiltersConfig(ref sinkRef) string {
+	var config string
+	for j, r := range routes(ref) {
+		config += fmt.Sprintf(
+			routeFilterConfig,
+			routeTag(ref),
+			r.key,
+			r.pattern,
+			routeValueTag(ref, j),
+			inputAlias(ref, fmt.Sprintf("route:%d", j)),
+			sc.emitterStorageConfig(),
+		)
+	}
+	return config
+}
+
+// routesConfig renders an http output for every route of a sink.
+func (sc *Config) routesConfig(ref sinkRef) string {
+	var config string
+	for j, r := range routes(ref) {
+		spec := ref.spec
+		spec.URL = r.url
+		config += buildHTTPConfig(routeValueTag(ref, j), routeAlias(ref, j), spec, sc.bufferLimitConfig(spec)+headersConfig(ref))
+	}
+	return config
+}
+
+// outputCount returns the number of outputs rendered for a sink.
+func outputCount(ref sinkRef) int {
+	if routed(ref) {
+		return 1 + len(routes(ref))
+	}
+	if ref.spec.Type == "syslog" && len(ref.spec.Receivers) > 0 {
+		return len(ref.spec.Receivers)
+	}
+	return 1
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestAnnotationRouting(t *testing.T) {
+	routedSink := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "routed-sink",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/default",
+			},
+			AnnotationRouting: &v1alpha1.AnnotationRoutingSpec{
+				Annotation: "example.com/team",
+				Routes: map[string]string{
+					"payments": "https://payments.example.com/logs",
+					"a.b":      "https://ab.example.com/logs",
+				},
+			},
+		},
+	}
+
+	t.Run("it routes the sink's copy by annotation value", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(routedSink)
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* route.cluster.routed-sink true
+    Emitter_Name cluster:routed-sink:route
+
+[FILTER]
+    Name rewrite_tag
+    Match route.cluster.routed-sink
+    Rule $kubernetes['annotations']['example.com/team'] ^a\.b$ routes.cluster.routed-sink.0 false
+    Emitter_Name cluster:routed-sink:route:0
+
+[FILTER]
+    Name rewrite_tag
+    Match route.cluster.routed-sink
+    Rule $kubernetes['annotations']['example.com/team'] ^payments$ routes.cluster.routed-sink.1 false
+    Emitter_Name cluster:routed-sink:route:1
+
+[OUTPUT]
+    Name http
+    Match route.cluster.routed-sink
+    Alias cluster:routed-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /default
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match routes.cluster.routed-sink.0
+    Alias cluster:routed-sink:route:0
+    Format json
+    Host ab.example.com
+    Port 443
+    URI /logs
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match routes.cluster.routed-sink.1
+    Alias cluster:routed-sink:route:1
+    Format json
+    Host payments.example.com
+    Port 443
+    URI /logs
+    tls On
+
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it sends unmatched values to the sink's URL", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(routedSink)
+
+		config := sc.String()
+		if !strings.Contains(config, "Match route.cluster.routed-sink\n    Alias cluster:routed-sink\n    Format json\n    Host example.com\n    Port 443\n    URI /default\n") {
+			t.Errorf("Expected the fallback output to match the sink's tag, got %s", config)
+		}
+	})
+
+	t.Run("it does not route LogSinks", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "routed-sink",
+				Namespace: "ns1",
+			},
+			Spec: routedSink.Spec,
+		})
+
+		if config := sc.String(); strings.Contains(config, "route.") {
+			t.Errorf("Expected no routes, got %s", config)
+		}
+	})
+}
+
+func TestStatusCodeRouting(t *testing.T) {
+	routedSink := &v1alpha1.ClusterLogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "access-logs",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/default",
+			},
+			StatusCodeRouting: &v1alpha1.StatusCodeRoutingSpec{
+				Field: "status",
+				Routes: map[string]string{
+					"500-599": "https://errors.example.com/logs",
+					"404":     "https://missing.example.com/logs",
+					"200-299": "https://ok.example.com/logs",
+				},
+			},
+		},
+	}
+
+	t.Run("it routes the sink's copy by status code range", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(routedSink)
+
+		expected := `
+[FILTER]
+    Name rewrite_tag
+    Match *_*
+    Rule $log .* route.cluster.access-logs true
+    Emitter_Name cluster:access-logs:route
+
+[FILTER]
+    Name rewrite_tag
+    Match route.cluster.access-logs
+    Rule $status ^2\d{2}$ routes.cluster.access-logs.0 false
+    Emitter_Name cluster:access-logs:route:0
+
+[FILTER]
+    Name rewrite_tag
+    Match route.cluster.access-logs
+    Rule $status ^404$ routes.cluster.access-logs.1 false
+    Emitter_Name cluster:access-logs:route:1
+
+[FILTER]
+    Name rewrite_tag
+    Match route.cluster.access-logs
+    Rule $status ^5\d{2}$ routes.cluster.access-logs.2 false
+    Emitter_Name cluster:access-logs:route:2
+`
+		if config := sc.String(); !strings.HasPrefix(config, expected) {
+			t.Errorf("Expected the routing filters, got %s", config)
+		}
+
+		for tag, output := range map[string]string{
+			"route.cluster.access-logs":    "Alias cluster:access-logs\n    Format json\n    Host example.com\n",
+			"routes.cluster.access-logs.0": "Alias cluster:access-logs:route:0\n    Format json\n    Host ok.example.com\n",
+			"routes.cluster.access-logs.1": "Alias cluster:access-logs:route:1\n    Format json\n    Host missing.example.com\n",
+			"routes.cluster.access-logs.2": "Alias cluster:access-logs:route:2\n    Format json\n    Host errors.example.com\n",
+		} {
+			output := "Match " + tag + "\n    " + output
+			if config := sc.String(); !strings.Contains(config, output) {
+				t.Errorf("Expected %s to be sent with %q, got %s", tag, output, config)
+			}
+		}
+	})
+
+	t.Run("it matches ranges that span digit counts", func(t *testing.T) {
+		s := routedSink.DeepCopy()
+		s.Spec.StatusCodeRouting.Routes = map[string]string{
+			"99-101": "https://odd.example.com/logs",
+		}
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(s)
+
+		if config := sc.String(); !strings.Contains(config, `Rule $status ^(99|10[0-1])$ routes.cluster.access-logs.0 false`) {
+			t.Errorf("Expected the range to be split by digits, got %s", config)
+		}
+	})
+
+	t.Run("it does not tag routes like a sink named after them", func(t *testing.T) {
+		named := routedSink.DeepCopy()
+		named.Name = "access-logs.0"
+		named.Spec.URL = "https://other.example.com/default"
+		sc := sink.NewConfig()
+		sc.UpsertClusterSink(routedSink)
+		sc.UpsertClusterSink(named)
+
+		config := sc.String()
+		for tag, output := range map[string]string{
+			"route.cluster.access-logs.0":  "Alias cluster:access-logs.0\n    Format json\n    Host other.example.com\n",
+			"routes.cluster.access-logs.0": "Alias cluster:access-logs:route:0\n    Format json\n    Host ok.example.com\n",
+		} {
+			match := "Match " + tag + "\n    Alias "
+			if n := strings.Count(config, match); n != 1 {
+				t.Errorf("Expected one output of %s, got %d in %s", tag, n, config)
+			}
+			if !strings.Contains(config, "Match "+tag+"\n    "+output) {
+				t.Errorf("Expected %s to be sent with %q, got %s", tag, output, config)
+			}
+		}
+	})
+}
+
+func TestParseCodeRange(t *testing.T) {
+	t.Run("it parses ranges and single codes", func(t *testing.T) {
+		for r, expected := range map[string]sink.CodeRange{
+			"500-599": {Lo: 500, Hi: 599},
+			"404":     {Lo: 404, Hi: 404},
+		} {
+			cr, ok := sink.ParseCodeRange(r)
+			if !ok {
+				t.Fatalf("Expected %s to parse", r)
+			}
+			if diff := cmp.Diff(expected, cr); diff != "" {
+				t.Errorf("Range of %s not equal (-want, +got) = %v", r, diff)
+			}
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		for _, r := range []string{"", "abc", "+404", "599-500", "500-", "-500", "500-599-600"} {
+			if _, ok := sink.ParseCodeRange(r); ok {
+				t.Errorf("Expected %q to be rejected", r)
+			}
+		}
+	})
+}
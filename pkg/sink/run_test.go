@@ -0,0 +1,293 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/knative/observability/pkg/sink/flbconfig"
+)
+
+// luaRecord is a record passed to a sink's Lua function.
+type luaRecord struct {
+	tag       string
+	timestamp float64
+	record    map[string]interface{}
+}
+
+// luaResult is what a sink's Lua function returned for a record.
+type luaResult struct {
+	Code      int                    `json:"code"`
+	Timestamp float64                `json:"timestamp"`
+	Record    map[string]interface{} `json:"record"`
+}
+
+// luaDriver calls a function of the script on every record and prints what
+// it returned as a line of JSON. Strings are printed a byte at a time so
+// that runLua can tell the bytes the function returned apart from the
+// replacements of a JSON decoder.
+const luaDriver = `
+local function encode(v)
+    local t = type(v)
+    if t == "table" then
+        local parts = {}
+        if #v > 0 then
+            for _, e in ipairs(v) do
+                parts[#parts + 1] = encode(e)
+            end
+            return "[" .. table.concat(parts, ",") .. "]"
+        end
+        for k, e in pairs(v) do
+            parts[#parts + 1] = encode(tostring(k)) .. ":" .. encode(e)
+        end
+        return "{" .. table.concat(parts, ",") .. "}"
+    elseif t == "string" then
+        return '"' .. (v:gsub('[%%c"\\\128-\255]', function(c)
+            return string.format("\\u%%04x", c:byte())
+        end)) .. '"'
+    elseif t == "number" then
+        return string.format("%%.17g", v)
+    elseif t == "boolean" then
+        return tostring(v)
+    end
+    return "null"
+end
+
+local function run(tag, timestamp, record)
+    local code, t, r = %s(tag, timestamp, record)
+    print(encode({code = code, timestamp = t, record = r}))
+end
+`
+
+// runLua runs the function fn of a rendered script on each record with
+// LuaJIT, the Lua fluent-bit embeds, and returns what the function
+// returned. Records the function drops are returned with a code of -1.
+// The test is skipped when luajit is not installed.
+func runLua(t *testing.T, script, fn string, records ...luaRecord) []luaResult {
+	t.Helper()
+	luajit, err := exec.LookPath("luajit")
+	if err != nil {
+		t.Skip("luajit is not installed")
+	}
+
+	calls := []string{script, fmt.Sprintf(luaDriver, fn)}
+	for _, r := range records {
+		calls = append(calls, fmt.Sprintf(
+			"run(%s, %s, %s)",
+			luaLiteral(r.tag),
+			strconv.FormatFloat(r.timestamp, 'f', -1, 64),
+			luaLiteral(r.record),
+		))
+	}
+
+	dir, err := ioutil.TempDir("", "lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sinks.lua")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(calls, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := exec.Command(luajit, path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unable to run the script: %s: %s", err, out)
+	}
+
+	var results []luaResult
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		var r luaResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Unable to decode %q: %s", line, err)
+		}
+		r.Record, _ = luaBytes(r.Record).(map[string]interface{})
+		results = append(results, r)
+	}
+	if len(results) != len(records) {
+		t.Fatalf("Expected a result for each of %d records, got %s", len(records), out)
+	}
+	return results
+}
+
+// luaLiteral renders a value as a Lua literal. Bytes outside of printable
+// ASCII are escaped, so strings keep invalid UTF-8.
+func luaLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, 0, len(v))
+		for _, k := range keys {
+			fields = append(fields, fmt.Sprintf("[%s] = %s", luaLiteral(k), luaLiteral(v[k])))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case []interface{}:
+		elems := make([]string, 0, len(v))
+		for _, e := range v {
+			elems = append(elems, luaLiteral(e))
+		}
+		return "{" + strings.Join(elems, ", ") + "}"
+	case string:
+		var b strings.Builder
+		b.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < ' ' || c > '~' || c == '"' || c == '\\' {
+				fmt.Fprintf(&b, "\\%03d", c)
+				continue
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte('"')
+		return b.String()
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return "nil"
+}
+
+// luaBytes turns the strings the driver printed a byte at a time back into
+// the bytes.
+func luaBytes(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[luaBytes(k).(string)] = luaBytes(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = luaBytes(e)
+		}
+		return v
+	case string:
+		b := make([]byte, 0, len(v))
+		for _, r := range v {
+			b = append(b, byte(r))
+		}
+		return string(b)
+	}
+	return v
+}
+
+// fluentBitRunConfig feeds a record to the filters of runFluentBit and
+// prints what they emit.
+const fluentBitRunConfig = `
+[SERVICE]
+    Flush 1
+    Log_Level error
+    Parsers_File %s
+
+[INPUT]
+    Name dummy
+    Tag kube.var.log.containers.pod_ns1_app-1.log
+    Dummy %s
+%s
+[OUTPUT]
+    Name stdout
+    Match *
+    Format json_lines
+`
+
+// runFluentBit runs the filters on a record with fluent-bit and returns the
+// first record they emit. The filters match every record and read the
+// parsers given. The test is skipped when fluent-bit is not installed.
+func runFluentBit(t *testing.T, filters []flbconfig.Section, parsers string, record map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	fluentBit, err := exec.LookPath("fluent-bit")
+	if err != nil {
+		t.Skip("fluent-bit is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "fluent-bit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	parsersPath := filepath.Join(dir, "parsers.conf")
+	if err := ioutil.WriteFile(parsersPath, []byte(parsers), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dummy, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sections string
+	for _, s := range filters {
+		sections += fmt.Sprintf("\n[%s]\n", s.Name)
+		for _, kv := range s.KeyValues {
+			if kv.Key == "Match" {
+				kv.Value = "*"
+			}
+			sections += fmt.Sprintf("    %s %s\n", kv.Key, kv.Value)
+		}
+	}
+	configPath := filepath.Join(dir, "fluent-bit.conf")
+	config := fmt.Sprintf(fluentBitRunConfig, parsersPath, dummy, sections)
+	if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, fluentBit, "-c", configPath)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out map[string]interface{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if err := json.Unmarshal(scanner.Bytes(), &out); err == nil {
+			break
+		}
+	}
+	cancel()
+	cmd.Wait()
+
+	if out == nil {
+		t.Fatalf("Expected fluent-bit to emit a record with config %s", config)
+	}
+	delete(out, "date")
+	return out
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+// sequenceLua sets the sequence field of a record to the next number of
+// its tag's sequence. The tag of container logs names the container, so
+// every container is numbered on its own and a gap in its numbers means
+// records were lost.
+//
+// The counters live in the Lua state of the sink's filter. Each fluent-bit
+// pod counts on its own, so a sequence only increases while its source
+// stays on the same node, and counting starts over at 1 when fluent-bit
+// restarts. Records are numbered before they reach the output, so outputs
+// with several workers or retries may deliver them out of order.
+func sequenceLua(name string) luaStep {
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {}\n", name),
+		body: fmt.Sprintf(`
+    local sequence = (%[1]s[tag] or 0) + 1
+    %[1]s[tag] = sequence
+    record["sequence"] = sequence
+    code = 1
+`, name),
+	}
+}
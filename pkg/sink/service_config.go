@@ -0,0 +1,68 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+const (
+	DefaultFlush = 1
+	DefaultGrace = 5
+)
+
+// serviceConfigTemplate is the main fluent-bit config. Fluent-bit does not
+// include files into a section, so the whole file is rendered to set the
+// service options. It must be kept in sync with the fluent-bit configmap.
+const serviceConfigTemplate = `[SERVICE]
+    Flush         %d
+    Grace         %d
+    Log_Level     warning
+    Daemon        off
+    Parsers_File  parsers.conf
+    HTTP_Server   On
+    HTTP_Listen   0.0.0.0
+    HTTP_Port     2020
+    storage.metrics on
+%s
+@INCLUDE inputs.conf
+@INCLUDE filters.conf
+@INCLUDE outputs.conf
+`
+
+// SetServiceConfig sets the seconds fluent-bit waits between flushes of its
+// outputs and the seconds it waits for outputs to flush when it shuts
+// down. When storagePath is set, fluent-bit buffers chunks in it so that
+// they survive restarts and can be limited per output.
+func SetServiceConfig(
+	cmp ConfigMapPatcher,
+	dsp DaemonSetPodDeleter,
+	sc *Config,
+	flush int,
+	grace int,
+	storagePath string,
+) {
+	var storage string
+	if storagePath != "" {
+		storage = fmt.Sprintf("    storage.path  %s\n", storagePath)
+	}
+
+	patchConfig(sc, cmp, dsp, []patch{
+		{
+			Op:    "replace",
+			Path:  "/data/fluent-bit.conf",
+			Value: fmt.Sprintf(serviceConfigTemplate, flush, grace, storage),
+		},
+	}, "Service config set")
+}
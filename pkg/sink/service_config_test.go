@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"testing"
+
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestSetServiceConfig(t *testing.T) {
+	spyConfigMapPatcher := &spyConfigMapPatcher{}
+	spyDaemonSetPodDeleter := &spyDaemonSetPodDeleter{}
+
+	sink.SetServiceConfig(
+		spyConfigMapPatcher,
+		spyDaemonSetPodDeleter,
+		sink.NewConfig(),
+		3,
+		10,
+		"",
+	)
+
+	expectedPatch := []spyPatch{
+		{
+			Path: "/data/fluent-bit.conf",
+			Value: `[SERVICE]
+    Flush         3
+    Grace         10
+    Log_Level     warning
+    Daemon        off
+    Parsers_File  parsers.conf
+    HTTP_Server   On
+    HTTP_Listen   0.0.0.0
+    HTTP_Port     2020
+    storage.metrics on
+
+@INCLUDE inputs.conf
+@INCLUDE filters.conf
+@INCLUDE outputs.conf
+`,
+		},
+	}
+
+	spyConfigMapPatcher.expectPatches(expectedPatch, t)
+	if spyDaemonSetPodDeleter.Selector != "app=fluent-bit" {
+		t.Errorf("DaemonSet PodDeleter not equal: Expected: %s, Actual: %s", spyDaemonSetPodDeleter.Selector, "app=fluent-bit")
+	}
+}
+
+func TestSetServiceConfigStoragePath(t *testing.T) {
+	spyConfigMapPatcher := &spyConfigMapPatcher{}
+
+	sink.SetServiceConfig(
+		spyConfigMapPatcher,
+		&spyDaemonSetPodDeleter{},
+		sink.NewConfig(),
+		1,
+		5,
+		"/var/log/flb-storage/",
+	)
+
+	expectedPatch := []spyPatch{
+		{
+			Path: "/data/fluent-bit.conf",
+			Value: `[SERVICE]
+    Flush         1
+    Grace         5
+    Log_Level     warning
+    Daemon        off
+    Parsers_File  parsers.conf
+    HTTP_Server   On
+    HTTP_Listen   0.0.0.0
+    HTTP_Port     2020
+    storage.metrics on
+    storage.path  /var/log/flb-storage/
+
+@INCLUDE inputs.conf
+@INCLUDE filters.conf
+@INCLUDE outputs.conf
+`,
+		},
+	}
+
+	spyConfigMapPatcher.expectPatches(expectedPatch, t)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ServiceController records the ports of the cluster's services, which the
+// service refs of sinks are resolved with.
+type ServiceController struct {
+	cmp ConfigMapPatcher
+	dsp DaemonSetPodDeleter
+	sc  *Config
+}
+
+func NewServiceController(cmp ConfigMapPatcher, dsp DaemonSetPodDeleter, sc *Config) *ServiceController {
+	return &ServiceController{
+		cmp: cmp,
+		dsp: dsp,
+		sc:  sc,
+	}
+}
+
+// OnAdd only patches the config when a sink references the service, so
+// that changes to other services do not restart fluent-bit.
+func (c *ServiceController) OnAdd(o interface{}) {
+	s, ok := o.(*coreV1.Service)
+	if !ok {
+		return
+	}
+
+	if c.sc.SetService(s) {
+		applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("Service %s/%s upserted", s.Namespace, s.Name))
+	}
+}
+
+func (c *ServiceController) OnUpdate(old, new interface{}) {
+	c.OnAdd(new)
+}
+
+func (c *ServiceController) OnDelete(o interface{}) {
+	if tombstone, ok := o.(cache.DeletedFinalStateUnknown); ok {
+		o = tombstone.Obj
+	}
+	s, ok := o.(*coreV1.Service)
+	if !ok {
+		return
+	}
+
+	if c.sc.DeleteService(s.Namespace, s.Name) {
+		applyConfig(c.sc, c.cmp, c.dsp, fmt.Sprintf("Service %s/%s deleted", s.Namespace, s.Name))
+	}
+}
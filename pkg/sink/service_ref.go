@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// service is what service refs are resolved with. externalName is the DNS
+// name an ExternalName service points at.
+type service struct {
+	external     bool
+	externalName string
+	ports        map[string]int32
+}
+
+// SetService records the named ports of a service and, for an ExternalName
+// service, the name it points at. It returns whether the rendered config
+// changed, which is only the case when a sink references the service and
+// it changed.
+func (sc *Config) SetService(svc *coreV1.Service) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	s := service{ports: make(map[string]int32, len(svc.Spec.Ports))}
+	for _, p := range svc.Spec.Ports {
+		if p.Name != "" {
+			s.ports[p.Name] = p.Port
+		}
+	}
+	if svc.Spec.Type == coreV1.ServiceTypeExternalName {
+		s.external = true
+		s.externalName = strings.TrimSuffix(svc.Spec.ExternalName, ".")
+	}
+
+	k := serviceKey(svc.Namespace, svc.Name)
+	old, ok := sc.services[k]
+	sc.services[k] = s
+	return sc.serviceReferenced(k) && (!ok || !reflect.DeepEqual(old, s))
+}
+
+// DeleteService removes a service. It returns whether the rendered config
+// changed.
+func (sc *Config) DeleteService(namespace, name string) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	k := serviceKey(namespace, name)
+	_, ok := sc.services[k]
+	delete(sc.services, k)
+	return ok && sc.serviceReferenced(k)
+}
+
+func (sc *Config) serviceReferenced(k string) bool {
+	for _, ref := range sc.sinkRefs("syslog") {
+		if ref.spec.ServiceRef != nil && serviceRefKey(ref) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceAddr resolves the service ref of a sink to the cluster DNS name of
+// the service and the number of the named port. An ExternalName service is
+// resolved to the name it points at instead, since fluent-bit verifies the
+// TLS certificate of the destination against the name it connects to. A
+// ref to a service or port that does not exist is not resolved, nor is one
+// to an ExternalName service without a name.
+func (sc *Config) serviceAddr(ref sinkRef) (string, bool) {
+	svc := sc.services[serviceRefKey(ref)]
+	port, ok := svc.ports[ref.spec.ServiceRef.PortName]
+	if !ok || (svc.external && svc.externalName == "") {
+		return "", false
+	}
+
+	if svc.external {
+		return fmt.Sprintf("%s:%d", svc.externalName, port), true
+	}
+	return fmt.Sprintf(
+		"%s.%s.svc:%d",
+		ref.spec.ServiceRef.Name,
+		serviceRefNamespace(ref),
+		port,
+	), true
+}
+
+// unresolved reports whether ref sends to a service that cannot be
+// resolved. Its output discards records until the service exists.
+func (sc *Config) unresolved(ref sinkRef) bool {
+	if ref.spec.ServiceRef == nil {
+		return false
+	}
+	_, ok := sc.serviceAddr(ref)
+	return !ok
+}
+
+// serviceRefNamespace is the namespace of the service a sink references,
+// which defaults to the namespace of a LogSink.
+func serviceRefNamespace(ref sinkRef) string {
+	if ref.spec.ServiceRef.Namespace != "" || ref.cluster {
+		return ref.spec.ServiceRef.Namespace
+	}
+	return canonicalNamespace(ref.namespace)
+}
+
+func serviceRefKey(ref sinkRef) string {
+	return serviceKey(serviceRefNamespace(ref), ref.spec.ServiceRef.Name)
+}
+
+func serviceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
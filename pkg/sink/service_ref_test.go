@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestServiceRef(t *testing.T) {
+	refSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				ServiceRef: &v1alpha1.ServiceRef{
+					Name:     "collector",
+					PortName: "syslog",
+				},
+			},
+		},
+	}
+	service := func(namespace string, port int32) *coreV1.Service {
+		return &coreV1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "collector",
+				Namespace: namespace,
+			},
+			Spec: coreV1.ServiceSpec{
+				Ports: []coreV1.ServicePort{
+					{Name: "syslog", Port: port},
+					{Name: "metrics", Port: 9090},
+				},
+			},
+		}
+	}
+
+	t.Run("it resolves the ref to the service's DNS name and port", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.SetService(service("ns1", 5140))
+		sc.UpsertSink(refSink)
+
+		if config := sc.String(); !strings.Contains(config, "Addr collector.ns1.svc:5140") {
+			t.Errorf("Expected the service address, got %s", config)
+		}
+	})
+
+	t.Run("it resolves ClusterLogSink refs in their namespace", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.SetService(service("collectors", 5140))
+		spec := refSink.Spec.DeepCopy()
+		spec.ServiceRef.Namespace = "collectors"
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+			Spec:       *spec,
+		})
+
+		if config := sc.String(); !strings.Contains(config, "Addr collector.collectors.svc:5140") {
+			t.Errorf("Expected the service address, got %s", config)
+		}
+	})
+
+	t.Run("it resolves refs to ExternalName services to their external name", func(t *testing.T) {
+		svc := service("ns1", 6514)
+		svc.Spec.Type = coreV1.ServiceTypeExternalName
+		svc.Spec.ExternalName = "logs.example.com."
+		sc := sink.NewConfig()
+		sc.SetService(svc)
+		sc.UpsertSink(refSink)
+
+		if config := sc.String(); !strings.Contains(config, "Addr logs.example.com:6514") {
+			t.Errorf("Expected the external name address, got %s", config)
+		}
+
+		svc = svc.DeepCopy()
+		svc.Spec.ExternalName = "logs-2.example.com"
+		if !sc.SetService(svc) {
+			t.Error("Expected a changed external name to change the config")
+		}
+		if config := sc.String(); !strings.Contains(config, "Addr logs-2.example.com:6514") {
+			t.Errorf("Expected the changed external name address, got %s", config)
+		}
+	})
+
+	t.Run("it does not resolve refs to ExternalName services without a name", func(t *testing.T) {
+		svc := service("ns1", 6514)
+		svc.Spec.Type = coreV1.ServiceTypeExternalName
+		sc := sink.NewConfig()
+		sc.SetService(svc)
+		sc.UpsertSink(refSink)
+
+		config := sc.String()
+		if strings.Contains(config, "Name syslog") || strings.Contains(config, "collector.ns1.svc") {
+			t.Errorf("Expected no syslog output, got %s", config)
+		}
+	})
+
+	t.Run("it discards records until the service exists", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(refSink)
+
+		config := sc.String()
+		if strings.Contains(config, "Name syslog") {
+			t.Errorf("Expected no syslog output, got %s", config)
+		}
+		if !strings.Contains(config, "Name null") {
+			t.Errorf("Expected a null output, got %s", config)
+		}
+	})
+
+	t.Run("it reports whether the config changed", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(refSink)
+
+		if !sc.SetService(service("ns1", 5140)) {
+			t.Error("Expected a new referenced service to change the config")
+		}
+		if sc.SetService(service("ns1", 5140)) {
+			t.Error("Expected an unchanged service to not change the config")
+		}
+		if !sc.SetService(service("ns1", 5141)) {
+			t.Error("Expected a changed port to change the config")
+		}
+		if sc.SetService(service("ns2", 5140)) {
+			t.Error("Expected an unreferenced service to not change the config")
+		}
+		if sc.DeleteService("ns2", "collector") {
+			t.Error("Expected deleting an unreferenced service to not change the config")
+		}
+		if !sc.DeleteService("ns1", "collector") {
+			t.Error("Expected deleting a referenced service to change the config")
+		}
+	})
+
+	t.Run("it patches the config when a referenced service is added", func(t *testing.T) {
+		spyPatcher := &spyConfigMapPatcher{}
+		sc := sink.NewConfig()
+		sc.UpsertSink(refSink)
+		c := sink.NewServiceController(spyPatcher, &spyDaemonSetPodDeleter{}, sc)
+
+		c.OnAdd(service("ns2", 5140))
+		if spyPatcher.patchCalled {
+			t.Fatal("Expected an unreferenced service to not be patched")
+		}
+
+		c.OnAdd(service("ns1", 5140))
+		patch := findPatch(lastPatch(t, spyPatcher), "/data/outputs.conf")
+		if !strings.Contains(patch.Value, "Addr collector.ns1.svc:5140") {
+			t.Errorf("Expected the service address, got %s", patch.Value)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultSeverityField is the field the numeric severity is set in when a
+// sink does not name one.
+const defaultSeverityField = "severity_number"
+
+// severityMappingLua sets the numeric severity of the records whose
+// severity is mapped. It runs after sampling, which reads the severity as
+// logged.
+func severityMappingLua(name string, mapping map[string]int, field string) luaStep {
+	if field == "" {
+		field = defaultSeverityField
+	}
+
+	names := make([]string, 0, len(mapping))
+	for s := range mapping {
+		names = append(names, s)
+	}
+	sort.Strings(names)
+
+	levels := make([]string, 0, len(names))
+	for _, s := range names {
+		levels = append(levels, fmt.Sprintf("[%q] = %d", s, mapping[s]))
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {%s}\n", name, strings.Join(levels, ", ")),
+		body: fmt.Sprintf(`
+    local level = %s[severity(record)]
+    if level ~= nil then
+        record[%q] = level
+        code = 1
+    end
+`, name, field),
+	}
+}
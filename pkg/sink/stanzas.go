@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink/flbconfig"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PipelineStanzas returns the type and plugin of every section fluent-bit
+// reads, e.g. "FILTER kubernetes", in the order it reads them, so tests can
+// assert the order of the pipeline without matching the rendered config.
+// files are the files of the fluent-bit configmap as deployed. The service
+// config and the outputs rendered for objs, which are LogSinks,
+// ClusterLogSinks and ClusterFilterSets, replace theirs and the @INCLUDEs
+// are followed from fluent-bit.conf.
+func PipelineStanzas(files map[string]string, objs ...runtime.Object) ([]string, error) {
+	sc := NewConfig()
+	for _, o := range objs {
+		switch obj := o.(type) {
+		case *v1alpha1.LogSink:
+			sc.UpsertSink(obj)
+		case *v1alpha1.ClusterLogSink:
+			sc.UpsertClusterSink(obj)
+		case *v1alpha1.ClusterFilterSet:
+			sc.UpsertFilterSet(obj)
+		default:
+			return nil, fmt.Errorf("unsupported object %T", o)
+		}
+	}
+
+	rendered := make(map[string]string, len(files)+2)
+	for name, content := range files {
+		rendered[name] = content
+	}
+	rendered["fluent-bit.conf"] = fmt.Sprintf(serviceConfigTemplate, DefaultFlush, DefaultGrace, "")
+	rendered["outputs.conf"] = sc.String()
+
+	config, err := expandIncludes(rendered, "fluent-bit.conf", nil)
+	if err != nil {
+		return nil, err
+	}
+	f, err := flbconfig.Parse("fluent-bit.conf", config)
+	if err != nil {
+		return nil, err
+	}
+
+	var stanzas []string
+	for _, s := range f.Sections {
+		if s.Name == "" {
+			continue
+		}
+		stanza := s.Name
+		for _, kv := range s.KeyValues {
+			if strings.EqualFold(kv.Key, "Name") {
+				stanza += " " + kv.Value
+				break
+			}
+		}
+		stanzas = append(stanzas, stanza)
+	}
+	return stanzas, nil
+}
+
+// expandIncludes returns the file with every @INCLUDE replaced by the
+// included file. including holds the files being expanded, which an
+// include cycle would include again.
+func expandIncludes(files map[string]string, name string, including []string) (string, error) {
+	for _, n := range including {
+		if n == name {
+			return "", fmt.Errorf("include cycle at %s", name)
+		}
+	}
+	content, ok := files[name]
+	if !ok {
+		return "", fmt.Errorf("included file %s not found", name)
+	}
+
+	var expanded []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "@INCLUDE" {
+			expanded = append(expanded, line)
+			continue
+		}
+
+		included, err := expandIncludes(files, fields[1], append(including, name))
+		if err != nil {
+			return "", err
+		}
+		expanded = append(expanded, included)
+	}
+	return strings.Join(expanded, "\n"), nil
+}
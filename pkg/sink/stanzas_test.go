@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coreV1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestPipelineStanzas(t *testing.T) {
+	files := deployedFiles(t)
+	grepSet := &v1alpha1.ClusterFilterSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "drop-health-checks"},
+		Spec: v1alpha1.ClusterFilterSetSpec{
+			Filters: []v1alpha1.FilterSpec{{
+				Name:    "grep",
+				Options: map[string]string{"Exclude": "log healthz"},
+			}},
+		},
+	}
+	filteredSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: v1alpha1.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			FilterSetRefs:        []string{"drop-health-checks"},
+			IncludeImageMetadata: true,
+		},
+	}
+
+	t.Run("it lists the stanzas in the order fluent-bit reads them", func(t *testing.T) {
+		stanzas, err := sink.PipelineStanzas(files, grepSet, filteredSink)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []string{
+			"SERVICE",
+			"INPUT tail",
+			"INPUT forward",
+			"FILTER kubernetes",
+			"FILTER rewrite_tag",
+			"FILTER grep",
+			"FILTER lua",
+			"OUTPUT http",
+		}
+		if diff := cmp.Diff(expected, stanzas); diff != "" {
+			t.Errorf("Stanzas not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it runs the kubernetes filter before every other filter", func(t *testing.T) {
+		clusterSink := &v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-sink"},
+			Spec: v1alpha1.SinkSpec{
+				Type: "syslog",
+				SyslogSpec: v1alpha1.SyslogSpec{
+					Host: "example.com",
+					Port: 12345,
+				},
+				FilterSetRefs:   []string{"drop-health-checks"},
+				IncludeSequence: true,
+				Priority:        -10,
+			},
+		}
+		stanzas, err := sink.PipelineStanzas(files, grepSet, filteredSink, clusterSink)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var kubernetes, filters int
+		for _, s := range stanzas {
+			switch {
+			case s == "FILTER kubernetes":
+				if filters > 0 {
+					t.Errorf("Expected the kubernetes filter first, got %v", stanzas)
+				}
+				kubernetes++
+			case len(s) > len("FILTER") && s[:len("FILTER")] == "FILTER":
+				filters++
+			}
+		}
+		if kubernetes != 1 || filters == 0 {
+			t.Errorf("Expected one kubernetes filter and the sinks' filters, got %v", stanzas)
+		}
+	})
+
+	t.Run("it rejects unsupported objects", func(t *testing.T) {
+		_, err := sink.PipelineStanzas(files, &coreV1.ConfigMap{})
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+// deployedFiles returns the files of the fluent-bit configmap as it is
+// deployed.
+func deployedFiles(t *testing.T) map[string]string {
+	f, err := os.Open("../../config/300-fluent-bit-config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var cm coreV1.ConfigMap
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&cm); err != nil {
+		t.Fatal(err)
+	}
+	return cm.Data
+}
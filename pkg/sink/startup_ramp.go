@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// startupRampLua drops the records the sink receives above its startup rate
+// in each second until its startup delay has passed since the script was
+// loaded. fluent-bit's throttle filter can not be lifted once it is
+// running, so the ramp is counted in Lua instead. The script is loaded when
+// fluent-bit starts, so the ramp restarts with every fluent-bit pod.
+//
+// Seconds are read from the clock rather than the records, since the
+// backlog read at startup has timestamps from before it.
+func startupRampLua(name string, spec v1alpha1.SinkSpec) luaStep {
+	delay := int(math.Ceil(spec.StartupDelay.Seconds()))
+	if delay < 1 {
+		delay = 1
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {ends = os.time() + %d, second = 0, count = 0}\n", name, delay),
+		body: fmt.Sprintf(`
+    if %[1]s.ends ~= nil then
+        local now = os.time()
+        if now >= %[1]s.ends then
+            %[1]s.ends = nil
+        else
+            if now ~= %[1]s.second then
+                %[1]s.second = now
+                %[1]s.count = 0
+            end
+            %[1]s.count = %[1]s.count + 1
+            if %[1]s.count > %[2]d then
+                return -1, timestamp, record
+            end
+        end
+    end
+`, name, spec.StartupRate),
+	}
+}
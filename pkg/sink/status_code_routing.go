@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// CodeRange is an inclusive range of status codes.
+type CodeRange struct {
+	Lo, Hi int
+}
+
+// codeRangeFormat matches a status code or a range of status codes.
+var codeRangeFormat = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+
+// statusCodeRoutes returns the routes of the status code ranges ordered by
+// their lowest code. Ranges that cannot be parsed are rejected by the
+// webhook and are not routed.
+func statusCodeRoutes(routing *v1alpha1.StatusCodeRoutingSpec) []route {
+	ranges := make(map[CodeRange]string, len(routing.Routes))
+	sorted := make([]CodeRange, 0, len(routing.Routes))
+	for r, url := range routing.Routes {
+		cr, ok := ParseCodeRange(r)
+		if !ok {
+			continue
+		}
+		ranges[cr] = url
+		sorted = append(sorted, cr)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Lo < sorted[j].Lo
+	})
+
+	rs := make([]route, 0, len(sorted))
+	for _, cr := range sorted {
+		rs = append(rs, route{
+			key:     "$" + routing.Field,
+			pattern: codeRangePattern(cr),
+			url:     ranges[cr],
+		})
+	}
+	return rs
+}
+
+// ParseCodeRange parses a range like 500-599 or a single code like 404.
+// The webhook rejects the routes it cannot parse.
+func ParseCodeRange(s string) (CodeRange, bool) {
+	m := codeRangeFormat.FindStringSubmatch(s)
+	if m == nil {
+		return CodeRange{}, false
+	}
+	lo, err := strconv.Atoi(m[1])
+	if err != nil {
+		return CodeRange{}, false
+	}
+	hi := lo
+	if m[2] != "" {
+		hi, err = strconv.Atoi(m[2])
+		if err != nil || hi < lo {
+			return CodeRange{}, false
+		}
+	}
+	return CodeRange{Lo: lo, Hi: hi}, true
+}
+
+// codeRangePattern returns a regex matching the decimal codes of the range
+// and nothing else. Rewrite_tag rules match the text of the field, so the
+// range is split into runs of digits, e.g. 500-599 becomes ^5\d{2}$.
+func codeRangePattern(cr CodeRange) string {
+	var patterns []string
+	for digits := len(strconv.Itoa(cr.Lo)); digits <= len(strconv.Itoa(cr.Hi)); digits++ {
+		lo, hi := cr.Lo, cr.Hi
+		if min := pow10(digits - 1); digits > 1 && lo < min {
+			lo = min
+		}
+		if max := pow10(digits) - 1; hi > max {
+			hi = max
+		}
+		patterns = append(patterns, digitPatterns(strconv.Itoa(lo), strconv.Itoa(hi))...)
+	}
+
+	if len(patterns) == 1 {
+		return "^" + patterns[0] + "$"
+	}
+	return "^(" + strings.Join(patterns, "|") + ")$"
+}
+
+// digitPatterns returns the patterns matching the numbers from lo to hi,
+// which have as many digits.
+func digitPatterns(lo, hi string) []string {
+	if lo == "" {
+		return []string{""}
+	}
+	if lo == strings.Repeat("0", len(lo)) && hi == strings.Repeat("9", len(hi)) {
+		return []string{anyDigits(len(lo))}
+	}
+	rest := len(lo) - 1
+	if lo[0] == hi[0] {
+		var patterns []string
+		for _, p := range digitPatterns(lo[1:], hi[1:]) {
+			patterns = append(patterns, lo[:1]+p)
+		}
+		return patterns
+	}
+
+	zeros := strings.Repeat("0", rest)
+	nines := strings.Repeat("9", rest)
+	first, last := lo[0], hi[0]
+	var patterns []string
+	if lo[1:] != zeros {
+		for _, p := range digitPatterns(lo[1:], nines) {
+			patterns = append(patterns, lo[:1]+p)
+		}
+		first++
+	}
+	if hi[1:] != nines {
+		last--
+	}
+	if first <= last {
+		patterns = append(patterns, digitClass(first, last)+anyDigits(rest))
+	}
+	if hi[1:] != nines {
+		for _, p := range digitPatterns(zeros, hi[1:]) {
+			patterns = append(patterns, hi[:1]+p)
+		}
+	}
+	return patterns
+}
+
+func digitClass(lo, hi byte) string {
+	if lo == hi {
+		return string(lo)
+	}
+	return fmt.Sprintf("[%c-%c]", lo, hi)
+}
+
+func anyDigits(n int) string {
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return `\d`
+	}
+	return fmt.Sprintf(`\d{%d}`, n)
+}
+
+func pow10(n int) int {
+	p := 1
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
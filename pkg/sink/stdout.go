@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+const stdoutOutputConfig = `
+[OUTPUT]
+    Name stdout
+    Match %s
+`
+
+// WithDebugStdout renders a stdout output for sinks with DebugStdout set.
+// Without it the field is ignored, so debug outputs can be disabled for a
+// whole cluster.
+func WithDebugStdout() ConfigOpt {
+	return func(sc *Config) {
+		sc.debugStdout = true
+	}
+}
+
+// debugStdoutConfig renders a stdout output for the records of every sink
+// with DebugStdout set. Sinks of a namespace that read its shared records
+// receive the same records, so they share an output and records are only
+// written once.
+func (sc *Config) debugStdoutConfig() string {
+	if !sc.debugStdout {
+		return ""
+	}
+
+	var config string
+	rendered := make(map[string]bool)
+	for _, ref := range sc.filteredSinkRefs() {
+		if !ref.spec.DebugStdout {
+			continue
+		}
+		match := sinkMatch(ref)
+		if rendered[match] {
+			continue
+		}
+		rendered[match] = true
+		config += fmt.Sprintf(stdoutOutputConfig, match)
+	}
+
+	return config
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestDebugStdout(t *testing.T) {
+	debugSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "debug-sink",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: v1alpha1.SyslogSpec{
+				Host: "example.com",
+				Port: 12345,
+			},
+			DebugStdout: true,
+		},
+	}
+
+	t.Run("it adds a stdout output for the sink's records", func(t *testing.T) {
+		sc := sink.NewConfig(sink.WithDebugStdout())
+		sc.UpsertSink(debugSink)
+		sc.UpsertSink(&v1alpha1.LogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "raw-sink",
+				Namespace: "ns2",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/raw",
+				},
+				RawMode:     true,
+				DebugStdout: true,
+			},
+		})
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-sink",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "webhook",
+				WebhookSpec: v1alpha1.WebhookSpec{
+					URL: "https://example.com/cluster",
+				},
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name syslog
+    Match *_ns1_*
+    Alias ns1/debug-sink
+    InstanceName ns1/debug-sink
+    Addr example.com:12345
+    Namespace ns1
+
+[OUTPUT]
+    Name http
+    Match raw.ns.ns2.raw-sink
+    Alias ns2/raw-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /raw
+    tls On
+
+
+[OUTPUT]
+    Name http
+    Match *_*
+    Alias cluster:cluster-sink
+    Format json
+    Host example.com
+    Port 443
+    URI /cluster
+    tls On
+
+
+[OUTPUT]
+    Name stdout
+    Match *_ns1_*
+
+[OUTPUT]
+    Name stdout
+    Match raw.ns.ns2.raw-sink
+`
+		config := sc.String()
+		config = config[strings.Index(config, "\n[OUTPUT]"):]
+		if diff := cmp.Diff(expected, config); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it adds one output for sinks sharing records", func(t *testing.T) {
+		other := debugSink.DeepCopy()
+		other.Name = "other-sink"
+
+		sc := sink.NewConfig(sink.WithDebugStdout())
+		sc.UpsertSink(debugSink)
+		sc.UpsertSink(other)
+
+		if n := strings.Count(sc.String(), "Name stdout"); n != 1 {
+			t.Errorf("Expected one stdout output, got %d in %s", n, sc.String())
+		}
+	})
+
+	t.Run("it omits the output when debug outputs are disabled", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(debugSink)
+
+		if config := sc.String(); strings.Contains(config, "stdout") {
+			t.Errorf("Expected no stdout output, got %s", config)
+		}
+	})
+
+	t.Run("it omits the output for sinks without debug stdout", func(t *testing.T) {
+		s := debugSink.DeepCopy()
+		s.Spec.DebugStdout = false
+
+		sc := sink.NewConfig(sink.WithDebugStdout())
+		sc.UpsertSink(s)
+
+		if config := sc.String(); strings.Contains(config, "stdout") {
+			t.Errorf("Expected no stdout output, got %s", config)
+		}
+	})
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"regexp/syntax"
+	"strings"
+)
+
+// stripKeysPrelude matches keys with regular expressions compiled by
+// regexProgLua. Lua patterns are not regular expressions, so the program of
+// the expression is run by a backtracking matcher that visits each
+// instruction at each position at most once. It matches bytes rather than
+// runes, which is enough for label and annotation keys since they are
+// ASCII.
+const stripKeysPrelude = `
+local function is_word(c)
+    return c ~= nil and (c == 95 or
+        (c >= 48 and c <= 57) or
+        (c >= 65 and c <= 90) or
+        (c >= 97 and c <= 122))
+end
+
+local function empty_ok(inst, s, i)
+    local before = nil
+    if i > 1 then
+        before = s:byte(i - 1)
+    end
+    local after = s:byte(i)
+
+    if inst.begin_text and i ~= 1 then
+        return false
+    end
+    if inst.end_text and i ~= #s + 1 then
+        return false
+    end
+    if inst.begin_line and before ~= nil and before ~= 10 then
+        return false
+    end
+    if inst.end_line and after ~= nil and after ~= 10 then
+        return false
+    end
+    local boundary = is_word(before) ~= is_word(after)
+    if inst.word_boundary and not boundary then
+        return false
+    end
+    if inst.no_word_boundary and boundary then
+        return false
+    end
+    return true
+end
+
+local function regex_find(prog, s)
+    local visited = {}
+    local width = #s + 2
+
+    local function run(pc, i)
+        local key = pc * width + i
+        if visited[key] then
+            return false
+        end
+        visited[key] = true
+
+        local inst = prog[pc]
+        local op = inst[1]
+        if op == "match" then
+            return true
+        elseif op == "alt" then
+            return run(inst[2], i) or run(inst[3], i)
+        elseif op == "nop" then
+            return run(inst[2], i)
+        elseif op == "empty" then
+            if empty_ok(inst, s, i) then
+                return run(inst[2], i)
+            end
+        elseif op == "byte" then
+            local c = s:byte(i)
+            if c ~= nil then
+                local ranges = inst[3]
+                for r = 1, #ranges, 2 do
+                    if c >= ranges[r] and c <= ranges[r + 1] then
+                        return run(inst[2], i + 1)
+                    end
+                end
+            end
+        elseif op == "any" then
+            if i <= #s then
+                return run(inst[2], i + 1)
+            end
+        elseif op == "any_not_nl" then
+            local c = s:byte(i)
+            if c ~= nil and c ~= 10 then
+                return run(inst[2], i + 1)
+            end
+        end
+        return false
+    end
+
+    for i = 1, #s + 1 do
+        if run(prog.start, i) then
+            return true
+        end
+    end
+    return false
+end
+
+local function strip_keys(record, prog)
+    local k = record["kubernetes"]
+    if type(k) ~= "table" then
+        return false
+    end
+
+    local stripped = false
+    for _, field in ipairs({"labels", "annotations"}) do
+        local m = k[field]
+        if type(m) == "table" then
+            for key in pairs(m) do
+                if type(key) == "string" and regex_find(prog, key) then
+                    m[key] = nil
+                    stripped = true
+                end
+            end
+        end
+    end
+    return stripped
+end
+`
+
+// stripKeysLua removes the labels and annotations with keys matching expr.
+// The program is declared as name.
+func stripKeysLua(name, expr string) (luaStep, bool) {
+	prog, err := regexProgLua(expr)
+	if err != nil {
+		log.Printf("Unable to compile strip key regex %q: %s", expr, err)
+		return luaStep{}, false
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = %s\n", name, prog),
+		body: fmt.Sprintf(`
+    if strip_keys(record, %s) then
+        code = 1
+    end
+`, name),
+	}, true
+}
+
+// regexProgLua compiles expr and renders its program as a Lua table of
+// instructions indexed by pc. Rune instructions are rendered as the ranges
+// of ASCII bytes they match.
+func regexProgLua(expr string) (string, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return "", err
+	}
+
+	insts := []string{fmt.Sprintf("start = %d", prog.Start)}
+	for pc, inst := range prog.Inst {
+		insts = append(insts, fmt.Sprintf("[%d] = %s", pc, instLua(inst)))
+	}
+
+	return "{" + strings.Join(insts, ", ") + "}", nil
+}
+
+func instLua(inst syntax.Inst) string {
+	switch inst.Op {
+	case syntax.InstAlt, syntax.InstAltMatch:
+		return fmt.Sprintf(`{"alt", %d, %d}`, inst.Out, inst.Arg)
+	case syntax.InstCapture, syntax.InstNop:
+		return fmt.Sprintf(`{"nop", %d}`, inst.Out)
+	case syntax.InstEmptyWidth:
+		return fmt.Sprintf(`{"empty", %d%s}`, inst.Out, emptyFlagsLua(syntax.EmptyOp(inst.Arg)))
+	case syntax.InstMatch:
+		return `{"match"}`
+	case syntax.InstRune, syntax.InstRune1:
+		ranges := asciiRanges(inst)
+		if len(ranges) == 0 {
+			return `{"fail"}`
+		}
+		return fmt.Sprintf(`{"byte", %d, {%s}}`, inst.Out, strings.Join(ranges, ", "))
+	case syntax.InstRuneAny:
+		return fmt.Sprintf(`{"any", %d}`, inst.Out)
+	case syntax.InstRuneAnyNotNL:
+		return fmt.Sprintf(`{"any_not_nl", %d}`, inst.Out)
+	default:
+		return `{"fail"}`
+	}
+}
+
+func emptyFlagsLua(op syntax.EmptyOp) string {
+	var flags string
+	for _, f := range []struct {
+		op   syntax.EmptyOp
+		name string
+	}{
+		{syntax.EmptyBeginLine, "begin_line"},
+		{syntax.EmptyEndLine, "end_line"},
+		{syntax.EmptyBeginText, "begin_text"},
+		{syntax.EmptyEndText, "end_text"},
+		{syntax.EmptyWordBoundary, "word_boundary"},
+		{syntax.EmptyNoWordBoundary, "no_word_boundary"},
+	} {
+		if op&f.op != 0 {
+			flags += fmt.Sprintf(", %s = true", f.name)
+		}
+	}
+	return flags
+}
+
+// asciiRanges returns the inclusive ranges of ASCII bytes the instruction
+// matches as lo, hi pairs.
+func asciiRanges(inst syntax.Inst) []string {
+	var ranges []string
+	for lo := rune(0); lo < 128; lo++ {
+		if !inst.MatchRune(lo) {
+			continue
+		}
+		hi := lo
+		for hi+1 < 128 && inst.MatchRune(hi+1) {
+			hi++
+		}
+		ranges = append(ranges, fmt.Sprintf("%d, %d", lo, hi))
+		lo = hi
+	}
+	return ranges
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// zoneOffsetYears is how many years of a timezone's offsets are rendered.
+// Lua has no timezone database, so the offsets are computed when the config
+// is rendered and the last one is used for records after them.
+const zoneOffsetYears = 12
+
+// zoneOffset is the offset from UTC in seconds that applies from start.
+type zoneOffset struct {
+	start  int64
+	offset int
+}
+
+// zoneOffsets returns the offsets of loc from the start of the year before
+// from until zoneOffsetYears later.
+func zoneOffsets(loc *time.Location, from time.Time) []zoneOffset {
+	start := time.Date(from.Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(zoneOffsetYears, 0, 0)
+
+	offsetAt := func(unix int64) int {
+		_, offset := time.Unix(unix, 0).In(loc).Zone()
+		return offset
+	}
+
+	offsets := []zoneOffset{{
+		start:  start.Unix(),
+		offset: offsetAt(start.Unix()),
+	}}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		lo, hi := day.Unix(), day.AddDate(0, 0, 1).Unix()
+		current := offsets[len(offsets)-1].offset
+		if offsetAt(hi) == current {
+			continue
+		}
+
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if offsetAt(mid) == current {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		offsets = append(offsets, zoneOffset{
+			start:  hi,
+			offset: offsetAt(hi),
+		})
+	}
+
+	return offsets
+}
+
+// timestampLua sets the timestamp field of records to their time in the
+// timezone.
+func timestampLua(timezone string, now time.Time) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		log.Printf("Unable to load timezone %s: %s", timezone, err)
+		return ""
+	}
+
+	var offsets []string
+	for _, o := range zoneOffsets(loc, now) {
+		offsets = append(offsets, fmt.Sprintf("{%d, %d}", o.start, o.offset))
+	}
+
+	return fmt.Sprintf(`
+    local offset = zone_offset(timestamp, {%s})
+    record["timestamp"] = format_time(timestamp, offset)
+    code = 1
+`, strings.Join(offsets, ", "))
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+const (
+	NodeKindNamespace   = "namespace"
+	NodeKindCluster     = "cluster"
+	NodeKindSink        = "logsink"
+	NodeKindClusterSink = "clusterlogsink"
+	NodeKindDestination = "destination"
+)
+
+// clusterNodeID is the single node every ClusterLogSink hangs off of, since
+// cluster sinks receive logs from all namespaces.
+const clusterNodeID = "cluster"
+
+// Topology is the routing graph of namespaces to sinks to destinations.
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+type TopologyNode struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// TopologyEdge connects two nodes. Edges from a sink to its destination
+// carry metadata about how records are delivered.
+type TopologyEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	TLS     bool   `json:"tls,omitempty"`
+	Filters int    `json:"filters"`
+}
+
+// Topology builds the routing graph for all sinks currently held by the
+// config.
+func (sc *Config) Topology() Topology {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	nodes := make(map[string]TopologyNode)
+	var edges []TopologyEdge
+
+	for _, s := range sc.sinks {
+		ns := canonicalNamespace(s.Namespace)
+		nsID := fmt.Sprintf("%s/%s", NodeKindNamespace, ns)
+		sinkID := fmt.Sprintf("%s/%s/%s", NodeKindSink, ns, s.Name)
+		nodes[nsID] = TopologyNode{ID: nsID, Kind: NodeKindNamespace, Name: ns}
+		nodes[sinkID] = TopologyNode{ID: sinkID, Kind: NodeKindSink, Name: s.Name}
+		edges = append(edges, TopologyEdge{From: nsID, To: sinkID})
+
+		for _, dest := range destinationNodes(ns, s.Spec) {
+			nodes[dest.ID] = dest
+			edges = append(edges, destinationEdge(sinkID, dest.ID, s.Spec))
+		}
+	}
+
+	if len(sc.clusterSinks) > 0 {
+		nodes[clusterNodeID] = TopologyNode{
+			ID:   clusterNodeID,
+			Kind: NodeKindCluster,
+			Name: clusterNodeID,
+		}
+	}
+	for _, s := range sc.clusterSinks {
+		sinkID := fmt.Sprintf("%s/%s", NodeKindClusterSink, s.Name)
+		nodes[sinkID] = TopologyNode{ID: sinkID, Kind: NodeKindClusterSink, Name: s.Name}
+		edges = append(edges, TopologyEdge{From: clusterNodeID, To: sinkID})
+
+		for _, dest := range destinationNodes("", s.Spec) {
+			nodes[dest.ID] = dest
+			edges = append(edges, destinationEdge(sinkID, dest.ID, s.Spec))
+		}
+	}
+
+	t := Topology{
+		Nodes: make([]TopologyNode, 0, len(nodes)),
+		Edges: make([]TopologyEdge, 0, len(edges)),
+	}
+	for _, n := range nodes {
+		t.Nodes = append(t.Nodes, n)
+	}
+	t.Edges = append(t.Edges, edges...)
+
+	sort.Slice(t.Nodes, func(i, j int) bool {
+		return t.Nodes[i].ID < t.Nodes[j].ID
+	})
+	sort.Slice(t.Edges, func(i, j int) bool {
+		if t.Edges[i].From != t.Edges[j].From {
+			return t.Edges[i].From < t.Edges[j].From
+		}
+		return t.Edges[i].To < t.Edges[j].To
+	})
+
+	return t
+}
+
+// TopologyHandler serves the config's routing graph as JSON.
+func TopologyHandler(sc *Config) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(sc.Topology())
+		if err != nil {
+			log.Printf("Unable to marshal topology: %s", err)
+		}
+	})
+}
+
+// destinationNodes returns the nodes of the destinations a sink sends to,
+// one for each receiver of a syslog sink. namespace is the namespace of a
+// LogSink, which its service ref defaults to, and empty for a
+// ClusterLogSink.
+func destinationNodes(namespace string, spec v1alpha1.SinkSpec) []TopologyNode {
+	var names []string
+	switch {
+	case spec.Type == "syslog" && spec.ServiceRef != nil:
+		if spec.ServiceRef.Namespace != "" {
+			namespace = spec.ServiceRef.Namespace
+		}
+		names = []string{fmt.Sprintf("syslog://%s.%s.svc:%s", spec.ServiceRef.Name, namespace, spec.ServiceRef.PortName)}
+	case spec.Type == "syslog":
+		for _, addr := range syslogAddrs(spec) {
+			names = append(names, "syslog://"+addr)
+		}
+	case spec.Type == "webhook":
+		u, err := url.Parse(spec.URL)
+		if err != nil {
+			return nil
+		}
+		u.User = nil
+		names = []string{u.String()}
+	case spec.Type == "unix_socket":
+		names = []string{"unix://" + spec.Path}
+	}
+
+	nodes := make([]TopologyNode, 0, len(names))
+	for _, name := range names {
+		nodes = append(nodes, TopologyNode{
+			ID:   fmt.Sprintf("%s/%s", NodeKindDestination, name),
+			Kind: NodeKindDestination,
+			Name: name,
+		})
+	}
+	return nodes
+}
+
+func destinationEdge(from, to string, spec v1alpha1.SinkSpec) TopologyEdge {
+	var tlsEnabled bool
+	switch spec.Type {
+	case "syslog":
+		tlsEnabled = spec.EnableTLS
+	case "webhook":
+		u, err := url.Parse(spec.URL)
+		tlsEnabled = err == nil && u.Scheme == "https"
+	}
+
+	return TopologyEdge{
+		From:    from,
+		To:      to,
+		TLS:     tlsEnabled,
+		Filters: filterCount(spec),
+	}
+}
+
+// filterCount is the number of filters applied to a sink's records on top of
+// the shared chain in filters.conf.
+func filterCount(spec v1alpha1.SinkSpec) int {
+	return len(sinkFilters("", "", spec))
+}
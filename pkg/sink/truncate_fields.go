@@ -0,0 +1,57 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// truncateFieldsLua cuts the string values of the fields to their limits.
+// A value is cut before the UTF-8 continuation bytes at the limit, so the
+// last character is not split. It runs after redaction, so that hashes
+// are taken of whole values, and before fields are required.
+func truncateFieldsLua(name string, limits map[string]int) luaStep {
+	fields := make([]string, 0, len(limits))
+	for f := range limits {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	quoted := make([]string, 0, len(fields))
+	for _, f := range fields {
+		quoted = append(quoted, fmt.Sprintf("{%s, %d}", luaFieldPath(f), limits[f]))
+	}
+
+	return luaStep{
+		decl: fmt.Sprintf("\nlocal %s = {%s}\n", name, strings.Join(quoted, ", ")),
+		body: fmt.Sprintf(`
+    for _, limit in ipairs(%s) do
+        local path, n = limit[1], limit[2]
+        local parent, key = field_parent(record, path), path[#path]
+        if parent ~= nil and type(parent[key]) == "string" and #parent[key] > n then
+            local value = parent[key]
+            while n > 0 and value:byte(n + 1) >= 128 and value:byte(n + 1) < 192 do
+                n = n - 1
+            end
+            parent[key] = value:sub(1, n)
+            code = 1
+        end
+    end
+`, name),
+	}
+}
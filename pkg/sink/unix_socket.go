@@ -0,0 +1,42 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+import "fmt"
+
+const unixSocketOutputConfig = `
+[OUTPUT]
+    Name forward
+    Match %s
+    Alias %s
+    Unix_Path %s
+%s`
+
+// unixSocketConfig renders a forward output over a Unix domain socket for
+// every unix_socket sink.
+func (sc *Config) unixSocketConfig() string {
+	var config string
+	for _, ref := range sc.sinkRefs("unix_socket") {
+		if sc.openCircuits[ref.key] {
+			config += nullOutputConfig(sinkMatch(ref), outputAlias(ref))
+			continue
+		}
+
+		config += fmt.Sprintf(unixSocketOutputConfig, sinkMatch(ref), outputAlias(ref), ref.spec.Path, sc.bufferLimitConfig(ref.spec))
+	}
+
+	return config
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/client/clientset/versioned/fake"
+	"github.com/knative/observability/pkg/sink"
+)
+
+func TestUnixSocketSinks(t *testing.T) {
+	unixSink := &v1alpha1.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "collector",
+			Namespace: "ns1",
+		},
+		Spec: v1alpha1.SinkSpec{
+			Type: "unix_socket",
+			UnixSocketSpec: v1alpha1.UnixSocketSpec{
+				Path: "/var/run/observability/collector.sock",
+			},
+		},
+	}
+
+	t.Run("it renders a forward output over the socket", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(unixSink)
+		sc.UpsertClusterSink(&v1alpha1.ClusterLogSink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster-collector",
+			},
+			Spec: v1alpha1.SinkSpec{
+				Type: "unix_socket",
+				UnixSocketSpec: v1alpha1.UnixSocketSpec{
+					Path: "/var/run/observability/cluster.sock",
+				},
+			},
+		})
+
+		expected := `
+[OUTPUT]
+    Name forward
+    Match *_ns1_*
+    Alias ns1/collector
+    Unix_Path /var/run/observability/collector.sock
+
+[OUTPUT]
+    Name forward
+    Match *_*
+    Alias cluster:cluster-collector
+    Unix_Path /var/run/observability/cluster.sock
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it applies the sink's filters", func(t *testing.T) {
+		s := unixSink.DeepCopy()
+		s.Spec.SeveritySampling = map[string]float64{"info": 0.5}
+		sc := sink.NewConfig()
+		sc.UpsertSink(s)
+
+		config := sc.String()
+		filter := strings.Index(config, "[FILTER]")
+		output := strings.Index(config, "Name forward")
+		if filter == -1 || filter > output {
+			t.Errorf("Expected a filter before the output, got %s", config)
+		}
+	})
+
+	t.Run("it renders a null output while the circuit is open", func(t *testing.T) {
+		sc := sink.NewConfig()
+		sc.UpsertSink(unixSink)
+		b := sink.NewBreaker(
+			&spyConfigMapPatcher{},
+			&spyDaemonSetPodDeleter{},
+			fake.NewSimpleClientset(unixSink).ObservabilityV1alpha1(),
+			sc,
+			sink.WithFailureThreshold(1),
+		)
+
+		b.Observe(map[string]sink.OutputMetrics{"ns1/collector": {}})
+		b.Observe(map[string]sink.OutputMetrics{"ns1/collector": {Errors: 1}})
+
+		expected := `
+[OUTPUT]
+    Name null
+    Match *_ns1_*
+    Alias ns1/collector
+`
+		if diff := cmp.Diff(expected, sc.String()); diff != "" {
+			t.Errorf("Config not equal (-want, +got) = %v", diff)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sink
+
+// utf8Prelude defines sanitize_utf8, which replaces every byte of a string
+// that does not start a valid UTF-8 sequence with U+FFFD. Overlong
+// encodings, surrogates and sequences past U+10FFFF are invalid. It
+// returns whether it replaced any byte.
+const utf8Prelude = `
+local function utf8_len(s, i)
+    local c = s:byte(i)
+    if c < 128 then
+        return 1
+    end
+
+    local n, lo, hi = 0, 128, 191
+    if c >= 194 and c <= 223 then
+        n = 2
+    elseif c >= 224 and c <= 239 then
+        n = 3
+        if c == 224 then
+            lo = 160
+        elseif c == 237 then
+            hi = 159
+        end
+    elseif c >= 240 and c <= 244 then
+        n = 4
+        if c == 240 then
+            lo = 144
+        elseif c == 244 then
+            hi = 143
+        end
+    else
+        return nil
+    end
+
+    for j = 1, n - 1 do
+        local b = s:byte(i + j)
+        if b == nil or b < lo or b > hi then
+            return nil
+        end
+        lo, hi = 128, 191
+    end
+    return n
+end
+
+local function sanitize_utf8(s)
+    local parts = {}
+    local i, start = 1, 1
+    while i <= #s do
+        local n = utf8_len(s, i)
+        if n == nil then
+            parts[#parts + 1] = s:sub(start, i - 1) .. "\239\191\189"
+            i = i + 1
+            start = i
+        else
+            i = i + n
+        end
+    end
+    if #parts == 0 then
+        return s, false
+    end
+    parts[#parts + 1] = s:sub(start)
+    return table.concat(parts), true
+end
+`
+
+// sanitizeUTF8Body replaces the invalid UTF-8 of a record's log.
+const sanitizeUTF8Body = `
+    local log = record["log"]
+    if type(log) == "string" then
+        local sanitized, replaced = sanitize_utf8(log)
+        if replaced then
+            record["log"] = sanitized
+            code = 1
+        end
+    end
+`
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func (s *Server) filterSetHandler(w http.ResponseWriter, r *http.Request) {
+	requestedAdmissionReview, httpErr := deserializeReview(r)
+	if httpErr != nil {
+		httpErr.Write(w)
+		return
+	}
+	if resp := s.checkOperation(requestedAdmissionReview); resp != nil {
+		writeResponse(w, requestedAdmissionReview, resp)
+		return
+	}
+
+	var fs sink.ClusterFilterSet
+	err := json.Unmarshal(requestedAdmissionReview.Request.Object.Raw, &fs)
+	if err != nil {
+		errUnableToDeserialize.Write(w)
+		return
+	}
+
+	resp := &v1beta1.AdmissionResponse{
+		UID:     requestedAdmissionReview.Request.UID,
+		Allowed: true,
+	}
+	if errs := ValidateClusterFilterSet(&fs); len(errs) > 0 {
+		resp = toAdmissionErrorResponse(errs[0].Detail)
+	}
+	writeResponse(w, requestedAdmissionReview, resp)
+}
+
+// ValidateClusterFilterSet validates the filters of a set, which are
+// rendered into the fluent-bit config a line per option. A name or key
+// with whitespace would be split into a different key and value, and a
+// value with a line break would add lines of its own. The sink-controller
+// sets the name and match of each filter, so the options cannot.
+func ValidateClusterFilterSet(fs *sink.ClusterFilterSet) field.ErrorList {
+	var errs field.ErrorList
+	fldPath := field.NewPath("spec", "filters")
+	for i, f := range fs.Spec.Filters {
+		if !validFilterToken(f.Name) {
+			errs = append(errs, field.Invalid(fldPath.Index(i).Child("name"), f.Name, ConfigFilterSetBadNameError))
+		}
+		for k, v := range f.Options {
+			optPath := fldPath.Index(i).Child("options").Key(k)
+			if !validFilterToken(k) || strings.EqualFold(k, "name") || strings.EqualFold(k, "match") {
+				errs = append(errs, field.Invalid(optPath, k, ConfigFilterSetBadKeyError))
+			}
+			if strings.ContainsAny(v, "\r\n") {
+				errs = append(errs, field.Invalid(optPath, v, ConfigFilterSetBadValueError))
+			}
+		}
+	}
+	return errs
+}
+
+func validFilterToken(s string) bool {
+	return s != "" && strings.IndexFunc(s, unicode.IsSpace) == -1
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestValidateClusterFilterSet(t *testing.T) {
+	filterSet := func(filters ...sink.FilterSpec) *sink.ClusterFilterSet {
+		return &sink.ClusterFilterSet{
+			Spec: sink.ClusterFilterSetSpec{Filters: filters},
+		}
+	}
+
+	t.Run("it allows filters with options", func(t *testing.T) {
+		errs := webhook.ValidateClusterFilterSet(filterSet(sink.FilterSpec{
+			Name:    "grep",
+			Options: map[string]string{"Exclude": "log health check"},
+		}))
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	for _, tc := range []struct {
+		name   string
+		filter sink.FilterSpec
+		msg    string
+	}{
+		{"an empty name", sink.FilterSpec{}, webhook.ConfigFilterSetBadNameError},
+		{"a name with whitespace", sink.FilterSpec{Name: "grep\n[OUTPUT]"}, webhook.ConfigFilterSetBadNameError},
+		{"a key with whitespace", sink.FilterSpec{Name: "grep", Options: map[string]string{"Regex log": "x"}}, webhook.ConfigFilterSetBadKeyError},
+		{"an empty key", sink.FilterSpec{Name: "grep", Options: map[string]string{"": "x"}}, webhook.ConfigFilterSetBadKeyError},
+		{"a match key", sink.FilterSpec{Name: "grep", Options: map[string]string{"Match": "*"}}, webhook.ConfigFilterSetBadKeyError},
+		{"a name key", sink.FilterSpec{Name: "grep", Options: map[string]string{"name": "modify"}}, webhook.ConfigFilterSetBadKeyError},
+		{"a value with a newline", sink.FilterSpec{Name: "grep", Options: map[string]string{"Exclude": "log x\n[OUTPUT]"}}, webhook.ConfigFilterSetBadValueError},
+		{"a value with a carriage return", sink.FilterSpec{Name: "grep", Options: map[string]string{"Exclude": "log x\r"}}, webhook.ConfigFilterSetBadValueError},
+	} {
+		t.Run("it rejects "+tc.name, func(t *testing.T) {
+			errs := webhook.ValidateClusterFilterSet(filterSet(tc.filter))
+			if len(errs) == 0 {
+				t.Fatal("expected an error")
+			}
+			if errs[0].Detail != tc.msg {
+				t.Errorf("expected message %q, got %q", tc.msg, errs[0].Detail)
+			}
+		})
+	}
+}
+
+func TestFilterSetHandler(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it rejects invalid filter sets", func(t *testing.T) {
+		resp := postReview(t, server, "/filterset", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterFilterSet",
+			"",
+			`{"filters": [{"name": "grep", "options": {"Exclude": "log x\n[OUTPUT]"}}]}`,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigFilterSetBadValueError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigFilterSetBadValueError, resp.Response.Result.Message)
+		}
+	})
+
+	t.Run("it allows valid filter sets", func(t *testing.T) {
+		resp := postReview(t, server, "/filterset", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterFilterSet",
+			"",
+			`{"filters": [{"name": "grep", "options": {"Exclude": "log healthz"}}]}`,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"expvar"
+	"strings"
+
+	"k8s.io/api/admission/v1beta1"
+)
+
+// AdmissionTotal counts the admission decisions of the webhook with keys of
+// the form <operation>_<decision>, e.g. create_reject. The decision is
+// allow, reject or warn for sinks that are allowed with an audit
+// annotation.
+var AdmissionTotal *expvar.Map
+
+func init() {
+	AdmissionTotal = expvar.NewMap("sink_admission_total")
+}
+
+func recordAdmission(rar *v1beta1.AdmissionReview, resp *v1beta1.AdmissionResponse) {
+	decision := "allow"
+	switch {
+	case !resp.Allowed:
+		decision = "reject"
+	case len(resp.AuditAnnotations) > 0:
+		decision = "warn"
+	}
+
+	AdmissionTotal.Add(strings.ToLower(string(rar.Request.Operation))+"_"+decision, 1)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestAdmissionMetrics(t *testing.T) {
+	server := webhook.NewServer(
+		"127.0.0.1:0",
+		webhook.WithObservabilityNamespace("knative-observability"),
+	)
+	server.Run(false)
+	defer server.Close()
+
+	var tests = []struct {
+		name     string
+		kind     string
+		spec     string
+		decision string
+	}{
+		{
+			"it counts allowed sinks",
+			"LogSink",
+			`{"type": "webhook", "url": "https://example.com/place"}`,
+			"create_allow",
+		},
+		{
+			"it counts rejected sinks",
+			"LogSink",
+			`{"type": "webhook", "url": "http://example.com/place"}`,
+			"create_reject",
+		},
+		{
+			"it counts sinks allowed with a warning",
+			"ClusterLogSink",
+			`{"type": "webhook", "url": "https://example.com/place"}`,
+			"create_warn",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			before := admissionTotal(t, server)
+
+			postReview(t, server, "/logsink", fmt.Sprintf(
+				namespacedAdmissionTemplate,
+				test.kind,
+				"team-a",
+				test.spec,
+			))
+
+			after := admissionTotal(t, server)
+			for k := range after {
+				expected := before[k]
+				if k == test.decision {
+					expected++
+				}
+				if after[k] != expected {
+					t.Errorf("expected %s to be %d, got %d", k, expected, after[k])
+				}
+			}
+			if after[test.decision] == 0 {
+				t.Errorf("expected %s to be counted, got %v", test.decision, after)
+			}
+		})
+	}
+}
+
+// admissionTotal returns the admission counters served by the webhook.
+func admissionTotal(t *testing.T, server *webhook.Server) map[string]int64 {
+	t.Helper()
+	var (
+		err  error
+		resp *http.Response
+	)
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get("http://" + server.Addr() + "/debug/vars")
+		if err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var vars struct {
+		AdmissionTotal map[string]int64 `json:"sink_admission_total"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	return vars.AdmissionTotal
+}
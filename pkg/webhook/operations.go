@@ -0,0 +1,199 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"k8s.io/api/admission/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+)
+
+// OperationsKey is the key of the operation policy in the configmap the
+// validator can watch for it.
+const OperationsKey = "operations"
+
+// operationKinds are the kinds the validator is called for.
+var operationKinds = map[string]bool{
+	"LogSink":           true,
+	"ClusterLogSink":    true,
+	"MetricSink":        true,
+	"ClusterMetricSink": true,
+}
+
+var operations = map[v1beta1.Operation]bool{
+	v1beta1.Create: true,
+	v1beta1.Update: true,
+	v1beta1.Delete: true,
+}
+
+// OperationPolicy restricts the operations accepted for each kind, e.g. to
+// freeze the creation of sinks during a migration. Kinds without an entry
+// accept every operation.
+type OperationPolicy struct {
+	Kinds map[string][]v1beta1.Operation
+}
+
+// ParseOperationPolicy parses semicolon separated kind=operations pairs,
+// e.g. "LogSink=UPDATE,DELETE;ClusterLogSink=". A kind without operations
+// accepts none.
+func ParseOperationPolicy(s string) (OperationPolicy, error) {
+	p := OperationPolicy{
+		Kinds: make(map[string][]v1beta1.Operation),
+	}
+
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kv := strings.SplitN(entry, "=", 2)
+		kind := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || !operationKinds[kind] {
+			return OperationPolicy{}, fmt.Errorf("invalid kind operations %q, expected kind=operations for a sink kind", entry)
+		}
+
+		ops := []v1beta1.Operation{}
+		for _, op := range splitTypes(kv[1]) {
+			o := v1beta1.Operation(strings.ToUpper(op))
+			if !operations[o] {
+				return OperationPolicy{}, fmt.Errorf("invalid operation %q of %s, expected CREATE, UPDATE or DELETE", op, kind)
+			}
+			ops = append(ops, o)
+		}
+		p.Kinds[kind] = ops
+	}
+
+	return p, nil
+}
+
+// check returns the message an operation on kind is rejected with, or an
+// empty string if it is allowed.
+func (p OperationPolicy) check(kind string, op v1beta1.Operation) string {
+	allowed, ok := p.Kinds[kind]
+	if !ok {
+		return ""
+	}
+
+	names := make([]string, 0, len(allowed))
+	for _, o := range allowed {
+		if o == op {
+			return ""
+		}
+		names = append(names, string(o))
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("%s: %s of %s is not allowed, no operations are allowed", ConfigOperationNotAllowedError, op, kind)
+	}
+	return fmt.Sprintf(
+		"%s: %s of %s is not allowed, allowed operations are %s",
+		ConfigOperationNotAllowedError,
+		op,
+		kind,
+		strings.Join(names, ", "),
+	)
+}
+
+// WithOperationPolicy restricts the operations accepted for each kind.
+func WithOperationPolicy(p OperationPolicy) ServerOpt {
+	return func(s *Server) {
+		s.operations = p
+	}
+}
+
+// SetOperationPolicy replaces the operation policy of a running server.
+func (s *Server) SetOperationPolicy(p OperationPolicy) {
+	s.opMu.Lock()
+	defer s.opMu.Unlock()
+	s.operations = p
+}
+
+// checkOperation returns the response to the review when the policy
+// rejects its operation. Deletes carry no object to validate, so a
+// response allowing them is returned when the policy does.
+func (s *Server) checkOperation(rar *v1beta1.AdmissionReview) *v1beta1.AdmissionResponse {
+	s.opMu.RLock()
+	msg := s.operations.check(rar.Request.Kind.Kind, rar.Request.Operation)
+	s.opMu.RUnlock()
+
+	if msg != "" {
+		return toAdmissionErrorResponse(msg)
+	}
+	if rar.Request.Operation == v1beta1.Delete {
+		return &v1beta1.AdmissionResponse{
+			UID:     rar.Request.UID,
+			Allowed: true,
+		}
+	}
+	return nil
+}
+
+type OperationPolicySetter interface {
+	SetOperationPolicy(OperationPolicy)
+}
+
+// OperationPolicyController sets the operation policy of the server from
+// the operations key of a configmap, so operations can be frozen without
+// restarting the validator. The fallback policy is set when the configmap
+// is deleted or has no policy. A policy that cannot be parsed is logged and
+// the previous one is kept.
+type OperationPolicyController struct {
+	s        OperationPolicySetter
+	fallback OperationPolicy
+}
+
+func NewOperationPolicyController(s OperationPolicySetter, fallback OperationPolicy) *OperationPolicyController {
+	return &OperationPolicyController{
+		s:        s,
+		fallback: fallback,
+	}
+}
+
+func (c *OperationPolicyController) OnAdd(o interface{}) {
+	c.set(o)
+}
+
+func (c *OperationPolicyController) OnUpdate(_, n interface{}) {
+	c.set(n)
+}
+
+func (c *OperationPolicyController) OnDelete(o interface{}) {
+	log.Print("Operation policy configmap deleted, restoring the default policy")
+	c.s.SetOperationPolicy(c.fallback)
+}
+
+func (c *OperationPolicyController) set(o interface{}) {
+	cm, ok := o.(*coreV1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	policy, ok := cm.Data[OperationsKey]
+	if !ok {
+		c.s.SetOperationPolicy(c.fallback)
+		return
+	}
+	p, err := ParseOperationPolicy(policy)
+	if err != nil {
+		log.Printf("Unable to parse operation policy of configmap %s: %s", cm.Name, err)
+		return
+	}
+	c.s.SetOperationPolicy(p)
+}
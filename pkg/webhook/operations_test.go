@@ -0,0 +1,259 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/api/admission/v1beta1"
+	coreV1 "k8s.io/api/core/v1"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestParseOperationPolicy(t *testing.T) {
+	t.Run("it parses the operations of each kind", func(t *testing.T) {
+		p, err := webhook.ParseOperationPolicy("LogSink=update, delete; ClusterLogSink=;")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := webhook.OperationPolicy{
+			Kinds: map[string][]v1beta1.Operation{
+				"LogSink":        {v1beta1.Update, v1beta1.Delete},
+				"ClusterLogSink": {},
+			},
+		}
+		if diff := cmp.Diff(expected, p); diff != "" {
+			t.Errorf("Policy not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it returns an error for invalid entries", func(t *testing.T) {
+		for _, s := range []string{"LogSink", "Pod=CREATE", "LogSink=CREATE,PATCH"} {
+			_, err := webhook.ParseOperationPolicy(s)
+			if err == nil {
+				t.Errorf("expected an error for %q", s)
+			}
+		}
+	})
+}
+
+func TestOperationPolicy(t *testing.T) {
+	logSinkSpec := `{
+		"type": "webhook",
+		"url": "https://example.com/place"
+	}`
+	policy, err := webhook.ParseOperationPolicy("LogSink=UPDATE,DELETE;ClusterLogSink=CREATE,UPDATE;MetricSink=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := webhook.NewServer("127.0.0.1:0", webhook.WithOperationPolicy(policy))
+	server.Run(false)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		review   string
+		message  string
+	}{
+		{
+			"blocked create",
+			"/logsink",
+			fmt.Sprintf(logSinkAdmissionTemplate, logSinkSpec),
+			"Operation not allowed: CREATE of LogSink is not allowed, allowed operations are UPDATE, DELETE",
+		},
+		{
+			"allowed update",
+			"/logsink",
+			fmt.Sprintf(logSinkUpdateAdmissionTemplate, logSinkSpec, logSinkSpec),
+			"",
+		},
+		{
+			"allowed delete",
+			"/logsink",
+			fmt.Sprintf(deleteAdmissionTemplate, "LogSink"),
+			"",
+		},
+		{
+			"allowed create of another kind",
+			"/logsink",
+			fmt.Sprintf(clusterLogSinkAdmissionTemplate, logSinkSpec),
+			"",
+		},
+		{
+			"blocked delete",
+			"/logsink",
+			fmt.Sprintf(deleteAdmissionTemplate, "ClusterLogSink"),
+			"Operation not allowed: DELETE of ClusterLogSink is not allowed, allowed operations are CREATE, UPDATE",
+		},
+		{
+			"blocked metric sink operation",
+			"/metricsink",
+			fmt.Sprintf(deleteAdmissionTemplate, "MetricSink"),
+			"Operation not allowed: DELETE of MetricSink is not allowed, no operations are allowed",
+		},
+		{
+			"delete of a kind without an entry",
+			"/metricsink",
+			fmt.Sprintf(deleteAdmissionTemplate, "ClusterMetricSink"),
+			"",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := postReview(t, server, test.endpoint, test.review)
+
+			if test.message == "" {
+				if !resp.Response.Allowed {
+					t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+				return
+			}
+
+			if resp.Response.Allowed {
+				t.Fatal("expected response to not be allowed")
+			}
+			if resp.Response.Result.Message != test.message {
+				t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+			}
+		})
+	}
+
+	t.Run("it allows every operation without a policy", func(t *testing.T) {
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		for _, review := range []string{
+			fmt.Sprintf(logSinkAdmissionTemplate, logSinkSpec),
+			fmt.Sprintf(deleteAdmissionTemplate, "LogSink"),
+		} {
+			resp := postReview(t, server, "/logsink", review)
+			if !resp.Response.Allowed {
+				t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+			}
+		}
+	})
+
+	t.Run("it applies a policy set while running", func(t *testing.T) {
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		frozen, err := webhook.ParseOperationPolicy("LogSink=UPDATE,DELETE")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.SetOperationPolicy(frozen)
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(logSinkAdmissionTemplate, logSinkSpec))
+		if resp.Response.Allowed {
+			t.Error("expected response to not be allowed")
+		}
+	})
+}
+
+func TestOperationPolicyController(t *testing.T) {
+	fallback := webhook.OperationPolicy{
+		Kinds: map[string][]v1beta1.Operation{"LogSink": {v1beta1.Create}},
+	}
+	configMap := func(data map[string]string) *coreV1.ConfigMap {
+		cm := &coreV1.ConfigMap{Data: data}
+		cm.Name = "validator-operations"
+		return cm
+	}
+
+	t.Run("it sets the policy of the configmap", func(t *testing.T) {
+		spy := &spyOperationPolicySetter{}
+		c := webhook.NewOperationPolicyController(spy, fallback)
+
+		c.OnAdd(configMap(map[string]string{"operations": "LogSink=DELETE"}))
+
+		expected := webhook.OperationPolicy{
+			Kinds: map[string][]v1beta1.Operation{"LogSink": {v1beta1.Delete}},
+		}
+		if diff := cmp.Diff(expected, spy.policy); diff != "" {
+			t.Errorf("Policy not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it keeps the policy when the configmap's cannot be parsed", func(t *testing.T) {
+		spy := &spyOperationPolicySetter{}
+		c := webhook.NewOperationPolicyController(spy, fallback)
+
+		c.OnUpdate(nil, configMap(map[string]string{"operations": "LogSink=PATCH"}))
+
+		if spy.called != 0 {
+			t.Errorf("expected the policy to be kept, got %+v", spy.policy)
+		}
+	})
+
+	t.Run("it restores the fallback", func(t *testing.T) {
+		for name, event := range map[string]func(c *webhook.OperationPolicyController){
+			"without a policy": func(c *webhook.OperationPolicyController) {
+				c.OnUpdate(nil, configMap(nil))
+			},
+			"when deleted": func(c *webhook.OperationPolicyController) {
+				c.OnDelete(configMap(map[string]string{"operations": "LogSink=DELETE"}))
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				spy := &spyOperationPolicySetter{}
+				c := webhook.NewOperationPolicyController(spy, fallback)
+
+				event(c)
+
+				if diff := cmp.Diff(fallback, spy.policy); diff != "" {
+					t.Errorf("Policy not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+type spyOperationPolicySetter struct {
+	called int
+	policy webhook.OperationPolicy
+}
+
+func (s *spyOperationPolicySetter) SetOperationPolicy(p webhook.OperationPolicy) {
+	s.called++
+	s.policy = p
+}
+
+var deleteAdmissionTemplate = `{
+	"kind": "AdmissionReview",
+	"apiVersion": "admission.k8s.io/v1beta1",
+	"request": {
+		"uid": "f9bc53a0-266b-11e9-928e-42010a800feb",
+		"kind": {
+			"group": "observability.knative.dev",
+			"version": "v1alpha1",
+			"kind": "%s"
+		},
+		"operation": "DELETE",
+		"oldObject": {
+			"apiVersion": "observability.knative.dev/v1alpha1",
+			"kind": "%[1]s",
+			"spec": {}
+		}
+	}
+}`
@@ -0,0 +1,104 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutputTypePolicy restricts the sink types LogSinks may use in a
+// namespace. ClusterLogSinks are managed by cluster admins and are not
+// restricted.
+type OutputTypePolicy struct {
+	// Default applies to namespaces without an entry. An empty list allows
+	// every type.
+	Default    []string
+	Namespaces map[string][]string
+}
+
+// ParseOutputTypePolicy parses the default types as a comma separated list
+// and the namespace entries as semicolon separated namespace=types pairs,
+// e.g. "team-a=syslog,webhook;team-b=syslog".
+func ParseOutputTypePolicy(defaults, namespaces string) (OutputTypePolicy, error) {
+	p := OutputTypePolicy{
+		Default:    splitTypes(defaults),
+		Namespaces: make(map[string][]string),
+	}
+
+	for _, entry := range strings.Split(namespaces, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return OutputTypePolicy{}, fmt.Errorf("invalid namespace output types %q, expected namespace=types", entry)
+		}
+		p.Namespaces[strings.TrimSpace(kv[0])] = splitTypes(kv[1])
+	}
+
+	return p, nil
+}
+
+func splitTypes(s string) []string {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+func (p OutputTypePolicy) allowedTypes(namespace string) []string {
+	if types, ok := p.Namespaces[namespace]; ok {
+		return types
+	}
+	return p.Default
+}
+
+// check returns the message a LogSink of the given type in namespace is
+// rejected with, or an empty string if it is allowed.
+func (p OutputTypePolicy) check(namespace, sinkType string) string {
+	allowed := p.allowedTypes(namespace)
+	if len(allowed) == 0 {
+		return ""
+	}
+
+	for _, t := range allowed {
+		if t == sinkType {
+			return ""
+		}
+	}
+
+	return fmt.Sprintf(
+		"%s: %s is not allowed in namespace %s, allowed types are %s",
+		ConfigTypeNotAllowedError,
+		sinkType,
+		namespace,
+		strings.Join(allowed, ", "),
+	)
+}
+
+// WithOutputTypePolicy restricts the types LogSinks may use.
+func WithOutputTypePolicy(p OutputTypePolicy) ServerOpt {
+	return func(s *Server) {
+		s.outputTypes = p
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/api/admission/v1beta1"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestParseOutputTypePolicy(t *testing.T) {
+	t.Run("it parses default and namespace types", func(t *testing.T) {
+		p, err := webhook.ParseOutputTypePolicy(
+			"syslog",
+			"team-a=syslog, webhook; team-b=webhook;",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := webhook.OutputTypePolicy{
+			Default: []string{"syslog"},
+			Namespaces: map[string][]string{
+				"team-a": {"syslog", "webhook"},
+				"team-b": {"webhook"},
+			},
+		}
+		if diff := cmp.Diff(expected, p); diff != "" {
+			t.Errorf("Policy not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it returns an error for invalid namespace entries", func(t *testing.T) {
+		for _, namespaces := range []string{"team-a", "=syslog"} {
+			_, err := webhook.ParseOutputTypePolicy("", namespaces)
+			if err == nil {
+				t.Errorf("expected an error for %q", namespaces)
+			}
+		}
+	})
+}
+
+func TestOutputTypePolicy(t *testing.T) {
+	policy := webhook.OutputTypePolicy{
+		Default: []string{"syslog"},
+		Namespaces: map[string][]string{
+			"team-a": {"syslog", "webhook"},
+		},
+	}
+	server := webhook.NewServer("127.0.0.1:0", webhook.WithOutputTypePolicy(policy))
+	server.Run(false)
+	defer server.Close()
+
+	syslogSpec := `{
+		"type": "syslog",
+		"host": "example.com",
+		"port": 100,
+		"enable_tls": true
+	}`
+	webhookSpec := `{
+		"type": "webhook",
+		"url": "https://example.com/place"
+	}`
+
+	tests := []struct {
+		name      string
+		kind      string
+		namespace string
+		spec      string
+		message   string
+	}{
+		{"default allowed type", "LogSink", "team-b", syslogSpec, ""},
+		{
+			"default disallowed type",
+			"LogSink",
+			"team-b",
+			webhookSpec,
+			"Sink type not allowed: webhook is not allowed in namespace team-b, allowed types are syslog",
+		},
+		{"namespace allowed type", "LogSink", "team-a", webhookSpec, ""},
+		{"cluster sink", "ClusterLogSink", "", webhookSpec, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := postReview(t, server, "/logsink", fmt.Sprintf(
+				namespacedAdmissionTemplate,
+				test.kind,
+				test.namespace,
+				test.spec,
+			))
+
+			if test.message == "" {
+				if !resp.Response.Allowed {
+					t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+				return
+			}
+
+			if resp.Response.Allowed {
+				t.Fatal("expected response to not be allowed")
+			}
+			if resp.Response.Result.Message != test.message {
+				t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+			}
+		})
+	}
+
+	t.Run("it allows every type without a policy", func(t *testing.T) {
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-b",
+			webhookSpec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+}
+
+func postReview(t *testing.T, server *webhook.Server, endpoint, body string) v1beta1.AdmissionReview {
+	var (
+		err  error
+		resp *http.Response
+	)
+	for i := 0; i < 100; i++ {
+		resp, err = http.Post(
+			"http://"+server.Addr()+endpoint,
+			"application/json",
+			strings.NewReader(body),
+		)
+		if err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http status 200, got %d", resp.StatusCode)
+	}
+
+	var review v1beta1.AdmissionReview
+	err = json.NewDecoder(resp.Body).Decode(&review)
+	if err != nil {
+		t.Fatalf("unable to decode resp body: %s", err)
+	}
+	return review
+}
+
+var namespacedAdmissionTemplate = `{
+	"kind": "AdmissionReview",
+	"apiVersion": "admission.k8s.io/v1beta1",
+	"request": {
+		"uid": "f9bc53a0-266b-11e9-928e-42010a800feb",
+		"kind": {
+			"group": "observability.knative.dev",
+			"version": "v1alpha1",
+			"kind": "%s"
+		},
+		"namespace": "%s",
+		"operation": "CREATE",
+		"object": {
+			"apiVersion": "observability.knative.dev/v1alpha1",
+			"kind": "%[1]s",
+			"spec": %[3]s
+		}
+	}
+}`
@@ -0,0 +1,64 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"log"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	listers "github.com/knative/observability/pkg/client/listers/sink/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// WithNamespaceRateCap rejects LogSinks that would raise the sum of the
+// startup rates of their namespace's LogSinks above max records per second.
+// The other sinks of the namespace are read from the lister, which is
+// expected to be backed by an informer cache.
+func WithNamespaceRateCap(max int, sinks listers.LogSinkLister) ServerOpt {
+	return func(s *Server) {
+		s.namespaceRateCap = max
+		s.sinkLister = sinks
+	}
+}
+
+// checkRateCap returns the message to reject a LogSink with when it raises
+// its namespace's rate above the cap. The sink's own cached version is
+// replaced by spec, so an update is checked against its new rate. A cache
+// that cannot be read is logged and the sink is allowed.
+func (s *Server) checkRateCap(kind, namespace, name string, spec *sink.SinkSpec) string {
+	if s.namespaceRateCap <= 0 || s.sinkLister == nil || kind != "LogSink" {
+		return ""
+	}
+
+	sinks, err := s.sinkLister.LogSinks(namespace).List(labels.Everything())
+	if err != nil {
+		log.Printf("Unable to list LogSinks of namespace %s: %s", namespace, err)
+		return ""
+	}
+
+	total := spec.StartupRate
+	for _, ls := range sinks {
+		if ls.Name == name {
+			continue
+		}
+		total += ls.Spec.StartupRate
+	}
+
+	if total > s.namespaceRateCap {
+		return ConfigNamespaceRateCapError
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	listers "github.com/knative/observability/pkg/client/listers/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestNamespaceRateCap(t *testing.T) {
+	indexer := cache.NewIndexer(
+		cache.MetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	for _, s := range []*sink.LogSink{
+		rateSink("team-a", "existing-1", 40),
+		rateSink("team-a", "existing-2", 30),
+		rateSink("team-b", "existing", 90),
+	} {
+		if err := indexer.Add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := webhook.NewServer(
+		"127.0.0.1:0",
+		webhook.WithNamespaceRateCap(100, listers.NewLogSinkLister(indexer)),
+	)
+	server.Run(false)
+	defer server.Close()
+
+	var tests = []struct {
+		name      string
+		kind      string
+		operation string
+		sinkName  string
+		rate      int
+		allowed   bool
+	}{
+		{"it allows sinks below the cap", "LogSink", "CREATE", "new", 20, true},
+		{"it allows sinks at the cap", "LogSink", "CREATE", "new", 30, true},
+		{"it rejects sinks above the cap", "LogSink", "CREATE", "new", 31, false},
+		{"it allows updates at the cap", "LogSink", "UPDATE", "existing-1", 70, true},
+		{"it rejects updates above the cap", "LogSink", "UPDATE", "existing-1", 71, false},
+		{"it does not count ClusterLogSinks", "ClusterLogSink", "CREATE", "new", 500, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace := "team-a"
+			if test.kind == "ClusterLogSink" {
+				namespace = ""
+			}
+			resp := postReview(t, server, "/logsink", fmt.Sprintf(
+				rateAdmissionTemplate,
+				test.kind,
+				namespace,
+				test.operation,
+				test.sinkName,
+				test.rate,
+			))
+			if resp.Response.Allowed != test.allowed {
+				t.Fatalf("expected allowed to be %t, got %+v", test.allowed, resp.Response)
+			}
+			if !test.allowed && resp.Response.Result.Message != webhook.ConfigNamespaceRateCapError {
+				t.Errorf("expected message %q, got %q", webhook.ConfigNamespaceRateCapError, resp.Response.Result.Message)
+			}
+		})
+	}
+
+	t.Run("it only counts sinks of the same namespace", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			rateAdmissionTemplate,
+			"LogSink",
+			"team-c",
+			"CREATE",
+			"new",
+			100,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it allows every rate without a cap", func(t *testing.T) {
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			rateAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			"CREATE",
+			"new",
+			1000,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+}
+
+func rateSink(namespace, name string, rate int) *sink.LogSink {
+	return &sink.LogSink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: sink.SinkSpec{
+			Type:         "webhook",
+			WebhookSpec:  sink.WebhookSpec{URL: "https://example.com"},
+			StartupDelay: &metav1.Duration{},
+			StartupRate:  rate,
+		},
+	}
+}
+
+var rateAdmissionTemplate = `{
+	"kind": "AdmissionReview",
+	"apiVersion": "admission.k8s.io/v1beta1",
+	"request": {
+		"uid": "f9bc53a0-266b-11e9-928e-42010a800feb",
+		"kind": {
+			"group": "observability.knative.dev",
+			"version": "v1alpha1",
+			"kind": "%s"
+		},
+		"namespace": "%s",
+		"operation": "%s",
+		"object": {
+			"apiVersion": "observability.knative.dev/v1alpha1",
+			"kind": "%[1]s",
+			"metadata": {"name": "%[4]s"},
+			"spec": {
+				"type": "webhook",
+				"url": "https://example.com",
+				"startup_delay": "1m",
+				"startup_rate": %[5]d
+			}
+		},
+		"oldObject": {
+			"apiVersion": "observability.knative.dev/v1alpha1",
+			"kind": "%[1]s",
+			"metadata": {"name": "%[4]s"},
+			"spec": {
+				"type": "webhook",
+				"url": "https://example.com",
+				"startup_delay": "1m",
+				"startup_rate": 1
+			}
+		}
+	}
+}`
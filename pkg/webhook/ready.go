@@ -0,0 +1,67 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"log"
+	"net/http"
+
+	"k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// WithCacheSyncs makes the server report unready until every informer
+// cache it reads from has synced. Until then it is left out of the
+// validator service, so clusters that ignore webhook failures admit sinks
+// instead of the webhook rejecting them with an empty cache.
+func WithCacheSyncs(synced ...cache.InformerSynced) ServerOpt {
+	return func(s *Server) {
+		s.cacheSyncs = append(s.cacheSyncs, synced...)
+	}
+}
+
+// selfTestReview is a LogSink review the server validates to check that it
+// can serve reviews. It may be rejected, e.g. by an output type policy, as
+// long as it is reviewed.
+var selfTestReview = v1beta1.AdmissionReview{
+	Request: &v1beta1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: "LogSink"},
+		Namespace: "default",
+		Operation: "CREATE",
+		Object: runtime.RawExtension{
+			Raw: []byte(`{"metadata": {"name": "self-test"}, "spec": {"type": "webhook", "url": "https://example.com"}}`),
+		},
+	},
+}
+
+// readyHandler responds with a 503 until the caches have synced and the
+// server reviews the self test sink.
+func (s *Server) readyHandler(w http.ResponseWriter, _ *http.Request) {
+	for _, synced := range s.cacheSyncs {
+		if !synced() {
+			http.Error(w, "Caches not synced", http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	review := selfTestReview.DeepCopy()
+	if _, err := s.validateLogSinkConfigRequest(review); err != nil {
+		log.Printf("Self test failed: %s", err)
+		http.Error(w, "Self test failed", http.StatusServiceUnavailable)
+	}
+}
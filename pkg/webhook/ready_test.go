@@ -0,0 +1,85 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestReady(t *testing.T) {
+	t.Run("it reports unready until the caches sync", func(t *testing.T) {
+		var synced int32
+		server := webhook.NewServer(
+			"127.0.0.1:0",
+			webhook.WithCacheSyncs(
+				func() bool { return true },
+				func() bool { return atomic.LoadInt32(&synced) == 1 },
+			),
+		)
+		server.Run(false)
+		defer server.Close()
+
+		if code := getReady(t, server); code != http.StatusServiceUnavailable {
+			t.Errorf("expected http status 503, got %d", code)
+		}
+
+		atomic.StoreInt32(&synced, 1)
+		if code := getReady(t, server); code != http.StatusOK {
+			t.Errorf("expected http status 200, got %d", code)
+		}
+	})
+
+	t.Run("it reports ready when the self test sink is rejected", func(t *testing.T) {
+		policy, err := webhook.ParseOutputTypePolicy("syslog", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		server := webhook.NewServer(
+			"127.0.0.1:0",
+			webhook.WithOutputTypePolicy(policy),
+		)
+		server.Run(false)
+		defer server.Close()
+
+		if code := getReady(t, server); code != http.StatusOK {
+			t.Errorf("expected http status 200, got %d", code)
+		}
+	})
+}
+
+func getReady(t *testing.T, server *webhook.Server) int {
+	var (
+		err  error
+		resp *http.Response
+	)
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get("http://" + server.Addr() + "/ready")
+		if err == nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"log"
+	"reflect"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"k8s.io/api/admission/v1beta1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// SubjectAccessReviewCreator asks the API server whether a user may perform
+// an action.
+type SubjectAccessReviewCreator interface {
+	Create(*authorizationv1.SubjectAccessReview) (*authorizationv1.SubjectAccessReview, error)
+}
+
+// WithScopeAuthorizer rejects updates to ClusterLogSinks that widen the
+// records they read unless the requesting user may get the logs of pods in
+// every namespace. RBAC can let a team edit a ClusterLogSink that its
+// namespace_globs or node_selector limit to the team's namespaces or nodes,
+// which would otherwise let them point it at any records.
+func WithScopeAuthorizer(sars SubjectAccessReviewCreator) ServerOpt {
+	return func(s *Server) {
+		s.scopeAuthorizer = sars
+	}
+}
+
+// checkScope returns the message to reject an update with when it widens
+// the scope of the sink and the user is not authorized to read every
+// namespace. old is nil unless the request is an update. Reviews that fail
+// are rejected.
+func (s *Server) checkScope(req *v1beta1.AdmissionRequest, spec *sink.SinkSpec, old *sink.ClusterLogSink) string {
+	if s.scopeAuthorizer == nil || old == nil || !widensScope(spec, &old.Spec) {
+		return ""
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for k, v := range req.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := s.scopeAuthorizer.Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:        "get",
+				Resource:    "pods",
+				Subresource: "log",
+			},
+			User:   req.UserInfo.Username,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+			UID:    req.UserInfo.UID,
+		},
+	})
+	if err != nil {
+		log.Printf("Unable to review the access of %s: %s", req.UserInfo.Username, err)
+		return ConfigScopeUnauthorizedError
+	}
+	if !sar.Status.Allowed {
+		return ConfigScopeUnauthorizedError
+	}
+	return ""
+}
+
+// widensScope reports whether spec may read records old does not: records
+// of namespaces or nodes old does not select, the audit log, raw log lines
+// or keys old does not project.
+func widensScope(spec, old *sink.SinkSpec) bool {
+	return widensList(spec.NamespaceGlobs, old.NamespaceGlobs) ||
+		widensList(spec.Project, old.Project) ||
+		widensSelector(spec.NodeSelector, old.NodeSelector) ||
+		widensAuditLog(spec.AuditLog, old.AuditLog) ||
+		spec.RawMode && !old.RawMode
+}
+
+// widensList reports whether a list limiting the records, or keys, a sink
+// reads allows any old does not. A nil list does not limit them. Entries
+// are compared as written, so any glob old does not have widens the scope.
+func widensList(list, old []string) bool {
+	if old == nil {
+		return false
+	}
+	if list == nil {
+		return true
+	}
+
+	entries := make(map[string]bool, len(old))
+	for _, e := range old {
+		entries[e] = true
+	}
+	for _, e := range list {
+		if !entries[e] {
+			return true
+		}
+	}
+	return false
+}
+
+// widensSelector reports whether a node selector may select nodes old does
+// not. A selector without labels selects every node, and a selector with
+// every label of old selects no other node.
+func widensSelector(selector, old map[string]string) bool {
+	for k, v := range old {
+		if w, ok := selector[k]; !ok || w != v {
+			return true
+		}
+	}
+	return false
+}
+
+// widensAuditLog reports whether a sink starts reading the audit log or
+// reads another audit log. Switching from the audit log to container logs
+// reads other records too. The default path and nodes are compared as
+// written.
+func widensAuditLog(audit, old *sink.AuditLogSpec) bool {
+	if audit == nil || old == nil {
+		return audit != old
+	}
+	return audit.Path != old.Path || !reflect.DeepEqual(audit.NodeSelector, old.NodeSelector)
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestScopeWidening(t *testing.T) {
+	const template = `{
+		"kind": "AdmissionReview",
+		"apiVersion": "admission.k8s.io/v1beta1",
+		"request": {
+			"uid": "f9bc53a0-266b-11e9-928e-42010a800feb",
+			"kind": {
+				"group": "observability.knative.dev",
+				"version": "v1alpha1",
+				"kind": "ClusterLogSink"
+			},
+			"resource": {
+				"group": "observability.knative.dev",
+				"version": "v1alpha1",
+				"resource": "clusterlogsinks"
+			},
+			"operation": "UPDATE",
+			"userInfo": {
+				"username": "team-a-admin",
+				"uid": "some-uid",
+				"groups": ["team-a"],
+				"extra": {"scopes": ["logs"]}
+			},
+			"object": {
+				"kind": "ClusterLogSink",
+				"spec": %s
+			},
+			"oldObject": {
+				"kind": "ClusterLogSink",
+				"spec": %s
+			}
+		}
+	}`
+	spec := func(globs string) string {
+		return fmt.Sprintf(`{
+			"type": "webhook",
+			"url": "https://example.com/place",
+			"namespace_globs": %s
+		}`, globs)
+	}
+	specWith := func(fields string) string {
+		if fields != "" {
+			fields = ", " + fields
+		}
+		return fmt.Sprintf(`{
+			"type": "webhook",
+			"url": "https://example.com/place"%s
+		}`, fields)
+	}
+
+	t.Run("it reviews the access of the user widening the scope", func(t *testing.T) {
+		authorizer := &spySubjectAccessReviewCreator{allowed: true}
+		server := webhook.NewServer("127.0.0.1:0", webhook.WithScopeAuthorizer(authorizer))
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			template,
+			spec(`["team-a-*", "team-b-*"]`),
+			spec(`["team-a-*"]`),
+		))
+		if !resp.Response.Allowed {
+			t.Fatalf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+
+		if len(authorizer.reviews) != 1 {
+			t.Fatalf("expected a review, got %d", len(authorizer.reviews))
+		}
+		review := authorizer.reviews[0].Spec
+		if review.User != "team-a-admin" || review.UID != "some-uid" {
+			t.Errorf("expected the review of the requesting user, got %+v", review)
+		}
+		if len(review.Groups) != 1 || review.Groups[0] != "team-a" {
+			t.Errorf("expected the groups of the user, got %v", review.Groups)
+		}
+		if scopes := review.Extra["scopes"]; len(scopes) != 1 || scopes[0] != "logs" {
+			t.Errorf("expected the extra of the user, got %v", review.Extra)
+		}
+		expected := authorizationv1.ResourceAttributes{
+			Verb:        "get",
+			Resource:    "pods",
+			Subresource: "log",
+		}
+		if review.ResourceAttributes == nil || *review.ResourceAttributes != expected {
+			t.Errorf("expected a review of pod logs in every namespace, got %+v", review.ResourceAttributes)
+		}
+	})
+
+	t.Run("it rejects widening by unauthorized users", func(t *testing.T) {
+		tests := map[string]struct {
+			authorizer *spySubjectAccessReviewCreator
+			globs      string
+		}{
+			"a new glob": {
+				&spySubjectAccessReviewCreator{},
+				`["team-a-*", "*"]`,
+			},
+			"removed globs": {
+				&spySubjectAccessReviewCreator{},
+				`null`,
+			},
+			"a failed review": {
+				&spySubjectAccessReviewCreator{allowed: true, err: errors.New("unavailable")},
+				`["*"]`,
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				server := webhook.NewServer("127.0.0.1:0", webhook.WithScopeAuthorizer(test.authorizer))
+				server.Run(false)
+				defer server.Close()
+
+				resp := postReview(t, server, "/logsink", fmt.Sprintf(
+					template,
+					spec(test.globs),
+					spec(`["team-a-*"]`),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != webhook.ConfigScopeUnauthorizedError {
+					t.Errorf("expected message %q, got %q", webhook.ConfigScopeUnauthorizedError, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects widening the records read by unauthorized users", func(t *testing.T) {
+		tests := map[string]struct {
+			fields    string
+			oldFields string
+		}{
+			"an added audit log":      {`"audit_log": {}`, ``},
+			"another audit log":       {`"audit_log": {"path": "/var/log/other.log"}`, `"audit_log": {}`},
+			"other audited nodes":     {`"audit_log": {"node_selector": {"pool": "b"}}`, `"audit_log": {"node_selector": {"pool": "a"}}`},
+			"a removed audit log":     {``, `"audit_log": {}`},
+			"raw mode":                {`"raw_mode": true`, ``},
+			"a removed node selector": {``, `"node_selector": {"pool": "a"}`},
+			"other nodes":             {`"node_selector": {"pool": "b"}`, `"node_selector": {"pool": "a"}`},
+			"a new projected key":     {`"project": ["log", "kubernetes"]`, `"project": ["log"]`},
+			"a removed projection":    {``, `"project": ["log"]`},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				authorizer := &spySubjectAccessReviewCreator{}
+				server := webhook.NewServer("127.0.0.1:0", webhook.WithScopeAuthorizer(authorizer))
+				server.Run(false)
+				defer server.Close()
+
+				resp := postReview(t, server, "/logsink", fmt.Sprintf(
+					template,
+					specWith(test.fields),
+					specWith(test.oldFields),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != webhook.ConfigScopeUnauthorizedError {
+					t.Errorf("expected message %q, got %q", webhook.ConfigScopeUnauthorizedError, resp.Response.Result.Message)
+				}
+				if len(authorizer.reviews) != 1 {
+					t.Errorf("expected a review, got %d", len(authorizer.reviews))
+				}
+			})
+		}
+	})
+
+	t.Run("it does not review updates that keep or narrow the records read", func(t *testing.T) {
+		tests := map[string]struct {
+			fields    string
+			oldFields string
+		}{
+			"the same audit log":      {`"audit_log": {"parser": "json"}`, `"audit_log": {}`},
+			"raw mode turned off":     {``, `"raw_mode": true`},
+			"an added node selector":  {`"node_selector": {"pool": "a"}`, ``},
+			"a narrower selector":     {`"node_selector": {"pool": "a", "zone": "1"}`, `"node_selector": {"pool": "a"}`},
+			"a removed projected key": {`"project": ["log"]`, `"project": ["log", "kubernetes"]`},
+			"an added projection":     {`"project": ["log"]`, ``},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				authorizer := &spySubjectAccessReviewCreator{}
+				server := webhook.NewServer("127.0.0.1:0", webhook.WithScopeAuthorizer(authorizer))
+				server.Run(false)
+				defer server.Close()
+
+				resp := postReview(t, server, "/logsink", fmt.Sprintf(
+					template,
+					specWith(test.fields),
+					specWith(test.oldFields),
+				))
+				if !resp.Response.Allowed {
+					t.Fatalf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+				if len(authorizer.reviews) != 0 {
+					t.Errorf("expected no reviews, got %d", len(authorizer.reviews))
+				}
+			})
+		}
+	})
+
+	t.Run("it does not review updates that keep or narrow the scope", func(t *testing.T) {
+		tests := map[string]struct {
+			globs    string
+			oldGlobs string
+		}{
+			"the same globs":            {`["team-a-*"]`, `["team-a-*"]`},
+			"a removed glob":            {`["team-a-*"]`, `["team-a-*", "team-b-*"]`},
+			"globs limiting every":      {`["team-a-*"]`, `null`},
+			"every namespace unchanged": {`null`, `null`},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				authorizer := &spySubjectAccessReviewCreator{}
+				server := webhook.NewServer("127.0.0.1:0", webhook.WithScopeAuthorizer(authorizer))
+				server.Run(false)
+				defer server.Close()
+
+				resp := postReview(t, server, "/logsink", fmt.Sprintf(
+					template,
+					spec(test.globs),
+					spec(test.oldGlobs),
+				))
+				if !resp.Response.Allowed {
+					t.Fatalf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+				if len(authorizer.reviews) != 0 {
+					t.Errorf("expected no reviews, got %d", len(authorizer.reviews))
+				}
+			})
+		}
+	})
+}
+
+type spySubjectAccessReviewCreator struct {
+	allowed bool
+	err     error
+	reviews []*authorizationv1.SubjectAccessReview
+}
+
+func (s *spySubjectAccessReviewCreator) Create(sar *authorizationv1.SubjectAccessReview) (*authorizationv1.SubjectAccessReview, error) {
+	s.reviews = append(s.reviews, sar)
+	if s.err != nil {
+		return nil, s.err
+	}
+	sar.Status.Allowed = s.allowed
+	return sar, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"path"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// selfCaptureAnnotation is the audit annotation added to ClusterLogSinks
+// that capture the observability namespace without opting in.
+const selfCaptureAnnotation = "observability.knative.dev/self-capture"
+
+// WithObservabilityNamespace rejects LogSinks in the namespace the logging
+// pipeline runs in unless they set allow_self_capture. Their records include
+// the logs fluent-bit writes about delivering them, so a sink that fails to
+// deliver produces more records for itself.
+func WithObservabilityNamespace(namespace string) ServerOpt {
+	return func(s *Server) {
+		s.observabilityNamespace = namespace
+	}
+}
+
+// checkSelfCapture returns the message to reject a sink with when it
+// captures the observability namespace. ClusterLogSinks capture every
+// namespace, or the namespaces their globs match, and were always allowed
+// to, so they are not rejected and warn is set instead.
+func (s *Server) checkSelfCapture(kind, namespace string, spec *sink.SinkSpec) (msg string, warn bool) {
+	if s.observabilityNamespace == "" || spec.AllowSelfCapture {
+		return "", false
+	}
+
+	if kind != "LogSink" {
+		return "", spec.NamespaceGlobs == nil || s.globsMatchObservability(spec.NamespaceGlobs)
+	}
+	if namespace == s.observabilityNamespace {
+		return ConfigSelfCaptureError, false
+	}
+	return "", false
+}
+
+// globsMatchObservability returns whether any of globs matches the
+// observability namespace.
+func (s *Server) globsMatchObservability(globs []string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, s.observabilityNamespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestSelfCapture(t *testing.T) {
+	server := webhook.NewServer(
+		"127.0.0.1:0",
+		webhook.WithObservabilityNamespace("knative-observability"),
+	)
+	server.Run(false)
+	defer server.Close()
+
+	spec := `{
+		"type": "webhook",
+		"url": "https://example.com/place"
+	}`
+	optInSpec := `{
+		"type": "webhook",
+		"url": "https://example.com/place",
+		"allow_self_capture": true
+	}`
+
+	t.Run("it rejects LogSinks in the observability namespace", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"knative-observability",
+			spec,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigSelfCaptureError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigSelfCaptureError, resp.Response.Result.Message)
+		}
+	})
+
+	t.Run("it allows LogSinks that opt in", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"knative-observability",
+			optInSpec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it allows LogSinks in other namespaces", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			spec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+		if len(resp.Response.AuditAnnotations) != 0 {
+			t.Errorf("expected no audit annotations, got %v", resp.Response.AuditAnnotations)
+		}
+	})
+
+	t.Run("it warns on ClusterLogSinks", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterLogSink",
+			"",
+			spec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+		if _, ok := resp.Response.AuditAnnotations["observability.knative.dev/self-capture"]; !ok {
+			t.Errorf("expected a self capture audit annotation, got %v", resp.Response.AuditAnnotations)
+		}
+	})
+
+	t.Run("it warns on ClusterLogSinks with globs matching the observability namespace", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterLogSink",
+			"",
+			`{
+				"type": "webhook",
+				"url": "https://example.com/place",
+				"namespace_globs": ["team-*", "knative-*"]
+			}`,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+		if _, ok := resp.Response.AuditAnnotations["observability.knative.dev/self-capture"]; !ok {
+			t.Errorf("expected a self capture audit annotation, got %v", resp.Response.AuditAnnotations)
+		}
+	})
+
+	t.Run("it does not warn on ClusterLogSinks with globs not matching the observability namespace", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterLogSink",
+			"",
+			`{
+				"type": "webhook",
+				"url": "https://example.com/place",
+				"namespace_globs": ["team-*"]
+			}`,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+		if len(resp.Response.AuditAnnotations) != 0 {
+			t.Errorf("expected no audit annotations, got %v", resp.Response.AuditAnnotations)
+		}
+	})
+
+	t.Run("it does not warn on ClusterLogSinks that opt in", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterLogSink",
+			"",
+			optInSpec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+		if len(resp.Response.AuditAnnotations) != 0 {
+			t.Errorf("expected no audit annotations, got %v", resp.Response.AuditAnnotations)
+		}
+	})
+
+	t.Run("it allows every namespace without an observability namespace", func(t *testing.T) {
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"knative-observability",
+			spec,
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+)
+
+// PipelineService is the service the logging pipeline ingests records
+// on. A Port of 0 matches every port of the service.
+type PipelineService struct {
+	Name      string
+	Namespace string
+	Port      int
+}
+
+// ParsePipelineService parses a service as name.namespace with an optional
+// :port, e.g. fluent-bit.knative-observability:24224. An empty string is
+// the zero PipelineService, which disables the check.
+func ParsePipelineService(s string) (PipelineService, error) {
+	if s == "" {
+		return PipelineService{}, nil
+	}
+
+	var svc PipelineService
+	host := s
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		port, err := strconv.Atoi(s[i+1:])
+		if err != nil || port < 1 || port > 65535 {
+			return PipelineService{}, fmt.Errorf("invalid pipeline service port in %q", s)
+		}
+		host, svc.Port = s[:i], port
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return PipelineService{}, fmt.Errorf("invalid pipeline service %q, expected name.namespace[:port]", s)
+	}
+	svc.Name, svc.Namespace = parts[0], parts[1]
+	return svc, nil
+}
+
+// WithPipelineService rejects sinks whose destinations resolve to the
+// service the logging pipeline ingests records on. Their records would be
+// ingested again and sent to them again, without end.
+func WithPipelineService(svc PipelineService) ServerOpt {
+	return func(s *Server) {
+		s.pipelineService = svc
+	}
+}
+
+// checkSelfReference returns the message to reject a sink with when one of
+// its destinations is the pipeline service. Hosts are resolved by
+// fluent-bit, which runs in the observability namespace, so the bare
+// service name resolves to it as well as its cluster DNS names. Service
+// refs match the service on any port, since their port is named.
+func (s *Server) checkSelfReference(kind, namespace string, spec *sink.SinkSpec) string {
+	if s.pipelineService.Name == "" {
+		return ""
+	}
+
+	for _, d := range destinations(spec) {
+		if s.isPipelineHost(d.host, d.port) {
+			return fmt.Sprintf("%s: %s", ConfigSelfReferenceError, net.JoinHostPort(d.host, strconv.Itoa(d.port)))
+		}
+	}
+
+	ref := spec.ServiceRef
+	if spec.Type == "syslog" && ref != nil {
+		if ref.Namespace != "" {
+			namespace = ref.Namespace
+		} else if kind != "LogSink" {
+			return ""
+		}
+		if ref.Name == s.pipelineService.Name && namespace == s.pipelineService.Namespace {
+			return fmt.Sprintf("%s: service %s/%s", ConfigSelfReferenceError, namespace, ref.Name)
+		}
+	}
+	return ""
+}
+
+type destination struct {
+	host string
+	port int
+}
+
+// destinations returns the hosts and ports records of the sink are sent
+// to. URLs that do not parse are rejected by the sink validation.
+func destinations(spec *sink.SinkSpec) []destination {
+	var ds []destination
+	switch spec.Type {
+	case "syslog":
+		if len(spec.Receivers) > 0 {
+			for _, r := range spec.Receivers {
+				ds = append(ds, destination{host: r.Host, port: r.Port})
+			}
+		} else if spec.ServiceRef == nil {
+			ds = append(ds, destination{host: spec.Host, port: spec.Port})
+		}
+	case "webhook":
+		urls := []string{spec.URL}
+		if spec.AnnotationRouting != nil {
+			urls = append(urls, sortedRouteURLs(spec.AnnotationRouting.Routes)...)
+		}
+		if spec.StatusCodeRouting != nil {
+			urls = append(urls, sortedRouteURLs(spec.StatusCodeRouting.Routes)...)
+		}
+		for _, raw := range urls {
+			if d, ok := urlDestination(raw); ok {
+				ds = append(ds, d)
+			}
+		}
+	}
+	return ds
+}
+
+func sortedRouteURLs(routes map[string]string) []string {
+	keys := make([]string, 0, len(routes))
+	for k := range routes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	urls := make([]string, 0, len(keys))
+	for _, k := range keys {
+		urls = append(urls, routes[k])
+	}
+	return urls
+}
+
+func urlDestination(raw string) (destination, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return destination{}, false
+	}
+
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if p := u.Port(); p != "" {
+		port, err = strconv.Atoi(p)
+		if err != nil {
+			return destination{}, false
+		}
+	}
+	return destination{host: u.Hostname(), port: port}, true
+}
+
+func (s *Server) isPipelineHost(host string, port int) bool {
+	svc := s.pipelineService
+	if svc.Port != 0 && port != svc.Port {
+		return false
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	qualified := svc.Name + "." + svc.Namespace
+	return host == svc.Name ||
+		host == qualified ||
+		host == qualified+".svc" ||
+		strings.HasPrefix(host, qualified+".svc.")
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestSelfReference(t *testing.T) {
+	svc, err := webhook.ParsePipelineService("fluent-bit.knative-observability:24224")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := webhook.NewServer(
+		"127.0.0.1:0",
+		webhook.WithPipelineService(svc),
+	)
+	server.Run(false)
+	defer server.Close()
+
+	syslogSpec := func(host string, port int) string {
+		return fmt.Sprintf(`{
+			"type": "syslog",
+			"host": %q,
+			"port": %d,
+			"enable_tls": true
+		}`, host, port)
+	}
+	webhookSpec := func(url string) string {
+		return fmt.Sprintf(`{
+			"type": "webhook",
+			"url": %q
+		}`, url)
+	}
+
+	rejected := map[string]string{
+		"the service name":     syslogSpec("fluent-bit", 24224),
+		"the namespaced name":  syslogSpec("fluent-bit.knative-observability", 24224),
+		"the service DNS name": syslogSpec("fluent-bit.knative-observability.svc", 24224),
+		"the cluster DNS name": syslogSpec("Fluent-Bit.knative-observability.svc.cluster.local.", 24224),
+		"a webhook URL":        webhookSpec("https://fluent-bit.knative-observability.svc.cluster.local:24224/"),
+		"a syslog receiver": `{
+			"type": "syslog",
+			"enable_tls": true,
+			"receivers": [
+				{"host": "example.com", "port": 514},
+				{"host": "fluent-bit.knative-observability", "port": 24224}
+			]
+		}`,
+		"a service ref": `{
+			"type": "syslog",
+			"enable_tls": true,
+			"service_ref": {"name": "fluent-bit", "port_name": "forward"}
+		}`,
+	}
+	for name, spec := range rejected {
+		t.Run("it rejects a destination of "+name, func(t *testing.T) {
+			resp := postReview(t, server, "/logsink", fmt.Sprintf(
+				namespacedAdmissionTemplate,
+				"LogSink",
+				"knative-observability",
+				spec,
+			))
+			if resp.Response.Allowed {
+				t.Fatal("expected response to not be allowed")
+			}
+			if !strings.HasPrefix(resp.Response.Result.Message, webhook.ConfigSelfReferenceError) {
+				t.Errorf("expected message %q, got %q", webhook.ConfigSelfReferenceError, resp.Response.Result.Message)
+			}
+		})
+	}
+
+	allowed := map[string]string{
+		"another port":      syslogSpec("fluent-bit.knative-observability", 2020),
+		"another namespace": syslogSpec("fluent-bit.team-a.svc.cluster.local", 24224),
+		"another host":      webhookSpec("https://fluent-bit.example.com:24224/"),
+		"a service ref in another namespace": `{
+			"type": "syslog",
+			"enable_tls": true,
+			"service_ref": {"name": "fluent-bit", "port_name": "forward"}
+		}`,
+	}
+	for name, spec := range allowed {
+		t.Run("it allows a destination of "+name, func(t *testing.T) {
+			resp := postReview(t, server, "/logsink", fmt.Sprintf(
+				namespacedAdmissionTemplate,
+				"LogSink",
+				"team-a",
+				spec,
+			))
+			if !resp.Response.Allowed {
+				t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+			}
+		})
+	}
+
+	t.Run("it allows every destination without a pipeline service", func(t *testing.T) {
+		unchecked := webhook.NewServer("127.0.0.1:0")
+		unchecked.Run(false)
+		defer unchecked.Close()
+
+		resp := postReview(t, unchecked, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			syslogSpec("fluent-bit.knative-observability", 24224),
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+}
+
+func TestParsePipelineService(t *testing.T) {
+	t.Run("it parses the name, namespace and port", func(t *testing.T) {
+		svc, err := webhook.ParsePipelineService("fluent-bit.knative-observability:24224")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := webhook.PipelineService{Name: "fluent-bit", Namespace: "knative-observability", Port: 24224}
+		if svc != expected {
+			t.Errorf("expected %+v, got %+v", expected, svc)
+		}
+	})
+
+	t.Run("it parses a service without a port", func(t *testing.T) {
+		svc, err := webhook.ParsePipelineService("fluent-bit.knative-observability")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if svc.Port != 0 {
+			t.Errorf("expected every port to match, got %d", svc.Port)
+		}
+	})
+
+	t.Run("it rejects invalid services", func(t *testing.T) {
+		for _, s := range []string{"fluent-bit", "fluent-bit.ns.svc", ".ns", "fluent-bit.ns:http", "fluent-bit.ns:0"} {
+			if _, err := webhook.ParsePipelineService(s); err == nil {
+				t.Errorf("expected an error for %q", s)
+			}
+		}
+	})
+}
@@ -4,35 +4,147 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"expvar"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"os/exec"
-	"strings"
 	"sync"
 	"time"
 
 	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	listers "github.com/knative/observability/pkg/client/listers/sink/v1alpha1"
 	"github.com/knative/observability/pkg/metric"
 	"k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 const (
-	ConfigTelegrafError            = "Failed to validate metricsink config"
-	ConfigIncludesKubernetesError  = "Kubernetes input plugin added by default in ClusterMetricSink"
-	ConfigLogNoTypeError           = "LogSink should have type"
-	ConfigLogChangeTypeError       = "Changing sink type invalid"
-	ConfigSyslogBadPortError       = "Port for syslog invalid, should be between 1 and 65535"
-	ConfigSyslogBadHostError       = "Host for syslog invalid"
-	ConfigSyslogInsecureError      = "Insecure syslog sink not allowed"
-	ConfigWebhookBadURLError       = "URL for webhook invalid"
-	ConfigWebhookInsecureError     = "Insecure webhook not allowed, scheme must be https"
-	ConfigMetricNoTypeError        = "Must specify type for each inputs/outputs"
-	ConfigMetricNonStringTypeError = "Input/output type must be a string"
+	ConfigTelegrafError               = "Failed to validate metricsink config"
+	ConfigIncludesKubernetesError     = "Kubernetes input plugin added by default in ClusterMetricSink"
+	ConfigLogNoTypeError              = "LogSink should have type"
+	ConfigLogChangeTypeError          = "Changing sink type invalid"
+	ConfigSyslogBadPortError          = "Port for syslog invalid, should be between 1 and 65535"
+	ConfigSyslogBadHostError          = "Host for syslog invalid"
+	ConfigSyslogInsecureError         = "Insecure syslog sink not allowed"
+	ConfigWebhookBadURLError          = "URL for webhook invalid"
+	ConfigWebhookInsecureError        = "Insecure webhook not allowed, scheme must be https"
+	ConfigMetricNoTypeError           = "Must specify type for each inputs/outputs"
+	ConfigMetricNonStringTypeError    = "Input/output type must be a string"
+	ConfigStartupIncompleteError      = "StartupDelay and StartupRate must be set together"
+	ConfigStartupBadDelayError        = "StartupDelay invalid, should be at least 1s"
+	ConfigStartupBadRateError         = "StartupRate invalid, should be greater than 0"
+	ConfigSamplingBadRateError        = "SeveritySampling rate invalid, should be greater than 0 and at most 1"
+	ConfigSamplingBadSeverityError    = "SeveritySampling severity invalid, should be one of debug, info, warning, error, critical"
+	ConfigMappingBadLevelError        = "SeverityMapping level invalid, should be a syslog severity from 0 to 7"
+	ConfigMappingBadSeverityError     = "SeverityMapping severity invalid, should be non-empty and lower case"
+	ConfigMappingFieldError           = "SeverityField is only supported with SeverityMapping"
+	ConfigRawModeSyslogError          = "RawMode is only supported for webhook sinks"
+	ConfigTypeNotAllowedError         = "Sink type not allowed"
+	ConfigOperationNotAllowedError    = "Operation not allowed"
+	ConfigScrapeAuthClusterError      = "ScrapeAuth is only supported for MetricSinks"
+	ConfigKubeletMetricsError         = "KubeletMetrics is only supported for ClusterMetricSinks"
+	ConfigScrapeAuthConflictError     = "ScrapeAuth must set exactly one of bearer_token_secret_ref and basic_auth"
+	ConfigScrapeAuthBadUsernameError  = "ScrapeAuth basic_auth username is required"
+	ConfigScrapeAuthBadSecretRefError = "ScrapeAuth secret ref invalid, should have a valid secret name and key"
+	ConfigScrapeTLSClusterError       = "ScrapeTLS is only supported for MetricSinks"
+	ConfigScrapeTLSUnpairedError      = "ScrapeTLS cert_secret_ref and key_secret_ref must be set together"
+	ConfigScrapeTLSBadSecretRefError  = "ScrapeTLS secret ref invalid, should have a valid secret name and key"
+	ConfigTimestampBadTimezoneError   = "Timestamp timezone invalid, should be an IANA timezone name"
+	ConfigTimestampBadMillisError     = "Timestamp epoch_millis_field invalid, should be a record key other than log and kubernetes"
+	ConfigMetadataBadRegexError       = "Metadata strip_key_regex invalid, should be a valid regular expression"
+	ConfigPriorityBadRangeError       = "Priority invalid, should be between -1000 and 1000"
+	ConfigFileRotationBadCountError   = "FileRotation rotation_count invalid, should be greater than 0"
+	ConfigFileRotationBadSizeError    = "FileRotation max_size invalid, should be greater than 0"
+	ConfigKeepAliveIncompleteError    = "KeepAliveIdleTimeout requires KeepAlive"
+	ConfigKeepAliveBadTimeoutError    = "KeepAliveIdleTimeout invalid, should be at least 1s"
+	ConfigSelfCaptureError            = "Sinks in the observability namespace capture the logging pipeline's own logs, set allow_self_capture to allow"
+	ConfigSelfReferenceError          = "Destination resolves to the logging pipeline's own service, so the sink's records would be sent back to it"
+	ConfigNamespaceRateCapError       = "StartupRate invalid, the namespace's LogSinks would exceed its rate cap"
+	ConfigProjectSyslogError          = "Project is only supported for webhook sinks"
+	ConfigProjectNoKeysError          = "Project invalid, should list at least one key"
+	ConfigProjectBadKeyError          = "Project key invalid, should be non-empty and contain no whitespace"
+	ConfigSchemaSyslogError           = "Schema strict mode is only supported for webhook sinks"
+	ConfigSchemaNoKeysError           = "Schema allowed_keys invalid, should list at least one key in strict mode"
+	ConfigSchemaBadKeyError           = "Schema key invalid, should be non-empty and contain no whitespace"
+	ConfigSchemaProjectError          = "Schema cannot be combined with project"
+	ConfigRequireFieldsEmptyError     = "RequireFields invalid, should list at least one field"
+	ConfigRequireFieldsBadFieldError  = "RequireFields field invalid, should be record keys joined by dots, with keys holding dots in brackets"
+	ConfigRedactBadFieldError         = "Redact field invalid, should be record keys joined by dots, with keys holding dots in brackets"
+	ConfigRedactBadMethodError        = "Redact method invalid, should be drop, hash or mask"
+	ConfigRedactDuplicateError        = "Redact field invalid, each field should be redacted by one rule"
+	ConfigMaxFieldBytesBadFieldError  = "MaxFieldBytes field invalid, should be record keys joined by dots, with keys holding dots in brackets"
+	ConfigMaxFieldBytesBadLimitError  = "MaxFieldBytes limit invalid, should be greater than 0"
+	ConfigGeoIPBadFieldError          = "GeoIP source_field invalid, should be a record key of letters, digits and underscores"
+	ConfigGeoIPBadDatabaseError       = "GeoIP database invalid, should be a clean path to a .mmdb file in /fluent-bit/geoip"
+	ConfigNodeSelectorBadError        = "NodeSelector invalid, should have valid label keys and values"
+	ConfigNodeSelectorSyslogError     = "NodeSelector is not supported for syslog sinks"
+	ConfigNodeSelectorConflictError   = "NodeSelector cannot be combined with raw_mode or audit_log"
+	ConfigStructuredDataBadIDError    = "StructuredData SD-ID invalid, should be name@<enterprise number> or an IANA registered ID"
+	ConfigStructuredDataBadParamError = "StructuredData param name invalid, should be 1 to 32 printable ASCII characters except '=', ']', '\"' and space"
+	ConfigGlobsClusterOnlyError       = "NamespaceGlobs is only supported for ClusterLogSinks"
+	ConfigGlobBadPatternError         = "NamespaceGlobs glob invalid, should be a non-empty glob pattern"
+	ConfigUnixSocketBadPathError      = "Path for unix socket invalid, should be a clean path under an allowed directory"
+	ConfigMetadataBadPolicyError      = "RequireKubernetesMetadata invalid, should be require or allow"
+	ConfigAuditLogClusterOnlyError    = "AuditLog is only supported for ClusterLogSinks"
+	ConfigAuditLogSyslogError         = "AuditLog is not supported for syslog sinks"
+	ConfigAuditLogConflictError       = "AuditLog cannot be combined with raw_mode, project or namespace_globs"
+	ConfigAuditLogBadParserError      = "AuditLog parser invalid, should be k8s-audit or json"
+	ConfigAuditLogBadPathError        = "AuditLog path invalid, should be a clean absolute path"
+	ConfigAuditLogBadSelectorError    = "AuditLog node_selector invalid, should have valid label keys and values"
+	ConfigSyslogBadFramingError       = "Framing for syslog invalid, should be octet-counting or non-transparent"
+	ConfigSyslogBadMsgIDError         = "MsgID invalid, should be up to 32 printable ASCII characters and record references like $key['subkey']"
+	ConfigMsgIDSyslogError            = "MsgID is only supported for syslog sinks"
+	ConfigStatsDClusterError          = "StatsD is only supported for MetricSinks"
+	ConfigStatsDBadPortError          = "StatsD port invalid, should be between 1 and 65535"
+	ConfigStatsDBadProtocolError      = "StatsD protocol invalid, should be udp or tcp"
+	ConfigDataFormatBadError          = "Output data_format invalid, should be one of carbon2, graphite, influx, json, prometheus, splunkmetric, wavefront"
+	ConfigLoadBalanceBadWeightsError  = "LoadBalance weights invalid, should be a positive weight for each of at least two outputs"
+	ConfigLoadBalanceBadOutputsError  = "LoadBalance outputs invalid, should be influxdb or influxdb_v2 outputs that differ only in their urls"
+	ConfigAuditFileBadPathError       = "AuditFile path invalid, should be a clean path to a file in an allowed directory"
+	ConfigAuditFileNamespaceError     = "AuditFile path invalid, LogSinks should write to the subdirectory named after their namespace and ClusterLogSinks directly to an allowed directory"
+	ConfigMaxConnectionsBadCountError = "MaxConnections invalid, should be greater than 0"
+	ConfigServiceRefConflictError     = "ServiceRef cannot be combined with host, port or receivers"
+	ConfigServiceRefBadNameError      = "ServiceRef invalid, should have a valid service name, namespace and port name"
+	ConfigServiceRefNoNamespaceError  = "ServiceRef namespace is required for ClusterLogSinks"
+	ConfigHostnameSourceBadError      = "HostnameSource invalid, should be one of node, pod, container"
+	ConfigHostnameSourceSyslogError   = "HostnameSource is only supported for syslog sinks"
+	ConfigReceiversConflictError      = "Receivers cannot be combined with host or port"
+	ConfigReceiversEmptyError         = "Receivers invalid, should list at least one receiver"
+	ConfigReceiversDuplicateError     = "Receivers invalid, each receiver should have a distinct host and port"
+	ConfigRetryBadBufferSizeError     = "Retry max_buffer_size invalid, should be a quantity greater than 0"
+	ConfigRoutingClusterOnlyError     = "AnnotationRouting is only supported for ClusterLogSinks"
+	ConfigRoutingSyslogError          = "AnnotationRouting is only supported for webhook sinks"
+	ConfigRoutingConflictError        = "AnnotationRouting cannot be combined with raw_mode, project, namespace_globs or audit_log"
+	ConfigRoutingBadAnnotationError   = "AnnotationRouting annotation invalid, should be a valid annotation key"
+	ConfigRoutingBadRouteError        = "AnnotationRouting routes invalid, should map values without whitespace to https URLs"
+	ConfigCodeRoutingClusterOnlyError = "StatusCodeRouting is only supported for ClusterLogSinks"
+	ConfigGeoIPClusterOnlyError       = "GeoIP is only supported for ClusterLogSinks"
+	ConfigCodeRoutingSyslogError      = "StatusCodeRouting is only supported for webhook sinks"
+	ConfigCodeRoutingConflictError    = "StatusCodeRouting cannot be combined with annotation_routing, raw_mode, project, namespace_globs or audit_log"
+	ConfigCodeRoutingBadFieldError    = "StatusCodeRouting field invalid, should be a key of letters, digits and underscores"
+	ConfigCodeRoutingBadRouteError    = "StatusCodeRouting routes invalid, should map codes or code ranges like 500-599 to https URLs"
+	ConfigCodeRoutingOverlapError     = "StatusCodeRouting routes invalid, code ranges should not overlap"
+	ConfigMetricsLogSinkOnlyError     = "Metrics is only supported for LogSinks"
+	ConfigHeadersBadSecretError       = "HeadersFromSecret secret_name invalid, should be a valid Secret name"
+	ConfigHeadersBadHeaderError       = "HeadersFromSecret headers invalid, should map header names to Secret keys"
+	ConfigCoalesceBadWindowError      = "Coalesce window invalid, should be between 1s and 1h"
+	ConfigHeartbeatBadIntervalError   = "Heartbeat interval invalid, should be at least 1s"
+	ConfigRetentionHintBadError       = "RetentionHint invalid, should be positive"
+	ConfigSkipOlderBadDurationError   = "SkipLogsOlderThan invalid, should be positive"
+	ConfigSkipOlderNoInputError       = "SkipLogsOlderThan is only supported for raw_mode and audit_log sinks"
+	ConfigLabelThrottleBadLabelError  = "PerLabelThrottle label invalid, should be a valid label key"
+	ConfigLabelThrottleBadRateError   = "PerLabelThrottle rate invalid, should be at least 1"
+	ConfigScopeUnauthorizedError      = "Widening the records a ClusterLogSink reads requires permission to get the logs of pods in every namespace"
+	ConfigFilterSetBadNameError       = "Filter name invalid, should be non-empty and contain no whitespace"
+	ConfigFilterSetBadKeyError        = "Filter option key invalid, should be non-empty, contain no whitespace and not be name or match"
+	ConfigFilterSetBadValueError      = "Filter option value invalid, should not contain line breaks"
 )
 
 type ServerOpt func(*Server)
@@ -42,8 +154,18 @@ type Server struct {
 	lis net.Listener
 	srv *http.Server
 
-	addr      string
-	tlsConfig *tls.Config
+	addr                   string
+	tlsConfig              *tls.Config
+	outputTypes            OutputTypePolicy
+	opMu                   sync.RWMutex
+	operations             OperationPolicy
+	observabilityNamespace string
+	pipelineService        PipelineService
+	namespaceRateCap       int
+	sinkLister             listers.LogSinkLister
+	serviceLister          corelisters.ServiceLister
+	scopeAuthorizer        SubjectAccessReviewCreator
+	cacheSyncs             []cache.InformerSynced
 }
 
 func NewServer(addr string, options ...ServerOpt) *Server {
@@ -109,8 +231,11 @@ func (s *Server) run() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", healthHandler)
-	mux.HandleFunc("/metricsink", metricSinkHandler)
-	mux.HandleFunc("/logsink", logSinkHandler)
+	mux.HandleFunc("/ready", s.readyHandler)
+	mux.HandleFunc("/metricsink", s.metricSinkHandler)
+	mux.HandleFunc("/logsink", s.logSinkHandler)
+	mux.HandleFunc("/filterset", s.filterSetHandler)
+	mux.Handle("/debug/vars", expvar.Handler())
 
 	s.mu.Lock()
 	s.lis = lis
@@ -136,12 +261,16 @@ var codecs = serializer.NewCodecFactory(scheme)
 
 func healthHandler(_ http.ResponseWriter, _ *http.Request) {}
 
-func metricSinkHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) metricSinkHandler(w http.ResponseWriter, r *http.Request) {
 	requestedAdmissionReview, httpErr := deserializeReview(r)
 	if httpErr != nil {
 		httpErr.Write(w)
 		return
 	}
+	if resp := s.checkOperation(requestedAdmissionReview); resp != nil {
+		writeResponse(w, requestedAdmissionReview, resp)
+		return
+	}
 
 	var cms sink.ClusterMetricSink
 	err := json.Unmarshal(requestedAdmissionReview.Request.Object.Raw, &cms)
@@ -155,11 +284,7 @@ func metricSinkHandler(w http.ResponseWriter, r *http.Request) {
 		httpErr.Write(w)
 		return
 	}
-
-	err = json.NewEncoder(w).Encode(&v1beta1.AdmissionReview{Response: resp})
-	if err != nil {
-		log.Printf("Unable to marshal resp: %s", err)
-	}
+	writeResponse(w, requestedAdmissionReview, resp)
 }
 
 func toAdmissionErrorResponse(err string) *v1beta1.AdmissionResponse {
@@ -170,66 +295,158 @@ func toAdmissionErrorResponse(err string) *v1beta1.AdmissionResponse {
 	}
 }
 
-func logSinkHandler(w http.ResponseWriter, r *http.Request) {
+func (s *Server) logSinkHandler(w http.ResponseWriter, r *http.Request) {
 	requestedAdmissionReview, httpErr := deserializeReview(r)
 	if httpErr != nil {
 		httpErr.Write(w)
 		return
 	}
-	resp, err := validateLogSinkConfigRequest(requestedAdmissionReview)
-	if err != nil {
-		errUnableToDeserialize.Write(w)
+	if resp := s.checkOperation(requestedAdmissionReview); resp != nil {
+		writeResponse(w, requestedAdmissionReview, resp)
+		return
 	}
+	resp, httpErr := s.validateLogSinkConfigRequest(requestedAdmissionReview)
+	if httpErr != nil {
+		httpErr.Write(w)
+		return
+	}
+	writeResponse(w, requestedAdmissionReview, resp)
+}
 
-	err = json.NewEncoder(w).Encode(&v1beta1.AdmissionReview{Response: resp})
+// validateSpecRequest validates the sink of the request as the kind it is,
+// and the update from old when old is not nil. namespace is the namespace
+// of the request.
+func validateSpecRequest(kind, namespace string, cls, old *sink.ClusterLogSink) field.ErrorList {
+	if kind == "LogSink" {
+		ls := &sink.LogSink{ObjectMeta: cls.ObjectMeta, Spec: cls.Spec}
+		ls.Namespace = namespace
+		if old != nil {
+			return ValidateLogSinkUpdate(ls, &sink.LogSink{ObjectMeta: old.ObjectMeta, Spec: old.Spec})
+		}
+		return ValidateLogSink(ls)
+	}
+	if old != nil {
+		return ValidateClusterLogSinkUpdate(cls, old)
+	}
+	return ValidateClusterLogSink(cls)
+}
+
+func writeResponse(w http.ResponseWriter, rar *v1beta1.AdmissionReview, resp *v1beta1.AdmissionResponse) {
+	recordAdmission(rar, resp)
+
+	err := json.NewEncoder(w).Encode(&v1beta1.AdmissionReview{Response: resp})
 	if err != nil {
 		log.Printf("Unable to marshal resp: %s", err)
 	}
 }
 
-func validateLogSinkConfigRequest(rar *v1beta1.AdmissionReview) (*v1beta1.AdmissionResponse, error) {
+func (s *Server) validateLogSinkConfigRequest(rar *v1beta1.AdmissionReview) (*v1beta1.AdmissionResponse, *httpError) {
 	var cls sink.ClusterLogSink
 	err := json.Unmarshal(rar.Request.Object.Raw, &cls)
 	if err != nil {
 		return nil, errUnableToDeserialize
 	}
 
+	namespace := rar.Request.Namespace
+	if namespace == "" {
+		namespace = cls.Namespace
+	}
+
+	var clsOld *sink.ClusterLogSink
 	if rar.Request.Operation == "UPDATE" {
-		var clsOld sink.ClusterLogSink
-		err := json.Unmarshal(rar.Request.OldObject.Raw, &clsOld)
+		clsOld = &sink.ClusterLogSink{}
+		err := json.Unmarshal(rar.Request.OldObject.Raw, clsOld)
 		if err != nil {
 			return nil, errUnableToDeserialize
 		}
-		if clsOld.Spec.Type != cls.Spec.Type {
-			return toAdmissionErrorResponse(ConfigLogChangeTypeError), nil
-		}
 	}
 
-	switch cls.Spec.Type {
-	case "syslog":
-		if !cls.Spec.EnableTLS {
-			return toAdmissionErrorResponse(ConfigSyslogInsecureError), nil
+	if errs := validateSpecRequest(rar.Request.Kind.Kind, namespace, &cls, clsOld); len(errs) > 0 {
+		return toAdmissionErrorResponse(errs[0].Detail), nil
+	}
+	if cls.Spec.Metrics != nil && rar.Request.Kind.Kind != "LogSink" {
+		return toAdmissionErrorResponse(ConfigMetricsLogSinkOnlyError), nil
+	}
+	if cls.Spec.Metrics != nil {
+		resp, httpErr := validateLogSinkMetrics(*rar, &cls)
+		if httpErr != nil {
+			return nil, httpErr
+		}
+		if !resp.Allowed {
+			return resp, nil
+		}
+	}
+	if rar.Request.Kind.Kind == "LogSink" {
+		if cls.Spec.NamespaceGlobs != nil {
+			return toAdmissionErrorResponse(ConfigGlobsClusterOnlyError), nil
+		}
+		if cls.Spec.AuditLog != nil {
+			return toAdmissionErrorResponse(ConfigAuditLogClusterOnlyError), nil
 		}
-		if cls.Spec.Host == "" {
-			return toAdmissionErrorResponse(ConfigSyslogBadHostError), nil
+		if cls.Spec.AnnotationRouting != nil {
+			return toAdmissionErrorResponse(ConfigRoutingClusterOnlyError), nil
 		}
-		if cls.Spec.Port > 65535 || cls.Spec.Port < 1 {
-			return toAdmissionErrorResponse(ConfigSyslogBadPortError), nil
+		if cls.Spec.StatusCodeRouting != nil {
+			return toAdmissionErrorResponse(ConfigCodeRoutingClusterOnlyError), nil
 		}
-	case "webhook":
-		if cls.Spec.URL == "" {
-			return toAdmissionErrorResponse(ConfigWebhookBadURLError), nil
+		if cls.Spec.GeoIP != nil {
+			return toAdmissionErrorResponse(ConfigGeoIPClusterOnlyError), nil
 		}
-		if !strings.HasPrefix(cls.Spec.URL, "https://") {
-			return toAdmissionErrorResponse(ConfigWebhookInsecureError), nil
+		if msg := s.outputTypes.check(namespace, cls.Spec.Type); msg != "" {
+			return toAdmissionErrorResponse(msg), nil
 		}
-	default:
-		return toAdmissionErrorResponse(ConfigLogNoTypeError), nil
 	}
-	return &v1beta1.AdmissionResponse{
+
+	if msg := s.checkRateCap(rar.Request.Kind.Kind, namespace, cls.Name, &cls.Spec); msg != "" {
+		return toAdmissionErrorResponse(msg), nil
+	}
+
+	msg, warn := s.checkSelfCapture(rar.Request.Kind.Kind, namespace, &cls.Spec)
+	if msg != "" {
+		return toAdmissionErrorResponse(msg), nil
+	}
+
+	if msg := s.checkSelfReference(rar.Request.Kind.Kind, namespace, &cls.Spec); msg != "" {
+		return toAdmissionErrorResponse(msg), nil
+	}
+
+	msg, serviceWarning := s.checkServiceRef(rar.Request.Kind.Kind, namespace, &cls.Spec)
+	if msg != "" {
+		return toAdmissionErrorResponse(msg), nil
+	}
+
+	if msg := s.checkScope(rar.Request, &cls.Spec, clsOld); msg != "" {
+		return toAdmissionErrorResponse(msg), nil
+	}
+
+	resp := &v1beta1.AdmissionResponse{
 		UID:     rar.Request.UID,
 		Allowed: true,
-	}, nil
+	}
+	if warn || serviceWarning != "" {
+		resp.AuditAnnotations = make(map[string]string)
+	}
+	if warn {
+		resp.AuditAnnotations[selfCaptureAnnotation] = "sink captures the observability namespace " + s.observabilityNamespace
+	}
+	if serviceWarning != "" {
+		resp.AuditAnnotations[serviceRefAnnotation] = serviceWarning
+	}
+	return resp, nil
+}
+
+// validateLogSinkMetrics validates the metrics of a LogSink like the spec
+// of a MetricSink in its namespace, which is how the metric-controller
+// provisions them.
+func validateLogSinkMetrics(rar v1beta1.AdmissionReview, ls *sink.ClusterLogSink) (*v1beta1.AdmissionResponse, *httpError) {
+	req := *rar.Request
+	req.Kind.Kind = "MetricSink"
+	rar.Request = &req
+
+	return validateMetricSinkConfig(rar, sink.ClusterMetricSink{
+		ObjectMeta: ls.ObjectMeta,
+		Spec:       *ls.Spec.Metrics,
+	})
 }
 
 func validRequest(r v1beta1.AdmissionReview) bool {
@@ -259,6 +476,28 @@ func validateMetricSinkConfig(rar v1beta1.AdmissionReview, cms sink.ClusterMetri
 		}
 	}
 
+	if cms.Spec.ScrapeAuth != nil && rar.Request.Kind.Kind != "MetricSink" {
+		return toAdmissionErrorResponse(ConfigScrapeAuthClusterError), nil
+	}
+	if cms.Spec.ScrapeTLS != nil && rar.Request.Kind.Kind != "MetricSink" {
+		return toAdmissionErrorResponse(ConfigScrapeTLSClusterError), nil
+	}
+	if cms.Spec.StatsD != nil && rar.Request.Kind.Kind != "MetricSink" {
+		return toAdmissionErrorResponse(ConfigStatsDClusterError), nil
+	}
+	if cms.Spec.KubeletMetrics && rar.Request.Kind.Kind != "ClusterMetricSink" {
+		return toAdmissionErrorResponse(ConfigKubeletMetricsError), nil
+	}
+	errs := validateScrapeAuth(cms.Spec.ScrapeAuth, field.NewPath("spec", "scrape_auth"))
+	errs = append(errs, validateScrapeTLS(cms.Spec.ScrapeTLS, field.NewPath("spec", "scrape_tls"))...)
+	errs = append(errs, validateFileRotation(cms.Spec.FileRotation, field.NewPath("spec", "file_rotation"))...)
+	errs = append(errs, validateStatsD(cms.Spec.StatsD, field.NewPath("spec", "statsd"))...)
+	errs = append(errs, validateDataFormats(cms.Spec.Outputs, field.NewPath("spec", "outputs"))...)
+	errs = append(errs, validateLoadBalance(&cms.Spec, field.NewPath("spec"))...)
+	if len(errs) > 0 {
+		return toAdmissionErrorResponse(errs[0].Detail), nil
+	}
+
 	// Which version of default inputs irrelevant to validation at time of
 	// commit.
 	cfg := metric.NewConfig("", metric.KubernetesDefault(false))
@@ -188,6 +188,15 @@ func TestValidator(t *testing.T) {
 						"url": "https://example.com/place"
 					}`,
 				},
+				{
+					"startup throttle",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"startup_delay": "30s",
+						"startup_rate": 100
+					}`,
+				},
 			}
 			server := webhook.NewServer("127.0.0.1:0")
 			server.Run(false)
@@ -318,6 +327,62 @@ func TestValidator(t *testing.T) {
 					}`,
 					"Insecure webhook not allowed, scheme must be https",
 				},
+				{
+					"startup delay without rate",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"startup_delay": "30s"
+					}`,
+					"StartupDelay and StartupRate must be set together",
+				},
+				{
+					"startup rate without delay",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"startup_rate": 100
+					}`,
+					"StartupDelay and StartupRate must be set together",
+				},
+				{
+					"short startup delay",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"startup_delay": "500ms",
+						"startup_rate": 100
+					}`,
+					"StartupDelay invalid, should be at least 1s",
+				},
+				{
+					"negative startup rate",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"startup_delay": "30s",
+						"startup_rate": -1
+					}`,
+					"StartupRate invalid, should be greater than 0",
+				},
+				{
+					"bad sampling rate",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"severity_sampling": {"info": 2}
+					}`,
+					"SeveritySampling rate invalid, should be greater than 0 and at most 1",
+				},
+				{
+					"audit file of another namespace",
+					`{
+						"type": "webhook",
+						"url": "https://example.com/place",
+						"audit_file": {"path": "/var/log/observability/other-team/some-sink.log"}
+					}`,
+					webhook.ConfigAuditFileNamespaceError,
+				},
 			}
 			server := webhook.NewServer("127.0.0.1:0")
 			server.Run(false)
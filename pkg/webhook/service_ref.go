@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"fmt"
+	"log"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	coreV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+// serviceRefAnnotation is the audit annotation added to sinks that
+// reference a service or port that does not exist.
+const serviceRefAnnotation = "observability.knative.dev/service-ref"
+
+// WithServiceLister checks that the services syslog sinks reference exist.
+// The services are read from the lister, which is expected to be backed by
+// an informer cache.
+func WithServiceLister(services corelisters.ServiceLister) ServerOpt {
+	return func(s *Server) {
+		s.serviceLister = services
+	}
+}
+
+// checkServiceRef returns the message to reject a sink with when its
+// service ref has no namespace to resolve in, which is the case for
+// ClusterLogSinks that do not set one. A missing service or port is not
+// rejected, since the service may be created after the sink, and warning
+// is set instead. ExternalName services are resolved to the name they
+// point at, so one without a name is warned about as well.
+func (s *Server) checkServiceRef(kind, namespace string, spec *sink.SinkSpec) (msg, warning string) {
+	ref := spec.ServiceRef
+	if spec.Type != "syslog" || ref == nil {
+		return "", ""
+	}
+
+	if ref.Namespace != "" {
+		namespace = ref.Namespace
+	} else if kind != "LogSink" {
+		return ConfigServiceRefNoNamespaceError, ""
+	}
+
+	if s.serviceLister == nil {
+		return "", ""
+	}
+	svc, err := s.serviceLister.Services(namespace).Get(ref.Name)
+	if errors.IsNotFound(err) {
+		return "", fmt.Sprintf("service %s/%s not found", namespace, ref.Name)
+	}
+	if err != nil {
+		log.Printf("Unable to get service %s/%s: %s", namespace, ref.Name, err)
+		return "", ""
+	}
+	if svc.Spec.Type == coreV1.ServiceTypeExternalName && svc.Spec.ExternalName == "" {
+		return "", fmt.Sprintf("service %s/%s is an ExternalName service without an external name", namespace, ref.Name)
+	}
+	for _, p := range svc.Spec.Ports {
+		if p.Name == ref.PortName {
+			return "", ""
+		}
+	}
+	return "", fmt.Sprintf("service %s/%s has no port %s", namespace, ref.Name, ref.PortName)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestServiceRef(t *testing.T) {
+	indexer := cache.NewIndexer(
+		cache.MetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	err := indexer.Add(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "collector",
+			Namespace: "team-a",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "syslog-tls", Port: 6514}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, externalName := range map[string]string{"external": "logs.example.com", "unnamed": ""} {
+		err = indexer.Add(&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "team-a",
+			},
+			Spec: corev1.ServiceSpec{
+				Type:         corev1.ServiceTypeExternalName,
+				ExternalName: externalName,
+				Ports:        []corev1.ServicePort{{Name: "syslog-tls", Port: 6514}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := webhook.NewServer(
+		"127.0.0.1:0",
+		webhook.WithServiceLister(corelisters.NewServiceLister(indexer)),
+	)
+	server.Run(false)
+	defer server.Close()
+
+	spec := func(ref string) string {
+		return fmt.Sprintf(`{
+			"type": "syslog",
+			"enable_tls": true,
+			"service_ref": %s
+		}`, ref)
+	}
+
+	var tests = []struct {
+		name       string
+		kind       string
+		namespace  string
+		ref        string
+		annotation string
+	}{
+		{
+			"it allows refs to existing ports",
+			"LogSink",
+			"team-a",
+			`{"name": "collector", "port_name": "syslog-tls"}`,
+			"",
+		},
+		{
+			"it allows ClusterLogSink refs with a namespace",
+			"ClusterLogSink",
+			"",
+			`{"name": "collector", "namespace": "team-a", "port_name": "syslog-tls"}`,
+			"",
+		},
+		{
+			"it warns on missing services",
+			"LogSink",
+			"team-b",
+			`{"name": "collector", "port_name": "syslog-tls"}`,
+			"service team-b/collector not found",
+		},
+		{
+			"it warns on missing ports",
+			"LogSink",
+			"team-a",
+			`{"name": "collector", "port_name": "syslog"}`,
+			"service team-a/collector has no port syslog",
+		},
+		{
+			"it allows refs to ExternalName services",
+			"LogSink",
+			"team-a",
+			`{"name": "external", "port_name": "syslog-tls"}`,
+			"",
+		},
+		{
+			"it warns on ExternalName services without a name",
+			"LogSink",
+			"team-a",
+			`{"name": "unnamed", "port_name": "syslog-tls"}`,
+			"service team-a/unnamed is an ExternalName service without an external name",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := postReview(t, server, "/logsink", fmt.Sprintf(
+				namespacedAdmissionTemplate,
+				test.kind,
+				test.namespace,
+				spec(test.ref),
+			))
+			if !resp.Response.Allowed {
+				t.Fatalf("expected response to be allowed, got %+v", resp.Response.Result)
+			}
+			if got := resp.Response.AuditAnnotations["observability.knative.dev/service-ref"]; got != test.annotation {
+				t.Errorf("expected annotation %q, got %q", test.annotation, got)
+			}
+		})
+	}
+
+	t.Run("it rejects ClusterLogSink refs without a namespace", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"ClusterLogSink",
+			"",
+			spec(`{"name": "collector", "port_name": "syslog-tls"}`),
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigServiceRefNoNamespaceError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigServiceRefNoNamespaceError, resp.Response.Result.Message)
+		}
+	})
+}
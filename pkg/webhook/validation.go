@@ -0,0 +1,999 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	sinkconfig "github.com/knative/observability/pkg/sink"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// severities are the keys allowed in SeveritySampling.
+var severities = map[string]bool{
+	"debug":    true,
+	"info":     true,
+	"warning":  true,
+	"error":    true,
+	"critical": true,
+}
+
+// AuditFileDirs are the directories on the nodes sinks may write audit
+// files to. The fluent-bit daemonset mounts them and rotates the files.
+// ClusterLogSinks write directly to one of them and LogSinks to the
+// subdirectory named after their namespace, so that sinks in different
+// namespaces cannot write to or rotate each other's files.
+var AuditFileDirs = []string{
+	"/var/log/observability",
+}
+
+// UnixSocketDirs are the directories on the nodes that unix socket sinks
+// may connect to sockets under. The fluent-bit daemonset mounts them.
+var UnixSocketDirs = []string{
+	"/var/run/observability",
+}
+
+// redactMethods are the methods rules may redact fields with.
+var redactMethods = map[string]bool{
+	"drop": true,
+	"hash": true,
+	"mask": true,
+}
+
+// GeoIPDatabaseDir is the directory the fluent-bit daemonset mounts the
+// GeoIP databases of sinks in.
+const GeoIPDatabaseDir = "/fluent-bit/geoip"
+
+// Sink priorities are limited so that operators can always order a sink
+// before or after any other.
+const (
+	minPriority = -1000
+	maxPriority = 1000
+)
+
+// ValidateLogSink returns the errors the webhook would reject the LogSink
+// with. Each error's Detail is the message returned by the webhook.
+func ValidateLogSink(s *sink.LogSink) field.ErrorList {
+	fldPath := field.NewPath("spec")
+	allErrs := validateSinkSpec(&s.Spec, fldPath)
+	if s.Spec.NamespaceGlobs != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("namespace_globs"), s.Spec.NamespaceGlobs, ConfigGlobsClusterOnlyError))
+	}
+	if s.Spec.AuditLog != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_log"), s.Spec.AuditLog, ConfigAuditLogClusterOnlyError))
+	}
+	if s.Spec.AnnotationRouting != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("annotation_routing"), s.Spec.AnnotationRouting, ConfigRoutingClusterOnlyError))
+	}
+	if s.Spec.StatusCodeRouting != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("status_code_routing"), s.Spec.StatusCodeRouting, ConfigCodeRoutingClusterOnlyError))
+	}
+	if s.Spec.GeoIP != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("geoip"), s.Spec.GeoIP, ConfigGeoIPClusterOnlyError))
+	}
+	if af := s.Spec.AuditFile; af != nil && allowedAuditFile(af.Path) && auditFileNamespace(af.Path) != s.Namespace {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_file", "path"), af.Path, ConfigAuditFileNamespaceError))
+	}
+	return allErrs
+}
+
+// ValidateClusterLogSink returns the errors the webhook would reject the
+// ClusterLogSink with.
+func ValidateClusterLogSink(s *sink.ClusterLogSink) field.ErrorList {
+	fldPath := field.NewPath("spec")
+	allErrs := validateSinkSpec(&s.Spec, fldPath)
+	for i, g := range s.Spec.NamespaceGlobs {
+		if _, err := path.Match(g, ""); g == "" || err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespace_globs").Index(i), g, ConfigGlobBadPatternError))
+		}
+	}
+	if s.Spec.AuditLog != nil {
+		allErrs = append(allErrs, validateAuditLog(&s.Spec, fldPath)...)
+	}
+	if s.Spec.AnnotationRouting != nil {
+		allErrs = append(allErrs, validateAnnotationRouting(&s.Spec, fldPath)...)
+	}
+	if s.Spec.StatusCodeRouting != nil {
+		allErrs = append(allErrs, validateStatusCodeRouting(&s.Spec, fldPath)...)
+	}
+	if af := s.Spec.AuditFile; af != nil && allowedAuditFile(af.Path) && auditFileNamespace(af.Path) != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_file", "path"), af.Path, ConfigAuditFileNamespaceError))
+	}
+	return allErrs
+}
+
+// validateAnnotationRouting validates the routes of a ClusterLogSink. The
+// annotation and values are rendered into rewrite_tag rules, which are
+// split on whitespace.
+func validateAnnotationRouting(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	routing := spec.AnnotationRouting
+	routingPath := fldPath.Child("annotation_routing")
+	if spec.Type != "webhook" {
+		allErrs = append(allErrs, field.Invalid(routingPath, routing, ConfigRoutingSyslogError))
+	}
+	if spec.RawMode || projects(spec) || spec.NamespaceGlobs != nil || spec.AuditLog != nil {
+		allErrs = append(allErrs, field.Invalid(routingPath, routing, ConfigRoutingConflictError))
+	}
+	if len(validation.IsQualifiedName(routing.Annotation)) > 0 {
+		allErrs = append(allErrs, field.Invalid(routingPath.Child("annotation"), routing.Annotation, ConfigRoutingBadAnnotationError))
+	}
+	if len(routing.Routes) == 0 {
+		allErrs = append(allErrs, field.Invalid(routingPath.Child("routes"), routing.Routes, ConfigRoutingBadRouteError))
+	}
+
+	values := make([]string, 0, len(routing.Routes))
+	for v := range routing.Routes {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	for _, v := range values {
+		url := routing.Routes[v]
+		if v == "" || strings.IndexFunc(v, unicode.IsSpace) >= 0 || !strings.HasPrefix(url, "https://") {
+			allErrs = append(allErrs, field.Invalid(routingPath.Child("routes").Key(v), url, ConfigRoutingBadRouteError))
+		}
+	}
+	return allErrs
+}
+
+// routingFieldPattern matches the record keys status codes can be routed
+// by. The key is rendered into a rewrite_tag rule as a record accessor. It
+// also matches the keys the geoip2 filter can look addresses up in.
+var routingFieldPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// codeRange is a parsed status code range and the route key it was parsed
+// from.
+type codeRange struct {
+	key string
+	sinkconfig.CodeRange
+}
+
+// validateStatusCodeRouting validates the routes of a ClusterLogSink. Every
+// record is moved by the rule of the first range it is in, so overlapping
+// ranges would route some codes to one URL and leave the other short.
+func validateStatusCodeRouting(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	routing := spec.StatusCodeRouting
+	routingPath := fldPath.Child("status_code_routing")
+	if spec.Type != "webhook" {
+		allErrs = append(allErrs, field.Invalid(routingPath, routing, ConfigCodeRoutingSyslogError))
+	}
+	if spec.AnnotationRouting != nil || spec.RawMode || projects(spec) || spec.NamespaceGlobs != nil || spec.AuditLog != nil {
+		allErrs = append(allErrs, field.Invalid(routingPath, routing, ConfigCodeRoutingConflictError))
+	}
+	if !routingFieldPattern.MatchString(routing.Field) {
+		allErrs = append(allErrs, field.Invalid(routingPath.Child("field"), routing.Field, ConfigCodeRoutingBadFieldError))
+	}
+	if len(routing.Routes) == 0 {
+		allErrs = append(allErrs, field.Invalid(routingPath.Child("routes"), routing.Routes, ConfigCodeRoutingBadRouteError))
+	}
+
+	keys := make([]string, 0, len(routing.Routes))
+	for r := range routing.Routes {
+		keys = append(keys, r)
+	}
+	sort.Strings(keys)
+	var ranges []codeRange
+	for _, r := range keys {
+		url := routing.Routes[r]
+		cr, ok := sinkconfig.ParseCodeRange(r)
+		if !ok || !strings.HasPrefix(url, "https://") {
+			allErrs = append(allErrs, field.Invalid(routingPath.Child("routes").Key(r), url, ConfigCodeRoutingBadRouteError))
+			continue
+		}
+		ranges = append(ranges, codeRange{key: r, CodeRange: cr})
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Lo != ranges[j].Lo {
+			return ranges[i].Lo < ranges[j].Lo
+		}
+		return ranges[i].key < ranges[j].key
+	})
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].Lo <= ranges[i-1].Hi {
+			allErrs = append(allErrs, field.Invalid(routingPath.Child("routes").Key(ranges[i].key), ranges[i].key, ConfigCodeRoutingOverlapError))
+		}
+		if ranges[i].Hi < ranges[i-1].Hi {
+			ranges[i].Hi = ranges[i-1].Hi
+		}
+	}
+	return allErrs
+}
+
+// auditParsers are the parsers in parsers.conf audit logs can be read with.
+var auditParsers = map[string]bool{
+	"":          true,
+	"k8s-audit": true,
+	"json":      true,
+}
+
+func validateAuditLog(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	audit := spec.AuditLog
+	if spec.Type == "syslog" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_log"), audit, ConfigAuditLogSyslogError))
+	}
+	if spec.RawMode || projects(spec) || spec.NamespaceGlobs != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_log"), audit, ConfigAuditLogConflictError))
+	}
+	if !auditParsers[audit.Parser] {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_log", "parser"), audit.Parser, ConfigAuditLogBadParserError))
+	}
+	if audit.Path != "" && (!path.IsAbs(audit.Path) || path.Clean(audit.Path) != audit.Path) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("audit_log", "path"), audit.Path, ConfigAuditLogBadPathError))
+	}
+
+	return append(allErrs, validateLabels(audit.NodeSelector, fldPath.Child("audit_log", "node_selector"), ConfigAuditLogBadSelectorError)...)
+}
+
+// validateLabels returns an error with msg for every label of a selector
+// with an invalid key or value.
+func validateLabels(selector map[string]string, fldPath *field.Path, msg string) field.ErrorList {
+	keys := make([]string, 0, len(selector))
+	for k := range selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var allErrs field.ErrorList
+	for _, k := range keys {
+		v := selector[k]
+		if len(validation.IsQualifiedName(k)) > 0 || len(validation.IsValidLabelValue(v)) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(k), v, msg))
+		}
+	}
+	return allErrs
+}
+
+// ValidateLogSinkUpdate returns the errors the webhook would reject an
+// update from old to s with.
+func ValidateLogSinkUpdate(s, old *sink.LogSink) field.ErrorList {
+	allErrs := validateSinkSpecUpdate(&s.Spec, &old.Spec, field.NewPath("spec"))
+	return append(allErrs, ValidateLogSink(s)...)
+}
+
+// ValidateClusterLogSinkUpdate returns the errors the webhook would reject
+// an update from old to s with.
+func ValidateClusterLogSinkUpdate(s, old *sink.ClusterLogSink) field.ErrorList {
+	allErrs := validateSinkSpecUpdate(&s.Spec, &old.Spec, field.NewPath("spec"))
+	return append(allErrs, ValidateClusterLogSink(s)...)
+}
+
+func validateSinkSpecUpdate(spec, old *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.Type != old.Type {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("type"), spec.Type, ConfigLogChangeTypeError))
+	}
+	return allErrs
+}
+
+func validateSinkSpec(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch spec.Type {
+	case "syslog":
+		if !spec.EnableTLS {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("enable_tls"), spec.EnableTLS, ConfigSyslogInsecureError))
+		}
+		if spec.ServiceRef != nil {
+			allErrs = append(allErrs, validateServiceRef(spec, fldPath)...)
+		} else if spec.Receivers != nil {
+			allErrs = append(allErrs, validateReceivers(spec, fldPath)...)
+		} else {
+			if spec.Host == "" {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("host"), spec.Host, ConfigSyslogBadHostError))
+			}
+			if spec.Port > 65535 || spec.Port < 1 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("port"), spec.Port, ConfigSyslogBadPortError))
+			}
+		}
+		if spec.RawMode {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("raw_mode"), spec.RawMode, ConfigRawModeSyslogError))
+		}
+		if spec.Project != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("project"), spec.Project, ConfigProjectSyslogError))
+		}
+		if spec.Schema != nil && spec.Schema.Strict {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("schema", "strict"), true, ConfigSchemaSyslogError))
+		}
+		allErrs = append(allErrs, validateStructuredData(spec.StructuredData, fldPath.Child("structured_data"))...)
+		switch spec.Framing {
+		case "", "octet-counting", "non-transparent":
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("framing"), spec.Framing, ConfigSyslogBadFramingError))
+		}
+		if spec.MsgID != "" && !validMsgID(spec.MsgID) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("msg_id"), spec.MsgID, ConfigSyslogBadMsgIDError))
+		}
+		switch spec.HostnameSource {
+		case "", "node", "pod", "container":
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("hostname_source"), spec.HostnameSource, ConfigHostnameSourceBadError))
+		}
+	case "webhook":
+		if spec.URL == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), spec.URL, ConfigWebhookBadURLError))
+		} else if !strings.HasPrefix(spec.URL, "https://") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), spec.URL, ConfigWebhookInsecureError))
+		}
+		if timeout := spec.KeepAliveIdleTimeout; timeout != nil {
+			if !spec.KeepAlive {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("keep_alive"), spec.KeepAlive, ConfigKeepAliveIncompleteError))
+			}
+			if timeout.Duration < time.Second {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("keep_alive_idle_timeout"), timeout.Duration.String(), ConfigKeepAliveBadTimeoutError))
+			}
+		}
+		if spec.MaxConnections < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("max_connections"), spec.MaxConnections, ConfigMaxConnectionsBadCountError))
+		}
+		if spec.HeadersFromSecret != nil {
+			allErrs = append(allErrs, validateHeadersFromSecret(spec.HeadersFromSecret, fldPath.Child("headers_from_secret"))...)
+		}
+	case "unix_socket":
+		if !allowedUnixSocket(spec.Path) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("path"), spec.Path, ConfigUnixSocketBadPathError))
+		}
+		if spec.RawMode {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("raw_mode"), spec.RawMode, ConfigRawModeSyslogError))
+		}
+		if spec.Project != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("project"), spec.Project, ConfigProjectSyslogError))
+		}
+		if spec.Schema != nil && spec.Schema.Strict {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("schema", "strict"), true, ConfigSchemaSyslogError))
+		}
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("type"), spec.Type, ConfigLogNoTypeError))
+	}
+	if spec.Type != "syslog" && spec.HostnameSource != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("hostname_source"), spec.HostnameSource, ConfigHostnameSourceSyslogError))
+	}
+	if spec.Type != "syslog" && spec.MsgID != "" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("msg_id"), spec.MsgID, ConfigMsgIDSyslogError))
+	}
+
+	if (spec.StartupDelay != nil) != (spec.StartupRate != 0) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startup_rate"), spec.StartupRate, ConfigStartupIncompleteError))
+	}
+	if spec.StartupDelay != nil && spec.StartupDelay.Duration < time.Second {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startup_delay"), spec.StartupDelay.Duration.String(), ConfigStartupBadDelayError))
+	}
+	if spec.StartupRate < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("startup_rate"), spec.StartupRate, ConfigStartupBadRateError))
+	}
+
+	sampled := make([]string, 0, len(spec.SeveritySampling))
+	for severity := range spec.SeveritySampling {
+		sampled = append(sampled, severity)
+	}
+	sort.Strings(sampled)
+	for _, severity := range sampled {
+		rate := spec.SeveritySampling[severity]
+		if !severities[severity] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("severity_sampling").Key(severity), severity, ConfigSamplingBadSeverityError))
+		}
+		if rate <= 0 || rate > 1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("severity_sampling").Key(severity), rate, ConfigSamplingBadRateError))
+		}
+	}
+
+	mapped := make([]string, 0, len(spec.SeverityMapping))
+	for severity := range spec.SeverityMapping {
+		mapped = append(mapped, severity)
+	}
+	sort.Strings(mapped)
+	for _, severity := range mapped {
+		level := spec.SeverityMapping[severity]
+		if severity == "" || severity != strings.ToLower(severity) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("severity_mapping").Key(severity), severity, ConfigMappingBadSeverityError))
+		}
+		if level < 0 || level > 7 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("severity_mapping").Key(severity), level, ConfigMappingBadLevelError))
+		}
+	}
+	if spec.SeverityField != "" && len(spec.SeverityMapping) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("severity_field"), spec.SeverityField, ConfigMappingFieldError))
+	}
+
+	if spec.Timestamp != nil {
+		ts := spec.Timestamp
+		if ts.Timezone != "" || ts.EpochMillisField == "" {
+			if _, err := time.LoadLocation(ts.Timezone); err != nil || !validTimezone(ts.Timezone) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("timestamp", "timezone"), ts.Timezone, ConfigTimestampBadTimezoneError))
+			}
+		}
+		if ts.EpochMillisField != "" && !validEpochMillisField(ts.EpochMillisField) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("timestamp", "epoch_millis_field"), ts.EpochMillisField, ConfigTimestampBadMillisError))
+		}
+	}
+
+	if spec.Metadata != nil && spec.Metadata.StripKeyRegex != "" {
+		if _, err := regexp.Compile(spec.Metadata.StripKeyRegex); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("metadata", "strip_key_regex"), spec.Metadata.StripKeyRegex, ConfigMetadataBadRegexError))
+		}
+	}
+
+	switch spec.RequireKubernetesMetadata {
+	case "", "require", "allow":
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("require_kubernetes_metadata"), spec.RequireKubernetesMetadata, ConfigMetadataBadPolicyError))
+	}
+
+	if spec.Project != nil && len(spec.Project) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("project"), spec.Project, ConfigProjectNoKeysError))
+	}
+	for i, k := range spec.Project {
+		if k == "" || strings.IndexFunc(k, unicode.IsSpace) != -1 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("project").Index(i), k, ConfigProjectBadKeyError))
+		}
+	}
+
+	if schema := spec.Schema; schema != nil {
+		schemaPath := fldPath.Child("schema")
+		if spec.Project != nil {
+			allErrs = append(allErrs, field.Invalid(schemaPath, schema, ConfigSchemaProjectError))
+		}
+		if schema.Strict && len(schema.AllowedKeys) == 0 {
+			allErrs = append(allErrs, field.Invalid(schemaPath.Child("allowed_keys"), schema.AllowedKeys, ConfigSchemaNoKeysError))
+		}
+		for i, k := range schema.AllowedKeys {
+			if k == "" || strings.IndexFunc(k, unicode.IsSpace) != -1 {
+				allErrs = append(allErrs, field.Invalid(schemaPath.Child("allowed_keys").Index(i), k, ConfigSchemaBadKeyError))
+			}
+		}
+	}
+
+	if spec.RequireFields != nil && len(spec.RequireFields) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("require_fields"), spec.RequireFields, ConfigRequireFieldsEmptyError))
+	}
+	for i, f := range spec.RequireFields {
+		if !validFieldPath(f) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("require_fields").Index(i), f, ConfigRequireFieldsBadFieldError))
+		}
+	}
+
+	redacted := make(map[string]bool, len(spec.Redact))
+	for i, r := range spec.Redact {
+		rulePath := fldPath.Child("redact").Index(i)
+		if !validFieldPath(r.Field) {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("field"), r.Field, ConfigRedactBadFieldError))
+		} else if redacted[r.Field] {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("field"), r.Field, ConfigRedactDuplicateError))
+		}
+		redacted[r.Field] = true
+		if !redactMethods[r.Method] {
+			allErrs = append(allErrs, field.Invalid(rulePath.Child("method"), r.Method, ConfigRedactBadMethodError))
+		}
+	}
+
+	limited := make([]string, 0, len(spec.MaxFieldBytes))
+	for f := range spec.MaxFieldBytes {
+		limited = append(limited, f)
+	}
+	sort.Strings(limited)
+	for _, f := range limited {
+		limit := spec.MaxFieldBytes[f]
+		if !validFieldPath(f) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("max_field_bytes").Key(f), f, ConfigMaxFieldBytesBadFieldError))
+		}
+		if limit <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("max_field_bytes").Key(f), limit, ConfigMaxFieldBytesBadLimitError))
+		}
+	}
+
+	if len(spec.NodeSelector) > 0 {
+		selectorPath := fldPath.Child("node_selector")
+		if spec.Type == "syslog" {
+			allErrs = append(allErrs, field.Invalid(selectorPath, spec.NodeSelector, ConfigNodeSelectorSyslogError))
+		}
+		if spec.RawMode || spec.AuditLog != nil {
+			allErrs = append(allErrs, field.Invalid(selectorPath, spec.NodeSelector, ConfigNodeSelectorConflictError))
+		}
+		allErrs = append(allErrs, validateLabels(spec.NodeSelector, selectorPath, ConfigNodeSelectorBadError)...)
+	}
+
+	if spec.GeoIP != nil {
+		geoIPPath := fldPath.Child("geoip")
+		if !routingFieldPattern.MatchString(spec.GeoIP.SourceField) {
+			allErrs = append(allErrs, field.Invalid(geoIPPath.Child("source_field"), spec.GeoIP.SourceField, ConfigGeoIPBadFieldError))
+		}
+		if !allowedGeoIPDatabase(spec.GeoIP.Database) {
+			allErrs = append(allErrs, field.Invalid(geoIPPath.Child("database"), spec.GeoIP.Database, ConfigGeoIPBadDatabaseError))
+		}
+	}
+
+	if spec.Priority < minPriority || spec.Priority > maxPriority {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("priority"), spec.Priority, ConfigPriorityBadRangeError))
+	}
+
+	if spec.AuditFile != nil {
+		filePath := fldPath.Child("audit_file")
+		if !allowedAuditFile(spec.AuditFile.Path) {
+			allErrs = append(allErrs, field.Invalid(filePath.Child("path"), spec.AuditFile.Path, ConfigAuditFileBadPathError))
+		}
+		allErrs = append(allErrs, validateFileRotation(spec.AuditFile.Rotation, filePath.Child("rotation"))...)
+	}
+
+	if spec.Retry != nil && spec.Retry.MaxBufferSize != nil && spec.Retry.MaxBufferSize.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("retry", "max_buffer_size"), spec.Retry.MaxBufferSize.String(), ConfigRetryBadBufferSizeError))
+	}
+
+	if spec.RetentionHint != nil && spec.RetentionHint.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("retention_hint"), spec.RetentionHint.Duration.String(), ConfigRetentionHintBadError))
+	}
+
+	if skip := spec.SkipLogsOlderThan; skip != nil {
+		if skip.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("skip_logs_older_than"), skip.Duration.String(), ConfigSkipOlderBadDurationError))
+		}
+		if !spec.RawMode && spec.AuditLog == nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("skip_logs_older_than"), skip.Duration.String(), ConfigSkipOlderNoInputError))
+		}
+	}
+
+	if t := spec.PerLabelThrottle; t != nil {
+		throttlePath := fldPath.Child("per_label_throttle")
+		if len(validation.IsQualifiedName(t.Label)) > 0 {
+			allErrs = append(allErrs, field.Invalid(throttlePath.Child("label"), t.Label, ConfigLabelThrottleBadLabelError))
+		}
+		if t.Rate < 1 {
+			allErrs = append(allErrs, field.Invalid(throttlePath.Child("rate"), t.Rate, ConfigLabelThrottleBadRateError))
+		}
+	}
+
+	if spec.Coalesce != nil {
+		if w := spec.Coalesce.Window.Duration; w < time.Second || w > time.Hour {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("coalesce", "window"), w.String(), ConfigCoalesceBadWindowError))
+		}
+	}
+
+	if spec.Heartbeat != nil && spec.Heartbeat.Interval.Duration < time.Second {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("heartbeat", "interval"), spec.Heartbeat.Interval.Duration.String(), ConfigHeartbeatBadIntervalError))
+	}
+
+	return allErrs
+}
+
+// allowedAuditFile returns whether p is a clean path to a file directly in
+// one of the AuditFileDirs or in one of their subdirectories. The rotator
+// creates the subdirectories, fluent-bit does not.
+func allowedAuditFile(p string) bool {
+	if path.Clean(p) != p {
+		return false
+	}
+	for _, dir := range AuditFileDirs {
+		if path.Dir(p) == dir || path.Dir(path.Dir(p)) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedUnixSocket returns whether p is a clean path under one of the
+// UnixSocketDirs.
+func allowedUnixSocket(p string) bool {
+	if path.Clean(p) != p {
+		return false
+	}
+	for _, dir := range UnixSocketDirs {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// auditFileNamespace returns the namespace whose subdirectory the allowed
+// audit file p is in, or "" for a file directly in one of the
+// AuditFileDirs.
+func auditFileNamespace(p string) string {
+	for _, dir := range AuditFileDirs {
+		if path.Dir(p) == dir {
+			return ""
+		}
+	}
+	return path.Base(path.Dir(p))
+}
+
+// projects returns whether the sink drops every key but the ones it lists,
+// by Project or a strict Schema.
+func projects(spec *sink.SinkSpec) bool {
+	return spec.Project != nil || (spec.Schema != nil && spec.Schema.Strict)
+}
+
+// validFieldPath returns whether f is record keys joined by dots, with the
+// keys holding dots in brackets.
+func validFieldPath(f string) bool {
+	_, err := sink.ParseFieldPath(f)
+	return err == nil
+}
+
+// epochMillisFieldPattern matches the keys the epoch milliseconds of a
+// record can be set in.
+var epochMillisFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validEpochMillisField rejects keys that are not plain identifiers and
+// the keys of the message and metadata, which would be lost if replaced by
+// a number.
+func validEpochMillisField(f string) bool {
+	return epochMillisFieldPattern.MatchString(f) && f != "log" && f != "kubernetes"
+}
+
+// allowedGeoIPDatabase returns whether p is a clean path to a MaxMind
+// database in the GeoIPDatabaseDir or one of its subdirectories.
+func allowedGeoIPDatabase(p string) bool {
+	return path.Clean(p) == p &&
+		strings.HasPrefix(p, GeoIPDatabaseDir+"/") &&
+		strings.HasSuffix(p, ".mmdb")
+}
+
+// validTimezone rejects the names time.LoadLocation accepts that are not
+// IANA timezones. Local is the timezone of the fluent-bit pod.
+func validTimezone(name string) bool {
+	return name != "" && name != "Local"
+}
+
+// registeredSDIDs are the SD-IDs registered with IANA. Every other SD-ID
+// must be of the form name@<private enterprise number>.
+var registeredSDIDs = map[string]bool{
+	"timeQuality": true,
+	"origin":      true,
+	"meta":        true,
+}
+
+func validateStructuredData(sd map[string]map[string]string, fldPath *field.Path) field.ErrorList {
+	ids := make([]string, 0, len(sd))
+	for id := range sd {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var allErrs field.ErrorList
+	for _, id := range ids {
+		if !validSDID(id) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(id), id, ConfigStructuredDataBadIDError))
+		}
+
+		params := make([]string, 0, len(sd[id]))
+		for p := range sd[id] {
+			params = append(params, p)
+		}
+		sort.Strings(params)
+		for _, p := range params {
+			if !validSDName(p) {
+				allErrs = append(allErrs, field.Invalid(fldPath.Key(id).Key(p), p, ConfigStructuredDataBadParamError))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validSDID(id string) bool {
+	if !validSDName(id) {
+		return false
+	}
+
+	at := strings.Index(id, "@")
+	if at == -1 {
+		return registeredSDIDs[id]
+	}
+	name, number := id[:at], id[at+1:]
+	if name == "" || number == "" || strings.Contains(number, "@") {
+		return false
+	}
+	for _, part := range strings.Split(number, ".") {
+		if part == "" || strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }) != -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// validSDName returns whether name is an RFC5424 SD-NAME: 1 to 32 printable
+// US-ASCII characters except '=', space, ']' and '"'.
+func validSDName(name string) bool {
+	if len(name) < 1 || len(name) > 32 {
+		return false
+	}
+	for _, r := range name {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+func validateScrapeAuth(auth *sink.ScrapeAuth, fldPath *field.Path) field.ErrorList {
+	if auth == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if (auth.BearerTokenSecretRef != nil) == (auth.BasicAuth != nil) {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", ConfigScrapeAuthConflictError))
+	}
+	if auth.BearerTokenSecretRef != nil {
+		allErrs = append(allErrs, validateSecretRef(auth.BearerTokenSecretRef, fldPath.Child("bearer_token_secret_ref"), ConfigScrapeAuthBadSecretRefError)...)
+	}
+	if auth.BasicAuth != nil {
+		basicPath := fldPath.Child("basic_auth")
+		if auth.BasicAuth.Username == "" {
+			allErrs = append(allErrs, field.Invalid(basicPath.Child("username"), "", ConfigScrapeAuthBadUsernameError))
+		}
+		allErrs = append(allErrs, validateSecretRef(auth.BasicAuth.PasswordSecretRef, basicPath.Child("password_secret_ref"), ConfigScrapeAuthBadSecretRefError)...)
+	}
+
+	return allErrs
+}
+
+// validateScrapeTLS validates the secret refs of a ScrapeTLS. The CA is
+// optional, the client cert and key are set together.
+func validateScrapeTLS(tls *sink.ScrapeTLS, fldPath *field.Path) field.ErrorList {
+	if tls == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if (tls.CertSecretRef != nil) != (tls.KeySecretRef != nil) {
+		allErrs = append(allErrs, field.Invalid(fldPath, "", ConfigScrapeTLSUnpairedError))
+	}
+	if tls.CASecretRef != nil {
+		allErrs = append(allErrs, validateSecretRef(tls.CASecretRef, fldPath.Child("ca_secret_ref"), ConfigScrapeTLSBadSecretRefError)...)
+	}
+	if tls.CertSecretRef != nil {
+		allErrs = append(allErrs, validateSecretRef(tls.CertSecretRef, fldPath.Child("cert_secret_ref"), ConfigScrapeTLSBadSecretRefError)...)
+	}
+	if tls.KeySecretRef != nil {
+		allErrs = append(allErrs, validateSecretRef(tls.KeySecretRef, fldPath.Child("key_secret_ref"), ConfigScrapeTLSBadSecretRefError)...)
+	}
+
+	return allErrs
+}
+
+func validateFileRotation(rotation *sink.FileRotation, fldPath *field.Path) field.ErrorList {
+	if rotation == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if rotation.RotationCount < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("rotation_count"), rotation.RotationCount, ConfigFileRotationBadCountError))
+	}
+	if rotation.MaxSize < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("max_size"), rotation.MaxSize, ConfigFileRotationBadSizeError))
+	}
+	return allErrs
+}
+
+func validateStatsD(statsd *sink.StatsD, fldPath *field.Path) field.ErrorList {
+	if statsd == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if statsd.Port > 65535 || statsd.Port < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("port"), statsd.Port, ConfigStatsDBadPortError))
+	}
+	switch statsd.Protocol {
+	case "", "udp", "tcp":
+	default:
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("protocol"), statsd.Protocol, ConfigStatsDBadProtocolError))
+	}
+	return allErrs
+}
+
+// dataFormats are the serializers of telegraf outputs.
+var dataFormats = map[string]bool{
+	"carbon2":      true,
+	"graphite":     true,
+	"influx":       true,
+	"json":         true,
+	"prometheus":   true,
+	"splunkmetric": true,
+	"wavefront":    true,
+}
+
+func validateDataFormats(outputs []sink.MetricSinkMap, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, output := range outputs {
+		df, ok := output["data_format"]
+		if !ok {
+			continue
+		}
+		if s, ok := df.(string); !ok || !dataFormats[s] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("data_format"), df, ConfigDataFormatBadError))
+		}
+	}
+	return allErrs
+}
+
+// loadBalancedOutputs are the outputs that write each batch to one of their
+// urls, which the outputs of a load balanced sink are merged into.
+var loadBalancedOutputs = map[string]bool{
+	"influxdb":    true,
+	"influxdb_v2": true,
+}
+
+// validateLoadBalance validates that the outputs of a load balanced sink
+// can be merged into one output. They have the same type and options and
+// only their urls differ.
+func validateLoadBalance(spec *sink.MetricSinkSpec, fldPath *field.Path) field.ErrorList {
+	lb := spec.LoadBalance
+	if lb == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	weightsPath := fldPath.Child("load_balance", "weights")
+	if len(spec.Outputs) < 2 || len(lb.Weights) != len(spec.Outputs) {
+		allErrs = append(allErrs, field.Invalid(weightsPath, lb.Weights, ConfigLoadBalanceBadWeightsError))
+	}
+	for i, w := range lb.Weights {
+		if w < 1 {
+			allErrs = append(allErrs, field.Invalid(weightsPath.Index(i), w, ConfigLoadBalanceBadWeightsError))
+		}
+	}
+
+	var first sink.MetricSinkMap
+	for i, output := range spec.Outputs {
+		options := make(sink.MetricSinkMap, len(output))
+		for k, v := range output {
+			if k != "urls" {
+				options[k] = v
+			}
+		}
+		t, _ := output["type"].(string)
+		urls, _ := output["urls"].([]interface{})
+		if i == 0 {
+			first = options
+		}
+		if !loadBalancedOutputs[t] || len(urls) == 0 || !reflect.DeepEqual(options, first) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("outputs").Index(i), output["type"], ConfigLoadBalanceBadOutputsError))
+		}
+	}
+	return allErrs
+}
+
+func validateSecretRef(ref *corev1.SecretKeySelector, fldPath *field.Path, msg string) field.ErrorList {
+	if ref == nil {
+		return field.ErrorList{field.Invalid(fldPath, "", msg)}
+	}
+
+	var allErrs field.ErrorList
+	if len(validation.IsDNS1123Subdomain(ref.Name)) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), ref.Name, msg))
+	}
+	if len(validation.IsConfigMapKey(ref.Key)) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("key"), ref.Key, msg))
+	}
+	return allErrs
+}
+
+// validateServiceRef validates the service a syslog sink sends to in place
+// of a host and port.
+func validateServiceRef(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	ref := spec.ServiceRef
+	refPath := fldPath.Child("service_ref")
+	if spec.Host != "" || spec.Port != 0 || spec.Receivers != nil {
+		allErrs = append(allErrs, field.Invalid(refPath, ref.Name, ConfigServiceRefConflictError))
+	}
+	if len(validation.IsDNS1035Label(ref.Name)) > 0 {
+		allErrs = append(allErrs, field.Invalid(refPath.Child("name"), ref.Name, ConfigServiceRefBadNameError))
+	}
+	if ref.Namespace != "" && len(validation.IsDNS1123Label(ref.Namespace)) > 0 {
+		allErrs = append(allErrs, field.Invalid(refPath.Child("namespace"), ref.Namespace, ConfigServiceRefBadNameError))
+	}
+	if len(validation.IsValidPortName(ref.PortName)) > 0 {
+		allErrs = append(allErrs, field.Invalid(refPath.Child("port_name"), ref.PortName, ConfigServiceRefBadNameError))
+	}
+	return allErrs
+}
+
+// validateReceivers validates the receivers a syslog sink sends every
+// record to in place of a host and port.
+func validateReceivers(spec *sink.SinkSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	receiversPath := fldPath.Child("receivers")
+	if spec.Host != "" || spec.Port != 0 {
+		allErrs = append(allErrs, field.Invalid(receiversPath, "", ConfigReceiversConflictError))
+	}
+	if len(spec.Receivers) == 0 {
+		allErrs = append(allErrs, field.Invalid(receiversPath, "", ConfigReceiversEmptyError))
+	}
+
+	seen := make(map[sink.SyslogReceiver]bool, len(spec.Receivers))
+	for i, r := range spec.Receivers {
+		rPath := receiversPath.Index(i)
+		if r.Host == "" {
+			allErrs = append(allErrs, field.Invalid(rPath.Child("host"), r.Host, ConfigSyslogBadHostError))
+		}
+		if r.Port > 65535 || r.Port < 1 {
+			allErrs = append(allErrs, field.Invalid(rPath.Child("port"), r.Port, ConfigSyslogBadPortError))
+		}
+		if seen[r] {
+			allErrs = append(allErrs, field.Invalid(rPath, r, ConfigReceiversDuplicateError))
+		}
+		seen[r] = true
+	}
+	return allErrs
+}
+
+// headerNameRegex matches the token characters of HTTP header names.
+var headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+func validateHeadersFromSecret(hfs *sink.HeadersFromSecret, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(validation.IsDNS1123Subdomain(hfs.SecretName)) > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("secret_name"), hfs.SecretName, ConfigHeadersBadSecretError))
+	}
+	if len(hfs.Headers) == 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("headers"), hfs.Headers, ConfigHeadersBadHeaderError))
+	}
+
+	headers := make([]string, 0, len(hfs.Headers))
+	for h := range hfs.Headers {
+		headers = append(headers, h)
+	}
+	sort.Strings(headers)
+	for _, h := range headers {
+		key := hfs.Headers[h]
+		if !headerNameRegex.MatchString(h) || len(validation.IsConfigMapKey(key)) > 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("headers").Key(h), key, ConfigHeadersBadHeaderError))
+		}
+	}
+	return allErrs
+}
+
+// msgIDReference matches a record reference of a MSGID, a record key
+// followed by the keys of the maps it is nested in.
+var msgIDReference = regexp.MustCompile(`\$[A-Za-z0-9_]+(\['[^'\]]+'\])*`)
+
+// validMsgID returns whether the text of msgID around its record references
+// is an RFC5424 MSGID, 1 to 32 printable US-ASCII characters. A '$' or '['
+// that does not belong to a complete reference is rejected.
+func validMsgID(msgID string) bool {
+	var text string
+	last := 0
+	for _, m := range msgIDReference.FindAllStringIndex(msgID, -1) {
+		if m[1] < len(msgID) && msgID[m[1]] == '[' {
+			return false
+		}
+		text += msgID[last:m[0]]
+		last = m[1]
+	}
+	text += msgID[last:]
+
+	if len(text) > 32 {
+		return false
+	}
+	for _, r := range text {
+		if r < 33 || r > 126 || r == '$' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,2983 @@
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsV1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	sink "github.com/knative/observability/pkg/apis/sink/v1alpha1"
+	"github.com/knative/observability/pkg/webhook"
+)
+
+func TestValidateSink(t *testing.T) {
+	t.Run("it returns no errors for", func(t *testing.T) {
+		tests := map[string]sink.SinkSpec{
+			"syslog": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:      "example.com",
+					Port:      100,
+					EnableTLS: true,
+				},
+			},
+			"webhook": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+			},
+			"required kubernetes metadata": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				RequireKubernetesMetadata: "require",
+			},
+			"unix socket": {
+				Type: "unix_socket",
+				UnixSocketSpec: sink.UnixSocketSpec{
+					Path: "/var/run/observability/collector.sock",
+				},
+			},
+			"startup throttle": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+				StartupRate:  100,
+			},
+			"syslog with structured data": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:      "example.com",
+					Port:      100,
+					EnableTLS: true,
+					StructuredData: map[string]map[string]string{
+						"siem@32473":   {"team": "a"},
+						"ext@1.3.6.1":  {"source-type": "k8s"},
+						"timeQuality":  {"tzKnown": "1"},
+						"no-params@99": {},
+					},
+				},
+			},
+			"projected webhook": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				Project: []string{"log", "kubernetes"},
+			},
+			"raw mode webhook": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				RawMode: true,
+			},
+			"severity sampling": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				SeveritySampling: map[string]float64{
+					"error": 1,
+					"info":  0.1,
+				},
+			},
+			"timestamp timezone": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				Timestamp: &sink.TimestampSpec{Timezone: "America/New_York"},
+			},
+			"epoch millis timestamp": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				Timestamp: &sink.TimestampSpec{EpochMillisField: "time_ms"},
+			},
+			"strip key regex": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				Metadata: &sink.MetadataSpec{StripKeyRegex: "(?i)(secret|token)"},
+			},
+			"webhook keepalive": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL:                  "https://example.com/place",
+					KeepAlive:            true,
+					KeepAliveIdleTimeout: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+			"octet-counting syslog framing": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:      "example.com",
+					Port:      12345,
+					EnableTLS: true,
+					Framing:   "octet-counting",
+				},
+			},
+			"syslog hostname source": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:           "example.com",
+					Port:           12345,
+					EnableTLS:      true,
+					HostnameSource: "node",
+				},
+			},
+			"static syslog msgid": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:      "example.com",
+					Port:      12345,
+					EnableTLS: true,
+					MsgID:     "app-logs",
+				},
+			},
+			"interpolated syslog msgid": {
+				Type: "syslog",
+				SyslogSpec: sink.SyslogSpec{
+					Host:      "example.com",
+					Port:      12345,
+					EnableTLS: true,
+					MsgID:     "$kubernetes['labels']['app.kubernetes.io/name']-$stream",
+				},
+			},
+			"webhook max connections": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL:            "https://example.com/place",
+					MaxConnections: 8,
+				},
+			},
+			"negative priority": {
+				Type: "webhook",
+				WebhookSpec: sink.WebhookSpec{
+					URL: "https://example.com/place",
+				},
+				Priority: -1000,
+			},
+		}
+
+		for name, spec := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec})
+				if len(errs) != 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+
+				errs = webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec})
+				if len(errs) != 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+			})
+		}
+	})
+
+	t.Run("it returns errors for", func(t *testing.T) {
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"no type": {
+				spec: sink.SinkSpec{
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						EnableTLS: true,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "type"), "", webhook.ConfigLogNoTypeError),
+				},
+			},
+			"unknown kubernetes metadata policy": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					RequireKubernetesMetadata: "always",
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "require_kubernetes_metadata"), "always", webhook.ConfigMetadataBadPolicyError),
+				},
+			},
+			"negative webhook max connections": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL:            "https://example.com/place",
+						MaxConnections: -1,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "max_connections"), -1, webhook.ConfigMaxConnectionsBadCountError),
+				},
+			},
+			"unknown syslog framing": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						Framing:   "newline",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "framing"), "newline", webhook.ConfigSyslogBadFramingError),
+				},
+			},
+			"unknown hostname source": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:           "example.com",
+						Port:           12345,
+						EnableTLS:      true,
+						HostnameSource: "cluster",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "hostname_source"), "cluster", webhook.ConfigHostnameSourceBadError),
+				},
+			},
+			"spaced syslog msgid": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						MsgID:     "app logs",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "msg_id"), "app logs", webhook.ConfigSyslogBadMsgIDError),
+				},
+			},
+			"long syslog msgid": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						MsgID:     "a-msgid-that-is-longer-than-32-chars",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "msg_id"), "a-msgid-that-is-longer-than-32-chars", webhook.ConfigSyslogBadMsgIDError),
+				},
+			},
+			"unterminated syslog msgid reference": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						MsgID:     "$kubernetes['labels",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "msg_id"), "$kubernetes['labels", webhook.ConfigSyslogBadMsgIDError),
+				},
+			},
+			"empty syslog msgid reference": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+						MsgID:     "$-id",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "msg_id"), "$-id", webhook.ConfigSyslogBadMsgIDError),
+				},
+			},
+			"webhook msgid": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					SyslogSpec: sink.SyslogSpec{
+						MsgID: "app",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "msg_id"), "app", webhook.ConfigMsgIDSyslogError),
+				},
+			},
+			"webhook hostname source": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					SyslogSpec: sink.SyslogSpec{
+						HostnameSource: "pod",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "hostname_source"), "pod", webhook.ConfigHostnameSourceSyslogError),
+				},
+			},
+			"relative unix socket path": {
+				spec: sink.SinkSpec{
+					Type: "unix_socket",
+					UnixSocketSpec: sink.UnixSocketSpec{
+						Path: "run/collector.sock",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "path"), "run/collector.sock", webhook.ConfigUnixSocketBadPathError),
+				},
+			},
+			"unclean unix socket path": {
+				spec: sink.SinkSpec{
+					Type: "unix_socket",
+					UnixSocketSpec: sink.UnixSocketSpec{
+						Path: "/var/run/../collector.sock",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "path"), "/var/run/../collector.sock", webhook.ConfigUnixSocketBadPathError),
+				},
+			},
+			"unix socket path outside the allowed directories": {
+				spec: sink.SinkSpec{
+					Type: "unix_socket",
+					UnixSocketSpec: sink.UnixSocketSpec{
+						Path: "/var/run/docker.sock",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "path"), "/var/run/docker.sock", webhook.ConfigUnixSocketBadPathError),
+				},
+			},
+			"no unix socket path": {
+				spec: sink.SinkSpec{
+					Type: "unix_socket",
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "path"), "", webhook.ConfigUnixSocketBadPathError),
+				},
+			},
+			"unix socket raw mode": {
+				spec: sink.SinkSpec{
+					Type: "unix_socket",
+					UnixSocketSpec: sink.UnixSocketSpec{
+						Path: "/var/run/observability/collector.sock",
+					},
+					RawMode: true,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "raw_mode"), true, webhook.ConfigRawModeSyslogError),
+				},
+			},
+			"high port": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      100000,
+						EnableTLS: true,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "port"), 100000, webhook.ConfigSyslogBadPortError),
+				},
+			},
+			"no port": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						EnableTLS: true,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "port"), 0, webhook.ConfigSyslogBadPortError),
+				},
+			},
+			"no host": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Port:      1,
+						EnableTLS: true,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "host"), "", webhook.ConfigSyslogBadHostError),
+				},
+			},
+			"insecure syslog": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host: "example.com",
+						Port: 5678,
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "enable_tls"), false, webhook.ConfigSyslogInsecureError),
+				},
+			},
+			"every syslog error": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "enable_tls"), false, webhook.ConfigSyslogInsecureError),
+					field.Invalid(field.NewPath("spec", "host"), "", webhook.ConfigSyslogBadHostError),
+					field.Invalid(field.NewPath("spec", "port"), 0, webhook.ConfigSyslogBadPortError),
+				},
+			},
+			"raw mode syslog": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      5678,
+						EnableTLS: true,
+					},
+					RawMode: true,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "raw_mode"), true, webhook.ConfigRawModeSyslogError),
+				},
+			},
+			"projected syslog": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      5678,
+						EnableTLS: true,
+					},
+					Project: []string{"log"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "project"), []string{"log"}, webhook.ConfigProjectSyslogError),
+				},
+			},
+			"structured data with malformed SD-IDs": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      5678,
+						EnableTLS: true,
+						StructuredData: map[string]map[string]string{
+							"unregistered":                         {},
+							"siem@":                                {},
+							"@32473":                               {},
+							"siem@abc":                             {},
+							"has space@1":                          {},
+							"siem@1@2":                             {},
+							"way-too-long-sd-id-for-the-rfc@32473": {},
+						},
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "structured_data").Key("@32473"), "@32473", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("has space@1"), "has space@1", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@"), "siem@", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@1@2"), "siem@1@2", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@abc"), "siem@abc", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("unregistered"), "unregistered", webhook.ConfigStructuredDataBadIDError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("way-too-long-sd-id-for-the-rfc@32473"), "way-too-long-sd-id-for-the-rfc@32473", webhook.ConfigStructuredDataBadIDError),
+				},
+			},
+			"structured data with malformed param names": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      5678,
+						EnableTLS: true,
+						StructuredData: map[string]map[string]string{
+							"siem@32473": {"": "a", "a=b": "c", "ok": "d", "quo\"te": "e"},
+						},
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@32473").Key(""), "", webhook.ConfigStructuredDataBadParamError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@32473").Key("a=b"), "a=b", webhook.ConfigStructuredDataBadParamError),
+					field.Invalid(field.NewPath("spec", "structured_data").Key("siem@32473").Key("quo\"te"), "quo\"te", webhook.ConfigStructuredDataBadParamError),
+				},
+			},
+			"projection without keys": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Project: []string{},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "project"), []string{}, webhook.ConfigProjectNoKeysError),
+				},
+			},
+			"projection of invalid keys": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Project: []string{"log", "", "two words"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "project").Index(1), "", webhook.ConfigProjectBadKeyError),
+					field.Invalid(field.NewPath("spec", "project").Index(2), "two words", webhook.ConfigProjectBadKeyError),
+				},
+			},
+			"no url": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "url"), "", webhook.ConfigWebhookBadURLError),
+				},
+			},
+			"insecure webhook": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "http://webhook.com",
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "url"), "http://webhook.com", webhook.ConfigWebhookInsecureError),
+				},
+			},
+			"startup delay without rate": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "startup_rate"), 0, webhook.ConfigStartupIncompleteError),
+				},
+			},
+			"short startup delay": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					StartupDelay: &metav1.Duration{Duration: 500 * time.Millisecond},
+					StartupRate:  100,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "startup_delay"), "500ms", webhook.ConfigStartupBadDelayError),
+				},
+			},
+			"negative startup rate": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					StartupDelay: &metav1.Duration{Duration: 30 * time.Second},
+					StartupRate:  -1,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "startup_rate"), -1, webhook.ConfigStartupBadRateError),
+				},
+			},
+			"zero sampling rate": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					SeveritySampling: map[string]float64{"info": 0},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "severity_sampling").Key("info"), 0.0, webhook.ConfigSamplingBadRateError),
+				},
+			},
+			"sampling rate above one": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					SeveritySampling: map[string]float64{"info": 1.5},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "severity_sampling").Key("info"), 1.5, webhook.ConfigSamplingBadRateError),
+				},
+			},
+			"unknown sampling severity": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					SeveritySampling: map[string]float64{"verbose": 0.5},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "severity_sampling").Key("verbose"), "verbose", webhook.ConfigSamplingBadSeverityError),
+				},
+			},
+			"unknown timezone": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Timestamp: &sink.TimestampSpec{Timezone: "Mars/Olympus_Mons"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "timestamp", "timezone"), "Mars/Olympus_Mons", webhook.ConfigTimestampBadTimezoneError),
+				},
+			},
+			"invalid strip key regex": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Metadata: &sink.MetadataSpec{StripKeyRegex: "secret("},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "metadata", "strip_key_regex"), "secret(", webhook.ConfigMetadataBadRegexError),
+				},
+			},
+			"keepalive idle timeout without keepalive": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL:                  "https://example.com/place",
+						KeepAliveIdleTimeout: &metav1.Duration{Duration: time.Minute},
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "keep_alive"), false, webhook.ConfigKeepAliveIncompleteError),
+				},
+			},
+			"keepalive idle timeout below 1s": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL:                  "https://example.com/place",
+						KeepAlive:            true,
+						KeepAliveIdleTimeout: &metav1.Duration{Duration: 500 * time.Millisecond},
+					},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "keep_alive_idle_timeout"), "500ms", webhook.ConfigKeepAliveBadTimeoutError),
+				},
+			},
+			"priority below the range": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Priority: -1001,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "priority"), -1001, webhook.ConfigPriorityBadRangeError),
+				},
+			},
+			"priority above the range": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Priority: 1001,
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "priority"), 1001, webhook.ConfigPriorityBadRangeError),
+				},
+			},
+			"empty timezone": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Timestamp: &sink.TimestampSpec{},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "timestamp", "timezone"), "", webhook.ConfigTimestampBadTimezoneError),
+				},
+			},
+			"dotted epoch millis field": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Timestamp: &sink.TimestampSpec{Timezone: "UTC", EpochMillisField: "time.ms"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "timestamp", "epoch_millis_field"), "time.ms", webhook.ConfigTimestampBadMillisError),
+				},
+			},
+			"epoch millis field replacing the message": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Timestamp: &sink.TimestampSpec{Timezone: "UTC", EpochMillisField: "log"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "timestamp", "epoch_millis_field"), "log", webhook.ConfigTimestampBadMillisError),
+				},
+			},
+			"epoch millis field replacing the metadata": {
+				spec: sink.SinkSpec{
+					Type: "webhook",
+					WebhookSpec: sink.WebhookSpec{
+						URL: "https://example.com/place",
+					},
+					Timestamp: &sink.TimestampSpec{Timezone: "UTC", EpochMillisField: "kubernetes"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "timestamp", "epoch_millis_field"), "kubernetes", webhook.ConfigTimestampBadMillisError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+
+				errs = webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it does not allow changing sink type", func(t *testing.T) {
+		old := sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				Host:      "example.com",
+				Port:      100,
+				EnableTLS: true,
+			},
+		}
+		spec := sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+		}
+		expected := field.ErrorList{
+			field.Invalid(field.NewPath("spec", "type"), "webhook", webhook.ConfigLogChangeTypeError),
+		}
+
+		errs := webhook.ValidateLogSinkUpdate(
+			&sink.LogSink{Spec: spec},
+			&sink.LogSink{Spec: old},
+		)
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		errs = webhook.ValidateClusterLogSinkUpdate(
+			&sink.ClusterLogSink{Spec: spec},
+			&sink.ClusterLogSink{Spec: old},
+		)
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestValidateScrapeAuth(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows", func(t *testing.T) {
+		requireTelegraf(t)
+		tests := map[string]string{
+			"a bearer token": `{
+				"bearer_token_secret_ref": {"name": "scrape-secret", "key": "token"}
+			}`,
+			"basic auth": `{
+				"basic_auth": {
+					"username": "some-user",
+					"password_secret_ref": {"name": "scrape-secret", "key": "password"}
+				}
+			}`,
+		}
+
+		for name, auth := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					metricAdmissionTemplate,
+					scrapeAuthSpec(auth),
+				))
+				if !resp.Response.Allowed {
+					t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			auth     string
+			message  string
+		}{
+			{
+				"auth on a ClusterMetricSink",
+				clusterMetricAdmissionTemplate,
+				`{"bearer_token_secret_ref": {"name": "scrape-secret", "key": "token"}}`,
+				webhook.ConfigScrapeAuthClusterError,
+			},
+			{
+				"no auth method",
+				metricAdmissionTemplate,
+				`{}`,
+				webhook.ConfigScrapeAuthConflictError,
+			},
+			{
+				"both auth methods",
+				metricAdmissionTemplate,
+				`{
+					"bearer_token_secret_ref": {"name": "scrape-secret", "key": "token"},
+					"basic_auth": {
+						"username": "some-user",
+						"password_secret_ref": {"name": "scrape-secret", "key": "password"}
+					}
+				}`,
+				webhook.ConfigScrapeAuthConflictError,
+			},
+			{
+				"a secret ref without a key",
+				metricAdmissionTemplate,
+				`{"bearer_token_secret_ref": {"name": "scrape-secret"}}`,
+				webhook.ConfigScrapeAuthBadSecretRefError,
+			},
+			{
+				"a secret ref with an invalid name",
+				metricAdmissionTemplate,
+				`{"bearer_token_secret_ref": {"name": "Scrape_Secret", "key": "token"}}`,
+				webhook.ConfigScrapeAuthBadSecretRefError,
+			},
+			{
+				"basic auth without a username",
+				metricAdmissionTemplate,
+				`{
+					"basic_auth": {
+						"password_secret_ref": {"name": "scrape-secret", "key": "password"}
+					}
+				}`,
+				webhook.ConfigScrapeAuthBadUsernameError,
+			},
+			{
+				"basic auth without a password",
+				metricAdmissionTemplate,
+				`{"basic_auth": {"username": "some-user"}}`,
+				webhook.ConfigScrapeAuthBadSecretRefError,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					test.template,
+					scrapeAuthSpec(test.auth),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != test.message {
+					t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func scrapeAuthSpec(auth string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": "discard"
+		} ],
+		"scrape_auth": %s
+	}`, auth)
+}
+
+func TestValidateFileRotation(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows positive values", func(t *testing.T) {
+		requireTelegraf(t)
+		resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+			metricAdmissionTemplate,
+			fileRotationSpec(`{"rotation_count": 3, "max_size": 10485760}`),
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			rotation string
+			message  string
+		}{
+			"a missing rotation count": {
+				`{"max_size": 10485760}`,
+				webhook.ConfigFileRotationBadCountError,
+			},
+			"a negative rotation count": {
+				`{"rotation_count": -1, "max_size": 10485760}`,
+				webhook.ConfigFileRotationBadCountError,
+			},
+			"a missing max size": {
+				`{"rotation_count": 3}`,
+				webhook.ConfigFileRotationBadSizeError,
+			},
+			"a negative max size": {
+				`{"rotation_count": 3, "max_size": -1}`,
+				webhook.ConfigFileRotationBadSizeError,
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					clusterMetricAdmissionTemplate,
+					fileRotationSpec(test.rotation),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != test.message {
+					t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func fileRotationSpec(rotation string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": "file",
+			"files": ["/tmp/metrics"]
+		} ],
+		"file_rotation": %s
+	}`, rotation)
+}
+
+func TestValidateStatsD(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows a statsd input", func(t *testing.T) {
+		requireTelegraf(t)
+		resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+			metricAdmissionTemplate,
+			statsdSpec(`{"port": 8125, "protocol": "tcp", "templates": ["measurement.field"]}`),
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			statsd   string
+			message  string
+		}{
+			{
+				"statsd on a ClusterMetricSink",
+				clusterMetricAdmissionTemplate,
+				`{"port": 8125}`,
+				webhook.ConfigStatsDClusterError,
+			},
+			{
+				"a missing port",
+				metricAdmissionTemplate,
+				`{}`,
+				webhook.ConfigStatsDBadPortError,
+			},
+			{
+				"a port out of range",
+				metricAdmissionTemplate,
+				`{"port": 65536}`,
+				webhook.ConfigStatsDBadPortError,
+			},
+			{
+				"an unknown protocol",
+				metricAdmissionTemplate,
+				`{"port": 8125, "protocol": "sctp"}`,
+				webhook.ConfigStatsDBadProtocolError,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					test.template,
+					statsdSpec(test.statsd),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != test.message {
+					t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func statsdSpec(statsd string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": "discard"
+		} ],
+		"statsd": %s
+	}`, statsd)
+}
+
+func TestValidateKubeletMetrics(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	const spec = `{
+		"outputs": [ {
+			"type": "discard"
+		} ],
+		"kubelet_metrics": true
+	}`
+
+	t.Run("it allows kubelet metrics on a ClusterMetricSink", func(t *testing.T) {
+		requireTelegraf(t)
+		resp := postReview(t, server, "/metricsink", fmt.Sprintf(clusterMetricAdmissionTemplate, spec))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it rejects kubelet metrics on a MetricSink", func(t *testing.T) {
+		resp := postReview(t, server, "/metricsink", fmt.Sprintf(metricAdmissionTemplate, spec))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigKubeletMetricsError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigKubeletMetricsError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestValidateDataFormat(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	for _, format := range []string{"influx", "json", "prometheus"} {
+		t.Run("it allows "+format, func(t *testing.T) {
+			requireTelegraf(t)
+			resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+				metricAdmissionTemplate,
+				dataFormatSpec(`"`+format+`"`),
+			))
+			if !resp.Response.Allowed {
+				t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+			}
+		})
+	}
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]string{
+			"an unknown format":   `"xml"`,
+			"a non string format": `1`,
+		}
+
+		for name, format := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					clusterMetricAdmissionTemplate,
+					dataFormatSpec(format),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != webhook.ConfigDataFormatBadError {
+					t.Errorf("expected message %q, got %q", webhook.ConfigDataFormatBadError, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func dataFormatSpec(format string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": "file",
+			"files": ["stdout"],
+			"data_format": %s
+		} ]
+	}`, format)
+}
+
+func TestValidateLoadBalance(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows weighted influxdb outputs", func(t *testing.T) {
+		requireTelegraf(t)
+		resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+			metricAdmissionTemplate,
+			loadBalanceSpec(`"influxdb"`, `"influxdb"`, `[3, 1]`),
+		))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			spec     string
+			expected string
+		}{
+			"a weight of 0": {
+				spec:     loadBalanceSpec(`"influxdb"`, `"influxdb"`, `[1, 0]`),
+				expected: webhook.ConfigLoadBalanceBadWeightsError,
+			},
+			"a negative weight": {
+				spec:     loadBalanceSpec(`"influxdb"`, `"influxdb"`, `[-1, 2]`),
+				expected: webhook.ConfigLoadBalanceBadWeightsError,
+			},
+			"a weight for each output": {
+				spec:     loadBalanceSpec(`"influxdb"`, `"influxdb"`, `[1]`),
+				expected: webhook.ConfigLoadBalanceBadWeightsError,
+			},
+			"outputs without urls": {
+				spec:     loadBalanceSpec(`"datadog"`, `"datadog"`, `[1, 1]`),
+				expected: webhook.ConfigLoadBalanceBadOutputsError,
+			},
+			"outputs of different types": {
+				spec:     loadBalanceSpec(`"influxdb"`, `"influxdb_v2"`, `[1, 1]`),
+				expected: webhook.ConfigLoadBalanceBadOutputsError,
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					clusterMetricAdmissionTemplate,
+					test.spec,
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != test.expected {
+					t.Errorf("expected message %q, got %q", test.expected, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func loadBalanceSpec(firstType, secondType, weights string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": %s,
+			"urls": ["http://tsdb-0:8086"]
+		}, {
+			"type": %s,
+			"urls": ["http://tsdb-1:8086"]
+		} ],
+		"load_balance": {"weights": %s}
+	}`, firstType, secondType, weights)
+}
+
+func TestValidateNamespaceGlobs(t *testing.T) {
+	spec := func(globs ...string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			NamespaceGlobs: globs,
+		}
+	}
+
+	t.Run("it allows globs on cluster sinks", func(t *testing.T) {
+		errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{
+			Spec: spec("team-*-prod", "ops", "team-?", "[a-c]-prod"),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects invalid globs", func(t *testing.T) {
+		errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{
+			Spec: spec("team-*", "team-[", "", `team-\`),
+		})
+		expected := field.ErrorList{
+			field.Invalid(field.NewPath("spec", "namespace_globs").Index(1), "team-[", webhook.ConfigGlobBadPatternError),
+			field.Invalid(field.NewPath("spec", "namespace_globs").Index(2), "", webhook.ConfigGlobBadPatternError),
+			field.Invalid(field.NewPath("spec", "namespace_globs").Index(3), `team-\`, webhook.ConfigGlobBadPatternError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+	})
+
+	t.Run("it rejects globs on namespaced sinks", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec("team-*")})
+		expected := field.ErrorList{
+			field.Invalid(field.NewPath("spec", "namespace_globs"), []string{"team-*"}, webhook.ConfigGlobsClusterOnlyError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			`{"type": "webhook", "url": "https://example.com", "namespace_globs": ["team-*"]}`,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigGlobsClusterOnlyError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigGlobsClusterOnlyError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestValidateAuditFile(t *testing.T) {
+	spec := func(audit *sink.AuditFileSpec) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			AuditFile: audit,
+		}
+	}
+
+	t.Run("it allows files in an allowed directory", func(t *testing.T) {
+		errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(&sink.AuditFileSpec{
+			Path: "/var/log/observability/some-sink.log",
+			Rotation: &sink.FileRotation{
+				RotationCount: 3,
+				MaxSize:       1024,
+			},
+		})})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it allows LogSink files in the subdirectory of their namespace", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team"},
+			Spec: spec(&sink.AuditFileSpec{
+				Path: "/var/log/observability/team/some-sink.log",
+			}),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects LogSink files outside the subdirectory of their namespace", func(t *testing.T) {
+		for _, p := range []string{
+			"/var/log/observability/some-sink.log",
+			"/var/log/observability/other-team/some-sink.log",
+		} {
+			errs := webhook.ValidateLogSink(&sink.LogSink{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team"},
+				Spec:       spec(&sink.AuditFileSpec{Path: p}),
+			})
+			expected := field.ErrorList{
+				field.Invalid(field.NewPath("spec", "audit_file", "path"), p, webhook.ConfigAuditFileNamespaceError),
+			}
+			if diff := cmp.Diff(expected, errs); diff != "" {
+				t.Errorf("Errors not equal for %s (-want, +got) = %v", p, diff)
+			}
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			audit    *sink.AuditFileSpec
+			expected field.ErrorList
+		}{
+			"a missing path": {
+				audit: &sink.AuditFileSpec{},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "path"), "", webhook.ConfigAuditFileBadPathError),
+				},
+			},
+			"a path outside the allowed directories": {
+				audit: &sink.AuditFileSpec{Path: "/etc/passwd"},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "path"), "/etc/passwd", webhook.ConfigAuditFileBadPathError),
+				},
+			},
+			"a path escaping the allowed directories": {
+				audit: &sink.AuditFileSpec{Path: "/var/log/observability/../passwd"},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "path"), "/var/log/observability/../passwd", webhook.ConfigAuditFileBadPathError),
+				},
+			},
+			"a path in a namespace's subdirectory": {
+				audit: &sink.AuditFileSpec{Path: "/var/log/observability/team/some-sink.log"},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "path"), "/var/log/observability/team/some-sink.log", webhook.ConfigAuditFileNamespaceError),
+				},
+			},
+			"a path in a nested subdirectory": {
+				audit: &sink.AuditFileSpec{Path: "/var/log/observability/team/nested/some-sink.log"},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "path"), "/var/log/observability/team/nested/some-sink.log", webhook.ConfigAuditFileBadPathError),
+				},
+			},
+			"an invalid rotation": {
+				audit: &sink.AuditFileSpec{
+					Path:     "/var/log/observability/some-sink.log",
+					Rotation: &sink.FileRotation{MaxSize: 1024},
+				},
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "audit_file", "rotation", "rotation_count"), 0, webhook.ConfigFileRotationBadCountError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(test.audit)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateAuditLog(t *testing.T) {
+	webhookSpec := func(audit *sink.AuditLogSpec) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/audit",
+			},
+			AuditLog: audit,
+		}
+	}
+
+	t.Run("it allows", func(t *testing.T) {
+		tests := map[string]*sink.AuditLogSpec{
+			"defaults": {},
+			"json parser": {
+				Path:   "/var/log/kube-apiserver/audit.log",
+				Parser: "json",
+			},
+			"node selector": {
+				NodeSelector: map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			},
+		}
+
+		for name, audit := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: webhookSpec(audit)})
+				if len(errs) != 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		auditPath := field.NewPath("spec", "audit_log")
+		syslog := sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				Host:      "example.com",
+				Port:      100,
+				EnableTLS: true,
+			},
+			AuditLog: &sink.AuditLogSpec{},
+		}
+		raw := webhookSpec(&sink.AuditLogSpec{})
+		raw.RawMode = true
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"an unknown parser": {
+				spec: webhookSpec(&sink.AuditLogSpec{Parser: "docker"}),
+				expected: field.ErrorList{
+					field.Invalid(auditPath.Child("parser"), "docker", webhook.ConfigAuditLogBadParserError),
+				},
+			},
+			"a relative path": {
+				spec: webhookSpec(&sink.AuditLogSpec{Path: "audit.log"}),
+				expected: field.ErrorList{
+					field.Invalid(auditPath.Child("path"), "audit.log", webhook.ConfigAuditLogBadPathError),
+				},
+			},
+			"an invalid node selector": {
+				spec: webhookSpec(&sink.AuditLogSpec{NodeSelector: map[string]string{"bad key": "v"}}),
+				expected: field.ErrorList{
+					field.Invalid(auditPath.Child("node_selector").Key("bad key"), "v", webhook.ConfigAuditLogBadSelectorError),
+				},
+			},
+			"syslog sinks": {
+				spec: syslog,
+				expected: field.ErrorList{
+					field.Invalid(auditPath, syslog.AuditLog, webhook.ConfigAuditLogSyslogError),
+				},
+			},
+			"raw mode": {
+				spec: raw,
+				expected: field.ErrorList{
+					field.Invalid(auditPath, raw.AuditLog, webhook.ConfigAuditLogConflictError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects audit logs on namespaced sinks", func(t *testing.T) {
+		spec := webhookSpec(&sink.AuditLogSpec{})
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec})
+		expected := field.ErrorList{
+			field.Invalid(field.NewPath("spec", "audit_log"), spec.AuditLog, webhook.ConfigAuditLogClusterOnlyError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			`{"type": "webhook", "url": "https://example.com", "audit_log": {}}`,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigAuditLogClusterOnlyError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigAuditLogClusterOnlyError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestValidateReceivers(t *testing.T) {
+	spec := func(receivers ...sink.SyslogReceiver) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				EnableTLS: true,
+				Receivers: receivers,
+			},
+		}
+	}
+
+	t.Run("it allows receivers in place of a host and port", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(
+			sink.SyslogReceiver{Host: "primary.example.com", Port: 6514},
+			sink.SyslogReceiver{Host: "secondary.example.com", Port: 6514},
+		)})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		receiversPath := field.NewPath("spec", "receivers")
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"an empty list": {
+				spec: func() sink.SinkSpec {
+					s := spec()
+					s.Receivers = []sink.SyslogReceiver{}
+					return s
+				}(),
+				expected: field.ErrorList{
+					field.Invalid(receiversPath, "", webhook.ConfigReceiversEmptyError),
+				},
+			},
+			"receivers combined with a host": {
+				spec: func() sink.SinkSpec {
+					s := spec(sink.SyslogReceiver{Host: "primary.example.com", Port: 6514})
+					s.Host = "example.com"
+					return s
+				}(),
+				expected: field.ErrorList{
+					field.Invalid(receiversPath, "", webhook.ConfigReceiversConflictError),
+				},
+			},
+			"receivers combined with a service ref": {
+				spec: func() sink.SinkSpec {
+					s := spec(sink.SyslogReceiver{Host: "primary.example.com", Port: 6514})
+					s.ServiceRef = &sink.ServiceRef{Name: "collector", Namespace: "ns1", PortName: "syslog"}
+					return s
+				}(),
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "service_ref"), "collector", webhook.ConfigServiceRefConflictError),
+				},
+			},
+			"a receiver without a host": {
+				spec: spec(sink.SyslogReceiver{Port: 6514}),
+				expected: field.ErrorList{
+					field.Invalid(receiversPath.Index(0).Child("host"), "", webhook.ConfigSyslogBadHostError),
+				},
+			},
+			"a receiver with an invalid port": {
+				spec: spec(sink.SyslogReceiver{Host: "primary.example.com", Port: 70000}),
+				expected: field.ErrorList{
+					field.Invalid(receiversPath.Index(0).Child("port"), 70000, webhook.ConfigSyslogBadPortError),
+				},
+			},
+			"the same receiver twice": {
+				spec: spec(
+					sink.SyslogReceiver{Host: "primary.example.com", Port: 6514},
+					sink.SyslogReceiver{Host: "primary.example.com", Port: 6514},
+				),
+				expected: field.ErrorList{
+					field.Invalid(
+						receiversPath.Index(1),
+						sink.SyslogReceiver{Host: "primary.example.com", Port: 6514},
+						webhook.ConfigReceiversDuplicateError,
+					),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateServiceRef(t *testing.T) {
+	spec := func(ref *sink.ServiceRef) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				EnableTLS:  true,
+				ServiceRef: ref,
+			},
+		}
+	}
+
+	t.Run("it allows a ref in place of a host and port", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(&sink.ServiceRef{
+			Name:     "syslog-collector",
+			PortName: "syslog-tls",
+		})})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		refPath := field.NewPath("spec", "service_ref")
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"a ref combined with a host": {
+				spec: func() sink.SinkSpec {
+					s := spec(&sink.ServiceRef{Name: "collector", PortName: "syslog"})
+					s.Host = "example.com"
+					return s
+				}(),
+				expected: field.ErrorList{
+					field.Invalid(refPath, "collector", webhook.ConfigServiceRefConflictError),
+				},
+			},
+			"a ref combined with a port": {
+				spec: func() sink.SinkSpec {
+					s := spec(&sink.ServiceRef{Name: "collector", PortName: "syslog"})
+					s.Port = 514
+					return s
+				}(),
+				expected: field.ErrorList{
+					field.Invalid(refPath, "collector", webhook.ConfigServiceRefConflictError),
+				},
+			},
+			"an invalid name": {
+				spec: spec(&sink.ServiceRef{Name: "Collector.example", PortName: "syslog"}),
+				expected: field.ErrorList{
+					field.Invalid(refPath.Child("name"), "Collector.example", webhook.ConfigServiceRefBadNameError),
+				},
+			},
+			"an invalid namespace": {
+				spec: spec(&sink.ServiceRef{Name: "collector", Namespace: "Team_A", PortName: "syslog"}),
+				expected: field.ErrorList{
+					field.Invalid(refPath.Child("namespace"), "Team_A", webhook.ConfigServiceRefBadNameError),
+				},
+			},
+			"a missing port name": {
+				spec: spec(&sink.ServiceRef{Name: "collector"}),
+				expected: field.ErrorList{
+					field.Invalid(refPath.Child("port_name"), "", webhook.ConfigServiceRefBadNameError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateRetry(t *testing.T) {
+	spec := func(size string) sink.SinkSpec {
+		q := resource.MustParse(size)
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			Retry: &sink.RetrySpec{MaxBufferSize: &q},
+		}
+	}
+
+	t.Run("it allows positive buffer sizes", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec("512Mi")})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		for _, size := range []string{"0", "-1Gi"} {
+			t.Run(size, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(size)})
+				expected := field.ErrorList{
+					field.Invalid(field.NewPath("spec", "retry", "max_buffer_size"), size, webhook.ConfigRetryBadBufferSizeError),
+				}
+				if diff := cmp.Diff(expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateAnnotationRouting(t *testing.T) {
+	routingPath := field.NewPath("spec", "annotation_routing")
+	spec := func(routing *sink.AnnotationRoutingSpec) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			AnnotationRouting: routing,
+		}
+	}
+	routes := func(routes map[string]string) *sink.AnnotationRoutingSpec {
+		return &sink.AnnotationRoutingSpec{
+			Annotation: "example.com/team",
+			Routes:     routes,
+		}
+	}
+
+	t.Run("it allows routes to https URLs", func(t *testing.T) {
+		errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{
+			Spec: spec(routes(map[string]string{"payments": "https://payments.example.com"})),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		syslog := sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				Host:      "example.com",
+				Port:      100,
+				EnableTLS: true,
+			},
+			AnnotationRouting: routes(map[string]string{"a": "https://a.example.com"}),
+		}
+		raw := spec(routes(map[string]string{"a": "https://a.example.com"}))
+		raw.RawMode = true
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"syslog sinks": {
+				spec: syslog,
+				expected: field.ErrorList{
+					field.Invalid(routingPath, syslog.AnnotationRouting, webhook.ConfigRoutingSyslogError),
+				},
+			},
+			"raw mode": {
+				spec: raw,
+				expected: field.ErrorList{
+					field.Invalid(routingPath, raw.AnnotationRouting, webhook.ConfigRoutingConflictError),
+				},
+			},
+			"an invalid annotation": {
+				spec: spec(&sink.AnnotationRoutingSpec{
+					Annotation: "bad key",
+					Routes:     map[string]string{"a": "https://a.example.com"},
+				}),
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("annotation"), "bad key", webhook.ConfigRoutingBadAnnotationError),
+				},
+			},
+			"no routes": {
+				spec: spec(routes(nil)),
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("routes"), map[string]string(nil), webhook.ConfigRoutingBadRouteError),
+				},
+			},
+			"bad routes": {
+				spec: spec(routes(map[string]string{
+					"":      "https://empty.example.com",
+					"a b":   "https://ab.example.com",
+					"plain": "http://plain.example.com",
+				})),
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("routes").Key(""), "https://empty.example.com", webhook.ConfigRoutingBadRouteError),
+					field.Invalid(routingPath.Child("routes").Key("a b"), "https://ab.example.com", webhook.ConfigRoutingBadRouteError),
+					field.Invalid(routingPath.Child("routes").Key("plain"), "http://plain.example.com", webhook.ConfigRoutingBadRouteError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects routing on namespaced sinks", func(t *testing.T) {
+		s := spec(routes(map[string]string{"a": "https://a.example.com"}))
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: s})
+		expected := field.ErrorList{
+			field.Invalid(routingPath, s.AnnotationRouting, webhook.ConfigRoutingClusterOnlyError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			`{"type": "webhook", "url": "https://example.com", "annotation_routing": {"annotation": "team", "routes": {"a": "https://a.example.com"}}}`,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigRoutingClusterOnlyError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigRoutingClusterOnlyError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestValidateStatusCodeRouting(t *testing.T) {
+	routingPath := field.NewPath("spec", "status_code_routing")
+	spec := func(routes map[string]string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			StatusCodeRouting: &sink.StatusCodeRoutingSpec{
+				Field:  "status",
+				Routes: routes,
+			},
+		}
+	}
+
+	t.Run("it allows disjoint ranges", func(t *testing.T) {
+		errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{
+			Spec: spec(map[string]string{
+				"404":     "https://missing.example.com",
+				"400-403": "https://client.example.com",
+				"500-599": "https://errors.example.com",
+			}),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		annotated := spec(map[string]string{"500-599": "https://errors.example.com"})
+		annotated.AnnotationRouting = &sink.AnnotationRoutingSpec{
+			Annotation: "team",
+			Routes:     map[string]string{"a": "https://a.example.com"},
+		}
+		badField := spec(map[string]string{"500-599": "https://errors.example.com"})
+		badField.StatusCodeRouting.Field = "$status"
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"annotation routing": {
+				spec: annotated,
+				expected: field.ErrorList{
+					field.Invalid(routingPath, annotated.StatusCodeRouting, webhook.ConfigCodeRoutingConflictError),
+				},
+			},
+			"an invalid field": {
+				spec: badField,
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("field"), "$status", webhook.ConfigCodeRoutingBadFieldError),
+				},
+			},
+			"bad ranges": {
+				spec: spec(map[string]string{
+					"5xx":     "https://errors.example.com",
+					"599-500": "https://reversed.example.com",
+					"404":     "http://missing.example.com",
+				}),
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("routes").Key("404"), "http://missing.example.com", webhook.ConfigCodeRoutingBadRouteError),
+					field.Invalid(routingPath.Child("routes").Key("599-500"), "https://reversed.example.com", webhook.ConfigCodeRoutingBadRouteError),
+					field.Invalid(routingPath.Child("routes").Key("5xx"), "https://errors.example.com", webhook.ConfigCodeRoutingBadRouteError),
+				},
+			},
+			"overlapping ranges": {
+				spec: spec(map[string]string{
+					"400-599": "https://errors.example.com",
+					"404":     "https://missing.example.com",
+					"500-503": "https://unavailable.example.com",
+					"600":     "https://other.example.com",
+				}),
+				expected: field.ErrorList{
+					field.Invalid(routingPath.Child("routes").Key("404"), "404", webhook.ConfigCodeRoutingOverlapError),
+					field.Invalid(routingPath.Child("routes").Key("500-503"), "500-503", webhook.ConfigCodeRoutingOverlapError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects routing on namespaced sinks", func(t *testing.T) {
+		s := spec(map[string]string{"500-599": "https://errors.example.com"})
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: s})
+		expected := field.ErrorList{
+			field.Invalid(routingPath, s.StatusCodeRouting, webhook.ConfigCodeRoutingClusterOnlyError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+	})
+}
+
+func TestValidateMetrics(t *testing.T) {
+	spec := `{"type": "webhook", "url": "https://example.com", "metrics": {"inputs": [], "outputs": [{"type": "datadog", "apikey": "some-key"}]}}`
+
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows metrics on LogSinks", func(t *testing.T) {
+		requireTelegraf(t)
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(namespacedAdmissionTemplate, "LogSink", "team-a", spec))
+		if !resp.Response.Allowed {
+			t.Errorf("expected response to be allowed, got %v", resp.Response.Result)
+		}
+	})
+
+	t.Run("it validates the metrics like a MetricSink", func(t *testing.T) {
+		tests := map[string]struct {
+			metrics  string
+			expected string
+		}{
+			"an input without a type": {
+				`{"inputs": [{"urls": ["http://localhost:9100"]}], "outputs": [{"type": "discard"}]}`,
+				webhook.ConfigMetricNoTypeError,
+			},
+			"a kubernetes input": {
+				`{"inputs": [{"type": "kubernetes"}], "outputs": [{"type": "discard"}]}`,
+				webhook.ConfigIncludesKubernetesError,
+			},
+			"kubelet metrics": {
+				`{"outputs": [{"type": "discard"}], "kubelet_metrics": true}`,
+				webhook.ConfigKubeletMetricsError,
+			},
+		}
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				spec := fmt.Sprintf(`{"type": "webhook", "url": "https://example.com", "metrics": %s}`, tc.metrics)
+				resp := postReview(t, server, "/logsink", fmt.Sprintf(namespacedAdmissionTemplate, "LogSink", "team-a", spec))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != tc.expected {
+					t.Errorf("expected message %q, got %q", tc.expected, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects metrics on ClusterLogSinks", func(t *testing.T) {
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(namespacedAdmissionTemplate, "ClusterLogSink", "", spec))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigMetricsLogSinkOnlyError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigMetricsLogSinkOnlyError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestValidateHeadersFromSecret(t *testing.T) {
+	headersPath := field.NewPath("spec", "headers_from_secret")
+	spec := func(hfs *sink.HeadersFromSecret) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL:               "https://example.com/place",
+				HeadersFromSecret: hfs,
+			},
+		}
+	}
+
+	t.Run("it allows headers from a secret", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(&sink.HeadersFromSecret{
+			SecretName: "auth",
+			Headers:    map[string]string{"Authorization": "token", "X-Api-Key": "api.key"},
+		})})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			hfs      *sink.HeadersFromSecret
+			expected field.ErrorList
+		}{
+			"an invalid secret name": {
+				hfs: &sink.HeadersFromSecret{
+					SecretName: "Auth_Secret",
+					Headers:    map[string]string{"Authorization": "token"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(headersPath.Child("secret_name"), "Auth_Secret", webhook.ConfigHeadersBadSecretError),
+				},
+			},
+			"no headers": {
+				hfs: &sink.HeadersFromSecret{SecretName: "auth"},
+				expected: field.ErrorList{
+					field.Invalid(headersPath.Child("headers"), map[string]string(nil), webhook.ConfigHeadersBadHeaderError),
+				},
+			},
+			"invalid headers and keys": {
+				hfs: &sink.HeadersFromSecret{
+					SecretName: "auth",
+					Headers:    map[string]string{"Bad Header": "token", "X-Key": "bad/key"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(headersPath.Child("headers").Key("Bad Header"), "token", webhook.ConfigHeadersBadHeaderError),
+					field.Invalid(headersPath.Child("headers").Key("X-Key"), "bad/key", webhook.ConfigHeadersBadHeaderError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(test.hfs)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateCoalesce(t *testing.T) {
+	spec := func(window time.Duration) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			Coalesce: &sink.CoalesceSpec{
+				Window: metav1.Duration{Duration: window},
+			},
+		}
+	}
+
+	t.Run("it allows windows between 1s and 1h", func(t *testing.T) {
+		for _, w := range []time.Duration{time.Second, time.Minute, time.Hour} {
+			errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(w)})
+			if len(errs) != 0 {
+				t.Errorf("expected no errors for %s, got %v", w, errs)
+			}
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		for _, w := range []time.Duration{0, 500 * time.Millisecond, 2 * time.Hour} {
+			t.Run(w.String(), func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(w)})
+				expected := field.ErrorList{
+					field.Invalid(field.NewPath("spec", "coalesce", "window"), w.String(), webhook.ConfigCoalesceBadWindowError),
+				}
+				if diff := cmp.Diff(expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateRetentionHint(t *testing.T) {
+	spec := func(hint time.Duration) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			RetentionHint: &metav1.Duration{Duration: hint},
+		}
+	}
+
+	t.Run("it allows a positive hint", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(720 * time.Hour)})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		for _, h := range []time.Duration{0, -time.Hour} {
+			t.Run(h.String(), func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(h)})
+				expected := field.ErrorList{
+					field.Invalid(field.NewPath("spec", "retention_hint"), h.String(), webhook.ConfigRetentionHintBadError),
+				}
+				if diff := cmp.Diff(expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateSkipLogsOlderThan(t *testing.T) {
+	skipPath := field.NewPath("spec", "skip_logs_older_than")
+	spec := func(skip time.Duration) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			RawMode:           true,
+			SkipLogsOlderThan: &metav1.Duration{Duration: skip},
+		}
+	}
+
+	t.Run("it allows a positive threshold", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(24 * time.Hour)})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		shared := spec(time.Hour)
+		shared.RawMode = false
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"zero": {
+				spec: spec(0),
+				expected: field.ErrorList{
+					field.Invalid(skipPath, "0s", webhook.ConfigSkipOlderBadDurationError),
+				},
+			},
+			"negative": {
+				spec: spec(-time.Hour),
+				expected: field.ErrorList{
+					field.Invalid(skipPath, "-1h0m0s", webhook.ConfigSkipOlderBadDurationError),
+				},
+			},
+			"sinks reading the shared input": {
+				spec: shared,
+				expected: field.ErrorList{
+					field.Invalid(skipPath, "1h0m0s", webhook.ConfigSkipOlderNoInputError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidatePerLabelThrottle(t *testing.T) {
+	spec := func(label string, rate int) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			PerLabelThrottle: &sink.PerLabelThrottleSpec{
+				Label: label,
+				Rate:  rate,
+			},
+		}
+	}
+	throttlePath := field.NewPath("spec", "per_label_throttle")
+
+	t.Run("it allows label keys and the metadata keys", func(t *testing.T) {
+		for _, l := range []string{"app", "app.kubernetes.io/name", "pod_name", "container_name"} {
+			errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(l, 10)})
+			if len(errs) != 0 {
+				t.Errorf("expected no errors for %s, got %v", l, errs)
+			}
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			label    string
+			rate     int
+			expected field.ErrorList
+		}{
+			"an empty label": {
+				"",
+				10,
+				field.ErrorList{
+					field.Invalid(throttlePath.Child("label"), "", webhook.ConfigLabelThrottleBadLabelError),
+				},
+			},
+			"an invalid label": {
+				"bad label",
+				10,
+				field.ErrorList{
+					field.Invalid(throttlePath.Child("label"), "bad label", webhook.ConfigLabelThrottleBadLabelError),
+				},
+			},
+			"a rate below 1": {
+				"app",
+				0,
+				field.ErrorList{
+					field.Invalid(throttlePath.Child("rate"), 0, webhook.ConfigLabelThrottleBadRateError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(test.label, test.rate)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateScrapeTLS(t *testing.T) {
+	server := webhook.NewServer("127.0.0.1:0")
+	server.Run(false)
+	defer server.Close()
+
+	t.Run("it allows", func(t *testing.T) {
+		requireTelegraf(t)
+		tests := map[string]string{
+			"mutual tls": `{
+				"ca_secret_ref": {"name": "scrape-ca", "key": "ca.crt"},
+				"cert_secret_ref": {"name": "scrape-client", "key": "tls.crt"},
+				"key_secret_ref": {"name": "scrape-client", "key": "tls.key"}
+			}`,
+			"only a ca": `{
+				"ca_secret_ref": {"name": "scrape-ca", "key": "ca.crt"}
+			}`,
+		}
+
+		for name, tls := range tests {
+			t.Run(name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					metricAdmissionTemplate,
+					scrapeTLSSpec(tls),
+				))
+				if !resp.Response.Allowed {
+					t.Errorf("expected response to be allowed, got %+v", resp.Response.Result)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			template string
+			tls      string
+			message  string
+		}{
+			{
+				"tls on a ClusterMetricSink",
+				clusterMetricAdmissionTemplate,
+				`{"ca_secret_ref": {"name": "scrape-ca", "key": "ca.crt"}}`,
+				webhook.ConfigScrapeTLSClusterError,
+			},
+			{
+				"a cert without a key",
+				metricAdmissionTemplate,
+				`{"cert_secret_ref": {"name": "scrape-client", "key": "tls.crt"}}`,
+				webhook.ConfigScrapeTLSUnpairedError,
+			},
+			{
+				"a key without a cert",
+				metricAdmissionTemplate,
+				`{"key_secret_ref": {"name": "scrape-client", "key": "tls.key"}}`,
+				webhook.ConfigScrapeTLSUnpairedError,
+			},
+			{
+				"a secret ref without a key",
+				metricAdmissionTemplate,
+				`{"ca_secret_ref": {"name": "scrape-ca"}}`,
+				webhook.ConfigScrapeTLSBadSecretRefError,
+			},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				resp := postReview(t, server, "/metricsink", fmt.Sprintf(
+					test.template,
+					scrapeTLSSpec(test.tls),
+				))
+				if resp.Response.Allowed {
+					t.Fatal("expected response to not be allowed")
+				}
+				if resp.Response.Result.Message != test.message {
+					t.Errorf("expected message %q, got %q", test.message, resp.Response.Result.Message)
+				}
+			})
+		}
+	})
+}
+
+func scrapeTLSSpec(tls string) string {
+	return fmt.Sprintf(`{
+		"inputs": [ {
+			"type": "cpu"
+		} ],
+		"outputs": [ {
+			"type": "discard"
+		} ],
+		"scrape_tls": %s
+	}`, tls)
+}
+
+func TestValidateRequireFields(t *testing.T) {
+	fieldsPath := field.NewPath("spec", "require_fields")
+	spec := func(fields []string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			RequireFields: fields,
+		}
+	}
+
+	t.Run("it allows top level and nested fields", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec([]string{"log", "kubernetes.pod_name"})})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			fields   []string
+			expected field.ErrorList
+		}{
+			"an empty list": {
+				fields: []string{},
+				expected: field.ErrorList{
+					field.Invalid(fieldsPath, []string{}, webhook.ConfigRequireFieldsEmptyError),
+				},
+			},
+			"empty keys": {
+				fields: []string{"", "kubernetes.", "a..b"},
+				expected: field.ErrorList{
+					field.Invalid(fieldsPath.Index(0), "", webhook.ConfigRequireFieldsBadFieldError),
+					field.Invalid(fieldsPath.Index(1), "kubernetes.", webhook.ConfigRequireFieldsBadFieldError),
+					field.Invalid(fieldsPath.Index(2), "a..b", webhook.ConfigRequireFieldsBadFieldError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(test.fields)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateRedact(t *testing.T) {
+	redactPath := field.NewPath("spec", "redact")
+	spec := func(rules ...sink.RedactRule) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			Redact: rules,
+		}
+	}
+
+	t.Run("it allows every method on top level and nested fields", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(
+			sink.RedactRule{Field: "password", Method: "drop"},
+			sink.RedactRule{Field: "user.email", Method: "hash"},
+			sink.RedactRule{Field: "ssn", Method: "mask"},
+		)})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			rules    []sink.RedactRule
+			expected field.ErrorList
+		}{
+			"empty keys": {
+				rules: []sink.RedactRule{
+					{Field: "", Method: "drop"},
+					{Field: "user.", Method: "hash"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(redactPath.Index(0).Child("field"), "", webhook.ConfigRedactBadFieldError),
+					field.Invalid(redactPath.Index(1).Child("field"), "user.", webhook.ConfigRedactBadFieldError),
+				},
+			},
+			"unknown methods": {
+				rules: []sink.RedactRule{
+					{Field: "email", Method: "encrypt"},
+					{Field: "ssn", Method: ""},
+				},
+				expected: field.ErrorList{
+					field.Invalid(redactPath.Index(0).Child("method"), "encrypt", webhook.ConfigRedactBadMethodError),
+					field.Invalid(redactPath.Index(1).Child("method"), "", webhook.ConfigRedactBadMethodError),
+				},
+			},
+			"a field redacted twice": {
+				rules: []sink.RedactRule{
+					{Field: "email", Method: "hash"},
+					{Field: "email", Method: "mask"},
+				},
+				expected: field.ErrorList{
+					field.Invalid(redactPath.Index(1).Child("field"), "email", webhook.ConfigRedactDuplicateError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: spec(test.rules...)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateGeoIP(t *testing.T) {
+	geoIPPath := field.NewPath("spec", "geoip")
+	spec := func(sourceField, database string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			GeoIP: &sink.GeoIPSpec{
+				SourceField: sourceField,
+				Database:    database,
+			},
+		}
+	}
+
+	t.Run("it allows a record key and a database in the mounted directory", func(t *testing.T) {
+		for _, db := range []string{
+			"/fluent-bit/geoip/GeoLite2-Country.mmdb",
+			"/fluent-bit/geoip/asn/GeoLite2-ASN.mmdb",
+		} {
+			errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec("client_ip", db)})
+			if len(errs) != 0 {
+				t.Errorf("expected no errors for %s, got %v", db, errs)
+			}
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			sourceField string
+			database    string
+			expected    field.ErrorList
+		}{
+			"an empty source field": {
+				sourceField: "",
+				database:    "/fluent-bit/geoip/GeoLite2-City.mmdb",
+				expected: field.ErrorList{
+					field.Invalid(geoIPPath.Child("source_field"), "", webhook.ConfigGeoIPBadFieldError),
+				},
+			},
+			"a nested source field": {
+				sourceField: "request.client_ip",
+				database:    "/fluent-bit/geoip/GeoLite2-City.mmdb",
+				expected: field.ErrorList{
+					field.Invalid(geoIPPath.Child("source_field"), "request.client_ip", webhook.ConfigGeoIPBadFieldError),
+				},
+			},
+			"a database outside the mounted directory": {
+				sourceField: "client_ip",
+				database:    "/fluent-bit/geoip/../etc/GeoLite2-City.mmdb",
+				expected: field.ErrorList{
+					field.Invalid(geoIPPath.Child("database"), "/fluent-bit/geoip/../etc/GeoLite2-City.mmdb", webhook.ConfigGeoIPBadDatabaseError),
+				},
+			},
+			"a database that is not a MaxMind database": {
+				sourceField: "client_ip",
+				database:    "/fluent-bit/geoip/countries.csv",
+				expected: field.ErrorList{
+					field.Invalid(geoIPPath.Child("database"), "/fluent-bit/geoip/countries.csv", webhook.ConfigGeoIPBadDatabaseError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateClusterLogSink(&sink.ClusterLogSink{Spec: spec(test.sourceField, test.database)})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects geoip on namespaced sinks", func(t *testing.T) {
+		s := spec("client_ip", "/fluent-bit/geoip/GeoLite2-Country.mmdb")
+		errs := webhook.ValidateLogSink(&sink.LogSink{Spec: s})
+		expected := field.ErrorList{
+			field.Invalid(geoIPPath, s.GeoIP, webhook.ConfigGeoIPClusterOnlyError),
+		}
+		if diff := cmp.Diff(expected, errs); diff != "" {
+			t.Errorf("Errors not equal (-want, +got) = %v", diff)
+		}
+
+		server := webhook.NewServer("127.0.0.1:0")
+		server.Run(false)
+		defer server.Close()
+
+		resp := postReview(t, server, "/logsink", fmt.Sprintf(
+			namespacedAdmissionTemplate,
+			"LogSink",
+			"team-a",
+			`{"type": "webhook", "url": "https://example.com", "geoip": {"source_field": "client_ip", "database": "/fluent-bit/geoip/GeoLite2-Country.mmdb"}}`,
+		))
+		if resp.Response.Allowed {
+			t.Fatal("expected response to not be allowed")
+		}
+		if resp.Response.Result.Message != webhook.ConfigGeoIPClusterOnlyError {
+			t.Errorf("expected message %q, got %q", webhook.ConfigGeoIPClusterOnlyError, resp.Response.Result.Message)
+		}
+	})
+}
+
+func TestGeoIPDatabaseDirIsMounted(t *testing.T) {
+	f, err := os.Open("../../config/500-fluent-bit-daemon.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var ds appsV1.DaemonSet
+	if err := yaml.NewYAMLOrJSONDecoder(f, 4096).Decode(&ds); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range ds.Spec.Template.Spec.Containers {
+		if c.Name != "fluent-bit" {
+			continue
+		}
+		for _, m := range c.VolumeMounts {
+			if m.MountPath == webhook.GeoIPDatabaseDir {
+				return
+			}
+		}
+		t.Fatalf("Expected the fluent-bit container to mount %s, got %v", webhook.GeoIPDatabaseDir, c.VolumeMounts)
+	}
+	t.Fatal("Expected a fluent-bit container")
+}
+
+func TestValidateNodeSelector(t *testing.T) {
+	selectorPath := field.NewPath("spec", "node_selector")
+	webhookSpec := func(selector map[string]string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			NodeSelector: selector,
+		}
+	}
+
+	t.Run("it allows a selector of valid labels", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{
+			Spec: webhookSpec(map[string]string{"cloud.google.com/gke-nodepool": "gpu-pool"}),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		selector := map[string]string{"pool": "gpu"}
+		rawSpec := webhookSpec(selector)
+		rawSpec.RawMode = true
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"an invalid label key": {
+				spec: webhookSpec(map[string]string{"bad key": "gpu"}),
+				expected: field.ErrorList{
+					field.Invalid(selectorPath.Key("bad key"), "gpu", webhook.ConfigNodeSelectorBadError),
+				},
+			},
+			"an invalid label value": {
+				spec: webhookSpec(map[string]string{"pool": "gpu pool"}),
+				expected: field.ErrorList{
+					field.Invalid(selectorPath.Key("pool"), "gpu pool", webhook.ConfigNodeSelectorBadError),
+				},
+			},
+			"a syslog sink": {
+				spec: sink.SinkSpec{
+					Type: "syslog",
+					SyslogSpec: sink.SyslogSpec{
+						Host:      "example.com",
+						Port:      12345,
+						EnableTLS: true,
+					},
+					NodeSelector: selector,
+				},
+				expected: field.ErrorList{
+					field.Invalid(selectorPath, selector, webhook.ConfigNodeSelectorSyslogError),
+				},
+			},
+			"a raw mode sink": {
+				spec: rawSpec,
+				expected: field.ErrorList{
+					field.Invalid(selectorPath, selector, webhook.ConfigNodeSelectorConflictError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateSeverityMapping(t *testing.T) {
+	mappingPath := field.NewPath("spec", "severity_mapping")
+	webhookSpec := func(mapping map[string]int, targetField string) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			SeverityMapping: mapping,
+			SeverityField:   targetField,
+		}
+	}
+
+	t.Run("it allows syslog severities", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{
+			Spec: webhookSpec(map[string]int{"emerg": 0, "warn": 4, "debug": 7}, "level_number"),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"a negative level": {
+				spec: webhookSpec(map[string]int{"warn": -1}, ""),
+				expected: field.ErrorList{
+					field.Invalid(mappingPath.Key("warn"), -1, webhook.ConfigMappingBadLevelError),
+				},
+			},
+			"a level above debug": {
+				spec: webhookSpec(map[string]int{"trace": 8}, ""),
+				expected: field.ErrorList{
+					field.Invalid(mappingPath.Key("trace"), 8, webhook.ConfigMappingBadLevelError),
+				},
+			},
+			"an upper case severity": {
+				spec: webhookSpec(map[string]int{"WARN": 4}, ""),
+				expected: field.ErrorList{
+					field.Invalid(mappingPath.Key("WARN"), "WARN", webhook.ConfigMappingBadSeverityError),
+				},
+			},
+			"an empty severity": {
+				spec: webhookSpec(map[string]int{"": 4}, ""),
+				expected: field.ErrorList{
+					field.Invalid(mappingPath.Key(""), "", webhook.ConfigMappingBadSeverityError),
+				},
+			},
+			"a field without a mapping": {
+				spec: webhookSpec(nil, "level_number"),
+				expected: field.ErrorList{
+					field.Invalid(field.NewPath("spec", "severity_field"), "level_number", webhook.ConfigMappingFieldError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateMaxFieldBytes(t *testing.T) {
+	limitsPath := field.NewPath("spec", "max_field_bytes")
+	webhookSpec := func(limits map[string]int) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			MaxFieldBytes: limits,
+		}
+	}
+
+	t.Run("it allows positive limits of fields", func(t *testing.T) {
+		errs := webhook.ValidateLogSink(&sink.LogSink{
+			Spec: webhookSpec(map[string]int{
+				"log":                            4096,
+				"kubernetes.labels.app":          64,
+				"labels[app.kubernetes.io/name]": 64,
+				"[a.b][c.d]":                     8,
+			}),
+		})
+		if len(errs) != 0 {
+			t.Errorf("expected no errors, got %v", errs)
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"a zero limit": {
+				spec: webhookSpec(map[string]int{"log": 0}),
+				expected: field.ErrorList{
+					field.Invalid(limitsPath.Key("log"), 0, webhook.ConfigMaxFieldBytesBadLimitError),
+				},
+			},
+			"a negative limit": {
+				spec: webhookSpec(map[string]int{"log": -1}),
+				expected: field.ErrorList{
+					field.Invalid(limitsPath.Key("log"), -1, webhook.ConfigMaxFieldBytesBadLimitError),
+				},
+			},
+			"an empty key": {
+				spec: webhookSpec(map[string]int{"kubernetes..app": 10}),
+				expected: field.ErrorList{
+					field.Invalid(limitsPath.Key("kubernetes..app"), "kubernetes..app", webhook.ConfigMaxFieldBytesBadFieldError),
+				},
+			},
+			"unbalanced brackets": {
+				spec: webhookSpec(map[string]int{"labels[app": 10, "labels]app": 10, "labels[]": 10, "[a]b": 10}),
+				expected: field.ErrorList{
+					field.Invalid(limitsPath.Key("[a]b"), "[a]b", webhook.ConfigMaxFieldBytesBadFieldError),
+					field.Invalid(limitsPath.Key("labels[]"), "labels[]", webhook.ConfigMaxFieldBytesBadFieldError),
+					field.Invalid(limitsPath.Key("labels[app"), "labels[app", webhook.ConfigMaxFieldBytesBadFieldError),
+					field.Invalid(limitsPath.Key("labels]app"), "labels]app", webhook.ConfigMaxFieldBytesBadFieldError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
+
+func TestValidateHeartbeat(t *testing.T) {
+	webhookSpec := func(interval time.Duration) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			Heartbeat: &sink.HeartbeatSpec{
+				Interval: metav1.Duration{Duration: interval},
+			},
+		}
+	}
+
+	for _, interval := range []time.Duration{time.Second, 1500 * time.Millisecond} {
+		t.Run("it allows an interval of "+interval.String(), func(t *testing.T) {
+			errs := webhook.ValidateLogSink(&sink.LogSink{Spec: webhookSpec(interval)})
+			if len(errs) != 0 {
+				t.Errorf("expected no errors, got %v", errs)
+			}
+		})
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second, time.Nanosecond, 500 * time.Millisecond} {
+		t.Run("it rejects an interval of "+interval.String(), func(t *testing.T) {
+			errs := webhook.ValidateLogSink(&sink.LogSink{Spec: webhookSpec(interval)})
+			expected := field.ErrorList{
+				field.Invalid(field.NewPath("spec", "heartbeat", "interval"), interval.String(), webhook.ConfigHeartbeatBadIntervalError),
+			}
+			if diff := cmp.Diff(expected, errs); diff != "" {
+				t.Errorf("Errors not equal (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schemaPath := field.NewPath("spec", "schema")
+	webhookSpec := func(schema *sink.SchemaSpec) sink.SinkSpec {
+		return sink.SinkSpec{
+			Type: "webhook",
+			WebhookSpec: sink.WebhookSpec{
+				URL: "https://example.com/place",
+			},
+			Schema: schema,
+		}
+	}
+
+	t.Run("it allows", func(t *testing.T) {
+		tests := map[string]*sink.SchemaSpec{
+			"a strict schema":               {AllowedKeys: []string{"log", "kubernetes"}, Strict: true},
+			"a lenient schema":              {AllowedKeys: []string{"log"}},
+			"a lenient schema without keys": {},
+		}
+		for name, schema := range tests {
+			t.Run(name, func(t *testing.T) {
+				if errs := webhook.ValidateLogSink(&sink.LogSink{Spec: webhookSpec(schema)}); len(errs) != 0 {
+					t.Errorf("expected no errors, got %v", errs)
+				}
+			})
+		}
+	})
+
+	t.Run("it rejects", func(t *testing.T) {
+		projectSpec := webhookSpec(&sink.SchemaSpec{AllowedKeys: []string{"log"}})
+		projectSpec.Project = []string{"log"}
+		syslogSpec := sink.SinkSpec{
+			Type: "syslog",
+			SyslogSpec: sink.SyslogSpec{
+				Host:      "example.com",
+				Port:      12345,
+				EnableTLS: true,
+			},
+			Schema: &sink.SchemaSpec{AllowedKeys: []string{"log"}, Strict: true},
+		}
+
+		tests := map[string]struct {
+			spec     sink.SinkSpec
+			expected field.ErrorList
+		}{
+			"a strict schema without keys": {
+				spec: webhookSpec(&sink.SchemaSpec{Strict: true}),
+				expected: field.ErrorList{
+					field.Invalid(schemaPath.Child("allowed_keys"), []string(nil), webhook.ConfigSchemaNoKeysError),
+				},
+			},
+			"an invalid key": {
+				spec: webhookSpec(&sink.SchemaSpec{AllowedKeys: []string{"log", "bad key"}, Strict: true}),
+				expected: field.ErrorList{
+					field.Invalid(schemaPath.Child("allowed_keys").Index(1), "bad key", webhook.ConfigSchemaBadKeyError),
+				},
+			},
+			"a schema with project": {
+				spec: projectSpec,
+				expected: field.ErrorList{
+					field.Invalid(schemaPath, projectSpec.Schema, webhook.ConfigSchemaProjectError),
+				},
+			},
+			"a strict schema on a syslog sink": {
+				spec: syslogSpec,
+				expected: field.ErrorList{
+					field.Invalid(schemaPath.Child("strict"), true, webhook.ConfigSchemaSyslogError),
+				},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				errs := webhook.ValidateLogSink(&sink.LogSink{Spec: test.spec})
+				if diff := cmp.Diff(test.expected, errs); diff != "" {
+					t.Errorf("Errors not equal (-want, +got) = %v", diff)
+				}
+			})
+		}
+	})
+}
@@ -173,6 +173,11 @@ func createMetricSink(
 					"data_format": "json",
 				},
 			},
+			// Keep the test output from filling the pod's disk.
+			FileRotation: &v1alpha1.FileRotation{
+				RotationCount: 1,
+				MaxSize:       1 << 20,
+			},
 		},
 	})
 	assertErr(t, "Error creating MetricSink: %v", err)
@@ -643,6 +643,33 @@ func checkMetrics(received, expected map[string]float64) []error {
 	return errorList
 }
 
+// telegrafWait is how long assertTelegrafOutputtedData waits for the
+// output of telegraf and how often it checks it.
+type telegrafWait struct {
+	timeout  time.Duration
+	interval time.Duration
+}
+
+var (
+	telegrafWaitTimeout = flag.Duration(
+		"telegraf-wait-timeout",
+		20*time.Second,
+		"how long to wait for the output of telegraf",
+	)
+	telegrafWaitInterval = flag.Duration(
+		"telegraf-wait-interval",
+		time.Second,
+		"how often to check the output of telegraf",
+	)
+)
+
+func defaultTelegrafWait() telegrafWait {
+	return telegrafWait{
+		timeout:  *telegrafWaitTimeout,
+		interval: *telegrafWaitInterval,
+	}
+}
+
 func assertTelegrafOutputtedData(
 	t *testing.T,
 	label string,
@@ -651,17 +678,38 @@ func assertTelegrafOutputtedData(
 	restCfg *rest.Config,
 	assert func(map[string]float64) []error,
 ) {
-	var errs []error
-	waitTime := 20
-	for timeWaited := 0; waitTime >= timeWaited; timeWaited++ {
+	assertTelegrafOutputtedDataWithin(t, defaultTelegrafWait(), label, namespace, kc, restCfg, assert)
+}
+
+func assertTelegrafOutputtedDataWithin(
+	t *testing.T,
+	wait telegrafWait,
+	label string,
+	namespace string,
+	kc *test.KubeClient,
+	restCfg *rest.Config,
+	assert func(map[string]float64) []error,
+) {
+	errs := pollTelegraf(wait, func() []error {
 		t.Logf("Checking output of telegraf")
-		errs = checkTelegrafOutputtedData(t, label, namespace, kc, restCfg, assert)
-		if len(errs) == 0 {
-			return
+		return checkTelegrafOutputtedData(t, label, namespace, kc, restCfg, assert)
+	})
+	if len(errs) > 0 {
+		t.Fatalf("Error looking for telegraf output after %s: %v\n", wait.timeout, errs)
+	}
+}
+
+// pollTelegraf runs check every interval until it returns no errors or the
+// timeout passes. It returns the errors of the last check.
+func pollTelegraf(wait telegrafWait, check func() []error) []error {
+	deadline := time.Now().Add(wait.timeout)
+	for {
+		errs := check()
+		if len(errs) == 0 || !time.Now().Add(wait.interval).Before(deadline) {
+			return errs
 		}
-		time.Sleep(1 * time.Second)
+		time.Sleep(wait.interval)
 	}
-	t.Fatalf("Error looking for telegraf output: %v\n", errs)
 }
 
 func checkTelegrafOutputtedData(
@@ -0,0 +1,66 @@
+// +build e2e
+
+/*
+Copyright 2018 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollTelegraf(t *testing.T) {
+	wait := telegrafWait{
+		timeout:  50 * time.Millisecond,
+		interval: 10 * time.Millisecond,
+	}
+
+	t.Run("it returns once the check passes", func(t *testing.T) {
+		var checks int
+		errs := pollTelegraf(wait, func() []error {
+			checks++
+			if checks < 3 {
+				return []error{errors.New("no output yet")}
+			}
+			return nil
+		})
+
+		if len(errs) != 0 || checks != 3 {
+			t.Errorf("Expected 3 checks and no errors, got %d and %v", checks, errs)
+		}
+	})
+
+	t.Run("it returns the last error on timeout", func(t *testing.T) {
+		var checks int
+		start := time.Now()
+		errs := pollTelegraf(wait, func() []error {
+			checks++
+			if checks == 1 {
+				return []error{errors.New("first error")}
+			}
+			return []error{errors.New("last error")}
+		})
+
+		if time.Since(start) > time.Second {
+			t.Errorf("Expected the shorter wait, took %s", time.Since(start))
+		}
+		if len(errs) != 1 || errs[0].Error() != "last error" {
+			t.Errorf("Expected the last error, got %v", errs)
+		}
+	})
+}